@@ -0,0 +1,52 @@
+package diagnose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	cmd := root.Command("diagnose", "Diagnose how we communicate with the OONI backend and the test helpers")
+	outputFile := cmd.Flag("output", "File where to save the JSON report to attach to bug reports").
+		Short('o').Default("ooniprobe-diagnose.json").String()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		return diagnose(*outputFile)
+	})
+}
+
+func diagnose(outputFile string) error {
+	output.SectionTitle("Network diagnosis")
+	probe, err := root.Init()
+	if err != nil {
+		log.Errorf("%s", err)
+		return err
+	}
+	// Note: we do not lookup the backends because we mostly
+	// need a diagnosis when we cannot reach the backends.
+	sess, err := probe.NewSession(context.Background(), model.RunTypeManual)
+	if err != nil {
+		log.WithError(err).Error("Failed to create a measurement session")
+		return err
+	}
+	defer sess.Close()
+	report := sess.DiagnoseNetwork(context.Background())
+	fmt.Print(report.Summary())
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputFile, data, 0600); err != nil {
+		log.WithError(err).Errorf("Failed to write %s", outputFile)
+		return err
+	}
+	log.Infof("Saved the report to %s: please, attach it to your bug report", outputFile)
+	return nil
+}
@@ -1,10 +1,15 @@
 package info
 
 import (
+	"os"
+
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 )
 
 func init() {
@@ -32,5 +37,23 @@ func doinfo(config doinfoconfig) error {
 	}
 	config.Logger.WithFields(log.Fields{"path": probeCLI.Home()}).Info("Home")
 	config.Logger.WithFields(log.Fields{"path": probeCLI.TempDir()}).Info("TempDir")
+	depth, dropped := submitQueueStats(probeCLI.Home())
+	config.Logger.WithFields(log.Fields{"depth": depth, "dropped": dropped}).Info("SubmissionQueue")
 	return nil
 }
+
+// submitQueueStats returns the number of measurements waiting to be submitted and the
+// number of measurements dropped because the queue was full. We do not want to create
+// the engine directory just for the purpose of checking.
+func submitQueueStats(home string) (int, int64) {
+	engineDir := utils.EngineDir(home)
+	if _, err := os.Stat(engineDir); err != nil {
+		return 0, 0
+	}
+	kvStore, err := kvstore.NewFS(engineDir)
+	if err != nil {
+		return 0, 0
+	}
+	queue := submitqueue.New(kvStore)
+	return queue.Len(), queue.Dropped()
+}
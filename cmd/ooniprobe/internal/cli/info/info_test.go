@@ -7,6 +7,10 @@ import (
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/oonitest"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 )
 
 func TestNewProbeCLIFailed(t *testing.T) {
@@ -54,7 +58,7 @@ func TestSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(handler.FakeEntries) != 2 {
+	if len(handler.FakeEntries) != 3 {
 		t.Fatal("invalid number of log entries")
 	}
 	entry := handler.FakeEntries[0]
@@ -77,4 +81,34 @@ func TestSuccess(t *testing.T) {
 	if entry.Fields["path"].(string) != "faketempdir" {
 		t.Fatal("invalid path")
 	}
+	entry = handler.FakeEntries[2]
+	if entry.Level != log.InfoLevel {
+		t.Fatal("invalid log level")
+	}
+	if entry.Message != "SubmissionQueue" {
+		t.Fatal("invalid .Message")
+	}
+	if entry.Fields["depth"].(int) != 0 {
+		t.Fatal("invalid depth")
+	}
+	if entry.Fields["dropped"].(int64) != 0 {
+		t.Fatal("invalid dropped")
+	}
+}
+
+func TestSubmitQueueStats(t *testing.T) {
+	home := t.TempDir()
+	if depth, dropped := submitQueueStats(home); depth != 0 || dropped != 0 {
+		t.Fatal("unexpected stats", depth, dropped)
+	}
+	kvStore, err := kvstore.NewFS(utils.EngineDir(home))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := submitqueue.New(kvStore).Enqueue(&model.Measurement{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if depth, dropped := submitQueueStats(home); depth != 1 || dropped != 0 {
+		t.Fatal("unexpected stats", depth, dropped)
+	}
 }
@@ -0,0 +1,120 @@
+package oonirun
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/onboard"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/oonirun"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
+)
+
+func init() {
+	cmd := root.Command("oonirun", "Author, validate and run OONI Run v2 descriptors")
+
+	validateCmd := cmd.Command("validate", "Validate OONI Run v2 descriptor files")
+	validateFiles := validateCmd.Arg("file", "Descriptor file to validate").Required().Strings()
+	validateCmd.Action(func(_ *kingpin.ParseContext) error {
+		return oonirun.V2ValidateDescriptorFiles(log.Log, *validateFiles)
+	})
+
+	runFileCmd := cmd.Command("run-file", "Run OONI Run v2 descriptor files")
+	runFiles := runFileCmd.Arg("file", "Descriptor file to run").Required().Strings()
+	noCollector := runFileCmd.Flag("no-collector", "Disable uploading measurements to a collector").Bool()
+	reportFile := runFileCmd.Flag("report-file", "Also save measurements into the given file").String()
+	runFileCmd.Action(func(_ *kingpin.ParseContext) error {
+		return withSession(*noCollector, func(ctx context.Context, config *oonirun.LinkConfig) error {
+			config.NoJSON = *reportFile == ""
+			config.ReportFile = *reportFile
+			var failed int
+			for _, filename := range *runFiles {
+				if err := oonirun.V2MeasureDescriptorFile(ctx, config, filename); err != nil {
+					log.WithError(err).Errorf("failed to run %s", filename)
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("failed to run %d of %d descriptors", failed, len(*runFiles))
+			}
+			return nil
+		})
+	})
+
+	diffCmd := cmd.Command("diff", "Show the differences between two OONI Run v2 descriptors")
+	oldSource := diffCmd.Arg("old", "Old descriptor file or HTTPS URL").Required().String()
+	newSource := diffCmd.Arg("new", "New descriptor file or HTTPS URL").Required().String()
+	diffCmd.Action(func(_ *kingpin.ParseContext) error {
+		if !oonirun.V2IsDescriptorURL(*oldSource) && !oonirun.V2IsDescriptorURL(*newSource) {
+			return diff(context.Background(), &oonirun.LinkConfig{}, *oldSource, *newSource)
+		}
+		return withSession(true, func(ctx context.Context, config *oonirun.LinkConfig) error {
+			return diff(ctx, config, *oldSource, *newSource)
+		})
+	})
+}
+
+// diff prints the differences between the old and the new descriptors.
+func diff(ctx context.Context, config *oonirun.LinkConfig, oldSource, newSource string) error {
+	d, err := oonirun.V2DiffDescriptorSources(ctx, config, oldSource, newSource)
+	if err != nil {
+		return err
+	}
+	if d == "" {
+		log.Infof("%s and %s are equal", oldSource, newSource)
+		return nil
+	}
+	fmt.Print(d)
+	return nil
+}
+
+// withSession creates a measurement session, looks up the backends and
+// the probe location, and calls fn with a suitable link config.
+func withSession(noCollector bool, fn func(ctx context.Context, config *oonirun.LinkConfig) error) error {
+	probe, err := root.Init()
+	if err != nil {
+		log.Errorf("%s", err)
+		return err
+	}
+	if err := onboard.MaybeOnboarding(probe); err != nil {
+		log.WithError(err).Error("failed to perform onboarding")
+		return err
+	}
+	ctx := context.Background()
+	sess, err := probe.NewSession(ctx, model.RunTypeManual)
+	if err != nil {
+		log.WithError(err).Error("Failed to create a measurement session")
+		return err
+	}
+	defer sess.Close()
+	if err := sess.MaybeLookupBackendsContext(ctx); err != nil {
+		log.WithError(err).Errorf("Failed to discover OONI backends")
+		return err
+	}
+	if err := sess.MaybeLookupLocationContext(ctx); err != nil {
+		log.WithError(err).Error("Failed to lookup the location of the probe")
+		return err
+	}
+	return fn(ctx, newLinkConfig(probe, sess, noCollector))
+}
+
+// newLinkConfig creates the link config for running descriptors.
+func newLinkConfig(probe *ooni.Probe, sess *engine.Session, noCollector bool) *oonirun.LinkConfig {
+	return &oonirun.LinkConfig{
+		AcceptChanges: false,
+		Annotations:   map[string]string{},
+		FollowUps:     probe.Config().Nettests.WebsitesFollowUps,
+		KVStore:       sess.KeyValueStore(),
+		NoCollector:   noCollector || !probe.Config().Sharing.UploadResults,
+		NoJSON:        true,
+		ProbeCC:       sess.ProbeCC(),
+		ProbeASN:      sess.ProbeASNString(),
+		Session:       sess,
+		SubmitQueue:   submitqueue.New(sess.KeyValueStore()),
+	}
+}
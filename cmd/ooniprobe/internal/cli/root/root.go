@@ -47,6 +47,9 @@ func init() {
 	proxy := Cmd.Flag(
 		"proxy", "specify a proxy address for speaking to the OONI Probe backend (use: --proxy=psiphon:/// for psiphon)",
 	).String()
+	probeServices := Cmd.Flag(
+		"probe-services", "specify the URL of the OONI backend to use (e.g., a local oonibackend-mock)",
+	).String()
 
 	Cmd.PreAction(func(ctx *kingpin.ParseContext) error {
 		// TODO(bassosimone): we need to properly deprecate --batch
@@ -88,6 +91,7 @@ func init() {
 			if *isBatch {
 				probe.SetIsBatch(true)
 			}
+			probe.SetProbeServicesURL(*probeServices)
 
 			return probe, nil
 		}
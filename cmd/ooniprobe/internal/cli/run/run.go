@@ -1,6 +1,10 @@
 package run
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/apex/log"
 	"github.com/fatih/color"
@@ -8,13 +12,17 @@ import (
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/nettests"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/oonirun"
 )
 
 func init() {
 	cmd := root.Command("run", "Run a test group or OONI Run link")
 	noCollector := cmd.Flag("no-collector", "Disable uploading measurements to a collector").Bool()
 	noCredentials := cmd.Flag("no-creds", "Submit measurements without an anonymous credential").Bool()
+	resume := cmd.Flag("resume", "Resume the interrupted run skipping the URLs we already measured").Bool()
 
 	var probe *ooni.Probe
 	cmd.Action(func(_ *kingpin.ParseContext) error {
@@ -45,6 +53,7 @@ func init() {
 				Probe:         probe,
 				RunType:       runType,
 				NoCredentials: *noCredentials,
+				Resume:        *resume,
 			}
 			if err := nettests.RunGroup(conf); err != nil {
 				log.WithError(err).Errorf("failed to run %s", name)
@@ -64,6 +73,14 @@ func init() {
 	websitesCmd := cmd.Command("websites", "")
 	inputFile := websitesCmd.Flag("input-file", "File containing input URLs").Strings()
 	input := websitesCmd.Flag("input", "Test the specified URL").Strings()
+	testList := websitesCmd.Flag("test-list", "Test list in citizenlab/test-lists CSV or JSON format").Strings()
+	categories := websitesCmd.Flag("category", "Only test URLs with the given category code").Strings()
+	excludeCategories := websitesCmd.Flag("exclude-category", "Do not test URLs with the given category code").Strings()
+	includePatterns := websitesCmd.Flag("include", "Only test URLs matching the given regexp").Strings()
+	excludePatterns := websitesCmd.Flag("exclude", "Do not test URLs matching the given regexp").Strings()
+	sampleSize := websitesCmd.Flag("sample-size", "Maximum number of URLs to test after filtering").Int()
+	sampleStrategy := websitesCmd.Flag("sample-strategy", "How to sample URLs").Default(
+		model.TargetSampleRandom).Enum(model.TargetSampleRandom, model.TargetSampleStratified)
 	websitesCmd.Action(func(_ *kingpin.ParseContext) error {
 		log.Infof("Running %s tests", color.BlueString("websites"))
 		return nettests.RunGroup(nettests.RunGroupConfig{
@@ -73,6 +90,16 @@ func init() {
 			Inputs:        *input,
 			RunType:       model.RunTypeManual,
 			NoCredentials: *noCredentials,
+			Resume:        *resume,
+			TargetFilter: newTargetFilter(&model.ExperimentTargetFilter{
+				CategoryCodes:        *categories,
+				ExcludeCategoryCodes: *excludeCategories,
+				ExcludePatterns:      *excludePatterns,
+				IncludePatterns:      *includePatterns,
+				SampleSize:           *sampleSize,
+				SampleStrategy:       *sampleStrategy,
+			}),
+			TestLists: *testList,
 		})
 	})
 
@@ -82,17 +109,95 @@ func init() {
 		cmd.Command(name, "").Action(genRunWithGroupName(name))
 	}
 
+	runLinks := func(runType model.RunType) error {
+		return nettests.RunLinks(nettests.RunLinksConfig{
+			NoCredentials: *noCredentials,
+			Probe:         probe,
+			RunType:       runType,
+			URLs:          probe.Config().OONIRun.Links,
+		})
+	}
+
+	linkCmd := cmd.Command("link", "Manage and run the OONI Run v2 links you subscribed to")
+	linkCmd.Command("run", "Run the subscribed links").Default().Action(func(_ *kingpin.ParseContext) error {
+		return runLinks(model.RunTypeManual)
+	})
+
+	linkAddCmd := linkCmd.Command("add", "Subscribe to an OONI Run v2 link")
+	linkAddURL := linkAddCmd.Arg("url", "The OONI Run v2 link URL").Required().String()
+	linkAddCmd.Action(func(_ *kingpin.ParseContext) error {
+		if !strings.HasPrefix(*linkAddURL, "https://") {
+			return errors.New("the OONI Run v2 link URL must use HTTPS")
+		}
+		if !probe.Config().OONIRun.AddLink(*linkAddURL) {
+			log.Infof("already subscribed to %s", *linkAddURL)
+			return nil
+		}
+		log.Infof("subscribed to %s", *linkAddURL)
+		return probe.Config().Write()
+	})
+
+	linkCmd.Command("list", "List the subscribed links").Action(func(_ *kingpin.ParseContext) error {
+		return listLinks(probe)
+	})
+
+	linkRemoveCmd := linkCmd.Command("remove", "Unsubscribe from an OONI Run v2 link")
+	linkRemoveURL := linkRemoveCmd.Arg("url", "The OONI Run v2 link URL").Required().String()
+	linkRemoveCmd.Action(func(_ *kingpin.ParseContext) error {
+		if !probe.Config().OONIRun.RemoveLink(*linkRemoveURL) {
+			return fmt.Errorf("not subscribed to %s", *linkRemoveURL)
+		}
+		log.Infof("unsubscribed from %s", *linkRemoveURL)
+		return probe.Config().Write()
+	})
+
 	unattendedCmd := cmd.Command("unattended", "")
 	unattendedCmd.Action(func(_ *kingpin.ParseContext) error {
-		return functionalRun(model.RunTypeTimed, func(name string, gr nettests.Group) bool {
+		if err := functionalRun(model.RunTypeTimed, func(name string, gr nettests.Group) bool {
 			return gr.UnattendedOK
-		})
+		}); err != nil {
+			return err
+		}
+		return runLinks(model.RunTypeTimed)
 	})
 
 	allCmd := cmd.Command("all", "").Default()
 	allCmd.Action(func(_ *kingpin.ParseContext) error {
-		return functionalRun(model.RunTypeManual, func(name string, gr nettests.Group) bool {
+		if err := functionalRun(model.RunTypeManual, func(name string, gr nettests.Group) bool {
 			return true
-		})
+		}); err != nil {
+			return err
+		}
+		return runLinks(model.RunTypeManual)
 	})
 }
+
+// newTargetFilter returns the given filter or nil when it does not filter any URL.
+func newTargetFilter(filter *model.ExperimentTargetFilter) *model.ExperimentTargetFilter {
+	if filter.Empty() {
+		return nil
+	}
+	return filter
+}
+
+// listLinks lists the subscribed OONI Run v2 links along with the
+// name of their cached descriptor, if we have already run them.
+func listLinks(probe *ooni.Probe) error {
+	links := probe.Config().OONIRun.Links
+	if len(links) <= 0 {
+		log.Info("you have not subscribed to any OONI Run v2 link")
+		return nil
+	}
+	kvStore, err := kvstore.NewFS(utils.EngineDir(probe.Home()))
+	if err != nil {
+		return err
+	}
+	for _, URL := range links {
+		name := "(not run yet)"
+		if desc, err := oonirun.V2CachedDescriptor(kvStore, URL); err == nil && desc != nil {
+			name = desc.Name
+		}
+		log.Infof("%s %s", URL, name)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package tactics
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/enginenetx"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	cmd := root.Command("tactics", "Manage the user-supplied bridges, SNIs and proxies used to reach the OONI backend")
+
+	validateCmd := cmd.Command("validate", "Validate tactics files")
+	validateFiles := validateCmd.Arg("file", "Tactics file to validate").Required().Strings()
+	validateCmd.Action(func(_ *kingpin.ParseContext) error {
+		return validate(log.Log, *validateFiles)
+	})
+
+	importCmd := cmd.Command("import", "Validate a tactics file and use it from now on")
+	importFile := importCmd.Arg("file", "Tactics file to import").Required().String()
+	importCmd.Action(func(_ *kingpin.ParseContext) error {
+		kvStore, err := newKVStore()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(*importFile)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to read %s", *importFile)
+			return err
+		}
+		if err := enginenetx.ImportUserPolicy(kvStore, data); err != nil {
+			log.WithError(err).Errorf("Failed to import %s", *importFile)
+			return err
+		}
+		log.Infof("Imported %s", *importFile)
+		return nil
+	})
+
+	exportCmd := cmd.Command("export", "Write the tactics file in use to a file or to the stdout")
+	exportFile := exportCmd.Arg("file", "File where to write the tactics").String()
+	exportCmd.Action(func(_ *kingpin.ParseContext) error {
+		kvStore, err := newKVStore()
+		if err != nil {
+			return err
+		}
+		data, err := enginenetx.ExportUserPolicy(kvStore)
+		if err != nil {
+			log.WithError(err).Error("No tactics file to export")
+			return err
+		}
+		if *exportFile == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		return os.WriteFile(*exportFile, data, 0600)
+	})
+}
+
+// newKVStore opens the engine key-value store where we keep the tactics.
+func newKVStore() (model.KeyValueStore, error) {
+	probe, err := root.Init()
+	if err != nil {
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	return kvstore.NewFS(utils.EngineDir(probe.Home()))
+}
+
+// validate validates the given tactics files.
+func validate(logger log.Interface, filenames []string) error {
+	var failed int
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err == nil {
+			err = enginenetx.ValidateUserPolicy(data)
+		}
+		if err != nil {
+			logger.Errorf("%s is not valid:\n%s", filename, err.Error())
+			failed++
+			continue
+		}
+		logger.Infof("%s is valid", filename)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tactics files are not valid", failed, len(filenames))
+	}
+	return nil
+}
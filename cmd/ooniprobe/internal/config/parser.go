@@ -53,6 +53,7 @@ type Config struct {
 
 	Sharing  Sharing  `json:"sharing"`
 	Nettests Nettests `json:"nettests"`
+	OONIRun  OONIRun  `json:"oonirun"`
 	Advanced Advanced `json:"advanced"`
 
 	mutex sync.Mutex
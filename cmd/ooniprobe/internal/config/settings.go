@@ -1,5 +1,7 @@
 package config
 
+import "slices"
+
 // Sharing settings
 type Sharing struct {
 	UploadResults bool `json:"upload_results"`
@@ -13,4 +15,33 @@ type Nettests struct {
 	WebsitesMaxRuntime           int64    `json:"websites_max_runtime"`
 	WebsitesURLLimit             int64    `json:"websites_url_limit"`
 	WebsitesEnabledCategoryCodes []string `json:"websites_enabled_category_codes"`
+	WebsitesParallelism          int64    `json:"websites_parallelism"`
+	WebsitesFollowUps            bool     `json:"websites_follow_ups"`
+}
+
+// OONIRun settings
+type OONIRun struct {
+	// Links contains the OONI Run v2 links the user subscribed to.
+	Links []string `json:"links,omitempty"`
+}
+
+// AddLink subscribes to the given link and returns false if we
+// were already subscribed to such a link.
+func (o *OONIRun) AddLink(URL string) bool {
+	if slices.Contains(o.Links, URL) {
+		return false
+	}
+	o.Links = append(o.Links, URL)
+	return true
+}
+
+// RemoveLink unsubscribes from the given link and returns false if
+// we were not subscribed to such a link.
+func (o *OONIRun) RemoveLink(URL string) bool {
+	idx := slices.Index(o.Links, URL)
+	if idx < 0 {
+		return false
+	}
+	o.Links = slices.Delete(o.Links, idx, idx+1)
+	return true
 }
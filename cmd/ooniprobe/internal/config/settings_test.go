@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOONIRunLinks(t *testing.T) {
+	var settings OONIRun
+	if !settings.AddLink("https://run.ooni.org/v2/1") || !settings.AddLink("https://run.ooni.org/v2/2") {
+		t.Fatal("expected to add the links")
+	}
+	if settings.AddLink("https://run.ooni.org/v2/1") {
+		t.Fatal("expected not to add the same link twice")
+	}
+	if !settings.RemoveLink("https://run.ooni.org/v2/1") {
+		t.Fatal("expected to remove the link")
+	}
+	if settings.RemoveLink("https://run.ooni.org/v2/1") {
+		t.Fatal("expected not to remove a link we removed")
+	}
+	if diff := cmp.Diff([]string{"https://run.ooni.org/v2/2"}, settings.Links); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,163 @@
+package nettests
+
+import (
+	"context"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/oonirun"
+)
+
+// RunLinksConfig contains the settings for running OONI Run v2 links.
+type RunLinksConfig struct {
+	NoCredentials bool
+	Probe         *ooni.Probe
+	RunType       model.RunType // only run the nettests enabled for this run type
+	URLs          []string
+}
+
+// RunLinks runs the OONI Run v2 links the user subscribed to. For each link, we
+// refresh the cached descriptor, showing what changed, and run the nettests that
+// the link enables for the given run type, storing their results in the database
+// under the link's name. When we cannot refresh a descriptor (e.g., because the
+// backend is not reachable) we use the descriptor we cached the last time.
+func RunLinks(config RunLinksConfig) error {
+	if len(config.URLs) <= 0 {
+		return nil
+	}
+	if config.Probe.IsTerminated() {
+		log.Debugf("context is terminated, stopping RunLinks early")
+		return nil
+	}
+
+	sess, network, err := newSession(config.Probe, config.RunType)
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	config.Probe.ListenForSignals()
+	config.Probe.MaybeListenForStdinClosed()
+	for _, URL := range config.URLs {
+		if config.Probe.IsTerminated() {
+			log.Debugf("context is terminated, stopping links early")
+			break
+		}
+		desc := refreshLink(sess, config.RunType, URL)
+		if desc == nil {
+			continue
+		}
+		if err := runLink(config, sess, network, URL, desc); err != nil {
+			log.WithError(err).Warnf("Failed to run %s", URL)
+		}
+	}
+	return nil
+}
+
+// refreshLink returns the up-to-date descriptor of the given link or the
+// cached descriptor when we cannot fetch it, or nil if we cannot obtain it.
+func refreshLink(sess *engine.Session, runType model.RunType, URL string) *oonirun.V2Descriptor {
+	linkConfig := &oonirun.LinkConfig{
+		AcceptChanges: true, // subscribing to a link means accepting its changes
+		KVStore:       sess.KeyValueStore(),
+		ProbeCC:       sess.ProbeCC(),
+		ProbeASN:      sess.ProbeASNString(),
+		RunType:       runType,
+		Session:       sess,
+	}
+	desc, diff, err := oonirun.V2RefreshDescriptor(context.Background(), linkConfig, URL)
+	if err == nil {
+		if diff != "" {
+			log.Infof("%s changed as follows:\n\n%s", URL, diff)
+		}
+		return desc
+	}
+	log.WithError(err).Warnf("Failed to refresh %s", URL)
+	desc, err = oonirun.V2CachedDescriptor(sess.KeyValueStore(), URL)
+	if err != nil || desc == nil {
+		log.Warnf("No cached descriptor for %s", URL)
+		return nil
+	}
+	log.Infof("Using the cached descriptor for %s", URL)
+	return desc
+}
+
+// runLink runs the nettests of the given link enabled for the current run type.
+func runLink(config RunLinksConfig, sess *engine.Session,
+	network *model.DatabaseNetwork, URL string, desc *oonirun.V2Descriptor) error {
+	var nettests []oonirun.V2Nettest
+	for _, nettest := range desc.Nettests {
+		if nettest.TestName != "" && nettest.EnabledForRunType(config.RunType) {
+			nettests = append(nettests, nettest)
+		}
+	}
+	name := desc.Name
+	if name == "" {
+		name = URL
+	}
+	if len(nettests) <= 0 {
+		log.Infof("%s has no nettests enabled for %s runs", name, config.RunType)
+		return nil
+	}
+	log.Infof("Running %s by %s", name, desc.Author)
+
+	db := config.Probe.DB()
+	result, err := db.CreateResult(config.Probe.Home(), name, network.ID)
+	if err != nil {
+		log.Errorf("DB result error: %s", err)
+		return err
+	}
+	for i, nettest := range nettests {
+		if config.Probe.IsTerminated() {
+			log.Debugf("context is terminated, stopping link nettests early")
+			break
+		}
+		nt := LinkNettest{Nettest: nettest}
+		ctl := NewController(nt, config.Probe, result, sess)
+		ctl.RunType = config.RunType
+		ctl.NoCredentials = config.NoCredentials
+		ctl.SetNettestIndex(i, len(nettests))
+		if err := nt.Run(ctl); err != nil {
+			log.WithError(err).Warnf("Failed to run %s", nettest.TestName)
+		}
+	}
+	return finishResult(db, result)
+}
+
+// LinkNettest is a nettest described by an OONI Run v2 link.
+type LinkNettest struct {
+	Nettest oonirun.V2Nettest
+}
+
+// Run starts the nettest.
+func (n LinkNettest) Run(ctl *Controller) error {
+	builder, err := ctl.Session.NewExperimentBuilder(n.Nettest.TestName)
+	if err != nil {
+		return err
+	}
+	if err := builder.SetOptionsJSON(n.Nettest.Options); err != nil {
+		return err
+	}
+	config := &model.ExperimentTargetLoaderConfig{
+		CheckInConfig: &model.OOAPICheckInConfig{
+			Charging: true,
+			OnWiFi:   true,
+			RunType:  ctl.RunType,
+		},
+		Session:            ctl.Session,
+		StaticInputs:       n.Nettest.Inputs,
+		StaticInputsConfig: n.Nettest.InputsExtra,
+	}
+	targets, err := builder.NewTargetLoader(config).Load(context.Background())
+	if err != nil {
+		return err
+	}
+	if builder.InputPolicy() != model.InputNone {
+		if targets, err = ctl.BuildAndSetInputIdxMap(targets); err != nil {
+			return err
+		}
+	}
+	return ctl.Run(builder, targets)
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/apex/log"
@@ -12,6 +16,8 @@ import (
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
 	engine "github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/oonirun"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 	"github.com/pkg/errors"
 )
 
@@ -53,6 +59,14 @@ type Controller struct {
 	// using the command line using the --input flag.
 	Inputs []string
 
+	// TargetFilter optionally contains rules to filter and sample
+	// the inputs (only for nettests that take inputs).
+	TargetFilter *model.ExperimentTargetFilter
+
+	// TestLists optionally contains the names of test lists to
+	// read inputs from along with their category codes.
+	TestLists []string
+
 	// RunType contains the run_type hint for the CheckIn API. If
 	// not set, the underlying code defaults to model.RunTypeTimed.
 	RunType model.RunType
@@ -60,11 +74,33 @@ type Controller struct {
 	// NoCredentials disables submitting with an anonymous credential.
 	NoCredentials bool
 
+	// Parallelism is the maximum number of inputs to measure concurrently. Zero
+	// or one means we measure inputs sequentially.
+	Parallelism int
+
 	// numInputs is the total number of inputs
 	numInputs int
 
 	// curInputIdx is the current input index
-	curInputIdx int
+	curInputIdx atomic.Int64
+
+	// submitter is the submitter we're using or nil
+	submitter model.Submitter
+
+	// submitQueue contains the measurements we could not submit or nil
+	submitQueue *submitqueue.Queue
+
+	// checkpoints is where we checkpoint Web Connectivity runs or nil
+	checkpoints *oonirun.RunCheckpointStore
+
+	// checkpointID identifies the run configuration
+	checkpointID string
+
+	// resumeFrom is the checkpoint of the interrupted run we're resuming or nil
+	resumeFrom *oonirun.RunCheckpoint
+
+	// followUps runs the Web Connectivity follow-ups or nil
+	followUps *oonirun.FollowUpRunner
 }
 
 // BuildAndSetInputIdxMap takes in input a list of URLs in the format
@@ -157,95 +193,365 @@ func (c *Controller) Run(builder model.ExperimentBuilder, inputs []model.Experim
 		maxRuntime = 0
 	}
 
-	var submitter model.Submitter
+	c.submitter = nil
+	c.submitQueue = nil
 	if c.Probe.Config().Sharing.UploadResults {
 		if s, err := c.Session.NewSubmitter(context.Background(), !c.NoCredentials); err != nil {
 			log.WithError(err).Debug("cannot create submitter; measurements will be saved to disk")
 		} else {
-			submitter = s
+			c.submitter = s
+			c.submitQueue = submitqueue.New(c.Session.KeyValueStore())
+		}
+	}
+	stopUploader := c.startSubmitQueueUploader()
+	defer stopUploader()
+
+	c.followUps = nil
+	stopFollowUps := func() {}
+	if isWebConnectivity && c.Probe.Config().Nettests.WebsitesFollowUps {
+		stopFollowUps = c.startFollowUps(maxRuntime)
+	}
+	defer stopFollowUps()
+
+	checkpoint := c.newCheckpoint(exp.Name(), inputs)
+	var start int
+	if checkpoint != nil {
+		start = checkpoint.NextIndex
+		if checkpoint.ReportID != "" {
+			// reuse the report of the run we're resuming
+			reportID = sql.NullString{String: checkpoint.ReportID, Valid: true}
+		}
+	}
+
+	var err error
+	if c.Parallelism <= 1 {
+		err = c.runSequentially(reportID, resultID, exp, inputs, start, maxRuntime, checkpoint)
+	} else {
+		err = c.runInParallel(reportID, resultID, exp, inputs, start, maxRuntime, checkpoint)
+	}
+	if err != nil {
+		return err // keep the checkpoint, if any, such that we can resume
+	}
+	if checkpoint != nil && checkpoint.Done() {
+		// the run is complete, so there is nothing to resume
+		if err := c.checkpoints.Clear(); err != nil {
+			log.WithError(err).Warn("cannot clear the run checkpoint")
 		}
 	}
+	stopFollowUps() // the follow-ups may queue measurements for the uploader
+	stopUploader()  // make sure the database knows what we submitted in the background
+	err = db.UpdateUploadedStatus(c.res)
+	log.Debugf("status.end")
+	return err
+}
 
-	start := time.Now()
-	c.ntStartTime = start
-	for idx, input := range inputs {
+// runSequentially measures the inputs starting from the start-th one after the other.
+func (c *Controller) runSequentially(reportID sql.NullString, resultID int64, exp model.Experiment,
+	inputs []model.ExperimentTarget, start int, maxRuntime time.Duration, checkpoint *oonirun.RunCheckpoint) error {
+	begin := time.Now()
+	c.ntStartTime = begin
+	for idx := start; idx < len(inputs); idx++ {
+		input := inputs[idx]
 		if c.Probe.IsTerminated() {
 			log.Info("user requested us to terminate using Ctrl-C")
 			break
 		}
-		if maxRuntime > 0 && time.Since(start) > maxRuntime {
+		if maxRuntime > 0 && time.Since(begin) > maxRuntime {
 			log.Info("exceeded maximum runtime")
 			break
 		}
-		c.curInputIdx = idx // allow for precise progress
-		idx64 := int64(idx)
+		c.curInputIdx.Store(int64(idx)) // allow for precise progress
 		log.Debug(color.RedString("status.measurement_start"))
-		var urlID sql.NullInt64
-		if c.inputIdxMap != nil {
-			urlID = sql.NullInt64{Int64: c.inputIdxMap[idx64], Valid: true}
-		}
-
-		msmt, err := db.CreateMeasurement(
-			reportID, exp.Name(), c.res.MeasurementDir, idx, resultID, urlID,
-		)
-		if err != nil {
-			return errors.Wrap(err, "failed to create measurement")
+		if _, err := c.createMeasurement(reportID, resultID, exp, idx); err != nil {
+			return err
 		}
-		c.msmts[idx64] = msmt
-
 		if input.Input() != "" {
 			c.OnProgress(0, fmt.Sprintf("processing input: %s", input))
 		}
 		measurement, err := exp.MeasureWithContext(context.Background(), input)
-		if err != nil {
-			log.WithError(err).Debug(color.RedString("failure.measurement"))
-			if err := db.Failed(c.msmts[idx64], err.Error()); err != nil {
-				return errors.Wrap(err, "failed to mark measurement as failed")
-			}
-			// Since https://github.com/ooni/probe-cli/pull/527, the Measure
-			// function returns EITHER a valid measurement OR an error. Before
-			// that, instead, the measurement was valid EVEN in case of an
-			// error, which is quite not the <value> OR <error> semantics that
-			// is so typical and widespread in the Go ecosystem. So, we must
-			// jump to the next iteration of the loop here rather than falling
-			// through and attempting to do something with the measurement.
-			continue
+		reuseReportID(reportID, measurement)
+		if err := c.saveMeasurement(idx, measurement, err); err != nil {
+			return err
 		}
+		c.saveCheckpoint(checkpoint, idx+1, measurement)
+	}
+	return nil
+}
 
-		saveToDisk := true
-		if submitter != nil {
-			if _, err := submitter.Submit(context.Background(), measurement); err != nil {
-				log.Debug(color.RedString("failure.measurement_submission"))
-				if err := db.UploadFailed(c.msmts[idx64], err.Error()); err != nil {
-					return errors.Wrap(err, "failed to mark upload as failed")
-				}
-			} else if err := db.UploadSucceeded(c.msmts[idx64]); err != nil {
-				return errors.Wrap(err, "failed to mark upload as succeeded")
-			} else {
-				// Everything went OK, don't save to disk
-				saveToDisk = false
+// runInParallel is like runSequentially but measures several inputs at the same time,
+// while still saving and submitting the measurements in the order of the inputs.
+func (c *Controller) runInParallel(reportID sql.NullString, resultID int64, exp model.Experiment,
+	inputs []model.ExperimentTarget, start int, maxRuntime time.Duration, checkpoint *oonirun.RunCheckpoint) error {
+	c.ntStartTime = time.Now()
+	scheduler := &oonirun.InputScheduler{
+		Inputs:     inputs[start:],
+		MaxRuntime: maxRuntime,
+		Measure: func(ctx context.Context, input model.ExperimentTarget, idx int) (*model.Measurement, error) {
+			log.Debug(color.RedString("status.measurement_start"))
+			if input.Input() != "" {
+				c.OnProgress(0, fmt.Sprintf("processing input: %s", input))
 			}
-		}
-		// We only save the measurement to disk if we failed to upload the measurement
-		if saveToDisk {
-			if err := engine.SaveMeasurement(measurement, msmt.MeasurementFilePath.String); err != nil {
-				return errors.Wrap(err, "failed to save measurement on disk")
+			return exp.MeasureWithContext(ctx, input)
+		},
+		Parallelism:        c.Parallelism,
+		PerHostParallelism: 0,
+		ShouldStop: func(idx int) bool {
+			if c.Probe.IsTerminated() {
+				log.Info("user requested us to terminate using Ctrl-C")
+				return true
 			}
+			c.curInputIdx.Store(int64(start + idx)) // allow for precise progress
+			return false
+		},
+	}
+	reason, err := scheduler.Run(context.Background(), func(idx int, measurement *model.Measurement, err error) error {
+		msmt, dberr := c.createMeasurement(reportID, resultID, exp, start+idx)
+		if dberr != nil {
+			return dberr
+		}
+		if measurement != nil {
+			// Note: we create the database entry after measuring, such that
+			// we can measure in parallel, so we use the measurement start time
+			// to correctly compute the measurement runtime.
+			msmt.StartTime = measurement.MeasurementStartTimeSaved.UTC()
+		}
+		reuseReportID(reportID, measurement)
+		if err := c.saveMeasurement(start+idx, measurement, err); err != nil {
+			return err
 		}
+		c.saveCheckpoint(checkpoint, start+idx+1, measurement)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if reason == oonirun.InputSchedulerStopMaxRuntime {
+		log.Info("exceeded maximum runtime")
+	}
+	return nil
+}
+
+// reuseReportID makes the measurement belong to the given report, if any, unless
+// the measurement already belongs to a report (e.g., when resuming a run).
+func reuseReportID(reportID sql.NullString, measurement *model.Measurement) {
+	if reportID.Valid && measurement != nil && measurement.ReportID == "" {
+		measurement.ReportID = reportID.String
+	}
+}
+
+// createMeasurement creates the database entry for the idx-th input.
+func (c *Controller) createMeasurement(reportID sql.NullString, resultID int64,
+	exp model.Experiment, idx int) (*model.DatabaseMeasurement, error) {
+	db := c.Probe.DB()
+	idx64 := int64(idx)
+	var urlID sql.NullInt64
+	if c.inputIdxMap != nil {
+		urlID = sql.NullInt64{Int64: c.inputIdxMap[idx64], Valid: true}
+	}
+
+	msmt, err := db.CreateMeasurement(
+		reportID, exp.Name(), c.res.MeasurementDir, idx, resultID, urlID,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create measurement")
+	}
+	c.msmts[idx64] = msmt
+	return msmt, nil
+}
 
-		if err := db.Done(c.msmts[idx64]); err != nil {
-			return errors.Wrap(err, "failed to mark measurement as done")
+// saveMeasurement records the result of measuring the idx-th input, whose database entry
+// we have already created, where err is the error that occurred while measuring, and
+// submits and saves the measurement.
+func (c *Controller) saveMeasurement(idx int, measurement *model.Measurement, err error) error {
+	db := c.Probe.DB()
+	idx64 := int64(idx)
+	msmt := c.msmts[idx64]
+
+	if err != nil {
+		log.WithError(err).Debug(color.RedString("failure.measurement"))
+		if err := db.Failed(c.msmts[idx64], err.Error()); err != nil {
+			return errors.Wrap(err, "failed to mark measurement as failed")
 		}
+		// Since https://github.com/ooni/probe-cli/pull/527, the Measure
+		// function returns EITHER a valid measurement OR an error. Before
+		// that, instead, the measurement was valid EVEN in case of an
+		// error, which is quite not the <value> OR <error> semantics that
+		// is so typical and widespread in the Go ecosystem. So, we must
+		// jump to the next input here rather than falling through and
+		// attempting to do something with the measurement.
+		return nil
+	}
 
-		sk := engine.MeasurementSummaryKeys(measurement)
-		log.Debugf("Fetching: %d %v", idx, c.msmts[idx64])
-		if err := db.AddTestKeys(c.msmts[idx64], sk); err != nil {
-			return errors.Wrap(err, "failed to add test keys to summary")
+	var measurementUID string
+	saveToDisk := true
+	if c.submitter != nil {
+		if measurementUID, err = c.submitter.Submit(context.Background(), measurement); err != nil {
+			log.Debug(color.RedString("failure.measurement_submission"))
+			if err := db.UploadFailed(c.msmts[idx64], err.Error()); err != nil {
+				return errors.Wrap(err, "failed to mark upload as failed")
+			}
+			c.enqueueMeasurement(msmt, measurement)
+		} else if err := db.UploadSucceeded(c.msmts[idx64]); err != nil {
+			return errors.Wrap(err, "failed to mark upload as succeeded")
+		} else {
+			// Everything went OK, don't save to disk
+			saveToDisk = false
 		}
 	}
-	err := db.UpdateUploadedStatus(c.res)
-	log.Debugf("status.end")
-	return err
+	// We only save the measurement to disk if we failed to upload the measurement
+	if saveToDisk {
+		if err := engine.SaveMeasurement(measurement, msmt.MeasurementFilePath.String); err != nil {
+			return errors.Wrap(err, "failed to save measurement on disk")
+		}
+	}
+
+	if err := db.Done(c.msmts[idx64]); err != nil {
+		return errors.Wrap(err, "failed to mark measurement as done")
+	}
+
+	if c.followUps != nil {
+		c.followUps.Schedule(measurement, measurementUID)
+	}
+
+	sk := engine.MeasurementSummaryKeys(measurement)
+	log.Debugf("Fetching: %d %v", idx, c.msmts[idx64])
+	if err := db.AddTestKeys(c.msmts[idx64], sk); err != nil {
+		return errors.Wrap(err, "failed to add test keys to summary")
+	}
+	return nil
+}
+
+// newCheckpoint returns the checkpoint of the current Web Connectivity run, which is
+// the one we're resuming when it matches the inputs, or nil if we cannot checkpoint.
+func (c *Controller) newCheckpoint(testName string, inputs []model.ExperimentTarget) *oonirun.RunCheckpoint {
+	if _, isWebConnectivity := c.nt.(WebConnectivity); !isWebConnectivity || c.checkpoints == nil {
+		return nil
+	}
+	if cp := c.resumeFrom; cp != nil && cp.TestName == testName && len(cp.Targets) == len(inputs) {
+		cp.ResultID = c.res.ID
+		return cp
+	}
+	cp, ok := oonirun.NewRunCheckpoint(c.checkpointID, testName, inputs)
+	if !ok {
+		return nil
+	}
+	cp.ResultID = c.res.ID
+	return cp
+}
+
+// saveCheckpoint records that the next input to measure is the one at nextIndex
+// along with the report ID of the measurement, if any, to reuse it on resume.
+func (c *Controller) saveCheckpoint(cp *oonirun.RunCheckpoint, nextIndex int, measurement *model.Measurement) {
+	if cp == nil {
+		return
+	}
+	cp.NextIndex = nextIndex
+	if measurement != nil && measurement.ReportID != "" {
+		cp.ReportID = measurement.ReportID
+	}
+	if err := c.checkpoints.Save(cp); err != nil {
+		// policy: failing to checkpoint does not stop the run
+		log.WithError(err).Warn("cannot save the run checkpoint")
+	}
+}
+
+// enqueueMeasurement adds a measurement we could not submit to the submission queue,
+// using the database ID as the tag, such that we can later mark it as uploaded.
+func (c *Controller) enqueueMeasurement(msmt *model.DatabaseMeasurement, measurement *model.Measurement) {
+	if c.submitQueue == nil {
+		return
+	}
+	if err := c.submitQueue.Enqueue(measurement, strconv.FormatInt(msmt.ID, 10)); err != nil {
+		log.WithError(err).Warn("cannot queue measurement for later submission")
+		return
+	}
+	log.Infof("queued measurement for later submission (%d queued)", c.submitQueue.Len())
+}
+
+// startFollowUps starts running the Web Connectivity follow-ups in the background
+// and returns a function that waits for them to complete, which skips the follow-ups
+// we did not start yet when the user asked us to terminate. We save the follow-up
+// measurements inside the result directory and we submit them, if possible, without
+// adding them to the database. It is safe to call the returned function more than once.
+func (c *Controller) startFollowUps(maxRuntime time.Duration) func() {
+	saver, err := oonirun.NewSaver(oonirun.SaverConfig{
+		Enabled:  true,
+		FilePath: filepath.Join(c.res.MeasurementDir, "followups.jsonl"),
+		Logger:   c.Session.Logger(),
+	})
+	if err != nil {
+		log.WithError(err).Warn("cannot run follow-ups")
+		return func() {}
+	}
+	template := &oonirun.Experiment{
+		MaxRuntime:    int64(maxRuntime / time.Second),
+		NoCollector:   c.submitter == nil,
+		NoCredentials: c.NoCredentials,
+		Session:       c.Session,
+		SubmitQueue:   c.submitQueue,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.followUps = oonirun.NewFollowUpRunner(ctx, template, saver)
+	once := &sync.Once{}
+	return func() {
+		once.Do(func() {
+			if c.Probe.IsTerminated() {
+				cancel()
+			}
+			c.followUps.Close()
+			c.followUps = nil
+			cancel()
+		})
+	}
+}
+
+// startSubmitQueueUploader starts submitting the queued measurements in the background
+// and returns a function that stops the uploader and marks the measurements it
+// submitted as uploaded. We update the database from the calling goroutine to avoid
+// concurrent writes. It is safe to call the returned function more than once.
+func (c *Controller) startSubmitQueueUploader() func() {
+	if c.submitQueue == nil {
+		return func() {}
+	}
+	if depth := c.submitQueue.Len(); depth > 0 {
+		log.Infof("%d measurements queued for submission", depth)
+	}
+	var (
+		mu        sync.Mutex
+		submitted []int64
+	)
+	uploader := &submitqueue.Uploader{
+		Logger: log.Log,
+		OnSubmitted: func(tag string, measurementUID string) {
+			msmtID, err := strconv.ParseInt(tag, 10, 64)
+			if err != nil {
+				return // not a measurement we created
+			}
+			mu.Lock()
+			submitted = append(submitted, msmtID)
+			mu.Unlock()
+		},
+		Queue:     c.submitQueue,
+		Submitter: c.submitter,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		uploader.Run(ctx)
+	}()
+	once := &sync.Once{}
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+			for _, msmtID := range submitted {
+				if err := c.Probe.DB().UploadSucceededByID(msmtID); err != nil {
+					log.WithError(err).Warn("cannot mark queued measurement as uploaded")
+				}
+			}
+		})
+	}
 }
 
 // OnProgress should be called when a new progress event is available.
@@ -269,11 +575,12 @@ func (c *Controller) OnProgress(perc float64, msg string) {
 	eta = -1.0
 	if c.numInputs > 1 {
 		// make the percentage relative to the current input over all inputs
-		floor := (float64(c.curInputIdx) / float64(c.numInputs))
+		curInputIdx := int(c.curInputIdx.Load())
+		floor := (float64(curInputIdx) / float64(c.numInputs))
 		step := 1.0 / float64(c.numInputs)
 		perc = floor + perc*step
-		if c.curInputIdx > 0 {
-			eta = (time.Since(c.ntStartTime).Seconds() / float64(c.curInputIdx)) * float64(c.numInputs-c.curInputIdx)
+		if curInputIdx > 0 {
+			eta = (time.Since(c.ntStartTime).Seconds() / float64(curInputIdx)) * float64(c.numInputs-curInputIdx)
 		}
 	}
 	if c.ntCount > 0 {
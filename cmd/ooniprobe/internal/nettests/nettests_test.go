@@ -65,3 +65,26 @@ func TestRun(t *testing.T) {
 	ctl := NewController(nt, probe, res, sess)
 	nt.Run(ctl)
 }
+
+func TestBuildAndSetInputIdxMapStoresCategoryCodes(t *testing.T) {
+	probe := newOONIProbe(t)
+	ctl := &Controller{Probe: probe}
+	targets := []model.ExperimentTarget{&model.OOAPIURLInfo{
+		CategoryCode: "NEWS",
+		CountryCode:  "IT",
+		URL:          "https://www.example.it/",
+	}}
+	if _, err := ctl.BuildAndSetInputIdxMap(targets); err != nil {
+		t.Fatal(err)
+	}
+	var urls []model.DatabaseURL
+	if err := probe.DB().Session().Collection("urls").Find().All(&urls); err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 1 || urls[0].CategoryCode.String != "NEWS" || urls[0].CountryCode.String != "IT" {
+		t.Fatal("unexpected URLs", urls)
+	}
+	if ctl.inputIdxMap[0] != urls[0].ID.Int64 {
+		t.Fatal("unexpected input index map", ctl.inputIdxMap)
+	}
+}
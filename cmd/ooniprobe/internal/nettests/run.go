@@ -8,7 +8,10 @@ import (
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/internal/database"
+	"github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/oonirun"
 	"github.com/pkg/errors"
 )
 
@@ -20,6 +23,9 @@ type RunGroupConfig struct {
 	Probe         *ooni.Probe
 	RunType       model.RunType // hint for check-in API
 	NoCredentials bool
+	Resume        bool // resume the interrupted run, if any
+	TargetFilter  *model.ExperimentTargetFilter
+	TestLists     []string
 }
 
 const websitesURLLimitRemoved = `WARNING: CONFIGURATION CHANGE REQUIRED:
@@ -60,28 +66,12 @@ func RunGroup(config RunGroupConfig) error {
 		return nil
 	}
 
-	sess, err := config.Probe.NewSession(context.Background(), config.RunType)
+	sess, network, err := newSession(config.Probe, config.RunType)
 	if err != nil {
-		log.WithError(err).Error("Failed to create a measurement session")
 		return err
 	}
 	defer sess.Close()
-
-	err = sess.MaybeLookupLocationContext(context.Background())
-	if err != nil {
-		log.WithError(err).Error("Failed to lookup the location of the probe")
-		return err
-	}
 	db := config.Probe.DB()
-	network, err := db.CreateNetwork(sess)
-	if err != nil {
-		log.WithError(err).Error("Failed to create the network row")
-		return err
-	}
-	if err := sess.MaybeLookupBackendsContext(context.Background()); err != nil {
-		log.WithError(err).Errorf("Failed to discover OONI backends")
-		return err
-	}
 
 	group, ok := All[config.GroupName]
 	if !ok {
@@ -90,11 +80,26 @@ func RunGroup(config RunGroupConfig) error {
 	}
 	log.Debugf("Running test group %s", group.Label)
 
-	result, err := db.CreateResult(
-		config.Probe.Home(), config.GroupName, network.ID)
-	if err != nil {
-		log.Errorf("DB result error: %s", err)
-		return err
+	checkpoints := &oonirun.RunCheckpointStore{
+		KVStore: sess.KeyValueStore(),
+		Key:     oonirun.RunCheckpointKey("ooniprobe." + config.GroupName),
+	}
+	checkpointID := oonirun.NewRunCheckpointID(config.GroupName, config.Inputs,
+		config.InputFiles, config.TestLists, config.TargetFilter)
+	var (
+		resumeFrom *oonirun.RunCheckpoint
+		result     *model.DatabaseResult
+	)
+	if config.Resume {
+		resumeFrom, result = resumeRun(db, checkpoints, checkpointID)
+	}
+	if result == nil {
+		result, err = db.CreateResult(
+			config.Probe.Home(), config.GroupName, network.ID)
+		if err != nil {
+			log.Errorf("DB result error: %s", err)
+			return err
+		}
 	}
 
 	config.Probe.ListenForSignals()
@@ -114,8 +119,13 @@ func RunGroup(config RunGroupConfig) error {
 		ctl := NewController(nt, config.Probe, result, sess)
 		ctl.InputFiles = config.InputFiles
 		ctl.Inputs = config.Inputs
+		ctl.TargetFilter = config.TargetFilter
+		ctl.TestLists = config.TestLists
 		ctl.RunType = config.RunType
 		ctl.NoCredentials = config.NoCredentials
+		ctl.checkpoints = checkpoints
+		ctl.checkpointID = checkpointID
+		ctl.resumeFrom = resumeFrom
 		ctl.SetNettestIndex(i, len(group.Nettests))
 		if err = nt.Run(ctl); err != nil {
 			// We used to emit an error here, now we emit a warning--the proper choice
@@ -124,6 +134,40 @@ func RunGroup(config RunGroupConfig) error {
 		}
 	}
 
+	return finishResult(db, result)
+}
+
+// newSession creates a measurement session, looks up the probe location
+// and the OONI backends, and creates the corresponding network row.
+func newSession(probe *ooni.Probe, runType model.RunType) (*engine.Session, *model.DatabaseNetwork, error) {
+	sess, err := probe.NewSession(context.Background(), runType)
+	if err != nil {
+		log.WithError(err).Error("Failed to create a measurement session")
+		return nil, nil, err
+	}
+
+	err = sess.MaybeLookupLocationContext(context.Background())
+	if err != nil {
+		log.WithError(err).Error("Failed to lookup the location of the probe")
+		sess.Close()
+		return nil, nil, err
+	}
+	network, err := probe.DB().CreateNetwork(sess)
+	if err != nil {
+		log.WithError(err).Error("Failed to create the network row")
+		sess.Close()
+		return nil, nil, err
+	}
+	if err := sess.MaybeLookupBackendsContext(context.Background()); err != nil {
+		log.WithError(err).Errorf("Failed to discover OONI backends")
+		sess.Close()
+		return nil, nil, err
+	}
+	return sess, network, nil
+}
+
+// finishResult marks the given result as finished.
+func finishResult(db *database.Database, result *model.DatabaseResult) error {
 	// Remove the directory if it's emtpy, which happens when the corresponding
 	// measurements have been submitted (see https://github.com/ooni/probe/issues/2090)
 	dir, err := os.Open(result.MeasurementDir)
@@ -141,6 +185,25 @@ func RunGroup(config RunGroupConfig) error {
 	return nil
 }
 
+// resumeRun returns the checkpoint of the interrupted run with the given ID and
+// the corresponding database result, or nil values when we cannot resume.
+func resumeRun(db *database.Database, checkpoints *oonirun.RunCheckpointStore,
+	checkpointID string) (*oonirun.RunCheckpoint, *model.DatabaseResult) {
+	checkpoint, err := checkpoints.LoadForID(checkpointID)
+	if err != nil {
+		log.Info("no interrupted run to resume")
+		return nil, nil
+	}
+	result, err := db.ResumeResult(checkpoint.ResultID)
+	if err != nil {
+		log.WithError(err).Warn("cannot resume the result of the interrupted run")
+		return nil, nil
+	}
+	log.Infof("resuming %s from URL %d/%d", checkpoint.TestName,
+		checkpoint.NextIndex+1, len(checkpoint.Targets))
+	return checkpoint, result
+}
+
 // onlyBackground is the interface implements by nettests that we don't
 // want to run in manual mode because they take too much runtime
 //
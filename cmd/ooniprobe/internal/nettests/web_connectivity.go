@@ -24,6 +24,8 @@ func (n WebConnectivity) lookupURLs(
 		Session:      ctl.Session,
 		SourceFiles:  ctl.InputFiles,
 		StaticInputs: ctl.Inputs,
+		TargetFilter: ctl.TargetFilter,
+		TestLists:    ctl.TestLists,
 	}
 	targetloader := builder.NewTargetLoader(config)
 	testlist, err := targetloader.Load(context.Background())
@@ -33,6 +35,15 @@ func (n WebConnectivity) lookupURLs(
 	return ctl.BuildAndSetInputIdxMap(testlist)
 }
 
+// resumeOrLookupURLs returns the URLs of the interrupted run we're resuming, if
+// any, and otherwise looks up the URLs to measure.
+func (n WebConnectivity) resumeOrLookupURLs(ctl *Controller, builder model.ExperimentBuilder) ([]model.ExperimentTarget, error) {
+	if cp := ctl.resumeFrom; cp != nil && cp.TestName == "web_connectivity" {
+		return ctl.BuildAndSetInputIdxMap(cp.AllTargets())
+	}
+	return n.lookupURLs(ctl, builder, ctl.Probe.Config().Nettests.WebsitesEnabledCategoryCodes)
+}
+
 // WebConnectivity test implementation
 type WebConnectivity struct{}
 
@@ -43,9 +54,10 @@ func (n WebConnectivity) Run(ctl *Controller) error {
 		return err
 	}
 	log.Debugf("Enabled category codes are the following %v", ctl.Probe.Config().Nettests.WebsitesEnabledCategoryCodes)
-	urls, err := n.lookupURLs(ctl, builder, ctl.Probe.Config().Nettests.WebsitesEnabledCategoryCodes)
+	urls, err := n.resumeOrLookupURLs(ctl, builder)
 	if err != nil {
 		return err
 	}
+	ctl.Parallelism = int(ctl.Probe.Config().Nettests.WebsitesParallelism)
 	return ctl.Run(builder, urls)
 }
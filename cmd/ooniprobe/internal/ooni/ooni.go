@@ -64,9 +64,10 @@ type Probe struct {
 
 	isTerminated *atomic.Int64
 
-	softwareName    string
-	softwareVersion string
-	proxyURL        *url.URL
+	softwareName     string
+	softwareVersion  string
+	proxyURL         *url.URL
+	probeServicesURL string
 }
 
 // SetIsBatch sets the value of isBatch.
@@ -74,6 +75,11 @@ func (p *Probe) SetIsBatch(v bool) {
 	p.isBatch = v
 }
 
+// SetProbeServicesURL sets the OPTIONAL URL of the OONI backend to use.
+func (p *Probe) SetProbeServicesURL(v string) {
+	p.probeServicesURL = v
+}
+
 // IsBatch returns whether we're running in batch mode.
 func (p *Probe) IsBatch() bool {
 	return p.isBatch
@@ -232,14 +238,22 @@ func (p *Probe) NewSession(ctx context.Context, runType model.RunType) (*engine.
 	if runType == model.RunTypeTimed && softwareName == DefaultSoftwareName {
 		softwareName = DefaultSoftwareName + "-unattended"
 	}
+	var availableProbeServices []model.OOAPIService
+	if p.probeServicesURL != "" {
+		availableProbeServices = []model.OOAPIService{{
+			Address: p.probeServicesURL,
+			Type:    "https",
+		}}
+	}
 	return engine.NewSession(ctx, engine.SessionConfig{
-		KVStore:         kvstore,
-		Logger:          logger,
-		SoftwareName:    softwareName,
-		SoftwareVersion: p.softwareVersion,
-		TempDir:         p.tempDir,
-		TunnelDir:       p.tunnelDir,
-		ProxyURL:        p.proxyURL,
+		AvailableProbeServices: availableProbeServices,
+		KVStore:                kvstore,
+		Logger:                 logger,
+		SoftwareName:           softwareName,
+		SoftwareVersion:        p.softwareVersion,
+		TempDir:                p.tempDir,
+		TunnelDir:              p.tunnelDir,
+		ProxyURL:               p.proxyURL,
 	})
 }
 
@@ -0,0 +1,159 @@
+// Package blockpages contains a versioned database of blockpage fingerprints.
+//
+// A fingerprint positively identifies a known blockpage by matching either a DNS
+// answer, an HTTP response header, or an HTTP response body. We embed a copy of the
+// database and we use a more recent copy when the check-in API provides it.
+package blockpages
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/checkincache"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// These are the supported fingerprint types.
+const (
+	// FingerprintTypeDNS matches an IP address returned by a DNS lookup.
+	FingerprintTypeDNS = "dns"
+
+	// FingerprintTypeHTTPHeader matches the value of an HTTP response header.
+	FingerprintTypeHTTPHeader = "http_header"
+
+	// FingerprintTypeHTTPBody matches the HTTP response body.
+	FingerprintTypeHTTPBody = "http_body"
+)
+
+// Fingerprint is a blockpage fingerprint.
+type Fingerprint struct {
+	// ID is the unique fingerprint ID (e.g., "dns_ir_10_10_34_x").
+	ID string `json:"id"`
+
+	// Type is the fingerprint type (e.g., [FingerprintTypeDNS]).
+	Type string `json:"type"`
+
+	// Header is the header name, which is only meaningful
+	// when using the [FingerprintTypeHTTPHeader] type.
+	Header string `json:"header,omitempty"`
+
+	// Pattern is the regular expression to match.
+	Pattern string `json:"pattern"`
+
+	// re is the compiled pattern.
+	re *regexp.Regexp
+}
+
+// Database is a versioned blockpage fingerprints database.
+type Database struct {
+	// Version is the database version. We only replace the embedded
+	// database with databases having a greater version number.
+	Version int64 `json:"version"`
+
+	// Fingerprints contains the fingerprints.
+	Fingerprints []*Fingerprint `json:"fingerprints"`
+}
+
+// ErrInvalidDatabase indicates that a fingerprints database is invalid.
+var ErrInvalidDatabase = errors.New("blockpages: invalid fingerprints database")
+
+// Parse parses and validates a JSON-serialized fingerprints database.
+func Parse(data []byte) (*Database, error) {
+	var db Database
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDatabase, err.Error())
+	}
+	if db.Version <= 0 {
+		return nil, fmt.Errorf("%w: invalid version %d", ErrInvalidDatabase, db.Version)
+	}
+	ids := map[string]bool{}
+	for _, fp := range db.Fingerprints {
+		if fp == nil || fp.ID == "" || ids[fp.ID] {
+			return nil, fmt.Errorf("%w: missing or duplicate fingerprint ID", ErrInvalidDatabase)
+		}
+		ids[fp.ID] = true
+		switch fp.Type {
+		case FingerprintTypeDNS, FingerprintTypeHTTPBody:
+		case FingerprintTypeHTTPHeader:
+			if fp.Header == "" {
+				return nil, fmt.Errorf("%w: %s: missing header name", ErrInvalidDatabase, fp.ID)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s: unknown type %q", ErrInvalidDatabase, fp.ID, fp.Type)
+		}
+		re, err := regexp.Compile(fp.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidDatabase, fp.ID, err.Error())
+		}
+		fp.re = re
+	}
+	return &db, nil
+}
+
+//go:embed fingerprints.json
+var embeddedDatabase []byte
+
+var (
+	embeddedOnce   sync.Once
+	embeddedParsed *Database
+)
+
+// Embedded returns the embedded fingerprints database.
+func Embedded() *Database {
+	embeddedOnce.Do(func() {
+		embeddedParsed = runtimex.Try1(Parse(embeddedDatabase))
+	})
+	return embeddedParsed
+}
+
+// Load returns the fingerprints database cached by the most recent check-in, if it is
+// valid and more recent than the embedded one, and otherwise the embedded database.
+func Load(kvStore model.KeyValueStore) *Database {
+	db := Embedded()
+	data, err := checkincache.GetBlockpageFingerprints(kvStore)
+	if err != nil {
+		return db
+	}
+	cached, err := Parse(data)
+	if err != nil || cached.Version <= db.Version {
+		return db
+	}
+	return cached
+}
+
+// MatchDNSAnswer returns the IDs of the DNS fingerprints matching the given IP address.
+func (db *Database) MatchDNSAnswer(addr string) (out []string) {
+	for _, fp := range db.Fingerprints {
+		if fp.Type == FingerprintTypeDNS && fp.re.MatchString(addr) {
+			out = append(out, fp.ID)
+		}
+	}
+	return
+}
+
+// MatchHTTPResponse returns the IDs of the HTTP fingerprints matching the given response.
+func (db *Database) MatchHTTPResponse(resp *model.ArchivalHTTPResponse) (out []string) {
+	body := string(resp.Body)
+	for _, fp := range db.Fingerprints {
+		switch fp.Type {
+		case FingerprintTypeHTTPBody:
+			if fp.re.MatchString(body) {
+				out = append(out, fp.ID)
+			}
+		case FingerprintTypeHTTPHeader:
+			for _, header := range resp.HeadersList {
+				if strings.EqualFold(string(header[0]), fp.Header) && fp.re.MatchString(string(header[1])) {
+					out = append(out, fp.ID)
+					break
+				}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,178 @@
+package blockpages
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/checkincache"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestParse(t *testing.T) {
+	type testcase struct {
+		name  string
+		input string
+	}
+
+	cases := []testcase{{
+		name:  "with invalid JSON",
+		input: `{`,
+	}, {
+		name:  "with invalid version",
+		input: `{"version":0,"fingerprints":[]}`,
+	}, {
+		name:  "with missing ID",
+		input: `{"version":1,"fingerprints":[{"type":"dns","pattern":"^10\\."}]}`,
+	}, {
+		name: "with duplicate ID",
+		input: `{"version":1,"fingerprints":[{"id":"a","type":"dns","pattern":"x"},` +
+			`{"id":"a","type":"http_body","pattern":"y"}]}`,
+	}, {
+		name:  "with unknown type",
+		input: `{"version":1,"fingerprints":[{"id":"a","type":"tls","pattern":"x"}]}`,
+	}, {
+		name:  "with missing header name",
+		input: `{"version":1,"fingerprints":[{"id":"a","type":"http_header","pattern":"x"}]}`,
+	}, {
+		name:  "with invalid pattern",
+		input: `{"version":1,"fingerprints":[{"id":"a","type":"http_body","pattern":"("}]}`,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := Parse([]byte(tc.input))
+			if !errors.Is(err, ErrInvalidDatabase) {
+				t.Fatal("unexpected error", err)
+			}
+			if db != nil {
+				t.Fatal("expected nil database")
+			}
+		})
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	db := Embedded()
+	if db.Version <= 0 || len(db.Fingerprints) <= 0 {
+		t.Fatal("unexpected embedded database")
+	}
+	if Embedded() != db {
+		t.Fatal("expected to see the same database")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	store := func(data string) model.KeyValueStore {
+		kvStore := &kvstore.Memory{}
+		resp := &model.OOAPICheckInResult{
+			Conf: model.OOAPICheckInResultConfig{
+				BlockpageFingerprints: []byte(data),
+			},
+		}
+		if err := checkincache.Store(kvStore, resp); err != nil {
+			t.Fatal(err)
+		}
+		return kvStore
+	}
+
+	t.Run("without a cached database", func(t *testing.T) {
+		if Load(&kvstore.Memory{}) != Embedded() {
+			t.Fatal("expected the embedded database")
+		}
+	})
+
+	t.Run("with an invalid cached database", func(t *testing.T) {
+		if Load(store(`{"version":1000}`+"{")) != Embedded() {
+			t.Fatal("expected the embedded database")
+		}
+	})
+
+	t.Run("with an older cached database", func(t *testing.T) {
+		if Load(store(`{"version":1,"fingerprints":[]}`)) != Embedded() {
+			t.Fatal("expected the embedded database")
+		}
+	})
+
+	t.Run("with a newer cached database", func(t *testing.T) {
+		db := Load(store(`{"version":1000,"fingerprints":[{"id":"x","type":"dns","pattern":"^1\\.1\\.1\\.1$"}]}`))
+		if db.Version != 1000 {
+			t.Fatal("expected the cached database")
+		}
+		if diff := cmp.Diff([]string{"x"}, db.MatchDNSAnswer("1.1.1.1")); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestDatabaseMatchDNSAnswer(t *testing.T) {
+	db := Embedded()
+
+	if diff := cmp.Diff([]string{"dns_ir_10_10_34_x"}, db.MatchDNSAnswer("10.10.34.35")); diff != "" {
+		t.Fatal(diff)
+	}
+	if got := db.MatchDNSAnswer("10.10.34.37"); len(got) != 0 {
+		t.Fatal("unexpected match", got)
+	}
+	if got := db.MatchDNSAnswer("93.184.216.34"); len(got) != 0 {
+		t.Fatal("unexpected match", got)
+	}
+}
+
+func TestDatabaseMatchHTTPResponse(t *testing.T) {
+	db := Embedded()
+
+	newHeader := func(key, value string) model.ArchivalHTTPHeader {
+		return model.ArchivalHTTPHeader{
+			model.ArchivalScrubbedMaybeBinaryString(key),
+			model.ArchivalScrubbedMaybeBinaryString(value),
+		}
+	}
+
+	type testcase struct {
+		name   string
+		resp   *model.ArchivalHTTPResponse
+		expect []string
+	}
+
+	cases := []testcase{{
+		name: "with a matching body",
+		resp: &model.ArchivalHTTPResponse{
+			Body: `<html><iframe src="http://10.10.34.34?type=Invalid Site" style="width: 100%"></iframe></html>`,
+			Code: 403,
+		},
+		expect: []string{"http_body_ir_iframe_10_10_34_x"},
+	}, {
+		name: "with a matching header using a different case",
+		resp: &model.ArchivalHTTPResponse{
+			Code:        302,
+			HeadersList: []model.ArchivalHTTPHeader{newHeader("location", "http://warning.rt.ru/?id=17")},
+		},
+		expect: []string{"http_header_location_ru_warning_rt_ru"},
+	}, {
+		name: "with a matching header and a matching body",
+		resp: &model.ArchivalHTTPResponse{
+			Body:        `<p>ERR_ACCESS_DENIED</p><a href="/webadmin/deny/index.php">`,
+			Code:        403,
+			HeadersList: []model.ArchivalHTTPHeader{newHeader("X-Squid-Error", "ERR_ACCESS_DENIED 0")},
+		},
+		expect: []string{"http_body_netsweeper", "http_header_squid_access_denied"},
+	}, {
+		name: "with a legitimate webpage",
+		resp: &model.ArchivalHTTPResponse{
+			Body:        `<html><body>Example Domain</body></html>`,
+			Code:        200,
+			HeadersList: []model.ArchivalHTTPHeader{newHeader("Content-Type", "text/html")},
+		},
+		expect: nil,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expect, db.MatchHTTPResponse(tc.resp)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
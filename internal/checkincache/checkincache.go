@@ -3,6 +3,7 @@ package checkincache
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -37,7 +38,62 @@ func Store(kvStore model.KeyValueStore, resp *model.OOAPICheckInResult) error {
 	}
 	data, err := json.Marshal(wrapper)
 	runtimex.PanicOnError(err, "json.Marshal unexpectedly failed")
-	return kvStore.Set(CheckInFlagsState, data)
+	if err := kvStore.Set(CheckInFlagsState, data); err != nil {
+		return err
+	}
+
+	// store the blockpage fingerprints, if any, in the key-value store
+	if len(resp.Conf.BlockpageFingerprints) > 0 {
+		if err := kvStore.Set(BlockpageFingerprintsState, resp.Conf.BlockpageFingerprints); err != nil {
+			return err
+		}
+	}
+
+	// store the service definitions, if any, in the key-value store
+	if len(resp.Conf.ServiceDefinitions) > 0 {
+		return kvStore.Set(ServiceDefinitionsState, resp.Conf.ServiceDefinitions)
+	}
+	return nil
+}
+
+// BlockpageFingerprintsState is the state containing the blockpage fingerprints.
+const BlockpageFingerprintsState = "blockpagefingerprints.state"
+
+// ErrNoBlockpageFingerprints indicates we have no cached blockpage fingerprints.
+var ErrNoBlockpageFingerprints = errors.New("checkincache: no blockpage fingerprints")
+
+// GetBlockpageFingerprints returns the raw blockpage fingerprints database provided
+// by the most recent check-in that included it. Unlike feature flags, the database
+// does not expire, since it is versioned and the caller decides whether to use it.
+func GetBlockpageFingerprints(kvStore model.KeyValueStore) ([]byte, error) {
+	data, err := kvStore.Get(BlockpageFingerprintsState)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= 0 {
+		return nil, ErrNoBlockpageFingerprints
+	}
+	return data, nil
+}
+
+// ServiceDefinitionsState is the state containing the service definitions.
+const ServiceDefinitionsState = "servicedefinitions.state"
+
+// ErrNoServiceDefinitions indicates we have no cached service definitions.
+var ErrNoServiceDefinitions = errors.New("checkincache: no service definitions")
+
+// GetServiceDefinitions returns the raw service definitions provided by the most
+// recent check-in that included them. Like blockpage fingerprints, the definitions
+// do not expire, since they are versioned and the caller decides whether to use them.
+func GetServiceDefinitions(kvStore model.KeyValueStore) ([]byte, error) {
+	data, err := kvStore.Get(ServiceDefinitionsState)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= 0 {
+		return nil, ErrNoServiceDefinitions
+	}
+	return data, nil
 }
 
 // GetFeatureFlag returns the value of a check-in feature flag. In case of any
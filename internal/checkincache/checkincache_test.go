@@ -43,6 +43,46 @@ func TestStore(t *testing.T) {
 		}
 	})
 
+	t.Run("when the response contains blockpage fingerprints", func(t *testing.T) {
+		memstore := &kvstore.Memory{}
+		expect := []byte(`{"version":2,"fingerprints":[]}`)
+		result := &model.OOAPICheckInResult{
+			Conf: model.OOAPICheckInResultConfig{
+				BlockpageFingerprints: expect,
+			},
+		}
+		if err := Store(memstore, result); err != nil {
+			t.Fatal(err)
+		}
+		data, err := GetBlockpageFingerprints(memstore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expect, data); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the response contains service definitions", func(t *testing.T) {
+		memstore := &kvstore.Memory{}
+		expect := []byte(`[{"name":"signal","version":2,"components":[]}]`)
+		result := &model.OOAPICheckInResult{
+			Conf: model.OOAPICheckInResultConfig{
+				ServiceDefinitions: expect,
+			},
+		}
+		if err := Store(memstore, result); err != nil {
+			t.Fatal(err)
+		}
+		data, err := GetServiceDefinitions(memstore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expect, data); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
 	t.Run("when there's a failure trying to store", func(t *testing.T) {
 		expected := errors.New("mocked error")
 		memstore := &mocks.KeyValueStore{
@@ -133,3 +173,69 @@ func TestGetFeatureFlag(t *testing.T) {
 		}
 	})
 }
+
+func TestGetBlockpageFingerprints(t *testing.T) {
+	t.Run("when we cannot get from the store", func(t *testing.T) {
+		expectedErr := errors.New("mocked error")
+		memstore := &mocks.KeyValueStore{
+			MockGet: func(key string) (value []byte, err error) {
+				return nil, expectedErr
+			},
+		}
+		data, err := GetBlockpageFingerprints(memstore)
+		if !errors.Is(err, expectedErr) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(data) != 0 {
+			t.Fatal("expected empty data")
+		}
+	})
+
+	t.Run("when the stored value is empty", func(t *testing.T) {
+		memstore := &mocks.KeyValueStore{
+			MockGet: func(key string) (value []byte, err error) {
+				return []byte{}, nil
+			},
+		}
+		data, err := GetBlockpageFingerprints(memstore)
+		if !errors.Is(err, ErrNoBlockpageFingerprints) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(data) != 0 {
+			t.Fatal("expected empty data")
+		}
+	})
+}
+
+func TestGetServiceDefinitions(t *testing.T) {
+	t.Run("when we cannot get from the store", func(t *testing.T) {
+		expectedErr := errors.New("mocked error")
+		memstore := &mocks.KeyValueStore{
+			MockGet: func(key string) (value []byte, err error) {
+				return nil, expectedErr
+			},
+		}
+		data, err := GetServiceDefinitions(memstore)
+		if !errors.Is(err, expectedErr) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(data) != 0 {
+			t.Fatal("expected empty data")
+		}
+	})
+
+	t.Run("when the stored value is empty", func(t *testing.T) {
+		memstore := &mocks.KeyValueStore{
+			MockGet: func(key string) (value []byte, err error) {
+				return []byte{}, nil
+			},
+		}
+		data, err := GetServiceDefinitions(memstore)
+		if !errors.Is(err, ErrNoServiceDefinitions) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(data) != 0 {
+			t.Fatal("expected empty data")
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// Package clockskew contains an on-disk cache for the clock skew measured by
+// the time_integrity experiment, which allows other experiments (e.g., webconnectivitylte)
+// to annotate their measurements with the most recent clock skew.
+package clockskew
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// These are the annotations we add to measurements when we know the clock skew.
+const (
+	// AnnotationClockSkew is the clock skew in seconds.
+	AnnotationClockSkew = "clock_skew"
+
+	// AnnotationClockSkewSource is the source of the reference time.
+	AnnotationClockSkewSource = "clock_skew_source"
+)
+
+// ClockSkewState is the state containing the most recent clock skew.
+const ClockSkewState = "clockskew.state"
+
+// ClockSkewTTL is the time after which we consider the clock skew stale. We do not keep
+// it for longer because users may fix their clock at any time and a stale clock skew
+// would lead analysts to discard valid certificate validation failures.
+const ClockSkewTTL = 6 * time.Hour
+
+// ClockSkew is the clock skew measured by the time_integrity experiment.
+type ClockSkew struct {
+	// Expire contains the expiration date.
+	Expire time.Time
+
+	// Skew is the difference in seconds between the probe clock and the reference time.
+	Skew float64
+
+	// Source is the source of the reference time (e.g., "nts").
+	Source string
+}
+
+// ErrNoClockSkew indicates we have no cached clock skew.
+var ErrNoClockSkew = errors.New("clockskew: no clock skew")
+
+// ErrStaleClockSkew indicates that the cached clock skew is stale.
+var ErrStaleClockSkew = errors.New("clockskew: stale clock skew")
+
+// Store stores the given clock skew in the given key-value store.
+func Store(kvStore model.KeyValueStore, skew float64, source string) error {
+	cs := &ClockSkew{
+		Expire: time.Now().Add(ClockSkewTTL),
+		Skew:   skew,
+		Source: source,
+	}
+	data, err := json.Marshal(cs)
+	runtimex.PanicOnError(err, "json.Marshal unexpectedly failed")
+	return kvStore.Set(ClockSkewState, data)
+}
+
+// Get returns the most recent clock skew unless it is stale.
+func Get(kvStore model.KeyValueStore) (*ClockSkew, error) {
+	data, err := kvStore.Get(ClockSkewState)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= 0 {
+		return nil, ErrNoClockSkew
+	}
+	var cs ClockSkew
+	if err := json.Unmarshal(data, &cs); err != nil {
+		return nil, err
+	}
+	if time.Now().After(cs.Expire) {
+		return nil, ErrStaleClockSkew
+	}
+	return &cs, nil
+}
+
+// Annotate adds the clock skew annotations to the given measurement.
+func Annotate(m *model.Measurement, skew float64, source string) {
+	m.AddAnnotation(AnnotationClockSkew, strconv.FormatFloat(skew, 'f', 3, 64))
+	m.AddAnnotation(AnnotationClockSkewSource, source)
+}
@@ -0,0 +1,96 @@
+package clockskew
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestStoreAndGet(t *testing.T) {
+	t.Run("when we can successfully store and get", func(t *testing.T) {
+		memstore := &kvstore.Memory{}
+		if err := Store(memstore, -3.5, "nts"); err != nil {
+			t.Fatal(err)
+		}
+		cs, err := Get(memstore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cs.Skew != -3.5 || cs.Source != "nts" {
+			t.Fatal("unexpected clock skew", cs)
+		}
+		if cs.Expire.Before(time.Now().Add(ClockSkewTTL - time.Hour)) {
+			t.Fatal("unexpected expire value")
+		}
+	})
+
+	t.Run("when we cannot store", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		memstore := &mocks.KeyValueStore{
+			MockSet: func(key string, value []byte) error {
+				return expected
+			},
+		}
+		if err := Store(memstore, 1, "ntp"); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when there is no clock skew", func(t *testing.T) {
+		if _, err := Get(&kvstore.Memory{}); !errors.Is(err, kvstore.ErrNoSuchKey) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when the clock skew is empty", func(t *testing.T) {
+		memstore := &kvstore.Memory{}
+		if err := memstore.Set(ClockSkewState, []byte{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Get(memstore); !errors.Is(err, ErrNoClockSkew) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when the clock skew is invalid", func(t *testing.T) {
+		memstore := &kvstore.Memory{}
+		if err := memstore.Set(ClockSkewState, []byte(`{`)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Get(memstore); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("when the clock skew is stale", func(t *testing.T) {
+		memstore := &kvstore.Memory{}
+		data, err := json.Marshal(&ClockSkew{Expire: time.Now().Add(-time.Minute), Skew: 1, Source: "ntp"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := memstore.Set(ClockSkewState, data); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Get(memstore); !errors.Is(err, ErrStaleClockSkew) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestAnnotate(t *testing.T) {
+	meas := &model.Measurement{}
+	Annotate(meas, -3.14159, "https_date")
+	expect := map[string]string{
+		AnnotationClockSkew:       "-3.142",
+		AnnotationClockSkewSource: "https_date",
+	}
+	if diff := cmp.Diff(expect, meas.Annotations); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -17,33 +17,47 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/legacy/assetsdir"
 	"github.com/ooni/probe-cli/v3/internal/logx"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/oonirun"
 	"github.com/ooni/probe-cli/v3/internal/registry"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 	"github.com/ooni/probe-cli/v3/internal/version"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Options contains the options you can set from the CLI.
 type Options struct {
 	Annotations         []string
 	AuthFile            string
+	Categories          []string
 	Emoji               bool
+	ExcludeCategories   []string
+	ExcludePatterns     []string
 	ExtraOptions        []string
+	FollowUps           bool
 	HomeDir             string
+	IncludePatterns     []string
 	Inputs              []string
 	InputFilePaths      []string
 	MaxRuntime          int64
+	NetDiagFile         string
 	NoJSON              bool
 	NoCollector         bool
 	NoCredentials       bool
+	Parallelism         int64
 	ProbeServicesURL    string
 	Proxy               string
 	Random              bool
 	RepeatEvery         int64
 	ReportFile          string
+	Resume              bool
+	SampleSeed          int64
+	SampleSize          int
+	SampleStrategy      string
 	SnowflakeRendezvous string
 	SoftwareName        string
 	SoftwareVersion     string
+	TestLists           []string
 	TorArgs             []string
 	TorBinary           string
 	Tunnel              string
@@ -78,6 +92,13 @@ func main() {
 		"whether to use emojis when logging",
 	)
 
+	flags.BoolVar(
+		&globalOptions.FollowUps,
+		"follow-ups",
+		false,
+		"run targeted follow-up experiments when web_connectivity detects an anomaly",
+	)
+
 	flags.StringVar(
 		&globalOptions.HomeDir,
 		"home",
@@ -200,6 +221,8 @@ func main() {
 	registerAllExperiments(rootCmd, &globalOptions)
 	registerOONIRun(rootCmd, &globalOptions)
 	registerJavaScript(rootCmd, &globalOptions)
+	registerNetDiag(rootCmd, &globalOptions)
+	registerTactics(rootCmd, &globalOptions)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -244,6 +267,52 @@ func registerOONIRun(rootCmd *cobra.Command, globalOptions *Options) {
 		"",
 		"Path to a file containing a bearer token for fetching a remote OONI Run v2 descriptor",
 	)
+	flags.Int64Var(
+		&globalOptions.Parallelism,
+		"parallelism",
+		1,
+		"maximum number of inputs to measure concurrently for each experiment",
+	)
+	flags.BoolVar(
+		&globalOptions.Resume,
+		"resume",
+		false,
+		"resume interrupted runs skipping the inputs we already measured",
+	)
+
+	subCmd.AddCommand(&cobra.Command{
+		Use:          "validate FILE...",
+		Short:        "Validates the given OONI Run v2 descriptors",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return oonirun.V2ValidateDescriptorFiles(newLogger(globalOptions), args)
+		},
+	})
+	subCmd.AddCommand(&cobra.Command{
+		Use:   "run-file FILE...",
+		Short: "Runs the given OONI Run v2 descriptors",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			globalOptions.InputFilePaths = append(globalOptions.InputFilePaths, args...)
+			MainWithConfiguration("oonirun", globalOptions)
+		},
+	})
+	subCmd.AddCommand(&cobra.Command{
+		Use:          "diff OLD NEW",
+		Short:        "Shows the differences between two OONI Run v2 descriptors (files or HTTPS URLs)",
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			globalOptions.Inputs = args
+			if !oonirun.V2IsDescriptorURL(args[0]) && !oonirun.V2IsDescriptorURL(args[1]) {
+				// Note: we do not need a session to read local files
+				logger := newLogger(globalOptions)
+				return ooniRunDiffMain(context.Background(), &oonirun.LinkConfig{}, logger, globalOptions)
+			}
+			return MainWithConfiguration("oonirun-diff", globalOptions)
+		},
+	})
 }
 
 // registerAllExperiments registers a subcommand for each experiment
@@ -290,6 +359,20 @@ func registerAllExperiments(rootCmd *cobra.Command, globalOptions *Options) {
 				"maximum runtime in seconds for the experiment (zero means infinite)",
 			)
 
+			flags.Int64Var(
+				&globalOptions.Parallelism,
+				"parallelism",
+				1,
+				"maximum number of inputs to measure concurrently",
+			)
+
+			flags.BoolVar(
+				&globalOptions.Resume,
+				"resume",
+				false,
+				"resume the interrupted run skipping the inputs we already measured",
+			)
+
 			flags.BoolVar(
 				&globalOptions.Random,
 				"random",
@@ -297,6 +380,8 @@ func registerAllExperiments(rootCmd *cobra.Command, globalOptions *Options) {
 				"randomize the inputs list",
 			)
 
+			registerTargetFilterFlags(flags, globalOptions)
+
 		default:
 			// nothing
 		}
@@ -313,31 +398,106 @@ func registerAllExperiments(rootCmd *cobra.Command, globalOptions *Options) {
 	}
 }
 
+// registerTargetFilterFlags registers the flags to load test lists and to
+// filter and sample the targets.
+func registerTargetFilterFlags(flags *pflag.FlagSet, globalOptions *Options) {
+	flags.StringSliceVar(
+		&globalOptions.TestLists,
+		"test-list",
+		[]string{},
+		"path to a citizenlab/test-lists CSV file or JSON test list (may be specified multiple times)",
+	)
+
+	flags.StringSliceVar(
+		&globalOptions.Categories,
+		"category",
+		[]string{},
+		"only measure targets with the given category code (may be specified multiple times)",
+	)
+
+	flags.StringSliceVar(
+		&globalOptions.ExcludeCategories,
+		"exclude-category",
+		[]string{},
+		"do not measure targets with the given category code (may be specified multiple times)",
+	)
+
+	flags.StringArrayVar(
+		&globalOptions.IncludePatterns,
+		"include",
+		[]string{},
+		"only measure targets matching the given regexp (may be specified multiple times)",
+	)
+
+	flags.StringArrayVar(
+		&globalOptions.ExcludePatterns,
+		"exclude",
+		[]string{},
+		"do not measure targets matching the given regexp (may be specified multiple times)",
+	)
+
+	flags.IntVar(
+		&globalOptions.SampleSize,
+		"sample-size",
+		0,
+		"maximum number of targets to measure after filtering (zero means all)",
+	)
+
+	flags.StringVar(
+		&globalOptions.SampleStrategy,
+		"sample-strategy",
+		model.TargetSampleRandom,
+		"how to sample targets (one of: random, stratified)",
+	)
+
+	flags.Int64Var(
+		&globalOptions.SampleSeed,
+		"sample-seed",
+		0,
+		"seed for sampling targets (zero means a different sample for each run)",
+	)
+}
+
+// newTargetFilter returns the target filter configured using the current
+// options or nil when the current options do not filter any target.
+func newTargetFilter(currentOptions *Options) *model.ExperimentTargetFilter {
+	filter := &model.ExperimentTargetFilter{
+		CategoryCodes:        currentOptions.Categories,
+		ExcludeCategoryCodes: currentOptions.ExcludeCategories,
+		ExcludePatterns:      currentOptions.ExcludePatterns,
+		IncludePatterns:      currentOptions.IncludePatterns,
+		SampleSize:           currentOptions.SampleSize,
+		SampleStrategy:       currentOptions.SampleStrategy,
+		Seed:                 currentOptions.SampleSeed,
+	}
+	if filter.Empty() {
+		return nil
+	}
+	return filter
+}
+
 // MainWithConfiguration is the miniooni main with a specific configuration
 // represented by the experiment name and the current options.
 //
 // This function will panic in case of a fatal error. It is up to you that
 // integrate this function to either handle the panic of ignore it.
-func MainWithConfiguration(experimentName string, currentOptions *Options) {
+//
+// The returned error is only non-nil for commands that report failures
+// without panicking (e.g., `miniooni oonirun diff`).
+func MainWithConfiguration(experimentName string, currentOptions *Options) error {
 	runtimex.PanicOnError(engine.CheckEmbeddedPsiphonConfig(), "Invalid embedded psiphon config")
 	if currentOptions.Tunnel != "" {
 		currentOptions.Proxy = fmt.Sprintf("%s:///", currentOptions.Tunnel)
 	}
 
-	logHandler := logx.NewHandlerWithDefaultSettings()
-	logHandler.Emoji = currentOptions.Emoji
-	logger := &log.Logger{Level: log.InfoLevel, Handler: logHandler}
-	if currentOptions.Verbose {
-		logger.Level = log.DebugLevel
-	}
+	logger := newLogger(currentOptions)
 	if currentOptions.ReportFile == "" {
 		currentOptions.ReportFile = "report.jsonl"
 	}
-	log.Log = logger
 	for {
-		mainSingleIteration(logger, experimentName, currentOptions)
+		err := mainSingleIteration(logger, experimentName, currentOptions)
 		if currentOptions.RepeatEvery <= 0 {
-			break
+			return err
 		}
 		log.Infof("waiting %ds before repeating the measurement", currentOptions.RepeatEvery)
 		log.Info("use Ctrl-C to interrupt miniooni")
@@ -345,9 +505,21 @@ func MainWithConfiguration(experimentName string, currentOptions *Options) {
 	}
 }
 
+// newLogger creates the logger to use and makes it the default logger.
+func newLogger(currentOptions *Options) *log.Logger {
+	logHandler := logx.NewHandlerWithDefaultSettings()
+	logHandler.Emoji = currentOptions.Emoji
+	logger := &log.Logger{Level: log.InfoLevel, Handler: logHandler}
+	if currentOptions.Verbose {
+		logger.Level = log.DebugLevel
+	}
+	log.Log = logger
+	return logger
+}
+
 // mainSingleIteration runs a single iteration. There may be multiple iterations
 // when the user specifies the --repeat-every command line flag.
-func mainSingleIteration(logger model.Logger, experimentName string, currentOptions *Options) {
+func mainSingleIteration(logger model.Logger, experimentName string, currentOptions *Options) error {
 
 	// We allow the inner code to fail but we stop propagating the panic here
 	// such that --repeat-every works as intended anyway
@@ -395,6 +567,14 @@ func mainSingleIteration(logger model.Logger, experimentName string, currentOpti
 			humanize.SI(sess.KibiBytesSent()*1024, "byte"),
 		)
 	}()
+
+	// We diagnose the network before looking up the backends because we
+	// mostly need a diagnosis when we cannot reach the backends.
+	if experimentName == "netdiag" {
+		netDiagMain(ctx, sess, currentOptions)
+		return nil
+	}
+
 	lookupBackendsOrPanic(ctx, sess)
 	lookupLocationOrPanic(ctx, sess)
 
@@ -402,11 +582,17 @@ func mainSingleIteration(logger model.Logger, experimentName string, currentOpti
 	// `miniooni -i {OONIRunURL} oonirun` to run a OONI Run URL (v1 or v2).
 	if experimentName == "oonirun" {
 		ooniRunMain(ctx, sess, currentOptions, annotations)
-		return
+		return nil
+	}
+
+	// Likewise, `miniooni oonirun diff` needs a session to fetch descriptors.
+	if experimentName == "oonirun-diff" {
+		return ooniRunDiffSessionMain(ctx, sess, currentOptions)
 	}
 
 	// Otherwise just run OONI experiments as we normally do.
 	runx(ctx, sess, experimentName, annotations, extraOptions, currentOptions)
+	return nil
 }
 
 func documentationForOptions(factory *registry.Factory) string {
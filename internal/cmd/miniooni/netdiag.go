@@ -0,0 +1,47 @@
+package main
+
+//
+// Diagnosing the engine network
+//
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/spf13/cobra"
+)
+
+// registerNetDiag registers the netdiag subcommand
+func registerNetDiag(rootCmd *cobra.Command, globalOptions *Options) {
+	subCmd := &cobra.Command{
+		Use:   "netdiag",
+		Short: "Diagnoses how we communicate with the OONI backend and the test helpers",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			MainWithConfiguration(cmd.Use, globalOptions)
+		},
+	}
+	rootCmd.AddCommand(subCmd)
+	subCmd.Flags().StringVarP(
+		&globalOptions.NetDiagFile,
+		"output",
+		"o",
+		"netdiag.json",
+		"file where to save the JSON report to attach to bug reports",
+	)
+}
+
+// netDiagMain diagnoses the network and saves the report.
+func netDiagMain(ctx context.Context, sess *engine.Session, currentOptions *Options) {
+	report := sess.DiagnoseNetwork(ctx)
+	fmt.Print(report.Summary())
+	data, err := json.MarshalIndent(report, "", "  ")
+	runtimex.PanicOnError(err, "json.MarshalIndent failed")
+	err = os.WriteFile(currentOptions.NetDiagFile, data, 0600)
+	runtimex.PanicOnError(err, "cannot write the netdiag report")
+	sess.Logger().Infof("netdiag: saved the report to %s", currentOptions.NetDiagFile)
+}
@@ -6,12 +6,14 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"os"
+	"fmt"
 
 	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/oonirun"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 )
 
 // ooniRunMain runs the experiments described by the given OONI Run URLs. This
@@ -23,16 +25,20 @@ func ooniRunMain(ctx context.Context,
 		AcceptChanges: currentOptions.Yes,
 		AuthFile:      currentOptions.AuthFile,
 		Annotations:   annotations,
+		FollowUps:     currentOptions.FollowUps,
 		KVStore:       sess.KeyValueStore(),
 		MaxRuntime:    currentOptions.MaxRuntime,
 		NoCollector:   currentOptions.NoCollector,
 		NoCredentials: currentOptions.NoCredentials,
 		NoJSON:        currentOptions.NoJSON,
+		Parallelism:   currentOptions.Parallelism,
 		Random:        currentOptions.Random,
 		ReportFile:    currentOptions.ReportFile,
+		Resume:        currentOptions.Resume,
 		ProbeCC:       sess.ProbeCC(),
 		ProbeASN:      sess.ProbeASNString(),
 		Session:       sess,
+		SubmitQueue:   submitqueue.New(sess.KeyValueStore()),
 	}
 	for _, URL := range currentOptions.Inputs {
 		r := oonirun.NewLinkRunner(cfg, URL)
@@ -47,21 +53,38 @@ func ooniRunMain(ctx context.Context,
 		}
 	}
 	for _, filename := range currentOptions.InputFilePaths {
-		data, err := os.ReadFile(filename) // #nosec G304 - this is working as intended
-		if err != nil {
-			logger.Warnf("oonirun: reading OONI Run v2 descriptor failed: %s", err.Error())
-			continue
-		}
-		var descr oonirun.V2Descriptor
-		if err := json.Unmarshal(data, &descr); err != nil {
-			logger.Warnf("oonirun: parsing OONI Run v2 descriptor failed: %s", err.Error())
-			continue
-		}
-		logger.Infof("oonirun: running '%s'", descr.Name)
-		logger.Infof("oonirun: link authored by '%s'", descr.Author)
-		if err := oonirun.V2MeasureDescriptor(ctx, cfg, &descr); err != nil {
-			logger.Warnf("oonirun: running link failed: %s", err.Error())
+		if err := oonirun.V2MeasureDescriptorFile(ctx, cfg, filename); err != nil {
+			logger.Warnf("oonirun: running %s failed: %s", filename, err.Error())
 			continue
 		}
 	}
 }
+
+// ooniRunDiffMain shows the differences between two descriptors, the first
+// two entries of currentOptions.Inputs, which may be files or HTTPS URLs.
+func ooniRunDiffMain(ctx context.Context, cfg *oonirun.LinkConfig, logger model.Logger, currentOptions *Options) error {
+	runtimex.Assert(len(currentOptions.Inputs) == 2, "oonirun: expected two descriptors")
+	oldSource, newSource := currentOptions.Inputs[0], currentOptions.Inputs[1]
+	diff, err := oonirun.V2DiffDescriptorSources(ctx, cfg, oldSource, newSource)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		logger.Infof("oonirun: %s and %s are equal", oldSource, newSource)
+		return nil
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+// ooniRunDiffSessionMain is like ooniRunDiffMain but uses the session to fetch descriptors.
+func ooniRunDiffSessionMain(ctx context.Context, sess *engine.Session, currentOptions *Options) error {
+	cfg := &oonirun.LinkConfig{
+		AuthFile: currentOptions.AuthFile,
+		KVStore:  sess.KeyValueStore(),
+		ProbeCC:  sess.ProbeCC(),
+		ProbeASN: sess.ProbeASNString(),
+		Session:  sess,
+	}
+	return ooniRunDiffMain(ctx, cfg, sess.Logger(), currentOptions)
+}
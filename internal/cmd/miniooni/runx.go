@@ -7,16 +7,20 @@ package main
 import (
 	"context"
 
+	"github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/oonirun"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 )
 
 // runx runs the given experiment by name
-func runx(ctx context.Context, sess oonirun.Session, experimentName string,
+func runx(ctx context.Context, sess *engine.Session, experimentName string,
 	annotations map[string]string, extraOptions map[string]any, currentOptions *Options) {
 	desc := &oonirun.Experiment{
 		Annotations:    annotations,
+		Checkpoints:    sess.KeyValueStore(),
 		ExtraOptions:   extraOptions,
+		FollowUps:      currentOptions.FollowUps,
 		Inputs:         currentOptions.Inputs,
 		InputFilePaths: currentOptions.InputFilePaths,
 		MaxRuntime:     currentOptions.MaxRuntime,
@@ -24,9 +28,14 @@ func runx(ctx context.Context, sess oonirun.Session, experimentName string,
 		NoCollector:    currentOptions.NoCollector,
 		NoCredentials:  currentOptions.NoCredentials,
 		NoJSON:         currentOptions.NoJSON,
+		Parallelism:    currentOptions.Parallelism,
 		Random:         currentOptions.Random,
 		ReportFile:     currentOptions.ReportFile,
+		Resume:         currentOptions.Resume,
 		Session:        sess,
+		SubmitQueue:    submitqueue.New(sess.KeyValueStore()),
+		TargetFilter:   newTargetFilter(currentOptions),
+		TestLists:      currentOptions.TestLists,
 	}
 	err := desc.Run(ctx)
 	runtimex.PanicOnError(err, "cannot run experiment")
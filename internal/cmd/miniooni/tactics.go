@@ -0,0 +1,123 @@
+package main
+
+//
+// Importing and exporting the user-supplied TLS dialing tactics
+//
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ooni/probe-cli/v3/internal/enginenetx"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/spf13/cobra"
+)
+
+// registerTactics registers the tactics subcommand
+func registerTactics(rootCmd *cobra.Command, globalOptions *Options) {
+	subCmd := &cobra.Command{
+		Use:   "tactics",
+		Short: "Manages the user-supplied bridges, SNIs and proxies used to reach the OONI backend",
+	}
+	rootCmd.AddCommand(subCmd)
+
+	subCmd.AddCommand(&cobra.Command{
+		Use:          "validate FILE...",
+		Short:        "Validates the given tactics files",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return tacticsValidateMain(newLogger(globalOptions), args)
+		},
+	})
+	subCmd.AddCommand(&cobra.Command{
+		Use:          "import FILE",
+		Short:        "Validates the given tactics file and uses it from now on",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kvStore, err := tacticsNewKVStore(globalOptions)
+			if err != nil {
+				return err
+			}
+			return tacticsImportMain(newLogger(globalOptions), kvStore, args[0])
+		},
+	})
+	subCmd.AddCommand(&cobra.Command{
+		Use:          "export [FILE]",
+		Short:        "Writes the tactics file we're using to FILE or to the stdout",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kvStore, err := tacticsNewKVStore(globalOptions)
+			if err != nil {
+				return err
+			}
+			var filename string
+			if len(args) == 1 {
+				filename = args[0]
+			}
+			return tacticsExportMain(kvStore, filename)
+		},
+	})
+}
+
+// tacticsNewKVStore opens the engine key-value store where we keep the tactics.
+func tacticsNewKVStore(currentOptions *Options) (model.KeyValueStore, error) {
+	homeDir := gethomedir(currentOptions.HomeDir)
+	if homeDir == "" {
+		return nil, errors.New("tactics: home directory is empty")
+	}
+	return kvstore.NewFS(filepath.Join(homeDir, ".miniooni", "engine"))
+}
+
+// tacticsValidateMain validates each tactics file.
+func tacticsValidateMain(logger model.Logger, filenames []string) error {
+	var failed int
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err == nil {
+			err = enginenetx.ValidateUserPolicy(data)
+		}
+		if err != nil {
+			logger.Warnf("tactics: %s is not valid:\n%s", filename, err.Error())
+			failed++
+			continue
+		}
+		logger.Infof("tactics: %s is valid", filename)
+	}
+	if failed > 0 {
+		return fmt.Errorf("tactics: %d of %d files are not valid", failed, len(filenames))
+	}
+	return nil
+}
+
+// tacticsImportMain validates and imports the given tactics file.
+func tacticsImportMain(logger model.Logger, kvStore model.KeyValueStore, filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := enginenetx.ImportUserPolicy(kvStore, data); err != nil {
+		return err
+	}
+	logger.Infof("tactics: imported %s", filename)
+	return nil
+}
+
+// tacticsExportMain writes the imported tactics file to the given file
+// or to the standard output when the filename is empty.
+func tacticsExportMain(kvStore model.KeyValueStore, filename string) error {
+	data, err := enginenetx.ExportUserPolicy(kvStore)
+	if err != nil {
+		return err
+	}
+	if filename == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(filename, data, 0600)
+}
@@ -0,0 +1,210 @@
+package main
+
+//
+// HTTP handler implementing the mock OONI backend
+//
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/must"
+	"github.com/ooni/probe-cli/v3/internal/testingx"
+)
+
+// measurementsFileName is the name of the file inside the data directory
+// where we append the submitted measurements using the JSONL format.
+const measurementsFileName = "measurements.jsonl"
+
+// backend is the mock OONI backend. Use [newBackend] to construct.
+type backend struct {
+	// collector implements the collector API.
+	collector *testingx.OONICollector
+
+	// config is the declarative configuration.
+	config *config
+
+	// faults injects errors and latency.
+	faults *faultInjector
+
+	// logger is the logger to use.
+	logger model.Logger
+
+	// loginFlow implements register, login and the APIs requiring login.
+	loginFlow *testingx.OONIBackendWithLoginFlow
+
+	// measurementsFile is the file where we store the measurements.
+	measurementsFile string
+
+	// mu provides mutual exclusion when writing measurements.
+	mu sync.Mutex
+}
+
+// newBackend creates a new [*backend] storing measurements inside the given
+// data directory, which we create if it does not exist.
+func newBackend(logger model.Logger, cfg *config, dataDir string) (*backend, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	b := &backend{
+		collector:        &testingx.OONICollector{},
+		config:           cfg,
+		faults:           &faultInjector{Logger: logger},
+		logger:           logger,
+		loginFlow:        &testingx.OONIBackendWithLoginFlow{},
+		measurementsFile: filepath.Join(dataDir, measurementsFileName),
+		mu:               sync.Mutex{},
+	}
+	b.collector.ValidateMeasurement = b.saveMeasurement
+	b.loginFlow.SetPsiphonConfig(cfg.PsiphonConfig)
+	b.loginFlow.SetTorTargets(must.MarshalJSON(cfg.TorTargets))
+	if err := b.faults.SetRules(cfg.Faults); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// NewHandler returns the [http.Handler] implementing the backend.
+func (b *backend) NewHandler() http.Handler {
+	loginMux := b.loginFlow.NewMux()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/test-helpers", b.handleTestHelpers)
+	mux.HandleFunc("POST /api/v1/check-in", b.handleCheckIn)
+	mux.Handle("/api/v1/login", loginMux)
+	mux.Handle("/api/v1/register", loginMux)
+	mux.HandleFunc("POST /api/v1/submit_measurement", b.handleSubmitMeasurement)
+	mux.Handle("/api/v1/test-list/psiphon-config", loginMux)
+	mux.Handle("/api/v1/test-list/tor-targets", loginMux)
+	mux.HandleFunc("GET /api/v2/ooniprobe/vpn-config/{provider}", b.handleOpenVPNConfig)
+	mux.HandleFunc("GET /api/v2/oonirun/links/{id}", b.handleOONIRunDescriptor)
+	mux.HandleFunc("POST /api/v2/oonirun/links/{id}/engine-descriptor/{revision}", b.handleOONIRunDescriptor)
+	mux.Handle("/report", b.collector)
+	mux.Handle("/report/", b.collector)
+
+	return b.faults.Wrap(b.withLogging(mux))
+}
+
+// withLogging logs each request.
+func (b *backend) withLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.logger.Infof("%s %s", r.Method, r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (b *backend) handleTestHelpers(w http.ResponseWriter, r *http.Request) {
+	b.writeJSON(w, b.config.TestHelpers)
+}
+
+func (b *backend) handleCheckIn(w http.ResponseWriter, r *http.Request) {
+	// make sure the request is a check-in request
+	var request model.OOAPICheckInConfig
+	if err := b.readJSON(r, &request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// copy the response so we can fill the dynamic fields
+	response := *b.config.CheckIn
+	response.UTCTime = time.Now().UTC()
+	if wc := response.Tests.WebConnectivity; wc != nil {
+		wcCopy := *wc
+		if wcCopy.ReportID == "" {
+			wcCopy.ReportID = uuid.Must(uuid.NewRandom()).String()
+		}
+		response.Tests.WebConnectivity = &wcCopy
+	}
+	b.writeJSON(w, &response)
+}
+
+func (b *backend) handleSubmitMeasurement(w http.ResponseWriter, r *http.Request) {
+	// make sure the request is a submit request
+	var request model.OOAPISubmitMeasurementRequest
+	if err := b.readJSON(r, &request); err != nil || request.Format != "json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// make sure the content is a measurement
+	var measurement model.Measurement
+	if err := json.Unmarshal([]byte(request.Content), &measurement); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := b.saveMeasurement(&measurement); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	response := &model.OOAPISubmitMeasurementResponse{
+		MeasurementUID: uuid.Must(uuid.NewRandom()).String(),
+	}
+	b.writeJSON(w, response)
+}
+
+func (b *backend) handleOpenVPNConfig(w http.ResponseWriter, r *http.Request) {
+	config, found := b.config.OpenVPNConfig[r.PathValue("provider")]
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	b.writeJSON(w, config)
+}
+
+func (b *backend) handleOONIRunDescriptor(w http.ResponseWriter, r *http.Request) {
+	descriptor, found := b.config.OONIRunDescriptors[r.PathValue("id")]
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(descriptor)
+}
+
+// saveMeasurement appends the measurement to the measurements file.
+func (b *backend) saveMeasurement(measurement *model.Measurement) error {
+	data, err := json.Marshal(measurement)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	defer b.mu.Unlock()
+	b.mu.Lock()
+
+	filep, err := os.OpenFile(b.measurementsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		b.logger.Warnf("cannot open %s: %s", b.measurementsFile, err.Error())
+		return err
+	}
+	if _, err := filep.Write(data); err != nil {
+		filep.Close()
+		b.logger.Warnf("cannot write %s: %s", b.measurementsFile, err.Error())
+		return err
+	}
+	b.logger.Infof("saved %s measurement into %s", measurement.TestName, b.measurementsFile)
+	return filep.Close()
+}
+
+// readJSON reads and parses the JSON request body.
+func (b *backend) readJSON(r *http.Request, value any) error {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// writeJSON serializes and writes the JSON response body.
+func (b *backend) writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(must.MarshalJSON(value))
+}
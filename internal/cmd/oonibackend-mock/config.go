@@ -0,0 +1,98 @@
+package main
+
+//
+// Declarative configuration of the mock backend
+//
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// config is the declarative configuration of the mock backend, which
+// we read from the file passed using the `-config` flag.
+//
+// Any field that the configuration file does not set keeps the value
+// returned by [newDefaultConfig].
+type config struct {
+	// CheckIn is the response to return for the check-in API. We fill
+	// the report ID and the time when we send the response.
+	CheckIn *model.OOAPICheckInResult `json:"check_in"`
+
+	// Faults contains the rules to inject errors and latency.
+	Faults []*faultRule `json:"faults"`
+
+	// OONIRunDescriptors maps an OONI Run v2 link ID to the descriptor to
+	// return, which we serve verbatim for any revision of the link.
+	OONIRunDescriptors map[string]json.RawMessage `json:"oonirun_descriptors"`
+
+	// OpenVPNConfig maps a VPN provider name (e.g., `riseupvpn`) to its config.
+	OpenVPNConfig map[string]*model.OOAPIVPNProviderConfig `json:"openvpn_config"`
+
+	// PsiphonConfig is the psiphon config to return to authenticated clients.
+	PsiphonConfig json.RawMessage `json:"psiphon_config"`
+
+	// TestHelpers is the response to return for the bouncer API.
+	TestHelpers map[string][]model.OOAPIService `json:"test_helpers"`
+
+	// TorTargets are the tor targets to return to authenticated clients.
+	TorTargets map[string]model.OOAPITorTarget `json:"tor_targets"`
+}
+
+// newDefaultConfig returns the default configuration.
+func newDefaultConfig() *config {
+	testHelpers := map[string][]model.OOAPIService{
+		"web-connectivity": {{
+			Address: "https://0.th.ooni.org",
+			Type:    "https",
+		}},
+	}
+	return &config{
+		CheckIn: &model.OOAPICheckInResult{
+			Conf: model.OOAPICheckInResultConfig{
+				Features:    map[string]bool{},
+				TestHelpers: testHelpers,
+			},
+			ProbeASN: model.DefaultProbeASNString,
+			ProbeCC:  model.DefaultProbeCC,
+			Tests: model.OOAPICheckInResultNettests{
+				WebConnectivity: &model.OOAPICheckInInfoWebConnectivity{
+					URLs: []model.OOAPIURLInfo{{
+						CategoryCode: model.DefaultCategoryCode,
+						CountryCode:  model.DefaultCountryCode,
+						URL:          "https://www.example.com/",
+					}},
+				},
+			},
+			V: 1,
+		},
+		Faults:             []*faultRule{},
+		OONIRunDescriptors: map[string]json.RawMessage{},
+		OpenVPNConfig:      map[string]*model.OOAPIVPNProviderConfig{},
+		PsiphonConfig:      json.RawMessage(`{}`),
+		TestHelpers:        testHelpers,
+		TorTargets:         map[string]model.OOAPITorTarget{},
+	}
+}
+
+// loadConfig loads the configuration from the given file or returns
+// the default configuration when the filename is empty.
+func loadConfig(filename string) (*config, error) {
+	cfg := newDefaultConfig()
+	if filename == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	if err := validateFaultRules(cfg.Faults); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+//
+// Scriptable injection of errors and latency
+//
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// faultRule injects errors and latency into the requests matching it.
+type faultRule struct {
+	// Count is the OPTIONAL number of matching requests to which this rule
+	// applies, after which the rule is exhausted. Zero means forever.
+	Count int `json:"count"`
+
+	// DelayMillis is the OPTIONAL number of milliseconds to wait
+	// before handling the request.
+	DelayMillis int64 `json:"delay_ms"`
+
+	// Method is the OPTIONAL HTTP method to match. Empty matches any method.
+	Method string `json:"method"`
+
+	// Path is the MANDATORY pattern matching the URL path using the
+	// syntax of [path.Match] (e.g., `/report/*`).
+	Path string `json:"path"`
+
+	// StatusCode is the OPTIONAL status code to return instead of
+	// handling the request. Zero means handling the request.
+	StatusCode int `json:"status_code"`
+
+	// applied counts the requests to which this rule applied.
+	applied int
+}
+
+// errInvalidFaultRule indicates that a fault rule is not valid.
+var errInvalidFaultRule = errors.New("invalid fault rule")
+
+// validateFaultRules returns an error if any of the given rules is not valid.
+func validateFaultRules(rules []*faultRule) error {
+	for idx, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("%w: faults[%d]: %s", errInvalidFaultRule, idx, err.Error())
+		}
+	}
+	return nil
+}
+
+func (fr *faultRule) validate() error {
+	if fr == nil {
+		return errors.New("nil rule")
+	}
+	if fr.Path == "" {
+		return errors.New("path: missing pattern")
+	}
+	if _, err := path.Match(fr.Path, ""); err != nil {
+		return fmt.Errorf("path: %w", err)
+	}
+	if fr.Count < 0 {
+		return fmt.Errorf("count: negative count %d", fr.Count)
+	}
+	if fr.DelayMillis < 0 {
+		return fmt.Errorf("delay_ms: negative delay %d", fr.DelayMillis)
+	}
+	if fr.StatusCode != 0 && (fr.StatusCode < 100 || fr.StatusCode > 599) {
+		return fmt.Errorf("status_code: invalid status code %d", fr.StatusCode)
+	}
+	return nil
+}
+
+// match returns whether the rule applies to the given request.
+func (fr *faultRule) match(r *http.Request) bool {
+	if fr.Count > 0 && fr.applied >= fr.Count {
+		return false
+	}
+	if fr.Method != "" && fr.Method != r.Method {
+		return false
+	}
+	matched, _ := path.Match(fr.Path, r.URL.Path)
+	return matched
+}
+
+// faultInjector injects errors and latency using rules that clients
+// may replace at runtime using the [faultsAdminPath] API.
+//
+// The zero value is ready to use and does not inject any fault.
+type faultInjector struct {
+	// Logger is the MANDATORY logger to use.
+	Logger model.Logger
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// rules contains the rules.
+	rules []*faultRule
+}
+
+// faultsAdminPath is the URL path of the API to get and replace the rules.
+const faultsAdminPath = "/mock/v1/faults"
+
+// SetRules replaces the rules. This method is safe to call concurrently
+// with incoming HTTP requests.
+func (fi *faultInjector) SetRules(rules []*faultRule) error {
+	if err := validateFaultRules(rules); err != nil {
+		return err
+	}
+	fi.mu.Lock()
+	fi.rules = rules
+	fi.mu.Unlock()
+	return nil
+}
+
+// lookup returns a copy of the first rule matching the request, if any, and
+// marks it as applied, so rules with a count are eventually exhausted.
+func (fi *faultInjector) lookup(r *http.Request) (faultRule, bool) {
+	defer fi.mu.Unlock()
+	fi.mu.Lock()
+	for _, rule := range fi.rules {
+		if rule.match(r) {
+			rule.applied++
+			return *rule, true
+		}
+	}
+	return faultRule{}, false
+}
+
+// Wrap returns a handler injecting faults before calling the given handler.
+func (fi *faultInjector) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// never inject faults into the API used to configure faults
+		if r.URL.Path == faultsAdminPath {
+			fi.serveAdmin(w, r)
+			return
+		}
+
+		rule, found := fi.lookup(r)
+		if !found {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if rule.DelayMillis > 0 {
+			fi.Logger.Infof("faults: delaying %s %s by %d ms", r.Method, r.URL.Path, rule.DelayMillis)
+			timer := time.NewTimer(time.Duration(rule.DelayMillis) * time.Millisecond)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if rule.StatusCode != 0 {
+			fi.Logger.Infof("faults: failing %s %s with %d", r.Method, r.URL.Path, rule.StatusCode)
+			w.WriteHeader(rule.StatusCode)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serveAdmin serves the API to get and replace the rules.
+func (fi *faultInjector) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		fi.mu.Lock()
+		data, err := json.Marshal(fi.rules)
+		fi.mu.Unlock()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var rules []*faultRule
+		if err := json.Unmarshal(data, &rules); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := fi.SetRules(rules); err != nil {
+			fi.Logger.Warnf("faults: %s", err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fi.Logger.Infof("faults: configured %d rules", len(rules))
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
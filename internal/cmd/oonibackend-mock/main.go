@@ -0,0 +1,90 @@
+// Command oonibackend-mock implements a mock OONI backend for testing
+// ooniprobe and miniooni without network access.
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+var (
+	// apiEndpoint is the endpoint where we serve the OONI API
+	apiEndpoint = flag.String("api-endpoint", "127.0.0.1:8080", "API endpoint")
+
+	// configFile is the OPTIONAL declarative configuration file
+	configFile = flag.String("config", "", "JSON file configuring the responses and the faults")
+
+	// dataDir is the directory where we save the measurements
+	dataDir = flag.String("datadir", "oonibackend-mock-data", "Directory where to save the measurements")
+
+	// debug controls whether to enable verbose logging
+	debug = flag.Bool("debug", false, "Toggle debug mode")
+
+	// sigs is the channel where we collect signals
+	sigs = make(chan os.Signal, 1)
+
+	// srvAddr is used to pass the server address to tests
+	srvAddr = make(chan string, 1)
+
+	// srvWg is used by tests to know when the server has shut down
+	srvWg = new(sync.WaitGroup)
+)
+
+func main() {
+	// parse command line options
+	flag.Parse()
+
+	// set log level
+	logmap := map[bool]log.Level{
+		true:  log.DebugLevel,
+		false: log.InfoLevel,
+	}
+	log.SetLevel(logmap[*debug])
+
+	// create the backend
+	cfg, err := loadConfig(*configFile)
+	runtimex.PanicOnError(err, "loadConfig failed")
+	backend, err := newBackend(log.Log, cfg, *dataDir)
+	runtimex.PanicOnError(err, "newBackend failed")
+
+	// create a listening server for serving ooniprobe requests
+	srv := &http.Server{
+		Addr:              *apiEndpoint,
+		Handler:           backend.NewHandler(),
+		ReadHeaderTimeout: 8 * time.Second,
+	}
+	listener, err := net.Listen("tcp", *apiEndpoint)
+	runtimex.PanicOnError(err, "net.Listen failed")
+
+	// await for the server's address to become available
+	srvAddr <- listener.Addr().String()
+	srvWg.Add(1)
+
+	// start listening in the background
+	go srv.Serve(listener)
+	log.Infof("serving the OONI API at http://%s/", listener.Addr().String())
+	log.Infof("saving measurements into %s", backend.measurementsFile)
+
+	// await for a signal
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigs
+	log.Infof("interrupted by signal: %v", sig)
+
+	// shutdown the server awaiting for pending requests
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+
+	// notify tests that we are now done
+	srvWg.Done()
+}
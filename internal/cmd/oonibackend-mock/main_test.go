@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/httpclientx"
+	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/probeservices"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// newTestBackend creates a backend using the given config and returns the
+// URL of the server serving it and the directory containing the data.
+func newTestBackend(t *testing.T, cfg *config) (string, string) {
+	dataDir := t.TempDir()
+	backend := runtimex.Try1(newBackend(model.DiscardLogger, cfg, dataDir))
+	srv := httptest.NewServer(backend.NewHandler())
+	t.Cleanup(srv.Close)
+	return srv.URL, dataDir
+}
+
+// newTestClient creates a probe services client using the given URL.
+func newTestClient(URL string) *probeservices.Client {
+	return runtimex.Try1(probeservices.NewClient(
+		&mockable.Session{
+			MockableHTTPClient: http.DefaultClient,
+			MockableLogger:     model.DiscardLogger,
+		},
+		model.OOAPIService{
+			Address: URL,
+			Type:    "https",
+		},
+	))
+}
+
+// readMeasurements reads the measurements saved into the data directory.
+func readMeasurements(t *testing.T, dataDir string) (out []*model.Measurement) {
+	filep, err := os.Open(filepath.Join(dataDir, measurementsFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filep.Close()
+	scanner := bufio.NewScanner(filep)
+	for scanner.Scan() {
+		var measurement model.Measurement
+		if err := json.Unmarshal(scanner.Bytes(), &measurement); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, &measurement)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+// newTestMeasurement returns a measurement that the collector accepts.
+func newTestMeasurement(testName string) *model.Measurement {
+	return &model.Measurement{
+		DataFormatVersion: model.OOAPIReportDefaultDataFormatVersion,
+		ProbeASN:          "AS30722",
+		ProbeCC:           "IT",
+		SoftwareName:      "miniooni",
+		SoftwareVersion:   "0.1.0-dev",
+		TestName:          testName,
+		TestStartTime:     "2026-10-19 10:00:00",
+		TestVersion:       "0.1.0",
+	}
+}
+
+func TestBackend(t *testing.T) {
+	t.Run("we serve the test helpers", func(t *testing.T) {
+		URL, _ := newTestBackend(t, newDefaultConfig())
+		testhelpers, err := newTestClient(URL).GetTestHelpers(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(newDefaultConfig().TestHelpers, testhelpers); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we serve the check-in response", func(t *testing.T) {
+		URL, _ := newTestBackend(t, newDefaultConfig())
+		result, err := newTestClient(URL).CheckIn(context.Background(), model.OOAPICheckInConfig{
+			Charging:        true,
+			OnWiFi:          true,
+			Platform:        "linux",
+			ProbeASN:        "AS30722",
+			ProbeCC:         "IT",
+			RunType:         model.RunTypeManual,
+			SoftwareName:    "miniooni",
+			SoftwareVersion: "0.1.0-dev",
+			WebConnectivity: model.OOAPICheckInConfigWebConnectivity{
+				CategoryCodes: []string{},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Tests.WebConnectivity == nil || result.Tests.WebConnectivity.ReportID == "" {
+			t.Fatal("expected a report ID")
+		}
+		if len(result.Tests.WebConnectivity.URLs) != 1 || result.UTCTime.IsZero() {
+			t.Fatal("unexpected result", result)
+		}
+	})
+
+	t.Run("we save the measurements submitted using reports", func(t *testing.T) {
+		URL, dataDir := newTestBackend(t, newDefaultConfig())
+		submitter := probeservices.NewSubmitter(newTestClient(URL), model.DiscardLogger)
+		for _, testName := range []string{"dnscheck", "dnscheck", "web_connectivity"} {
+			if _, err := submitter.Submit(context.Background(), newTestMeasurement(testName)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		measurements := readMeasurements(t, dataDir)
+		if len(measurements) != 3 {
+			t.Fatal("expected three measurements", len(measurements))
+		}
+		if measurements[0].ReportID == "" || measurements[0].ReportID != measurements[1].ReportID {
+			t.Fatal("expected the first two measurements to share the report ID")
+		}
+		if measurements[2].TestName != "web_connectivity" || measurements[2].ReportID == measurements[1].ReportID {
+			t.Fatal("expected the third measurement to use another report")
+		}
+	})
+
+	t.Run("we save the measurements submitted without reports", func(t *testing.T) {
+		URL, dataDir := newTestBackend(t, newDefaultConfig())
+		uid, err := newTestClient(URL).Submit(context.Background(), newTestMeasurement("dnscheck"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uid == "" {
+			t.Fatal("expected a measurement UID")
+		}
+		if measurements := readMeasurements(t, dataDir); len(measurements) != 1 {
+			t.Fatal("expected one measurement", len(measurements))
+		}
+	})
+
+	t.Run("we serve the tor targets after register and login", func(t *testing.T) {
+		cfg := newDefaultConfig()
+		cfg.TorTargets = map[string]model.OOAPITorTarget{
+			"bridge": {Address: "1.2.3.4:443", Protocol: "obfs4"},
+		}
+		URL, _ := newTestBackend(t, cfg)
+		client := newTestClient(URL)
+		metadata := model.OOAPIProbeMetadata{
+			Platform:        "linux",
+			ProbeASN:        "AS30722",
+			ProbeCC:         "IT",
+			SoftwareName:    "miniooni",
+			SoftwareVersion: "0.1.0-dev",
+			SupportedTests:  []string{"tor"},
+		}
+		if err := client.MaybeRegister(context.Background(), metadata); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.MaybeLogin(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		targets, err := client.FetchTorTargets(context.Background(), "IT")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(cfg.TorTargets, targets); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we serve the openvpn config", func(t *testing.T) {
+		cfg := newDefaultConfig()
+		cfg.OpenVPNConfig["riseupvpn"] = &model.OOAPIVPNProviderConfig{
+			Provider: "riseupvpn",
+			Inputs:   []string{"openvpn://riseupvpn.corp/?address=1.2.3.4:1194&transport=udp"},
+		}
+		URL, _ := newTestBackend(t, cfg)
+		client := newTestClient(URL)
+		config, err := client.FetchOpenVPNConfig(context.Background(), "riseup", "IT")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(cfg.OpenVPNConfig["riseupvpn"].Inputs, config.Inputs); diff != "" {
+			t.Fatal(diff)
+		}
+		if _, err := client.FetchOpenVPNConfig(context.Background(), "nonexistent", "IT"); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("we serve the OONI Run v2 descriptors", func(t *testing.T) {
+		cfg := newDefaultConfig()
+		descriptor := json.RawMessage(`{"name":"example","nettests":[{"test_name":"dnscheck"}]}`)
+		cfg.OONIRunDescriptors["10"] = descriptor
+		URL, _ := newTestBackend(t, cfg)
+
+		requests := []*http.Request{
+			runtimex.Try1(http.NewRequest("GET", URL+"/api/v2/oonirun/links/10", nil)),
+			runtimex.Try1(http.NewRequest("POST", URL+"/api/v2/oonirun/links/10/engine-descriptor/latest",
+				strings.NewReader(`{"run_type":"manual"}`))),
+		}
+		for _, req := range requests {
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body bytes.Buffer
+			_, _ = body.ReadFrom(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != 200 || body.String() != string(descriptor) {
+				t.Fatal("unexpected response", resp.StatusCode, body.String())
+			}
+		}
+
+		resp, err := http.Get(URL + "/api/v2/oonirun/links/11")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+	})
+}
+
+func TestFaultInjector(t *testing.T) {
+	t.Run("we fail the requests matching a rule until it is exhausted", func(t *testing.T) {
+		cfg := newDefaultConfig()
+		cfg.Faults = []*faultRule{{
+			Count:      2,
+			Method:     "GET",
+			Path:       "/api/v1/test-*",
+			StatusCode: 503,
+		}}
+		URL, _ := newTestBackend(t, cfg)
+		client := newTestClient(URL)
+		for idx := 0; idx < 2; idx++ {
+			_, err := client.GetTestHelpers(context.Background())
+			var failure *httpclientx.ErrRequestFailed
+			if !errors.As(err, &failure) || failure.StatusCode != 503 {
+				t.Fatal("unexpected error", err)
+			}
+		}
+		if _, err := client.GetTestHelpers(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("we delay the requests matching a rule", func(t *testing.T) {
+		cfg := newDefaultConfig()
+		cfg.Faults = []*faultRule{{
+			DelayMillis: 250,
+			Path:        "/api/v1/test-helpers",
+		}}
+		URL, _ := newTestBackend(t, cfg)
+		t0 := time.Now()
+		if _, err := newTestClient(URL).GetTestHelpers(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(t0); elapsed < 250*time.Millisecond {
+			t.Fatal("expected a delay", elapsed)
+		}
+	})
+
+	t.Run("we can replace the rules at runtime", func(t *testing.T) {
+		URL, _ := newTestBackend(t, newDefaultConfig())
+		client := newTestClient(URL)
+		if _, err := client.GetTestHelpers(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		putRules := func(body string) int {
+			req := runtimex.Try1(http.NewRequest("PUT", URL+faultsAdminPath, strings.NewReader(body)))
+			resp := runtimex.Try1(http.DefaultClient.Do(req))
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		if code := putRules(`[{"path": "/api/v1/*", "status_code": 500}]`); code != http.StatusNoContent {
+			t.Fatal("unexpected status code", code)
+		}
+		if _, err := client.GetTestHelpers(context.Background()); err == nil {
+			t.Fatal("expected an error")
+		}
+
+		if code := putRules(`[{"path": "[", "status_code": 500}]`); code != http.StatusBadRequest {
+			t.Fatal("unexpected status code", code)
+		}
+
+		if code := putRules(`[]`); code != http.StatusNoContent {
+			t.Fatal("unexpected status code", code)
+		}
+		if _, err := client.GetTestHelpers(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("we reject invalid rules", func(t *testing.T) {
+		cases := []*faultRule{
+			nil,
+			{Path: ""},
+			{Path: "["},
+			{Path: "/", Count: -1},
+			{Path: "/", DelayMillis: -1},
+			{Path: "/", StatusCode: 1000},
+		}
+		for _, rule := range cases {
+			if err := validateFaultRules([]*faultRule{rule}); !errors.Is(err, errInvalidFaultRule) {
+				t.Fatal("unexpected error", err)
+			}
+		}
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("we use the default config without a file", func(t *testing.T) {
+		cfg, err := loadConfig("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(newDefaultConfig(), cfg, cmp.AllowUnexported(faultRule{})); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("the file overrides the default config", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "config.json")
+		data := []byte(`{"faults": [{"path": "/report", "delay_ms": 100}], "psiphon_config": {"foo": 1}}`)
+		runtimex.Try0(os.WriteFile(filename, data, 0600))
+		cfg, err := loadConfig(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.Faults) != 1 || string(cfg.PsiphonConfig) != `{"foo": 1}` || cfg.CheckIn == nil {
+			t.Fatal("unexpected config", cfg)
+		}
+	})
+
+	t.Run("we reject invalid fault rules", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "config.json")
+		runtimex.Try0(os.WriteFile(filename, []byte(`{"faults": [{"path": ""}]}`), 0600))
+		if _, err := loadConfig(filename); !errors.Is(err, errInvalidFaultRule) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestMainWorkingAsIntended(t *testing.T) {
+	// let the kernel pick a random free port and use a temporary directory
+	*apiEndpoint = "127.0.0.1:0"
+	*dataDir = t.TempDir()
+
+	// run the main function in a background goroutine
+	go main()
+
+	// make sure the server is working
+	endpoint := <-srvAddr
+	resp, err := http.Get("http://" + endpoint + "/api/v1/test-helpers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatal("unexpected status code", resp.StatusCode)
+	}
+
+	// tell the server to shutdown and wait for it
+	sigs <- syscall.SIGINT
+	srvWg.Wait()
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"flag"
 	"net"
+	"net/http"
 	"sync"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 )
 
 var (
+	srvAddress  = "127.0.0.1"
 	srvCtx      context.Context
 	srvCancel   context.CancelFunc
 	srvWg       = new(sync.WaitGroup)
@@ -40,11 +42,15 @@ func handleConnection(ctx context.Context, conn net.Conn) {
 
 func listenTCP(ctx context.Context, port string) {
 	defer srvWg.Done()
-	address := net.JoinHostPort("127.0.0.1", port)
+	address := net.JoinHostPort(srvAddress, port)
 	listener, err := net.Listen("tcp", address)
 	runtimex.PanicOnError(err, "net.Listen failed")
 	go shutdown(ctx, listener)
 	srvTestChan <- port // send to channel to imply server will start listening on port
+	acceptLoop(ctx, listener, port)
+}
+
+func acceptLoop(ctx context.Context, listener net.Listener, port string) {
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
@@ -55,24 +61,85 @@ func listenTCP(ctx context.Context, port string) {
 	}
 }
 
+func listenUDP(ctx context.Context, port string) {
+	defer srvWg.Done()
+	address := net.JoinHostPort(srvAddress, port)
+	pconn, err := net.ListenPacket("udp", address)
+	runtimex.PanicOnError(err, "net.ListenPacket failed")
+	go shutdownPacketConn(ctx, pconn)
+	echoLoop(pconn, port)
+}
+
+func shutdownPacketConn(ctx context.Context, pconn net.PacketConn) {
+	<-ctx.Done()
+	_ = pconn.Close()
+}
+
+// echoLoop sends back each datagram it receives to its sender.
+func echoLoop(pconn net.PacketConn, port string) {
+	buffer := make([]byte, 1<<10)
+	for {
+		count, addr, err := pconn.ReadFrom(buffer)
+		if err != nil {
+			log.Infof("listener unable to read datagrams on port: %s", port)
+			return
+		}
+		_, _ = pconn.WriteTo(buffer[:count], addr)
+	}
+}
+
+func serveControl(ctx context.Context, address string, handler http.Handler) {
+	defer srvWg.Done()
+	srv := &http.Server{Addr: address, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	log.Infof("serving the control API at: %s", address)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Warnf("control API failed: %s", err.Error())
+	}
+}
+
 func main() {
 	logmap := map[bool]log.Level{
 		true:  log.DebugLevel,
 		false: log.InfoLevel,
 	}
+	address := flag.String("address", srvAddress, "Address where to listen for probes")
+	control := flag.String("control", "", "Optional address where to serve the API for opening ports (e.g., 127.0.0.1:8080)")
 	debug := flag.Bool("debug", false, "Toggle debug mode")
+	lifetime := flag.Duration("port-lifetime", time.Minute, "Minimum time ports opened through the control API stay open")
+	maxLifetime := flag.Duration("max-port-lifetime", 30*time.Minute, "Maximum time a client may ask to keep ports open")
+	maxPorts := flag.Int("max-ports", 1024, "Maximum number of ports the control API keeps open")
+	portSpec := flag.String("ports", "default", "Ports, port ranges and profiles to listen on at startup")
+	udp := flag.Bool("udp", false, "Also run UDP echo servers on the startup ports")
 	flag.Parse()
 	log.SetLevel(logmap[*debug])
+	srvAddress = *address
 	defer srvCancel()
-	ports := portfiltering.Ports
+	ports, err := portfiltering.ParsePortSet(*portSpec)
+	runtimex.PanicOnError(err, "portfiltering.ParsePortSet failed")
 	if srvTest {
 		ports = TestPorts
 	}
+	var listening []string
 	for _, port := range ports {
 		srvWg.Add(1)
 		ctx, cancel := context.WithCancel(srvCtx)
 		defer cancel()
 		go listenTCP(ctx, port)
+		listening = append(listening, "tcp/"+port)
+		if *udp {
+			srvWg.Add(1)
+			go listenUDP(ctx, port)
+			listening = append(listening, "udp/"+port)
+		}
+	}
+	if *control != "" {
+		srvWg.Add(1)
+		opener := newPortOpener(srvCtx, *lifetime, *maxLifetime, *maxPorts, listening...)
+		go serveControl(srvCtx, *control, opener)
 	}
 	<-srvCtx.Done()
 	srvWg.Wait() // wait for listeners on all ports to close
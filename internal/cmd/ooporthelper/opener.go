@@ -0,0 +1,192 @@
+package main
+
+//
+// Control API for opening ports on demand
+//
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/experiment/portfiltering"
+)
+
+// portOpener is an [http.Handler] that opens the ports requested using
+// [portfiltering.OpenPortsRequest] for a limited amount of time.
+type portOpener struct {
+	// ctx is the context bounding the lifetime of all ports.
+	ctx context.Context
+
+	// lifetime is the minimum time each port stays open.
+	lifetime time.Duration
+
+	// listening contains the ports we listen on since startup.
+	listening map[string]bool
+
+	// maxLifetime is the maximum time a client may ask us to keep ports open.
+	maxLifetime time.Duration
+
+	// maxPorts is the maximum number of ports we keep open.
+	maxPorts int
+
+	// mu protects active.
+	mu sync.Mutex
+
+	// active contains the currently open ports.
+	active map[string]*openPort
+}
+
+// openPort is a port opened through the control API.
+type openPort struct {
+	// expiry is when we close the port.
+	expiry time.Time
+
+	// timer closes the port on expiry.
+	timer *time.Timer
+}
+
+// newPortOpener creates a new [*portOpener]. The listening argument contains the
+// ports we listen on since startup using the "<network>/<port>" format.
+func newPortOpener(ctx context.Context, lifetime, maxLifetime time.Duration,
+	maxPorts int, listening ...string) *portOpener {
+	po := &portOpener{
+		ctx:         ctx,
+		lifetime:    lifetime,
+		listening:   map[string]bool{},
+		maxLifetime: maxLifetime,
+		maxPorts:    maxPorts,
+		mu:          sync.Mutex{},
+		active:      map[string]*openPort{},
+	}
+	for _, key := range listening {
+		po.listening[key] = true
+	}
+	return po
+}
+
+// maxRequestBodySize is the maximum size of the request body.
+const maxRequestBodySize = 1 << 20
+
+// ServeHTTP implements http.Handler.
+func (po *portOpener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var request portfiltering.OpenPortsRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	lifetime := po.lifetimeFor(&request)
+	response := &portfiltering.OpenPortsResponse{
+		Lifetime: lifetime.Milliseconds(),
+		TCP:      po.openMany("tcp", request.TCP, lifetime),
+		UDP:      po.openMany("udp", request.UDP, lifetime),
+	}
+	data, err = json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// lifetimeFor returns how long we keep open the ports of the given request, which
+// is the lifetime requested by the client bounded by our minimum and maximum.
+func (po *portOpener) lifetimeFor(request *portfiltering.OpenPortsRequest) time.Duration {
+	lifetime := time.Duration(request.Lifetime) * time.Millisecond
+	if lifetime < po.lifetime {
+		return po.lifetime
+	}
+	if lifetime > po.maxLifetime {
+		return po.maxLifetime
+	}
+	return lifetime
+}
+
+// openMany opens the given ports and returns those that are open.
+func (po *portOpener) openMany(network string, ports []string, lifetime time.Duration) []string {
+	out := []string{}
+	for _, port := range ports {
+		if po.open(network, port, lifetime) {
+			out = append(out, port)
+		}
+	}
+	return out
+}
+
+// open opens the given port for the given lifetime unless we have reached the
+// maximum number of open ports and returns whether the port is open. When the
+// port is already open, we extend its lifetime if needed.
+func (po *portOpener) open(network, port string, lifetime time.Duration) bool {
+	if value, err := strconv.Atoi(port); err != nil || value < 1 || value > 65535 {
+		return false
+	}
+	key := network + "/" + port
+	if po.listening[key] {
+		return true // we have been listening since startup
+	}
+	expiry := time.Now().Add(lifetime)
+	po.mu.Lock()
+	defer po.mu.Unlock()
+	if op := po.active[key]; op != nil {
+		if expiry.After(op.expiry) {
+			if !op.timer.Reset(lifetime) {
+				return false // the port is closing
+			}
+			op.expiry = expiry
+		}
+		return true
+	}
+	if len(po.active) >= po.maxPorts {
+		return false
+	}
+	ctx, cancel := context.WithCancel(po.ctx)
+	address := net.JoinHostPort(srvAddress, port)
+	switch network {
+	case "tcp":
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			log.Debugf("cannot open tcp port %s: %s", port, err.Error())
+			cancel()
+			return false
+		}
+		go shutdown(ctx, listener)
+		go acceptLoop(ctx, listener, port)
+	default:
+		pconn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			log.Debugf("cannot open udp port %s: %s", port, err.Error())
+			cancel()
+			return false
+		}
+		go shutdownPacketConn(ctx, pconn)
+		go echoLoop(pconn, port)
+	}
+	op := &openPort{expiry: expiry, timer: time.AfterFunc(lifetime, cancel)}
+	po.active[key] = op
+	go func() {
+		<-ctx.Done()
+		op.timer.Stop()
+		po.mu.Lock()
+		if po.active[key] == op {
+			delete(po.active, key)
+		}
+		po.mu.Unlock()
+	}()
+	return true
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/experiment/portfiltering"
+)
+
+// freePort returns a port that is likely free for the given network.
+func freePort(t *testing.T, network string) string {
+	var address string
+	switch network {
+	case "tcp":
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		address = listener.Addr().String()
+		listener.Close()
+	default:
+		pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		address = pconn.LocalAddr().String()
+		pconn.Close()
+	}
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return port
+}
+
+func openPorts(t *testing.T, po *portOpener, request *portfiltering.OpenPortsRequest) *portfiltering.OpenPortsResponse {
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	w := httptest.NewRecorder()
+	po.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatal("unexpected status code", w.Code)
+	}
+	var response portfiltering.OpenPortsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return &response
+}
+
+func TestPortOpener(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("opens TCP and UDP ports", func(t *testing.T) {
+		po := newPortOpener(ctx, time.Minute, time.Hour, 10)
+		tcpPort, udpPort := freePort(t, "tcp"), freePort(t, "udp")
+		response := openPorts(t, po, &portfiltering.OpenPortsRequest{
+			TCP: []string{tcpPort, "0", "https"},
+			UDP: []string{udpPort},
+		})
+		expect := &portfiltering.OpenPortsResponse{
+			Lifetime: time.Minute.Milliseconds(),
+			TCP:      []string{tcpPort},
+			UDP:      []string{udpPort},
+		}
+		if diff := cmp.Diff(expect, response); diff != "" {
+			t.Fatal(diff)
+		}
+
+		conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", tcpPort))
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+
+		conn, err = net.Dial("udp", net.JoinHostPort("127.0.0.1", udpPort))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		buffer := make([]byte, 16)
+		count, err := conn.Read(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buffer[:count]) != "ping" {
+			t.Fatal("unexpected echo reply")
+		}
+	})
+
+	t.Run("honours the maximum number of ports", func(t *testing.T) {
+		po := newPortOpener(ctx, time.Minute, time.Hour, 1)
+		first, second := freePort(t, "tcp"), freePort(t, "tcp")
+		response := openPorts(t, po, &portfiltering.OpenPortsRequest{
+			TCP: []string{first, second},
+		})
+		if diff := cmp.Diff([]string{first}, response.TCP); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("reports the ports we listen on since startup as open", func(t *testing.T) {
+		po := newPortOpener(ctx, time.Minute, time.Hour, 0, "tcp/80", "udp/443")
+		response := openPorts(t, po, &portfiltering.OpenPortsRequest{
+			TCP: []string{"80", "443"},
+			UDP: []string{"443"},
+		})
+		expect := &portfiltering.OpenPortsResponse{
+			Lifetime: time.Minute.Milliseconds(),
+			TCP:      []string{"80"},
+			UDP:      []string{"443"},
+		}
+		if diff := cmp.Diff(expect, response); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("sizes the lifetime to the request", func(t *testing.T) {
+		po := newPortOpener(ctx, time.Minute, time.Hour, 1)
+		type testcase struct {
+			request int64
+			expect  time.Duration
+		}
+		for _, tc := range []testcase{
+			{request: 0, expect: time.Minute},
+			{request: (2 * time.Minute).Milliseconds(), expect: 2 * time.Minute},
+			{request: (2 * time.Hour).Milliseconds(), expect: time.Hour},
+		} {
+			got := po.lifetimeFor(&portfiltering.OpenPortsRequest{Lifetime: tc.request})
+			if got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		}
+	})
+
+	t.Run("extends the lifetime of open ports", func(t *testing.T) {
+		po := newPortOpener(ctx, time.Minute, time.Hour, 1)
+		port := freePort(t, "tcp")
+		openPorts(t, po, &portfiltering.OpenPortsRequest{TCP: []string{port}})
+		po.mu.Lock()
+		first := po.active["tcp/"+port].expiry
+		po.mu.Unlock()
+		response := openPorts(t, po, &portfiltering.OpenPortsRequest{
+			Lifetime: (10 * time.Minute).Milliseconds(),
+			TCP:      []string{port},
+		})
+		if diff := cmp.Diff([]string{port}, response.TCP); diff != "" {
+			t.Fatal(diff)
+		}
+		po.mu.Lock()
+		second := po.active["tcp/"+port].expiry
+		po.mu.Unlock()
+		if !second.After(first.Add(8 * time.Minute)) {
+			t.Fatal("expected the lifetime to be extended")
+		}
+	})
+
+	t.Run("closes ports after their lifetime", func(t *testing.T) {
+		po := newPortOpener(ctx, 10*time.Millisecond, time.Hour, 1)
+		port := freePort(t, "tcp")
+		response := openPorts(t, po, &portfiltering.OpenPortsRequest{TCP: []string{port}})
+		if len(response.TCP) != 1 {
+			t.Fatal("expected the port to be open")
+		}
+		for {
+			po.mu.Lock()
+			count := len(po.active)
+			po.mu.Unlock()
+			if count == 0 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		po := newPortOpener(ctx, time.Minute, time.Hour, 1)
+
+		w := httptest.NewRecorder()
+		po.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Fatal("unexpected status code", w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		po.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("{"))))
+		if w.Code != http.StatusBadRequest {
+			t.Fatal("unexpected status code", w.Code)
+		}
+	})
+}
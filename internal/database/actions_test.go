@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -531,3 +533,117 @@ func TestUpdateDatabaseMeasurementWithSummaryKeys(t *testing.T) {
 		}
 	})
 }
+
+func TestUploadSucceededByID(t *testing.T) {
+	tmpdir := t.TempDir()
+	database, err := Open(tmpdir + "/db.sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := database.CreateNetwork(&locationInfo{countryCode: "IT", networkName: "Unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := database.CreateResult(tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msmt, err := database.CreateMeasurement(sql.NullString{}, "antani", tmpdir, 0, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UploadFailed(msmt, "mocked error"); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpdateUploadedStatus(result); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.UploadSucceededByID(msmt.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotMsmt model.DatabaseMeasurement
+	if err := database.Session().Collection("measurements").Find("measurement_id", msmt.ID).One(&gotMsmt); err != nil {
+		t.Fatal(err)
+	}
+	if !gotMsmt.IsUploaded || gotMsmt.TestName != "antani" {
+		t.Fatal("unexpected measurement", gotMsmt)
+	}
+	var gotResult model.DatabaseResult
+	if err := database.Session().Collection("results").Find("result_id", result.ID).One(&gotResult); err != nil {
+		t.Fatal(err)
+	}
+	if !gotResult.IsUploaded {
+		t.Fatal("expected the result to be uploaded")
+	}
+
+	if err := database.UploadSucceededByID(msmt.ID + 1); err == nil {
+		t.Fatal("expected an error for a nonexistent measurement")
+	}
+}
+
+func TestResumeResult(t *testing.T) {
+	tmpdir := t.TempDir()
+	database, err := Open(tmpdir + "/db.sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := database.CreateNetwork(&locationInfo{countryCode: "IT", networkName: "Unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := database.CreateResult(tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Finished(result); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(result.MeasurementDir); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := database.ResumeResult(result.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.ID != result.ID || resumed.IsDone || resumed.Runtime != 0 {
+		t.Fatal("unexpected resumed result", resumed)
+	}
+	if _, err := os.Stat(resumed.MeasurementDir); err != nil {
+		t.Fatal("expected the measurement directory to exist", err)
+	}
+	if err := database.Finished(resumed); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := database.ResumeResult(result.ID + 1); err == nil {
+		t.Fatal("expected an error for a nonexistent result")
+	}
+}
+
+func TestCreateResultWithArbitraryName(t *testing.T) {
+	tmpdir := t.TempDir()
+	database, err := Open(tmpdir + "/db.sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := database.CreateNetwork(&locationInfo{countryCode: "IT", networkName: "Unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := database.CreateResult(tmpdir, "My link: ../test", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TestGroupName != "My link: ../test" {
+		t.Fatal("unexpected test group name", result.TestGroupName)
+	}
+	if filepath.Dir(result.MeasurementDir) != filepath.Join(tmpdir, "msmts") {
+		t.Fatal("unexpected measurement dir", result.MeasurementDir)
+	}
+	if !strings.HasPrefix(filepath.Base(result.MeasurementDir), "My_link_____test-") {
+		t.Fatal("unexpected measurement dir", result.MeasurementDir)
+	}
+}
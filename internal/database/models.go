@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"os"
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -66,3 +67,41 @@ func (d *Database) UploadSucceeded(msmt *model.DatabaseMeasurement) error {
 	}
 	return nil
 }
+
+// UploadSucceededByID marks the measurement with the given ID as uploaded and
+// updates the uploaded status of the corresponding result. We use this method
+// when we submit a measurement after the run that created it has finished.
+func (d *Database) UploadSucceededByID(msmtID int64) error {
+	var msmt model.DatabaseMeasurement
+	if err := d.sess.Collection("measurements").Find("measurement_id", msmtID).One(&msmt); err != nil {
+		return errors.Wrap(err, "loading measurement")
+	}
+	if err := d.UploadSucceeded(&msmt); err != nil {
+		return err
+	}
+	var result model.DatabaseResult
+	if err := d.sess.Collection("results").Find("result_id", msmt.ResultID).One(&result); err != nil {
+		return errors.Wrap(err, "loading result")
+	}
+	return d.UpdateUploadedStatus(&result)
+}
+
+// ResumeResult loads the result with the given ID and marks it as not done, such
+// that we can add measurements to it when resuming an interrupted run. We recreate
+// the measurement directory, which we remove when it is empty at the end of a run.
+func (d *Database) ResumeResult(resultID int64) (*model.DatabaseResult, error) {
+	var result model.DatabaseResult
+	res := d.sess.Collection("results").Find("result_id", resultID)
+	if err := res.One(&result); err != nil {
+		return nil, errors.Wrap(err, "loading result")
+	}
+	if err := os.MkdirAll(result.MeasurementDir, 0700); err != nil {
+		return nil, err
+	}
+	result.IsDone = false
+	result.Runtime = 0
+	if err := res.Update(result); err != nil {
+		return nil, errors.Wrap(err, "updating resumed result")
+	}
+	return &result, nil
+}
@@ -5,16 +5,29 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
 )
 
 // resultTimestamp is a windows friendly timestamp
 const resultTimestamp = "2006-01-02T150405.999999999Z0700"
 
+// resultsDirName returns a file name safe version of the given result name, which
+// may be an arbitrary string (e.g., the name of an OONI Run link).
+func resultsDirName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
 // makeResultsDir creates and returns a directory for the result
 func makeResultsDir(home string, name string, ts time.Time) (string, error) {
 	p := filepath.Join(home, "msmts",
-		fmt.Sprintf("%s-%s", name, ts.Format(resultTimestamp)))
+		fmt.Sprintf("%s-%s", resultsDirName(name), ts.Format(resultTimestamp)))
 
 	// If the path already exists, this is a problem. It should not clash, because
 	// we are using nanosecond precision for the starttime.
@@ -0,0 +1,123 @@
+package engine
+
+//
+// Diagnosing the engine network, for attaching to bug reports
+//
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/enginenetx"
+	"github.com/ooni/probe-cli/v3/internal/engineresolver"
+)
+
+// NetworkDiagnosis is the report produced by [*Session.DiagnoseNetwork].
+type NetworkDiagnosis struct {
+	// DNS contains the results of resolving api.ooni.io with each resolver.
+	DNS []*engineresolver.Diagnosis `json:"dns"`
+
+	// Platform is the platform we're running on.
+	Platform string `json:"platform"`
+
+	// ProxyScheme is the scheme of the proxy we're using, if any. We do not
+	// include the whole proxy URL because it may contain credentials.
+	ProxyScheme string `json:"proxy_scheme"`
+
+	// Runtime is the time it took to diagnose the network.
+	Runtime time.Duration `json:"runtime"`
+
+	// SoftwareName is the name of the application.
+	SoftwareName string `json:"software_name"`
+
+	// SoftwareVersion is the version of the application.
+	SoftwareVersion string `json:"software_version"`
+
+	// StartTime is the time when we started diagnosing the network.
+	StartTime time.Time `json:"start_time"`
+
+	// TLS contains the stats and the results of trying the TLS dialing tactics.
+	TLS *enginenetx.DiagnoseReport `json:"tls"`
+}
+
+// DiagnoseNetwork diagnoses the network the engine uses to communicate with the OONI
+// backend and the test helpers. It reports which resolvers work, the stats about the
+// TLS dialing tactics, and which tactics (bridges, SNIs, addresses resolved using the
+// DNS) work right now. This method does not contact the OONI backend using the
+// probe services API, so it works also when we cannot reach the backend.
+func (s *Session) DiagnoseNetwork(ctx context.Context) *NetworkDiagnosis {
+	t0 := time.Now()
+	report := &NetworkDiagnosis{
+		Platform:        s.Platform(),
+		SoftwareName:    s.softwareName,
+		SoftwareVersion: s.softwareVersion,
+		StartTime:       t0,
+	}
+	if s.proxyURL != nil {
+		report.ProxyScheme = s.proxyURL.Scheme
+	}
+	s.logger.Info("diagnose: resolving api.ooni.io using each resolver")
+	report.DNS = s.resolver.Diagnose(ctx, "api.ooni.io")
+	s.logger.Info("diagnose: trying the TLS dialing tactics; please, be patient")
+	report.TLS = enginenetx.Diagnose(ctx, &enginenetx.DiagnoseConfig{
+		KVStore:  s.kvStore,
+		Logger:   s.logger,
+		Resolver: s.resolver,
+	})
+	report.Runtime = time.Since(t0)
+	return report
+}
+
+// Summary returns a human readable summary of the diagnosis.
+func (d *NetworkDiagnosis) Summary() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "DNS resolvers (resolving api.ooni.io):\n")
+	for _, entry := range d.DNS {
+		switch {
+		case entry.Skipped:
+			fmt.Fprintf(&sb, "  %-48s skipped (cannot use it with a proxy)\n", entry.URL)
+		case entry.Failure != nil:
+			fmt.Fprintf(&sb, "  %-48s %s\n", entry.URL, *entry.Failure)
+		default:
+			fmt.Fprintf(&sb, "  %-48s ok %v\n", entry.URL, entry.Addresses)
+		}
+	}
+
+	fmt.Fprintf(&sb, "\nTLS dialing tactics (working/tried):\n")
+	type summaryKey struct{ endpoint, policy string }
+	var keys []summaryKey
+	working, tried := map[summaryKey][]string{}, map[summaryKey]int{}
+	for _, entry := range d.TLS.Tactics {
+		key := summaryKey{entry.DomainEndpoint, entry.Policy}
+		if _, found := tried[key]; !found {
+			keys = append(keys, key)
+		}
+		tried[key]++
+		if entry.Failure == nil {
+			tactic := fmt.Sprintf("%s sni=%s", entry.Address, entry.SNI)
+			if entry.ECH {
+				tactic += " ech=true"
+			}
+			if entry.Proxy != "" {
+				tactic += fmt.Sprintf(" proxy=%s", entry.Proxy)
+			}
+			working[key] = append(working[key], tactic)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].endpoint < keys[j].endpoint
+	})
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "  %-36s %-12s %d/%d\n", key.endpoint, key.policy, len(working[key]), tried[key])
+		for _, tactic := range working[key] {
+			fmt.Fprintf(&sb, "    %s\n", tactic)
+		}
+	}
+
+	fmt.Fprintf(&sb, "\nPersisted TLS dialing stats: %d tactics\n", len(d.TLS.Stats))
+	return sb.String()
+}
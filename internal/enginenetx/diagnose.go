@@ -0,0 +1,396 @@
+package enginenetx
+
+//
+// Diagnosing which tactics work, for attaching to bug reports
+//
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// DiagnoseConfig contains config for [Diagnose].
+//
+// The zero value is invalid; please, init the MANDATORY fields.
+type DiagnoseConfig struct {
+	// Endpoints contains the OPTIONAL domain endpoints (e.g., "api.ooni.io:443") to
+	// diagnose. When empty, we diagnose api.ooni.io and the test helpers.
+	Endpoints []string
+
+	// KVStore is the MANDATORY key-value store containing the stats and
+	// the user policy. We do not modify the key-value store.
+	KVStore model.KeyValueStore
+
+	// Logger is the MANDATORY logger.
+	Logger model.Logger
+
+	// MaxTacticsPerEndpoint is the OPTIONAL maximum number of tactics to try
+	// for each policy and domain endpoint. Because the bridges and the test
+	// helpers policies emit SNIs in random order, trying just some of them
+	// gives us an idea of which SNIs work. When zero, we use 16.
+	MaxTacticsPerEndpoint int
+
+	// Resolver is the MANDATORY resolver used by the DNS policy.
+	Resolver model.Resolver
+
+	// Timeout is the OPTIONAL timeout for trying each tactic. When zero,
+	// we use a ten seconds timeout.
+	Timeout time.Duration
+}
+
+// DiagnoseReport is the report produced by [Diagnose].
+type DiagnoseReport struct {
+	// Stats contains the persisted stats about the tactics.
+	Stats []*DiagnoseStats `json:"stats"`
+
+	// Tactics contains the results of trying each policy's tactics.
+	Tactics []*DiagnoseTactic `json:"tactics"`
+}
+
+// DiagnoseStats contains the persisted stats about a tactic.
+type DiagnoseStats struct {
+	// Address is the IPv4/IPv6 address for dialing.
+	Address string `json:"address"`
+
+	// CountStarted counts the number of operations we started.
+	CountStarted int64 `json:"count_started"`
+
+	// CountSuccess counts the number of successes.
+	CountSuccess int64 `json:"count_success"`
+
+	// CountTCPConnectError counts the number of TCP connect errors.
+	CountTCPConnectError int64 `json:"count_tcp_connect_error"`
+
+	// CountTLSHandshakeError counts the number of TLS handshake errors.
+	CountTLSHandshakeError int64 `json:"count_tls_handshake_error"`
+
+	// CountTLSVerificationError counts the number of TLS verification errors.
+	CountTLSVerificationError int64 `json:"count_tls_verification_error"`
+
+	// DomainEndpoint is the domain endpoint (e.g., "api.ooni.io:443").
+	DomainEndpoint string `json:"domain_endpoint"`
+
+	// ECH indicates whether the tactic uses Encrypted Client Hello.
+	ECH bool `json:"ech"`
+
+	// HistoTCPConnectError contains an histogram of TCP connect errors.
+	HistoTCPConnectError map[string]int64 `json:"histo_tcp_connect_error"`
+
+	// HistoTLSHandshakeError contains an histogram of TLS handshake errors.
+	HistoTLSHandshakeError map[string]int64 `json:"histo_tls_handshake_error"`
+
+	// HistoTLSVerificationError contains an histogram of TLS verification errors.
+	HistoTLSVerificationError map[string]int64 `json:"histo_tls_verification_error"`
+
+	// LastUpdated is the last time we updated the stats.
+	LastUpdated time.Time `json:"last_updated"`
+
+	// Port is the TCP port for dialing.
+	Port string `json:"port"`
+
+	// Proxy is the URL of the proxy without credentials or empty
+	// if the tactic does not dial through a proxy.
+	Proxy string `json:"proxy"`
+
+	// SNI is the TLS ServerName to send over the wire.
+	SNI string `json:"sni"`
+
+	// SuccessRate is the ratio between CountSuccess and CountStarted.
+	SuccessRate float64 `json:"success_rate"`
+
+	// VerifyHostname is the hostname used for X.509 certificate verification.
+	VerifyHostname string `json:"verify_hostname"`
+}
+
+// DiagnoseTactic is the result of trying a tactic.
+type DiagnoseTactic struct {
+	// Address is the IPv4/IPv6 address for dialing.
+	Address string `json:"address"`
+
+	// DomainEndpoint is the domain endpoint (e.g., "api.ooni.io:443").
+	DomainEndpoint string `json:"domain_endpoint"`
+
+	// ECH indicates whether the tactic uses Encrypted Client Hello.
+	ECH bool `json:"ech"`
+
+	// Failure is the failure or nil on success.
+	Failure *string `json:"failure"`
+
+	// Policy is the name of the policy that emitted the tactic, which is one
+	// of "user", "dns", "ech", "testhelpers", "bridges", and "stats".
+	Policy string `json:"policy"`
+
+	// Port is the TCP port for dialing.
+	Port string `json:"port"`
+
+	// Proxy is the URL of the proxy without credentials or empty
+	// if the tactic does not dial through a proxy.
+	Proxy string `json:"proxy"`
+
+	// Runtime is the time it took to establish the TLS connection.
+	Runtime time.Duration `json:"runtime"`
+
+	// SNI is the TLS ServerName to send over the wire.
+	SNI string `json:"sni"`
+
+	// VerifyHostname is the hostname used for X.509 certificate verification.
+	VerifyHostname string `json:"verify_hostname"`
+
+	// echConfigList is the ECH config list or nil.
+	echConfigList []byte
+
+	// proxyURL is the URL of the proxy including the credentials.
+	proxyURL string
+}
+
+// Diagnose dumps the persisted stats and tries the tactics emitted by each policy
+// for each endpoint, returning a report saying which tactics work. This function
+// does not update the stats, so it does not change how the engine dials.
+func Diagnose(ctx context.Context, config *DiagnoseConfig) *DiagnoseReport {
+	endpoints := config.Endpoints
+	if len(endpoints) <= 0 {
+		endpoints = append(endpoints, net.JoinHostPort("api.ooni.io", "443"))
+		for _, domain := range testHelpersDomains {
+			endpoints = append(endpoints, net.JoinHostPort(domain, "443"))
+		}
+	}
+	maxTactics := config.MaxTacticsPerEndpoint
+	if maxTactics <= 0 {
+		maxTactics = 16
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	// load the stats without starting the background trimmer and without saving
+	// them when done, since we're just going to read them
+	container, err := loadStatsContainer(config.KVStore)
+	if err != nil {
+		container = newStatsContainer()
+	}
+	stats := &statsManager{container: container}
+
+	// collect the tactics emitted by each policy for each endpoint
+	policies := diagnosePolicies(config, stats)
+	var todo []*DiagnoseTactic
+	for _, endpoint := range endpoints {
+		domain, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			config.Logger.Warnf("diagnose: invalid endpoint %s: %s", endpoint, err.Error())
+			continue
+		}
+		for _, policy := range policies {
+			tactics := filterOnlyKeepUniqueTactics(filterOutNilTactics(policy.policy.LookupTactics(ctx, domain, port)))
+			var count int
+			for tactic := range tactics {
+				if count >= maxTactics {
+					continue // drain the channel
+				}
+				count++
+				todo = append(todo, &DiagnoseTactic{
+					Address:        tactic.Address,
+					DomainEndpoint: endpoint,
+					ECH:            len(tactic.ECHConfigList) > 0,
+					Policy:         policy.name,
+					Port:           tactic.Port,
+					Proxy:          proxyRedactURL(tactic.Proxy),
+					SNI:            tactic.SNI,
+					VerifyHostname: tactic.VerifyHostname,
+					echConfigList:  tactic.ECHConfigList,
+					proxyURL:       tactic.Proxy,
+				})
+			}
+		}
+	}
+
+	// try the tactics in parallel
+	diagnoseTryTactics(ctx, config.Logger, config.Resolver, timeout, todo)
+
+	return &DiagnoseReport{
+		Stats:   diagnoseStats(container),
+		Tactics: todo,
+	}
+}
+
+// diagnosePolicy is a named policy we diagnose.
+type diagnosePolicy struct {
+	name   string
+	policy httpsDialerPolicy
+}
+
+// diagnosePolicies returns the policies to diagnose, which are the building
+// blocks of the policy returned by [newHTTPSDialerPolicy].
+func diagnosePolicies(config *DiagnoseConfig, stats *statsManager) (out []diagnosePolicy) {
+	if user, err := newUserPolicyV2(config.KVStore); err == nil {
+		out = append(out, diagnosePolicy{"user", user})
+	}
+	dns := &dnsPolicy{config.Logger, config.Resolver}
+	return append(out,
+		diagnosePolicy{"dns", dns},
+		diagnosePolicy{"ech", &echPolicy{config.Logger, config.Resolver}},
+		diagnosePolicy{"testhelpers", &diagnoseTestHelpersPolicy{dns}},
+		diagnosePolicy{"bridges", &bridgesPolicyV2{}},
+		diagnosePolicy{"stats", &statsPolicyV2{Stats: stats}},
+	)
+}
+
+// diagnoseTestHelpersPolicy is like [*testHelpersPolicy] but only emits the
+// tactics with extra SNIs, since the DNS policy already emits the other ones.
+type diagnoseTestHelpersPolicy struct {
+	Child httpsDialerPolicy
+}
+
+var _ httpsDialerPolicy = &diagnoseTestHelpersPolicy{}
+
+// LookupTactics implements httpsDialerPolicy.
+func (p *diagnoseTestHelpersPolicy) LookupTactics(ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
+	out := make(chan *httpsDialerTactic)
+	go func() {
+		defer close(out)
+		for tactic := range (&testHelpersPolicy{p.Child}).LookupTactics(ctx, domain, port) {
+			if tactic.SNI != tactic.VerifyHostname {
+				out <- tactic
+			}
+		}
+	}()
+	return out
+}
+
+// diagnoseSingleTacticPolicy is a policy emitting a single tactic.
+type diagnoseSingleTacticPolicy struct {
+	Tactic *httpsDialerTactic
+}
+
+var _ httpsDialerPolicy = &diagnoseSingleTacticPolicy{}
+
+// LookupTactics implements httpsDialerPolicy.
+func (p *diagnoseSingleTacticPolicy) LookupTactics(ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
+	return streamTacticsFromSlice([]*httpsDialerTactic{p.Tactic})
+}
+
+// diagnoseTryTactics tries the given tactics in parallel and fills their results. When
+// several policies emit the same tactic, we only try it once.
+func diagnoseTryTactics(ctx context.Context,
+	logger model.Logger, resolver model.Resolver, timeout time.Duration, tactics []*DiagnoseTactic) {
+	byKey := map[string][]*DiagnoseTactic{}
+	var keys []string
+	for _, entry := range tactics {
+		key := entry.tactic().tacticSummaryKey()
+		if _, found := byKey[key]; !found {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], entry)
+	}
+
+	const parallelism = 16
+	sema := make(chan any, parallelism)
+	wg := &sync.WaitGroup{}
+	for _, key := range keys {
+		entries := byKey[key]
+		sema <- true
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+			failure, runtime := diagnoseTryTactic(ctx, logger, resolver, timeout, entries[0].tactic())
+			for _, entry := range entries {
+				entry.Failure, entry.Runtime = failure, runtime
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// diagnoseTryTactic tries to establish a TLS connection using the given tactic.
+func diagnoseTryTactic(ctx context.Context,
+	logger model.Logger, resolver model.Resolver, timeout time.Duration, tactic *httpsDialerTactic) (*string, time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	dialer := newHTTPSDialer(
+		logger,
+		&netxlite.Netx{Underlying: nil}, // nil means using netxlite's singleton
+		&diagnoseSingleTacticPolicy{tactic},
+		resolver,
+		&nullStatsManager{},
+	)
+	t0 := time.Now()
+	conn, err := dialer.DialTLSContext(ctx, "tcp", net.JoinHostPort(tactic.VerifyHostname, tactic.Port))
+	runtime := time.Since(t0)
+	if err != nil {
+		failure := err.Error()
+		return &failure, runtime
+	}
+	conn.Close()
+	return nil, runtime
+}
+
+// tactic returns the [*httpsDialerTactic] corresponding to the entry.
+func (dt *DiagnoseTactic) tactic() *httpsDialerTactic {
+	return &httpsDialerTactic{
+		Address:        dt.Address,
+		ECHConfigList:  dt.echConfigList,
+		InitialDelay:   0,
+		Port:           dt.Port,
+		Proxy:          dt.proxyURL,
+		SNI:            dt.SNI,
+		VerifyHostname: dt.VerifyHostname,
+	}
+}
+
+// diagnoseStats converts the stats container to a list of [*DiagnoseStats]
+// sorted by domain endpoint and by descending success rate.
+func diagnoseStats(container *statsContainer) (out []*DiagnoseStats) {
+	var domainEpnts []string
+	for domainEpnt := range container.DomainEndpoints {
+		domainEpnts = append(domainEpnts, domainEpnt)
+	}
+	sort.Strings(domainEpnts)
+	for _, domainEpnt := range domainEpnts {
+		// sort the keys first such that ties are always resolved in the same way
+		var keys []string
+		for key := range container.DomainEndpoints[domainEpnt].Tactics {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var input []*statsTactic
+		for _, key := range keys {
+			input = append(input, container.DomainEndpoints[domainEpnt].Tactics[key])
+		}
+		tactics := statsDefensivelySortTacticsByDescendingSuccessRateWithAcceptPredicate(
+			input, func(st *statsTactic) bool {
+				return true // the sorting function already removes malformed entries
+			},
+		)
+		for _, st := range tactics {
+			out = append(out, &DiagnoseStats{
+				Address:                   st.Tactic.Address,
+				CountStarted:              st.CountStarted,
+				CountSuccess:              st.CountSuccess,
+				CountTCPConnectError:      st.CountTCPConnectError,
+				CountTLSHandshakeError:    st.CountTLSHandshakeError,
+				CountTLSVerificationError: st.CountTLSVerificationError,
+				DomainEndpoint:            domainEpnt,
+				ECH:                       len(st.Tactic.ECHConfigList) > 0,
+				HistoTCPConnectError:      st.HistoTCPConnectError,
+				HistoTLSHandshakeError:    st.HistoTLSHandshakeError,
+				HistoTLSVerificationError: st.HistoTLSVerificationError,
+				LastUpdated:               st.LastUpdated,
+				Port:                      st.Tactic.Port,
+				Proxy:                     st.Tactic.Proxy,
+				SNI:                       st.Tactic.SNI,
+				SuccessRate:               statsNilSafeSuccessRate(st),
+				VerifyHostname:            st.Tactic.VerifyHostname,
+			})
+		}
+	}
+	return
+}
@@ -0,0 +1,89 @@
+package enginenetx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+func TestDiagnose(t *testing.T) {
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+
+	// seed the stats with a tactic using a custom SNI that worked in the past
+	container := newStatsContainer()
+	goodTactic := &httpsDialerTactic{
+		Address:        netemx.AddressApiOONIIo,
+		Port:           "443",
+		SNI:            "www.repubblica.it",
+		VerifyHostname: "api.ooni.io",
+	}
+	container.SetStatsTacticLocked(goodTactic, &statsTactic{
+		CountStarted: 4,
+		CountSuccess: 3,
+		LastUpdated:  time.Now().Add(-time.Minute),
+		Tactic:       goodTactic,
+	})
+	kvStore := &kvstore.Memory{}
+	stored := runtimex.Try1(json.Marshal(container))
+	if err := kvStore.Set(statsKey, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	env.Do(func() {
+		netx := &netxlite.Netx{}
+		report := Diagnose(context.Background(), &DiagnoseConfig{
+			Endpoints:             []string{"api.ooni.io:443"},
+			KVStore:               kvStore,
+			Logger:                log.Log,
+			MaxTacticsPerEndpoint: 2,
+			Resolver:              netx.NewStdlibResolver(log.Log),
+			Timeout:               time.Second,
+		})
+
+		// make sure we dumped the stats
+		if len(report.Stats) != 1 {
+			t.Fatal("unexpected stats", report.Stats)
+		}
+		if stats := report.Stats[0]; stats.SNI != "www.repubblica.it" || stats.SuccessRate != 0.75 {
+			t.Fatal("unexpected stats entry", stats)
+		}
+
+		// make sure we tried the tactics of each policy
+		results := map[string][]*DiagnoseTactic{}
+		for _, entry := range report.Tactics {
+			results[entry.Policy] = append(results[entry.Policy], entry)
+		}
+		if entries := results["dns"]; len(entries) != 1 || entries[0].Failure != nil {
+			t.Fatal("unexpected dns results", entries)
+		}
+		if entries := results["stats"]; len(entries) != 1 || entries[0].Failure != nil {
+			t.Fatal("unexpected stats results", entries)
+		}
+		if entries := results["bridges"]; len(entries) != 2 {
+			t.Fatal("unexpected bridges results", entries)
+		}
+		for _, entry := range results["bridges"] {
+			// the bridge address is the address of api.ooni.io in the scenario
+			if entry.Failure != nil {
+				t.Fatal("unexpected bridges failure", *entry.Failure)
+			}
+		}
+		if entries := results["testhelpers"]; len(entries) != 0 {
+			t.Fatal("api.ooni.io is not a test helper", entries)
+		}
+	})
+
+	// make sure we did not modify the stats
+	if data, err := kvStore.Get(statsKey); err != nil || !bytes.Equal(data, stored) {
+		t.Fatal("the stats changed", err)
+	}
+}
@@ -0,0 +1,91 @@
+package enginenetx
+
+//
+// HTTPS dialing policy where we conceal the domain using
+// Encrypted Client Hello (ECH) when the domain supports it
+//
+
+import (
+	"context"
+	"net"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// echPolicy is a TLS dialing policy emitting tactics using Encrypted Client
+// Hello when the domain publishes ECH configs inside its HTTPS DNS record.
+//
+// Using ECH is a cleaner way to conceal the domain than using an alternative
+// SNI, because the outer ClientHello contains the public name of the ECH config,
+// while the encrypted inner ClientHello contains the real domain.
+//
+// The zero value is invalid; please, init all MANDATORY fields.
+type echPolicy struct {
+	// Logger is the MANDATORY logger.
+	Logger model.Logger
+
+	// Resolver is the MANDATORY resolver, which SHOULD be able to
+	// perform HTTPS lookups (e.g., using DNS-over-HTTPS).
+	Resolver model.Resolver
+}
+
+var _ httpsDialerPolicy = &echPolicy{}
+
+// LookupTactics implements httpsDialerPolicy.
+func (p *echPolicy) LookupTactics(
+	ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
+	out := make(chan *httpsDialerTactic)
+
+	go func() {
+		// make sure we close the output channel when done
+		// so the reader knows that we're done
+		defer close(out)
+
+		// Do not even start the DNS lookup if the context has already been canceled, which
+		// happens if some policy running before us had successfully connected
+		if err := ctx.Err(); err != nil {
+			p.Logger.Debugf("echPolicy: LookupTactics: %s", err.Error())
+			return
+		}
+
+		// there are no HTTPS records for IP addresses
+		if net.ParseIP(domain) != nil {
+			return
+		}
+
+		// obtain the ECH config list from the HTTPS record
+		ol := logx.NewOperationLogger(p.Logger, "echPolicy: LookupHTTPS %s", domain)
+		svc, err := p.Resolver.LookupHTTPS(ctx, domain)
+		ol.Stop(err)
+		if err != nil || len(svc.Ech) <= 0 {
+			return
+		}
+
+		// prefer the address hints and otherwise resolve the domain
+		addrs := append(append([]string{}, svc.IPv4...), svc.IPv6...)
+		if len(addrs) <= 0 {
+			addrs, err = p.Resolver.LookupHost(ctx, domain)
+			if err != nil {
+				p.Logger.Warnf("echPolicy: LookupHost: %s", err.Error())
+				return
+			}
+		}
+
+		// The tactics we generate here have SNI == VerifyHostname == domain and
+		// the SNI is only visible inside the encrypted inner ClientHello
+		for _, addr := range addrs {
+			tactic := &httpsDialerTactic{
+				Address:        addr,
+				ECHConfigList:  svc.Ech,
+				InitialDelay:   0, // set when dialing
+				Port:           port,
+				SNI:            domain,
+				VerifyHostname: domain,
+			}
+			out <- tactic
+		}
+	}()
+
+	return out
+}
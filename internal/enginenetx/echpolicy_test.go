@@ -0,0 +1,241 @@
+package enginenetx
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func TestECHPolicy(t *testing.T) {
+	// collect collects the tactics emitted by the policy
+	collect := func(policy httpsDialerPolicy, domain string) (out []*httpsDialerTactic) {
+		for tactic := range policy.LookupTactics(context.Background(), domain, "443") {
+			out = append(out, tactic)
+		}
+		return
+	}
+
+	t.Run("we emit nothing for IP addresses", func(t *testing.T) {
+		policy := &echPolicy{
+			Logger:   model.DiscardLogger,
+			Resolver: &mocks.Resolver{}, // empty so we crash if we hit the resolver
+		}
+		if tactics := collect(policy, "130.192.91.211"); len(tactics) != 0 {
+			t.Fatal("expected no tactics", tactics)
+		}
+	})
+
+	t.Run("we emit nothing when the HTTPS lookup fails", func(t *testing.T) {
+		policy := &echPolicy{
+			Logger: model.DiscardLogger,
+			Resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
+			},
+		}
+		if tactics := collect(policy, "api.ooni.io"); len(tactics) != 0 {
+			t.Fatal("expected no tactics", tactics)
+		}
+	})
+
+	t.Run("we emit nothing without ECH configs", func(t *testing.T) {
+		policy := &echPolicy{
+			Logger: model.DiscardLogger,
+			Resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return &model.HTTPSSvc{ALPN: []string{"h2"}, IPv4: []string{"130.192.91.211"}}, nil
+				},
+			},
+		}
+		if tactics := collect(policy, "api.ooni.io"); len(tactics) != 0 {
+			t.Fatal("expected no tactics", tactics)
+		}
+	})
+
+	t.Run("we use the address hints", func(t *testing.T) {
+		policy := &echPolicy{
+			Logger: model.DiscardLogger,
+			Resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					svc := &model.HTTPSSvc{
+						Ech:  []byte{1, 2, 3},
+						IPv4: []string{"130.192.91.211"},
+						IPv6: []string{"2001:db8::1"},
+					}
+					return svc, nil
+				},
+			},
+		}
+		expect := []*httpsDialerTactic{{
+			Address:        "130.192.91.211",
+			ECHConfigList:  []byte{1, 2, 3},
+			Port:           "443",
+			SNI:            "api.ooni.io",
+			VerifyHostname: "api.ooni.io",
+		}, {
+			Address:        "2001:db8::1",
+			ECHConfigList:  []byte{1, 2, 3},
+			Port:           "443",
+			SNI:            "api.ooni.io",
+			VerifyHostname: "api.ooni.io",
+		}}
+		if diff := cmp.Diff(expect, collect(policy, "api.ooni.io")); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we resolve the domain without address hints", func(t *testing.T) {
+		policy := &echPolicy{
+			Logger: model.DiscardLogger,
+			Resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return &model.HTTPSSvc{Ech: []byte{1, 2, 3}}, nil
+				},
+				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+					return []string{"130.192.91.231"}, nil
+				},
+			},
+		}
+		tactics := collect(policy, "api.ooni.io")
+		if len(tactics) != 1 || tactics[0].Address != "130.192.91.231" {
+			t.Fatal("unexpected tactics", tactics)
+		}
+	})
+}
+
+// echTestNewKeys generates an ECH config list and the corresponding server
+// keys using the given public name for the outer ClientHello.
+func echTestNewKeys(publicName string) ([]byte, []tls.EncryptedClientHelloKey) {
+	privateKey := runtimex.Try1(ecdh.X25519().GenerateKey(rand.Reader))
+
+	// See https://www.ietf.org/archive/id/draft-ietf-tls-esni-22.html#name-encrypted-clienthello-confi
+	var config cryptobyte.Builder
+	config.AddUint16(0xfe0d) // version
+	config.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(1)       // config_id
+		b.AddUint16(0x0020) // DHKEM(X25519, HKDF-SHA256)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(privateKey.PublicKey().Bytes())
+		})
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x0001) // HKDF-SHA256
+			b.AddUint16(0x0001) // AES-128-GCM
+		})
+		b.AddUint8(0) // maximum_name_length
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes([]byte(publicName))
+		})
+		b.AddUint16(0) // extensions
+	})
+	configBytes := config.BytesOrPanic()
+
+	var list cryptobyte.Builder
+	list.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(configBytes)
+	})
+
+	keys := []tls.EncryptedClientHelloKey{{
+		Config:      configBytes,
+		PrivateKey:  privateKey.Bytes(),
+		SendAsRetry: true,
+	}}
+	return list.BytesOrPanic(), keys
+}
+
+// QA using the host network
+func TestHTTPSDialerWithECHTactic(t *testing.T) {
+	ca := netem.MustNewCA()
+	configList, keys := echTestNewKeys("public.example.com")
+
+	// create a server that only accepts TLS v1.3 and ECH
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.TLS.ECHAccepted {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = ca.MustNewServerTLSConfig("server.local", "public.example.com")
+	server.TLS.EncryptedClientHelloKeys = keys
+	server.TLS.MinVersion = tls.VersionTLS13
+	server.StartTLS()
+	defer server.Close()
+	serverURL := runtimex.Try1(url.Parse(server.URL))
+
+	policy := &echPolicy{
+		Logger: log.Log,
+		Resolver: &mocks.Resolver{
+			MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+				return &model.HTTPSSvc{Ech: configList, IPv4: []string{"127.0.0.1"}}, nil
+			},
+		},
+	}
+
+	kvStore := &kvstore.Memory{}
+	stats := newStatsManager(kvStore, log.Log, time.Minute)
+	defer stats.Close()
+
+	tproxy := &netxlite.DefaultTProxy{}
+	httpsDialer := newHTTPSDialer(
+		log.Log,
+		&netxlite.Netx{Underlying: &mocks.UnderlyingNetwork{
+			MockDefaultCertPool: func() *x509.CertPool {
+				return ca.DefaultCertPool() // just override the CA
+			},
+			MockDialTimeout:                tproxy.DialTimeout,
+			MockDialContext:                tproxy.DialContext,
+			MockListenTCP:                  tproxy.ListenTCP,
+			MockListenUDP:                  tproxy.ListenUDP,
+			MockGetaddrinfoLookupANY:       tproxy.GetaddrinfoLookupANY,
+			MockGetaddrinfoResolverNetwork: tproxy.GetaddrinfoResolverNetwork,
+		}},
+		policy,
+		policy.Resolver,
+		stats,
+	)
+
+	tlsConn, err := httpsDialer.DialTLSContext(
+		context.Background(), "tcp", net.JoinHostPort("server.local", serverURL.Port()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := tlsConn.(model.TLSConn).ConnectionState()
+	tlsConn.Close()
+	if !state.ECHAccepted {
+		t.Fatal("expected the server to accept ECH")
+	}
+
+	// make sure the stats track the ECH tactic
+	tactics, good := stats.LookupTactics("server.local", serverURL.Port())
+	if !good || len(tactics) != 1 || tactics[0].CountSuccess != 1 {
+		t.Fatal("unexpected stats", tactics)
+	}
+	expectKey := "127.0.0.1:" + serverURL.Port() + " sni=server.local verify=server.local ech=true"
+	if diff := cmp.Diff(expectKey, tactics[0].Tactic.tacticSummaryKey()); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// make sure the stats policy does not reuse ECH tactics
+	if out := statsPolicyFilterStatsTactics(tactics, good); len(out) != 0 {
+		t.Fatal("expected no tactics", out)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -23,6 +24,11 @@ type httpsDialerTactic struct {
 	// Address is the IPv4/IPv6 address for dialing.
 	Address string
 
+	// ECHConfigList is the OPTIONAL Encrypted Client Hello config list. When
+	// set, the SNI travels inside the encrypted inner ClientHello, while the
+	// outer ClientHello uses the public name contained in the config.
+	ECHConfigList []byte `json:",omitempty"`
+
 	// InitialDelay is the time in nanoseconds after which
 	// you would like to start this policy.
 	InitialDelay time.Duration
@@ -30,6 +36,12 @@ type httpsDialerTactic struct {
 	// Port is the TCP port for dialing.
 	Port string
 
+	// Proxy is the OPTIONAL URL of the SOCKS5 or HTTP CONNECT proxy
+	// through which we should dial Address and Port. When using a proxy,
+	// Address MAY also be a domain name, which we resolve locally when using
+	// `socks5://` and which the proxy resolves otherwise.
+	Proxy string `json:",omitempty"`
+
 	// SNI is the TLS ServerName to send over the wire.
 	SNI string
 
@@ -44,13 +56,23 @@ var _ fmt.Stringer = &httpsDialerTactic{}
 func (dt *httpsDialerTactic) Clone() *httpsDialerTactic {
 	return &httpsDialerTactic{
 		Address:        dt.Address,
+		ECHConfigList:  slices.Clone(dt.ECHConfigList),
 		InitialDelay:   dt.InitialDelay,
 		Port:           dt.Port,
+		Proxy:          dt.Proxy,
 		SNI:            dt.SNI,
 		VerifyHostname: dt.VerifyHostname,
 	}
 }
 
+// cloneWithRedactedProxy is like Clone but removes the credentials from
+// the proxy URL, so that we can safely persist the tactic in the stats.
+func (dt *httpsDialerTactic) cloneWithRedactedProxy() *httpsDialerTactic {
+	out := dt.Clone()
+	out.Proxy = proxyRedactURL(dt.Proxy)
+	return out
+}
+
 // String implements fmt.Stringer.
 func (dt *httpsDialerTactic) String() string {
 	return string(runtimex.Try1(json.Marshal(dt)))
@@ -68,18 +90,32 @@ func (dt *httpsDialerTactic) String() string {
 //
 // - VerifyHostname
 //
+// - whether we're using ECH (only when set)
+//
+// - Proxy (only when set and without credentials)
+//
 // The returned string contains the above fields separated by space with
-// `sni=` before the SNI and `verify=` before the verify hostname.
+// `sni=` before the SNI, `verify=` before the verify hostname, `ech=true`
+// when using ECH, and `proxy=` before the proxy URL. We omit `ech=` when
+// not using ECH and `proxy=` when not using a proxy. We do not include the
+// ECH config list, which changes when the server rotates its keys.
 //
 // We should be careful not to change this format unless we also change the
 // format version used by user policies and by the state management.
 func (dt *httpsDialerTactic) tacticSummaryKey() string {
-	return fmt.Sprintf(
+	key := fmt.Sprintf(
 		"%v sni=%v verify=%v",
 		net.JoinHostPort(dt.Address, dt.Port),
 		dt.SNI,
 		dt.VerifyHostname,
 	)
+	if len(dt.ECHConfigList) > 0 {
+		key += " ech=true"
+	}
+	if dt.Proxy != "" {
+		key += fmt.Sprintf(" proxy=%v", proxyRedactURL(dt.Proxy))
+	}
+	return key
 }
 
 // domainEndpointKey returns a string consisting of the domain endpoint only.
@@ -133,6 +169,9 @@ type httpsDialer struct {
 	// policy defines the dialing policy to use.
 	policy httpsDialerPolicy
 
+	// resolver is the resolver we use with proxies.
+	resolver model.Resolver
+
 	// rootCAs contains the root certificate pool we should use.
 	rootCAs *x509.CertPool
 
@@ -150,6 +189,8 @@ type httpsDialer struct {
 //
 // - policy defines the dialer policy;
 //
+// - resolver is the resolver we use for resolving domains when using proxies;
+//
 // - stats tracks what happens while we're dialing.
 //
 // The returned [*httpsDialer] would use the underlying network's
@@ -158,6 +199,7 @@ func newHTTPSDialer(
 	logger model.Logger,
 	netx *netxlite.Netx,
 	policy httpsDialerPolicy,
+	resolver model.Resolver,
 	stats httpsDialerEventsHandler,
 ) *httpsDialer {
 	return &httpsDialer{
@@ -166,10 +208,11 @@ func newHTTPSDialer(
 			Prefix: "httpsDialer: ",
 			Logger: logger,
 		},
-		netx:    netx,
-		policy:  policy,
-		rootCAs: netx.MaybeCustomUnderlyingNetwork().Get().DefaultCertPool(),
-		stats:   stats,
+		netx:     netx,
+		policy:   policy,
+		resolver: resolver,
+		rootCAs:  netx.MaybeCustomUnderlyingNetwork().Get().DefaultCertPool(),
+		stats:    stats,
 	}
 }
 
@@ -364,12 +407,23 @@ func (hd *httpsDialer) dialTLS(
 	// tell the observer that we're starting
 	hd.stats.OnStarting(tactic)
 
-	// create dialer and establish TCP connection
+	// create dialer and establish TCP connection, possibly using a proxy
 	endpoint := net.JoinHostPort(tactic.Address, tactic.Port)
-	ol := logx.NewOperationLogger(logger, "TCPConnect %s", endpoint)
-	dialer := hd.netx.NewDialerWithoutResolver(logger)
-	tcpConn, err := dialer.DialContext(ctx, "tcp", endpoint)
-	ol.Stop(err)
+	var (
+		tcpConn net.Conn
+		err     error
+	)
+	if tactic.Proxy != "" {
+		ol := logx.NewOperationLogger(
+			logger, "TCPConnect %s via %s", endpoint, proxyRedactURL(tactic.Proxy))
+		tcpConn, err = proxyDialContext(ctx, hd.netx, logger, hd.resolver, tactic.Proxy, endpoint)
+		ol.Stop(err)
+	} else {
+		ol := logx.NewOperationLogger(logger, "TCPConnect %s", endpoint)
+		dialer := hd.netx.NewDialerWithoutResolver(logger)
+		tcpConn, err = dialer.DialContext(ctx, "tcp", endpoint)
+		ol.Stop(err)
+	}
 
 	// handle a dialing error
 	if err != nil {
@@ -385,13 +439,22 @@ func (hd *httpsDialer) dialTLS(
 		ServerName:         tactic.SNI,
 	}
 
+	// using ECH requires TLS v1.3 and, if the server rejects ECH, the
+	// handshake fails with a [*tls.ECHRejectionError], therefore, on
+	// success, we know that we've concealed the SNI
+	if len(tactic.ECHConfigList) > 0 {
+		tlsConfig.EncryptedClientHelloConfigList = tactic.ECHConfigList
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
 	// create handshaker and establish a TLS connection
-	ol = logx.NewOperationLogger(
+	ol := logx.NewOperationLogger(
 		logger,
-		"TLSHandshake with %s SNI=%s ALPN=%v",
+		"TLSHandshake with %s SNI=%s ALPN=%v ECH=%v",
 		endpoint,
 		tlsConfig.ServerName,
 		tlsConfig.NextProtos,
+		len(tlsConfig.EncryptedClientHelloConfigList) > 0,
 	)
 	thx := hd.netx.NewTLSHandshakerStdlib(logger)
 	tlsConn, err := thx.Handshake(ctx, tcpConn, tlsConfig)
@@ -386,6 +386,7 @@ func TestHTTPSDialerNetemQA(t *testing.T) {
 					log.Log,
 					netx,
 					policy,
+					resolver,
 					tc.stats,
 				)
 				defer dialer.CloseIdleConnections()
@@ -513,6 +514,7 @@ func TestHTTPSDialerHostNetworkQA(t *testing.T) {
 				Logger:   log.Log,
 				Resolver: resolver,
 			},
+			resolver,
 			&nullStatsManager{},
 		)
 
@@ -6,12 +6,14 @@ package enginenetx
 //
 
 import (
+	"errors"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/bytecounter"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
@@ -104,6 +106,7 @@ func NewNetwork(
 		logger,
 		&netxlite.Netx{Underlying: nil}, // nil means using netxlite's singleton
 		newHTTPSDialerPolicy(kvStore, logger, proxyURL, resolver, stats),
+		resolver,
 		stats,
 	)
 
@@ -177,18 +180,31 @@ func newHTTPSDialerPolicy(
 		Factor:   3,
 	}
 
+	// interleave composed with tactics using ECH, when the domain supports it,
+	// such that composed has priority, because the HTTPS lookup is slower than the
+	// A/AAAA lookup given that we may try several resolvers before giving up
+	withECH := &mixPolicyInterleave{
+		Primary:  composed,
+		Fallback: &echPolicy{logger, resolver},
+		Factor:   3,
+	}
+
 	// attempt to load a user-provided dialing policy
 	primary, err := newUserPolicyV2(kvStore)
 
-	// on error, just use composed
+	// on error, just use withECH but let the user know in case the
+	// policy exists and we could not load it (e.g., it's invalid)
 	if err != nil {
-		return composed
+		if !errors.Is(err, kvstore.ErrNoSuchKey) {
+			logger.Warnf("enginenetx: ignoring the user policy: %s", err.Error())
+		}
+		return withECH
 	}
 
 	// otherwise, finish creating the dialing policy
 	policy := &mixPolicyEitherOr{
 		Primary:  primary,
-		Fallback: composed,
+		Fallback: withECH,
 	}
 
 	return policy
@@ -256,7 +256,12 @@ func TestNewHTTPSDialerPolicyTypes(t *testing.T) {
 	// this function ensures that the policy used when there's no use policy has
 	// the correct type and anything below it also has the correct type
 	verifyNoUserPolicyChain := func(t *testing.T, root httpsDialerPolicy) {
-		interleavePolicy := root.(*mixPolicyInterleave)
+		withECHPolicy := root.(*mixPolicyInterleave)
+		if withECHPolicy.Factor != 3 {
+			t.Fatal("expected .Factory to be 3")
+		}
+		_ = withECHPolicy.Fallback.(*echPolicy)
+		interleavePolicy := withECHPolicy.Primary.(*mixPolicyInterleave)
 		if interleavePolicy.Factor != 3 {
 			t.Fatal("expected .Factory to be 3")
 		}
@@ -394,6 +399,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: nil,
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return nil, netxlite.ErrOODNSNoSuchHost
 				},
@@ -410,6 +418,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: nil,
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return []string{"93.184.215.14", "2606:2800:21f:cb07:6820:80da:af6b:8b2c"}, nil
 				},
@@ -438,6 +449,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: nil,
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return nil, netxlite.ErrOODNSNoSuchHost
 				},
@@ -454,6 +468,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: nil,
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return []string{"130.192.91.211", "130.192.91.231"}, nil
 				},
@@ -482,6 +499,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: nil,
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return nil, netxlite.ErrOODNSNoSuchHost
 				},
@@ -498,6 +518,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: nil,
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return []string{"130.192.91.211", "130.192.91.231"}, nil
 				},
@@ -529,6 +552,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: &url.URL{}, // does not need to be filled
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return nil, netxlite.ErrOODNSNoSuchHost
 				},
@@ -545,6 +571,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: &url.URL{}, // does not need to be filled
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return []string{"93.184.215.14", "2606:2800:21f:cb07:6820:80da:af6b:8b2c"}, nil
 				},
@@ -573,6 +602,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: &url.URL{}, // does not need to be filled
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return nil, netxlite.ErrOODNSNoSuchHost
 				},
@@ -589,6 +621,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: &url.URL{}, // does not need to be filled
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return []string{"130.192.91.211", "130.192.91.231"}, nil
 				},
@@ -617,6 +652,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: &url.URL{}, // does not need to be filled
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return nil, netxlite.ErrOODNSNoSuchHost
 				},
@@ -633,6 +671,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			},
 			proxyURL: &url.URL{}, // does not need to be filled
 			resolver: &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					return nil, netxlite.ErrOODNSNoAnswer
+				},
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return []string{"130.192.91.211", "130.192.91.231"}, nil
 				},
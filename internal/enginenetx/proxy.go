@@ -0,0 +1,192 @@
+package enginenetx
+
+//
+// Dialing through the SOCKS5 and HTTP CONNECT proxies
+// configured by the user inside the user policy
+//
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"golang.org/x/net/proxy"
+)
+
+// errProxyUnsupportedScheme indicates that we do not support the proxy URL scheme.
+var errProxyUnsupportedScheme = errors.New("unsupported proxy scheme")
+
+// errProxyConnectFailed indicates that the HTTP proxy refused our CONNECT request.
+var errProxyConnectFailed = errors.New("HTTP CONNECT failed")
+
+// proxyParseURL parses and validates the URL of a tactic's proxy.
+func proxyParseURL(value string) (*url.URL, error) {
+	URL, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	switch URL.Scheme {
+	case "http", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("%w: %q", errProxyUnsupportedScheme, URL.Scheme)
+	}
+	if URL.Hostname() == "" || URL.Port() == "" {
+		return nil, fmt.Errorf("missing proxy host or port in %q", proxyRedactURL(value))
+	}
+	if URL.Path != "" && URL.Path != "/" {
+		return nil, fmt.Errorf("unexpected path in proxy URL %q", proxyRedactURL(value))
+	}
+	return URL, nil
+}
+
+// proxyRedactURL returns the given proxy URL without any credentials. We use this
+// function when logging and when saving proxy tactics inside the stats.
+func proxyRedactURL(value string) string {
+	URL, err := url.Parse(value)
+	if err != nil {
+		return "[invalid proxy URL]"
+	}
+	URL.User = nil
+	return URL.String()
+}
+
+// proxyDialContext uses the given proxy to establish a TCP connection with
+// the given endpoint. When the endpoint contains a domain, the proxy resolves
+// it, except for socks5 proxies, for which we resolve it locally using the
+// given resolver, which we also use to resolve the proxy address.
+func proxyDialContext(
+	ctx context.Context,
+	netx *netxlite.Netx,
+	logger model.Logger,
+	resolver model.Resolver,
+	proxyURL string,
+	endpoint string,
+) (net.Conn, error) {
+	URL, err := proxyParseURL(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// the proxy address MAY be a domain name, so we need a resolver
+	dialer := netx.NewDialerWithResolver(logger, resolver)
+
+	switch URL.Scheme {
+	case "http":
+		return proxyDialHTTPConnect(ctx, dialer, URL, endpoint)
+
+	default:
+		var auth *proxy.Auth
+		if URL.User != nil {
+			password, _ := URL.User.Password()
+			auth = &proxy.Auth{User: URL.User.Username(), Password: password}
+		}
+		// the code at proxy/socks5.go never fails; see https://git.io/JfJ4g
+		child, _ := proxy.SOCKS5("tcp", URL.Host, auth, &proxyDialerAdapter{dialer})
+		socks5 := child.(proxy.ContextDialer)
+		if URL.Scheme == "socks5h" {
+			// the trailing "h" means that the proxy resolves domains
+			return socks5.DialContext(ctx, "tcp", endpoint)
+		}
+		return proxyDialSOCKS5ResolvingLocally(ctx, socks5, resolver, endpoint)
+	}
+}
+
+// proxyDialSOCKS5ResolvingLocally resolves the endpoint's domain, if any, using the
+// given resolver and then uses the SOCKS5 dialer to connect to each address in
+// sequence until we successfully connect to one of them.
+func proxyDialSOCKS5ResolvingLocally(
+	ctx context.Context, dialer proxy.ContextDialer, resolver model.Resolver, endpoint string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, "tcp", endpoint)
+	}
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errorslist []error
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		errorslist = append(errorslist, err)
+	}
+	if len(errorslist) <= 0 {
+		return nil, netxlite.NewTopLevelGenericErrWrapper(netxlite.ErrOODNSNoAnswer)
+	}
+	return nil, errors.Join(errorslist...)
+}
+
+// proxyDialerAdapter adapts a [model.Dialer] to be a [proxy.Dialer]. The SOCKS5
+// code checks whether the dialer implements DialContext and prefers using it.
+type proxyDialerAdapter struct {
+	model.Dialer
+}
+
+// Dial implements proxy.Dialer.
+func (d *proxyDialerAdapter) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// proxyDialHTTPConnect uses an HTTP CONNECT proxy to connect to the given endpoint.
+func proxyDialHTTPConnect(
+	ctx context.Context, dialer model.Dialer, URL *url.URL, endpoint string) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", URL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	// make sure we honor the context while performing the HTTP handshake
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: endpoint},
+		Host:   endpoint,
+		Header: http.Header{},
+	}
+	if URL.User != nil {
+		password, _ := URL.User.Password()
+		credentials := URL.User.Username() + ":" + password
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, netxlite.NewTopLevelGenericErrWrapper(err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, netxlite.NewTopLevelGenericErrWrapper(err)
+	}
+	_ = resp.Body.Close()
+
+	// the proxy MUST NOT send data before we start the TLS handshake
+	if resp.StatusCode != http.StatusOK || reader.Buffered() > 0 {
+		_ = conn.Close()
+		return nil, fmt.Errorf("%w: %s", errProxyConnectFailed, resp.Status)
+	}
+
+	// make sure the context does not close the conn after we return
+	if !stop() {
+		_ = conn.Close()
+		return nil, netxlite.NewTopLevelGenericErrWrapper(ctx.Err())
+	}
+	return conn, nil
+}
@@ -481,7 +481,7 @@ func (mt *statsManager) OnStarting(tactic *httpsDialerTactic) {
 			HistoTLSHandshakeError:     map[string]int64{},
 			HistoTLSVerificationError:  map[string]int64{},
 			LastUpdated:                time.Time{},
-			Tactic:                     tactic.Clone(), // avoid storing the original
+			Tactic:                     tactic.cloneWithRedactedProxy(), // avoid storing the original
 		}
 		mt.container.SetStatsTacticLocked(tactic, record)
 	}
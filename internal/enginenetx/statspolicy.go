@@ -39,16 +39,21 @@ func statsPolicyFilterStatsTactics(tactics []*statsTactic, good bool) (out []*ht
 		return
 	}
 
-	// only keep well-formed successful entries
+	// only keep well-formed successful entries not using a proxy, since the stats
+	// contain proxy URLs without credentials and we only want to use the proxies
+	// that are currently configured inside the user policy, and not using ECH, since
+	// servers rotate their ECH configs and the echPolicy fetches fresh ones
 	onlySuccesses := statsDefensivelySortTacticsByDescendingSuccessRateWithAcceptPredicate(
 		tactics, func(st *statsTactic) bool {
-			return st != nil && st.Tactic != nil && st.CountSuccess > 0
+			return st != nil && st.Tactic != nil && st.CountSuccess > 0 &&
+				st.Tactic.Proxy == "" && len(st.Tactic.ECHConfigList) <= 0
 		},
 	)
 
 	// convert the statsTactic list into a list of tactics
 	for _, t := range onlySuccesses {
-		runtimex.Assert(t != nil && t.Tactic != nil && t.CountSuccess > 0, "expected well-formed *statsTactic")
+		runtimex.Assert(t != nil && t.Tactic != nil && t.CountSuccess > 0 &&
+			t.Tactic.Proxy == "" && len(t.Tactic.ECHConfigList) <= 0, "expected well-formed *statsTactic")
 		out = append(out, t.Tactic)
 	}
 	return
@@ -13,6 +13,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 
 	"github.com/ooni/probe-cli/v3/internal/hujsonx"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -40,25 +41,13 @@ func newUserPolicyV2(kvStore model.KeyValueStore) (*userPolicyV2, error) {
 		return nil, err
 	}
 
-	// attempt to parse the user policy using human-readable JSON
-	var root userPolicyRoot
-	if err := hujsonx.Unmarshal(data, &root); err != nil {
-		return nil, err
-	}
-
-	// make sure the version is OK
-	if root.Version != userPolicyVersion {
-		err := fmt.Errorf(
-			"%s: %w: expected=%d got=%d",
-			userPolicyKey,
-			errUserPolicyWrongVersion,
-			userPolicyVersion,
-			root.Version,
-		)
+	// attempt to parse and validate the user policy
+	root, err := userPolicyParse(data)
+	if err != nil {
 		return nil, err
 	}
 
-	out := &userPolicyV2{Root: &root}
+	out := &userPolicyV2{Root: root}
 	return out, nil
 }
 
@@ -101,18 +90,169 @@ const userPolicyKey = "bridges.conf"
 // errUserPolicyWrongVersion means that the user policy document has the wrong version number.
 var errUserPolicyWrongVersion = errors.New("wrong user policy version")
 
+// ErrInvalidUserPolicy indicates that the user policy document is not valid.
+var ErrInvalidUserPolicy = errors.New("invalid user policy")
+
 // userPolicyVersion is the current version of the user policy file.
-const userPolicyVersion = 3
+//
+// Version 4 added support for dialing through a proxy and for using ECH as well as
+// strict validation of the document. We still accept version 3 documents, which we
+// parse leniently and for which we ignore the Proxy and ECHConfigList fields.
+const userPolicyVersion = 4
+
+// userPolicyVersionWithoutProxy is the previous version of the user policy file.
+const userPolicyVersionWithoutProxy = 3
 
 // userPolicyRoot is the root of the user policy.
+//
+// The document is human-readable JSON (i.e., JSON with comments and trailing
+// commas) and, starting with version 4, we reject documents containing unknown fields. See the
+// DESIGN.md file inside this directory for a complete example.
 type userPolicyRoot struct {
-	// DomainEndpoints maps each domain endpoint to its policies.
+	// DomainEndpoints maps each domain endpoint (e.g., `api.ooni.io:443`) to its
+	// tactics. A tactic MUST contain the Address, Port and VerifyHostname fields. The
+	// Address MUST be an IP address unless the tactic uses a Proxy, which MUST be a
+	// `socks5://`, `socks5h://` or `http://` URL with optional credentials. The SNI MAY
+	// be empty, in which case we do not send the SNI extension, unless the tactic
+	// contains a base64 encoded ECHConfigList, in which case we send the SNI inside
+	// the encrypted inner ClientHello using Encrypted Client Hello.
 	DomainEndpoints map[string][]*httpsDialerTactic
 
 	// Version is the data structure version.
 	Version int
 }
 
+// ValidateUserPolicy parses and validates a user policy document. The returned error
+// is such that errors.Is(err, [ErrInvalidUserPolicy]) when the document is syntactically
+// valid but contains invalid values, and it points to the offending entry. We only
+// validate the entries of version 4 documents (see [userPolicyVersion]).
+func ValidateUserPolicy(data []byte) error {
+	_, err := userPolicyParse(data)
+	return err
+}
+
+// ImportUserPolicy validates the user policy document and, on success, saves it into the
+// given key-value store, where the engine reads it when creating a new [*Network].
+func ImportUserPolicy(kvStore model.KeyValueStore, data []byte) error {
+	if err := ValidateUserPolicy(data); err != nil {
+		return err
+	}
+	return kvStore.Set(userPolicyKey, data)
+}
+
+// ExportUserPolicy returns the user policy document saved into the given key-value
+// store. We return the document verbatim to preserve the user's comments.
+func ExportUserPolicy(kvStore model.KeyValueStore) ([]byte, error) {
+	return kvStore.Get(userPolicyKey)
+}
+
+// userPolicyParse parses and validates the user policy document.
+//
+// We parse version 3 documents like we did before introducing version 4, i.e., we ignore
+// unknown fields and we do not validate the tactics, so that existing documents keep
+// working. Because version 3 did not know about the Proxy and ECHConfigList fields, we
+// also clear them, exactly like we would have ignored them before.
+func userPolicyParse(data []byte) (*userPolicyRoot, error) {
+	// attempt to parse the user policy using human-readable JSON
+	var root userPolicyRoot
+	if err := hujsonx.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	switch root.Version {
+	case userPolicyVersionWithoutProxy:
+		for _, tactics := range root.DomainEndpoints {
+			for _, tactic := range tactics {
+				if tactic != nil {
+					tactic.Proxy = ""
+					tactic.ECHConfigList = nil
+				}
+			}
+		}
+		return &root, nil
+
+	case userPolicyVersion:
+		// fallthrough to the strict parsing below
+
+	default:
+		err := fmt.Errorf(
+			"%s: %w: expected=%d got=%d",
+			userPolicyKey,
+			errUserPolicyWrongVersion,
+			userPolicyVersion,
+			root.Version,
+		)
+		return nil, err
+	}
+
+	// parse again rejecting unknown fields, which likely are typos
+	root = userPolicyRoot{}
+	if err := hujsonx.UnmarshalStrict(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", userPolicyKey, err)
+	}
+
+	// make sure the entries are OK
+	for domainEndpoint, tactics := range root.DomainEndpoints {
+		if err := userPolicyValidateDomainEndpoint(domainEndpoint, tactics); err != nil {
+			return nil, fmt.Errorf("%s: %w: %s", userPolicyKey, ErrInvalidUserPolicy, err.Error())
+		}
+	}
+
+	return &root, nil
+}
+
+// userPolicyValidateDomainEndpoint validates the tactics of a domain endpoint.
+func userPolicyValidateDomainEndpoint(domainEndpoint string, tactics []*httpsDialerTactic) error {
+	domain, port, err := net.SplitHostPort(domainEndpoint)
+	if err != nil || domain == "" || !userPolicyIsValidPort(port) {
+		return fmt.Errorf("DomainEndpoints[%q]: expected a domain endpoint like \"api.ooni.io:443\"", domainEndpoint)
+	}
+	for idx, tactic := range tactics {
+		if tactic == nil {
+			continue // we tolerate and skip nil entries
+		}
+		if err := userPolicyValidateTactic(tactic); err != nil {
+			return fmt.Errorf("DomainEndpoints[%q][%d]: %w", domainEndpoint, idx, err)
+		}
+	}
+	return nil
+}
+
+// userPolicyValidateTactic validates a single tactic.
+func userPolicyValidateTactic(tactic *httpsDialerTactic) error {
+	switch {
+	case len(tactic.ECHConfigList) > 0 && tactic.SNI == "":
+		return errors.New("SNI: ECH requires the SNI to put inside the inner ClientHello")
+
+	case tactic.Proxy != "":
+		if _, err := proxyParseURL(tactic.Proxy); err != nil {
+			return fmt.Errorf("Proxy: %w", err)
+		}
+		if tactic.Address == "" {
+			return errors.New("Address: missing address")
+		}
+
+	case net.ParseIP(tactic.Address) == nil:
+		return fmt.Errorf("Address: %q is not an IP address", tactic.Address)
+	}
+	if !userPolicyIsValidPort(tactic.Port) {
+		return fmt.Errorf("Port: %q is not a valid port", tactic.Port)
+	}
+	if tactic.VerifyHostname == "" {
+		return errors.New("VerifyHostname: missing hostname")
+	}
+	if tactic.InitialDelay < 0 {
+		return fmt.Errorf("InitialDelay: negative delay %d", tactic.InitialDelay)
+	}
+	return nil
+}
+
+// userPolicyIsValidPort returns whether the given string is a valid TCP port.
+func userPolicyIsValidPort(port string) bool {
+	value, err := strconv.Atoi(port)
+	return err == nil && value > 0 && value <= 65535
+}
+
 func userPolicyRemoveNilEntries(input []*httpsDialerTactic) (output []*httpsDialerTactic) {
 	for _, entry := range input {
 		if entry != nil {
@@ -0,0 +1,65 @@
+package engineresolver
+
+//
+// Diagnosing which resolvers work, for attaching to bug reports
+//
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Diagnosis is the result of resolving a domain using a child resolver.
+type Diagnosis struct {
+	// Addresses contains the resolved addresses.
+	Addresses []string `json:"addresses"`
+
+	// Failure is the failure or nil on success.
+	Failure *string `json:"failure"`
+
+	// Runtime is the time it took to resolve the domain.
+	Runtime time.Duration `json:"runtime"`
+
+	// Score is the persisted score of the resolver.
+	Score float64 `json:"score"`
+
+	// Skipped indicates that we did not use the resolver because
+	// we cannot use it with the configured proxy.
+	Skipped bool `json:"skipped"`
+
+	// URL is the URL of the resolver.
+	URL string `json:"url"`
+}
+
+// Diagnose resolves the given domain using each child resolver in parallel,
+// returning the results sorted by descending persisted score. Unlike LookupHost,
+// this method does not update the persisted scores.
+func (r *Resolver) Diagnose(ctx context.Context, domain string) (out []*Diagnosis) {
+	wg := &sync.WaitGroup{}
+	for _, e := range r.readstatedefault() {
+		entry := &Diagnosis{Score: e.Score, URL: e.URL}
+		out = append(out, entry)
+		if r.ProxyURL != nil && r.shouldSkipWithProxy(e) {
+			entry.Skipped = true
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t0 := time.Now()
+			// Note: we're passing a copy of the resolverinfo such that
+			// we don't modify the persisted score.
+			addrs, err := r.lookupHost(ctx, &resolverinfo{URL: e.URL, Score: e.Score}, domain)
+			entry.Runtime = time.Since(t0)
+			if err != nil {
+				failure := err.Error()
+				entry.Failure = &failure
+				return
+			}
+			entry.Addresses = addrs
+		}()
+	}
+	wg.Wait()
+	return
+}
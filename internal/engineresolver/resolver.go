@@ -17,6 +17,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/legacy/multierror"
 	"github.com/ooni/probe-cli/v3/internal/logx"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 // Resolver is the session resolver. Resolver will try to use
@@ -68,6 +69,10 @@ type Resolver struct {
 	// run just once.
 	once sync.Once
 
+	// noHTTPS maps a domain for which we know there is no
+	// HTTPS record to the moment when we should forget it.
+	noHTTPS map[string]*lookupHTTPSNegativeEntry
+
 	// res maps a URL to a child resolver. We will
 	// construct child resolvers just once and we
 	// will track them into this field.
@@ -83,9 +88,105 @@ func (r *Resolver) CloseIdleConnections() {
 // errLookupNotImplemented indicates a given lookup type is not implemented.
 var errLookupNotImplemented = errors.New("sessionresolver: lookup not implemented")
 
-// LookupHTTPS implements Resolver.LookupHTTPS.
+// ErrLookupHTTPS indicates that LookupHTTPS failed.
+var ErrLookupHTTPS = errors.New("sessionresolver: LookupHTTPS failed")
+
+// LookupHTTPS implements Resolver.LookupHTTPS. This function tries the DNS-over-HTTPS
+// resolvers in order of descending score and returns the first successful reply or a
+// multierror.Union error on failure. We skip the system resolver, which cannot
+// issue HTTPS queries. Because many domains do not have HTTPS records, failing
+// to get a reply does not say much about the resolver and we do not update
+// the resolvers scores as part of this lookup.
+//
+// When a resolver tells us that the domain does not exist or has no HTTPS record, we
+// stop trying other resolvers, since they would most likely give us the same answer,
+// and we remember this answer for [lookupHTTPSNegativeCacheTTL]. This is important
+// because we call this function every time the engine dials a domain.
 func (r *Resolver) LookupHTTPS(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
-	return nil, errLookupNotImplemented
+	if err := r.lookupHTTPSNegativeCacheGet(domain); err != nil {
+		return nil, err
+	}
+	me := multierror.New(ErrLookupHTTPS)
+	for _, e := range r.readstatedefault() {
+		if e.URL == systemResolverURL {
+			continue // getaddrinfo cannot perform HTTPS lookups
+		}
+		if r.ProxyURL != nil && r.shouldSkipWithProxy(e) {
+			continue // we cannot proxy this URL so ignore it
+		}
+		if err := ctx.Err(); err != nil {
+			me.Add(newErrWrapper(err, e.URL))
+			continue
+		}
+		svc, err := r.lookupHTTPS(ctx, e, domain)
+		if err == nil {
+			return svc, nil
+		}
+		me.Add(newErrWrapper(err, e.URL))
+		if lookupHTTPSIsDefinitiveError(err) {
+			r.lookupHTTPSNegativeCachePut(domain, me)
+			break
+		}
+	}
+	return nil, me
+}
+
+// lookupHTTPSNegativeCacheTTL is the amount of time for which we remember
+// that a domain does not exist or does not have any HTTPS record.
+const lookupHTTPSNegativeCacheTTL = 15 * time.Minute
+
+// lookupHTTPSNegativeEntry is an entry of the LookupHTTPS negative cache.
+type lookupHTTPSNegativeEntry struct {
+	err     error
+	expires time.Time
+}
+
+// lookupHTTPSIsDefinitiveError returns whether the error is a DNS answer telling
+// us that the domain does not exist or does not have any HTTPS record.
+func lookupHTTPSIsDefinitiveError(err error) bool {
+	return errors.Is(err, netxlite.ErrOODNSNoAnswer) || errors.Is(err, netxlite.ErrOODNSNoSuchHost)
+}
+
+// lookupHTTPSNegativeCacheGet returns the cached error for the domain, if any.
+func (r *Resolver) lookupHTTPSNegativeCacheGet(domain string) error {
+	defer r.mu.Unlock()
+	r.mu.Lock()
+	entry, found := r.noHTTPS[domain]
+	if !found {
+		return nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(r.noHTTPS, domain)
+		return nil
+	}
+	return entry.err
+}
+
+// lookupHTTPSNegativeCachePut remembers that the lookup for the domain failed with err.
+func (r *Resolver) lookupHTTPSNegativeCachePut(domain string, err error) {
+	defer r.mu.Unlock()
+	r.mu.Lock()
+	if r.noHTTPS == nil {
+		r.noHTTPS = make(map[string]*lookupHTTPSNegativeEntry)
+	}
+	r.noHTTPS[domain] = &lookupHTTPSNegativeEntry{
+		err:     err,
+		expires: time.Now().Add(lookupHTTPSNegativeCacheTTL),
+	}
+}
+
+func (r *Resolver) lookupHTTPS(ctx context.Context, ri *resolverinfo, domain string) (*model.HTTPSSvc, error) {
+	re, err := r.getresolver(ri.URL)
+	if err != nil {
+		return nil, err
+	}
+	op := logx.NewOperationLogger(
+		r.logger(), "sessionresolver: lookup HTTPS %s using %s", domain, ri.URL)
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeLimitedLookupTimeout)
+	defer cancel()
+	svc, err := re.LookupHTTPS(ctx, domain)
+	op.Stop(err)
+	return svc, err
 }
 
 // LookupNS implements Resolver.LookupNS.
@@ -6,10 +6,12 @@ import (
 	"errors"
 	"net"
 	"net/url"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/bytecounter"
@@ -188,6 +190,106 @@ func TestLittleLLookupHostWithFailure(t *testing.T) {
 	}
 }
 
+func TestResolverLookupHTTPS(t *testing.T) {
+	t.Run("we return the first successful reply without touching the scores", func(t *testing.T) {
+		expected := &model.HTTPSSvc{ALPN: []string{"h2"}, Ech: []byte{1, 2, 3}}
+		var urls []string
+		kvStore := &kvstore.Memory{}
+		reso := &Resolver{
+			KVStore: kvStore,
+			newChildResolverFn: func(h3 bool, URL string) (model.Resolver, error) {
+				reso := &mocks.Resolver{
+					MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+						urls = append(urls, URL)
+						if len(urls) < 2 {
+							return nil, netxlite.ErrOODNSRefused
+						}
+						return expected, nil
+					},
+				}
+				return reso, nil
+			},
+		}
+		svc, err := reso.LookupHTTPS(context.Background(), "crypto.cloudflare.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, svc); diff != "" {
+			t.Fatal(diff)
+		}
+		if len(urls) != 2 || slices.Contains(urls, systemResolverURL) {
+			t.Fatal("unexpected resolvers", urls)
+		}
+		if _, err := kvStore.Get(storekey); !errors.Is(err, kvstore.ErrNoSuchKey) {
+			t.Fatal("expected no persisted scores", err)
+		}
+	})
+
+	t.Run("we return a multierror when all the resolvers fail", func(t *testing.T) {
+		reso := &Resolver{
+			KVStore: &kvstore.Memory{},
+			newChildResolverFn: func(h3 bool, URL string) (model.Resolver, error) {
+				reso := &mocks.Resolver{
+					MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+						return nil, netxlite.ErrOODNSRefused
+					},
+				}
+				return reso, nil
+			},
+		}
+		svc, err := reso.LookupHTTPS(context.Background(), "www.example.com")
+		if !errors.Is(err, ErrLookupHTTPS) {
+			t.Fatal("unexpected error", err)
+		}
+		if svc != nil {
+			t.Fatal("expected nil svc")
+		}
+		if len(reso.noHTTPS) != 0 {
+			t.Fatal("expected no negative cache entries")
+		}
+	})
+
+	for _, expectedErr := range []error{netxlite.ErrOODNSNoAnswer, netxlite.ErrOODNSNoSuchHost} {
+		t.Run("we stop at and cache the definitive answer: "+expectedErr.Error(), func(t *testing.T) {
+			var urls []string
+			reso := &Resolver{
+				KVStore: &kvstore.Memory{},
+				newChildResolverFn: func(h3 bool, URL string) (model.Resolver, error) {
+					reso := &mocks.Resolver{
+						MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+							urls = append(urls, URL)
+							return nil, netxlite.NewTopLevelGenericErrWrapper(expectedErr)
+						},
+					}
+					return reso, nil
+				},
+			}
+
+			for idx := 0; idx < 2; idx++ {
+				svc, err := reso.LookupHTTPS(context.Background(), "www.example.com")
+				if !errors.Is(err, ErrLookupHTTPS) || !errors.Is(err, expectedErr) {
+					t.Fatal("unexpected error", err)
+				}
+				if svc != nil {
+					t.Fatal("expected nil svc")
+				}
+			}
+			if len(urls) != 1 {
+				t.Fatal("expected a single lookup", urls)
+			}
+
+			// make sure we lookup again once the entry has expired
+			reso.noHTTPS["www.example.com"].expires = time.Now().Add(-time.Second)
+			if _, err := reso.LookupHTTPS(context.Background(), "www.example.com"); !errors.Is(err, expectedErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if len(urls) != 2 {
+				t.Fatal("expected a second lookup", urls)
+			}
+		})
+	}
+}
+
 func TestMaybeConfusionNoConfusion(t *testing.T) {
 	reso := &Resolver{}
 	rv := reso.maybeConfusion(nil, 0)
@@ -378,17 +480,6 @@ func TestShouldSkipWithProxyWorks(t *testing.T) {
 }
 
 func TestUnimplementedFunctions(t *testing.T) {
-	t.Run("LookupHTTPS", func(t *testing.T) {
-		r := &Resolver{}
-		https, err := r.LookupHTTPS(context.Background(), "dns.google")
-		if !errors.Is(err, errLookupNotImplemented) {
-			t.Fatal("unexpected error", err)
-		}
-		if https != nil {
-			t.Fatal("expected nil result")
-		}
-	})
-
 	t.Run("LookupNS", func(t *testing.T) {
 		r := &Resolver{}
 		ns, err := r.LookupNS(context.Background(), "dns.google")
@@ -9,6 +9,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/url"
+	"strings"
 
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
@@ -29,7 +30,7 @@ func collect(ctx context.Context, baseURL, authorization string,
 	if err != nil {
 		return err
 	}
-	URL.Path = collectPath
+	URL.Path = strings.TrimSuffix(URL.Path, "/") + collectPath
 	req, err := deps.NewHTTPRequestWithContext(ctx, "POST", URL.String(), bytes.NewReader(data))
 	if err != nil {
 		return err
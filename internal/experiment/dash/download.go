@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
@@ -66,7 +67,7 @@ func download(ctx context.Context, config downloadConfig) (downloadResult, error
 	if err != nil {
 		return result, err
 	}
-	URL.Path = fmt.Sprintf("%s%d", downloadPath, nbytes)
+	URL.Path = fmt.Sprintf("%s%s%d", strings.TrimSuffix(URL.Path, "/"), downloadPath, nbytes)
 	req, err := config.deps.NewHTTPRequestWithContext(ctx, "GET", URL.String(), nil)
 	if err != nil {
 		return result, err
@@ -172,3 +172,32 @@ func TestDownloadSuccess(t *testing.T) {
 		t.Fatal("invalid timestamp")
 	}
 }
+
+func TestDownloadWithBaseURLPath(t *testing.T) {
+	deps := &mockableDependencies{
+		MockNewHTTPRequestWithContext: http.NewRequestWithContext,
+		MockHTTPClient: func() model.HTTPClient {
+			return &mocks.HTTPClient{
+				MockDo: func(req *http.Request) (*http.Response, error) {
+					resp := &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(strings.NewReader("[]")),
+					}
+					return resp, nil
+				},
+			}
+		},
+	}
+
+	result, err := download(context.Background(), downloadConfig{
+		baseURL: "https://dash.example.com/foo/?access_token=x",
+		deps:    deps,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.serverURL != "https://dash.example.com/foo/dash/download/0?access_token=x" {
+		t.Fatal("invalid serverURL", result.serverURL)
+	}
+}
@@ -6,6 +6,8 @@ package dash
 
 import (
 	"context"
+	"net/url"
+	"strings"
 
 	"github.com/ooni/probe-cli/v3/internal/mlablocatev2"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
@@ -22,3 +24,30 @@ func locate(ctx context.Context, deps dependencies) (*mlablocatev2.DashResult, e
 	runtimex.Assert(len(result) >= 1, "too few entries")
 	return result[0], nil // ~same as with locate services v1
 }
+
+// locateOrUseServerURL returns the result for the given serverURL, if not
+// empty, and otherwise uses [locate] to query m-lab's locate services. The
+// URLs we return for serverURL are relative to its path and keep its query.
+func locateOrUseServerURL(
+	ctx context.Context, deps dependencies, serverURL string) (*mlablocatev2.DashResult, error) {
+	if serverURL == "" {
+		return locate(ctx, deps)
+	}
+	URL, err := url.Parse(serverURL)
+	if err != nil || (URL.Scheme != "http" && URL.Scheme != "https") || URL.Host == "" {
+		return nil, errInvalidServerURL
+	}
+	// Note: we keep the path and the query such that it's possible to use a server
+	// behind a reverse proxy or requiring an access token in the query
+	basePath := strings.TrimSuffix(URL.Path, "/")
+	negotiateURL, baseURL := *URL, *URL
+	negotiateURL.Path = basePath + negotiatePath
+	baseURL.Path = basePath + "/"
+	out := &mlablocatev2.DashResult{
+		Hostname:     URL.Hostname(),
+		Site:         "",
+		NegotiateURL: negotiateURL.String(),
+		BaseURL:      baseURL.String(),
+	}
+	return out, nil
+}
@@ -0,0 +1,50 @@
+package dash
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLocateOrUseServerURL(t *testing.T) {
+	t.Run("with a valid server URL", func(t *testing.T) {
+		out, err := locateOrUseServerURL(context.Background(), nil, "http://127.0.0.1:8080/foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.Hostname != "127.0.0.1" {
+			t.Fatal("unexpected Hostname", out.Hostname)
+		}
+		if out.NegotiateURL != "http://127.0.0.1:8080/foo/negotiate/dash" {
+			t.Fatal("unexpected NegotiateURL", out.NegotiateURL)
+		}
+		if out.BaseURL != "http://127.0.0.1:8080/foo/" {
+			t.Fatal("unexpected BaseURL", out.BaseURL)
+		}
+	})
+
+	t.Run("with a server URL with a query", func(t *testing.T) {
+		out, err := locateOrUseServerURL(context.Background(), nil, "https://dash.example.com/?access_token=x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.NegotiateURL != "https://dash.example.com/negotiate/dash?access_token=x" {
+			t.Fatal("unexpected NegotiateURL", out.NegotiateURL)
+		}
+		if out.BaseURL != "https://dash.example.com/?access_token=x" {
+			t.Fatal("unexpected BaseURL", out.BaseURL)
+		}
+	})
+
+	t.Run("with invalid server URLs", func(t *testing.T) {
+		for _, input := range []string{"\t", "ws://dash.example.com/", "https:///dash"} {
+			out, err := locateOrUseServerURL(context.Background(), nil, input)
+			if !errors.Is(err, errInvalidServerURL) {
+				t.Fatal("unexpected error", err)
+			}
+			if out != nil {
+				t.Fatal("expected nil result")
+			}
+		}
+	})
+}
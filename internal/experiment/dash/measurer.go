@@ -6,16 +6,38 @@ package dash
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/netx"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 // Config contains the experiment config.
-type Config struct{}
+type Config struct {
+	// ServerURL is the OPTIONAL base URL of an M-Lab-compatible DASH server
+	// (e.g., https://dash.example.com/) to use instead of querying M-Lab's
+	// locate API.
+	ServerURL string `ooni:"base URL of an M-Lab-compatible DASH server to use instead of the locate API"`
+
+	// Duration is the OPTIONAL approximate duration of the streaming in seconds.
+	Duration int64 `ooni:"approximate duration of the streaming in seconds"`
+
+	// MaxBytes is the OPTIONAL number of bytes after which we stop streaming.
+	MaxBytes int64 `ooni:"stop streaming after downloading these many bytes"`
+}
+
+// numIterations returns the number of segments we should download. Because
+// each segment should take two seconds to download, we use the configured
+// duration to compute the number of segments.
+func (c Config) numIterations() int64 {
+	if c.Duration > 0 {
+		return max(1, c.Duration/segmentDuration)
+	}
+	return totalStep
+}
 
 // Simple contains the experiment summary.
 type Simple struct {
@@ -78,20 +100,18 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	tk := &TestKeys{}
 	measurement.TestKeys = tk
 
-	// create a special purpose HTTP client for the measurement.
-	saver := &tracex.Saver{}
+	// create a special purpose HTTP client for the measurement. We use the trace
+	// to save the connect time of connections, which is part of the results.
+	trace := measurexlite.NewTrace(0, measurement.MeasurementStartTimeSaved)
+	netx := &netxlite.Netx{}
+	logger := sess.Logger()
+	// Implements shaping if the user builds using `-tags shaping`
+	// See https://github.com/ooni/probe/issues/2112
+	dialer := bytecounter.WrapWithContextAwareDialer(netxlite.NewMaybeShapingDialer(
+		netx.NewDialerWithResolver(logger, netx.NewStdlibResolver(logger))))
+	tlsDialer := netxlite.NewTLSDialer(dialer, netx.NewTLSHandshakerStdlib(logger))
 	httpClient := &http.Client{
-		Transport: netx.NewHTTPTransport(netx.Config{
-			ContextByteCounting: true,
-			// Implements shaping if the user builds using `-tags shaping`
-			// See https://github.com/ooni/probe/issues/2112
-			Dialer: netxlite.NewMaybeShapingDialer(netx.NewDialer(netx.Config{
-				ContextByteCounting: true,
-				Saver:               saver,
-				Logger:              sess.Logger(),
-			})),
-			Logger: sess.Logger(),
-		}),
+		Transport: netxlite.NewHTTPTransport(logger, dialer, tlsDialer),
 	}
 	defer httpClient.CloseIdleConnections()
 
@@ -99,23 +119,32 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
+	// make sure the trace observes the network operations
+	ctx = netxlite.ContextWithTrace(ctx, trace)
+
 	// create an instance of runner.
 	r := &runnerConfig{
 		callbacks:  callbacks,
 		httpClient: httpClient,
-		saver:      saver,
+		trace:      trace,
 		sess:       sess,
 		tk:         tk,
+
+		maxBytes:      m.config.MaxBytes,
+		numIterations: m.config.numIterations(),
+		serverURL:     m.config.ServerURL,
 	}
 
 	// run the experiment.
 	//
-	// Implementation note: we ignore the return value of r.do rather than
+	// Implementation note: we mostly ignore the return value of r.do rather than
 	// returning it to the caller. We do that because returning an error means
 	// the measurement failed for some fundamental reason (e.g., the input
-	// is an URL that you cannot parse). For DASH, this case will never happen
-	// because there is no input, so always returning nil is fine here.
-	_ = runnerMain(ctx, r)
+	// is an URL that you cannot parse). For DASH, this case only happens
+	// when the user configures an invalid server URL.
+	if err := runnerMain(ctx, r); errors.Is(err, errInvalidServerURL) {
+		return err
+	}
 	return nil
 }
 
@@ -10,6 +10,7 @@ import (
 	"github.com/montanaflynn/stats"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
 )
 
 func TestTestKeysAnalyzeWithNoData(t *testing.T) {
@@ -74,11 +75,40 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "dash" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.14.1" {
+	if measurer.ExperimentVersion() != "0.16.0" {
 		t.Fatal("unexpected version")
 	}
 }
 
+func TestConfigNumIterations(t *testing.T) {
+	expect := map[int64]int64{0: totalStep, -1: totalStep, 1: 1, 2: 1, 30: 15, 60: 30}
+	for duration, expected := range expect {
+		if got := (Config{Duration: duration}).numIterations(); got != expected {
+			t.Fatal("for", duration, "expected", expected, "got", got)
+		}
+	}
+}
+
+func TestMeasureWithInvalidServerURL(t *testing.T) {
+	measurement := new(model.Measurement)
+	m := &Measurer{config: Config{ServerURL: "ws://dash.example.com/"}}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(log.Log),
+		Measurement: measurement,
+		Session: &mocks.Session{
+			MockLogger: func() model.Logger {
+				return model.DiscardLogger
+			},
+			MockUserAgent: func() string {
+				return "miniooni/0.1.0-dev"
+			},
+		},
+	}
+	if err := m.Run(context.Background(), args); !errors.Is(err, errInvalidServerURL) {
+		t.Fatal("unexpected error value", err)
+	}
+}
+
 func TestMeasureWithCancelledContext(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // cause failure
@@ -131,3 +161,44 @@ func TestSummaryKeysGood(t *testing.T) {
 		t.Fatal("sk.Anomaly() does not return sk.IsAnomaly's value")
 	}
 }
+
+func TestMeasureWithNetem(t *testing.T) {
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+
+	env.Do(func() {
+		measurement := new(model.Measurement)
+		m := NewExperimentMeasurer(Config{
+			ServerURL: "https://mlab-speedtest.org/",
+			Duration:  4,
+		})
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(log.Log),
+			Measurement: measurement,
+			Session: &mocks.Session{
+				MockLogger: func() model.Logger {
+					return model.DiscardLogger
+				},
+				MockUserAgent: func() string {
+					return "miniooni/0.1.0-dev"
+				},
+			},
+		}
+		if err := m.Run(context.Background(), args); err != nil {
+			t.Fatal(err)
+		}
+		tk := measurement.TestKeys.(*TestKeys)
+		if tk.Failure != nil {
+			t.Fatal("unexpected failure", *tk.Failure)
+		}
+		if tk.Server.Hostname != "mlab-speedtest.org" {
+			t.Fatal("unexpected hostname", tk.Server.Hostname)
+		}
+		if len(tk.ReceiverData) != 2 {
+			t.Fatal("unexpected number of iterations", len(tk.ReceiverData))
+		}
+		if tk.Simple.MedianBitrate <= 0 {
+			t.Fatal("expected nonzero median bitrate")
+		}
+	})
+}
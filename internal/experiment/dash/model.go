@@ -25,17 +25,23 @@ const (
 	testName = "dash"
 
 	// testVersion is the version of the experiment.
-	testVersion = "0.14.1"
+	testVersion = "0.16.0"
 
 	// totalStep is the total number of steps we should run
 	// during the download experiment.
 	totalStep = 15
+
+	// segmentDuration is the expected duration in seconds of downloading a segment.
+	segmentDuration = 2
 )
 
 var (
 	// errServerBusy is the error returned when the DASH server is busy.
 	errServerBusy = errors.New("dash: server busy; try again later")
 
+	// errInvalidServerURL is the error returned when the configured server URL is invalid.
+	errInvalidServerURL = errors.New("dash: invalid server URL")
+
 	// errHTTPRequest failed is the error returned when an HTTP request fails.
 	errHTTPRequestFailed = errors.New("dash: request failed")
 )
@@ -14,7 +14,7 @@ import (
 
 	"github.com/montanaflynn/stats"
 	"github.com/ooni/probe-cli/v3/internal/humanize"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 )
@@ -28,15 +28,24 @@ type runnerConfig struct {
 	// httpClient is the HTTP client we're using.
 	httpClient model.HTTPClient
 
-	// saver is MUTABLE and is used to save the connect time of connections,
+	// trace is MUTABLE and is used to save the connect time of connections,
 	// which is part of the DASH measurement results.
-	saver *tracex.Saver
+	trace *measurexlite.Trace
 
 	// sess is the measurement session.
 	sess model.ExperimentSession
 
 	// tk contains the MUTABLE test keys.
 	tk *TestKeys
+
+	// maxBytes is the OPTIONAL number of bytes after which we stop streaming.
+	maxBytes int64
+
+	// numIterations is the number of iterations used by [runnerMain].
+	numIterations int64
+
+	// serverURL is the OPTIONAL server URL to use instead of the locate API.
+	serverURL string
 }
 
 var _ dependencies = &runnerConfig{}
@@ -65,7 +74,7 @@ func (r *runnerConfig) UserAgent() string {
 // runnerRunAllPhases runs all the experiment phases.
 func runnerRunAllPhases(ctx context.Context, r *runnerConfig, numIterations int64) error {
 	// 1. locate the server with which to perform the measurement
-	locateResult, err := locate(ctx, r)
+	locateResult, err := locateOrUseServerURL(ctx, r, r.serverURL)
 	if err != nil {
 		return err
 	}
@@ -183,10 +192,8 @@ func runnerMeasure(
 		// 2.3. Read the events so far and possibly update our measurement
 		// of the latest connect time. We should have one sample in most
 		// cases, because the connection should be persistent.
-		for _, ev := range r.saver.Read() {
-			if _, ok := ev.(*tracex.EventConnectOperation); ok {
-				connectTime = ev.Value().Duration.Seconds()
-			}
+		for _, ev := range r.trace.TCPConnects() {
+			connectTime = ev.T - ev.T0
 		}
 		current.ConnectTime = connectTime
 
@@ -206,6 +213,12 @@ func runnerMeasure(
 		speed *= 8.0    // to bits per second
 		speed /= 1000.0 // to kbit/s
 		current.Rate = int64(speed)
+
+		// 2.6. stop if we have downloaded enough data.
+		if r.maxBytes > 0 && total >= r.maxBytes {
+			r.Logger().Infof("dash: stopping after downloading %d bytes", total)
+			break
+		}
 	}
 
 	return nil
@@ -248,7 +261,7 @@ func (tk *TestKeys) analyze() error {
 // runnerMain is the main function that runs the experiment.
 func runnerMain(ctx context.Context, r *runnerConfig) error {
 	defer r.callbacks.OnProgress(1, "streaming: done")
-	err := runnerRunAllPhases(ctx, r, totalStep)
+	err := runnerRunAllPhases(ctx, r, r.numIterations)
 	if err != nil {
 		s := err.Error()
 		r.tk.Failure = &s
@@ -10,11 +10,19 @@ import (
 	"time"
 
 	"github.com/apex/log"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
+// newTraceWithConnect returns a trace containing a TCP connect with the given duration.
+func newTraceWithConnect(duration time.Duration) *measurexlite.Trace {
+	zeroTime := time.Now()
+	trace := measurexlite.NewTrace(0, zeroTime)
+	trace.OnConnectDone(zeroTime, "tcp", "", "130.192.91.211:443", nil, zeroTime.Add(duration))
+	return trace
+}
+
 func TestRunnerRunAllPhasesLocateFailure(t *testing.T) {
 	expected := errors.New("mocked error")
 
@@ -30,7 +38,7 @@ func TestRunnerRunAllPhasesLocateFailure(t *testing.T) {
 				}
 			},
 		},
-		saver: &tracex.Saver{},
+		trace: measurexlite.NewTrace(0, time.Now()),
 		sess: &mocks.Session{
 			MockLogger: func() model.Logger {
 				return model.DiscardLogger
@@ -73,7 +81,7 @@ func TestRunnerRunAllPhasesNegotiateFailure(t *testing.T) {
 			},
 		},
 
-		saver: &tracex.Saver{},
+		trace: measurexlite.NewTrace(0, time.Now()),
 		sess: &mocks.Session{
 			MockLogger: func() model.Logger {
 				return model.DiscardLogger
@@ -123,7 +131,7 @@ func TestRunnerRunAllPhasesMeasureFailure(t *testing.T) {
 			},
 		},
 
-		saver: &tracex.Saver{},
+		trace: measurexlite.NewTrace(0, time.Now()),
 		sess: &mocks.Session{
 			MockLogger: func() model.Logger {
 				return model.DiscardLogger
@@ -142,8 +150,7 @@ func TestRunnerRunAllPhasesMeasureFailure(t *testing.T) {
 
 func TestRunnerRunAllPhasesCollectFailure(t *testing.T) {
 	expected := errors.New("mocked error")
-	saver := new(tracex.Saver)
-	saver.Write(&tracex.EventConnectOperation{V: &tracex.EventValue{Duration: 150 * time.Millisecond}})
+	trace := newTraceWithConnect(150 * time.Millisecond)
 	r := &runnerConfig{
 		callbacks: model.NewPrinterCallbacks(log.Log),
 
@@ -182,7 +189,7 @@ func TestRunnerRunAllPhasesCollectFailure(t *testing.T) {
 			},
 		},
 
-		saver: saver,
+		trace: trace,
 		sess: &mocks.Session{
 			MockLogger: func() model.Logger {
 				return model.DiscardLogger
@@ -200,8 +207,7 @@ func TestRunnerRunAllPhasesCollectFailure(t *testing.T) {
 }
 
 func TestRunnerRunAllPhasesSuccess(t *testing.T) {
-	saver := &tracex.Saver{}
-	saver.Write(&tracex.EventConnectOperation{V: &tracex.EventValue{Duration: 150 * time.Millisecond}})
+	trace := newTraceWithConnect(150 * time.Millisecond)
 
 	r := &runnerConfig{
 		callbacks: model.NewPrinterCallbacks(log.Log),
@@ -247,7 +253,7 @@ func TestRunnerRunAllPhasesSuccess(t *testing.T) {
 			},
 		},
 
-		saver: saver,
+		trace: trace,
 		sess: &mocks.Session{
 			MockLogger: func() model.Logger {
 				return model.DiscardLogger
@@ -262,4 +268,75 @@ func TestRunnerRunAllPhasesSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(r.tk.ReceiverData) != 1 {
+		t.Fatal("not the ReceiverData we expected")
+	}
+	if connectTime := r.tk.ReceiverData[0].ConnectTime; connectTime < 0.149 || connectTime > 0.151 {
+		t.Fatal("not the ConnectTime we expected", connectTime)
+	}
+}
+
+func TestRunnerRunAllPhasesWithServerURLAndMaxBytes(t *testing.T) {
+	r := &runnerConfig{
+		callbacks: model.NewPrinterCallbacks(log.Log),
+
+		httpClient: &mocks.HTTPClient{
+			MockDo: func(req *http.Request) (*http.Response, error) {
+				switch {
+				case req.URL.Hostname() != "dash.example.com":
+					return nil, errors.New("unexpected HTTP request")
+				case req.URL.Path == negotiatePath:
+					resp := &http.Response{
+						StatusCode: 200,
+						Body: io.NopCloser(strings.NewReader(
+							`{"authorization": "xx", "unchoked": 1}`,
+						)),
+					}
+					return resp, nil
+				case strings.HasPrefix(req.URL.Path, downloadPath):
+					resp := &http.Response{
+						StatusCode: 200,
+						Body: io.NopCloser(strings.NewReader(
+							`1234567`,
+						)),
+					}
+					return resp, nil
+				case req.URL.Path == collectPath:
+					resp := &http.Response{
+						StatusCode: 200,
+						Body: io.NopCloser(strings.NewReader(
+							`[]`,
+						)),
+					}
+					return resp, nil
+				default:
+					return nil, errors.New("unexpected HTTP request")
+				}
+			},
+		},
+
+		trace: newTraceWithConnect(150 * time.Millisecond),
+		sess: &mocks.Session{
+			MockLogger: func() model.Logger {
+				return model.DiscardLogger
+			},
+			MockUserAgent: func() string {
+				return "miniooni/0.1.0-dev"
+			},
+		},
+		tk:        &TestKeys{},
+		maxBytes:  10,
+		serverURL: "https://dash.example.com/",
+	}
+	err := runnerRunAllPhases(context.Background(), r, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each segment is seven bytes long, so we should stop after two segments
+	if len(r.tk.ReceiverData) != 2 {
+		t.Fatal("not the ReceiverData we expected", len(r.tk.ReceiverData))
+	}
+	if r.tk.Server.Hostname != "dash.example.com" {
+		t.Fatal("not the Hostname we expected", r.tk.Server.Hostname)
+	}
 }
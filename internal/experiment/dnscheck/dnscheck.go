@@ -15,16 +15,16 @@ import (
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
-	"github.com/ooni/probe-cli/v3/internal/legacy/netx"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 	"github.com/ooni/probe-cli/v3/internal/targetloading"
 )
 
 const (
 	testName      = "dnscheck"
-	testVersion   = "0.9.3"
+	testVersion   = "0.9.4"
 	defaultDomain = "example.org"
 )
 
@@ -181,18 +181,14 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	// with IP addresses successfully, we just get back the IPs when we are
 	// passing as input an IP address rather than a domain name.
 	begin := measurement.MeasurementStartTimeSaved
-	evsaver := new(tracex.Saver)
-	resolver := netx.NewResolver(netx.Config{
-		BogonIsError: true,
-		Logger:       sess.Logger(),
-		Saver:        evsaver,
-	})
+	netx := &netxlite.Netx{}
+	resolver := netxlite.MaybeWrapWithBogonResolver(true, netx.NewStdlibResolver(sess.Logger()))
 	addrs, err := m.lookupHost(ctx, URL.Hostname(), resolver)
-	queries := tracex.NewDNSQueriesList(begin, evsaver.Read())
-	tk.BootstrapFailure = tracex.NewFailure(err)
+	queries := urlgetter.NewArchivalDNSLookupResults(resolver, URL.Hostname(), addrs, err, time.Since(begin))
+	tk.BootstrapFailure = measurexlite.NewFailure(err)
 	if len(queries) > 0 {
-		// We get no queries in case we are resolving an IP address, since
-		// the address resolver doesn't generate events
+		// We get no queries only when the lookup succeeds without addresses, while
+		// resolving an IP address produces a query whose answer is the address
 		tk.Bootstrap = &urlgetter.TestKeys{Queries: queries}
 	}
 
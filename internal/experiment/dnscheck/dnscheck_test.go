@@ -45,7 +45,7 @@ func TestExperimentNameAndVersion(t *testing.T) {
 	if measurer.ExperimentName() != "dnscheck" {
 		t.Error("unexpected experiment name")
 	}
-	if measurer.ExperimentVersion() != "0.9.3" {
+	if measurer.ExperimentVersion() != "0.9.4" {
 		t.Error("unexpected experiment version")
 	}
 }
@@ -0,0 +1,66 @@
+package dnscheck
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/testingx"
+)
+
+// TestGolden runs dnscheck inside a [netemx.QAEnv] and compares the test keys with
+// the golden files inside ./testdata. Set OONI_UPDATE_GOLDEN=1 to regenerate them.
+func TestGolden(t *testing.T) {
+	type testcase struct {
+		// name is the test case name and the golden file name.
+		name string
+
+		// input is the URL of the resolver to measure.
+		input string
+	}
+
+	cases := []testcase{{
+		name:  "dns_over_udp",
+		input: "udp://dns.google:53",
+	}, {
+		name:  "dns_over_https",
+		input: "https://dns.google/dns-query",
+	}, {
+		name:  "dns_over_tls_unreachable",
+		input: "dot://dns.google:853",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := netemx.MustNewScenario(netemx.InternetScenario)
+			defer env.Close()
+			env.Do(func() {
+				measurer := NewExperimentMeasurer()
+				measurement := &model.Measurement{
+					Input:                     model.MeasurementInput(tc.input),
+					MeasurementStartTimeSaved: time.Now(),
+				}
+				args := &model.ExperimentArgs{
+					Callbacks:   model.NewPrinterCallbacks(log.Log),
+					Measurement: measurement,
+					Session:     newsession(),
+					Target: &Target{
+						URL:    tc.input,
+						Config: &Config{Domain: "www.example.com"},
+					},
+				}
+				if err := measurer.Run(context.Background(), args); err != nil {
+					t.Fatal(err)
+				}
+				path := filepath.Join("testdata", "golden", tc.name+".json")
+				if err := testingx.CompareGolden(path, measurement.TestKeys); err != nil {
+					t.Fatal(err)
+				}
+			})
+		})
+	}
+}
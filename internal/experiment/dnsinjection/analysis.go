@@ -0,0 +1,111 @@
+package dnsinjection
+
+//
+// Classification of the replies
+//
+
+import (
+	"sort"
+
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+const (
+	// TargetStatusNoReplies indicates that the target did not reply to any query.
+	TargetStatusNoReplies = "no_replies"
+
+	// TargetStatusInjection indicates that we received replies only for sensitive
+	// domains, hence we classify such replies as injected.
+	TargetStatusInjection = "injection"
+
+	// TargetStatusDNSServer indicates that we received replies for control domains,
+	// which means the target is not a dead address (or that a middlebox answers
+	// all the queries), so we cannot classify the replies as injected.
+	TargetStatusDNSServer = "dns_server"
+)
+
+// analyze summarizes the replies and classifies each target.
+func analyze(tk *TestKeys) {
+	// sort the probes to make the results more readable
+	sort.SliceStable(tk.Probes, func(i, j int) bool {
+		left, right := tk.Probes[i], tk.Probes[j]
+		if left.Target != right.Target {
+			return left.Target < right.Target
+		}
+		if left.Control != right.Control {
+			return left.Control
+		}
+		return left.Domain < right.Domain
+	})
+
+	// extract the replies from the queries and the delayed responses
+	for _, probe := range tk.Probes {
+		probe.Replies = append(probe.Replies, newReplies(probe.Queries, false)...)
+		probe.Replies = append(probe.Replies, newReplies(probe.DelayedResponses, true)...)
+	}
+
+	// classify each target
+	for _, target := range tk.Targets {
+		var controlReplies, sensitiveReplies bool
+		for _, probe := range tk.Probes {
+			if probe.Target != target.Address || len(probe.Replies) <= 0 {
+				continue
+			}
+			if probe.Control {
+				controlReplies = true
+			} else {
+				sensitiveReplies = true
+			}
+		}
+		switch {
+		case controlReplies:
+			target.Status = TargetStatusDNSServer
+		case sensitiveReplies:
+			target.Status = TargetStatusInjection
+			tk.Injection = true
+			for _, probe := range tk.Probes {
+				if probe.Target == target.Address && len(probe.Replies) > 0 {
+					probe.Injected = true
+				}
+			}
+		default:
+			target.Status = TargetStatusNoReplies
+		}
+	}
+}
+
+// newReplies creates a [*Reply] for each lookup including a raw response.
+func newReplies(lookups []*model.ArchivalDNSLookupResult, late bool) (out []*Reply) {
+	for _, lookup := range lookups {
+		if len(lookup.RawResponse) <= 0 {
+			continue // no reply
+		}
+		reply := &Reply{
+			QueryType: lookup.QueryType,
+			Rcode:     int(lookup.Rcode),
+			Answers:   []string{},
+			TTLs:      []uint32{},
+			T:         lookup.T,
+			Late:      late,
+		}
+		msg := &dns.Msg{}
+		if err := msg.Unpack(lookup.RawResponse); err == nil {
+			for _, answer := range msg.Answer {
+				switch v := answer.(type) {
+				case *dns.A:
+					reply.Answers = append(reply.Answers, v.A.String())
+				case *dns.AAAA:
+					reply.Answers = append(reply.Answers, v.AAAA.String())
+				case *dns.CNAME:
+					reply.Answers = append(reply.Answers, v.Target)
+				default:
+					continue
+				}
+				reply.TTLs = append(reply.TTLs, answer.Header().Ttl)
+			}
+		}
+		out = append(out, reply)
+	}
+	return
+}
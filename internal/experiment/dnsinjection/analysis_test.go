@@ -0,0 +1,124 @@
+package dnsinjection
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// newRawResponse returns a raw DNS response containing the given A record.
+func newRawResponse(t *testing.T, domain string, address string, ttl uint32) []byte {
+	query := &dns.Msg{}
+	query.SetQuestion(dns.Fqdn(domain), dns.TypeA)
+	reply := &dns.Msg{}
+	reply.SetReply(query)
+	reply.Answer = append(reply.Answer, &dns.CNAME{
+		Hdr:    dns.RR_Header{Name: dns.Fqdn(domain), Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: ttl},
+		Target: "cdn.example.com.",
+	}, &dns.A{
+		Hdr: dns.RR_Header{Name: "cdn.example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+		A:   net.ParseIP(address),
+	})
+	data, err := reply.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestNewReplies(t *testing.T) {
+	lookups := []*model.ArchivalDNSLookupResult{{
+		QueryType:   "A",
+		RawResponse: newRawResponse(t, "www.example.org", "10.10.34.35", 60),
+		T:           0.5,
+	}, {
+		QueryType: "AAAA",
+		T:         1.0,
+	}, {
+		QueryType:   "A",
+		RawResponse: []byte{0x01},
+		Rcode:       2,
+		T:           1.5,
+	}}
+	expect := []*Reply{{
+		QueryType: "A",
+		Answers:   []string{"cdn.example.com.", "10.10.34.35"},
+		TTLs:      []uint32{60, 60},
+		T:         0.5,
+		Late:      true,
+	}, {
+		QueryType: "A",
+		Rcode:     2,
+		Answers:   []string{},
+		TTLs:      []uint32{},
+		T:         1.5,
+		Late:      true,
+	}}
+	if diff := cmp.Diff(expect, newReplies(lookups, true)); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	reply := func(domain string) []*model.ArchivalDNSLookupResult {
+		return []*model.ArchivalDNSLookupResult{{
+			QueryType:   "A",
+			RawResponse: newRawResponse(t, domain, "10.10.34.35", 60),
+		}}
+	}
+	tk := NewTestKeys()
+	tk.addTargets(
+		&Target{Address: "192.0.2.1"},
+		&Target{Address: "192.0.2.2"},
+		&Target{Address: "192.0.2.3"},
+	)
+	tk.addProbes(
+		// 192.0.2.1 replies only for sensitive domains
+		&SingleProbe{Target: "192.0.2.1", Domain: "www.example.org", DelayedResponses: reply("www.example.org")},
+		&SingleProbe{Target: "192.0.2.1", Domain: "example.com", Control: true},
+		// 192.0.2.2 replies for all domains
+		&SingleProbe{Target: "192.0.2.2", Domain: "www.example.org", Queries: reply("www.example.org")},
+		&SingleProbe{Target: "192.0.2.2", Domain: "example.com", Control: true, Queries: reply("example.com")},
+		// 192.0.2.3 does not reply
+		&SingleProbe{Target: "192.0.2.3", Domain: "www.example.org"},
+		&SingleProbe{Target: "192.0.2.3", Domain: "example.com", Control: true},
+	)
+
+	analyze(tk)
+
+	if !tk.Injection {
+		t.Fatal("expected injection")
+	}
+	var statuses []string
+	for _, target := range tk.Targets {
+		statuses = append(statuses, target.Status)
+	}
+	expectStatuses := []string{TargetStatusInjection, TargetStatusDNSServer, TargetStatusNoReplies}
+	if diff := cmp.Diff(expectStatuses, statuses); diff != "" {
+		t.Fatal(diff)
+	}
+	type summary struct {
+		Target   string
+		Control  bool
+		Replies  int
+		Injected bool
+	}
+	var summaries []summary
+	for _, probe := range tk.Probes {
+		summaries = append(summaries, summary{probe.Target, probe.Control, len(probe.Replies), probe.Injected})
+	}
+	expectSummaries := []summary{
+		{"192.0.2.1", true, 0, false},
+		{"192.0.2.1", false, 1, true},
+		{"192.0.2.2", true, 1, false},
+		{"192.0.2.2", false, 1, false},
+		{"192.0.2.3", true, 0, false},
+		{"192.0.2.3", false, 0, false},
+	}
+	if diff := cmp.Diff(expectSummaries, summaries); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,180 @@
+// Package dnsinjection contains the dnsinjection experiment.
+//
+// This experiment sends DNS-over-UDP queries for sensitive and control domains
+// to IP addresses that do not run any DNS server (e.g., bogons). Because nothing should answer these queries, any
+// reply we receive for sensitive domains is injected by an on-path middlebox. We
+// keep listening after the first reply, as [netxlite.DNSOverUDPTransport] allows
+// us to do, to collect all the injected replies along with their TTLs and timing.
+//
+// Users may opt-in to also query an unused address inside the probe's network,
+// which we derive from the probe IP, using the `probe_network` target. We never
+// archive such an address, because it would reveal the probe's /24.
+package dnsinjection
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+const (
+	testName    = "dnsinjection"
+	testVersion = "0.2.0"
+)
+
+// Config contains the experiment configuration.
+type Config struct {
+	// ControlDomains is the space-separated list of control domains.
+	ControlDomains string `ooni:"space-separated list of domains that should not be censored"`
+
+	// Domains is the space-separated list of sensitive domains to measure.
+	Domains string `ooni:"space-separated list of sensitive domains to measure"`
+
+	// LateTimeout is the time to wait for late replies (in milliseconds).
+	LateTimeout int64 `ooni:"milliseconds to wait for additional replies after the first one"`
+
+	// Targets is the space-separated list of IP addresses not running any DNS
+	// server to which we send queries, where the opt-in `probe_network` is an
+	// address inside the probe's network that we derive from the probe IP.
+	Targets string `ooni:"space-separated list of IP addresses not running any DNS server"`
+}
+
+func (c Config) controlDomains() []string {
+	if c.ControlDomains != "" {
+		return strings.Fields(c.ControlDomains)
+	}
+	return []string{"example.com"}
+}
+
+func (c Config) domains() []string {
+	if c.Domains != "" {
+		return strings.Fields(c.Domains)
+	}
+	return []string{"www.facebook.com", "twitter.com", "www.youtube.com"}
+}
+
+func (c Config) lateTimeout() time.Duration {
+	if c.LateTimeout > 0 {
+		return time.Duration(c.LateTimeout) * time.Millisecond
+	}
+	return time.Second
+}
+
+func (c Config) targets() []string {
+	if c.Targets != "" {
+		return strings.Fields(c.Targets)
+	}
+	return []string{"10.255.255.254", "192.0.2.1"}
+}
+
+// Measurer performs the measurement.
+type Measurer struct {
+	config Config
+}
+
+// ExperimentName implements ExperimentMeasurer.ExperimentName.
+func (m *Measurer) ExperimentName() string {
+	return testName
+}
+
+// ExperimentVersion implements ExperimentMeasurer.ExperimentVersion.
+func (m *Measurer) ExperimentVersion() string {
+	return testVersion
+}
+
+// errNoTargets indicates that there are no targets to measure.
+var errNoTargets = errors.New("dnsinjection: no targets to measure")
+
+// Run implements ExperimentMeasurer.Run.
+func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	// unpack experiment args
+	_ = args.Callbacks
+	measurement := args.Measurement
+	sess := args.Session
+	logger := sess.Logger()
+
+	// determine the addresses to which we send queries
+	targets, err := resolveTargets(logger, sess.ProbeIP(), m.config.targets())
+	if err != nil {
+		return err
+	}
+	if len(targets) <= 0 {
+		return errNoTargets
+	}
+
+	// create the empty measurement test keys
+	tk := NewTestKeys()
+	measurement.TestKeys = tk
+
+	// query each target for each domain in parallel
+	wg := new(sync.WaitGroup)
+	var index int64
+	for _, target := range targets {
+		tk.addTargets(target)
+		for _, domain := range m.config.controlDomains() {
+			index++
+			wg.Add(1)
+			go m.query(ctx, index, measurement.MeasurementStartTimeSaved, logger, target, domain, true, wg, tk)
+		}
+		for _, domain := range m.config.domains() {
+			index++
+			wg.Add(1)
+			go m.query(ctx, index, measurement.MeasurementStartTimeSaved, logger, target, domain, false, wg, tk)
+		}
+	}
+	wg.Wait()
+
+	// classify the replies we have received
+	analyze(tk)
+
+	return nil // return nil so we always submit the measurement
+}
+
+// query sends queries for the given domain to the given target and
+// collects the first reply and any subsequent late reply.
+func (m *Measurer) query(ctx context.Context, index int64, zeroTime time.Time, logger model.Logger,
+	target *Target, domain string, control bool, wg *sync.WaitGroup, tk *TestKeys) {
+	// make sure we inform the parent when we're done
+	defer wg.Done()
+
+	// create trace for collecting information
+	trace := measurexlite.NewTrace(index, zeroTime)
+
+	// create dialer and resolver
+	dialer := trace.NewDialerWithoutResolver(logger)
+	resolver := trace.NewParallelUDPResolver(logger, dialer, target.Endpoint())
+
+	// perform the lookup proper
+	//
+	// Note: the DNS-over-UDP transport waits at most five seconds for the first reply
+	ol := logx.NewOperationLogger(logger, "DNSInjection #%d %s %s", index, target.Address, domain)
+	_, err := resolver.LookupHost(ctx, domain)
+	ol.Stop(err)
+
+	// wait a bit for late replies, which we only get if there is a first reply
+	delayedResps := trace.DelayedDNSResponseWithTimeout(ctx, m.config.lateTimeout())
+	if len(delayedResps) > 0 {
+		logger.Warnf("DNSInjection #%d... received %d late replies", index, len(delayedResps))
+	}
+
+	tk.addProbes(&SingleProbe{
+		Target:           target.Address,
+		Domain:           domain,
+		Control:          control,
+		Queries:          target.maybeSanitize(trace.DNSLookupsFromRoundTrip()),
+		DelayedResponses: target.maybeSanitize(delayedResps),
+		Replies:          []*Reply{},
+		Injected:         false,
+	})
+}
+
+// NewExperimentMeasurer creates a new ExperimentMeasurer.
+func NewExperimentMeasurer(config Config) model.ExperimentMeasurer {
+	return &Measurer{config: config}
+}
@@ -0,0 +1,197 @@
+package dnsinjection
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+)
+
+func TestMeasurerExperimentNameVersion(t *testing.T) {
+	measurer := NewExperimentMeasurer(Config{})
+	if measurer.ExperimentName() != "dnsinjection" {
+		t.Fatal("unexpected ExperimentName")
+	}
+	if measurer.ExperimentVersion() != "0.2.0" {
+		t.Fatal("unexpected ExperimentVersion")
+	}
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("default values", func(t *testing.T) {
+		c := Config{}
+		if diff := cmp.Diff([]string{"example.com"}, c.controlDomains()); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff([]string{"www.facebook.com", "twitter.com", "www.youtube.com"}, c.domains()); diff != "" {
+			t.Fatal(diff)
+		}
+		if c.lateTimeout() != time.Second {
+			t.Fatal("unexpected late timeout")
+		}
+		if diff := cmp.Diff([]string{"10.255.255.254", "192.0.2.1"}, c.targets()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("configured values", func(t *testing.T) {
+		c := Config{
+			ControlDomains: "example.org",
+			Domains:        "www.torproject.org  signal.org",
+			LateTimeout:    250,
+			Targets:        "192.0.2.17",
+		}
+		if diff := cmp.Diff([]string{"example.org"}, c.controlDomains()); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff([]string{"www.torproject.org", "signal.org"}, c.domains()); diff != "" {
+			t.Fatal(diff)
+		}
+		if c.lateTimeout() != 250*time.Millisecond {
+			t.Fatal("unexpected late timeout")
+		}
+		if diff := cmp.Diff([]string{"192.0.2.17"}, c.targets()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+// newSession returns a session using the given probe IP.
+func newSession(probeIP string) model.ExperimentSession {
+	return &mocks.Session{
+		MockLogger: func() model.Logger {
+			return log.Log
+		},
+		MockProbeIP: func() string {
+			return probeIP
+		},
+	}
+}
+
+// runHelper runs the experiment with the given config and probe IP.
+func runHelper(config Config, probeIP string) (*model.Measurement, error) {
+	measurer := NewExperimentMeasurer(config)
+	measurement := &model.Measurement{
+		MeasurementStartTimeSaved: time.Now(),
+	}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(log.Log),
+		Measurement: measurement,
+		Session:     newSession(probeIP),
+	}
+	err := measurer.Run(context.Background(), args)
+	return measurement, err
+}
+
+func TestMeasurerRun(t *testing.T) {
+	t.Run("with invalid target", func(t *testing.T) {
+		_, err := runHelper(Config{Targets: "dns.google"}, "130.192.91.211")
+		if !errors.Is(err, errInvalidTarget) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("without any target", func(t *testing.T) {
+		_, err := runHelper(Config{Targets: "probe_network"}, model.DefaultProbeIP)
+		if !errors.Is(err, errNoTargets) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("without injection", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+		env.Do(func() {
+			config := Config{
+				Domains:     "www.example.org",
+				LateTimeout: 100,
+				Targets:     "probe_network 192.0.2.1",
+			}
+			meas, err := runHelper(config, "130.192.91.211")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tk := meas.TestKeys.(*TestKeys)
+			if tk.Injection {
+				t.Fatal("expected no injection")
+			}
+			if len(tk.Targets) != 2 || len(tk.Probes) != 4 {
+				t.Fatal("unexpected number of targets or probes")
+			}
+			if tk.Targets[0].Address != "[scrubbed]" || tk.Targets[0].Kind != TargetKindProbeNetwork {
+				t.Fatal("unexpected first target", tk.Targets[0])
+			}
+			data, err := json.Marshal(tk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(data, []byte("130.192.91")) {
+				t.Fatal("the test keys contain the probe network", string(data))
+			}
+			for _, target := range tk.Targets {
+				if target.Status != TargetStatusNoReplies {
+					t.Fatal("unexpected status", target.Status)
+				}
+			}
+		})
+	})
+
+	t.Run("with injection", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+		env.DPIEngine().AddRule(&netem.DPISpoofDNSResponse{
+			Addresses: []string{"10.10.34.35"},
+			Logger:    log.Log,
+			Domain:    "www.example.org",
+		})
+		env.Do(func() {
+			config := Config{
+				Domains:     "www.example.org",
+				LateTimeout: 100,
+				Targets:     "192.0.2.1",
+			}
+			meas, err := runHelper(config, "130.192.91.211")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tk := meas.TestKeys.(*TestKeys)
+			if !tk.Injection {
+				t.Fatal("expected injection")
+			}
+			if len(tk.Targets) != 1 || tk.Targets[0].Status != TargetStatusInjection {
+				t.Fatal("unexpected targets")
+			}
+			if tk.Targets[0].Kind != TargetKindBogon {
+				t.Fatal("unexpected target kind", tk.Targets[0].Kind)
+			}
+			if len(tk.Probes) != 2 {
+				t.Fatal("unexpected number of probes")
+			}
+			control, sensitive := tk.Probes[0], tk.Probes[1]
+			if !control.Control || control.Injected || len(control.Replies) != 0 {
+				t.Fatal("unexpected control probe")
+			}
+			if sensitive.Control || !sensitive.Injected || len(sensitive.Replies) <= 0 {
+				t.Fatal("unexpected sensitive probe")
+			}
+			var found bool
+			for _, reply := range sensitive.Replies {
+				if reply.QueryType == "A" && len(reply.Answers) == 1 && reply.Answers[0] == "10.10.34.35" {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatal("did not find the injected reply")
+			}
+		})
+	})
+}
@@ -0,0 +1,122 @@
+package dnsinjection
+
+//
+// Targets to which we send DNS queries
+//
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/scrubber"
+)
+
+const (
+	// targetProbeNetwork is the configuration token for an address
+	// inside the probe's network, which we derive from the probe IP.
+	targetProbeNetwork = "probe_network"
+
+	// TargetKindBogon indicates that the target is a bogon.
+	TargetKindBogon = "bogon"
+
+	// TargetKindCustom indicates that the user configured the target.
+	TargetKindCustom = "custom"
+
+	// TargetKindProbeNetwork indicates that the target is inside the probe's network.
+	TargetKindProbeNetwork = "probe_network"
+)
+
+// Target is an IP address to which we send DNS queries.
+type Target struct {
+	// Address is the target IP address, which we scrub when the
+	// target is inside the probe's network.
+	Address string `json:"address"`
+
+	// Kind is one of the TargetKind constants.
+	Kind string `json:"kind"`
+
+	// Status is one of the TargetStatus constants.
+	Status string `json:"status"`
+
+	// address is the unscrubbed target IP address.
+	address string
+}
+
+// newTarget creates a new [*Target] for the given address and kind.
+func newTarget(address, kind string) *Target {
+	target := &Target{Address: address, Kind: kind, address: address}
+	if kind == TargetKindProbeNetwork {
+		// the address reveals the probe's /24, so we never archive it
+		target.Address = scrubber.ScrubString(address)
+	}
+	return target
+}
+
+// Endpoint returns the DNS-over-UDP endpoint for the target.
+func (t *Target) Endpoint() string {
+	return net.JoinHostPort(t.address, "53")
+}
+
+// maybeSanitize scrubs the resolver address and the failures of the given
+// lookups when the target is inside the probe's network. We cannot scrub
+// the whole lookups, as the tor experiment does, because that would also remove the
+// injected addresses, which are what we want to archive.
+func (t *Target) maybeSanitize(lookups []*model.ArchivalDNSLookupResult) []*model.ArchivalDNSLookupResult {
+	if t.Kind != TargetKindProbeNetwork {
+		return lookups
+	}
+	for _, lookup := range lookups {
+		lookup.ResolverAddress = scrubber.ScrubString(lookup.ResolverAddress)
+		if lookup.Failure != nil {
+			failure := scrubber.ScrubString(*lookup.Failure)
+			lookup.Failure = &failure
+		}
+	}
+	return lookups
+}
+
+// errInvalidTarget indicates that a configured target is not an IP address.
+var errInvalidTarget = errors.New("dnsinjection: invalid target")
+
+// resolveTargets converts the configured targets to a list of [*Target], using
+// the given probe IP to expand the [targetProbeNetwork] token. We skip such a token
+// when the probe IP is not a public IPv4 address (e.g., because we do not know it).
+func resolveTargets(logger model.Logger, probeIP string, targets []string) ([]*Target, error) {
+	out := []*Target{}
+	for _, target := range targets {
+		if target == targetProbeNetwork {
+			address, err := probeNetworkAddress(probeIP)
+			if err != nil {
+				logger.Warnf("dnsinjection: skipping %s: %s", targetProbeNetwork, err.Error())
+				continue
+			}
+			out = append(out, newTarget(address, TargetKindProbeNetwork))
+			continue
+		}
+		if net.ParseIP(target) == nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidTarget, target)
+		}
+		kind := TargetKindCustom
+		if netxlite.IsBogon(target) {
+			kind = TargetKindBogon
+		}
+		out = append(out, newTarget(target, kind))
+	}
+	return out, nil
+}
+
+// errNoProbeNetwork indicates that we cannot derive an address inside the probe's network.
+var errNoProbeNetwork = errors.New("the probe IP is not a public IPv4 address")
+
+// probeNetworkAddress returns the first address of the /24 containing the probe
+// IP, which is in the probe's ASN and is unlikely to run a DNS server.
+func probeNetworkAddress(probeIP string) (string, error) {
+	ip := net.ParseIP(probeIP).To4()
+	if ip == nil || netxlite.IsBogon(probeIP) {
+		return "", errNoProbeNetwork
+	}
+	return net.IPv4(ip[0], ip[1], ip[2], 0).String(), nil
+}
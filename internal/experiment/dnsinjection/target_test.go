@@ -0,0 +1,107 @@
+package dnsinjection
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestResolveTargets(t *testing.T) {
+	type testcase struct {
+		name      string
+		probeIP   string
+		targets   []string
+		expect    []*Target
+		expectErr error
+	}
+	cases := []testcase{{
+		name:    "with all kinds of targets",
+		probeIP: "130.192.91.211",
+		targets: []string{"probe_network", "10.255.255.254", "8.8.8.9", "::1"},
+		expect: []*Target{
+			{Address: "[scrubbed]", Kind: TargetKindProbeNetwork, address: "130.192.91.0"},
+			{Address: "10.255.255.254", Kind: TargetKindBogon, address: "10.255.255.254"},
+			{Address: "8.8.8.9", Kind: TargetKindCustom, address: "8.8.8.9"},
+			{Address: "::1", Kind: TargetKindBogon, address: "::1"},
+		},
+	}, {
+		name:    "we skip the probe network with unknown probe IP",
+		probeIP: model.DefaultProbeIP,
+		targets: []string{"probe_network", "192.0.2.1"},
+		expect: []*Target{
+			{Address: "192.0.2.1", Kind: TargetKindBogon, address: "192.0.2.1"},
+		},
+	}, {
+		name:    "we skip the probe network with IPv6 probe IP",
+		probeIP: "2001:4860:4860::8888",
+		targets: []string{"probe_network"},
+		expect:  []*Target{},
+	}, {
+		name:      "with invalid target",
+		probeIP:   "130.192.91.211",
+		targets:   []string{"192.0.2.1", "dns.google"},
+		expectErr: errInvalidTarget,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveTargets(model.DiscardLogger, tc.probeIP, tc.targets)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if tc.expectErr != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.expect, got, cmp.AllowUnexported(Target{})); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestTargetEndpoint(t *testing.T) {
+	t.Run("with a bogon", func(t *testing.T) {
+		if newTarget("::1", TargetKindBogon).Endpoint() != "[::1]:53" {
+			t.Fatal("unexpected endpoint")
+		}
+	})
+
+	t.Run("with the probe network", func(t *testing.T) {
+		if newTarget("130.192.91.0", TargetKindProbeNetwork).Endpoint() != "130.192.91.0:53" {
+			t.Fatal("unexpected endpoint")
+		}
+	})
+}
+
+func TestTargetMaybeSanitize(t *testing.T) {
+	newLookups := func() []*model.ArchivalDNSLookupResult {
+		failure := "unknown_failure: read udp 130.192.91.0:53: connection refused"
+		return []*model.ArchivalDNSLookupResult{{
+			Answers:         []model.ArchivalDNSAnswer{{AnswerType: "A", IPv4: "10.10.34.35"}},
+			Failure:         &failure,
+			ResolverAddress: "130.192.91.0:53",
+		}}
+	}
+
+	t.Run("with the probe network", func(t *testing.T) {
+		lookups := newTarget("130.192.91.0", TargetKindProbeNetwork).maybeSanitize(newLookups())
+		if lookups[0].ResolverAddress != "[scrubbed]" {
+			t.Fatal("unexpected resolver address", lookups[0].ResolverAddress)
+		}
+		if *lookups[0].Failure != "unknown_failure: read udp [scrubbed]: connection refused" {
+			t.Fatal("unexpected failure", *lookups[0].Failure)
+		}
+		if lookups[0].Answers[0].IPv4 != "10.10.34.35" {
+			t.Fatal("we should not scrub the answers")
+		}
+	})
+
+	t.Run("with other targets", func(t *testing.T) {
+		expect := newLookups()
+		got := newTarget("192.0.2.1", TargetKindBogon).maybeSanitize(newLookups())
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
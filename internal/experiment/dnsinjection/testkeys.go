@@ -0,0 +1,91 @@
+package dnsinjection
+
+import (
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// TestKeys contains the experiment results.
+type TestKeys struct {
+	// Targets contains the addresses to which we sent queries.
+	Targets []*Target `json:"targets"`
+
+	// Probes contains the results of querying each target for each domain.
+	Probes []*SingleProbe `json:"probes"`
+
+	// Injection is true if we received injected replies from any target.
+	Injection bool `json:"injection"`
+
+	// mu provides mutual exclusion
+	mu sync.Mutex
+}
+
+// SingleProbe contains the results of querying a target for a domain.
+type SingleProbe struct {
+	// Target is the address of the target.
+	Target string `json:"target"`
+
+	// Domain is the domain we queried for.
+	Domain string `json:"domain"`
+
+	// Control is true if the domain is a control domain.
+	Control bool `json:"control"`
+
+	// Queries contains the A and AAAA queries.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// DelayedResponses contains the replies received after the first one.
+	DelayedResponses []*model.ArchivalDNSLookupResult `json:"delayed_responses"`
+
+	// Replies summarizes all the replies we received including their TTLs.
+	Replies []*Reply `json:"replies"`
+
+	// Injected is true if we classified the replies as injected.
+	Injected bool `json:"injected"`
+}
+
+// Reply summarizes a DNS reply.
+type Reply struct {
+	// QueryType is the type of the query (e.g., "A").
+	QueryType string `json:"query_type"`
+
+	// Rcode is the response code.
+	Rcode int `json:"rcode"`
+
+	// Answers contains the IP addresses and CNAMEs in the answer section.
+	Answers []string `json:"answers"`
+
+	// TTLs contains the TTL of each answer.
+	TTLs []uint32 `json:"ttls"`
+
+	// T is the time when we received the reply relative to the beginning of the measurement.
+	T float64 `json:"t"`
+
+	// Late is true if the reply was received after the first one.
+	Late bool `json:"late"`
+}
+
+// NewTestKeys creates new dnsinjection TestKeys
+func NewTestKeys() *TestKeys {
+	return &TestKeys{
+		Targets:   []*Target{},
+		Probes:    []*SingleProbe{},
+		Injection: false,
+		mu:        sync.Mutex{},
+	}
+}
+
+// addTargets adds []*Target to the test keys
+func (tk *TestKeys) addTargets(targets ...*Target) {
+	tk.mu.Lock()
+	tk.Targets = append(tk.Targets, targets...)
+	tk.mu.Unlock()
+}
+
+// addProbes adds []*SingleProbe to the test keys
+func (tk *TestKeys) addProbes(probes ...*SingleProbe) {
+	tk.mu.Lock()
+	tk.Probes = append(tk.Probes, probes...)
+	tk.mu.Unlock()
+}
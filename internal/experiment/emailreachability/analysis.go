@@ -0,0 +1,16 @@
+package emailreachability
+
+//
+// Analysis of the endpoints results
+//
+
+// analyzeSTARTTLSStripping returns whether STARTTLS has been stripped from the
+// capabilities of the given endpoint, either because an uncensored vantage point
+// sees the server advertising STARTTLS but we do not, or because a middlebox
+// masked the capabilities (which some firewalls do for SMTP).
+func analyzeSTARTTLSStripping(ep *Endpoint) bool {
+	if ep.ImplicitTLS {
+		return false
+	}
+	return (ep.STARTTLSExpected && !ep.STARTTLSAdvertised) || hasMaskedCapability(ep.Capabilities)
+}
@@ -0,0 +1,39 @@
+package emailreachability
+
+import "testing"
+
+func TestAnalyzeSTARTTLSStripping(t *testing.T) {
+	type testcase struct {
+		name   string
+		ep     *Endpoint
+		expect bool
+	}
+	cases := []testcase{{
+		name:   "with STARTTLS advertised as expected",
+		ep:     &Endpoint{STARTTLSAdvertised: true, STARTTLSExpected: true},
+		expect: false,
+	}, {
+		name:   "with STARTTLS neither advertised nor expected",
+		ep:     &Endpoint{},
+		expect: false,
+	}, {
+		name:   "with STARTTLS expected but not advertised",
+		ep:     &Endpoint{STARTTLSExpected: true},
+		expect: true,
+	}, {
+		name:   "with masked capabilities",
+		ep:     &Endpoint{Capabilities: []string{"PIPELINING", "XXXXXXXA"}},
+		expect: true,
+	}, {
+		name:   "with implicit TLS",
+		ep:     &Endpoint{ImplicitTLS: true, STARTTLSExpected: true},
+		expect: false,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := analyzeSTARTTLSStripping(tc.ep); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package emailreachability
+
+//
+// Config for the email_reachability experiment
+//
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config contains the experiment configuration.
+type Config struct {
+	// ControlSTARTTLS is the space-separated list of ports where an uncensored
+	// vantage point sees the server advertising STARTTLS.
+	ControlSTARTTLS string `json:"control_starttls,omitempty" ooni:"space-separated list of ports where STARTTLS is advertised when measuring from an uncensored network"`
+
+	// Ports is the space-separated list of ports to measure.
+	Ports string `json:"ports,omitempty" ooni:"space-separated list of ports to measure"`
+
+	// Timeout is the timeout for measuring each endpoint (in milliseconds).
+	Timeout int64 `json:"timeout,omitempty" ooni:"timeout for measuring each endpoint in milliseconds"`
+}
+
+// errInvalidPort indicates that a port is not a mail port we know about.
+var errInvalidPort = errors.New("emailreachability: invalid mail port")
+
+// parsePorts parses a space-separated list of mail ports.
+func parsePorts(value string) ([]uint16, error) {
+	out := []uint16{}
+	for _, entry := range strings.Fields(value) {
+		port, err := strconv.ParseUint(entry, 10, 16)
+		if err != nil {
+			return nil, errInvalidPort
+		}
+		if _, found := protocols[uint16(port)]; !found {
+			return nil, errInvalidPort
+		}
+		if !slices.Contains(out, uint16(port)) {
+			out = append(out, uint16(port))
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+func (c Config) controlSTARTTLS() ([]uint16, error) {
+	return parsePorts(c.ControlSTARTTLS)
+}
+
+func (c Config) ports() ([]uint16, error) {
+	if c.Ports != "" {
+		return parsePorts(c.Ports)
+	}
+	return []uint16{25, 110, 143, 465, 587, 993, 995}, nil
+}
+
+func (c Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Millisecond
+	}
+	return 10 * time.Second
+}
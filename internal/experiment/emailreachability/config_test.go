@@ -0,0 +1,80 @@
+package emailreachability
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfig_ports(t *testing.T) {
+	type testcase struct {
+		name      string
+		input     string
+		expect    []uint16
+		expectErr error
+	}
+	cases := []testcase{{
+		name:   "with empty configuration",
+		input:  "",
+		expect: []uint16{25, 110, 143, 465, 587, 993, 995},
+	}, {
+		name:   "we sort and deduplicate the ports",
+		input:  "993 25 993",
+		expect: []uint16{25, 993},
+	}, {
+		name:      "with a port that is not a number",
+		input:     "25 antani",
+		expectErr: errInvalidPort,
+	}, {
+		name:      "with a port that is not a mail port",
+		input:     "25 443",
+		expectErr: errInvalidPort,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Config{Ports: tc.input}.ports()
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if diff := cmp.Diff(tc.expect, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestConfig_controlSTARTTLS(t *testing.T) {
+	c := Config{}
+	got, err := c.controlSTARTTLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatal("expected no ports by default")
+	}
+	c.ControlSTARTTLS = "587 25"
+	got, err = c.controlSTARTTLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]uint16{25, 587}, got); diff != "" {
+		t.Fatal(diff)
+	}
+	c.ControlSTARTTLS = "antani"
+	if _, err := c.controlSTARTTLS(); !errors.Is(err, errInvalidPort) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestConfig_timeout(t *testing.T) {
+	c := Config{}
+	if c.timeout() != 10*time.Second {
+		t.Fatal("invalid default timeout")
+	}
+	c.Timeout = 1000
+	if c.timeout() != 1*time.Second {
+		t.Fatal("invalid configured timeout")
+	}
+}
@@ -0,0 +1,18 @@
+// Package emailreachability implements the email_reachability experiment.
+//
+// This experiment connects to a mail server using SMTP (ports 25, 465, and 587),
+// IMAP (ports 143 and 993), and POP3 (ports 110 and 995). For each endpoint, it
+// reads the server banner, queries the server capabilities and upgrades the
+// connection to TLS, using either STARTTLS or implicit TLS depending on the port.
+//
+// Because the capabilities are sent in cleartext, a middlebox may remove STARTTLS
+// from the capabilities to prevent the client from upgrading to TLS, which is
+// known as STARTTLS stripping. To detect it, the ControlSTARTTLS option (which
+// is usually provided per-input using richer input) lists the ports where an
+// uncensored vantage point sees the server advertising STARTTLS. We also flag
+// as stripped SMTP capabilities masked with X characters (e.g., XXXXXXXA), which
+// is how some firewalls strip STARTTLS.
+//
+// The input is a mail://<domain> URL. Adding a port to the URL restricts the
+// measurement to that port, otherwise we measure all the configured ports.
+package emailreachability
@@ -0,0 +1,191 @@
+package emailreachability
+
+//
+// Measuring a single endpoint
+//
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+const (
+	// operationTCPConnect is the failed operation when we cannot connect.
+	operationTCPConnect = "tcp_connect"
+
+	// operationTLSHandshake is the failed operation when the TLS handshake fails.
+	operationTLSHandshake = "tls_handshake"
+
+	// operationBanner is the failed operation when reading the banner fails.
+	operationBanner = "banner"
+
+	// operationCapabilities is the failed operation when querying the capabilities fails.
+	operationCapabilities = "capabilities"
+
+	// operationSTARTTLS is the failed operation when the STARTTLS command fails.
+	operationSTARTTLS = "starttls"
+)
+
+var (
+	// errUnexpectedReply indicates that the server reply is not the expected one.
+	errUnexpectedReply = errors.New("unexpected_reply")
+
+	// errLineTooLong indicates that the server sent a line that is too long.
+	errLineTooLong = errors.New("line_too_long")
+)
+
+// newFailure is like [measurexlite.NewFailure] but archives protocol errors as is.
+func newFailure(err error) *string {
+	if errors.Is(err, errUnexpectedReply) || errors.Is(err, errLineTooLong) {
+		s := err.Error()
+		return &s
+	}
+	return measurexlite.NewFailure(err)
+}
+
+// session is a session with a mail server.
+type session struct {
+	conn     net.Conn
+	dialect  *dialect
+	ep       *Endpoint
+	reader   *bufio.Reader
+	tls      bool
+	zeroTime time.Time
+}
+
+// setConn sets the conn to use for the session.
+func (s *session) setConn(conn net.Conn, tls bool) {
+	s.conn, s.reader, s.tls = conn, bufio.NewReader(conn), tls
+}
+
+// record adds a line to the transcript.
+func (s *session) record(origin, line string) {
+	s.ep.Transcript = append(s.ep.Transcript, &TranscriptEntry{
+		Line:   line,
+		Origin: origin,
+		T:      time.Since(s.zeroTime).Seconds(),
+		TLS:    s.tls,
+	})
+}
+
+// roundTrip sends the command, unless it's empty, and reads the reply.
+func (s *session) roundTrip(command string) ([]string, error) {
+	if command != "" {
+		s.record("client", command)
+		if _, err := s.conn.Write([]byte(command + "\r\n")); err != nil {
+			return nil, err
+		}
+	}
+	reply := []string{}
+	for {
+		data, err := s.reader.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil, errLineTooLong
+		}
+		if err != nil {
+			return nil, err
+		}
+		line := strings.TrimRight(string(data), "\r\n")
+		s.record("server", line)
+		reply = append(reply, line)
+		if s.dialect.isFinal(command, line) {
+			break
+		}
+	}
+	if !s.dialect.isSuccess(command, reply) {
+		return nil, errUnexpectedReply
+	}
+	return reply, nil
+}
+
+// measureEndpoint measures the given endpoint and saves the results into ep.
+func (m *Measurer) measureEndpoint(ctx context.Context, config *Config, index int64, zeroTime time.Time,
+	logger model.Logger, domain string, proto *protocol, ep *Endpoint) {
+	ctx, cancel := context.WithTimeout(ctx, config.timeout())
+	defer cancel()
+	trace := measurexlite.NewTrace(index, zeroTime)
+	ol := logx.NewOperationLogger(logger, "EmailReachability[#%d] %s %s", index, proto.dialect.name, ep.Address)
+	defer func() {
+		ep.NetworkEvents = trace.NetworkEvents()
+		ep.TCPConnect = trace.FirstTCPConnectOrNil()
+		ep.TLSHandshake = trace.FirstTLSHandshakeOrNil()
+		if ep.Failure != nil {
+			ol.Stop(errors.New(*ep.Failure))
+			return
+		}
+		ol.Stop(nil)
+	}()
+	fail := func(operation string, err error) {
+		ep.FailedOperation = &operation
+		ep.Failure = newFailure(err)
+	}
+
+	// 1. establish a TCP connection
+	dialer := trace.NewDialerWithoutResolver(logger)
+	conn, err := dialer.DialContext(ctx, "tcp", ep.Address)
+	if err != nil {
+		fail(operationTCPConnect, err)
+		return
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	s := &session{dialect: proto.dialect, ep: ep, zeroTime: zeroTime}
+	s.setConn(conn, false)
+
+	// 2. handshake TLS right away when using implicit TLS
+	tlsConfig := &tls.Config{ServerName: domain}
+	handshaker := trace.NewTLSHandshakerStdlib(logger)
+	if proto.implicitTLS {
+		tlsConn, err := handshaker.Handshake(ctx, conn, tlsConfig)
+		if err != nil {
+			fail(operationTLSHandshake, err)
+			return
+		}
+		s.setConn(tlsConn, true)
+	}
+
+	// 3. read the banner
+	if ep.Banner, err = s.roundTrip(""); err != nil {
+		fail(operationBanner, err)
+		return
+	}
+
+	// 4. query the capabilities
+	reply, err := s.roundTrip(proto.dialect.capabilitiesCommand)
+	if err != nil {
+		fail(operationCapabilities, err)
+		return
+	}
+	ep.Capabilities = proto.dialect.parseCapabilities(reply)
+	ep.STARTTLSAdvertised = !proto.implicitTLS &&
+		hasCapability(ep.Capabilities, proto.dialect.starttlsCapability)
+	ep.STARTTLSStripped = analyzeSTARTTLSStripping(ep)
+
+	// 5. upgrade to TLS when the server advertises STARTTLS
+	if ep.STARTTLSAdvertised {
+		if _, err := s.roundTrip(proto.dialect.starttlsCommand); err != nil {
+			fail(operationSTARTTLS, err)
+			return
+		}
+		tlsConn, err := handshaker.Handshake(ctx, conn, tlsConfig)
+		if err != nil {
+			fail(operationTLSHandshake, err)
+			return
+		}
+		s.setConn(tlsConn, true)
+	}
+
+	// 6. politely close the session ignoring errors
+	_, _ = s.roundTrip(proto.dialect.quitCommand)
+}
@@ -0,0 +1,160 @@
+package emailreachability
+
+//
+// Measurer
+//
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/targetloading"
+)
+
+const (
+	testName    = "email_reachability"
+	testVersion = "0.1.0"
+)
+
+// Measurer performs the measurement.
+type Measurer struct{}
+
+// ExperimentName implements ExperimentMeasurer.ExperimentName.
+func (m *Measurer) ExperimentName() string {
+	return testName
+}
+
+// ExperimentVersion implements ExperimentMeasurer.ExperimentVersion.
+func (m *Measurer) ExperimentVersion() string {
+	return testVersion
+}
+
+var (
+	// ErrInputRequired indicates that no richer-input target was provided.
+	ErrInputRequired = targetloading.ErrInputRequired
+
+	// ErrInvalidInputType indicates that the richer-input target has the wrong type.
+	ErrInvalidInputType = targetloading.ErrInvalidInputType
+
+	// errNoInputProvided indicates you didn't provide any input
+	errNoInputProvided = errors.New("no input provided")
+
+	// errInputIsNotAnURL indicates that input is not an URL
+	errInputIsNotAnURL = errors.New("input is not an URL")
+
+	// errInvalidInputScheme indicates that the input scheme is invalid
+	errInvalidInputScheme = errors.New("input scheme must be mail")
+
+	// errMissingHost indicates that the input URL does not contain a host
+	errMissingHost = errors.New("input URL must include a host")
+)
+
+// Run implements ExperimentMeasurer.Run.
+func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	_ = args.Callbacks
+	measurement := args.Measurement
+	sess := args.Session
+
+	// obtain the richer-input target
+	if args.Target == nil {
+		return ErrInputRequired
+	}
+	target, ok := args.Target.(*Target)
+	if !ok {
+		return ErrInvalidInputType
+	}
+	config, input := target.Config, target.URL
+
+	if input == "" {
+		return errNoInputProvided
+	}
+	parsed, err := url.Parse(input)
+	if err != nil {
+		return errInputIsNotAnURL
+	}
+	if parsed.Scheme != "mail" {
+		return errInvalidInputScheme
+	}
+	if parsed.Hostname() == "" {
+		return errMissingHost
+	}
+	ports, err := config.ports()
+	if err != nil {
+		return err
+	}
+	if parsed.Port() != "" {
+		if ports, err = parsePorts(parsed.Port()); err != nil {
+			return err
+		}
+	}
+	controlSTARTTLS, err := config.controlSTARTTLS()
+	if err != nil {
+		return err
+	}
+	tk := NewTestKeys()
+	measurement.TestKeys = tk
+	tk.Domain = parsed.Hostname()
+	zeroTime := measurement.MeasurementStartTimeSaved
+	logger := sess.Logger()
+	// 1. resolve the mail server, which is a no-op for IP addresses
+	addrs, err := m.DNSLookup(ctx, zeroTime, logger, tk.Domain, tk)
+	if err != nil {
+		tk.Failure = measurexlite.NewFailure(err)
+		return nil // we want to submit this measurement
+	}
+	// 2. measure each endpoint in parallel and analyze the results
+	wg := new(sync.WaitGroup)
+	for _, addr := range addrs {
+		for _, port := range ports {
+			proto := protocols[port]
+			ep := &Endpoint{
+				Address:          net.JoinHostPort(addr, strconv.Itoa(int(port))),
+				ImplicitTLS:      proto.implicitTLS,
+				Protocol:         proto.dialect.name,
+				STARTTLSExpected: !proto.implicitTLS && slices.Contains(controlSTARTTLS, port),
+				Transcript:       []*TranscriptEntry{},
+			}
+			tk.Endpoints = append(tk.Endpoints, ep)
+			index := int64(len(tk.Endpoints))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				m.measureEndpoint(ctx, config, index, zeroTime, logger, tk.Domain, proto, ep)
+			}()
+		}
+	}
+	wg.Wait()
+	for _, ep := range tk.Endpoints {
+		tk.STARTTLSStripped = tk.STARTTLSStripped || ep.STARTTLSStripped
+	}
+	return nil
+}
+
+// DNSLookup resolves the domain using the system resolver and returns the addresses
+func (m *Measurer) DNSLookup(ctx context.Context, zeroTime time.Time,
+	logger model.Logger, domain string, tk *TestKeys) ([]string, error) {
+	if net.ParseIP(domain) != nil {
+		return []string{domain}, nil
+	}
+	trace := measurexlite.NewTrace(0, zeroTime)
+	ol := logx.NewOperationLogger(logger, "DNSLookup %s", domain)
+	resolver := trace.NewStdlibResolver(logger)
+	addrs, err := resolver.LookupHost(ctx, domain)
+	ol.Stop(err)
+	tk.Queries = append(tk.Queries, trace.DNSLookupsFromRoundTrip()...)
+	return addrs, err
+}
+
+// NewExperimentMeasurer creates a new ExperimentMeasurer.
+func NewExperimentMeasurer() *Measurer {
+	return &Measurer{}
+}
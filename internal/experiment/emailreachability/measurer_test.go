@@ -0,0 +1,277 @@
+package emailreachability
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/gopacket/layers"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestMeasurerExperimentNameVersion(t *testing.T) {
+	measurer := NewExperimentMeasurer()
+	if measurer.ExperimentName() != "email_reachability" {
+		t.Fatal("unexpected ExperimentName")
+	}
+	if measurer.ExperimentVersion() != "0.1.0" {
+		t.Fatal("unexpected ExperimentVersion")
+	}
+}
+
+func runHelper(ctx context.Context, target model.ExperimentTarget) (*model.Measurement, error) {
+	m := NewExperimentMeasurer()
+	meas := &model.Measurement{}
+	sess := &mocks.Session{
+		MockLogger: func() model.Logger {
+			return model.DiscardLogger
+		},
+	}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+		Measurement: meas,
+		Session:     sess,
+		Target:      target,
+	}
+	err := m.Run(ctx, args)
+	return meas, err
+}
+
+func TestMeasurer_input_failure(t *testing.T) {
+	type testcase struct {
+		name      string
+		target    model.ExperimentTarget
+		expectErr error
+	}
+	cases := []testcase{{
+		name:      "with no target",
+		target:    nil,
+		expectErr: ErrInputRequired,
+	}, {
+		name:      "with invalid target type",
+		target:    &model.OOAPIURLInfo{},
+		expectErr: ErrInvalidInputType,
+	}, {
+		name:      "with empty input",
+		target:    &Target{Config: &Config{}, URL: ""},
+		expectErr: errNoInputProvided,
+	}, {
+		name:      "with invalid URL",
+		target:    &Target{Config: &Config{}, URL: "\t"},
+		expectErr: errInputIsNotAnURL,
+	}, {
+		name:      "with invalid scheme",
+		target:    &Target{Config: &Config{}, URL: "smtp://mail.example.com"},
+		expectErr: errInvalidInputScheme,
+	}, {
+		name:      "with missing host",
+		target:    &Target{Config: &Config{}, URL: "mail:///"},
+		expectErr: errMissingHost,
+	}, {
+		name:      "with invalid port in the URL",
+		target:    &Target{Config: &Config{}, URL: "mail://mail.example.com:443"},
+		expectErr: errInvalidPort,
+	}, {
+		name:      "with invalid ports",
+		target:    &Target{Config: &Config{Ports: "antani"}, URL: "mail://mail.example.com"},
+		expectErr: errInvalidPort,
+	}, {
+		name:      "with invalid control STARTTLS ports",
+		target:    &Target{Config: &Config{ControlSTARTTLS: "antani"}, URL: "mail://mail.example.com"},
+		expectErr: errInvalidPort,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runHelper(context.Background(), tc.target)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
+
+func TestMeasurer_with_DNS_failure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // immediately fail the DNS lookup
+	meas, err := runHelper(ctx, &Target{Config: &Config{}, URL: "mail://mail.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := meas.TestKeys.(*TestKeys)
+	if tk.Failure == nil || *tk.Failure != netxlite.FailureInterrupted {
+		t.Fatal("unexpected failure", tk.Failure)
+	}
+	if len(tk.Endpoints) != 0 {
+		t.Fatal("expected no endpoints")
+	}
+}
+
+// findEndpoint returns the endpoint with the given address or fails the test.
+func findEndpoint(t *testing.T, tk *TestKeys, address string) *Endpoint {
+	for _, ep := range tk.Endpoints {
+		if ep.Address == address {
+			return ep
+		}
+	}
+	t.Fatal("cannot find endpoint", address)
+	return nil
+}
+
+func TestMeasurer_with_netem(t *testing.T) {
+	t.Run("without censorship", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+
+		env.Do(func() {
+			target := &Target{
+				Config: &Config{ControlSTARTTLS: "25 110 143 587"},
+				URL:    "mail://mail.example.com",
+			}
+			meas, err := runHelper(context.Background(), target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tk := meas.TestKeys.(*TestKeys)
+			if tk.Failure != nil {
+				t.Fatal("unexpected failure", *tk.Failure)
+			}
+			if tk.STARTTLSStripped {
+				t.Fatal("did not expect STARTTLS stripping")
+			}
+			if len(tk.Endpoints) != 7 {
+				t.Fatal("unexpected number of endpoints", len(tk.Endpoints))
+			}
+			for _, ep := range tk.Endpoints {
+				if ep.Failure != nil {
+					t.Fatal("unexpected failure for", ep.Address, *ep.Failure, *ep.FailedOperation)
+				}
+				if ep.TCPConnect == nil || ep.TLSHandshake == nil || ep.TLSHandshake.Failure != nil {
+					t.Fatal("expected successful TCP connect and TLS handshake for", ep.Address)
+				}
+				if ep.STARTTLSAdvertised == ep.ImplicitTLS {
+					t.Fatal("unexpected STARTTLS advertisement for", ep.Address)
+				}
+				if len(ep.NetworkEvents) <= 0 || len(ep.Transcript) <= 0 {
+					t.Fatal("expected network events and transcript for", ep.Address)
+				}
+			}
+			smtp := findEndpoint(t, tk, netemx.AddressMailExampleCom+":25")
+			last := smtp.Transcript[len(smtp.Transcript)-1]
+			if last.Origin != "server" || !last.TLS || last.Line != "221 2.0.0 Bye" {
+				t.Fatal("unexpected last transcript entry", last)
+			}
+		})
+	})
+
+	t.Run("with port 25 blocked", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+
+		env.DPIEngine().AddRule(&netem.DPIDropTrafficForServerEndpoint{
+			Logger:          model.DiscardLogger,
+			ServerIPAddress: netemx.AddressMailExampleCom,
+			ServerPort:      25,
+			ServerProtocol:  layers.IPProtocolTCP,
+		})
+
+		env.Do(func() {
+			target := &Target{
+				Config: &Config{Ports: "25 587", Timeout: 1000},
+				URL:    "mail://mail.example.com",
+			}
+			meas, err := runHelper(context.Background(), target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tk := meas.TestKeys.(*TestKeys)
+			blocked := findEndpoint(t, tk, netemx.AddressMailExampleCom+":25")
+			if blocked.Failure == nil || *blocked.Failure != netxlite.FailureGenericTimeoutError {
+				t.Fatal("unexpected failure", blocked.Failure)
+			}
+			if *blocked.FailedOperation != operationTCPConnect {
+				t.Fatal("unexpected failed operation", *blocked.FailedOperation)
+			}
+			if blocked.TCPConnect == nil || blocked.TLSHandshake != nil || len(blocked.Transcript) != 0 {
+				t.Fatal("expected only a failed TCP connect")
+			}
+			submission := findEndpoint(t, tk, netemx.AddressMailExampleCom+":587")
+			if submission.Failure != nil {
+				t.Fatal("unexpected failure", *submission.Failure)
+			}
+		})
+	})
+
+	t.Run("with a middlebox stripping STARTTLS", func(t *testing.T) {
+		env := netemx.MustNewQAEnv(netemx.QAEnvOptionNetStack(
+			netemx.AddressMailExampleCom,
+			&netemx.MailServerFactory{
+				MaskSTARTTLS:   true,
+				ServerNameMain: "mail.example.com",
+			},
+		))
+		defer env.Close()
+		env.AddRecordToAllResolvers("mail.example.com", "", netemx.AddressMailExampleCom)
+
+		env.Do(func() {
+			target := &Target{
+				Config: &Config{ControlSTARTTLS: "25 110 143 587", Ports: "25 110 143 993"},
+				URL:    "mail://mail.example.com",
+			}
+			meas, err := runHelper(context.Background(), target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tk := meas.TestKeys.(*TestKeys)
+			if !tk.STARTTLSStripped {
+				t.Fatal("expected STARTTLS stripping")
+			}
+			expectStripped := map[string]bool{
+				netemx.AddressMailExampleCom + ":25":  true,
+				netemx.AddressMailExampleCom + ":110": true,
+				netemx.AddressMailExampleCom + ":143": true,
+				netemx.AddressMailExampleCom + ":993": false,
+			}
+			for address, expect := range expectStripped {
+				ep := findEndpoint(t, tk, address)
+				if ep.Failure != nil {
+					t.Fatal("unexpected failure for", address, *ep.Failure)
+				}
+				if ep.STARTTLSStripped != expect {
+					t.Fatal("unexpected STARTTLS stripping for", address)
+				}
+			}
+			smtp := findEndpoint(t, tk, netemx.AddressMailExampleCom+":25")
+			if smtp.TLSHandshake != nil {
+				t.Fatal("did not expect a TLS handshake")
+			}
+		})
+	})
+
+	t.Run("with SMTP capabilities masked but not expected", func(t *testing.T) {
+		env := netemx.MustNewQAEnv(netemx.QAEnvOptionNetStack(
+			netemx.AddressMailExampleCom,
+			&netemx.MailServerFactory{
+				MaskSTARTTLS:   true,
+				ServerNameMain: "mail.example.com",
+			},
+		))
+		defer env.Close()
+		env.AddRecordToAllResolvers("mail.example.com", "", netemx.AddressMailExampleCom)
+
+		env.Do(func() {
+			target := &Target{Config: &Config{}, URL: "mail://mail.example.com:587"}
+			meas, err := runHelper(context.Background(), target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tk := meas.TestKeys.(*TestKeys)
+			if !tk.STARTTLSStripped || !tk.Endpoints[0].STARTTLSStripped {
+				t.Fatal("expected STARTTLS stripping because of masked capabilities")
+			}
+		})
+	})
+}
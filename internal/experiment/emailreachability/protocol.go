@@ -0,0 +1,171 @@
+package emailreachability
+
+//
+// Mail protocols dialects
+//
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// dialect describes how to speak a line-oriented mail protocol.
+type dialect struct {
+	// capabilitiesCommand is the command to query the server capabilities.
+	capabilitiesCommand string
+
+	// isFinal returns whether line is the last line of the reply to command,
+	// where the command is empty when we're reading the banner.
+	isFinal func(command, line string) bool
+
+	// isSuccess returns whether the reply to command is successful.
+	isSuccess func(command string, reply []string) bool
+
+	// name is the protocol name.
+	name string
+
+	// parseCapabilities returns the capabilities from a successful reply.
+	parseCapabilities func(reply []string) []string
+
+	// quitCommand is the command to close the session.
+	quitCommand string
+
+	// starttlsCapability is the capability advertising STARTTLS.
+	starttlsCapability string
+
+	// starttlsCommand is the command to upgrade the connection to TLS.
+	starttlsCommand string
+}
+
+// protocol describes the protocol spoken on a given port.
+type protocol struct {
+	// dialect is the protocol dialect.
+	dialect *dialect
+
+	// implicitTLS indicates that we should handshake TLS right after connecting.
+	implicitTLS bool
+}
+
+var (
+	// smtpDialect is the SMTP dialect (see RFC 5321 and RFC 3207).
+	smtpDialect = &dialect{
+		capabilitiesCommand: "EHLO localhost",
+		isFinal: func(command, line string) bool {
+			return len(line) < 4 || line[3] != '-'
+		},
+		isSuccess: func(command string, reply []string) bool {
+			expect := map[string]string{
+				"":               "220",
+				"EHLO localhost": "250",
+				"STARTTLS":       "220",
+				"QUIT":           "221",
+			}
+			return strings.HasPrefix(reply[len(reply)-1], expect[command])
+		},
+		name: "smtp",
+		parseCapabilities: func(reply []string) (out []string) {
+			// the first line of the reply is the server greeting
+			for _, line := range reply[1:] {
+				if len(line) > 4 {
+					out = append(out, line[4:])
+				}
+			}
+			return
+		},
+		quitCommand:        "QUIT",
+		starttlsCapability: "STARTTLS",
+		starttlsCommand:    "STARTTLS",
+	}
+
+	// imapDialect is the IMAP dialect (see RFC 9051).
+	imapDialect = &dialect{
+		capabilitiesCommand: "a001 CAPABILITY",
+		isFinal: func(command, line string) bool {
+			if command == "" {
+				return true // the banner is a single untagged line
+			}
+			return strings.HasPrefix(line, imapTag(command)+" ")
+		},
+		isSuccess: func(command string, reply []string) bool {
+			last := reply[len(reply)-1]
+			if command == "" {
+				return strings.HasPrefix(last, "* OK") || strings.HasPrefix(last, "* PREAUTH")
+			}
+			return strings.HasPrefix(last, imapTag(command)+" OK")
+		},
+		name: "imap",
+		parseCapabilities: func(reply []string) (out []string) {
+			for _, line := range reply {
+				if value, found := strings.CutPrefix(line, "* CAPABILITY "); found {
+					out = append(out, strings.Fields(value)...)
+				}
+			}
+			return
+		},
+		quitCommand:        "a003 LOGOUT",
+		starttlsCapability: "STARTTLS",
+		starttlsCommand:    "a002 STARTTLS",
+	}
+
+	// pop3Dialect is the POP3 dialect (see RFC 1939 and RFC 2595).
+	pop3Dialect = &dialect{
+		capabilitiesCommand: "CAPA",
+		isFinal: func(command, line string) bool {
+			// only the reply to CAPA is multi-line, unless it's an error
+			return command != "CAPA" || line == "." || strings.HasPrefix(line, "-ERR")
+		},
+		isSuccess: func(command string, reply []string) bool {
+			return strings.HasPrefix(reply[0], "+OK")
+		},
+		name: "pop3",
+		parseCapabilities: func(reply []string) []string {
+			// skip the status line and the terminating dot
+			return slices.Clone(reply[1 : len(reply)-1])
+		},
+		quitCommand:        "QUIT",
+		starttlsCapability: "STLS",
+		starttlsCommand:    "STLS",
+	}
+)
+
+// imapTag returns the tag of an IMAP command.
+func imapTag(command string) string {
+	tag, _, _ := strings.Cut(command, " ")
+	return tag
+}
+
+// protocols maps each port we know about to the protocol spoken on it.
+var protocols = map[uint16]*protocol{
+	25:  {dialect: smtpDialect, implicitTLS: false},
+	110: {dialect: pop3Dialect, implicitTLS: false},
+	143: {dialect: imapDialect, implicitTLS: false},
+	465: {dialect: smtpDialect, implicitTLS: true},
+	587: {dialect: smtpDialect, implicitTLS: false},
+	993: {dialect: imapDialect, implicitTLS: true},
+	995: {dialect: pop3Dialect, implicitTLS: true},
+}
+
+// maskedCapability matches SMTP capabilities that a middlebox has overwritten with
+// X characters, such as XXXXXXXA, which is how some firewalls strip STARTTLS.
+var maskedCapability = regexp.MustCompile(`^X{4,}A?$`)
+
+// hasCapability returns whether the given capability is in the list.
+func hasCapability(capabilities []string, capability string) bool {
+	for _, entry := range capabilities {
+		if strings.EqualFold(entry, capability) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMaskedCapability returns whether one of the capabilities is masked.
+func hasMaskedCapability(capabilities []string) bool {
+	for _, entry := range capabilities {
+		if maskedCapability.MatchString(entry) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package emailreachability
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDialects(t *testing.T) {
+	type testcase struct {
+		name         string
+		dialect      *dialect
+		command      string
+		reply        []string
+		expectOK     bool
+		expectCapabs []string
+	}
+	cases := []testcase{{
+		name:     "SMTP banner",
+		dialect:  smtpDialect,
+		command:  "",
+		reply:    []string{"220-mail.example.com ESMTP", "220 ready"},
+		expectOK: true,
+	}, {
+		name:     "SMTP service not available",
+		dialect:  smtpDialect,
+		command:  "",
+		reply:    []string{"554 no SMTP service here"},
+		expectOK: false,
+	}, {
+		name:         "SMTP capabilities",
+		dialect:      smtpDialect,
+		command:      "EHLO localhost",
+		reply:        []string{"250-mail.example.com", "250-PIPELINING", "250-STARTTLS", "250 8BITMIME"},
+		expectOK:     true,
+		expectCapabs: []string{"PIPELINING", "STARTTLS", "8BITMIME"},
+	}, {
+		name:     "SMTP STARTTLS refused",
+		dialect:  smtpDialect,
+		command:  "STARTTLS",
+		reply:    []string{"502 5.5.2 Error: command not recognized"},
+		expectOK: false,
+	}, {
+		name:     "IMAP banner",
+		dialect:  imapDialect,
+		command:  "",
+		reply:    []string{"* OK IMAP4rev1 ready"},
+		expectOK: true,
+	}, {
+		name:         "IMAP capabilities",
+		dialect:      imapDialect,
+		command:      "a001 CAPABILITY",
+		reply:        []string{"* CAPABILITY IMAP4rev1 STARTTLS", "a001 OK CAPABILITY completed"},
+		expectOK:     true,
+		expectCapabs: []string{"IMAP4rev1", "STARTTLS"},
+	}, {
+		name:     "IMAP STARTTLS refused",
+		dialect:  imapDialect,
+		command:  "a002 STARTTLS",
+		reply:    []string{"a002 BAD unknown command"},
+		expectOK: false,
+	}, {
+		name:     "POP3 banner",
+		dialect:  pop3Dialect,
+		command:  "",
+		reply:    []string{"+OK POP3 ready"},
+		expectOK: true,
+	}, {
+		name:         "POP3 capabilities",
+		dialect:      pop3Dialect,
+		command:      "CAPA",
+		reply:        []string{"+OK Capability list follows", "USER", "STLS", "."},
+		expectOK:     true,
+		expectCapabs: []string{"USER", "STLS"},
+	}, {
+		name:     "POP3 capabilities not supported",
+		dialect:  pop3Dialect,
+		command:  "CAPA",
+		reply:    []string{"-ERR unknown command"},
+		expectOK: false,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for idx, line := range tc.reply {
+				if final := tc.dialect.isFinal(tc.command, line); final != (idx == len(tc.reply)-1) {
+					t.Fatal("unexpected isFinal result for", line)
+				}
+			}
+			if ok := tc.dialect.isSuccess(tc.command, tc.reply); ok != tc.expectOK {
+				t.Fatal("unexpected isSuccess result", ok)
+			}
+			if !tc.expectOK || tc.command != tc.dialect.capabilitiesCommand {
+				return
+			}
+			if diff := cmp.Diff(tc.expectCapabs, tc.dialect.parseCapabilities(tc.reply)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestHasCapability(t *testing.T) {
+	capabilities := []string{"PIPELINING", "starttls"}
+	if !hasCapability(capabilities, "STARTTLS") {
+		t.Fatal("expected to find STARTTLS")
+	}
+	if hasCapability(capabilities, "STLS") {
+		t.Fatal("did not expect to find STLS")
+	}
+}
+
+func TestHasMaskedCapability(t *testing.T) {
+	type testcase struct {
+		capabilities []string
+		expect       bool
+	}
+	cases := []testcase{{
+		capabilities: []string{"PIPELINING", "XXXXXXXA"},
+		expect:       true,
+	}, {
+		capabilities: []string{"XXXXXXXXXXXXX"},
+		expect:       true,
+	}, {
+		capabilities: []string{"PIPELINING", "STARTTLS", "XCLIENT"},
+		expect:       false,
+	}}
+	for _, tc := range cases {
+		if got := hasMaskedCapability(tc.capabilities); got != tc.expect {
+			t.Fatal("unexpected result for", tc.capabilities)
+		}
+	}
+}
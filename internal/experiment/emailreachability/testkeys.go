@@ -0,0 +1,91 @@
+package emailreachability
+
+import "github.com/ooni/probe-cli/v3/internal/model"
+
+// TestKeys contains the experiment results
+type TestKeys struct {
+	// Domain is the mail server domain we measured.
+	Domain string `json:"domain"`
+
+	// Endpoints contains the result of measuring each endpoint.
+	Endpoints []*Endpoint `json:"endpoints"`
+
+	// Failure is the failure that prevented us from measuring the endpoints.
+	Failure *string `json:"failure"`
+
+	// Queries contains the DNS lookup, if the input host is a domain.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// STARTTLSStripped indicates that STARTTLS was stripped for at least one endpoint.
+	STARTTLSStripped bool `json:"starttls_stripped"`
+}
+
+// NewTestKeys creates new email_reachability TestKeys
+func NewTestKeys() *TestKeys {
+	return &TestKeys{
+		Endpoints: []*Endpoint{},
+		Queries:   []*model.ArchivalDNSLookupResult{},
+	}
+}
+
+// Endpoint contains the result of measuring a single endpoint
+type Endpoint struct {
+	// Address is the endpoint address.
+	Address string `json:"address"`
+
+	// Banner is the server banner.
+	Banner []string `json:"banner"`
+
+	// Capabilities contains the capabilities advertised by the server
+	// before upgrading the connection to TLS.
+	Capabilities []string `json:"capabilities"`
+
+	// FailedOperation is the operation that failed or nil.
+	FailedOperation *string `json:"failed_operation"`
+
+	// Failure is the failure that occurred or nil.
+	Failure *string `json:"failure"`
+
+	// ImplicitTLS indicates that we handshake TLS right after connecting.
+	ImplicitTLS bool `json:"implicit_tls"`
+
+	// NetworkEvents contains the network events.
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+
+	// Protocol is the protocol spoken on this endpoint (e.g., "smtp").
+	Protocol string `json:"protocol"`
+
+	// STARTTLSAdvertised indicates that the server advertised STARTTLS.
+	STARTTLSAdvertised bool `json:"starttls_advertised"`
+
+	// STARTTLSExpected indicates that the server advertises STARTTLS when
+	// measuring from an uncensored vantage point.
+	STARTTLSExpected bool `json:"starttls_expected"`
+
+	// STARTTLSStripped indicates that a middlebox stripped STARTTLS.
+	STARTTLSStripped bool `json:"starttls_stripped"`
+
+	// TCPConnect is the TCP connect result.
+	TCPConnect *model.ArchivalTCPConnectResult `json:"tcp_connect"`
+
+	// TLSHandshake is the TLS handshake result or nil.
+	TLSHandshake *model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshake"`
+
+	// Transcript contains the lines exchanged with the server.
+	Transcript []*TranscriptEntry `json:"transcript"`
+}
+
+// TranscriptEntry is a line exchanged with the server
+type TranscriptEntry struct {
+	// Line is the line without the trailing CRLF.
+	Line string `json:"line"`
+
+	// Origin is either "client" or "server".
+	Origin string `json:"origin"`
+
+	// T is the time when we sent or received the line relative to the zero time.
+	T float64 `json:"t"`
+
+	// TLS indicates that we exchanged the line over TLS.
+	TLS bool `json:"tls"`
+}
@@ -1,59 +1,33 @@
 // Package fbmessenger contains the Facebook Messenger network experiment.
 //
 // See https://github.com/ooni/spec/blob/master/nettests/ts-019-facebook-messenger.md
+//
+// We measure the endpoints listed by the "facebook_messenger" service definition (see
+// the internal/servicedefs package) using the service_reachability engine.
 package fbmessenger
 
 import (
 	"context"
-	"math/rand"
-	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
+	"github.com/ooni/probe-cli/v3/internal/experiment/servicereachability"
 	"github.com/ooni/probe-cli/v3/internal/model"
-	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 const (
 	// FacebookASN is Facebook's ASN
 	FacebookASN = 32934
 
-	// ServiceSTUN is the STUN service
-	ServiceSTUN = "dnslookup://stun.fbsbx.com"
-
-	// ServiceBAPI is the b-api service
-	ServiceBAPI = "tcpconnect://b-api.facebook.com:443"
-
-	// ServiceBGraph is the b-graph service
-	ServiceBGraph = "tcpconnect://b-graph.facebook.com:443"
-
-	// ServiceEdge is the edge service
-	ServiceEdge = "tcpconnect://edge-mqtt.facebook.com:443"
-
-	// ServiceExternalCDN is the external CDN service
-	ServiceExternalCDN = "tcpconnect://external.xx.fbcdn.net:443"
-
-	// ServiceScontentCDN is the scontent CDN service
-	ServiceScontentCDN = "tcpconnect://scontent.xx.fbcdn.net:443"
-
-	// ServiceStar is the star service
-	ServiceStar = "tcpconnect://star.c10r.facebook.com:443"
-
 	testName    = "facebook_messenger"
-	testVersion = "0.2.1"
+	testVersion = "0.3.0"
 )
 
-// Services is the list of services we're testing.
-var Services = []string{
-	ServiceSTUN, ServiceBAPI, ServiceBGraph, ServiceEdge, ServiceExternalCDN,
-	ServiceScontentCDN, ServiceStar,
-}
-
 // Config contains the experiment config.
 type Config struct{}
 
 // TestKeys contains the experiment results
 type TestKeys struct {
-	urlgetter.TestKeys
+	*servicereachability.TestKeys
+	servicereachability.LegacyTestKeys
 	Analysis
 }
 
@@ -77,73 +51,56 @@ type Analysis struct {
 	FacebookTCPBlocking              *bool `json:"facebook_tcp_blocking"`
 }
 
-// Update updates the TestKeys using the given MultiOutput result.
-func (tk *TestKeys) Update(v urlgetter.MultiOutput) {
-	// Update the easy to update entries first
-	tk.NetworkEvents = append(tk.NetworkEvents, v.TestKeys.NetworkEvents...)
-	tk.Queries = append(tk.Queries, v.TestKeys.Queries...)
-	tk.Requests = append(tk.Requests, v.TestKeys.Requests...)
-	tk.TCPConnect = append(tk.TCPConnect, v.TestKeys.TCPConnect...)
-	tk.TLSHandshakes = append(tk.TLSHandshakes, v.TestKeys.TLSHandshakes...)
-	// Set the status of endpoints
-	switch v.Input.Target {
-	case ServiceSTUN:
-		var ignored *bool
-		tk.ComputeEndpointStatus(v, &tk.FacebookSTUNDNSConsistent, &ignored)
-	case ServiceBAPI:
-		tk.ComputeEndpointStatus(
-			v, &tk.FacebookBAPIDNSConsistent, &tk.FacebookBAPIReachable)
-	case ServiceBGraph:
-		tk.ComputeEndpointStatus(
-			v, &tk.FacebookBGraphDNSConsistent, &tk.FacebookBGraphReachable)
-	case ServiceEdge:
-		tk.ComputeEndpointStatus(
-			v, &tk.FacebookEdgeDNSConsistent, &tk.FacebookEdgeReachable)
-	case ServiceExternalCDN:
-		tk.ComputeEndpointStatus(
-			v, &tk.FacebookExternalCDNDNSConsistent, &tk.FacebookExternalCDNReachable)
-	case ServiceScontentCDN:
-		tk.ComputeEndpointStatus(
-			v, &tk.FacebookScontentCDNDNSConsistent, &tk.FacebookScontentCDNReachable)
-	case ServiceStar:
-		tk.ComputeEndpointStatus(
-			v, &tk.FacebookStarDNSConsistent, &tk.FacebookStarReachable)
-	}
-}
-
 var (
 	trueValue  = true
 	falseValue = false
 )
 
-// ComputeEndpointStatus computes the DNS and TCP status of a specific endpoint.
-func (tk *TestKeys) ComputeEndpointStatus(v urlgetter.MultiOutput, dns, tcp **bool) {
+// computeAnalysis sets the analysis fields using the verdict of each component. We
+// leave the fields of components missing from the service definition set to nil.
+func (tk *TestKeys) computeAnalysis() {
+	var ignored *bool
+	tk.computeEndpointStatus("stun", &tk.FacebookSTUNDNSConsistent, &ignored)
+	tk.computeEndpointStatus("b_api", &tk.FacebookBAPIDNSConsistent, &tk.FacebookBAPIReachable)
+	tk.computeEndpointStatus("b_graph", &tk.FacebookBGraphDNSConsistent, &tk.FacebookBGraphReachable)
+	tk.computeEndpointStatus("edge", &tk.FacebookEdgeDNSConsistent, &tk.FacebookEdgeReachable)
+	tk.computeEndpointStatus(
+		"external_cdn", &tk.FacebookExternalCDNDNSConsistent, &tk.FacebookExternalCDNReachable)
+	tk.computeEndpointStatus(
+		"scontent_cdn", &tk.FacebookScontentCDNDNSConsistent, &tk.FacebookScontentCDNReachable)
+	tk.computeEndpointStatus("star", &tk.FacebookStarDNSConsistent, &tk.FacebookStarReachable)
+	// if we haven't yet determined the status of DNS blocking and TCP blocking
+	// then no blocking has been detected and we can set them
+	if tk.FacebookDNSBlocking == nil {
+		tk.FacebookDNSBlocking = &falseValue
+	}
+	if tk.FacebookTCPBlocking == nil {
+		tk.FacebookTCPBlocking = &falseValue
+	}
+}
+
+// computeEndpointStatus computes the DNS and TCP status of a specific component.
+func (tk *TestKeys) computeEndpointStatus(name string, dns, tcp **bool) {
 	// start where all is unknown
 	*dns, *tcp = nil, nil
-	// process DNS first
-	if v.TestKeys.FailedOperation != nil && *v.TestKeys.FailedOperation == netxlite.ResolveOperation {
-		tk.FacebookDNSBlocking = &trueValue
-		*dns = &falseValue
-		return // we know that the DNS has failed
+	cr, found := tk.Components[name]
+	if !found {
+		return
 	}
-	for _, query := range v.TestKeys.Queries {
-		for _, ans := range query.Answers {
-			if ans.ASN != FacebookASN {
-				tk.FacebookDNSBlocking = &trueValue
-				*dns = &falseValue
-				return // because DNS is lying
-			}
-		}
+	if !cr.Blocked() {
+		*dns, *tcp = &trueValue, &trueValue
+		return
 	}
-	*dns = &trueValue
-	// now process connect
-	if v.TestKeys.FailedOperation != nil && *v.TestKeys.FailedOperation == netxlite.ConnectOperation {
+	switch {
+	case cr.Blocking != nil && *cr.Blocking == "dns":
+		// the DNS has failed or is lying
+		tk.FacebookDNSBlocking = &trueValue
+		*dns = &falseValue
+	case cr.Blocking != nil && *cr.Blocking == "tcp":
+		// the DNS is fine but connect failed
 		tk.FacebookTCPBlocking = &trueValue
-		*tcp = &falseValue
-		return // because connect failed
+		*dns, *tcp = &trueValue, &falseValue
 	}
-	// all good
-	*tcp = &trueValue
 }
 
 // Measurer performs the measurement
@@ -151,9 +108,6 @@ type Measurer struct {
 	// Config contains the experiment settings. If empty we
 	// will be using default settings.
 	Config Config
-
-	// Getter is an optional getter to be used for testing.
-	Getter urlgetter.MultiGetter
 }
 
 // ExperimentName implements ExperimentMeasurer.ExperimentName
@@ -168,37 +122,17 @@ func (m Measurer) ExperimentVersion() string {
 
 // Run implements ExperimentMeasurer.Run
 func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
-	callbacks := args.Callbacks
-	measurement := args.Measurement
-	sess := args.Session
-	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
-	defer cancel()
-	urlgetter.RegisterExtensions(measurement)
-	// generate targets
-	var inputs []urlgetter.MultiInput
-	for _, service := range Services {
-		inputs = append(inputs, urlgetter.MultiInput{Target: service})
-	}
-	rnd := rand.New(rand.NewSource(time.Now().UnixNano())) // #nosec G404 -- not really important
-	rnd.Shuffle(len(inputs), func(i, j int) {
-		inputs[i], inputs[j] = inputs[j], inputs[i]
-	})
-	// measure in parallel
-	multi := urlgetter.Multi{Begin: time.Now(), Getter: m.Getter, Session: sess}
-	testkeys := new(TestKeys)
-	testkeys.Agent = "redirect"
-	measurement.TestKeys = testkeys
-	for entry := range multi.Collect(ctx, inputs, "facebook_messenger", callbacks) {
-		testkeys.Update(entry)
-	}
-	// if we haven't yet determined the status of DNS blocking and TCP blocking
-	// then no blocking has been detected and we can set them
-	if testkeys.FacebookDNSBlocking == nil {
-		testkeys.FacebookDNSBlocking = &falseValue
+	def, err := servicereachability.LoadDefinition(args.Session, testName)
+	if err != nil {
+		return err
 	}
-	if testkeys.FacebookTCPBlocking == nil {
-		testkeys.FacebookTCPBlocking = &falseValue
+	testkeys := &TestKeys{
+		TestKeys:       servicereachability.NewTestKeys(),
+		LegacyTestKeys: servicereachability.NewLegacyTestKeys(),
 	}
+	args.Measurement.TestKeys = testkeys
+	servicereachability.Measure(ctx, args, def, testkeys.TestKeys)
+	testkeys.computeAnalysis()
 	return nil
 }
 
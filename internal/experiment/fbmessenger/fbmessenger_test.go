@@ -2,36 +2,61 @@ package fbmessenger_test
 
 import (
 	"context"
-	"io"
-	"net/url"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/gopacket/layers"
 	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/checkincache"
 	"github.com/ooni/probe-cli/v3/internal/experiment/fbmessenger"
-	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netemx"
-	"github.com/ooni/probe-cli/v3/internal/netxlite"
-	"github.com/ooni/probe-cli/v3/internal/runtimex"
 )
 
 // servicesAddr is the IP address implementing al fbmessenger services in netem-based tests
 const servicesAddr = "157.240.20.35"
 
-// configureDNSWithAddr is like [configureDNS] but uses a specific addr.
+// servicesDomains contains the domains used by the embedded facebook_messenger definition.
+var servicesDomains = []string{
+	"stun.fbsbx.com",
+	"b-api.facebook.com",
+	"b-graph.facebook.com",
+	"edge-mqtt.facebook.com",
+	"external.xx.fbcdn.net",
+	"scontent.xx.fbcdn.net",
+	"star.c10r.facebook.com",
+}
+
+// configureDNSWithAddr is like [configureDNSWithDefaults] but uses a specific addr.
 func configureDNSWithAddr(config *netem.DNSConfig, addr string) {
-	for _, svc := range fbmessenger.Services {
-		hostname := runtimex.Try1(url.Parse(svc)).Hostname()
+	for _, hostname := range servicesDomains {
 		config.AddRecord(hostname, hostname, addr)
 	}
 }
 
+// newSession creates a session whose key-value store contains the given service definitions.
+func newSession(t *testing.T, defs string) model.ExperimentSession {
+	kvStore := &kvstore.Memory{}
+	if defs != "" {
+		resp := &model.OOAPICheckInResult{
+			Conf: model.OOAPICheckInResultConfig{
+				ServiceDefinitions: []byte(defs),
+			},
+		}
+		if err := checkincache.Store(kvStore, resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &mocks.Session{
+		MockLogger:        func() model.Logger { return model.DiscardLogger },
+		MockKeyValueStore: func() model.KeyValueStore { return kvStore },
+	}
+}
+
 // configureDNSWithDefaults configures the given [*netem.DNSConfig] with all the required domains
-// listed in [fbmessenger.Services] using [servicesAddr] as the IP address.
+// listed in [servicesDomains] using [servicesAddr] as the IP address.
 func configureDNSWithDefaults(config *netem.DNSConfig) {
 	configureDNSWithAddr(config, servicesAddr)
 }
@@ -46,7 +71,7 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "facebook_messenger" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.2.1" {
+	if measurer.ExperimentVersion() != "0.3.0" {
 		t.Fatal("unexpected version")
 	}
 }
@@ -98,7 +123,7 @@ func TestMeasurerRun(t *testing.T) {
 		env.Do(func() {
 			measurer := fbmessenger.NewExperimentMeasurer(fbmessenger.Config{})
 			ctx := context.Background()
-			sess := &mocks.Session{MockLogger: func() model.Logger { return model.DiscardLogger }}
+			sess := newSession(t, "")
 			measurement := new(model.Measurement)
 			callbacks := model.NewPrinterCallbacks(model.DiscardLogger)
 			args := &model.ExperimentArgs{
@@ -164,7 +189,7 @@ func TestMeasurerRun(t *testing.T) {
 			measurer := fbmessenger.NewExperimentMeasurer(fbmessenger.Config{})
 			ctx, cancel := context.WithCancel(context.Background())
 			cancel() // so we fail immediately
-			sess := &mocks.Session{MockLogger: func() model.Logger { return model.DiscardLogger }}
+			sess := newSession(t, "")
 			measurement := new(model.Measurement)
 			callbacks := model.NewPrinterCallbacks(model.DiscardLogger)
 			args := &model.ExperimentArgs{
@@ -196,14 +221,17 @@ func TestMeasurerRun(t *testing.T) {
 			FacebookTCPBlocking:       &trueValue,
 		}
 
-		// overwrite global Services, otherwise the test times out because there are too many endpoints
-		orig := fbmessenger.Services
-		fbmessenger.Services = []string{
-			fbmessenger.ServiceBAPI,
-		}
-		defer func() {
-			fbmessenger.Services = orig
-		}()
+		// use a more recent definition with a single service, otherwise the
+		// test times out because there are too many endpoints
+		const singleService = `[{
+			"name": "facebook_messenger",
+			"version": 1000,
+			"components": [
+				{"name": "b_api", "checks": [
+					{"type": "tcp", "endpoint": "b-api.facebook.com:443", "expected_asns": [32934]}
+				]}
+			]
+		}]`
 
 		// create a new test environment
 		env := netemx.MustNewQAEnv(netemx.QAEnvOptionNetStack(
@@ -239,7 +267,7 @@ func TestMeasurerRun(t *testing.T) {
 		env.Do(func() {
 			measurer := fbmessenger.NewExperimentMeasurer(fbmessenger.Config{})
 			ctx := context.Background()
-			sess := &mocks.Session{MockLogger: func() model.Logger { return model.DiscardLogger }}
+			sess := newSession(t, singleService)
 			measurement := new(model.Measurement)
 			callbacks := model.NewPrinterCallbacks(model.DiscardLogger)
 			args := &model.ExperimentArgs{
@@ -306,7 +334,7 @@ func TestMeasurerRun(t *testing.T) {
 		env.Do(func() {
 			measurer := fbmessenger.NewExperimentMeasurer(fbmessenger.Config{})
 			ctx := context.Background()
-			sess := &mocks.Session{MockLogger: func() model.Logger { return model.DiscardLogger }}
+			sess := newSession(t, "")
 			measurement := new(model.Measurement)
 			callbacks := model.NewPrinterCallbacks(model.DiscardLogger)
 			args := &model.ExperimentArgs{
@@ -326,66 +354,6 @@ func TestMeasurerRun(t *testing.T) {
 	})
 }
 
-func TestComputeEndpointStatsTCPBlocking(t *testing.T) {
-	failure := io.EOF.Error()
-	operation := netxlite.ConnectOperation
-	tk := fbmessenger.TestKeys{}
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{Target: fbmessenger.ServiceEdge},
-		TestKeys: urlgetter.TestKeys{
-			Failure:         &failure,
-			FailedOperation: &operation,
-			Queries: []tracex.DNSQueryEntry{{
-				Answers: []tracex.DNSAnswerEntry{{
-					ASN: fbmessenger.FacebookASN,
-				}},
-			}},
-		},
-	})
-	if *tk.FacebookEdgeDNSConsistent != true {
-		t.Fatal("invalid FacebookEdgeDNSConsistent")
-	}
-	if *tk.FacebookEdgeReachable != false {
-		t.Fatal("invalid FacebookEdgeReachable")
-	}
-	if tk.FacebookDNSBlocking != nil { // meaning: not determined yet
-		t.Fatal("invalid FacebookDNSBlocking")
-	}
-	if *tk.FacebookTCPBlocking != true {
-		t.Fatal("invalid FacebookTCPBlocking")
-	}
-}
-
-func TestComputeEndpointStatsDNSIsLying(t *testing.T) {
-	failure := io.EOF.Error()
-	operation := netxlite.ConnectOperation
-	tk := fbmessenger.TestKeys{}
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{Target: fbmessenger.ServiceEdge},
-		TestKeys: urlgetter.TestKeys{
-			Failure:         &failure,
-			FailedOperation: &operation,
-			Queries: []tracex.DNSQueryEntry{{
-				Answers: []tracex.DNSAnswerEntry{{
-					ASN: 0,
-				}},
-			}},
-		},
-	})
-	if *tk.FacebookEdgeDNSConsistent != false {
-		t.Fatal("invalid FacebookEdgeDNSConsistent")
-	}
-	if tk.FacebookEdgeReachable != nil {
-		t.Fatal("invalid FacebookEdgeReachable")
-	}
-	if *tk.FacebookDNSBlocking != true {
-		t.Fatal("invalid FacebookDNSBlocking")
-	}
-	if tk.FacebookTCPBlocking != nil { // meaning: not determined yet
-		t.Fatal("invalid FacebookTCPBlocking")
-	}
-}
-
 func TestSummaryKeysWithNils(t *testing.T) {
 	measurement := &model.Measurement{TestKeys: &fbmessenger.TestKeys{}}
 	osk := measurement.TestKeys.(*fbmessenger.TestKeys).MeasurementSummaryKeys()
@@ -0,0 +1,124 @@
+package fbmessenger
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/experiment/servicereachability"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/servicedefs"
+)
+
+func TestComputeAnalysis(t *testing.T) {
+	blocked := func(blocking, failure string) *servicereachability.ComponentResult {
+		return &servicereachability.ComponentResult{
+			Blocking: &blocking,
+			Failure:  &failure,
+			Status:   servicereachability.StatusBlocked,
+		}
+	}
+	ok := &servicereachability.ComponentResult{Status: servicereachability.StatusOK}
+
+	t.Run("with TCP blocking", func(t *testing.T) {
+		tk := &TestKeys{TestKeys: servicereachability.NewTestKeys()}
+		tk.Components = map[string]*servicereachability.ComponentResult{
+			"stun": ok,
+			"edge": blocked("tcp", netxlite.FailureGenericTimeoutError),
+		}
+		tk.computeAnalysis()
+		expect := Analysis{
+			FacebookEdgeDNSConsistent: &trueValue,
+			FacebookEdgeReachable:     &falseValue,
+			FacebookSTUNDNSConsistent: &trueValue,
+			FacebookDNSBlocking:       &falseValue,
+			FacebookTCPBlocking:       &trueValue,
+		}
+		if diff := cmp.Diff(expect, tk.Analysis); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with DNS lying", func(t *testing.T) {
+		tk := &TestKeys{TestKeys: servicereachability.NewTestKeys()}
+		tk.Components = map[string]*servicereachability.ComponentResult{
+			"edge": blocked("dns", servicereachability.FailureDNSUnexpectedASN),
+			"star": ok,
+		}
+		tk.computeAnalysis()
+		expect := Analysis{
+			FacebookEdgeDNSConsistent: &falseValue,
+			FacebookStarDNSConsistent: &trueValue,
+			FacebookStarReachable:     &trueValue,
+			FacebookDNSBlocking:       &trueValue,
+			FacebookTCPBlocking:       &falseValue,
+		}
+		if diff := cmp.Diff(expect, tk.Analysis); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+// analyze fills the test keys with the results of running the checks of the embedded
+// facebook_messenger definition, where the check of the given component fails with the
+// given failure and operation, and then computes the analysis.
+func analyze(t *testing.T, component, failure, operation string) *TestKeys {
+	def, err := servicedefs.Embedded(testName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []*servicereachability.CheckResult
+	for _, comp := range def.Components {
+		for _, check := range comp.Checks {
+			result := &servicereachability.CheckResult{
+				Component: comp.Name,
+				Target:    check.Target(),
+				Type:      check.Type,
+			}
+			if comp.Name == component {
+				result.FailedOperation = &operation
+				result.Failure = &failure
+			}
+			results = append(results, result)
+		}
+	}
+	tk := &TestKeys{TestKeys: servicereachability.NewTestKeys()}
+	tk.Checks = results
+	tk.Components = servicereachability.AnalyzeComponents(def, results)
+	tk.computeAnalysis()
+	return tk
+}
+
+func TestComputeEndpointStatsTCPBlocking(t *testing.T) {
+	tk := analyze(t, "edge", netxlite.FailureEOFError, netxlite.ConnectOperation)
+	if *tk.FacebookEdgeDNSConsistent != true {
+		t.Fatal("invalid FacebookEdgeDNSConsistent")
+	}
+	if *tk.FacebookEdgeReachable != false {
+		t.Fatal("invalid FacebookEdgeReachable")
+	}
+	if *tk.FacebookDNSBlocking != false {
+		t.Fatal("invalid FacebookDNSBlocking")
+	}
+	if *tk.FacebookTCPBlocking != true {
+		t.Fatal("invalid FacebookTCPBlocking")
+	}
+	if *tk.FacebookStarReachable != true {
+		t.Fatal("invalid FacebookStarReachable")
+	}
+}
+
+func TestComputeEndpointStatsDNSIsLying(t *testing.T) {
+	tk := analyze(t, "edge", servicereachability.FailureDNSUnexpectedASN, netxlite.ResolveOperation)
+	if *tk.FacebookEdgeDNSConsistent != false {
+		t.Fatal("invalid FacebookEdgeDNSConsistent")
+	}
+	if tk.FacebookEdgeReachable != nil {
+		t.Fatal("invalid FacebookEdgeReachable")
+	}
+	if *tk.FacebookDNSBlocking != true {
+		t.Fatal("invalid FacebookDNSBlocking")
+	}
+	if *tk.FacebookTCPBlocking != false {
+		t.Fatal("invalid FacebookTCPBlocking")
+	}
+}
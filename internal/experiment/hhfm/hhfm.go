@@ -14,7 +14,7 @@ import (
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/randx"
@@ -22,7 +22,7 @@ import (
 
 const (
 	testName    = "http_header_field_manipulation"
-	testVersion = "0.2.1"
+	testVersion = "0.3.0"
 )
 
 // Config contains the experiment config.
@@ -33,11 +33,11 @@ type Config struct{}
 // Here we are emitting for the same set of test keys that are
 // produced by the MK implementation.
 type TestKeys struct {
-	Agent      string                `json:"agent"`
-	Failure    *string               `json:"failure"`
-	Requests   []tracex.RequestEntry `json:"requests"`
-	SOCKSProxy *string               `json:"socksproxy"`
-	Tampering  Tampering             `json:"tampering"`
+	Agent      string                            `json:"agent"`
+	Failure    *string                           `json:"failure"`
+	Requests   []model.ArchivalHTTPRequestResult `json:"requests"`
+	SOCKSProxy *string                           `json:"socksproxy"`
+	Tampering  Tampering                         `json:"tampering"`
 }
 
 // Tampering describes the detected forms of tampering.
@@ -147,7 +147,7 @@ func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	// from that and then see to improve the robustness in the future.
 	resp, data, err := Transact(txp, req.WithContext(ctx), callbacks)
 	if err != nil {
-		tk.Failure = tracex.NewFailure(err)
+		tk.Failure = measurexlite.NewFailure(err)
 		tk.Requests[0].Failure = tk.Failure
 		tk.Tampering.Total = true
 		return nil // measurement did not fail, we measured tampering
@@ -255,13 +255,13 @@ func newHeadersFromMap(input map[string]string) map[string][]string {
 	return out
 }
 
-// NewRequestEntryList creates a new []tracex.RequestEntry given a
+// NewRequestEntryList creates a new []model.ArchivalHTTPRequestResult given a
 // specific *http.Request and headers with random case.
-func NewRequestEntryList(req *http.Request, headers map[string]string) (out []tracex.RequestEntry) {
+func NewRequestEntryList(req *http.Request, headers map[string]string) (out []model.ArchivalHTTPRequestResult) {
 	// Note: using the random capitalization headers here
 	realHeaders := newHeadersFromMap(headers)
-	out = []tracex.RequestEntry{{
-		Request: tracex.HTTPRequest{
+	out = []model.ArchivalHTTPRequestResult{{
+		Request: model.ArchivalHTTPRequest{
 			Headers:     model.ArchivalNewHTTPHeadersMap(realHeaders),
 			HeadersList: model.ArchivalNewHTTPHeadersList(realHeaders),
 			Method:      req.Method,
@@ -271,10 +271,10 @@ func NewRequestEntryList(req *http.Request, headers map[string]string) (out []tr
 	return
 }
 
-// NewHTTPResponse creates a new tracex.HTTPResponse given a
+// NewHTTPResponse creates a new model.ArchivalHTTPResponse given a
 // specific *http.Response instance and its body.
-func NewHTTPResponse(resp *http.Response, data []byte) (out tracex.HTTPResponse) {
-	out = tracex.HTTPResponse{
+func NewHTTPResponse(resp *http.Response, data []byte) (out model.ArchivalHTTPResponse) {
+	out = model.ArchivalHTTPResponse{
 		Body:        model.ArchivalScrubbedMaybeBinaryString(data),
 		Code:        int64(resp.StatusCode),
 		Headers:     model.ArchivalNewHTTPHeadersMap(resp.Header),
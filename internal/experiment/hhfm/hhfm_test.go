@@ -16,7 +16,6 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/experiment/hhfm"
 	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
 	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
@@ -26,7 +25,7 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "http_header_field_manipulation" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.2.1" {
+	if measurer.ExperimentVersion() != "0.3.0" {
 		t.Fatal("unexpected version")
 	}
 }
@@ -586,7 +585,7 @@ func TestTestKeys_FillTampering(t *testing.T) {
 	type fields struct {
 		Agent      string
 		Failure    *string
-		Requests   []tracex.RequestEntry
+		Requests   []model.ArchivalHTTPRequestResult
 		SOCKSProxy *string
 		Tampering  hhfm.Tampering
 	}
@@ -721,7 +720,7 @@ func TestNewRequestEntryList(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		wantOut []tracex.RequestEntry
+		wantOut []model.ArchivalHTTPRequestResult
 	}{{
 		name: "common case",
 		args: args{
@@ -738,8 +737,8 @@ func TestNewRequestEntryList(t *testing.T) {
 				"User-aGENT":   "foo/1.0",
 			},
 		},
-		wantOut: []tracex.RequestEntry{{
-			Request: tracex.HTTPRequest{
+		wantOut: []model.ArchivalHTTPRequestResult{{
+			Request: model.ArchivalHTTPRequest{
 				HeadersList: []model.ArchivalHTTPHeader{{
 					model.ArchivalScrubbedMaybeBinaryString("ContENt-tYPE"),
 					model.ArchivalScrubbedMaybeBinaryString("text/plain"),
@@ -767,8 +766,8 @@ func TestNewRequestEntryList(t *testing.T) {
 				},
 			},
 		},
-		wantOut: []tracex.RequestEntry{{
-			Request: tracex.HTTPRequest{
+		wantOut: []model.ArchivalHTTPRequestResult{{
+			Request: model.ArchivalHTTPRequest{
 				Method:      "GeT",
 				Headers:     make(map[string]model.ArchivalScrubbedMaybeBinaryString),
 				HeadersList: []model.ArchivalHTTPHeader{},
@@ -794,7 +793,7 @@ func TestNewHTTPResponse(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		wantOut tracex.HTTPResponse
+		wantOut model.ArchivalHTTPResponse
 	}{{
 		name: "common case",
 		args: args{
@@ -807,7 +806,7 @@ func TestNewHTTPResponse(t *testing.T) {
 			},
 			data: []byte("deadbeef"),
 		},
-		wantOut: tracex.HTTPResponse{
+		wantOut: model.ArchivalHTTPResponse{
 			Body: model.ArchivalScrubbedMaybeBinaryString("deadbeef"),
 			Code: 200,
 			HeadersList: []model.ArchivalHTTPHeader{{
@@ -827,7 +826,7 @@ func TestNewHTTPResponse(t *testing.T) {
 		args: args{
 			resp: &http.Response{StatusCode: 200},
 		},
-		wantOut: tracex.HTTPResponse{
+		wantOut: model.ArchivalHTTPResponse{
 			Body:        model.ArchivalScrubbedMaybeBinaryString(""),
 			Code:        200,
 			HeadersList: []model.ArchivalHTTPHeader{},
@@ -11,9 +11,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
 	"github.com/ooni/probe-cli/v3/internal/legacy/legacymodel"
-	"github.com/ooni/probe-cli/v3/internal/legacy/netx"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/randx"
@@ -21,7 +21,7 @@ import (
 
 const (
 	testName    = "http_invalid_request_line"
-	testVersion = "0.2.1"
+	testVersion = "0.3.0"
 	timeout     = 5 * time.Second
 )
 
@@ -122,7 +122,7 @@ func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 			}
 			continue
 		}
-		failure := tracex.NewFailure(result.Err)
+		failure := measurexlite.NewFailure(result.Err)
 		tk.FailureList = append(tk.FailureList, failure)
 		tk.Received = append(tk.Received, result.Received)
 		tk.Sent = append(tk.Sent, result.Sent)
@@ -250,10 +250,17 @@ func (meth squidCacheManager) Run(ctx context.Context, config MethodConfig) {
 type RunMethodConfig struct {
 	MethodConfig
 	Name        string
-	NewDialer   func(config netx.Config) model.Dialer
+	NewDialer   func(logger model.Logger) model.Dialer
 	RequestLine string
 }
 
+// newDialer is the default factory for the dialer used by RunMethod.
+func newDialer(logger model.Logger) model.Dialer {
+	netx := &netxlite.Netx{}
+	dialer := netx.NewDialerWithResolver(logger, netx.NewStdlibResolver(logger))
+	return bytecounter.WrapWithContextAwareDialer(dialer)
+}
+
 // RunMethod runs the specific method using the given config and context
 func RunMethod(ctx context.Context, config RunMethodConfig) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
@@ -263,12 +270,9 @@ func RunMethod(ctx context.Context, config RunMethodConfig) {
 		config.Out <- result
 	}()
 	if config.NewDialer == nil {
-		config.NewDialer = netx.NewDialer
+		config.NewDialer = newDialer
 	}
-	dialer := config.NewDialer(netx.Config{
-		ContextByteCounting: true,
-		Logger:              config.Logger,
-	})
+	dialer := config.NewDialer(config.Logger)
 	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(config.Address, "80"))
 	if err != nil {
 		result.Err = err
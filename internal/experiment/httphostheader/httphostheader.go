@@ -14,7 +14,7 @@ import (
 
 const (
 	testName    = "http_host_header"
-	testVersion = "0.3.0"
+	testVersion = "0.4.0"
 )
 
 // Config contains the experiment config.
@@ -3,9 +3,13 @@ package ndt7
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/ooni/probe-cli/v3/internal/humanize"
 	"github.com/ooni/probe-cli/v3/internal/mlablocatev2"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -15,15 +19,57 @@ import (
 
 const (
 	testName    = "ndt"
-	testVersion = "0.10.2"
+	testVersion = "0.11.0"
 )
 
 // Config contains the experiment settings
 type Config struct {
+	// ServerURL is the OPTIONAL base URL of an M-Lab-compatible ndt7 server
+	// (e.g., wss://ndt.example.com/) to use instead of querying M-Lab's
+	// locate API. The URL MAY contain query parameters (e.g., access tokens).
+	ServerURL string `ooni:"base URL of an M-Lab-compatible ndt7 server to use instead of the locate API"`
+
+	// Duration is the OPTIONAL maximum duration of each subtest in seconds.
+	Duration int64 `ooni:"maximum duration of the download and of the upload in seconds"`
+
+	// Streams is the OPTIONAL number of parallel connections for each subtest.
+	Streams int64 `ooni:"number of parallel connections for the download and for the upload"`
+
+	// MaxBytes is the OPTIONAL number of bytes after which we stop each subtest.
+	MaxBytes int64 `ooni:"stop the download and the upload after transferring these many bytes"`
+
 	noDownload bool
 	noUpload   bool
 }
 
+func (c Config) maxRuntime() time.Duration {
+	if c.Duration > 0 {
+		return time.Duration(c.Duration) * time.Second
+	}
+	return paramMaxRuntime
+}
+
+// maxRuntimeUpperBound returns the upper bound of each subtest runtime in seconds,
+// which we use to compute the percentage of completion.
+func (c Config) maxRuntimeUpperBound() float64 {
+	return c.maxRuntime().Seconds() * paramMaxRuntimeUpperBound / paramMaxRuntime.Seconds()
+}
+
+func (c Config) streams() int {
+	if c.Streams > 0 && c.Streams <= paramMaxStreams {
+		return int(c.Streams)
+	}
+	if c.Streams > paramMaxStreams {
+		return paramMaxStreams
+	}
+	return 1
+}
+
+// maxBytesReached returns whether a subtest has transferred enough bytes.
+func (c Config) maxBytesReached(total int64) bool {
+	return c.MaxBytes > 0 && total >= c.MaxBytes
+}
+
 // Summary is the measurement summary
 type Summary struct {
 	AvgRTT         float64 `json:"avg_rtt"`         // Average RTT [ms]
@@ -74,8 +120,33 @@ type Measurer struct {
 	preUploadHook   func()
 }
 
+// errInvalidServerURL indicates that the configured server URL is invalid.
+var errInvalidServerURL = errors.New("ndt7: invalid server URL")
+
+// newStaticLocateResult returns the locate result for the given server URL.
+func newStaticLocateResult(serverURL string) (*mlablocatev2.NDT7Result, error) {
+	URL, err := url.Parse(serverURL)
+	if err != nil || (URL.Scheme != "ws" && URL.Scheme != "wss") || URL.Host == "" {
+		return nil, errInvalidServerURL
+	}
+	basePath := strings.TrimSuffix(URL.Path, "/")
+	downloadURL, uploadURL := *URL, *URL
+	downloadURL.Path = basePath + "/ndt/v7/download"
+	uploadURL.Path = basePath + "/ndt/v7/upload"
+	out := &mlablocatev2.NDT7Result{
+		Hostname:       URL.Hostname(),
+		Site:           "",
+		WSSDownloadURL: downloadURL.String(),
+		WSSUploadURL:   uploadURL.String(),
+	}
+	return out, nil
+}
+
 func (m *Measurer) discover(
 	ctx context.Context, sess model.ExperimentSession) (*mlablocatev2.NDT7Result, error) {
+	if m.config.ServerURL != "" {
+		return newStaticLocateResult(m.config.ServerURL)
+	}
 	// Implementation note: here we cannot use the session's HTTP client because it MAY be proxied
 	// and instead we need to connect directly to M-Lab's locate service.
 	//
@@ -109,66 +180,91 @@ func (m *Measurer) doDownload(
 	if m.config.noDownload {
 		return nil // useful to make tests faster
 	}
-	conn, err := newDialManager(URL,
-		sess.Logger(), sess.UserAgent()).dialDownload(ctx)
+	conns, err := m.dialStreams(ctx, newDialManager(URL,
+		sess.Logger(), sess.UserAgent()).dialDownload)
 	if err != nil {
 		return err
 	}
 	defer callbacks.OnProgress(0.5, " download: done")
-	defer conn.Close()
-	mgr := newDownloadManager(
-		conn,
-		func(timediff time.Duration, count int64) {
-			elapsed := timediff.Seconds()
-			// The percentage of completion of download goes from 0 to
-			// 50% of the whole experiment, hence the `/2.0`.
-			percentage := elapsed / paramMaxRuntimeUpperBound / 2.0
-			speed := float64(count) * 8.0 / elapsed
-			message := fmt.Sprintf(" download: speed %s", humanize.SI(
-				float64(speed), "bit/s"))
-			tk.Summary.Download = speed / 1e03 /* bit/s => kbit/s */
-			callbacks.OnProgress(percentage, message)
-			tk.Download = append(tk.Download, Measurement{
-				AppInfo: &AppInfo{
-					ElapsedTime: int64(timediff / time.Microsecond),
-					NumBytes:    count,
-				},
-				Origin: "client",
-				Test:   "download",
-			})
-		},
-		func(data []byte) error {
-			sess.Logger().Debugf("%s", string(data))
-			var measurement Measurement
-			if err := m.jsonUnmarshal(data, &measurement); err != nil {
-				return err
-			}
-			if measurement.TCPInfo != nil {
-				rtt := float64(measurement.TCPInfo.RTT) / 1e03 /* us => ms */
-				tk.Summary.AvgRTT = rtt
-				tk.Summary.MSS = int64(measurement.TCPInfo.AdvMSS)
-				if tk.Summary.MaxRTT < rtt {
-					tk.Summary.MaxRTT = rtt
-				}
-				tk.Summary.MinRTT = float64(measurement.TCPInfo.MinRTT) / 1e03 /* us => ms */
-				tk.Summary.Ping = tk.Summary.MinRTT
-				if measurement.TCPInfo.BytesSent > 0 {
-					tk.Summary.RetransmitRate = (float64(measurement.TCPInfo.BytesRetrans) /
-						float64(measurement.TCPInfo.BytesSent))
+	defer closeStreams(conns)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	counter := newStreamsCounter(len(conns))
+	runStreams(conns, func(stream int, conn *websocket.Conn) {
+		mgr := newDownloadManager(
+			conn,
+			func(timediff time.Duration, count int64) {
+				defer counter.mu.Unlock()
+				counter.mu.Lock()
+				count = counter.update(stream, count)
+				m.onDownloadPerformance(callbacks, tk, timediff, count)
+				if m.config.maxBytesReached(count) {
+					cancel()
 				}
-				measurement.BBRInfo = nil        // don't encourage people to use it
-				measurement.ConnectionInfo = nil // do we need to save it?
-				measurement.Origin = "server"
-				measurement.Test = "download"
-				tk.Download = append(tk.Download, measurement)
-			}
-			return nil
+			},
+			func(data []byte) error {
+				defer counter.mu.Unlock()
+				counter.mu.Lock()
+				return m.onDownloadJSON(sess, tk, data)
+			},
+		)
+		mgr.maxRuntime = m.config.maxRuntime()
+		if err := mgr.run(ctx); err != nil && err.Error() != "generic_timeout_error" {
+			sess.Logger().Warnf("download: %s", err)
+		}
+	})
+	return nil // failure is only when we cannot connect
+}
+
+// onDownloadPerformance handles the performance measured by the client.
+func (m *Measurer) onDownloadPerformance(callbacks model.ExperimentCallbacks,
+	tk *TestKeys, timediff time.Duration, count int64) {
+	elapsed := timediff.Seconds()
+	// The percentage of completion of download goes from 0 to
+	// 50% of the whole experiment, hence the `/2.0`.
+	percentage := elapsed / m.config.maxRuntimeUpperBound() / 2.0
+	speed := float64(count) * 8.0 / elapsed
+	message := fmt.Sprintf(" download: speed %s", humanize.SI(
+		float64(speed), "bit/s"))
+	tk.Summary.Download = speed / 1e03 /* bit/s => kbit/s */
+	callbacks.OnProgress(percentage, message)
+	tk.Download = append(tk.Download, Measurement{
+		AppInfo: &AppInfo{
+			ElapsedTime: int64(timediff / time.Microsecond),
+			NumBytes:    count,
 		},
-	)
-	if err := mgr.run(ctx); err != nil && err.Error() != "generic_timeout_error" {
-		sess.Logger().Warnf("download: %s", err)
+		Origin: "client",
+		Test:   "download",
+	})
+}
+
+// onDownloadJSON handles the measurements sent by the server.
+func (m *Measurer) onDownloadJSON(sess model.ExperimentSession, tk *TestKeys, data []byte) error {
+	sess.Logger().Debugf("%s", string(data))
+	var measurement Measurement
+	if err := m.jsonUnmarshal(data, &measurement); err != nil {
+		return err
 	}
-	return nil // failure is only when we cannot connect
+	if measurement.TCPInfo != nil {
+		rtt := float64(measurement.TCPInfo.RTT) / 1e03 /* us => ms */
+		tk.Summary.AvgRTT = rtt
+		tk.Summary.MSS = int64(measurement.TCPInfo.AdvMSS)
+		if tk.Summary.MaxRTT < rtt {
+			tk.Summary.MaxRTT = rtt
+		}
+		tk.Summary.MinRTT = float64(measurement.TCPInfo.MinRTT) / 1e03 /* us => ms */
+		tk.Summary.Ping = tk.Summary.MinRTT
+		if measurement.TCPInfo.BytesSent > 0 {
+			tk.Summary.RetransmitRate = (float64(measurement.TCPInfo.BytesRetrans) /
+				float64(measurement.TCPInfo.BytesSent))
+		}
+		measurement.BBRInfo = nil        // don't encourage people to use it
+		measurement.ConnectionInfo = nil // do we need to save it?
+		measurement.Origin = "server"
+		measurement.Test = "download"
+		tk.Download = append(tk.Download, measurement)
+	}
+	return nil
 }
 
 func (m *Measurer) doUpload(
@@ -179,41 +275,59 @@ func (m *Measurer) doUpload(
 	if m.config.noUpload {
 		return nil // useful to make tests faster
 	}
-	conn, err := newDialManager(URL,
-		sess.Logger(), sess.UserAgent()).dialUpload(ctx)
+	conns, err := m.dialStreams(ctx, newDialManager(URL,
+		sess.Logger(), sess.UserAgent()).dialUpload)
 	if err != nil {
 		return err
 	}
 	defer callbacks.OnProgress(1, "   upload: done")
-	defer conn.Close()
-	mgr := newUploadManager(
-		conn,
-		func(timediff time.Duration, count int64) {
-			elapsed := timediff.Seconds()
-			// The percentage of completion of upload goes from 50% to 100% of
-			// the whole experiment, hence `0.5 +` and `/2.0`.
-			percentage := 0.5 + elapsed/paramMaxRuntimeUpperBound/2.0
-			speed := float64(count) * 8.0 / elapsed
-			message := fmt.Sprintf("   upload: speed %s", humanize.SI(
-				float64(speed), "bit/s"))
-			tk.Summary.Upload = speed / 1e03 /* bit/s => kbit/s */
-			callbacks.OnProgress(percentage, message)
-			tk.Upload = append(tk.Upload, Measurement{
-				AppInfo: &AppInfo{
-					ElapsedTime: int64(timediff / time.Microsecond),
-					NumBytes:    count,
-				},
-				Origin: "client",
-				Test:   "upload",
-			})
-		},
-	)
-	if err := mgr.run(ctx); err != nil && err.Error() != "generic_timeout_error" {
-		sess.Logger().Warnf("upload: %s", err)
-	}
+	defer closeStreams(conns)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	counter := newStreamsCounter(len(conns))
+	runStreams(conns, func(stream int, conn *websocket.Conn) {
+		mgr := newUploadManager(
+			conn,
+			func(timediff time.Duration, count int64) {
+				defer counter.mu.Unlock()
+				counter.mu.Lock()
+				count = counter.update(stream, count)
+				m.onUploadPerformance(callbacks, tk, timediff, count)
+				if m.config.maxBytesReached(count) {
+					cancel()
+				}
+			},
+		)
+		mgr.maxRuntime = m.config.maxRuntime()
+		if err := mgr.run(ctx); err != nil && err.Error() != "generic_timeout_error" {
+			sess.Logger().Warnf("upload: %s", err)
+		}
+	})
 	return nil // failure is only when we cannot connect
 }
 
+// onUploadPerformance handles the performance measured by the client.
+func (m *Measurer) onUploadPerformance(callbacks model.ExperimentCallbacks,
+	tk *TestKeys, timediff time.Duration, count int64) {
+	elapsed := timediff.Seconds()
+	// The percentage of completion of upload goes from 50% to 100% of
+	// the whole experiment, hence `0.5 +` and `/2.0`.
+	percentage := 0.5 + elapsed/m.config.maxRuntimeUpperBound()/2.0
+	speed := float64(count) * 8.0 / elapsed
+	message := fmt.Sprintf("   upload: speed %s", humanize.SI(
+		float64(speed), "bit/s"))
+	tk.Summary.Upload = speed / 1e03 /* bit/s => kbit/s */
+	callbacks.OnProgress(percentage, message)
+	tk.Upload = append(tk.Upload, Measurement{
+		AppInfo: &AppInfo{
+			ElapsedTime: int64(timediff / time.Microsecond),
+			NumBytes:    count,
+		},
+		Origin: "client",
+		Test:   "upload",
+	})
+}
+
 // Run implements ExperimentMeasurer.Run.
 func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	callbacks := args.Callbacks
@@ -223,6 +337,9 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	tk.Protocol = 7
 	measurement.TestKeys = tk
 	locateResult, err := m.discover(ctx, sess)
+	if errors.Is(err, errInvalidServerURL) {
+		return err // this is a configuration error
+	}
 	if err != nil {
 		tk.Failure = failureFromError(err)
 		return nil // we still want to submit this measurement
@@ -5,10 +5,12 @@ import (
 	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
@@ -17,7 +19,7 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "ndt" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.10.2" {
+	if measurer.ExperimentVersion() != "0.11.0" {
 		t.Fatal("unexpected version")
 	}
 }
@@ -264,3 +266,130 @@ func TestSummaryKeysGood(t *testing.T) {
 		t.Fatal("invalid Anomaly()")
 	}
 }
+
+func TestConfig(t *testing.T) {
+	t.Run("maxRuntime", func(t *testing.T) {
+		if (Config{}).maxRuntime() != paramMaxRuntime {
+			t.Fatal("unexpected default max runtime")
+		}
+		if (Config{Duration: 3}).maxRuntime() != 3*time.Second {
+			t.Fatal("unexpected max runtime")
+		}
+	})
+
+	t.Run("maxRuntimeUpperBound", func(t *testing.T) {
+		if (Config{}).maxRuntimeUpperBound() != paramMaxRuntimeUpperBound {
+			t.Fatal("unexpected default upper bound")
+		}
+		if (Config{Duration: 20}).maxRuntimeUpperBound() != 2*paramMaxRuntimeUpperBound {
+			t.Fatal("unexpected upper bound")
+		}
+	})
+
+	t.Run("streams", func(t *testing.T) {
+		expect := map[int64]int{-1: 1, 0: 1, 4: 4, 1024: paramMaxStreams}
+		for value, expected := range expect {
+			if got := (Config{Streams: value}).streams(); got != expected {
+				t.Fatal("for", value, "expected", expected, "got", got)
+			}
+		}
+	})
+
+	t.Run("maxBytesReached", func(t *testing.T) {
+		if (Config{}).maxBytesReached(1 << 30) {
+			t.Fatal("by default there should be no limit")
+		}
+		if (Config{MaxBytes: 100}).maxBytesReached(99) {
+			t.Fatal("should not have reached the limit")
+		}
+		if !(Config{MaxBytes: 100}).maxBytesReached(100) {
+			t.Fatal("should have reached the limit")
+		}
+	})
+}
+
+func TestNewStaticLocateResult(t *testing.T) {
+	t.Run("with valid URLs", func(t *testing.T) {
+		expect := map[string][2]string{
+			"ws://127.0.0.1:8080": {
+				"ws://127.0.0.1:8080/ndt/v7/download",
+				"ws://127.0.0.1:8080/ndt/v7/upload",
+			},
+			"wss://ndt.example.com/base/?access_token=xo": {
+				"wss://ndt.example.com/base/ndt/v7/download?access_token=xo",
+				"wss://ndt.example.com/base/ndt/v7/upload?access_token=xo",
+			},
+		}
+		for input, urls := range expect {
+			out, err := newStaticLocateResult(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.WSSDownloadURL != urls[0] || out.WSSUploadURL != urls[1] {
+				t.Fatal("unexpected URLs", out.WSSDownloadURL, out.WSSUploadURL)
+			}
+		}
+	})
+
+	t.Run("with invalid URLs", func(t *testing.T) {
+		for _, input := range []string{"\t", "https://ndt.example.com/", "ws:///ndt"} {
+			out, err := newStaticLocateResult(input)
+			if !errors.Is(err, errInvalidServerURL) {
+				t.Fatal("unexpected error", err)
+			}
+			if out != nil {
+				t.Fatal("expected nil result")
+			}
+		}
+	})
+
+	t.Run("Run fails with an invalid server URL", func(t *testing.T) {
+		m := NewExperimentMeasurer(Config{ServerURL: "https://ndt.example.com/"})
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(log.Log),
+			Measurement: new(model.Measurement),
+			Session:     &mockable.Session{MockableLogger: log.Log},
+		}
+		if err := m.Run(context.Background(), args); !errors.Is(err, errInvalidServerURL) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestWithNetem(t *testing.T) {
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+
+	env.Do(func() {
+		m := NewExperimentMeasurer(Config{
+			ServerURL: "ws://mlab-speedtest.org/",
+			Duration:  1,
+			Streams:   2,
+		})
+		measurement := new(model.Measurement)
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(log.Log),
+			Measurement: measurement,
+			Session: &mockable.Session{
+				MockableLogger:    log.Log,
+				MockableUserAgent: "miniooni/0.1.0-dev",
+			},
+		}
+		if err := m.Run(context.Background(), args); err != nil {
+			t.Fatal(err)
+		}
+		tk := measurement.TestKeys.(*TestKeys)
+		if tk.Failure != nil {
+			t.Fatal("unexpected failure", *tk.Failure)
+		}
+		if tk.Server.Hostname != "mlab-speedtest.org" {
+			t.Fatal("unexpected hostname", tk.Server.Hostname)
+		}
+		if len(tk.Download) <= 0 || len(tk.Upload) <= 0 {
+			t.Fatal("expected download and upload measurements")
+		}
+		if tk.Summary.Download <= 0 || tk.Summary.Upload <= 0 {
+			t.Fatal("expected nonzero download and upload speed")
+		}
+	})
+}
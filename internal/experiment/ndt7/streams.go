@@ -0,0 +1,76 @@
+package ndt7
+
+//
+// Support for parallel streams
+//
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialStreams establishes the configured number of parallel connections and fails
+// if any connection fails, since we want all streams to run at the same time.
+func (m *Measurer) dialStreams(ctx context.Context,
+	dial func(ctx context.Context) (*websocket.Conn, error)) ([]*websocket.Conn, error) {
+	conns := []*websocket.Conn{}
+	for idx := 0; idx < m.config.streams(); idx++ {
+		conn, err := dial(ctx)
+		if err != nil {
+			closeStreams(conns)
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+// closeStreams closes all the given connections.
+func closeStreams(conns []*websocket.Conn) {
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+// runStreams runs fx in parallel for each conn and waits for all of them to finish.
+func runStreams(conns []*websocket.Conn, fx func(stream int, conn *websocket.Conn)) {
+	wg := &sync.WaitGroup{}
+	for idx, conn := range conns {
+		wg.Add(1)
+		go func(stream int, conn *websocket.Conn) {
+			defer wg.Done()
+			fx(stream, conn)
+		}(idx, conn)
+	}
+	wg.Wait()
+}
+
+// streamsCounter aggregates the number of bytes transferred by parallel streams.
+type streamsCounter struct {
+	// counts contains the number of bytes transferred by each stream.
+	counts []int64
+
+	// mu allows the caller to serialize access to the counter and to the
+	// test keys when streams concurrently invoke callbacks.
+	mu sync.Mutex
+}
+
+// newStreamsCounter creates a new [*streamsCounter] for the given number of streams.
+func newStreamsCounter(streams int) *streamsCounter {
+	return &streamsCounter{
+		counts: make([]int64, streams),
+		mu:     sync.Mutex{},
+	}
+}
+
+// update sets the bytes transferred by the given stream and returns the total. The
+// caller is responsible for locking the mutex before calling this method.
+func (sc *streamsCounter) update(stream int, count int64) (total int64) {
+	sc.counts[stream] = count
+	for _, value := range sc.counts {
+		total += value
+	}
+	return
+}
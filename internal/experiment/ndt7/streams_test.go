@@ -0,0 +1,62 @@
+package ndt7
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDialStreams(t *testing.T) {
+	t.Run("on success", func(t *testing.T) {
+		m := &Measurer{config: Config{Streams: 3}}
+		conns, err := m.dialStreams(context.Background(), func(ctx context.Context) (*websocket.Conn, error) {
+			return &websocket.Conn{}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(conns) != 3 {
+			t.Fatal("unexpected number of conns", len(conns))
+		}
+	})
+
+	t.Run("on failure", func(t *testing.T) {
+		m := &Measurer{config: Config{Streams: 3}}
+		expected := errors.New("mocked error")
+		conns, err := m.dialStreams(context.Background(), func(ctx context.Context) (*websocket.Conn, error) {
+			return nil, expected
+		})
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(conns) != 0 {
+			t.Fatal("expected no conns")
+		}
+	})
+}
+
+func TestRunStreams(t *testing.T) {
+	var called int64
+	runStreams(make([]*websocket.Conn, 4), func(stream int, conn *websocket.Conn) {
+		atomic.AddInt64(&called, 1<<(stream*8))
+	})
+	if called != 0x01010101 {
+		t.Fatalf("unexpected called value: %x", called)
+	}
+}
+
+func TestStreamsCounter(t *testing.T) {
+	sc := newStreamsCounter(3)
+	if total := sc.update(0, 10); total != 10 {
+		t.Fatal("unexpected total", total)
+	}
+	if total := sc.update(2, 5); total != 15 {
+		t.Fatal("unexpected total", total)
+	}
+	if total := sc.update(0, 20); total != 25 {
+		t.Fatal("unexpected total", total)
+	}
+}
@@ -17,7 +17,7 @@ import (
 
 const (
 	testName        = "openvpn"
-	testVersion     = "0.1.7"
+	testVersion     = "0.1.8"
 	openVPNProtocol = "openvpn"
 )
 
@@ -7,7 +7,6 @@ import (
 	"slices"
 	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/netx"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
@@ -42,16 +41,13 @@ func sampleN(a []string, n int) []string {
 // bogons.
 func resolveOONIAddresses(logger model.Logger) ([]string, error) {
 
-	// We explicitely resolve with BogonIsError set to false, and
+	// We explicitely resolve without treating bogons as errors, and
 	// later remove bogons from the list. The reason is that in this way
 	// we are able to control the rate at which we run tests by adding bogon addresses to the
 	// domain records for the test.
 
-	resolver := netx.NewResolver(netx.Config{
-		BogonIsError: false,
-		Logger:       logger,
-		Saver:        nil,
-	})
+	netx := &netxlite.Netx{}
+	resolver := netx.NewStdlibResolver(logger)
 
 	addrs := []string{}
 
@@ -0,0 +1,69 @@
+package pathtrace
+
+//
+// Analysis of the probe traces
+//
+
+import "github.com/ooni/probe-cli/v3/internal/netxlite"
+
+// replyFailures contains the failures implying that we received a reply.
+var replyFailures = map[string]bool{
+	netxlite.FailureConnectionRefused:     true,
+	netxlite.FailureConnectionReset:       true,
+	netxlite.FailureEOFError:              true,
+	netxlite.FailureSSLFailedHandshake:    true,
+	netxlite.FailureSSLInvalidCertificate: true,
+	netxlite.FailureSSLInvalidHostname:    true,
+	netxlite.FailureSSLUnknownAuthority:   true,
+}
+
+// isReply returns whether the given failure implies that we received a reply.
+func isReply(failure *string) bool {
+	return failure == nil || replyFailures[*failure]
+}
+
+// analyze fills the ReplyTTL and Failure fields of the probe trace and, unless the
+// probe is a TCP SYN probe, uses the distance of the server to determine whether
+// the reply was injected and the hop at which blocking occurs.
+//
+// The hops MUST have already been sorted using [alignHops].
+func (pt *ProbeTrace) analyze(serverTTL *int64) {
+	for _, hop := range pt.Hops {
+		pt.Failure = hop.Failure
+		if hop.Reply {
+			ttl := hop.TTL
+			pt.ReplyTTL = &ttl
+			break
+		}
+	}
+	if pt.Probe == probeTCPSYN {
+		return
+	}
+	switch {
+	case pt.ReplyTTL != nil && serverTTL != nil && *pt.ReplyTTL < *serverTTL:
+		// Something between us and the server replied on behalf of the server
+		pt.Injected = true
+		pt.BlockingTTL = pt.ReplyTTL
+
+	case pt.ReplyTTL != nil && pt.Failure != nil:
+		// We cannot distinguish a middlebox at the server's hop from the server
+		pt.BlockingTTL = pt.ReplyTTL
+
+	case pt.ReplyTTL == nil && serverTTL != nil:
+		// The server is reachable but the probe is dropped after the last
+		// hop that sent us back an ICMP message (if any)
+		pt.BlockingTTL = nextHopAfterLastSoError(pt.Hops)
+	}
+}
+
+// nextHopAfterLastSoError returns the TTL following the largest TTL whose
+// hop has a soft ICMP error, or nil if no hop has a soft ICMP error.
+func nextHopAfterLastSoError(hops []*Hop) (out *int64) {
+	for _, hop := range hops {
+		if hop.SoError != nil {
+			ttl := hop.TTL + 1
+			out = &ttl
+		}
+	}
+	return
+}
@@ -0,0 +1,149 @@
+package pathtrace
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestIsReply(t *testing.T) {
+	type testcase struct {
+		failure *string
+		expect  bool
+	}
+	cases := []testcase{{
+		failure: nil,
+		expect:  true,
+	}, {
+		failure: failurePtr(netxlite.FailureConnectionReset),
+		expect:  true,
+	}, {
+		failure: failurePtr(netxlite.FailureSSLInvalidHostname),
+		expect:  true,
+	}, {
+		failure: failurePtr(netxlite.FailureGenericTimeoutError),
+		expect:  false,
+	}, {
+		failure: failurePtr(netxlite.FailureHostUnreachable),
+		expect:  false,
+	}}
+	for _, tc := range cases {
+		if got := isReply(tc.failure); got != tc.expect {
+			t.Fatal("expected", tc.expect, "got", got, "for", tc.failure)
+		}
+	}
+}
+
+func TestProbeTraceAnalyze(t *testing.T) {
+	timeout := failurePtr(netxlite.FailureGenericTimeoutError)
+	reset := failurePtr(netxlite.FailureConnectionReset)
+	hostUnreachable := failurePtr(netxlite.FailureHostUnreachable)
+
+	type testcase struct {
+		name      string
+		probe     string
+		hops      []*Hop
+		serverTTL *int64
+		expect    *ProbeTrace
+	}
+
+	cases := []testcase{{
+		name:  "tcp_syn probe reaching the server",
+		probe: probeTCPSYN,
+		hops: []*Hop{
+			{TTL: 1, Failure: timeout},
+			{TTL: 2, Failure: timeout},
+			{TTL: 3, Reply: true},
+		},
+		expect: &ProbeTrace{
+			Probe:    probeTCPSYN,
+			ReplyTTL: ttlPtr(3),
+		},
+	}, {
+		name:  "tls_client_hello probe reaching the server",
+		probe: probeTLSClientHello,
+		hops: []*Hop{
+			{TTL: 1, Failure: timeout, SoError: hostUnreachable},
+			{TTL: 2, Reply: true},
+		},
+		serverTTL: ttlPtr(2),
+		expect: &ProbeTrace{
+			Probe:    probeTLSClientHello,
+			ReplyTTL: ttlPtr(2),
+		},
+	}, {
+		name:  "tls_client_hello probe with injected RST",
+		probe: probeTLSClientHello,
+		hops: []*Hop{
+			{TTL: 1, Failure: timeout},
+			{TTL: 2, Failure: reset, Reply: true},
+		},
+		serverTTL: ttlPtr(5),
+		expect: &ProbeTrace{
+			Probe:       probeTLSClientHello,
+			ReplyTTL:    ttlPtr(2),
+			Failure:     reset,
+			Injected:    true,
+			BlockingTTL: ttlPtr(2),
+		},
+	}, {
+		name:  "http_request probe reset at the server's hop",
+		probe: probeHTTPRequest,
+		hops: []*Hop{
+			{TTL: 1, Failure: timeout},
+			{TTL: 2, Failure: reset, Reply: true},
+		},
+		serverTTL: ttlPtr(2),
+		expect: &ProbeTrace{
+			Probe:       probeHTTPRequest,
+			ReplyTTL:    ttlPtr(2),
+			Failure:     reset,
+			BlockingTTL: ttlPtr(2),
+		},
+	}, {
+		name:  "dns_over_udp probe dropped after a given hop",
+		probe: probeDNSOverUDP,
+		hops: []*Hop{
+			{TTL: 1, Failure: timeout, SoError: hostUnreachable},
+			{TTL: 2, Failure: timeout, SoError: hostUnreachable},
+			{TTL: 3, Failure: timeout},
+			{TTL: 4, Failure: timeout},
+		},
+		serverTTL: ttlPtr(4),
+		expect: &ProbeTrace{
+			Probe:       probeDNSOverUDP,
+			Failure:     timeout,
+			BlockingTTL: ttlPtr(3),
+		},
+	}, {
+		name:  "probe without reply for unreachable server",
+		probe: probeTLSClientHello,
+		hops: []*Hop{
+			{TTL: 1, Failure: timeout, SoError: hostUnreachable},
+		},
+		expect: &ProbeTrace{
+			Probe:   probeTLSClientHello,
+			Failure: timeout,
+		},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pt := &ProbeTrace{Probe: tc.probe, Hops: tc.hops}
+			pt.analyze(tc.serverTTL)
+			pt.Hops = nil
+			if diff := cmp.Diff(tc.expect, pt, cmpIgnoreMutex); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func failurePtr(failure string) *string {
+	return &failure
+}
+
+func ttlPtr(ttl int64) *int64 {
+	return &ttl
+}
@@ -0,0 +1,88 @@
+package pathtrace
+
+//
+// Config for the pathtrace experiment
+//
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// Config contains the experiment configuration.
+type Config struct {
+	// Delay is the delay between each iteration (in milliseconds).
+	Delay int64 `ooni:"delay between consecutive iterations in milliseconds"`
+
+	// Domain is the domain to use for the SNI, the Host header, and the DNS query.
+	Domain string `ooni:"domain for the SNI, the Host header, and the DNS query"`
+
+	// MaxTTL is the maximum TTL value we use.
+	MaxTTL int64 `ooni:"maximum TTL value to iterate upto"`
+
+	// Probes is the space-separated list of probes to run.
+	Probes string `ooni:"space-separated list of probes to run"`
+
+	// Timeout is the timeout of each TTL-limited probe (in milliseconds).
+	Timeout int64 `ooni:"timeout of each TTL-limited probe in milliseconds"`
+}
+
+func (c Config) delay() time.Duration {
+	if c.Delay > 0 {
+		return time.Duration(c.Delay) * time.Millisecond
+	}
+	return 100 * time.Millisecond
+}
+
+// domain returns the configured domain or the target hostname, if it's
+// a domain name, or a default domain if the target is an IP address.
+func (c Config) domain(hostname string) string {
+	if c.Domain != "" {
+		return c.Domain
+	}
+	if net.ParseIP(hostname) == nil {
+		return hostname
+	}
+	return "example.com"
+}
+
+func (c Config) maxttl() int64 {
+	if c.MaxTTL > 0 {
+		return c.MaxTTL
+	}
+	return 20
+}
+
+// errInvalidProbe indicates that the configured probes list contains an unknown probe.
+var errInvalidProbe = errors.New("pathtrace: invalid probe")
+
+// probes returns the configured probes in canonical order such that
+// we always measure the distance of the server using TCP SYN probes first.
+func (c Config) probes() ([]string, error) {
+	if c.Probes == "" {
+		return allProbes, nil
+	}
+	enabled := make(map[string]bool)
+	for _, name := range strings.Fields(c.Probes) {
+		if !isValidProbe(name) {
+			return nil, errInvalidProbe
+		}
+		enabled[name] = true
+	}
+	out := []string{}
+	for _, name := range allProbes {
+		if enabled[name] {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
+
+func (c Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Millisecond
+	}
+	return 5 * time.Second
+}
@@ -0,0 +1,109 @@
+package pathtrace
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfig_delay(t *testing.T) {
+	c := Config{}
+	if c.delay() != 100*time.Millisecond {
+		t.Fatal("invalid default delay")
+	}
+	c.Delay = 17
+	if c.delay() != 17*time.Millisecond {
+		t.Fatal("invalid configured delay")
+	}
+}
+
+func TestConfig_domain(t *testing.T) {
+	type testcase struct {
+		name     string
+		config   Config
+		hostname string
+		expect   string
+	}
+	cases := []testcase{{
+		name:     "with configured domain",
+		config:   Config{Domain: "www.example.org"},
+		hostname: "www.example.com",
+		expect:   "www.example.org",
+	}, {
+		name:     "with domain as the hostname",
+		hostname: "www.example.com",
+		expect:   "www.example.com",
+	}, {
+		name:     "with IP address as the hostname",
+		hostname: "8.8.8.8",
+		expect:   "example.com",
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.config.domain(tc.hostname); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestConfig_maxttl(t *testing.T) {
+	c := Config{}
+	if c.maxttl() != 20 {
+		t.Fatal("invalid default number of repetitions")
+	}
+	c.MaxTTL = 5
+	if c.maxttl() != 5 {
+		t.Fatal("invalid configured maxttl")
+	}
+}
+
+func TestConfig_probes(t *testing.T) {
+	type testcase struct {
+		name      string
+		input     string
+		expect    []string
+		expectErr error
+	}
+	cases := []testcase{{
+		name:   "with empty configuration",
+		input:  "",
+		expect: allProbes,
+	}, {
+		name:   "we sort the probes in canonical order",
+		input:  "dns_over_udp tcp_syn tls_client_hello",
+		expect: []string{probeTCPSYN, probeTLSClientHello, probeDNSOverUDP},
+	}, {
+		name:   "we deduplicate the probes",
+		input:  "http_request http_request",
+		expect: []string{probeHTTPRequest},
+	}, {
+		name:      "with invalid probe",
+		input:     "tcp_syn antani",
+		expectErr: errInvalidProbe,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Config{Probes: tc.input}.probes()
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if diff := cmp.Diff(tc.expect, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestConfig_timeout(t *testing.T) {
+	c := Config{}
+	if c.timeout() != 5*time.Second {
+		t.Fatal("invalid default timeout")
+	}
+	c.Timeout = 1000
+	if c.timeout() != 1*time.Second {
+		t.Fatal("invalid configured timeout")
+	}
+}
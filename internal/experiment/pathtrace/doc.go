@@ -0,0 +1,12 @@
+// Package pathtrace implements the pathtrace experiment.
+//
+// This experiment sends TTL-limited TCP SYN, TLS ClientHello, HTTP request, and
+// DNS-over-UDP probes to a target using increasing TTL values, to determine where along
+// the path TCP RST segments, blockpages, and DNS responses originate. Because we
+// do not use raw sockets, we cannot see ICMP time exceeded messages directly. Rather,
+// we compare the TTL at which a probe first gets a reply with the distance of the
+// server measured using TCP SYN probes, and we use the kernel's soft ICMP errors
+// (SO_ERROR) when they are available.
+//
+// We reuse the tlsmiddlebox syscall helpers for setting the TTL.
+package pathtrace
@@ -0,0 +1,162 @@
+package pathtrace
+
+//
+// Measurer
+//
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+const (
+	testName    = "pathtrace"
+	testVersion = "0.1.0"
+)
+
+// Measurer performs the measurement.
+type Measurer struct {
+	config Config
+}
+
+// ExperimentName implements ExperimentMeasurer.ExperimentName.
+func (m *Measurer) ExperimentName() string {
+	return testName
+}
+
+// ExperimentVersion implements ExperimentMeasurer.ExperimentVersion.
+func (m *Measurer) ExperimentVersion() string {
+	return testVersion
+}
+
+var (
+	// errNoInputProvided indicates you didn't provide any input
+	errNoInputProvided = errors.New("no input provided")
+
+	// errInputIsNotAnURL indicates that input is not an URL
+	errInputIsNotAnURL = errors.New("input is not an URL")
+
+	// errInvalidInputScheme indicates that the input scheme is invalid
+	errInvalidInputScheme = errors.New("input scheme must be pathtrace")
+
+	// errMissingHost indicates that the input URL does not contain a host
+	errMissingHost = errors.New("input URL must contain a host")
+)
+
+// Run implements ExperimentMeasurer.Run.
+func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	_ = args.Callbacks
+	measurement := args.Measurement
+	sess := args.Session
+	if measurement.Input == "" {
+		return errNoInputProvided
+	}
+	parsed, err := url.Parse(string(measurement.Input))
+	if err != nil {
+		return errInputIsNotAnURL
+	}
+	if parsed.Scheme != "pathtrace" {
+		return errInvalidInputScheme
+	}
+	if parsed.Hostname() == "" {
+		return errMissingHost
+	}
+	probes, err := m.config.probes()
+	if err != nil {
+		return err
+	}
+	tk := NewTestKeys()
+	measurement.TestKeys = tk
+	zeroTime := measurement.MeasurementStartTimeSaved
+	logger := sess.Logger()
+	tk.Domain = m.config.domain(parsed.Hostname())
+	// 1. resolve the target, which is a no-op for IP addresses
+	addrs, err := m.DNSLookup(ctx, 0, zeroTime, logger, parsed.Hostname(), tk)
+	if err != nil {
+		tk.Failure = measurexlite.NewFailure(err)
+		return nil // we want to submit this measurement
+	}
+	// 2. trace each address
+	wg := new(sync.WaitGroup)
+	for idx, addr := range addrs {
+		wg.Add(1)
+		go func(index int64, address string) {
+			defer wg.Done()
+			tk.addTraces(m.TraceAddress(ctx, index, zeroTime, logger, address, tk.Domain, probes))
+		}(int64(idx+1), addr)
+	}
+	wg.Wait()
+	return nil
+}
+
+// DNSLookup resolves the domain using the system resolver
+func (m *Measurer) DNSLookup(ctx context.Context, index int64, zeroTime time.Time,
+	logger model.Logger, domain string, tk *TestKeys) ([]string, error) {
+	trace := measurexlite.NewTrace(index, zeroTime)
+	ol := logx.NewOperationLogger(logger, "DNSLookup #%d %s", index, domain)
+	resolver := trace.NewStdlibResolver(logger)
+	addrs, err := resolver.LookupHost(ctx, domain)
+	ol.Stop(err)
+	tk.addQueries(trace.DNSLookupsFromRoundTrip())
+	return addrs, err
+}
+
+// TraceAddress runs all the probes for the given address in order
+func (m *Measurer) TraceAddress(ctx context.Context, index int64, zeroTime time.Time, logger model.Logger,
+	address string, domain string, probes []string) *AddressTrace {
+	at := &AddressTrace{
+		Address: address,
+		Probes:  []*ProbeTrace{},
+	}
+	funcs := m.probeFuncs()
+	for _, probe := range probes {
+		pt := &ProbeTrace{
+			Probe:    probe,
+			Endpoint: net.JoinHostPort(address, probePorts[probe]),
+			Hops:     []*Hop{},
+		}
+		m.traceWithIncreasingTTLs(ctx, index, zeroTime, logger, domain, funcs[probe], pt)
+		pt.Hops = alignHops(pt.Hops)
+		if probe == probeTCPSYN {
+			pt.analyze(nil)
+			at.ServerTTL = pt.ReplyTTL
+		} else {
+			pt.analyze(at.ServerTTL)
+		}
+		at.Probes = append(at.Probes, pt)
+	}
+	return at
+}
+
+// traceWithIncreasingTTLs sends the probe using increasing TTL values
+func (m *Measurer) traceWithIncreasingTTLs(ctx context.Context, index int64, zeroTime time.Time,
+	logger model.Logger, domain string, probe probeFunc, pt *ProbeTrace) {
+	ticker := time.NewTicker(m.config.delay())
+	defer ticker.Stop()
+	wg := new(sync.WaitGroup)
+	maxTTL := m.config.maxttl()
+	for ttl := int64(1); ttl <= maxTTL; ttl++ {
+		wg.Add(1)
+		go func(ttl int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, m.config.timeout())
+			defer cancel()
+			pt.addHops(probe(ctx, index, zeroTime, logger, pt.Endpoint, domain, ttl))
+		}(int(ttl))
+		<-ticker.C
+	}
+	wg.Wait()
+}
+
+// NewExperimentMeasurer creates a new ExperimentMeasurer.
+func NewExperimentMeasurer(config Config) *Measurer {
+	return &Measurer{config: config}
+}
@@ -0,0 +1,144 @@
+package pathtrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestMeasurerExperimentNameVersion(t *testing.T) {
+	measurer := NewExperimentMeasurer(Config{})
+	if measurer.ExperimentName() != "pathtrace" {
+		t.Fatal("unexpected ExperimentName")
+	}
+	if measurer.ExperimentVersion() != "0.1.0" {
+		t.Fatal("unexpected ExperimentVersion")
+	}
+}
+
+func runHelper(ctx context.Context, config Config, input string) (*model.Measurement, error) {
+	m := NewExperimentMeasurer(config)
+	meas := &model.Measurement{
+		Input: model.MeasurementInput(input),
+	}
+	sess := &mocks.Session{
+		MockLogger: func() model.Logger {
+			return model.DiscardLogger
+		},
+	}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+		Measurement: meas,
+		Session:     sess,
+	}
+	err := m.Run(ctx, args)
+	return meas, err
+}
+
+func TestMeasurer_input_failure(t *testing.T) {
+	type testcase struct {
+		name      string
+		config    Config
+		input     string
+		expectErr error
+	}
+	cases := []testcase{{
+		name:      "with empty input",
+		input:     "",
+		expectErr: errNoInputProvided,
+	}, {
+		name:      "with invalid URL",
+		input:     "\t",
+		expectErr: errInputIsNotAnURL,
+	}, {
+		name:      "with invalid scheme",
+		input:     "http://8.8.8.8/",
+		expectErr: errInvalidInputScheme,
+	}, {
+		name:      "with missing host",
+		input:     "pathtrace:///",
+		expectErr: errMissingHost,
+	}, {
+		name:      "with invalid probes",
+		config:    Config{Probes: "antani"},
+		input:     "pathtrace://8.8.8.8",
+		expectErr: errInvalidProbe,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runHelper(context.Background(), tc.config, tc.input)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
+
+func TestMeasurer_with_DNS_failure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // immediately fail the DNS lookup
+	meas, err := runHelper(ctx, Config{}, "pathtrace://www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := meas.TestKeys.(*TestKeys)
+	if tk.Failure == nil || *tk.Failure != netxlite.FailureInterrupted {
+		t.Fatal("unexpected failure", tk.Failure)
+	}
+	if len(tk.Traces) != 0 {
+		t.Fatal("expected no traces")
+	}
+}
+
+func TestMeasurer_with_loopback_address(t *testing.T) {
+	config := Config{
+		Delay:   1,
+		Domain:  "www.example.com",
+		MaxTTL:  3,
+		Probes:  "tcp_syn tls_client_hello",
+		Timeout: 500,
+	}
+	meas, err := runHelper(context.Background(), config, "pathtrace://127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := meas.TestKeys.(*TestKeys)
+	if tk.Domain != "www.example.com" {
+		t.Fatal("unexpected domain", tk.Domain)
+	}
+	if tk.Failure != nil {
+		t.Fatal("unexpected failure", *tk.Failure)
+	}
+	if len(tk.Traces) != 1 {
+		t.Fatal("unexpected number of traces")
+	}
+	trace := tk.Traces[0]
+	if trace.Address != "127.0.0.1" {
+		t.Fatal("unexpected address", trace.Address)
+	}
+	if len(trace.Probes) != 2 {
+		t.Fatal("unexpected number of probes")
+	}
+	syn := trace.Probes[0]
+	if syn.Probe != probeTCPSYN || syn.Endpoint != "127.0.0.1:443" {
+		t.Fatal("unexpected first probe", syn.Probe, syn.Endpoint)
+	}
+	// loopback is zero hops away, so we must receive either a SYN-ACK
+	// or a RST using the smallest TTL value
+	if syn.ReplyTTL == nil || *syn.ReplyTTL != 1 || trace.ServerTTL == nil || *trace.ServerTTL != 1 {
+		t.Fatal("unexpected reply TTL")
+	}
+	if len(syn.Hops) != 1 {
+		t.Fatal("unexpected number of hops")
+	}
+	if trace.Probes[1].Probe != probeTLSClientHello {
+		t.Fatal("unexpected second probe", trace.Probes[1].Probe)
+	}
+	if trace.Probes[1].Injected {
+		t.Fatal("loopback cannot inject")
+	}
+}
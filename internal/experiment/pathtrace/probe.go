@@ -0,0 +1,218 @@
+package pathtrace
+
+//
+// TTL-limited probes
+//
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/experiment/tlsmiddlebox"
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+const (
+	// probeTCPSYN sends a TCP SYN segment.
+	probeTCPSYN = "tcp_syn"
+
+	// probeTLSClientHello sends a TLS ClientHello after connecting.
+	probeTLSClientHello = "tls_client_hello"
+
+	// probeHTTPRequest sends an HTTP request after connecting.
+	probeHTTPRequest = "http_request"
+
+	// probeDNSOverUDP sends a DNS query using UDP.
+	probeDNSOverUDP = "dns_over_udp"
+)
+
+// allProbes contains all the probes in the order in which we run them.
+var allProbes = []string{probeTCPSYN, probeTLSClientHello, probeHTTPRequest, probeDNSOverUDP}
+
+// probePorts maps each probe to the port we send it to.
+var probePorts = map[string]string{
+	probeTCPSYN:         "443",
+	probeTLSClientHello: "443",
+	probeHTTPRequest:    "80",
+	probeDNSOverUDP:     "53",
+}
+
+// isValidProbe returns whether name is the name of a probe.
+func isValidProbe(name string) bool {
+	_, found := probePorts[name]
+	return found
+}
+
+// defaultTTL is the TTL we restore to ensure that conns close successfully.
+const defaultTTL = 64
+
+// maxBodySnapshotSize is the maximum HTTP response body we save.
+const maxBodySnapshotSize = 1 << 14
+
+// probeFunc is the type of a function sending a TTL-limited probe.
+type probeFunc func(ctx context.Context, index int64, zeroTime time.Time, logger model.Logger,
+	endpoint string, domain string, ttl int) *Hop
+
+// probeFuncs maps each probe to the function sending it.
+func (m *Measurer) probeFuncs() map[string]probeFunc {
+	return map[string]probeFunc{
+		probeTCPSYN:         m.tcpSYN,
+		probeTLSClientHello: m.tlsClientHello,
+		probeHTTPRequest:    m.httpRequest,
+		probeDNSOverUDP:     m.dnsOverUDP,
+	}
+}
+
+// newHop creates a new hop from the probe error and the soft ICMP error.
+func newHop(ttl int, err error, soErr error, reply bool) *Hop {
+	return &Hop{
+		TTL:     int64(ttl),
+		Failure: measurexlite.NewFailure(err),
+		SoError: measurexlite.NewFailure(soErr),
+		Reply:   reply,
+	}
+}
+
+// tcpSYN connects to the endpoint using the given TTL for the SYN segments.
+func (m *Measurer) tcpSYN(ctx context.Context, index int64, zeroTime time.Time, logger model.Logger,
+	endpoint string, domain string, ttl int) *Hop {
+	ol := logx.NewOperationLogger(logger, "TCP SYN #%d TTL %d %s", index, ttl, endpoint)
+	conn, err := tlsmiddlebox.NewDialerTTLWrapperWithTTL(ttl).DialContext(ctx, "tcp", endpoint)
+	ol.Stop(err)
+	if conn != nil {
+		// Note: Do not check for errors here
+		_ = tlsmiddlebox.SetConnTTL(conn, defaultTTL)
+		conn.Close()
+	}
+	return newHop(ttl, err, nil, isReply(measurexlite.NewFailure(err)))
+}
+
+// connectWithTTL connects using the default TTL and then sets the given TTL.
+func connectWithTTL(ctx context.Context, endpoint string, ttl int) (net.Conn, error) {
+	conn, err := tlsmiddlebox.NewDialerTTLWrapper().DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if err := tlsmiddlebox.SetConnTTL(conn, ttl); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// tlsClientHello connects and sends a ClientHello using the given TTL.
+func (m *Measurer) tlsClientHello(ctx context.Context, index int64, zeroTime time.Time, logger model.Logger,
+	endpoint string, domain string, ttl int) *Hop {
+	ol := logx.NewOperationLogger(logger, "TLS ClientHello #%d TTL %d %s %s", index, ttl, endpoint, domain)
+	conn, err := connectWithTTL(ctx, endpoint, ttl)
+	if err != nil {
+		ol.Stop(err)
+		return newHop(ttl, err, nil, false) // the failure does not depend on the TTL
+	}
+	defer conn.Close()
+	trace := measurexlite.NewTrace(index, zeroTime)
+	thx := trace.NewTLSHandshakerStdlib(logger)
+	_, err = thx.Handshake(ctx, conn, newTLSConfig(domain))
+	ol.Stop(err)
+	soErr := tlsmiddlebox.ExtractSoError(conn, netxlite.TLSHandshakeOperation)
+	// Note: Do not check for errors here
+	_ = tlsmiddlebox.SetConnTTL(conn, defaultTTL)
+	hop := newHop(ttl, err, soErr, isReply(measurexlite.NewFailure(err)))
+	hop.TLSHandshake = trace.FirstTLSHandshakeOrNil()
+	return hop
+}
+
+// newTLSConfig generates the tls.Config for the given SNI
+func newTLSConfig(sni string) *tls.Config {
+	return &tls.Config{ // #nosec G402 - we need to use a large TLS versions range for measuring
+		RootCAs:            nil,
+		ServerName:         sni,
+		NextProtos:         []string{"h2", "http/1.1"},
+		InsecureSkipVerify: true, // #nosec G402 - it's fine to skip verify in a nettest
+	}
+}
+
+// httpRequest connects and sends an HTTP request using the given TTL.
+func (m *Measurer) httpRequest(ctx context.Context, index int64, zeroTime time.Time, logger model.Logger,
+	endpoint string, domain string, ttl int) *Hop {
+	ol := logx.NewOperationLogger(logger, "HTTP request #%d TTL %d %s %s", index, ttl, endpoint, domain)
+	conn, err := connectWithTTL(ctx, endpoint, ttl)
+	if err != nil {
+		ol.Stop(err)
+		return newHop(ttl, err, nil, false) // the failure does not depend on the TTL
+	}
+	defer conn.Close()
+	started := time.Since(zeroTime)
+	req, resp, body, err := httpRoundTrip(ctx, conn, domain)
+	finished := time.Since(zeroTime)
+	ol.Stop(err)
+	soErr := tlsmiddlebox.ExtractSoError(conn, netxlite.HTTPRoundTripOperation)
+	// Note: Do not check for errors here
+	_ = tlsmiddlebox.SetConnTTL(conn, defaultTTL)
+	hop := newHop(ttl, err, soErr, resp != nil || isReply(measurexlite.NewFailure(err)))
+	hop.Request = measurexlite.NewArchivalHTTPRequestResult(index, started, "tcp", endpoint, "", "tcp",
+		req, resp, maxBodySnapshotSize, body, err, finished)
+	return hop
+}
+
+// httpRoundTrip sends an HTTP request for the given domain using the given conn
+// and returns the request, the response, and a snapshot of the body.
+func httpRoundTrip(ctx context.Context, conn net.Conn, domain string) (
+	*http.Request, *http.Response, []byte, error) {
+	URL := &url.URL{Scheme: "http", Host: domain, Path: "/"}
+	req, err := http.NewRequestWithContext(ctx, "GET", URL.String(), nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	req.Header.Set("Accept", model.HTTPHeaderAccept)
+	req.Header.Set("Accept-Language", model.HTTPHeaderAcceptLanguage)
+	// Implementation note: req.Write ignores the Host header, so we
+	// only set it such that we archive it.
+	req.Header.Set("Host", domain)
+	req.Header.Set("User-Agent", model.HTTPHeaderUserAgent)
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if err := req.Write(conn); err != nil {
+		return req, nil, nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return req, nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySnapshotSize))
+	return req, resp, body, err
+}
+
+// dnsOverUDP sends a DNS query for the domain using the given TTL.
+func (m *Measurer) dnsOverUDP(ctx context.Context, index int64, zeroTime time.Time, logger model.Logger,
+	endpoint string, domain string, ttl int) *Hop {
+	ol := logx.NewOperationLogger(logger, "DNS-over-UDP #%d TTL %d %s %s", index, ttl, endpoint, domain)
+	txp := netxlite.NewUnwrappedDNSOverUDPTransport(tlsmiddlebox.NewDialerTTLWrapperWithTTL(ttl), endpoint)
+	defer txp.CloseIdleConnections()
+	encoder := &netxlite.DNSEncoderMiekg{}
+	query := encoder.Encode(domain, dns.TypeA, false)
+	started := time.Since(zeroTime)
+	resp, err := txp.RoundTrip(ctx, query)
+	var addrs []string
+	if err == nil {
+		addrs, err = resp.DecodeLookupHost()
+	}
+	finished := time.Since(zeroTime)
+	ol.Stop(err)
+	hop := newHop(ttl, err, nil, resp != nil)
+	hop.Query = measurexlite.NewArchivalDNSLookupResultFromRoundTrip(
+		index, started, txp, query, resp, addrs, err, finished)
+	return hop
+}
@@ -0,0 +1,124 @@
+package pathtrace
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestIsValidProbe(t *testing.T) {
+	for _, probe := range allProbes {
+		if !isValidProbe(probe) {
+			t.Fatal("expected valid probe", probe)
+		}
+	}
+	if isValidProbe("antani") {
+		t.Fatal("expected invalid probe")
+	}
+}
+
+// probeHelper sends the given probe to the given endpoint using a large TTL.
+func probeHelper(probe string, endpoint string) *Hop {
+	m := NewExperimentMeasurer(Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	fx := m.probeFuncs()[probe]
+	return fx(ctx, 0, time.Now(), model.DiscardLogger, endpoint, "www.example.com", defaultTTL)
+}
+
+func TestProbes(t *testing.T) {
+	t.Run("tcp_syn", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		hop := probeHelper(probeTCPSYN, listener.Addr().String())
+		if hop.Failure != nil || !hop.Reply || hop.TTL != defaultTTL {
+			t.Fatal("unexpected hop", hop.Failure, hop.Reply, hop.TTL)
+		}
+	})
+
+	t.Run("tls_client_hello", func(t *testing.T) {
+		srv := httptest.NewTLSServer(http.NotFoundHandler())
+		defer srv.Close()
+		URL, _ := url.Parse(srv.URL)
+		hop := probeHelper(probeTLSClientHello, URL.Host)
+		if hop.Failure != nil || !hop.Reply {
+			t.Fatal("unexpected hop", hop.Failure, hop.Reply)
+		}
+		if hop.TLSHandshake == nil || hop.TLSHandshake.ServerName != "www.example.com" {
+			t.Fatal("unexpected TLS handshake")
+		}
+	})
+
+	t.Run("tls_client_hello with connect failure", func(t *testing.T) {
+		hop := probeHelper(probeTLSClientHello, "127.0.0.1:1")
+		if hop.Failure == nil || *hop.Failure != netxlite.FailureConnectionRefused || hop.Reply {
+			t.Fatal("unexpected hop", hop.Failure, hop.Reply)
+		}
+		if hop.TLSHandshake != nil {
+			t.Fatal("expected no TLS handshake")
+		}
+	})
+
+	t.Run("http_request", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Host))
+		}))
+		defer srv.Close()
+		URL, _ := url.Parse(srv.URL)
+		hop := probeHelper(probeHTTPRequest, URL.Host)
+		if hop.Failure != nil || !hop.Reply {
+			t.Fatal("unexpected hop", hop.Failure, hop.Reply)
+		}
+		if hop.Request == nil || hop.Request.Response.Code != 200 {
+			t.Fatal("unexpected request")
+		}
+		if hop.Request.Response.Body != "www.example.com" {
+			t.Fatal("unexpected body", hop.Request.Response.Body)
+		}
+	})
+
+	t.Run("dns_over_udp", func(t *testing.T) {
+		pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pconn.Close()
+		go func() {
+			buffer := make([]byte, 1024)
+			count, addr, err := pconn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			query := &dns.Msg{}
+			if err := query.Unpack(buffer[:count]); err != nil {
+				return
+			}
+			reply := &dns.Msg{}
+			reply.SetReply(query)
+			reply.Answer = append(reply.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET},
+				A:   net.IPv4(10, 0, 0, 1),
+			})
+			data, _ := reply.Pack()
+			pconn.WriteTo(data, addr)
+		}()
+		hop := probeHelper(probeDNSOverUDP, pconn.LocalAddr().String())
+		if hop.Failure != nil || !hop.Reply {
+			t.Fatal("unexpected hop", hop.Failure, hop.Reply)
+		}
+		if hop.Query == nil || len(hop.Query.Answers) != 1 || hop.Query.Answers[0].IPv4 != "10.0.0.1" {
+			t.Fatal("unexpected query")
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package pathtrace
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// TestKeys contains the experiment results
+type TestKeys struct {
+	Domain  string                           `json:"domain"`
+	Failure *string                          `json:"failure"`
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+	Traces  []*AddressTrace                  `json:"traces"`
+
+	mu sync.Mutex
+}
+
+// NewTestKeys creates new pathtrace TestKeys
+func NewTestKeys() *TestKeys {
+	return &TestKeys{
+		Queries: []*model.ArchivalDNSLookupResult{},
+		Traces:  []*AddressTrace{},
+	}
+}
+
+// addQueries adds []*model.ArchivalDNSLookupResult to the test keys queries
+func (tk *TestKeys) addQueries(ev []*model.ArchivalDNSLookupResult) {
+	tk.mu.Lock()
+	tk.Queries = append(tk.Queries, ev...)
+	tk.mu.Unlock()
+}
+
+// addTraces adds []*AddressTrace to the test keys traces
+func (tk *TestKeys) addTraces(ev ...*AddressTrace) {
+	tk.mu.Lock()
+	tk.Traces = append(tk.Traces, ev...)
+	tk.mu.Unlock()
+}
+
+// AddressTrace contains all the probe traces for a given IP address
+type AddressTrace struct {
+	// Address is the IP address we're tracing.
+	Address string `json:"address"`
+
+	// ServerTTL is the smallest TTL at which a TCP SYN probe got a reply, which
+	// is the distance of the server unless someone spoofs SYN-ACKs or RSTs.
+	ServerTTL *int64 `json:"server_ttl"`
+
+	// Probes contains the trace of each probe.
+	Probes []*ProbeTrace `json:"probes"`
+}
+
+// ProbeTrace contains the results of sending a probe with increasing TTLs
+type ProbeTrace struct {
+	// Probe is the probe name (e.g., "tls_client_hello").
+	Probe string `json:"probe"`
+
+	// Endpoint is the endpoint to which we sent the probe.
+	Endpoint string `json:"endpoint"`
+
+	// Hops contains the results sorted by increasing TTL up to the first reply.
+	Hops []*Hop `json:"hops"`
+
+	// ReplyTTL is the smallest TTL at which we received a reply.
+	ReplyTTL *int64 `json:"reply_ttl"`
+
+	// Failure is the failure at ReplyTTL or, if there was no reply, the failure
+	// of the probe sent with the largest TTL.
+	Failure *string `json:"failure"`
+
+	// Injected indicates that the reply came from a hop closer than the server.
+	Injected bool `json:"injected"`
+
+	// BlockingTTL is the TTL of the hop at which blocking occurs, if we can tell.
+	BlockingTTL *int64 `json:"blocking_ttl"`
+
+	mu sync.Mutex
+}
+
+// addHops adds hops to the probe trace
+func (pt *ProbeTrace) addHops(ev ...*Hop) {
+	pt.mu.Lock()
+	pt.Hops = append(pt.Hops, ev...)
+	pt.mu.Unlock()
+}
+
+// Hop is the result of sending a probe using a given TTL
+type Hop struct {
+	// TTL is the TTL we used for sending the probe.
+	TTL int64 `json:"ttl"`
+
+	// Failure is the failure that occurred, if any.
+	Failure *string `json:"failure"`
+
+	// SoError is the soft ICMP error reported by the kernel, if any, which
+	// indicates that the router at this TTL sent back an ICMP message.
+	SoError *string `json:"so_error"`
+
+	// Reply indicates that we received a reply to the probe, including
+	// a TCP RST or FIN segment, from the server or from a middlebox.
+	Reply bool `json:"reply"`
+
+	// Query is the DNS round trip of the dns_over_udp probe.
+	Query *model.ArchivalDNSLookupResult `json:"query,omitempty"`
+
+	// Request is the HTTP round trip of the http_request probe.
+	Request *model.ArchivalHTTPRequestResult `json:"request,omitempty"`
+
+	// TLSHandshake is the TLS handshake of the tls_client_hello probe.
+	TLSHandshake *model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshake,omitempty"`
+}
+
+// alignHops sorts the hops according to increasing TTL
+// and stops after the first hop that received a reply
+func alignHops(in []*Hop) (out []*Hop) {
+	out = []*Hop{}
+	sort.Slice(in, func(i int, j int) bool {
+		return in[i].TTL < in[j].TTL
+	})
+	for _, hop := range in {
+		out = append(out, hop)
+		if hop.Reply {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package pathtrace
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// cmpIgnoreMutex allows to compare structures containing a sync.Mutex.
+var cmpIgnoreMutex = cmpopts.IgnoreUnexported(ProbeTrace{}, TestKeys{})
+
+func TestAlignHops(t *testing.T) {
+	t.Run("we sort and stop after the first reply", func(t *testing.T) {
+		in := []*Hop{
+			{TTL: 3, Reply: true},
+			{TTL: 1},
+			{TTL: 4, Reply: true},
+			{TTL: 2},
+		}
+		expect := []*Hop{
+			{TTL: 1},
+			{TTL: 2},
+			{TTL: 3, Reply: true},
+		}
+		if diff := cmp.Diff(expect, alignHops(in)); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with empty input", func(t *testing.T) {
+		out := alignHops(nil)
+		if out == nil || len(out) != 0 {
+			t.Fatal("expected empty non-nil output")
+		}
+	})
+}
@@ -4,12 +4,36 @@ package portfiltering
 // Config for the port-filtering experiment
 //
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
 
 // Config contains the experiment configuration.
 type Config struct {
 	// Delay is the delay between each repetition (in milliseconds).
 	Delay int64 `ooni:"number of milliseconds to wait before testing each port"`
+
+	// OpenPorts indicates whether to ask the test helper to open the
+	// ports before testing them. When this option is set, we only test
+	// the ports that the test helper reports as open.
+	OpenPorts bool `ooni:"ask the test helper to open the ports before testing them"`
+
+	// Ports is the comma-separated set of ports to test. See [ParsePortSet]
+	// for the syntax. When empty, we test the default ports.
+	Ports string `ooni:"comma-separated ports, port ranges (e.g., 8000-8010) and profiles (e.g., vpn, mail, gaming)"`
+
+	// Protocols is the comma-separated list of transport protocols to
+	// use. We support "tcp" and "udp". When empty, we only use "tcp".
+	Protocols string `ooni:"comma-separated transport protocols to test (tcp, udp)"`
+
+	// TestHelper is the URL of the port-filtering test helper.
+	TestHelper string `ooni:"URL of the port-filtering test helper"`
+
+	// Timeout is the timeout of each probe (in milliseconds).
+	Timeout int64 `ooni:"number of milliseconds after which a probe is considered timed out"`
 }
 
 func (c *Config) delay() time.Duration {
@@ -18,3 +42,46 @@ func (c *Config) delay() time.Duration {
 	}
 	return 100 * time.Millisecond
 }
+
+func (c *Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Millisecond
+	}
+	return 5 * time.Second
+}
+
+// runtime returns the time it takes to test the given number of probes.
+func (c *Config) runtime(probes int) time.Duration {
+	return time.Duration(probes)*c.delay() + c.timeout()
+}
+
+func (c *Config) testHelper() string {
+	if c.TestHelper != "" {
+		return c.TestHelper
+	}
+	// TODO(DecFox): Replace the localhost deployment with an OONI testhelper
+	// Ensure that we only do this once we have a deployed testhelper
+	return "http://127.0.0.1"
+}
+
+func (c *Config) ports() ([]string, error) {
+	return ParsePortSet(c.Ports)
+}
+
+func (c *Config) protocols() ([]string, error) {
+	if strings.TrimSpace(c.Protocols) == "" {
+		return []string{"tcp"}, nil
+	}
+	var out []string
+	for _, proto := range strings.Split(c.Protocols, ",") {
+		switch proto = strings.TrimSpace(proto); proto {
+		case "tcp", "udp":
+			if !slices.Contains(out, proto) {
+				out = append(out, proto)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %q", errUnsupportedProtocol, proto)
+		}
+	}
+	return out, nil
+}
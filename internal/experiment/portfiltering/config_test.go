@@ -1,8 +1,11 @@
 package portfiltering
 
 import (
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestConfig_delay(t *testing.T) {
@@ -11,3 +14,67 @@ func TestConfig_delay(t *testing.T) {
 		t.Fatal("invalid default delay")
 	}
 }
+
+func TestConfig_timeout(t *testing.T) {
+	c := Config{}
+	if c.timeout() != 5*time.Second {
+		t.Fatal("invalid default timeout")
+	}
+	c.Timeout = 250
+	if c.timeout() != 250*time.Millisecond {
+		t.Fatal("invalid timeout")
+	}
+}
+
+func TestConfig_runtime(t *testing.T) {
+	c := Config{Delay: 10, Timeout: 250}
+	if c.runtime(4) != 290*time.Millisecond {
+		t.Fatal("invalid runtime")
+	}
+}
+
+func TestConfig_testHelper(t *testing.T) {
+	c := Config{}
+	if c.testHelper() != "http://127.0.0.1" {
+		t.Fatal("invalid default test helper")
+	}
+	c.TestHelper = "http://10.0.0.1:8080"
+	if c.testHelper() != "http://10.0.0.1:8080" {
+		t.Fatal("invalid test helper")
+	}
+}
+
+func TestConfig_protocols(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		c := Config{}
+		protocols, err := c.protocols()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"tcp"}, protocols); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("tcp and udp", func(t *testing.T) {
+		c := Config{Protocols: "udp, tcp,udp"}
+		protocols, err := c.protocols()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"udp", "tcp"}, protocols); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("unsupported protocol", func(t *testing.T) {
+		c := Config{Protocols: "tcp,sctp"}
+		protocols, err := c.protocols()
+		if !errors.Is(err, errUnsupportedProtocol) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(protocols) != 0 {
+			t.Fatal("expected no protocols")
+		}
+	})
+}
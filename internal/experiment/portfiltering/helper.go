@@ -0,0 +1,56 @@
+package portfiltering
+
+//
+// Protocol for asking the test helper to open ports
+//
+
+import (
+	"context"
+
+	"github.com/ooni/probe-cli/v3/internal/httpclientx"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// OpenPortsRequest is the request we POST to the test helper to ask
+// it to listen on the given TCP and UDP ports.
+type OpenPortsRequest struct {
+	// Lifetime is how long we need the ports to be open (in milliseconds).
+	Lifetime int64 `json:"lifetime,omitempty"`
+
+	// TCP contains the TCP ports to open.
+	TCP []string `json:"tcp"`
+
+	// UDP contains the UDP ports to open.
+	UDP []string `json:"udp"`
+}
+
+// OpenPortsResponse is the test helper response to [OpenPortsRequest]. It
+// contains the subset of the requested ports the test helper is listening
+// on, which may be smaller than the request because the test helper limits
+// the number of ports it keeps open and because some ports may be in use.
+type OpenPortsResponse struct {
+	// Lifetime is how long the test helper keeps the ports open (in milliseconds),
+	// which may be shorter than the requested lifetime. Zero means unknown.
+	Lifetime int64 `json:"lifetime,omitempty"`
+
+	// TCP contains the open TCP ports.
+	TCP []string `json:"tcp"`
+
+	// UDP contains the open UDP ports.
+	UDP []string `json:"udp"`
+}
+
+// openPorts asks the test helper at the given URL to open the given ports.
+func openPorts(ctx context.Context, sess model.ExperimentSession,
+	URL string, request *OpenPortsRequest) (*OpenPortsResponse, error) {
+	return httpclientx.PostJSON[*OpenPortsRequest, *OpenPortsResponse](
+		ctx,
+		httpclientx.NewEndpoint(URL),
+		request,
+		&httpclientx.Config{
+			Client:    sess.DefaultHTTPClient(),
+			Logger:    sess.Logger(),
+			UserAgent: sess.UserAgent(),
+		},
+	)
+}
@@ -7,14 +7,21 @@ package portfiltering
 import (
 	"context"
 	"errors"
+	"math/rand"
+	"net"
 	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ooni/probe-cli/v3/internal/logx"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
 const (
 	testName    = "portfiltering"
-	testVersion = "0.1.0"
+	testVersion = "0.2.0"
 )
 
 // Measurer performs the measurement.
@@ -35,30 +42,165 @@ func (m *Measurer) ExperimentVersion() string {
 var (
 	// errInvalidTestHelper indicates that the given test helper is not an URL
 	errInvalidTestHelper = errors.New("testhelper is not an URL")
+
+	// errUnsupportedProtocol indicates that the user selected an unsupported protocol
+	errUnsupportedProtocol = errors.New("unsupported protocol")
 )
 
+// probe is a single port we should test using a given protocol.
+type probe struct {
+	protocol string
+	port     int
+}
+
+// probeResult is the result of a [probe].
+type probeResult struct {
+	probe      *probe
+	tcpConnect *model.ArchivalTCPConnectResult
+	udpEcho    *UDPEchoResult
+}
+
 // Run implements ExperimentMeasurer.Run.
 func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	_ = args.Callbacks
 	measurement := args.Measurement
 	sess := args.Session
-	// TODO(DecFox): Replace the localhost deployment with an OONI testhelper
-	// Ensure that we only do this once we have a deployed testhelper
-	testhelper := "http://127.0.0.1"
-	parsed, err := url.Parse(testhelper)
+	ports, err := m.config.ports()
+	if err != nil {
+		return err
+	}
+	protocols, err := m.config.protocols()
 	if err != nil {
+		return err
+	}
+	testhelper := m.config.testHelper()
+	parsed, err := url.Parse(testhelper)
+	if err != nil || parsed.Hostname() == "" {
 		return errInvalidTestHelper
 	}
 	tk := new(TestKeys)
 	measurement.TestKeys = tk
-	out := make(chan *model.ArchivalTCPConnectResult)
-	go m.tcpConnectLoop(ctx, measurement.MeasurementStartTimeSaved, sess.Logger(), parsed.Host, out)
-	for len(tk.TCPConnect) < len(Ports) {
-		tk.TCPConnect = append(tk.TCPConnect, <-out)
+	request := newOpenPortsRequest(protocols, ports)
+	if m.config.OpenPorts {
+		// ask the test helper to keep the ports open until we have tested them all
+		request.Lifetime = m.config.runtime(len(request.TCP) + len(request.UDP)).Milliseconds()
+		ol := logx.NewOperationLogger(sess.Logger(), "OpenPorts %s", testhelper)
+		response, err := openPorts(ctx, sess, testhelper, request)
+		ol.Stop(err)
+		if err != nil {
+			failure := err.Error()
+			tk.HelperFailure = &failure
+			return nil // return nil so we always submit the measurement
+		}
+		if response.Lifetime > 0 && response.Lifetime < request.Lifetime {
+			sess.Logger().Warnf("portfiltering: the test helper keeps ports open for %d ms but we need %d ms",
+				response.Lifetime, request.Lifetime)
+		}
+		// only keep the requested ports that the test helper opened
+		request.TCP = intersectPorts(sess.Logger(), "tcp", request.TCP, response.TCP)
+		request.UDP = intersectPorts(sess.Logger(), "udp", request.UDP, response.UDP)
 	}
+	probes := append(newProbes("tcp", request.TCP), newProbes("udp", request.UDP)...)
+	rand.Shuffle(len(probes), func(i, j int) {
+		probes[i], probes[j] = probes[j], probes[i]
+	})
+	out := make(chan *probeResult)
+	go m.probeLoop(ctx, measurement.MeasurementStartTimeSaved, sess.Logger(), parsed.Hostname(), probes, out)
+	for idx := 0; idx < len(probes); idx++ {
+		tk.addProbeResult(<-out)
+	}
+	slices.SortFunc(tk.Ports, func(a, b *PortResult) int {
+		if diff := strings.Compare(a.Protocol, b.Protocol); diff != 0 {
+			return diff
+		}
+		return a.Port - b.Port
+	})
 	return nil // return nil so we always submit the measurement
 }
 
+// newOpenPortsRequest creates the [*OpenPortsRequest] for the given protocols and ports.
+func newOpenPortsRequest(protocols, ports []string) *OpenPortsRequest {
+	request := &OpenPortsRequest{}
+	for _, protocol := range protocols {
+		switch protocol {
+		case "tcp":
+			request.TCP = ports
+		case "udp":
+			request.UDP = ports
+		}
+	}
+	return request
+}
+
+// intersectPorts returns the ports in requested that also appear in opened and
+// warns about the ports that the test helper did not open.
+func intersectPorts(logger model.Logger, protocol string, requested, opened []string) (out []string) {
+	var skipped []string
+	for _, port := range requested {
+		if slices.Contains(opened, port) {
+			out = append(out, port)
+			continue
+		}
+		skipped = append(skipped, port)
+	}
+	if len(skipped) > 0 {
+		logger.Warnf("portfiltering: skipping %s ports the test helper did not open: %s",
+			protocol, strings.Join(skipped, ","))
+	}
+	return
+}
+
+// newProbes creates the probes for the given protocol and ports.
+func newProbes(protocol string, ports []string) (out []*probe) {
+	for _, sport := range ports {
+		port, err := strconv.Atoi(sport)
+		if err != nil {
+			continue // cannot happen because we validate ports
+		}
+		out = append(out, &probe{protocol: protocol, port: port})
+	}
+	return
+}
+
+// probeLoop runs all the probes and emits the results onto the out channel.
+func (m *Measurer) probeLoop(ctx context.Context, zeroTime time.Time,
+	logger model.Logger, address string, probes []*probe, out chan<- *probeResult) {
+	ticker := time.NewTicker(m.config.delay())
+	defer ticker.Stop()
+	for i, p := range probes {
+		go m.probeAsync(ctx, int64(i), zeroTime, logger, address, p, out)
+		<-ticker.C
+	}
+}
+
+// probeAsync runs a single probe and emits the result onto the out channel.
+func (m *Measurer) probeAsync(ctx context.Context, index int64, zeroTime time.Time,
+	logger model.Logger, address string, p *probe, out chan<- *probeResult) {
+	endpoint := net.JoinHostPort(address, strconv.Itoa(p.port))
+	result := &probeResult{probe: p}
+	switch p.protocol {
+	case "udp":
+		result.udpEcho = m.udpEcho(ctx, index, zeroTime, logger, endpoint)
+	default:
+		result.tcpConnect = m.tcpConnect(ctx, index, zeroTime, logger, endpoint)
+	}
+	out <- result
+}
+
+// addProbeResult adds the result of a probe to the test keys.
+func (tk *TestKeys) addProbeResult(result *probeResult) {
+	var failure *string
+	switch {
+	case result.tcpConnect != nil:
+		tk.TCPConnect = append(tk.TCPConnect, result.tcpConnect)
+		failure = result.tcpConnect.Status.Failure
+	case result.udpEcho != nil:
+		tk.UDPEcho = append(tk.UDPEcho, result.udpEcho)
+		failure = result.udpEcho.Failure
+	}
+	tk.Ports = append(tk.Ports, newPortResult(result.probe.protocol, result.probe.port, failure))
+}
+
 // NewExperimentMeasurer creates a new ExperimentMeasurer.
 func NewExperimentMeasurer(config Config) model.ExperimentMeasurer {
 	return &Measurer{config: config}
@@ -2,8 +2,15 @@ package portfiltering
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
@@ -13,7 +20,7 @@ func TestMeasurerExperimentNameVersion(t *testing.T) {
 	if measurer.ExperimentName() != "portfiltering" {
 		t.Fatal("unexpected ExperimentName")
 	}
-	if measurer.ExperimentVersion() != "0.1.0" {
+	if measurer.ExperimentVersion() != "0.2.0" {
 		t.Fatal("unexpected ExperimentVersion")
 	}
 }
@@ -43,3 +50,160 @@ func TestMeasurer_run(t *testing.T) {
 		t.Fatal("unexpected number of ports")
 	}
 }
+
+func TestMeasurer_runWithOpenPorts(t *testing.T) {
+	// create a local UDP echo server
+	pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pconn.Close()
+	go func() {
+		buffer := make([]byte, 1024)
+		for {
+			count, addr, err := pconn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			_, _ = pconn.WriteTo(buffer[:count], addr)
+		}
+	}()
+	_, udpPort, err := net.SplitHostPort(pconn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// create a test helper that claims to have opened only the UDP port
+	var request OpenPortsRequest
+	helper := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &request)
+		data, _ = json.Marshal(&OpenPortsResponse{Lifetime: 60000, UDP: []string{udpPort}})
+		_, _ = w.Write(data)
+	}))
+	defer helper.Close()
+
+	m := NewExperimentMeasurer(Config{
+		Delay:      1,
+		OpenPorts:  true,
+		Ports:      udpPort + ",1",
+		Protocols:  "tcp,udp",
+		TestHelper: helper.URL,
+	})
+	meas := &model.Measurement{}
+	sess := &mocks.Session{
+		MockDefaultHTTPClient: func() model.HTTPClient {
+			return http.DefaultClient
+		},
+		MockLogger: func() model.Logger {
+			return model.DiscardLogger
+		},
+		MockUserAgent: func() string {
+			return "miniooni/0.1.0-dev"
+		},
+	}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+		Measurement: meas,
+		Session:     sess,
+	}
+	if err := m.Run(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+
+	expectRequest := OpenPortsRequest{
+		Lifetime: 5004, // four probes 1 ms apart plus the default timeout
+		TCP:      []string{udpPort, "1"},
+		UDP:      []string{udpPort, "1"},
+	}
+	if diff := cmp.Diff(expectRequest, request); diff != "" {
+		t.Fatal(diff)
+	}
+	tk := meas.TestKeys.(*TestKeys)
+	if tk.HelperFailure != nil {
+		t.Fatal("unexpected helper failure", *tk.HelperFailure)
+	}
+	if len(tk.TCPConnect) != 0 || len(tk.UDPEcho) != 1 || len(tk.Ports) != 1 {
+		t.Fatal("unexpected number of results")
+	}
+	if tk.Ports[0].Protocol != "udp" || tk.Ports[0].Status != StatusSuccess {
+		t.Fatal("unexpected port result", tk.Ports[0])
+	}
+	if len(tk.UDPEcho[0].NetworkEvents) != 2 {
+		t.Fatal("expected write and read network events")
+	}
+}
+
+func TestMeasurer_runWithHelperFailure(t *testing.T) {
+	helper := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer helper.Close()
+
+	m := NewExperimentMeasurer(Config{
+		OpenPorts:  true,
+		TestHelper: helper.URL,
+	})
+	meas := &model.Measurement{}
+	sess := &mocks.Session{
+		MockDefaultHTTPClient: func() model.HTTPClient {
+			return http.DefaultClient
+		},
+		MockLogger: func() model.Logger {
+			return model.DiscardLogger
+		},
+		MockUserAgent: func() string {
+			return "miniooni/0.1.0-dev"
+		},
+	}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+		Measurement: meas,
+		Session:     sess,
+	}
+	if err := m.Run(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+	tk := meas.TestKeys.(*TestKeys)
+	if tk.HelperFailure == nil {
+		t.Fatal("expected helper failure")
+	}
+	if len(tk.Ports) != 0 {
+		t.Fatal("expected no port results")
+	}
+}
+
+func TestMeasurer_runWithInvalidConfig(t *testing.T) {
+	cases := []struct {
+		name      string
+		config    Config
+		expectErr error
+	}{{
+		name:      "invalid ports",
+		config:    Config{Ports: "http"},
+		expectErr: ErrInvalidPortSet,
+	}, {
+		name:      "invalid protocols",
+		config:    Config{Protocols: "icmp"},
+		expectErr: errUnsupportedProtocol,
+	}, {
+		name:      "invalid test helper",
+		config:    Config{TestHelper: "\t"},
+		expectErr: errInvalidTestHelper,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewExperimentMeasurer(tc.config)
+			args := &model.ExperimentArgs{
+				Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+				Measurement: &model.Measurement{},
+				Session:     &mocks.Session{},
+			}
+			err := m.Run(context.Background(), args)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ package portfiltering
 // List of ports we want to measure
 //
 
+// Ports is the default list of ports we measure.
+//
 // List generated from nmap-services: https://github.com/nmap/nmap/blob/master/nmap-services
 // Note: Using privileged ports like :80 requires elevated permissions
 var Ports = []string{
@@ -71,3 +73,46 @@ var Ports = []string{
 	"2048",  // udp
 	"626",   // udp - Mac OS X Server serial number (licensing) daemon
 }
+
+// Profiles maps a named port profile to the ports it contains. Users can
+// reference these names inside the Ports option (e.g., "vpn,mail").
+var Profiles = map[string][]string{
+	// default contains the default list of ports.
+	"default": Ports,
+
+	// vpn contains ports commonly used by VPN and proxy protocols.
+	"vpn": {
+		"443",   // tcp, udp - OpenVPN/AnyConnect over TLS, QUIC-based VPNs
+		"500",   // udp - IKE
+		"1080",  // tcp - SOCKS
+		"1194",  // tcp, udp - OpenVPN
+		"1701",  // udp - L2TP
+		"1723",  // tcp - PPTP
+		"4500",  // udp - IKE Nat Traversal
+		"8388",  // tcp, udp - Shadowsocks
+		"51820", // udp - WireGuard
+	},
+
+	// mail contains ports used by email protocols.
+	"mail": {
+		"25",  // tcp - SMTP
+		"110", // tcp - POP3
+		"143", // tcp - IMAP
+		"465", // tcp - SMTP over TLS
+		"587", // tcp - SMTP submission
+		"993", // tcp - IMAP over TLS
+		"995", // tcp - POP3 over TLS
+	},
+
+	// gaming contains ports used by popular gaming platforms.
+	"gaming": {
+		"3074",  // tcp, udp - Xbox Live
+		"3478",  // tcp, udp - PlayStation Network, STUN
+		"3479",  // tcp, udp - PlayStation Network
+		"3659",  // tcp, udp - EA games
+		"6112",  // tcp, udp - Battle.net
+		"25565", // tcp - Minecraft
+		"27015", // tcp, udp - Steam game servers
+		"27036", // tcp, udp - Steam in-home streaming
+	},
+}
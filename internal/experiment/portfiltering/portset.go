@@ -0,0 +1,83 @@
+package portfiltering
+
+//
+// Parsing of user-provided port sets
+//
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPortSet indicates that a port set specification is invalid.
+var ErrInvalidPortSet = errors.New("portfiltering: invalid port set")
+
+// ParsePortSet parses a comma-separated port set specification. Each
+// entry is either a port (e.g., "443"), an inclusive port range (e.g.,
+// "8000-8010"), or the name of one of the [Profiles] (e.g., "vpn"). The
+// empty specification expands to the "default" profile. The returned
+// list does not contain duplicates and preserves the order in which
+// ports first appear in the specification.
+func ParsePortSet(spec string) ([]string, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = "default"
+	}
+	var (
+		out  []string
+		seen = make(map[string]bool)
+	)
+	add := func(port string) {
+		if !seen[port] {
+			seen[port] = true
+			out = append(out, port)
+		}
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if profile, found := Profiles[entry]; found {
+			for _, port := range profile {
+				add(port)
+			}
+			continue
+		}
+		first, last, err := parsePortRange(entry)
+		if err != nil {
+			return nil, err
+		}
+		for port := first; port <= last; port++ {
+			add(strconv.Itoa(port))
+		}
+	}
+	return out, nil
+}
+
+// parsePortRange parses either a single port or an inclusive port range.
+func parsePortRange(entry string) (int, int, error) {
+	sfirst, slast, isRange := strings.Cut(entry, "-")
+	first, err := parsePort(sfirst)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return first, first, nil
+	}
+	last, err := parsePort(slast)
+	if err != nil {
+		return 0, 0, err
+	}
+	if first > last {
+		return 0, 0, fmt.Errorf("%w: empty range: %s", ErrInvalidPortSet, entry)
+	}
+	return first, last, nil
+}
+
+// parsePort parses and validates a single port number.
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("%w: invalid port: %q", ErrInvalidPortSet, s)
+	}
+	return port, nil
+}
@@ -0,0 +1,73 @@
+package portfiltering
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePortSet(t *testing.T) {
+	type testcase struct {
+		name      string
+		spec      string
+		expect    []string
+		expectErr error
+	}
+
+	cases := []testcase{{
+		name:   "empty spec expands to the default profile",
+		spec:   "",
+		expect: Ports,
+	}, {
+		name:   "single ports",
+		spec:   "443, 80",
+		expect: []string{"443", "80"},
+	}, {
+		name:   "ranges and duplicates",
+		spec:   "8000-8002,8001,22",
+		expect: []string{"8000", "8001", "8002", "22"},
+	}, {
+		name:   "profiles",
+		spec:   "mail,25,1194",
+		expect: []string{"25", "110", "143", "465", "587", "993", "995", "1194"},
+	}, {
+		name:   "full range",
+		spec:   "1-65535",
+		expect: nil, // checked below
+	}, {
+		name:      "invalid port",
+		spec:      "443,https",
+		expectErr: ErrInvalidPortSet,
+	}, {
+		name:      "port out of range",
+		spec:      "65536",
+		expectErr: ErrInvalidPortSet,
+	}, {
+		name:      "empty range",
+		spec:      "10-1",
+		expectErr: ErrInvalidPortSet,
+	}, {
+		name:      "zero port",
+		spec:      "0-10",
+		expectErr: ErrInvalidPortSet,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ports, err := ParsePortSet(tc.spec)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if tc.spec == "1-65535" {
+				if len(ports) != 65535 || ports[0] != "1" || ports[65534] != "65535" {
+					t.Fatal("unexpected full range")
+				}
+				return
+			}
+			if diff := cmp.Diff(tc.expect, ports); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -1,8 +1,102 @@
 package portfiltering
 
-import "github.com/ooni/probe-cli/v3/internal/model"
+import (
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
 
 // TestKeys contains the experiment results.
 type TestKeys struct {
+	// TCPConnect contains the TCP connect results.
 	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect"`
+
+	// UDPEcho contains the UDP echo results.
+	UDPEcho []*UDPEchoResult `json:"udp_echo"`
+
+	// Ports contains a summary of the outcome of each probe sorted
+	// by protocol and then by port number.
+	Ports []*PortResult `json:"ports"`
+
+	// HelperFailure is the failure that occurred when asking the
+	// test helper to open the ports, if any.
+	HelperFailure *string `json:"helper_failure"`
+}
+
+// UDPEchoResult contains the result of sending a datagram to
+// a UDP port and waiting for the test helper to echo it back.
+type UDPEchoResult struct {
+	// IP is the IP address we sent the datagram to.
+	IP string `json:"ip"`
+
+	// Port is the port we sent the datagram to.
+	Port int `json:"port"`
+
+	// Failure is the failure that occurred, if any.
+	Failure *string `json:"failure"`
+
+	// NetworkEvents contains the write and read events.
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+
+	// T0 is when we started the probe.
+	T0 float64 `json:"t0"`
+
+	// T is when the probe terminated.
+	T float64 `json:"t"`
+}
+
+const (
+	// StatusSuccess indicates that we could connect to a TCP port or
+	// that the test helper echoed our UDP datagram back.
+	StatusSuccess = "success"
+
+	// StatusRST indicates that the TCP connect was reset or that we
+	// received an ICMP port unreachable for the UDP datagram.
+	StatusRST = "rst"
+
+	// StatusTimeout indicates that the probe timed out.
+	StatusTimeout = "timeout"
+
+	// StatusFailure indicates that the probe failed otherwise.
+	StatusFailure = "failure"
+)
+
+// PortResult summarizes the outcome of probing a single port.
+type PortResult struct {
+	// Port is the port number.
+	Port int `json:"port"`
+
+	// Protocol is either "tcp" or "udp".
+	Protocol string `json:"protocol"`
+
+	// Status is one of [StatusSuccess], [StatusRST], [StatusTimeout],
+	// and [StatusFailure].
+	Status string `json:"status"`
+
+	// Failure is the failure that occurred, if any.
+	Failure *string `json:"failure"`
+}
+
+// newPortResult creates a new [*PortResult] classifying the given failure.
+func newPortResult(protocol string, port int, failure *string) *PortResult {
+	return &PortResult{
+		Port:     port,
+		Protocol: protocol,
+		Status:   classifyFailure(failure),
+		Failure:  failure,
+	}
+}
+
+// classifyFailure maps a failure to the corresponding status.
+func classifyFailure(failure *string) string {
+	switch {
+	case failure == nil:
+		return StatusSuccess
+	case *failure == netxlite.FailureConnectionRefused,
+		*failure == netxlite.FailureConnectionReset:
+		return StatusRST
+	case *failure == netxlite.FailureGenericTimeoutError:
+		return StatusTimeout
+	default:
+		return StatusFailure
+	}
 }
@@ -0,0 +1,30 @@
+package portfiltering
+
+import (
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	failure := func(s string) *string {
+		return &s
+	}
+
+	cases := []struct {
+		failure *string
+		expect  string
+	}{
+		{nil, StatusSuccess},
+		{failure(netxlite.FailureConnectionRefused), StatusRST},
+		{failure(netxlite.FailureConnectionReset), StatusRST},
+		{failure(netxlite.FailureGenericTimeoutError), StatusTimeout},
+		{failure(netxlite.FailureHostUnreachable), StatusFailure},
+	}
+
+	for _, tc := range cases {
+		if got := classifyFailure(tc.failure); got != tc.expect {
+			t.Fatal("expected", tc.expect, "got", got)
+		}
+	}
+}
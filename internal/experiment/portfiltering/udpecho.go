@@ -0,0 +1,72 @@
+package portfiltering
+
+//
+// UDP echo for portfiltering
+//
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// udpEchoPayload is the datagram we expect the test helper to echo back.
+const udpEchoPayload = "ooniprobe-portfiltering"
+
+// errUnexpectedEchoReply indicates that the reply differs from the datagram we sent.
+var errUnexpectedEchoReply = errors.New("portfiltering: unexpected UDP echo reply")
+
+// udpEcho sends a datagram to the given UDP address and waits for the
+// test helper to echo it back, returning the result to the caller.
+func (m *Measurer) udpEcho(ctx context.Context, index int64,
+	zeroTime time.Time, logger model.Logger, address string) *UDPEchoResult {
+	ctx, cancel := context.WithTimeout(ctx, m.config.timeout())
+	defer cancel()
+	trace := measurexlite.NewTrace(index, zeroTime)
+	ol := logx.NewOperationLogger(logger, "UDPEcho #%d %s", index, address)
+	t0 := trace.TimeNow()
+	dialer := trace.NewDialerWithoutResolver(logger)
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err == nil {
+		err = udpEchoExchange(ctx, conn)
+		_ = conn.Close()
+	}
+	ol.Stop(err)
+	ip, sport, _ := net.SplitHostPort(address)
+	port, _ := strconv.Atoi(sport)
+	return &UDPEchoResult{
+		IP:            ip,
+		Port:          port,
+		Failure:       measurexlite.NewFailure(err),
+		NetworkEvents: trace.NetworkEvents(),
+		T0:            t0.Sub(zeroTime).Seconds(),
+		T:             trace.TimeNow().Sub(zeroTime).Seconds(),
+	}
+}
+
+// udpEchoExchange writes the payload and reads the echoed datagram.
+func udpEchoExchange(ctx context.Context, conn net.Conn) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	payload := []byte(udpEchoPayload)
+	if _, err := conn.Write(payload); err != nil {
+		return err
+	}
+	buffer := make([]byte, 1<<10)
+	count, err := conn.Read(buffer)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(payload, buffer[:count]) {
+		return errUnexpectedEchoReply
+	}
+	return nil
+}
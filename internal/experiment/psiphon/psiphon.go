@@ -15,7 +15,7 @@ import (
 
 const (
 	testName    = "psiphon"
-	testVersion = "0.6.0"
+	testVersion = "0.7.0"
 )
 
 // Config contains the experiment's configuration.
@@ -0,0 +1,182 @@
+package ptreachability
+
+//
+// Parsing bridge lines
+//
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/scrubber"
+)
+
+var (
+	// errInvalidBridgeLine indicates that the bridge line is invalid.
+	errInvalidBridgeLine = errors.New("ptreachability: invalid bridge line")
+
+	// errUnsupportedTransport indicates that we do not support the bridge line transport.
+	errUnsupportedTransport = errors.New("ptreachability: unsupported transport")
+
+	// errMissingBridgeArgument indicates that a mandatory bridge line argument is missing.
+	errMissingBridgeArgument = errors.New("ptreachability: missing bridge line argument")
+)
+
+// These are the transports we support.
+const (
+	transportMeek      = "meek_lite"
+	transportOBFS4     = "obfs4"
+	transportSnowflake = "snowflake"
+	transportSSH       = "ssh"
+	transportWebTunnel = "webtunnel"
+)
+
+// bridgeLine is a parsed bridge line.
+type bridgeLine struct {
+	// address is the bridge address.
+	address string
+
+	// args contains the transport arguments (e.g., cert for obfs4).
+	args map[string]string
+
+	// fingerprint is the OPTIONAL bridge fingerprint.
+	fingerprint string
+
+	// transport is the transport name.
+	transport string
+}
+
+// mandatoryArgs contains the mandatory arguments of each transport.
+var mandatoryArgs = map[string][]string{
+	transportMeek:      {"url"},
+	transportOBFS4:     {"cert"},
+	transportSnowflake: {},
+	transportSSH:       {},
+	transportWebTunnel: {"url"},
+}
+
+// parseBridgeLine parses a bridge line such as "obfs4 192.0.2.1:443 FINGERPRINT
+// cert=... iat-mode=0", optionally starting with the "Bridge" keyword.
+func parseBridgeLine(line string) (*bridgeLine, error) {
+	fields := strings.Fields(line)
+	if len(fields) > 0 && fields[0] == "Bridge" {
+		fields = fields[1:]
+	}
+	if len(fields) < 2 {
+		return nil, errInvalidBridgeLine
+	}
+	bl := &bridgeLine{
+		address:   fields[1],
+		args:      map[string]string{},
+		transport: fields[0],
+	}
+	if bl.transport == "meek" {
+		bl.transport = transportMeek // tor uses meek_lite but we also accept meek
+	}
+	mandatory, found := mandatoryArgs[bl.transport]
+	if !found {
+		return nil, errUnsupportedTransport
+	}
+	host, _, err := net.SplitHostPort(bl.address)
+	if err != nil {
+		return nil, errInvalidBridgeLine
+	}
+	if bl.transport == transportOBFS4 && net.ParseIP(host) == nil {
+		return nil, errInvalidBridgeLine // we cannot perform the handshake with a domain
+	}
+	for _, field := range fields[2:] {
+		if key, value, found := strings.Cut(field, "="); found {
+			bl.args[key] = value
+			continue
+		}
+		if bl.fingerprint != "" || !isFingerprint(field) {
+			return nil, errInvalidBridgeLine
+		}
+		bl.fingerprint = field
+	}
+	for _, key := range mandatory {
+		if bl.args[key] == "" {
+			return nil, errMissingBridgeArgument
+		}
+	}
+	if value, found := bl.args["url"]; found {
+		if URL, err := url.Parse(value); err != nil || URL.Scheme != "https" || URL.Host == "" {
+			return nil, errInvalidBridgeLine
+		}
+	}
+	return bl, nil
+}
+
+// isFingerprint returns whether the given value is a bridge fingerprint.
+func isFingerprint(value string) bool {
+	data, err := hex.DecodeString(value)
+	return err == nil && len(data) == 20
+}
+
+// fronts returns the domain fronts of the bridge, if any.
+func (bl *bridgeLine) fronts() []string {
+	if value := bl.args["fronts"]; value != "" {
+		return strings.Split(value, ",")
+	}
+	if value := bl.args["front"]; value != "" {
+		return []string{value}
+	}
+	return nil
+}
+
+// private returns whether the bridge is private. We consider public the meek
+// and snowflake bridges, which only use public infrastructure (i.e., brokers
+// and domain fronts), while the address and the arguments of the other bridges
+// are exactly what allows users to reach them despite censorship.
+func (bl *bridgeLine) private() bool {
+	return bl.transport != transportMeek && bl.transport != transportSnowflake
+}
+
+// publicArgs contains the arguments that do not identify a bridge.
+var publicArgs = map[string]bool{
+	"iat-mode": true,
+	"ver":      true,
+}
+
+// secrets returns the values that identify a private bridge sorted by
+// decreasing length, so we replace a URL before its host.
+func (bl *bridgeLine) secrets() []string {
+	host, _, _ := net.SplitHostPort(bl.address) // already validated
+	out := []string{bl.address, host}
+	if bl.fingerprint != "" {
+		out = append(out, bl.fingerprint)
+	}
+	for key, value := range bl.args {
+		if publicArgs[key] || value == "" {
+			continue
+		}
+		out = append(out, value)
+		if URL, err := url.Parse(value); err == nil && URL.Host != "" {
+			out = append(out, URL.Host, URL.Hostname())
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if len(out[i]) != len(out[j]) {
+			return len(out[i]) > len(out[j])
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// maybeSanitize returns the given string with the values identifying the bridge
+// and any IP endpoint scrubbed, if the bridge is private, and otherwise returns
+// the string unchanged, which follows what the tor experiment does.
+func (bl *bridgeLine) maybeSanitize(s string) string {
+	if !bl.private() {
+		return s
+	}
+	for _, secret := range bl.secrets() {
+		s = strings.ReplaceAll(s, secret, "[scrubbed]")
+	}
+	return scrubber.ScrubString(s)
+}
@@ -0,0 +1,170 @@
+package ptreachability
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseBridgeLine(t *testing.T) {
+	type testcase struct {
+		name      string
+		input     string
+		expect    *bridgeLine
+		expectErr error
+	}
+	cases := []testcase{{
+		name:  "with obfs4",
+		input: "obfs4 192.0.2.1:443 74FAD13168806246602538555B5521A0383A1875 cert=antani iat-mode=0",
+		expect: &bridgeLine{
+			address:     "192.0.2.1:443",
+			args:        map[string]string{"cert": "antani", "iat-mode": "0"},
+			fingerprint: "74FAD13168806246602538555B5521A0383A1875",
+			transport:   transportOBFS4,
+		},
+	}, {
+		name:  "with the Bridge keyword and meek",
+		input: "Bridge meek 192.0.2.2:80 url=https://meek.example.com/ front=front.example.com",
+		expect: &bridgeLine{
+			address:   "192.0.2.2:80",
+			args:      map[string]string{"url": "https://meek.example.com/", "front": "front.example.com"},
+			transport: transportMeek,
+		},
+	}, {
+		name:  "with ssh",
+		input: "ssh 192.0.2.3:22",
+		expect: &bridgeLine{
+			address:   "192.0.2.3:22",
+			args:      map[string]string{},
+			transport: transportSSH,
+		},
+	}, {
+		name:      "with only the transport",
+		input:     "obfs4",
+		expectErr: errInvalidBridgeLine,
+	}, {
+		name:      "with a vanilla bridge",
+		input:     "192.0.2.1:443 74FAD13168806246602538555B5521A0383A1875",
+		expectErr: errUnsupportedTransport,
+	}, {
+		name:      "with an unsupported transport",
+		input:     "scramblesuit 192.0.2.1:443",
+		expectErr: errUnsupportedTransport,
+	}, {
+		name:      "with an invalid address",
+		input:     "ssh 192.0.2.1",
+		expectErr: errInvalidBridgeLine,
+	}, {
+		name:      "with obfs4 and a domain",
+		input:     "obfs4 bridge.example.com:443 cert=antani iat-mode=0",
+		expectErr: errInvalidBridgeLine,
+	}, {
+		name:      "with an invalid fingerprint",
+		input:     "obfs4 192.0.2.1:443 antani cert=antani iat-mode=0",
+		expectErr: errInvalidBridgeLine,
+	}, {
+		name:      "with two fingerprints",
+		input:     "obfs4 192.0.2.1:443 74FAD13168806246602538555B5521A0383A1875 74FAD13168806246602538555B5521A0383A1875 cert=antani",
+		expectErr: errInvalidBridgeLine,
+	}, {
+		name:      "with a missing mandatory argument",
+		input:     "obfs4 192.0.2.1:443 iat-mode=0",
+		expectErr: errMissingBridgeArgument,
+	}, {
+		name:      "with a URL not using HTTPS",
+		input:     "webtunnel [2001:db8::1]:443 url=http://webtunnel.example.com/path",
+		expectErr: errInvalidBridgeLine,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseBridgeLine(tc.input)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if diff := cmp.Diff(tc.expect, got, cmp.AllowUnexported(bridgeLine{})); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestBridgeLine_fronts(t *testing.T) {
+	type testcase struct {
+		name   string
+		args   map[string]string
+		expect []string
+	}
+	cases := []testcase{{
+		name:   "with fronts",
+		args:   map[string]string{"fronts": "a.example.com,b.example.com", "front": "c.example.com"},
+		expect: []string{"a.example.com", "b.example.com"},
+	}, {
+		name:   "with front",
+		args:   map[string]string{"front": "c.example.com"},
+		expect: []string{"c.example.com"},
+	}, {
+		name:   "without fronts",
+		args:   map[string]string{},
+		expect: nil,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bl := &bridgeLine{args: tc.args}
+			if diff := cmp.Diff(tc.expect, bl.fronts()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestBridgeLine_maybeSanitize(t *testing.T) {
+	type testcase struct {
+		name   string
+		input  string
+		expect string
+	}
+	cases := []testcase{{
+		name:   "with an obfs4 bridge",
+		input:  "Bridge obfs4 192.0.2.1:443 74FAD13168806246602538555B5521A0383A1875 cert=AAAA+bbbb/cccc iat-mode=0",
+		expect: "Bridge obfs4 [scrubbed] [scrubbed] cert=[scrubbed] iat-mode=0",
+	}, {
+		name:   "with a webtunnel bridge",
+		input:  "webtunnel [2001:db8::1]:443 url=https://webtunnel.example.com/path servername=webtunnel.example.com ver=0.0.1",
+		expect: "webtunnel [scrubbed] url=[scrubbed] servername=[scrubbed] ver=0.0.1",
+	}, {
+		name:   "with an ssh bridge",
+		input:  "ssh bridge.example.com:22",
+		expect: "ssh [scrubbed]",
+	}, {
+		name:   "with a meek bridge",
+		input:  "meek_lite 192.0.2.18:80 url=https://meek.example.com/ front=front.example.com",
+		expect: "meek_lite 192.0.2.18:80 url=https://meek.example.com/ front=front.example.com",
+	}, {
+		name:   "with a snowflake bridge",
+		input:  "snowflake 192.0.2.3:80 url=https://broker.example.com/ fronts=front.example.com",
+		expect: "snowflake 192.0.2.3:80 url=https://broker.example.com/ fronts=front.example.com",
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bl, err := parseBridgeLine(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bl.maybeSanitize(tc.input); got != tc.expect {
+				t.Fatal("unexpected sanitized line", got)
+			}
+		})
+	}
+
+	t.Run("we also scrub the host of the URL and the IP endpoints", func(t *testing.T) {
+		bl, err := parseBridgeLine("webtunnel [2001:db8::1]:443 url=https://webtunnel.example.com/path")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := bl.maybeSanitize("GET https://webtunnel.example.com/path via 192.0.2.55:443 (webtunnel.example.com)")
+		if got != "GET [scrubbed] via [scrubbed] ([scrubbed])" {
+			t.Fatal("unexpected sanitized string", got)
+		}
+	})
+}
@@ -0,0 +1,20 @@
+package ptreachability
+
+//
+// Config for the ptreachability experiment
+//
+
+import "time"
+
+// Config contains the experiment configuration.
+type Config struct {
+	// Timeout is the timeout of each operation (in milliseconds).
+	Timeout int64 `json:"timeout,omitempty" ooni:"timeout of each operation in milliseconds"`
+}
+
+func (c Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Millisecond
+	}
+	return 15 * time.Second
+}
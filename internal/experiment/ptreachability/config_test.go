@@ -0,0 +1,17 @@
+package ptreachability
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_timeout(t *testing.T) {
+	c := Config{}
+	if c.timeout() != 15*time.Second {
+		t.Fatal("invalid default timeout")
+	}
+	c.Timeout = 1000
+	if c.timeout() != 1*time.Second {
+		t.Fatal("invalid configured timeout")
+	}
+}
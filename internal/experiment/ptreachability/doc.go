@@ -0,0 +1,25 @@
+// Package ptreachability implements the ptreachability experiment.
+//
+// This experiment measures the reachability of Tor bridges by performing
+// only the pluggable transport handshake, without bootstrapping tor, which
+// makes it lightweight enough to run frequently on mobile. The input is a
+// bridge line, using the same syntax of tor's Bridge option, and we support
+// the following transports:
+//
+// - obfs4, for which we perform the obfs4 handshake;
+//
+// - meek_lite, for which we fetch the meek server page using domain fronting;
+//
+// - snowflake, for which we fetch the broker's robots.txt using domain
+// fronting, since the broker is what censors usually block;
+//
+// - webtunnel, for which we perform the HTTP upgrade;
+//
+// - ssh, which is not a pluggable transport but a common way of reaching
+// bridges and proxies, for which we check the server banner (e.g., "ssh
+// 192.0.2.1:22" is a valid input).
+//
+// Because meek and snowflake only use public infrastructure, we consider all
+// the other bridges private and, like the tor experiment does for private
+// bridges, we scrub their addresses and arguments from the measurement.
+package ptreachability
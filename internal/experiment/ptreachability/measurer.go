@@ -0,0 +1,136 @@
+package ptreachability
+
+//
+// Measurer
+//
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/targetloading"
+)
+
+const (
+	testName    = "ptreachability"
+	testVersion = "0.2.0"
+)
+
+// Measurer performs the measurement.
+type Measurer struct{}
+
+// ExperimentName implements ExperimentMeasurer.ExperimentName.
+func (m *Measurer) ExperimentName() string {
+	return testName
+}
+
+// ExperimentVersion implements ExperimentMeasurer.ExperimentVersion.
+func (m *Measurer) ExperimentVersion() string {
+	return testVersion
+}
+
+var (
+	// ErrInputRequired indicates that no richer-input target was provided.
+	ErrInputRequired = targetloading.ErrInputRequired
+
+	// ErrInvalidInputType indicates that the richer-input target has the wrong type.
+	ErrInvalidInputType = targetloading.ErrInvalidInputType
+
+	// errNoInputProvided indicates you didn't provide any input
+	errNoInputProvided = errors.New("no input provided")
+)
+
+// Run implements ExperimentMeasurer.Run.
+func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	_ = args.Callbacks
+	measurement := args.Measurement
+	sess := args.Session
+
+	// obtain the richer-input target
+	if args.Target == nil {
+		return ErrInputRequired
+	}
+	target, ok := args.Target.(*Target)
+	if !ok {
+		return ErrInvalidInputType
+	}
+	config, input := target.Config, target.BridgeLine
+
+	if input == "" {
+		return errNoInputProvided
+	}
+	bridge, err := parseBridgeLine(input)
+	if err != nil {
+		return err
+	}
+	registerExtensions(measurement)
+	tk := NewTestKeys()
+	tk.Address = bridge.address
+	tk.Transport = bridge.transport
+
+	r := &runner{
+		config: config,
+		logger: maybeScrubbingLogger(sess.Logger(), bridge),
+		sess:   sess,
+		tk:     tk,
+		trace:  measurexlite.NewTrace(0, measurement.MeasurementStartTimeSaved),
+	}
+	ol := logx.NewOperationLogger(r.logger, "ptreachability %s %s", bridge.transport, bridge.address)
+	operation, err := r.run(ctx, bridge)
+	ol.Stop(err)
+	r.collect()
+	tk.Success = err == nil
+	if err != nil {
+		tk.FailedOperation = &operation
+		tk.Failure = measurexlite.NewFailure(err)
+	}
+	measurement.TestKeys = maybeSanitize(tk, bridge)
+	return nil // we want to submit this measurement
+}
+
+// maybeSanitize scrubs the values identifying the bridge and all the IP endpoints
+// from the test keys when the bridge is private. Like the tor experiment, we use
+// a strict policy for convenience, because we already have a well tested scrubber.
+func maybeSanitize(tk *TestKeys, bridge *bridgeLine) *TestKeys {
+	if !bridge.private() {
+		return tk
+	}
+	// Note: we disable HTML escaping so that URLs appear verbatim in the JSON
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(tk)
+	runtimex.PanicOnError(err, "json.Encode should not fail here")
+	out := &TestKeys{}
+	err = json.Unmarshal([]byte(bridge.maybeSanitize(buf.String())), out)
+	runtimex.PanicOnError(err, "json.Unmarshal should not fail here")
+	return out
+}
+
+// maybeScrubbingLogger returns a logger scrubbing IP endpoints when the bridge is private.
+func maybeScrubbingLogger(logger model.Logger, bridge *bridgeLine) model.Logger {
+	if !bridge.private() {
+		return logger
+	}
+	return &logx.ScrubberLogger{Logger: logger}
+}
+
+// registerExtensions registers the data format extensions we use.
+func registerExtensions(m *model.Measurement) {
+	model.ArchivalExtDNS.AddTo(m)
+	model.ArchivalExtNetevents.AddTo(m)
+	model.ArchivalExtHTTP.AddTo(m)
+	model.ArchivalExtTCPConnect.AddTo(m)
+	model.ArchivalExtTLSHandshake.AddTo(m)
+}
+
+// NewExperimentMeasurer creates a new ExperimentMeasurer.
+func NewExperimentMeasurer() *Measurer {
+	return &Measurer{}
+}
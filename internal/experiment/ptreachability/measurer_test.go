@@ -0,0 +1,209 @@
+package ptreachability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/gopacket/layers"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestMeasurerExperimentNameVersion(t *testing.T) {
+	measurer := NewExperimentMeasurer()
+	if measurer.ExperimentName() != "ptreachability" {
+		t.Fatal("unexpected ExperimentName")
+	}
+	if measurer.ExperimentVersion() != "0.2.0" {
+		t.Fatal("unexpected ExperimentVersion")
+	}
+}
+
+func runHelper(t *testing.T, target model.ExperimentTarget) (*model.Measurement, error) {
+	m := NewExperimentMeasurer()
+	meas := &model.Measurement{}
+	sess := &mocks.Session{
+		MockLogger: func() model.Logger {
+			return model.DiscardLogger
+		},
+		MockTempDir: func() string {
+			return t.TempDir()
+		},
+	}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+		Measurement: meas,
+		Session:     sess,
+		Target:      target,
+	}
+	err := m.Run(context.Background(), args)
+	return meas, err
+}
+
+func TestMeasurer_input_failure(t *testing.T) {
+	type testcase struct {
+		name      string
+		target    model.ExperimentTarget
+		expectErr error
+	}
+	cases := []testcase{{
+		name:      "with no target",
+		target:    nil,
+		expectErr: ErrInputRequired,
+	}, {
+		name:      "with invalid target type",
+		target:    &model.OOAPIURLInfo{},
+		expectErr: ErrInvalidInputType,
+	}, {
+		name:      "with empty input",
+		target:    &Target{Config: &Config{}, BridgeLine: ""},
+		expectErr: errNoInputProvided,
+	}, {
+		name:      "with invalid bridge line",
+		target:    &Target{Config: &Config{}, BridgeLine: "scramblesuit 192.0.2.1:443"},
+		expectErr: errUnsupportedTransport,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runHelper(t, tc.target)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
+
+// obfs4BridgeLine returns the bridge line for the obfs4 server using the given cert.
+func obfs4BridgeLine(cert string) string {
+	return fmt.Sprintf("obfs4 %s:9443 74FAD13168806246602538555B5521A0383A1875 cert=%s iat-mode=0",
+		netemx.AddressTorBridge, cert)
+}
+
+func TestMeasurer_with_netem(t *testing.T) {
+	type testcase struct {
+		name            string
+		bridgeLine      string
+		rule            netem.DPIRule
+		expectOperation string
+		expectFailure   string
+	}
+	cases := []testcase{{
+		name:       "obfs4",
+		bridgeLine: obfs4BridgeLine(netemx.MustOBFS4ServerCert()),
+	}, {
+		name:       "obfs4 with the bridge endpoint blocked",
+		bridgeLine: obfs4BridgeLine(netemx.MustOBFS4ServerCert()),
+		rule: &netem.DPIDropTrafficForServerEndpoint{
+			Logger:          model.DiscardLogger,
+			ServerIPAddress: netemx.AddressTorBridge,
+			ServerPort:      9443,
+			ServerProtocol:  layers.IPProtocolTCP,
+		},
+		expectOperation: netxlite.ConnectOperation,
+		expectFailure:   netxlite.FailureGenericTimeoutError,
+	}, {
+		// the server does not reply when the client uses the wrong cert, which
+		// is the same behavior we would see if a censor dropped the handshake
+		name:            "obfs4 with the wrong cert",
+		bridgeLine:      obfs4BridgeLine("ssH+9rP8dG2NLDN2XuFw63hIO/9MNNinLmxQDpVa+7kTOa9/m+tGWT1SmSYpQ9uTBGa6Hw"),
+		expectOperation: OBFS4HandshakeOperation,
+		expectFailure:   netxlite.FailureGenericTimeoutError,
+	}, {
+		name:       "meek",
+		bridgeLine: "meek_lite 192.0.2.18:80 url=https://meek.example.com/ front=front.example.com",
+	}, {
+		name:       "meek with the front blocked",
+		bridgeLine: "meek_lite 192.0.2.18:80 url=https://meek.example.com/ front=front.example.com",
+		rule: &netem.DPIResetTrafficForTLSSNI{
+			Logger: model.DiscardLogger,
+			SNI:    "front.example.com",
+		},
+		expectOperation: netxlite.TLSHandshakeOperation,
+		expectFailure:   netxlite.FailureConnectionReset,
+	}, {
+		name:       "meek using the second front",
+		bridgeLine: "meek_lite 192.0.2.18:80 url=https://meek.example.com/ fronts=front.example.com,broker.example.com",
+		rule: &netem.DPIResetTrafficForTLSSNI{
+			Logger: model.DiscardLogger,
+			SNI:    "front.example.com",
+		},
+	}, {
+		name:       "snowflake",
+		bridgeLine: "snowflake 192.0.2.3:80 url=https://broker.example.com/ fronts=front.example.com",
+	}, {
+		name:       "webtunnel",
+		bridgeLine: "webtunnel [2001:db8::1]:443 url=https://webtunnel.example.com/webtunnel ver=0.0.1",
+	}, {
+		name:            "webtunnel with the wrong path",
+		bridgeLine:      "webtunnel [2001:db8::1]:443 url=https://webtunnel.example.com/antani ver=0.0.1",
+		expectOperation: netxlite.HTTPRoundTripOperation,
+		expectFailure:   FailureHTTPUnexpectedStatusCode,
+	}, {
+		name:       "ssh",
+		bridgeLine: fmt.Sprintf("ssh %s:22", netemx.AddressTorBridge),
+	}, {
+		name:            "ssh with a server that is not an SSH server",
+		bridgeLine:      fmt.Sprintf("ssh %s:443", netemx.AddressTorBridge),
+		expectOperation: netxlite.ReadOperation,
+		expectFailure:   netxlite.FailureGenericTimeoutError,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := netemx.MustNewScenario(netemx.InternetScenario)
+			defer env.Close()
+			if tc.rule != nil {
+				env.DPIEngine().AddRule(tc.rule)
+			}
+
+			env.Do(func() {
+				target := &Target{BridgeLine: tc.bridgeLine, Config: &Config{Timeout: 1000}}
+				meas, err := runHelper(t, target)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tk := meas.TestKeys.(*TestKeys)
+				if tc.expectFailure == "" {
+					if tk.Failure != nil || !tk.Success {
+						t.Fatal("unexpected failure", *tk.Failure, *tk.FailedOperation)
+					}
+				} else {
+					if tk.Failure == nil || *tk.Failure != tc.expectFailure || tk.Success {
+						t.Fatal("unexpected failure", tk.Failure)
+					}
+					if *tk.FailedOperation != tc.expectOperation {
+						t.Fatal("unexpected failed operation", *tk.FailedOperation)
+					}
+				}
+				if len(tk.TCPConnect) <= 0 || len(tk.NetworkEvents) <= 0 {
+					t.Fatal("expected TCP connect results and network events")
+				}
+				bridge, err := parseBridgeLine(tc.bridgeLine)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bridge.private() {
+					return
+				}
+				data, err := json.Marshal(tk)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tk.Address != "[scrubbed]" {
+					t.Fatal("unexpected address", tk.Address)
+				}
+				for _, secret := range bridge.secrets() {
+					if bytes.Contains(data, []byte(secret)) {
+						t.Fatal("the test keys contain", secret, string(data))
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package ptreachability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/targetloading"
+)
+
+func TestTarget(t *testing.T) {
+	target := &Target{
+		BridgeLine: "ssh 192.0.2.1:22",
+		Config: &Config{
+			Timeout: 1000,
+		},
+	}
+
+	t.Run("Category", func(t *testing.T) {
+		if target.Category() != model.DefaultCategoryCode {
+			t.Fatal("invalid Category")
+		}
+	})
+
+	t.Run("Country", func(t *testing.T) {
+		if target.Country() != model.DefaultCountryCode {
+			t.Fatal("invalid Country")
+		}
+	})
+
+	t.Run("Input", func(t *testing.T) {
+		t.Run("with a private bridge", func(t *testing.T) {
+			if target.Input() != "ssh [scrubbed]" {
+				t.Fatal("invalid Input")
+			}
+		})
+
+		t.Run("with a public bridge", func(t *testing.T) {
+			const line = "snowflake 192.0.2.3:80"
+			if (&Target{BridgeLine: line}).Input() != line {
+				t.Fatal("invalid Input")
+			}
+		})
+
+		t.Run("with an invalid bridge line", func(t *testing.T) {
+			if (&Target{BridgeLine: "scramblesuit 192.0.2.1:443"}).Input() != "scramblesuit [scrubbed]" {
+				t.Fatal("invalid Input")
+			}
+		})
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		expect := []string{"Timeout=1000"}
+		if diff := cmp.Diff(expect, target.Options()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("String", func(t *testing.T) {
+		if target.String() != "ssh 192.0.2.1:22" {
+			t.Fatal("invalid String")
+		}
+	})
+}
+
+func TestNewLoader(t *testing.T) {
+	child := &targetloading.Loader{}
+	options := &Config{}
+	loader := NewLoader(child, options).(*targetLoader)
+	if child != loader.loader {
+		t.Fatal("invalid loader pointer")
+	}
+	if options != loader.options {
+		t.Fatal("invalid options pointer")
+	}
+}
+
+func TestTargetLoaderLoad(t *testing.T) {
+	type testcase struct {
+		name          string
+		options       *Config
+		loader        *targetloading.Loader
+		expectErr     error
+		expectTargets []model.ExperimentTarget
+	}
+
+	cases := []testcase{
+		{
+			name:    "with options and inputs",
+			options: &Config{Timeout: 1000},
+			loader: &targetloading.Loader{
+				ExperimentName: "ptreachability",
+				InputPolicy:    model.InputStrictlyRequired,
+				Logger:         model.DiscardLogger,
+				Session:        &mocks.Session{},
+				StaticInputs:   []string{"ssh 192.0.2.1:22"},
+			},
+			expectErr: nil,
+			expectTargets: []model.ExperimentTarget{
+				&Target{
+					BridgeLine: "ssh 192.0.2.1:22",
+					Config:     &Config{Timeout: 1000},
+				},
+			},
+		},
+
+		{
+			name:    "per-input inputs_extra overlays the experiment-wide config",
+			options: &Config{Timeout: 1000},
+			loader: &targetloading.Loader{
+				ExperimentName: "ptreachability",
+				InputPolicy:    model.InputStrictlyRequired,
+				Logger:         model.DiscardLogger,
+				Session:        &mocks.Session{},
+				StaticInputs: []string{
+					"ssh 192.0.2.1:22",
+					"ssh 192.0.2.2:22",
+				},
+				StaticInputsConfig: []json.RawMessage{
+					json.RawMessage(`{"timeout":500}`),
+					json.RawMessage(`{}`),
+				},
+			},
+			expectErr: nil,
+			expectTargets: []model.ExperimentTarget{
+				&Target{
+					BridgeLine: "ssh 192.0.2.1:22",
+					Config:     &Config{Timeout: 500}, // overridden per-input
+				},
+				&Target{
+					BridgeLine: "ssh 192.0.2.2:22",
+					Config:     &Config{Timeout: 1000}, // unchanged
+				},
+			},
+		},
+
+		{
+			name:    "no input errors because input is strictly required",
+			options: &Config{},
+			loader: &targetloading.Loader{
+				ExperimentName: "ptreachability",
+				InputPolicy:    model.InputStrictlyRequired,
+				Logger:         model.DiscardLogger,
+				Session:        &mocks.Session{},
+			},
+			expectErr:     targetloading.ErrInputRequired,
+			expectTargets: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tl := &targetLoader{
+				loader:  tc.loader,
+				options: tc.options,
+			}
+			targets, err := tl.Load(context.Background())
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if diff := cmp.Diff(tc.expectTargets, targets); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
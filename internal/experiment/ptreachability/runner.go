@@ -0,0 +1,337 @@
+package ptreachability
+
+//
+// Code to perform the pluggable transports handshakes
+//
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/ptx"
+)
+
+// These are the failures specific to the handshakes of this experiment, which we
+// emit when a bridge is reachable but does not behave like a bridge should.
+const (
+	// FailureHTTPUnexpectedStatusCode indicates that the HTTP round
+	// trip returned a status code we did not expect.
+	FailureHTTPUnexpectedStatusCode = "http_unexpected_status_code"
+
+	// FailureSSHInvalidBanner indicates that the server did not send
+	// us a valid SSH banner.
+	FailureSSHInvalidBanner = "ssh_invalid_banner"
+)
+
+// OBFS4HandshakeOperation is the operation name for the obfs4 handshake.
+const OBFS4HandshakeOperation = "obfs4_handshake"
+
+var (
+	errHTTPUnexpectedStatusCode = &netxlite.ErrWrapper{
+		Failure:    FailureHTTPUnexpectedStatusCode,
+		Operation:  netxlite.HTTPRoundTripOperation,
+		WrappedErr: errors.New("http round trip returned an unexpected status code"),
+	}
+
+	errSSHInvalidBanner = &netxlite.ErrWrapper{
+		Failure:    FailureSSHInvalidBanner,
+		Operation:  netxlite.ReadOperation,
+		WrappedErr: errors.New("the server did not send a valid ssh banner"),
+	}
+)
+
+// runner runs the handshake with a bridge.
+type runner struct {
+	config *Config
+	logger model.Logger
+	sess   model.ExperimentSession
+	tk     *TestKeys
+	trace  *measurexlite.Trace
+}
+
+// run performs the handshake with the given bridge. On failure, it returns
+// the failed operation along with the error.
+func (r *runner) run(ctx context.Context, bridge *bridgeLine) (string, error) {
+	switch bridge.transport {
+	case transportOBFS4:
+		return r.obfs4(ctx, bridge)
+	case transportMeek:
+		return r.frontedGET(ctx, bridge.fronts(), bridge.args["url"])
+	case transportSnowflake:
+		return r.snowflake(ctx, bridge)
+	case transportWebTunnel:
+		return r.webTunnel(ctx, bridge)
+	default:
+		return r.ssh(ctx, bridge)
+	}
+}
+
+// collect saves the results collected by the trace into the test keys.
+func (r *runner) collect() {
+	r.tk.NetworkEvents = append(r.tk.NetworkEvents, r.trace.NetworkEvents()...)
+	r.tk.Queries = append(r.tk.Queries, r.trace.DNSLookupsFromRoundTrip()...)
+	r.tk.TCPConnect = append(r.tk.TCPConnect, r.trace.TCPConnects()...)
+	r.tk.TLSHandshakes = append(r.tk.TLSHandshakes, r.trace.TLSHandshakes()...)
+}
+
+// obfs4 performs the obfs4 handshake with the bridge.
+func (r *runner) obfs4(ctx context.Context, bridge *bridgeLine) (string, error) {
+	conn, operation, err := r.tcpDial(ctx, bridge.address)
+	if err != nil {
+		return operation, err
+	}
+	defer conn.Close()
+	iatMode := bridge.args["iat-mode"]
+	if iatMode == "" {
+		iatMode = "0"
+	}
+	dialer := &ptx.OBFS4Dialer{
+		Address:          bridge.address,
+		Cert:             bridge.args["cert"],
+		DataDir:          r.sess.TempDir(),
+		Fingerprint:      bridge.fingerprint,
+		IATMode:          iatMode,
+		UnderlyingDialer: netxlite.NewSingleUseDialer(conn),
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout())
+	defer cancel()
+	o4conn, err := dialer.DialContext(ctx)
+	if err != nil {
+		return OBFS4HandshakeOperation, err
+	}
+	o4conn.Close()
+	return "", nil
+}
+
+// snowflake fetches the robots.txt of the snowflake broker using domain fronting.
+func (r *runner) snowflake(ctx context.Context, bridge *bridgeLine) (string, error) {
+	brokerURL, fronts := bridge.args["url"], bridge.fronts()
+	if brokerURL == "" {
+		rendezvous := ptx.NewSnowflakeRendezvousMethodDomainFronting()
+		brokerURL, fronts = rendezvous.BrokerURL(), []string{rendezvous.FrontDomain()}
+	}
+	robotsURL, err := url.JoinPath(brokerURL, "robots.txt")
+	if err != nil {
+		return netxlite.TopLevelOperation, err // should not happen for valid bridge lines
+	}
+	return r.frontedGET(ctx, fronts, robotsURL)
+}
+
+// webTunnel performs the HTTP upgrade with the webtunnel bridge.
+func (r *runner) webTunnel(ctx context.Context, bridge *bridgeLine) (string, error) {
+	var key [16]byte
+	_, _ = rand.Read(key[:])
+	headers := http.Header{}
+	headers.Set("Connection", "Upgrade")
+	headers.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key[:]))
+	headers.Set("Sec-WebSocket-Version", "13")
+	headers.Set("Upgrade", "websocket")
+	return r.httpGET(ctx, "", bridge.args["servername"], bridge.args["url"], headers, http.StatusSwitchingProtocols)
+}
+
+// frontedGET fetches the given URL using the first domain front that works or
+// connecting directly to the URL's host when there are no domain fronts.
+func (r *runner) frontedGET(ctx context.Context, fronts []string, URL string) (string, error) {
+	if len(fronts) <= 0 {
+		return r.httpGET(ctx, "", "", URL, http.Header{}, http.StatusOK)
+	}
+	var (
+		operation string
+		err       error
+	)
+	for _, front := range fronts {
+		operation, err = r.httpGET(ctx, front, front, URL, http.Header{}, http.StatusOK)
+		if err == nil {
+			return "", nil
+		}
+	}
+	return operation, err
+}
+
+// httpGET fetches the given URL connecting to the front, if not empty, or to the
+// URL's host, and using the given server name, if not empty, or the host as the SNI.
+func (r *runner) httpGET(ctx context.Context, front, serverName, URL string,
+	headers http.Header, expectStatus int) (string, error) {
+	parsed, err := url.Parse(URL)
+	if err != nil {
+		return netxlite.TopLevelOperation, err // should not happen for valid bridge lines
+	}
+	host, port := parsed.Hostname(), parsed.Port()
+	if front != "" {
+		host, port = front, ""
+	}
+	if port == "" {
+		port = "443"
+	}
+	if serverName == "" {
+		serverName = host
+	}
+	addrs, operation, err := r.resolve(ctx, host)
+	if err != nil {
+		return operation, err
+	}
+	for _, addr := range addrs {
+		address := net.JoinHostPort(addr, port)
+		operation, err = r.httpTransaction(ctx, address, serverName, parsed, headers, expectStatus)
+		if err == nil {
+			return "", nil
+		}
+	}
+	return operation, err
+}
+
+// httpTransaction establishes a TLS connection with the given address, sends
+// the request and checks whether the response status is the expected one.
+func (r *runner) httpTransaction(ctx context.Context, address, serverName string,
+	URL *url.URL, headers http.Header, expectStatus int) (string, error) {
+	config := &tls.Config{
+		// We use HTTP/1.1 because the HTTP upgrade requires it.
+		NextProtos: []string{"http/1.1"},
+		ServerName: serverName,
+	}
+	tlsConn, operation, err := r.tlsDial(ctx, address, config)
+	if err != nil {
+		return operation, err
+	}
+	defer tlsConn.Close()
+	txp := netxlite.NewHTTPTransportWithOptions(
+		r.logger, netxlite.NewNullDialer(), netxlite.NewSingleUseTLSDialer(tlsConn))
+	defer txp.CloseIdleConnections()
+
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", URL.String(), nil)
+	if err != nil {
+		return netxlite.TopLevelOperation, err
+	}
+	req.Header = headers.Clone()
+	req.Header.Set("Accept", model.HTTPHeaderAccept)
+	req.Header.Set("Accept-Language", model.HTTPHeaderAcceptLanguage)
+	req.Header.Set("User-Agent", model.HTTPHeaderUserAgent)
+
+	const maxbody = 1 << 13
+	started := r.trace.TimeSince(r.trace.ZeroTime())
+	r.tk.NetworkEvents = append(r.tk.NetworkEvents, measurexlite.NewArchivalNetworkEvent(
+		r.trace.Index(), started, "http_transaction_start", "tcp", address, 0, nil, started))
+	resp, err := txp.RoundTrip(req)
+	var body []byte
+	if err == nil {
+		defer resp.Body.Close()
+		// Note: after a successful upgrade the body is the connection itself
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			body, err = netxlite.StreamAllContext(ctx, io.LimitReader(resp.Body, maxbody))
+		}
+	}
+	finished := r.trace.TimeSince(r.trace.ZeroTime())
+	r.tk.NetworkEvents = append(r.tk.NetworkEvents, measurexlite.NewArchivalNetworkEvent(
+		r.trace.Index(), finished, "http_transaction_done", "tcp", address, 0, nil, finished))
+	r.tk.Requests = append(r.tk.Requests, measurexlite.NewArchivalHTTPRequestResult(
+		r.trace.Index(), started, "tcp", address, "http/1.1", txp.Network(),
+		req, resp, maxbody, body, err, finished))
+
+	if err != nil {
+		return netxlite.HTTPRoundTripOperation, err
+	}
+	if resp.StatusCode != expectStatus {
+		return netxlite.HTTPRoundTripOperation, errHTTPUnexpectedStatusCode
+	}
+	return "", nil
+}
+
+// ssh connects to the bridge and reads the SSH banner.
+func (r *runner) ssh(ctx context.Context, bridge *bridgeLine) (string, error) {
+	host, _, _ := net.SplitHostPort(bridge.address) // already validated
+	addrs, operation, err := r.resolve(ctx, host)
+	if err != nil {
+		return operation, err
+	}
+	_, port, _ := net.SplitHostPort(bridge.address)
+	conn, operation, err := r.tcpDial(ctx, net.JoinHostPort(addrs[0], port))
+	if err != nil {
+		return operation, err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout())
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// The server MAY send other lines before the banner (see RFC 4253 Sect. 4.2)
+	// and the maximum line length is 255 bytes including the trailing CRLF.
+	const maxLines = 16
+	reader := bufio.NewReaderSize(conn, 256)
+	for idx := 0; idx < maxLines; idx++ {
+		data, err := reader.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return netxlite.ReadOperation, errSSHInvalidBanner
+		}
+		if err != nil {
+			return netxlite.ReadOperation, err
+		}
+		line := strings.TrimRight(string(data), "\r\n")
+		if strings.HasPrefix(line, "SSH-") {
+			r.tk.SSHBanner = &line
+			if !strings.HasPrefix(line, "SSH-2.0-") && !strings.HasPrefix(line, "SSH-1.99-") {
+				return netxlite.ReadOperation, errSSHInvalidBanner
+			}
+			return "", nil
+		}
+	}
+	return netxlite.ReadOperation, errSSHInvalidBanner
+}
+
+// resolve resolves the given domain unless it is an IP address.
+func (r *runner) resolve(ctx context.Context, domain string) ([]string, string, error) {
+	if net.ParseIP(domain) != nil {
+		return []string{domain}, "", nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout())
+	defer cancel()
+	reso := r.trace.NewStdlibResolver(r.logger)
+	addrs, err := reso.LookupHost(ctx, domain)
+	if err != nil {
+		return nil, netxlite.ResolveOperation, err
+	}
+	return addrs, "", nil
+}
+
+// tcpDial establishes a TCP connection with the given address.
+func (r *runner) tcpDial(ctx context.Context, address string) (net.Conn, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout())
+	defer cancel()
+	dialer := r.trace.NewDialerWithoutResolver(r.logger)
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, netxlite.ConnectOperation, err
+	}
+	return conn, "", nil
+}
+
+// tlsDial establishes a TLS connection with the given address.
+func (r *runner) tlsDial(ctx context.Context, address string, config *tls.Config) (model.TLSConn, string, error) {
+	tcpConn, operation, err := r.tcpDial(ctx, address)
+	if err != nil {
+		return nil, operation, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout())
+	defer cancel()
+	handshaker := r.trace.NewTLSHandshakerStdlib(r.logger)
+	tlsConn, err := handshaker.Handshake(ctx, tcpConn, config)
+	if err != nil {
+		tcpConn.Close()
+		return nil, netxlite.TLSHandshakeOperation, err
+	}
+	return tlsConn, "", nil
+}
@@ -0,0 +1,50 @@
+package ptreachability
+
+import "github.com/ooni/probe-cli/v3/internal/model"
+
+// TestKeys contains the experiment results
+type TestKeys struct {
+	// Address is the bridge address.
+	Address string `json:"address"`
+
+	// FailedOperation is the operation that failed or nil.
+	FailedOperation *string `json:"failed_operation"`
+
+	// Failure is the failure that occurred or nil.
+	Failure *string `json:"failure"`
+
+	// NetworkEvents contains the network events.
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+
+	// Queries contains the DNS lookups.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// Requests contains the HTTP requests.
+	Requests []*model.ArchivalHTTPRequestResult `json:"requests"`
+
+	// SSHBanner is the banner sent by the SSH server or nil.
+	SSHBanner *string `json:"ssh_banner"`
+
+	// Success indicates that the handshake succeeded.
+	Success bool `json:"success"`
+
+	// TCPConnect contains the TCP connect results.
+	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect"`
+
+	// TLSHandshakes contains the TLS handshake results.
+	TLSHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
+
+	// Transport is the transport we measured (e.g., "obfs4").
+	Transport string `json:"transport"`
+}
+
+// NewTestKeys creates new ptreachability TestKeys
+func NewTestKeys() *TestKeys {
+	return &TestKeys{
+		NetworkEvents: []*model.ArchivalNetworkEvent{},
+		Queries:       []*model.ArchivalDNSLookupResult{},
+		Requests:      []*model.ArchivalHTTPRequestResult{},
+		TCPConnect:    []*model.ArchivalTCPConnectResult{},
+		TLSHandshakes: []*model.ArchivalTLSOrQUICHandshakeResult{},
+	}
+}
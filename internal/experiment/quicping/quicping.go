@@ -18,7 +18,7 @@ import (
 	_ "crypto/sha256"
 
 	"github.com/ooni/probe-cli/v3/internal/legacy/legacymodel"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/targetloading"
 )
@@ -34,7 +34,7 @@ const (
 
 const (
 	testName    = "quicping"
-	testVersion = "0.1.2"
+	testVersion = "0.1.3"
 )
 
 var (
@@ -111,7 +111,7 @@ func makeResponse(resp *responseInfo) *SinglePingResponse {
 	}
 	return &SinglePingResponse{
 		Data:              data,
-		Failure:           tracex.NewFailure(resp.err),
+		Failure:           measurexlite.NewFailure(resp.err),
 		T:                 resp.t,
 		SupportedVersions: resp.versions,
 	}
@@ -296,7 +296,7 @@ L:
 				tk.Pings = append(tk.Pings, &SinglePing{
 					ConnIdDst: req.dstID,
 					ConnIdSrc: req.srcID,
-					Failure:   tracex.NewFailure(req.err),
+					Failure:   measurexlite.NewFailure(req.err),
 					Request:   &legacymodel.ArchivalMaybeBinaryData{Value: string(req.raw)},
 					T:         req.t,
 				})
@@ -336,7 +336,7 @@ L:
 			tk.Pings = append(tk.Pings, &SinglePing{
 				ConnIdDst: ping.request.dstID,
 				ConnIdSrc: ping.request.srcID,
-				Failure:   tracex.NewFailure(timeoutErr),
+				Failure:   measurexlite.NewFailure(timeoutErr),
 				Request:   &legacymodel.ArchivalMaybeBinaryData{Value: string(ping.request.raw)},
 				T:         ping.request.t,
 			})
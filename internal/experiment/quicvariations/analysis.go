@@ -0,0 +1,203 @@
+package quicvariations
+
+//
+// Analysis of the variations
+//
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+const (
+	// OutcomeAccessible means that no variation was blocked.
+	OutcomeAccessible = "accessible"
+
+	// OutcomeEndpointBlocked means that all the variations were blocked.
+	OutcomeEndpointBlocked = "endpoint_blocked"
+
+	// OutcomeFeatureBlocked means that the triggers explain all the blocked variations.
+	OutcomeFeatureBlocked = "feature_blocked"
+
+	// OutcomeInconclusive means that we cannot explain all the blocked variations.
+	OutcomeInconclusive = "inconclusive"
+)
+
+const (
+	featureALPN              = "alpn"
+	featureInitialPacketSize = "initial_packet_size"
+	featureSNI               = "sni"
+	featureVersion           = "version"
+)
+
+// allFeatures contains all the features we vary.
+var allFeatures = []string{featureVersion, featureSNI, featureALPN, featureInitialPacketSize}
+
+// Analysis contains the results of analyzing the variations
+type Analysis struct {
+	// Blocked is the number of blocked variations.
+	Blocked int64 `json:"blocked"`
+
+	// Outcome is the outcome of the analysis (e.g., "feature_blocked").
+	Outcome string `json:"outcome"`
+
+	// Total is the total number of variations.
+	Total int64 `json:"total"`
+
+	// Triggers contains the combinations of features that trigger blocking.
+	Triggers []*Trigger `json:"triggers"`
+}
+
+// Trigger is a combination of features such that all the variations having all
+// these features are blocked, e.g., version=v1 and sni=real.
+type Trigger struct {
+	Features []*Feature `json:"features"`
+}
+
+// Feature is a feature of a variation (e.g., version=v2).
+type Feature struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// blocked returns whether the variation was blocked. We consider a variation blocked when
+// it times out, because any other failure implies that we received a server response.
+func (v *Variation) blocked() bool {
+	return v.Failure != nil && *v.Failure == netxlite.FailureGenericTimeoutError
+}
+
+// feature returns the value of the given feature and whether the feature is applicable
+// to this variation, since draft versions have no SNI and no ALPN.
+func (v *Variation) feature(name string) (string, bool) {
+	switch name {
+	case featureALPN:
+		return v.ALPN, v.SNI != ""
+	case featureInitialPacketSize:
+		return strconv.FormatInt(v.InitialPacketSize, 10), true
+	case featureSNI:
+		return v.SNI, v.SNI != ""
+	case featureVersion:
+		return v.Version, true
+	default:
+		return "", false
+	}
+}
+
+// matches returns whether the variation has all the given features.
+func (v *Variation) matches(features ...*Feature) bool {
+	for _, f := range features {
+		if value, ok := v.feature(f.Name); !ok || value != f.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// isTrigger returns whether the variations having all the given features exist and are blocked.
+func isTrigger(variations []*Variation, features ...*Feature) bool {
+	var count int
+	for _, v := range variations {
+		if !v.matches(features...) {
+			continue
+		}
+		if !v.blocked() {
+			return false
+		}
+		count++
+	}
+	return count > 0
+}
+
+// allFeatureValues returns all the feature values, sorted by feature and value.
+func allFeatureValues(variations []*Variation) []*Feature {
+	out := []*Feature{}
+	for _, name := range allFeatures {
+		values := map[string]bool{}
+		for _, v := range variations {
+			if value, ok := v.feature(name); ok {
+				values[value] = true
+			}
+		}
+		sorted := []string{}
+		for value := range values {
+			sorted = append(sorted, value)
+		}
+		sort.Strings(sorted)
+		for _, value := range sorted {
+			out = append(out, &Feature{Name: name, Value: value})
+		}
+	}
+	return out
+}
+
+// analyze analyzes the variations to find the features triggering blocking. We first
+// look for single features and then, for the blocked variations that single features
+// cannot explain, we look for pairs of features (e.g., a censor that only recognizes
+// the SNI when using QUIC v1 would block version=v1 and sni=real).
+func analyze(variations []*Variation) *Analysis {
+	a := &Analysis{
+		Outcome:  OutcomeAccessible,
+		Total:    int64(len(variations)),
+		Triggers: []*Trigger{},
+	}
+	for _, v := range variations {
+		if v.blocked() {
+			a.Blocked++
+		}
+	}
+	switch {
+	case a.Blocked <= 0:
+		return a
+	case a.Blocked == a.Total:
+		a.Outcome = OutcomeEndpointBlocked
+		return a
+	}
+	covered := map[int64]bool{}
+	cover := func(features ...*Feature) {
+		a.Triggers = append(a.Triggers, &Trigger{Features: features})
+		for _, v := range variations {
+			if v.matches(features...) {
+				covered[v.ID] = true
+			}
+		}
+	}
+	candidates := []*Feature{}
+	for _, f := range allFeatureValues(variations) {
+		if isTrigger(variations, f) {
+			cover(f)
+			continue
+		}
+		candidates = append(candidates, f)
+	}
+	for i := 0; i < len(candidates); i++ {
+		for j := i + 1; j < len(candidates); j++ {
+			first, second := candidates[i], candidates[j]
+			if first.Name == second.Name || !isTrigger(variations, first, second) {
+				continue
+			}
+			if coversNewVariations(variations, covered, first, second) {
+				cover(first, second)
+			}
+		}
+	}
+	a.Outcome = OutcomeFeatureBlocked
+	for _, v := range variations {
+		if v.blocked() && !covered[v.ID] {
+			a.Outcome = OutcomeInconclusive
+			break
+		}
+	}
+	return a
+}
+
+// coversNewVariations returns whether the features match variations not already covered.
+func coversNewVariations(variations []*Variation, covered map[int64]bool, features ...*Feature) bool {
+	for _, v := range variations {
+		if v.matches(features...) && !covered[v.ID] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package quicvariations
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// failurePtr returns a pointer to the given failure.
+func failurePtr(failure string) *string {
+	return &failure
+}
+
+// newTestVariations returns the default variations using two ALPNs.
+func newTestVariations() []*Variation {
+	return newVariations(allVersions, []int64{1200, 1452}, "www.example.com", "example.com", []string{"h3", "hq-interop"})
+}
+
+// block sets a timeout failure for the variations for which fx returns true and
+// sets another failure implying we reached the server for one of the others.
+func block(variations []*Variation, fx func(v *Variation) bool) []*Variation {
+	for _, v := range variations {
+		if fx(v) {
+			v.Failure = failurePtr(netxlite.FailureGenericTimeoutError)
+		}
+	}
+	for _, v := range variations {
+		if v.Failure == nil && v.SNI == sniControl {
+			v.Failure = failurePtr(netxlite.FailureSSLFailedHandshake)
+			break
+		}
+	}
+	return variations
+}
+
+func TestNewVariations(t *testing.T) {
+	variations := newTestVariations()
+	// (v1, v2) * (1200, 1452) * (real, control, empty) * (h3, hq-interop) + draft-29 * (1200, 1452)
+	if len(variations) != 2*2*3*2+2 {
+		t.Fatal("unexpected number of variations", len(variations))
+	}
+	for idx, v := range variations {
+		if v.ID != int64(idx+1) {
+			t.Fatal("unexpected ID", v.ID)
+		}
+		if v.Version == versionDraft29 && (v.SNI != "" || v.ALPN != "") {
+			t.Fatal("draft variations should not have an SNI or an ALPN")
+		}
+		if v.SNI == sniEmpty && v.ServerName != "" {
+			t.Fatal("empty SNI variations should have an empty server name")
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	type testcase struct {
+		name       string
+		variations []*Variation
+		expect     *Analysis
+	}
+
+	feature := func(name, value string) *Feature {
+		return &Feature{Name: name, Value: value}
+	}
+
+	cases := []testcase{{
+		name:       "with no blocked variations",
+		variations: newTestVariations(),
+		expect: &Analysis{
+			Blocked:  0,
+			Outcome:  OutcomeAccessible,
+			Total:    26,
+			Triggers: []*Trigger{},
+		},
+	}, {
+		name: "with all variations blocked",
+		variations: block(newTestVariations(), func(v *Variation) bool {
+			return true
+		}),
+		expect: &Analysis{
+			Blocked:  26,
+			Outcome:  OutcomeEndpointBlocked,
+			Total:    26,
+			Triggers: []*Trigger{},
+		},
+	}, {
+		name: "with blocking based on the version",
+		variations: block(newTestVariations(), func(v *Variation) bool {
+			return v.Version == versionV2
+		}),
+		expect: &Analysis{
+			Blocked: 12,
+			Outcome: OutcomeFeatureBlocked,
+			Total:   26,
+			Triggers: []*Trigger{{
+				Features: []*Feature{feature(featureVersion, versionV2)},
+			}},
+		},
+	}, {
+		name: "with blocking based on the SNI",
+		variations: block(newTestVariations(), func(v *Variation) bool {
+			return v.SNI == sniReal
+		}),
+		expect: &Analysis{
+			Blocked: 8,
+			Outcome: OutcomeFeatureBlocked,
+			Total:   26,
+			Triggers: []*Trigger{{
+				Features: []*Feature{feature(featureSNI, sniReal)},
+			}},
+		},
+	}, {
+		name: "with blocking based on the ALPN and on the Initial packet size",
+		variations: block(newTestVariations(), func(v *Variation) bool {
+			return v.ALPN == "hq-interop" || v.InitialPacketSize == 1452
+		}),
+		expect: &Analysis{
+			Blocked: 19,
+			Outcome: OutcomeFeatureBlocked,
+			Total:   26,
+			Triggers: []*Trigger{{
+				Features: []*Feature{feature(featureALPN, "hq-interop")},
+			}, {
+				Features: []*Feature{feature(featureInitialPacketSize, "1452")},
+			}},
+		},
+	}, {
+		name: "with blocking based on the SNI only when using QUIC v1",
+		variations: block(newTestVariations(), func(v *Variation) bool {
+			return v.SNI == sniReal && v.Version == versionV1
+		}),
+		expect: &Analysis{
+			Blocked: 4,
+			Outcome: OutcomeFeatureBlocked,
+			Total:   26,
+			Triggers: []*Trigger{{
+				Features: []*Feature{feature(featureVersion, versionV1), feature(featureSNI, sniReal)},
+			}},
+		},
+	}, {
+		name: "with blocking we cannot explain",
+		variations: block(newTestVariations(), func(v *Variation) bool {
+			return v.ID == 1
+		}),
+		expect: &Analysis{
+			Blocked:  1,
+			Outcome:  OutcomeInconclusive,
+			Total:    26,
+			Triggers: []*Trigger{},
+		},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expect, analyze(tc.variations)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestVariation_feature(t *testing.T) {
+	v := &Variation{Version: versionDraft29, InitialPacketSize: 1200}
+	if _, ok := v.feature(featureSNI); ok {
+		t.Fatal("the SNI should not be applicable to draft versions")
+	}
+	if _, ok := v.feature(featureALPN); ok {
+		t.Fatal("the ALPN should not be applicable to draft versions")
+	}
+	if value, ok := v.feature(featureInitialPacketSize); !ok || value != "1200" {
+		t.Fatal("unexpected Initial packet size", value, ok)
+	}
+	if _, ok := v.feature("antani"); ok {
+		t.Fatal("unknown features should not be applicable")
+	}
+}
@@ -0,0 +1,123 @@
+package quicvariations
+
+//
+// Config for the quicvariations experiment
+//
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config contains the experiment configuration.
+type Config struct {
+	// ALPN is the space-separated list of ALPNs to try.
+	ALPN string `ooni:"space-separated list of ALPNs to try"`
+
+	// ControlSNI is the SNI that we do not expect to be blocked.
+	ControlSNI string `ooni:"control SNI that we do not expect to be blocked"`
+
+	// InitialPacketSizes is the space-separated list of Initial packet sizes to try.
+	InitialPacketSizes string `ooni:"space-separated list of Initial packet sizes to try"`
+
+	// Parallelism is the number of variations to run in parallel.
+	Parallelism int64 `ooni:"number of variations to run in parallel"`
+
+	// SNI is the SNI to test, which defaults to the input domain.
+	SNI string `ooni:"SNI to test, which defaults to the input domain"`
+
+	// Timeout is the timeout of each variation (in milliseconds).
+	Timeout int64 `ooni:"timeout of each variation in milliseconds"`
+
+	// Versions is the space-separated list of QUIC versions to try.
+	Versions string `ooni:"space-separated list of QUIC versions to try (v1, v2, draft-29)"`
+}
+
+func (c Config) alpns() []string {
+	if c.ALPN != "" {
+		return strings.Fields(c.ALPN)
+	}
+	return []string{"h3", "hq-interop"}
+}
+
+func (c Config) controlSNI() string {
+	if c.ControlSNI != "" {
+		return c.ControlSNI
+	}
+	return "example.com"
+}
+
+const (
+	// minInitialPacketSize is the minimum size of a QUIC Initial packet.
+	minInitialPacketSize = 1200
+
+	// maxInitialPacketSize is the maximum size of a QUIC Initial packet that
+	// fits into an Ethernet frame when using IPv6.
+	maxInitialPacketSize = 1452
+)
+
+// errInvalidInitialPacketSize indicates that an Initial packet size is invalid.
+var errInvalidInitialPacketSize = errors.New("quicvariations: invalid Initial packet size")
+
+func (c Config) initialPacketSizes() ([]int64, error) {
+	if c.InitialPacketSizes == "" {
+		return []int64{minInitialPacketSize, maxInitialPacketSize}, nil
+	}
+	out := []int64{}
+	for _, entry := range strings.Fields(c.InitialPacketSizes) {
+		size, err := strconv.ParseInt(entry, 10, 64)
+		if err != nil || size < minInitialPacketSize || size > maxInitialPacketSize {
+			return nil, errInvalidInitialPacketSize
+		}
+		out = append(out, size)
+	}
+	return out, nil
+}
+
+func (c Config) parallelism() int {
+	if c.Parallelism > 0 {
+		return int(c.Parallelism)
+	}
+	return 4
+}
+
+// errMissingSNI indicates that we don't know which SNI to test.
+var errMissingSNI = errors.New("quicvariations: SNI is required when the input host is an IP address")
+
+// sni returns the configured SNI or the target hostname, if it's a domain name.
+func (c Config) sni(hostname string) (string, error) {
+	if c.SNI != "" {
+		return c.SNI, nil
+	}
+	if net.ParseIP(hostname) == nil {
+		return hostname, nil
+	}
+	return "", errMissingSNI
+}
+
+func (c Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Millisecond
+	}
+	return 3 * time.Second
+}
+
+// errInvalidVersion indicates that a QUIC version is invalid.
+var errInvalidVersion = errors.New("quicvariations: invalid QUIC version")
+
+func (c Config) versions() ([]string, error) {
+	if c.Versions == "" {
+		return allVersions, nil
+	}
+	out := []string{}
+	for _, version := range strings.Fields(c.Versions) {
+		if !isValidVersion(version) {
+			return nil, errInvalidVersion
+		}
+		out = append(out, version)
+	}
+	return out, nil
+}
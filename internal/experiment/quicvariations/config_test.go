@@ -0,0 +1,151 @@
+package quicvariations
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfig_alpns(t *testing.T) {
+	c := Config{}
+	if diff := cmp.Diff([]string{"h3", "hq-interop"}, c.alpns()); diff != "" {
+		t.Fatal(diff)
+	}
+	c.ALPN = "h3  h3-29"
+	if diff := cmp.Diff([]string{"h3", "h3-29"}, c.alpns()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestConfig_controlSNI(t *testing.T) {
+	c := Config{}
+	if c.controlSNI() != "example.com" {
+		t.Fatal("invalid default control SNI")
+	}
+	c.ControlSNI = "example.org"
+	if c.controlSNI() != "example.org" {
+		t.Fatal("invalid configured control SNI")
+	}
+}
+
+func TestConfig_initialPacketSizes(t *testing.T) {
+	type testcase struct {
+		name      string
+		config    Config
+		expect    []int64
+		expectErr error
+	}
+	cases := []testcase{{
+		name:   "with default sizes",
+		expect: []int64{1200, 1452},
+	}, {
+		name:   "with configured sizes",
+		config: Config{InitialPacketSizes: "1300 1400"},
+		expect: []int64{1300, 1400},
+	}, {
+		name:      "with non numeric size",
+		config:    Config{InitialPacketSizes: "antani"},
+		expectErr: errInvalidInitialPacketSize,
+	}, {
+		name:      "with too small size",
+		config:    Config{InitialPacketSizes: "1199"},
+		expectErr: errInvalidInitialPacketSize,
+	}, {
+		name:      "with too large size",
+		config:    Config{InitialPacketSizes: "1453"},
+		expectErr: errInvalidInitialPacketSize,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sizes, err := tc.config.initialPacketSizes()
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if diff := cmp.Diff(tc.expect, sizes); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestConfig_parallelism(t *testing.T) {
+	c := Config{}
+	if c.parallelism() != 4 {
+		t.Fatal("invalid default parallelism")
+	}
+	c.Parallelism = 11
+	if c.parallelism() != 11 {
+		t.Fatal("invalid configured parallelism")
+	}
+}
+
+func TestConfig_sni(t *testing.T) {
+	type testcase struct {
+		name      string
+		config    Config
+		hostname  string
+		expect    string
+		expectErr error
+	}
+	cases := []testcase{{
+		name:     "with configured SNI",
+		config:   Config{SNI: "www.example.org"},
+		hostname: "8.8.8.8",
+		expect:   "www.example.org",
+	}, {
+		name:     "with domain as the hostname",
+		hostname: "www.example.com",
+		expect:   "www.example.com",
+	}, {
+		name:      "with IP address as the hostname",
+		hostname:  "8.8.8.8",
+		expectErr: errMissingSNI,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.config.sni(tc.hostname)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestConfig_timeout(t *testing.T) {
+	c := Config{}
+	if c.timeout() != 3*time.Second {
+		t.Fatal("invalid default timeout")
+	}
+	c.Timeout = 500
+	if c.timeout() != 500*time.Millisecond {
+		t.Fatal("invalid configured timeout")
+	}
+}
+
+func TestConfig_versions(t *testing.T) {
+	c := Config{}
+	versions, err := c.versions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(allVersions, versions); diff != "" {
+		t.Fatal(diff)
+	}
+	c.Versions = "v2 draft-29"
+	versions, err = c.versions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"v2", "draft-29"}, versions); diff != "" {
+		t.Fatal(diff)
+	}
+	c.Versions = "v3"
+	if _, err := c.versions(); !errors.Is(err, errInvalidVersion) {
+		t.Fatal("unexpected error", err)
+	}
+}
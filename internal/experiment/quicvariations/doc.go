@@ -0,0 +1,13 @@
+// Package quicvariations implements the quicvariations experiment.
+//
+// This experiment performs QUIC handshakes with the same endpoint using several
+// combinations of QUIC version, SNI (the real SNI, a control SNI, and no SNI),
+// ALPN, and Initial packet size, to determine which feature of the handshake
+// causes censors to drop QUIC traffic. Because quic-go does not implement draft
+// versions of QUIC, for draft versions we send a padded long header packet and
+// wait for the server to reply with a version negotiation packet, which tells us
+// whether packets using a draft version can reach the server.
+//
+// The input is a quichandshake://<address>:<port> URL, the same input used
+// by the simplequicping experiment.
+package quicvariations
@@ -0,0 +1,146 @@
+package quicvariations
+
+//
+// Measurer
+//
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+const (
+	testName    = "quicvariations"
+	testVersion = "0.1.0"
+)
+
+// Measurer performs the measurement.
+type Measurer struct {
+	config Config
+}
+
+// ExperimentName implements ExperimentMeasurer.ExperimentName.
+func (m *Measurer) ExperimentName() string {
+	return testName
+}
+
+// ExperimentVersion implements ExperimentMeasurer.ExperimentVersion.
+func (m *Measurer) ExperimentVersion() string {
+	return testVersion
+}
+
+var (
+	// errNoInputProvided indicates you didn't provide any input
+	errNoInputProvided = errors.New("no input provided")
+
+	// errInputIsNotAnURL indicates that input is not an URL
+	errInputIsNotAnURL = errors.New("input is not an URL")
+
+	// errInvalidInputScheme indicates that the input scheme is invalid
+	errInvalidInputScheme = errors.New("input scheme must be quichandshake")
+
+	// errMissingPort indicates that the input URL does not contain a port
+	errMissingPort = errors.New("input URL must include a port")
+)
+
+// Run implements ExperimentMeasurer.Run.
+func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	_ = args.Callbacks
+	measurement := args.Measurement
+	sess := args.Session
+	if measurement.Input == "" {
+		return errNoInputProvided
+	}
+	parsed, err := url.Parse(string(measurement.Input))
+	if err != nil {
+		return errInputIsNotAnURL
+	}
+	if parsed.Scheme != "quichandshake" {
+		return errInvalidInputScheme
+	}
+	if parsed.Port() == "" {
+		return errMissingPort
+	}
+	sni, err := m.config.sni(parsed.Hostname())
+	if err != nil {
+		return err
+	}
+	versions, err := m.config.versions()
+	if err != nil {
+		return err
+	}
+	sizes, err := m.config.initialPacketSizes()
+	if err != nil {
+		return err
+	}
+	tk := NewTestKeys()
+	measurement.TestKeys = tk
+	tk.SNI = sni
+	tk.ControlSNI = m.config.controlSNI()
+	zeroTime := measurement.MeasurementStartTimeSaved
+	logger := sess.Logger()
+	// 1. resolve the target, which is a no-op for IP addresses
+	addr, err := m.DNSLookup(ctx, zeroTime, logger, parsed.Hostname(), tk)
+	if err != nil {
+		tk.Failure = measurexlite.NewFailure(err)
+		return nil // we want to submit this measurement
+	}
+	tk.Address = net.JoinHostPort(addr, parsed.Port())
+	// 2. run all the variations and analyze the results
+	tk.Variations = newVariations(versions, sizes, tk.SNI, tk.ControlSNI, m.config.alpns())
+	m.runVariations(ctx, zeroTime, logger, tk.Address, tk.Variations)
+	tk.Analysis = analyze(tk.Variations)
+	return nil
+}
+
+// DNSLookup resolves the domain using the system resolver and returns the first address
+func (m *Measurer) DNSLookup(ctx context.Context, zeroTime time.Time,
+	logger model.Logger, domain string, tk *TestKeys) (string, error) {
+	if net.ParseIP(domain) != nil {
+		return domain, nil
+	}
+	trace := measurexlite.NewTrace(0, zeroTime)
+	ol := logx.NewOperationLogger(logger, "DNSLookup %s", domain)
+	resolver := trace.NewStdlibResolver(logger)
+	addrs, err := resolver.LookupHost(ctx, domain)
+	ol.Stop(err)
+	tk.Queries = append(tk.Queries, trace.DNSLookupsFromRoundTrip()...)
+	if err != nil {
+		return "", err
+	}
+	return addrs[0], nil
+}
+
+// runVariations runs the variations using the configured parallelism
+func (m *Measurer) runVariations(ctx context.Context, zeroTime time.Time,
+	logger model.Logger, address string, variations []*Variation) {
+	inputs := make(chan *Variation)
+	wg := new(sync.WaitGroup)
+	for idx := 0; idx < m.config.parallelism(); idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range inputs {
+				m.runVariation(ctx, zeroTime, logger, address, v)
+			}
+		}()
+	}
+	for _, v := range variations {
+		inputs <- v
+	}
+	close(inputs)
+	wg.Wait()
+}
+
+// NewExperimentMeasurer creates a new ExperimentMeasurer.
+func NewExperimentMeasurer(config Config) *Measurer {
+	return &Measurer{config: config}
+}
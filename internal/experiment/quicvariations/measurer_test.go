@@ -0,0 +1,266 @@
+package quicvariations
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/gopacket/layers"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/quic-go/quic-go"
+)
+
+func TestMeasurerExperimentNameVersion(t *testing.T) {
+	measurer := NewExperimentMeasurer(Config{})
+	if measurer.ExperimentName() != "quicvariations" {
+		t.Fatal("unexpected ExperimentName")
+	}
+	if measurer.ExperimentVersion() != "0.1.0" {
+		t.Fatal("unexpected ExperimentVersion")
+	}
+}
+
+func runHelper(ctx context.Context, config Config, input string) (*model.Measurement, error) {
+	m := NewExperimentMeasurer(config)
+	meas := &model.Measurement{
+		Input: model.MeasurementInput(input),
+	}
+	sess := &mocks.Session{
+		MockLogger: func() model.Logger {
+			return model.DiscardLogger
+		},
+	}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+		Measurement: meas,
+		Session:     sess,
+	}
+	err := m.Run(ctx, args)
+	return meas, err
+}
+
+func TestMeasurer_input_failure(t *testing.T) {
+	type testcase struct {
+		name      string
+		config    Config
+		input     string
+		expectErr error
+	}
+	cases := []testcase{{
+		name:      "with empty input",
+		input:     "",
+		expectErr: errNoInputProvided,
+	}, {
+		name:      "with invalid URL",
+		input:     "\t",
+		expectErr: errInputIsNotAnURL,
+	}, {
+		name:      "with invalid scheme",
+		input:     "https://8.8.8.8:443/",
+		expectErr: errInvalidInputScheme,
+	}, {
+		name:      "with missing port",
+		input:     "quichandshake://8.8.8.8",
+		expectErr: errMissingPort,
+	}, {
+		name:      "with missing SNI",
+		input:     "quichandshake://8.8.8.8:443",
+		expectErr: errMissingSNI,
+	}, {
+		name:      "with invalid versions",
+		config:    Config{Versions: "antani"},
+		input:     "quichandshake://dns.google:443",
+		expectErr: errInvalidVersion,
+	}, {
+		name:      "with invalid Initial packet sizes",
+		config:    Config{InitialPacketSizes: "antani"},
+		input:     "quichandshake://dns.google:443",
+		expectErr: errInvalidInitialPacketSize,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runHelper(context.Background(), tc.config, tc.input)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
+
+// dpiDropQUICVersion is a [netem.DPIRule] dropping QUIC long header packets
+// using the given version and sent to the given server endpoint.
+type dpiDropQUICVersion struct {
+	address string
+	version uint32
+}
+
+var _ netem.DPIRule = &dpiDropQUICVersion{}
+
+// Filter implements netem.DPIRule.
+func (r *dpiDropQUICVersion) Filter(
+	direction netem.DPIDirection, packet *netem.DissectedPacket) (*netem.DPIPolicy, bool) {
+	if !packet.MatchesDestination(layers.IPProtocolUDP, r.address, 443) {
+		return nil, false
+	}
+	payload := packet.UDP.Payload
+	if len(payload) < 5 || payload[0]&0x80 == 0 || binary.BigEndian.Uint32(payload[1:5]) != r.version {
+		return nil, false
+	}
+	return &netem.DPIPolicy{Flags: netem.FrameFlagDrop}, true
+}
+
+// dpiDropLargeDatagrams is a [netem.DPIRule] dropping UDP datagrams
+// larger than the given size sent to the given server endpoint.
+type dpiDropLargeDatagrams struct {
+	address string
+	size    int
+}
+
+var _ netem.DPIRule = &dpiDropLargeDatagrams{}
+
+// Filter implements netem.DPIRule.
+func (r *dpiDropLargeDatagrams) Filter(
+	direction netem.DPIDirection, packet *netem.DissectedPacket) (*netem.DPIPolicy, bool) {
+	if !packet.MatchesDestination(layers.IPProtocolUDP, r.address, 443) {
+		return nil, false
+	}
+	if len(packet.UDP.Payload) <= r.size {
+		return nil, false
+	}
+	return &netem.DPIPolicy{Flags: netem.FrameFlagDrop}, true
+}
+
+func TestMeasurer_with_netem(t *testing.T) {
+	type testcase struct {
+		name          string
+		rule          netem.DPIRule
+		expectOutcome string
+		expectTrigger []*Trigger
+	}
+
+	cases := []testcase{{
+		name:          "without censorship",
+		rule:          nil,
+		expectOutcome: OutcomeAccessible,
+		expectTrigger: []*Trigger{},
+	}, {
+		name: "with all QUIC traffic dropped",
+		rule: &netem.DPIDropTrafficForServerEndpoint{
+			Logger:          model.DiscardLogger,
+			ServerIPAddress: netemx.AddressWwwExampleCom,
+			ServerPort:      443,
+			ServerProtocol:  layers.IPProtocolUDP,
+		},
+		expectOutcome: OutcomeEndpointBlocked,
+		expectTrigger: []*Trigger{},
+	}, {
+		name: "with QUIC v2 dropped",
+		rule: &dpiDropQUICVersion{
+			address: netemx.AddressWwwExampleCom,
+			version: uint32(quic.Version2),
+		},
+		expectOutcome: OutcomeFeatureBlocked,
+		expectTrigger: []*Trigger{{
+			Features: []*Feature{{Name: featureVersion, Value: versionV2}},
+		}},
+	}, {
+		name: "with QUIC draft-29 dropped",
+		rule: &dpiDropQUICVersion{
+			address: netemx.AddressWwwExampleCom,
+			version: draftVersions[versionDraft29],
+		},
+		expectOutcome: OutcomeFeatureBlocked,
+		expectTrigger: []*Trigger{{
+			Features: []*Feature{{Name: featureVersion, Value: versionDraft29}},
+		}},
+	}, {
+		name: "with large Initial packets dropped",
+		rule: &dpiDropLargeDatagrams{
+			address: netemx.AddressWwwExampleCom,
+			size:    1300,
+		},
+		expectOutcome: OutcomeFeatureBlocked,
+		expectTrigger: []*Trigger{{
+			Features: []*Feature{{Name: featureInitialPacketSize, Value: "1452"}},
+		}},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := netemx.MustNewScenario(netemx.InternetScenario)
+			defer env.Close()
+			if tc.rule != nil {
+				env.DPIEngine().AddRule(tc.rule)
+			}
+
+			env.Do(func() {
+				config := Config{
+					ALPN:        "h3",
+					Parallelism: 14,
+					Timeout:     1000,
+				}
+				meas, err := runHelper(context.Background(), config, "quichandshake://www.example.com:443")
+				if err != nil {
+					t.Fatal(err)
+				}
+				tk := meas.TestKeys.(*TestKeys)
+				if tk.Failure != nil {
+					t.Fatal("unexpected failure", *tk.Failure)
+				}
+				if tk.Address != "93.184.216.34:443" {
+					t.Fatal("unexpected address", tk.Address)
+				}
+				if len(tk.Queries) != 1 {
+					t.Fatal("expected a DNS lookup")
+				}
+				if len(tk.Variations) != 14 {
+					t.Fatal("unexpected number of variations", len(tk.Variations))
+				}
+				for _, v := range tk.Variations {
+					if len(v.NetworkEvents) <= 0 {
+						t.Fatal("expected network events")
+					}
+					if tc.rule == nil && v.Failure != nil {
+						t.Fatal("unexpected failure", v.ID, *v.Failure)
+					}
+					if tc.rule == nil && v.Version == versionDraft29 {
+						if diff := cmp.Diff([]string{"v1", "v2"}, v.SupportedVersions); diff != "" {
+							t.Fatal(diff)
+						}
+					}
+				}
+				if tk.Analysis.Outcome != tc.expectOutcome {
+					t.Fatal("unexpected outcome", tk.Analysis.Outcome)
+				}
+				if diff := cmp.Diff(tc.expectTrigger, tk.Analysis.Triggers); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+		})
+	}
+
+	t.Run("with DNS lookup failure", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+
+		env.Do(func() {
+			meas, err := runHelper(context.Background(), Config{}, "quichandshake://www.nonexistent.com:443")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tk := meas.TestKeys.(*TestKeys)
+			if tk.Failure == nil || *tk.Failure != netxlite.FailureDNSNXDOMAINError {
+				t.Fatal("unexpected failure", tk.Failure)
+			}
+			if len(tk.Variations) != 0 {
+				t.Fatal("expected no variations")
+			}
+		})
+	})
+}
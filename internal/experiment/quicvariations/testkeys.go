@@ -0,0 +1,69 @@
+package quicvariations
+
+import "github.com/ooni/probe-cli/v3/internal/model"
+
+// TestKeys contains the experiment results
+type TestKeys struct {
+	// Address is the endpoint we measured.
+	Address string `json:"address"`
+
+	// Analysis contains the results of analyzing the variations.
+	Analysis *Analysis `json:"analysis"`
+
+	// ControlSNI is the SNI we do not expect to be blocked.
+	ControlSNI string `json:"control_sni"`
+
+	// Failure is the failure that prevented us from running the variations.
+	Failure *string `json:"failure"`
+
+	// Queries contains the DNS lookup, if the input host is a domain.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// SNI is the SNI we're testing.
+	SNI string `json:"sni"`
+
+	// Variations contains the result of each variation.
+	Variations []*Variation `json:"variations"`
+}
+
+// NewTestKeys creates new quicvariations TestKeys
+func NewTestKeys() *TestKeys {
+	return &TestKeys{
+		Queries:    []*model.ArchivalDNSLookupResult{},
+		Variations: []*Variation{},
+	}
+}
+
+// Variation contains the result of a single variation
+type Variation struct {
+	// ALPN is the ALPN we used or empty for draft versions.
+	ALPN string `json:"alpn"`
+
+	// Failure is the failure of the handshake or of the version negotiation.
+	Failure *string `json:"failure"`
+
+	// ID is the variation ID, which is also the trace index.
+	ID int64 `json:"id"`
+
+	// InitialPacketSize is the size of the Initial packets we sent.
+	InitialPacketSize int64 `json:"initial_packet_size"`
+
+	// NetworkEvents contains the network events.
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+
+	// QUICHandshake is the handshake result or nil for draft versions.
+	QUICHandshake *model.ArchivalTLSOrQUICHandshakeResult `json:"quic_handshake"`
+
+	// ServerName is the SNI we sent (the empty string means no SNI).
+	ServerName string `json:"server_name"`
+
+	// SNI is the kind of SNI we used (e.g., "control") or empty for draft versions.
+	SNI string `json:"sni"`
+
+	// SupportedVersions contains the versions in the version negotiation packet
+	// sent by the server when we're using a draft version.
+	SupportedVersions []string `json:"supported_versions"`
+
+	// Version is the QUIC version we used (e.g., "v1").
+	Version string `json:"version"`
+}
@@ -0,0 +1,123 @@
+package quicvariations
+
+//
+// Variations of the QUIC handshake
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"slices"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/quic-go/quic-go"
+)
+
+const (
+	versionV1      = "v1"
+	versionV2      = "v2"
+	versionDraft29 = "draft-29"
+)
+
+// allVersions contains all the QUIC versions we know about.
+var allVersions = []string{versionV1, versionV2, versionDraft29}
+
+func isValidVersion(version string) bool {
+	return slices.Contains(allVersions, version)
+}
+
+// handshakeVersions maps the versions implemented by quic-go to their value.
+var handshakeVersions = map[string]quic.Version{
+	versionV1: quic.Version1,
+	versionV2: quic.Version2,
+}
+
+// draftVersions maps the draft versions to their value.
+var draftVersions = map[string]uint32{
+	versionDraft29: 0xff00001d,
+}
+
+const (
+	sniReal    = "real"
+	sniControl = "control"
+	sniEmpty   = "empty"
+)
+
+// newVariations creates all the combinations of the given versions, sizes, SNIs, and ALPNs. For
+// draft versions, we only vary the Initial packet size because we do not send a ClientHello.
+func newVariations(versions []string, sizes []int64, sni, controlSNI string, alpns []string) []*Variation {
+	serverNames := []struct{ kind, name string }{
+		{sniReal, sni},
+		{sniControl, controlSNI},
+		{sniEmpty, ""},
+	}
+	out := []*Variation{}
+	add := func(v *Variation) {
+		v.ID = int64(len(out) + 1) // trace index zero is for the DNS lookup
+		v.NetworkEvents = []*model.ArchivalNetworkEvent{}
+		v.SupportedVersions = []string{}
+		out = append(out, v)
+	}
+	for _, version := range versions {
+		for _, size := range sizes {
+			if _, found := draftVersions[version]; found {
+				add(&Variation{InitialPacketSize: size, Version: version})
+				continue
+			}
+			for _, entry := range serverNames {
+				for _, alpn := range alpns {
+					add(&Variation{
+						ALPN:              alpn,
+						InitialPacketSize: size,
+						ServerName:        entry.name,
+						SNI:               entry.kind,
+						Version:           version,
+					})
+				}
+			}
+		}
+	}
+	return out
+}
+
+// runVariation runs the given variation and saves the results into it.
+func (m *Measurer) runVariation(ctx context.Context, zeroTime time.Time,
+	logger model.Logger, address string, v *Variation) {
+	ctx, cancel := context.WithTimeout(ctx, m.config.timeout())
+	defer cancel()
+	if _, found := draftVersions[v.Version]; found {
+		m.versionNegotiation(ctx, zeroTime, logger, address, v)
+		return
+	}
+	m.quicHandshake(ctx, zeroTime, logger, address, v)
+}
+
+// quicHandshake performs a QUIC handshake using the given variation.
+func (m *Measurer) quicHandshake(ctx context.Context, zeroTime time.Time,
+	logger model.Logger, address string, v *Variation) {
+	trace := measurexlite.NewTrace(v.ID, zeroTime)
+	ol := logx.NewOperationLogger(logger, "QUICVariation #%d %s version=%s sni=%q alpn=%s size=%d",
+		v.ID, address, v.Version, v.ServerName, v.ALPN, v.InitialPacketSize)
+	dialer := trace.NewQUICDialerWithoutResolver(trace.NewUDPListener(), logger)
+	// We're interested in whether the handshake completes rather than in whether
+	// the server is authentic, and we could not verify the certificate when sending
+	// the control SNI or no SNI anyway. Note that we still save the certificates.
+	tlsConfig := &tls.Config{ // #nosec G402 - we're measuring whether the handshake completes
+		InsecureSkipVerify: true,
+		NextProtos:         []string{v.ALPN},
+		ServerName:         v.ServerName,
+	}
+	quicConfig := &quic.Config{
+		InitialPacketSize: uint16(v.InitialPacketSize),
+		Versions:          []quic.Version{handshakeVersions[v.Version]},
+	}
+	conn, err := dialer.DialContext(ctx, address, tlsConfig, quicConfig)
+	defer measurexlite.MaybeCloseQUICConn(conn)
+	ol.Stop(err)
+	v.Failure = measurexlite.NewFailure(err)
+	v.QUICHandshake = trace.FirstQUICHandshakeOrNil()
+	v.NetworkEvents = append(v.NetworkEvents, trace.NetworkEvents()...)
+}
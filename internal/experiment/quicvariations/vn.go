@@ -0,0 +1,150 @@
+package quicvariations
+
+//
+// Version negotiation for draft versions
+//
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// versionNegotiation sends a long header packet using a draft version and waits for
+// the server to send back a version negotiation packet. Servers not supporting the
+// draft version send such a packet when the datagram is large enough (see RFC 9000
+// Sect. 6.1), so a timeout indicates that the packet did not reach the server.
+func (m *Measurer) versionNegotiation(ctx context.Context, zeroTime time.Time,
+	logger model.Logger, address string, v *Variation) {
+	trace := measurexlite.NewTrace(v.ID, zeroTime)
+	ol := logx.NewOperationLogger(logger, "QUICVariation #%d %s version=%s size=%d",
+		v.ID, address, v.Version, v.InitialPacketSize)
+	versions, err := m.doVersionNegotiation(ctx, trace, address, v)
+	ol.Stop(err)
+	v.Failure = measurexlite.NewFailure(err)
+	v.NetworkEvents = append(v.NetworkEvents, trace.NetworkEvents()...)
+	v.SupportedVersions = append(v.SupportedVersions, versions...)
+}
+
+func (m *Measurer) doVersionNegotiation(ctx context.Context,
+	trace *measurexlite.Trace, address string, v *Variation) ([]string, error) {
+	udpAddr, err := netxlite.ParseUDPAddr(address)
+	if err != nil {
+		return nil, netxlite.NewTopLevelGenericErrWrapper(err)
+	}
+	pconn, err := trace.NewUDPListener().Listen(&net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		return nil, netxlite.NewTopLevelGenericErrWrapper(err)
+	}
+	defer pconn.Close()
+	pconn = trace.MaybeWrapUDPLikeConn(pconn)
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = pconn.SetDeadline(deadline)
+	}
+	dcid, scid := newConnectionID(), newConnectionID()
+	packet := newLongHeaderPacket(draftVersions[v.Version], dcid, scid, int(v.InitialPacketSize))
+	if _, err := pconn.WriteTo(packet, udpAddr); err != nil {
+		return nil, netxlite.NewTopLevelGenericErrWrapper(err)
+	}
+	buffer := make([]byte, 1<<11)
+	for {
+		count, _, err := pconn.ReadFrom(buffer)
+		if err != nil {
+			return nil, netxlite.NewTopLevelGenericErrWrapper(err)
+		}
+		versions, err := parseVersionNegotiation(buffer[:count], scid)
+		if err != nil {
+			continue // not the packet we're waiting for
+		}
+		return versions, nil
+	}
+}
+
+// connectionIDLength is the length of the connection IDs we use.
+const connectionIDLength = 8
+
+// newConnectionID returns a new random connection ID.
+func newConnectionID() []byte {
+	cid := make([]byte, connectionIDLength)
+	runtimex.Try1(rand.Read(cid))
+	return cid
+}
+
+// newLongHeaderPacket creates an Initial long header packet for the given version padded
+// to the given size. The payload is random, which is fine because servers send version
+// negotiation packets without attempting to decrypt packets with unsupported versions.
+func newLongHeaderPacket(version uint32, dcid, scid []byte, size int) []byte {
+	packet := []byte{0xc3} // long header, fixed bit, Initial, 4-byte packet number
+	packet = binary.BigEndian.AppendUint32(packet, version)
+	packet = append(packet, byte(len(dcid)))
+	packet = append(packet, dcid...)
+	packet = append(packet, byte(len(scid)))
+	packet = append(packet, scid...)
+	packet = append(packet, 0) // token length
+	length := max(0, size-len(packet)-2)
+	packet = binary.BigEndian.AppendUint16(packet, 0x4000|uint16(length)) // two-byte varint
+	payload := make([]byte, length)
+	runtimex.Try1(rand.Read(payload))
+	return append(packet, payload...)
+}
+
+// errNotVersionNegotiation indicates that a packet is not the version negotiation
+// packet we're expecting (see RFC 9000 Sect. 17.2.1).
+var errNotVersionNegotiation = errors.New("quicvariations: not a version negotiation packet")
+
+// parseVersionNegotiation parses a version negotiation packet sent in response to
+// a packet using the given source connection ID and returns the supported versions.
+func parseVersionNegotiation(packet []byte, scid []byte) ([]string, error) {
+	if len(packet) < 7 || packet[0]&0x80 == 0 || binary.BigEndian.Uint32(packet[1:5]) != 0 {
+		return nil, errNotVersionNegotiation
+	}
+	packet = packet[5:]
+	dcidLength := int(packet[0])
+	if len(packet) < 1+dcidLength+1 || !bytes.Equal(packet[1:1+dcidLength], scid) {
+		return nil, errNotVersionNegotiation
+	}
+	packet = packet[1+dcidLength:]
+	scidLength := int(packet[0])
+	if len(packet) < 1+scidLength {
+		return nil, errNotVersionNegotiation
+	}
+	packet = packet[1+scidLength:]
+	if len(packet) <= 0 || len(packet)%4 != 0 {
+		return nil, errNotVersionNegotiation
+	}
+	versions := []string{}
+	for ; len(packet) > 0; packet = packet[4:] {
+		version := binary.BigEndian.Uint32(packet)
+		if version&0x0f0f0f0f == 0x0a0a0a0a {
+			continue // skip the versions reserved for greasing (see RFC 9000 Sect. 15)
+		}
+		versions = append(versions, versionName(version))
+	}
+	return versions, nil
+}
+
+// versionName returns the name of the given version or its hex representation.
+func versionName(version uint32) string {
+	for name, value := range handshakeVersions {
+		if uint32(value) == version {
+			return name
+		}
+	}
+	for name, value := range draftVersions {
+		if value == version {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%08x", version)
+}
@@ -0,0 +1,91 @@
+package quicvariations
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newVersionNegotiationPacket creates a version negotiation packet.
+func newVersionNegotiationPacket(dcid, scid []byte, versions ...uint32) []byte {
+	packet := []byte{0x80}
+	packet = binary.BigEndian.AppendUint32(packet, 0)
+	packet = append(packet, byte(len(dcid)))
+	packet = append(packet, dcid...)
+	packet = append(packet, byte(len(scid)))
+	packet = append(packet, scid...)
+	for _, version := range versions {
+		packet = binary.BigEndian.AppendUint32(packet, version)
+	}
+	return packet
+}
+
+func TestNewLongHeaderPacket(t *testing.T) {
+	dcid, scid := newConnectionID(), newConnectionID()
+	for _, size := range []int{1200, 1452} {
+		packet := newLongHeaderPacket(0xff00001d, dcid, scid, size)
+		if len(packet) != size {
+			t.Fatal("unexpected packet size", len(packet))
+		}
+		if packet[0]&0xc0 != 0xc0 {
+			t.Fatal("not a long header packet")
+		}
+		if binary.BigEndian.Uint32(packet[1:5]) != 0xff00001d {
+			t.Fatal("unexpected version")
+		}
+	}
+}
+
+func TestParseVersionNegotiation(t *testing.T) {
+	scid := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	dcid := []byte{8, 7, 6, 5, 4, 3, 2, 1}
+
+	t.Run("with a valid packet", func(t *testing.T) {
+		packet := newVersionNegotiationPacket(scid, dcid, 0x00000001, 0x6b3343cf, 0xfa8a4a1a, 0x51303530)
+		versions, err := parseVersionNegotiation(packet, scid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"v1", "v2", "0x51303530"}, versions); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	invalid := map[string][]byte{
+		"too short":          {0x80, 0, 0},
+		"short header":       newVersionNegotiationPacket(scid, dcid, 1)[1:],
+		"nonzero version":    append([]byte{0x80, 0, 0, 0, 1}, newVersionNegotiationPacket(scid, dcid, 1)[5:]...),
+		"another dcid":       newVersionNegotiationPacket(dcid, dcid, 1),
+		"truncated dcid":     newVersionNegotiationPacket(scid, dcid)[:9],
+		"truncated scid":     newVersionNegotiationPacket(scid, dcid)[:17],
+		"no versions":        newVersionNegotiationPacket(scid, dcid),
+		"truncated versions": newVersionNegotiationPacket(scid, dcid, 1)[:25],
+	}
+	for name, packet := range invalid {
+		t.Run("with "+name, func(t *testing.T) {
+			versions, err := parseVersionNegotiation(packet, scid)
+			if !errors.Is(err, errNotVersionNegotiation) {
+				t.Fatal("unexpected error", err)
+			}
+			if len(versions) != 0 {
+				t.Fatal("expected no versions")
+			}
+		})
+	}
+}
+
+func TestVersionName(t *testing.T) {
+	expect := map[uint32]string{
+		0x00000001: "v1",
+		0x6b3343cf: "v2",
+		0xff00001d: "draft-29",
+		0x51303530: "0x51303530",
+	}
+	for version, name := range expect {
+		if got := versionName(version); got != name {
+			t.Fatal("expected", name, "got", got)
+		}
+	}
+}
@@ -12,7 +12,6 @@ import (
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/internal/experiment/riseupvpn"
 	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
@@ -205,7 +204,7 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "riseupvpn" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.3.0" {
+	if measurer.ExperimentVersion() != "0.4.0" {
 		t.Fatal("unexpected version")
 	}
 }
@@ -518,11 +517,11 @@ func generateMockGetter(requestResponse map[string]string, responseStatus map[st
 			responseStatus = 0
 		}
 
-		tcpConnect := tracex.TCPConnectEntry{
+		tcpConnect := model.ArchivalTCPConnectResult{
 			// use some dummy IP/Port combination for URLs, we don't do DNS resolution
 			IP:   "123.456.234.123",
 			Port: 443,
-			Status: tracex.TCPConnectStatus{
+			Status: model.ArchivalTCPConnectStatus{
 				Success: isSuccessStatus,
 				Blocked: &isBlocked,
 				Failure: failure,
@@ -542,14 +541,14 @@ func generateMockGetter(requestResponse map[string]string, responseStatus map[st
 			FailedOperation:    failedOperation,
 			HTTPResponseStatus: responseStatus,
 			HTTPResponseBody:   responseBody,
-			Requests: []tracex.RequestEntry{{
+			Requests: []model.ArchivalHTTPRequestResult{{
 				Failure: failure,
-				Request: tracex.HTTPRequest{
+				Request: model.ArchivalHTTPRequest{
 					URL:             url,
 					Body:            model.ArchivalScrubbedMaybeBinaryString(""),
 					BodyIsTruncated: false,
 				},
-				Response: tracex.HTTPResponse{
+				Response: model.ArchivalHTTPResponse{
 					Body: model.ArchivalScrubbedMaybeBinaryString(
 						responseBody,
 					),
@@ -557,7 +556,7 @@ func generateMockGetter(requestResponse map[string]string, responseStatus map[st
 					Code:            responseStatus,
 				}},
 			},
-			TCPConnect: []tracex.TCPConnectEntry{tcpConnect},
+			TCPConnect: []model.ArchivalTCPConnectResult{tcpConnect},
 		}
 		return tk, nil
 	}
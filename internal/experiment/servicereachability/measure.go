@@ -0,0 +1,410 @@
+package servicereachability
+
+//
+// Code to run the checks of a service definition
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/servicedefs"
+	"github.com/quic-go/quic-go"
+)
+
+// These are the failures of checks whose network operations succeeded but whose
+// results do not match what the service definition expects.
+const (
+	// FailureDNSUnexpectedASN indicates that the DNS lookup returned an IP
+	// address not belonging to any of the expected ASNs.
+	FailureDNSUnexpectedASN = "dns_unexpected_asn"
+
+	// FailureHTTPUnexpectedStatusCode indicates that the HTTP round
+	// trip returned a status code we did not expect.
+	FailureHTTPUnexpectedStatusCode = "http_unexpected_status_code"
+)
+
+var (
+	errDNSUnexpectedASN = &netxlite.ErrWrapper{
+		Failure:    FailureDNSUnexpectedASN,
+		Operation:  netxlite.ResolveOperation,
+		WrappedErr: errors.New("dns lookup returned addresses with unexpected ASNs"),
+	}
+
+	errHTTPUnexpectedStatusCode = &netxlite.ErrWrapper{
+		Failure:    FailureHTTPUnexpectedStatusCode,
+		Operation:  netxlite.HTTPRoundTripOperation,
+		WrappedErr: errors.New("http round trip returned an unexpected status code"),
+	}
+)
+
+// parallelism is the number of checks we run in parallel.
+const parallelism = 4
+
+// Measure runs the checks of the given definition, in parallel, and saves the results
+// inside the given test keys. We use the position of each check in the definition,
+// starting from one, as the transaction ID of the check.
+func Measure(ctx context.Context, args *model.ExperimentArgs, def *servicedefs.Definition, tk *TestKeys) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	registerExtensions(args.Measurement)
+	tk.Service = def.Name
+	tk.ServiceVersion = def.Version
+
+	// create a cert pool for each certificates bundle
+	pools := map[string]*x509.CertPool{}
+	for bundle := range def.Certificates {
+		pools[bundle] = def.CertPool(bundle)
+	}
+
+	// flatten the checks keeping the definition order
+	type job struct {
+		component string
+		check     *servicedefs.Check
+	}
+	var jobs []*job
+	for _, comp := range def.Components {
+		for _, check := range comp.Checks {
+			jobs = append(jobs, &job{component: comp.Name, check: check})
+		}
+	}
+
+	runner := &checkRunner{
+		logger:   args.Session.Logger(),
+		pools:    pools,
+		tk:       tk,
+		zeroTime: args.Measurement.MeasurementStartTimeSaved,
+	}
+	results := make([]*CheckResult, len(jobs))
+	sema := make(chan bool, parallelism)
+	wg := &sync.WaitGroup{}
+	completed := &atomic.Int64{}
+	for idx, j := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sema <- true
+			defer func() { <-sema }()
+			results[idx] = runner.run(ctx, int64(idx)+1, j.component, j.check)
+			progress := float64(completed.Add(1)) / float64(len(jobs))
+			args.Callbacks.OnProgress(progress, fmt.Sprintf("%s: %s %s", def.Name, j.check.Type, j.check.Target()))
+		}()
+	}
+	wg.Wait()
+
+	tk.Checks = results
+	tk.Components = AnalyzeComponents(def, results)
+}
+
+// registerExtensions registers the data format extensions we use.
+func registerExtensions(m *model.Measurement) {
+	model.ArchivalExtDNS.AddTo(m)
+	model.ArchivalExtNetevents.AddTo(m)
+	model.ArchivalExtHTTP.AddTo(m)
+	model.ArchivalExtTCPConnect.AddTo(m)
+	model.ArchivalExtTLSHandshake.AddTo(m)
+}
+
+// checkRunner runs checks.
+type checkRunner struct {
+	logger   model.Logger
+	pools    map[string]*x509.CertPool
+	tk       *TestKeys
+	zeroTime time.Time
+}
+
+// run runs the given check and returns its result.
+func (r *checkRunner) run(ctx context.Context, index int64, component string, check *servicedefs.Check) *CheckResult {
+	ol := logx.NewOperationLogger(r.logger, "[#%d] %s: %s %s", index, component, check.Type, check.Target())
+	trace := measurexlite.NewTrace(index, r.zeroTime)
+	defer func() {
+		r.tk.appendNetworkEvents(trace.NetworkEvents()...)
+	}()
+
+	var (
+		operation string
+		err       error
+	)
+	switch check.Type {
+	case servicedefs.CheckTypeDNS:
+		_, operation, err = r.resolve(ctx, trace, check.Domain, check.ExpectedASNs)
+	case servicedefs.CheckTypeTCP:
+		operation, err = r.forEachAddress(ctx, trace, check.Endpoint, check.ExpectedASNs, r.tcpConnect)
+	case servicedefs.CheckTypeTLS:
+		operation, err = r.forEachAddress(ctx, trace, check.Endpoint, check.ExpectedASNs,
+			func(ctx context.Context, trace *measurexlite.Trace, address string) (string, error) {
+				return r.tlsHandshake(ctx, trace, address, check)
+			})
+	case servicedefs.CheckTypeQUIC:
+		operation, err = r.forEachAddress(ctx, trace, check.Endpoint, check.ExpectedASNs,
+			func(ctx context.Context, trace *measurexlite.Trace, address string) (string, error) {
+				return r.quicHandshake(ctx, trace, address, check)
+			})
+	case servicedefs.CheckTypeHTTP:
+		operation, err = r.httpRoundTrip(ctx, trace, check)
+	}
+	ol.Stop(err)
+
+	result := &CheckResult{
+		Component:       component,
+		FailedOperation: nil,
+		Failure:         measurexlite.NewFailure(err),
+		Target:          check.Target(),
+		TransactionID:   index,
+		Type:            check.Type,
+	}
+	if err != nil {
+		result.FailedOperation = &operation
+	}
+	return result
+}
+
+// resolve resolves the given domain, unless it is an IP address, and checks whether
+// the resolved addresses belong to the expected ASNs. On failure, it returns the
+// failed operation along with the error.
+func (r *checkRunner) resolve(ctx context.Context,
+	trace *measurexlite.Trace, domain string, expectedASNs []int64) ([]string, string, error) {
+	if net.ParseIP(domain) != nil {
+		return []string{domain}, "", nil
+	}
+	const resolveTimeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+	reso := trace.NewStdlibResolver(r.logger)
+	addrs, err := reso.LookupHost(ctx, domain)
+	queries := trace.DNSLookupsFromRoundTrip()
+	r.tk.appendQueries(queries...)
+	if err != nil {
+		return nil, netxlite.ResolveOperation, err
+	}
+	if len(expectedASNs) > 0 {
+		for _, query := range queries {
+			for _, answer := range query.Answers {
+				if answer.IPv4 == "" && answer.IPv6 == "" {
+					continue
+				}
+				if !slices.Contains(expectedASNs, answer.ASN) {
+					return nil, netxlite.ResolveOperation, errDNSUnexpectedASN
+				}
+			}
+		}
+	}
+	return addrs, "", nil
+}
+
+// forEachAddress resolves the host of the given endpoint and calls the given
+// function for each resolved address until it succeeds.
+func (r *checkRunner) forEachAddress(ctx context.Context, trace *measurexlite.Trace,
+	endpoint string, expectedASNs []int64,
+	fx func(ctx context.Context, trace *measurexlite.Trace, address string) (string, error)) (string, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return netxlite.TopLevelOperation, err // should not happen for valid definitions
+	}
+	addrs, operation, err := r.resolve(ctx, trace, host, expectedASNs)
+	if err != nil {
+		return operation, err
+	}
+	for _, addr := range addrs {
+		operation, err = fx(ctx, trace, net.JoinHostPort(addr, port))
+		if err == nil {
+			return "", nil
+		}
+	}
+	return operation, err
+}
+
+// tcpConnect connects to the given address.
+func (r *checkRunner) tcpConnect(ctx context.Context, trace *measurexlite.Trace, address string) (string, error) {
+	conn, operation, err := r.tcpDial(ctx, trace, address)
+	if err != nil {
+		return operation, err
+	}
+	conn.Close()
+	return "", nil
+}
+
+// tcpDial establishes a TCP connection with the given address.
+func (r *checkRunner) tcpDial(ctx context.Context, trace *measurexlite.Trace, address string) (net.Conn, string, error) {
+	const tcpTimeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, tcpTimeout)
+	defer cancel()
+	dialer := trace.NewDialerWithoutResolver(r.logger)
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	r.tk.appendTCPConnect(trace.TCPConnects()...)
+	if err != nil {
+		return nil, netxlite.ConnectOperation, err
+	}
+	return conn, "", nil
+}
+
+// tlsHandshake connects to the given address and performs a TLS handshake.
+func (r *checkRunner) tlsHandshake(ctx context.Context,
+	trace *measurexlite.Trace, address string, check *servicedefs.Check) (string, error) {
+	conn, operation, err := r.tlsDial(ctx, trace, address, r.tlsConfig(check, check.Endpoint, check.ALPN))
+	if err != nil {
+		return operation, err
+	}
+	conn.Close()
+	return "", nil
+}
+
+// tlsDial establishes a TLS connection with the given address.
+func (r *checkRunner) tlsDial(ctx context.Context,
+	trace *measurexlite.Trace, address string, config *tls.Config) (model.TLSConn, string, error) {
+	tcpConn, operation, err := r.tcpDial(ctx, trace, address)
+	if err != nil {
+		return nil, operation, err
+	}
+	const tlsTimeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, tlsTimeout)
+	defer cancel()
+	handshaker := trace.NewTLSHandshakerStdlib(r.logger)
+	tlsConn, err := handshaker.Handshake(ctx, tcpConn, config)
+	r.tk.appendTLSHandshakes(trace.TLSHandshakes()...)
+	if err != nil {
+		tcpConn.Close()
+		return nil, netxlite.TLSHandshakeOperation, err
+	}
+	return tlsConn, "", nil
+}
+
+// quicHandshake performs a QUIC handshake with the given address.
+func (r *checkRunner) quicHandshake(ctx context.Context,
+	trace *measurexlite.Trace, address string, check *servicedefs.Check) (string, error) {
+	const quicTimeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, quicTimeout)
+	defer cancel()
+	netx := &netxlite.Netx{}
+	dialer := trace.NewQUICDialerWithoutResolver(netx.NewUDPListener(), r.logger)
+	alpn := check.ALPN
+	if len(alpn) <= 0 {
+		alpn = []string{"h3"}
+	}
+	conn, err := dialer.DialContext(ctx, address, r.tlsConfig(check, check.Endpoint, alpn), &quic.Config{})
+	r.tk.appendQUICHandshakes(trace.QUICHandshakes()...)
+	if err != nil {
+		return netxlite.QUICHandshakeOperation, err
+	}
+	measurexlite.MaybeCloseQUICConn(conn)
+	return "", nil
+}
+
+// tlsConfig returns the TLS config for the given check and endpoint.
+func (r *checkRunner) tlsConfig(check *servicedefs.Check, endpoint string, alpn []string) *tls.Config {
+	sni := check.SNI
+	if sni == "" {
+		host, _, _ := net.SplitHostPort(endpoint)
+		if net.ParseIP(host) == nil {
+			sni = host
+		}
+	}
+	// Note that a nil RootCAs causes netxlite to use the cached default Mozilla
+	// cert pool. See https://github.com/ooni/probe/issues/2413.
+	return &tls.Config{ // #nosec G402 - we need to use a large TLS versions range for measuring
+		NextProtos: alpn,
+		RootCAs:    r.pools[check.RootCAs],
+		ServerName: sni,
+	}
+}
+
+// httpRoundTrip performs the HTTP round trip of the given check.
+func (r *checkRunner) httpRoundTrip(ctx context.Context, trace *measurexlite.Trace, check *servicedefs.Check) (string, error) {
+	URL, err := url.Parse(check.URL)
+	if err != nil {
+		return netxlite.TopLevelOperation, err // should not happen for valid definitions
+	}
+	port := URL.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[URL.Scheme]
+	}
+	endpoint := net.JoinHostPort(URL.Hostname(), port)
+	return r.forEachAddress(ctx, trace, endpoint, check.ExpectedASNs,
+		func(ctx context.Context, trace *measurexlite.Trace, address string) (string, error) {
+			var (
+				alpn string
+				txp  model.HTTPTransport
+			)
+			switch URL.Scheme {
+			case "https":
+				config := r.tlsConfig(check, endpoint, []string{"h2", "http/1.1"})
+				tlsConn, operation, err := r.tlsDial(ctx, trace, address, config)
+				if err != nil {
+					return operation, err
+				}
+				defer tlsConn.Close()
+				alpn = netxlite.MaybeTLSConnectionState(tlsConn).NegotiatedProtocol
+				txp = netxlite.NewHTTPTransportWithOptions(
+					r.logger, netxlite.NewNullDialer(), netxlite.NewSingleUseTLSDialer(tlsConn))
+			default:
+				tcpConn, operation, err := r.tcpDial(ctx, trace, address)
+				if err != nil {
+					return operation, err
+				}
+				defer tcpConn.Close()
+				txp = netxlite.NewHTTPTransportWithOptions(
+					r.logger, netxlite.NewSingleUseDialer(tcpConn), netxlite.NewNullTLSDialer())
+			}
+			defer txp.CloseIdleConnections()
+			return r.httpTransaction(ctx, trace, address, alpn, txp, URL, check)
+		})
+}
+
+// httpTransaction sends the HTTP request and reads the response using the given transport.
+func (r *checkRunner) httpTransaction(ctx context.Context, trace *measurexlite.Trace,
+	address, alpn string, txp model.HTTPTransport, URL *url.URL, check *servicedefs.Check) (string, error) {
+	const httpTimeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	method := check.Method
+	if method == "" {
+		method = "GET"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, URL.String(), nil)
+	if err != nil {
+		return netxlite.TopLevelOperation, err // should not happen for valid definitions
+	}
+	req.Header.Set("Accept", model.HTTPHeaderAccept)
+	req.Header.Set("Accept-Language", model.HTTPHeaderAcceptLanguage)
+	req.Header.Set("User-Agent", model.HTTPHeaderUserAgent)
+
+	const maxbody = 1 << 19
+	started := trace.TimeSince(trace.ZeroTime())
+	r.tk.appendNetworkEvents(measurexlite.NewArchivalNetworkEvent(
+		trace.Index(), started, "http_transaction_start", "tcp", address, 0, nil, started))
+	resp, err := txp.RoundTrip(req)
+	var body []byte
+	if err == nil {
+		defer resp.Body.Close()
+		body, err = netxlite.StreamAllContext(ctx, io.LimitReader(resp.Body, maxbody))
+	}
+	finished := trace.TimeSince(trace.ZeroTime())
+	r.tk.appendNetworkEvents(measurexlite.NewArchivalNetworkEvent(
+		trace.Index(), finished, "http_transaction_done", "tcp", address, 0, nil, finished))
+	r.tk.appendRequests(measurexlite.NewArchivalHTTPRequestResult(
+		trace.Index(), started, "tcp", address, alpn, txp.Network(),
+		req, resp, maxbody, body, err, finished))
+
+	if err != nil {
+		return netxlite.HTTPRoundTripOperation, err
+	}
+	if len(check.ExpectedStatusCodes) > 0 && !slices.Contains(check.ExpectedStatusCodes, int64(resp.StatusCode)) {
+		return netxlite.HTTPRoundTripOperation, errHTTPUnexpectedStatusCode
+	}
+	return "", nil
+}
@@ -0,0 +1,101 @@
+// Package servicereachability contains the service_reachability experiment.
+//
+// This experiment measures the reachability of a service using a versioned
+// definition (see the internal/servicedefs package) listing the DNS names, the
+// TCP, TLS and QUIC endpoints and the HTTP URLs used by each of the service
+// components. We run all the checks using measurexlite and we emit a
+// verdict telling whether and at which step each component is blocked.
+//
+// The input is the name of the service to measure (e.g., "signal").
+package servicereachability
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/servicedefs"
+	"github.com/ooni/probe-cli/v3/internal/targetloading"
+)
+
+const (
+	testName    = "service_reachability"
+	testVersion = "0.1.0"
+)
+
+// Config contains the experiment config.
+type Config struct{}
+
+// Measurer performs the measurement.
+type Measurer struct {
+	// Config contains the experiment settings.
+	Config Config
+}
+
+// NewExperimentMeasurer creates a new [*Measurer].
+func NewExperimentMeasurer(config Config) model.ExperimentMeasurer {
+	return &Measurer{Config: config}
+}
+
+// ExperimentName implements model.ExperimentMeasurer.
+func (m *Measurer) ExperimentName() string {
+	return testName
+}
+
+// ExperimentVersion implements model.ExperimentMeasurer.
+func (m *Measurer) ExperimentVersion() string {
+	return testVersion
+}
+
+// ErrInputRequired indicates that no service name was provided.
+var ErrInputRequired = targetloading.ErrInputRequired
+
+// Run implements model.ExperimentMeasurer.
+func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	name := string(args.Measurement.Input)
+	if name == "" {
+		return ErrInputRequired
+	}
+	def, err := LoadDefinition(args.Session, name)
+	if err != nil {
+		return err
+	}
+	tk := NewTestKeys()
+	args.Measurement.TestKeys = tk
+	Measure(ctx, args, def, tk)
+	return nil
+}
+
+// LoadDefinition returns the definition of the given service. We use the definition
+// cached by the check-in API if it is more recent than the embedded one. Otherwise, we
+// use the embedded definition.
+func LoadDefinition(sess model.ExperimentSession, name string) (*servicedefs.Definition, error) {
+	return servicedefs.Load(sess.KeyValueStore(), name)
+}
+
+var _ model.MeasurementSummaryKeysProvider = &TestKeys{}
+
+// SummaryKeys contains summary keys for this experiment.
+type SummaryKeys struct {
+	BlockedComponents []string `json:"blocked_components"`
+	Service           string   `json:"service"`
+	IsAnomaly         bool     `json:"-"`
+}
+
+// MeasurementSummaryKeys implements model.MeasurementSummaryKeysProvider.
+func (tk *TestKeys) MeasurementSummaryKeys() model.MeasurementSummaryKeys {
+	sk := &SummaryKeys{BlockedComponents: []string{}, Service: tk.Service}
+	for name, cr := range tk.Components {
+		if cr.Blocked() {
+			sk.BlockedComponents = append(sk.BlockedComponents, name)
+		}
+	}
+	sort.Strings(sk.BlockedComponents)
+	sk.IsAnomaly = len(sk.BlockedComponents) > 0
+	return sk
+}
+
+// Anomaly implements model.MeasurementSummaryKeys.
+func (sk *SummaryKeys) Anomaly() bool {
+	return sk.IsAnomaly
+}
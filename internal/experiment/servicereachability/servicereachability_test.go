@@ -0,0 +1,274 @@
+package servicereachability_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/checkincache"
+	"github.com/ooni/probe-cli/v3/internal/experiment/servicereachability"
+	"github.com/ooni/probe-cli/v3/internal/geoipx"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/servicedefs"
+)
+
+// newDefinition returns the JSON definition of the example service we use for testing.
+func newDefinition() string {
+	asn, _, _ := geoipx.LookupASN(netemx.AddressWwwExampleCom, "")
+	signal := runtimex.Try1(servicedefs.Embedded("signal"))
+	return fmt.Sprintf(`{
+		"name": "example",
+		"version": 1,
+		"certificates": {"other": [%q]},
+		"components": [
+			{"name": "dns", "checks": [{"type": "dns", "domain": "www.example.com", "expected_asns": [%d]}]},
+			{"name": "fallback", "success": "any", "checks": [
+				{"type": "tcp", "endpoint": "www.example.com:5222"},
+				{"type": "tcp", "endpoint": "www.example.com:443"}
+			]},
+			{"name": "pinned", "checks": [{"type": "tls", "endpoint": "www.example.com:443", "root_cas": "other"}]},
+			{"name": "quic", "checks": [{"type": "quic", "endpoint": "www.example.com:443"}]},
+			{"name": "status", "checks": [{"type": "http", "url": "https://www.example.com/", "expected_status_codes": [404]}]},
+			{"name": "tcp", "checks": [{"type": "tcp", "endpoint": "93.184.216.34:443"}]},
+			{"name": "tls", "checks": [{"type": "tls", "endpoint": "www.example.com:443"}]},
+			{"name": "web", "checks": [
+				{"type": "http", "url": "http://www.example.com/"},
+				{"type": "http", "method": "GET", "url": "https://www.example.com/", "expected_status_codes": [200]}
+			]}
+		]
+	}`, signal.Certificates["signal"][0], asn)
+}
+
+// newSession returns a session whose key-value store contains the given definitions.
+func newSession(t *testing.T, defs string) model.ExperimentSession {
+	kvStore := &kvstore.Memory{}
+	resp := &model.OOAPICheckInResult{
+		Conf: model.OOAPICheckInResultConfig{
+			ServiceDefinitions: []byte(defs),
+		},
+	}
+	if err := checkincache.Store(kvStore, resp); err != nil {
+		t.Fatal(err)
+	}
+	return &mocks.Session{
+		MockLogger:        func() model.Logger { return log.Log },
+		MockKeyValueStore: func() model.KeyValueStore { return kvStore },
+	}
+}
+
+// runExample measures the example service and returns the test keys.
+func runExample(t *testing.T, env *netemx.QAEnv) *servicereachability.TestKeys {
+	var tk *servicereachability.TestKeys
+	env.Do(func() {
+		measurer := servicereachability.NewExperimentMeasurer(servicereachability.Config{})
+		measurement := &model.Measurement{Input: "example"}
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(log.Log),
+			Measurement: measurement,
+			Session:     newSession(t, "["+newDefinition()+"]"),
+		}
+		if err := measurer.Run(context.Background(), args); err != nil {
+			t.Fatal(err)
+		}
+		tk = measurement.TestKeys.(*servicereachability.TestKeys)
+	})
+	return tk
+}
+
+// verdicts returns a map from each component name to its status and blocking step.
+func verdicts(tk *servicereachability.TestKeys) map[string]string {
+	out := map[string]string{}
+	for name, cr := range tk.Components {
+		out[name] = cr.Status
+		if cr.Blocking != nil {
+			out[name] += "/" + *cr.Blocking
+		}
+	}
+	return out
+}
+
+func TestNewExperimentMeasurer(t *testing.T) {
+	measurer := servicereachability.NewExperimentMeasurer(servicereachability.Config{})
+	if measurer.ExperimentName() != "service_reachability" {
+		t.Fatal("unexpected name")
+	}
+	if measurer.ExperimentVersion() != "0.1.0" {
+		t.Fatal("unexpected version")
+	}
+}
+
+func TestMeasurerRun(t *testing.T) {
+	t.Run("without input", func(t *testing.T) {
+		measurer := servicereachability.NewExperimentMeasurer(servicereachability.Config{})
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(log.Log),
+			Measurement: &model.Measurement{},
+			Session:     newSession(t, "[]"),
+		}
+		err := measurer.Run(context.Background(), args)
+		if !errors.Is(err, servicereachability.ErrInputRequired) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with an unknown service", func(t *testing.T) {
+		measurer := servicereachability.NewExperimentMeasurer(servicereachability.Config{})
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(log.Log),
+			Measurement: &model.Measurement{Input: "example"},
+			Session:     newSession(t, "[]"),
+		}
+		err := measurer.Run(context.Background(), args)
+		if !errors.Is(err, servicedefs.ErrNoSuchService) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("without censorship", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+
+		tk := runExample(t, env)
+		expect := map[string]string{
+			"dns":      "ok",
+			"fallback": "ok",
+			"pinned":   "blocked/tls",
+			"quic":     "ok",
+			"status":   "blocked/http",
+			"tcp":      "ok",
+			"tls":      "ok",
+			"web":      "ok",
+		}
+		if diff := cmp.Diff(expect, verdicts(tk)); diff != "" {
+			t.Fatal(diff)
+		}
+		if *tk.Components["pinned"].Failure != netxlite.FailureSSLUnknownAuthority {
+			t.Fatal("unexpected pinned failure", *tk.Components["pinned"].Failure)
+		}
+		if *tk.Components["status"].Failure != servicereachability.FailureHTTPUnexpectedStatusCode {
+			t.Fatal("unexpected status failure", *tk.Components["status"].Failure)
+		}
+		if tk.Service != "example" || tk.ServiceVersion != 1 {
+			t.Fatal("unexpected service")
+		}
+		if len(tk.Checks) != 10 || tk.Checks[1].FailedOperation == nil ||
+			*tk.Checks[1].FailedOperation != netxlite.ConnectOperation {
+			t.Fatal("unexpected checks")
+		}
+		if len(tk.NetworkEvents) <= 0 || len(tk.Queries) <= 0 || len(tk.QUICHandshakes) <= 0 ||
+			len(tk.Requests) <= 0 || len(tk.TCPConnect) <= 0 || len(tk.TLSHandshakes) <= 0 {
+			t.Fatal("expected to see archival data")
+		}
+		sk := tk.MeasurementSummaryKeys().(*servicereachability.SummaryKeys)
+		if diff := cmp.Diff([]string{"pinned", "status"}, sk.BlockedComponents); diff != "" {
+			t.Fatal(diff)
+		}
+		if !sk.Anomaly() {
+			t.Fatal("expected an anomaly")
+		}
+	})
+
+	t.Run("with DNS poisoning", func(t *testing.T) {
+		if asn, _, err := geoipx.LookupASN(netemx.AddressWwwExampleCom, ""); err != nil || asn == 0 {
+			t.Skip("skip test because we cannot map IP addresses to ASNs")
+		}
+
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+		env.ISPResolverConfig().AddRecord("www.example.com", "", "10.10.34.35")
+
+		tk := runExample(t, env)
+		if diff := cmp.Diff("blocked/dns", verdicts(tk)["dns"]); diff != "" {
+			t.Fatal(diff)
+		}
+		if *tk.Components["dns"].Failure != servicereachability.FailureDNSUnexpectedASN {
+			t.Fatal("unexpected failure", *tk.Components["dns"].Failure)
+		}
+	})
+
+	t.Run("with DPI resetting TLS handshakes", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+		env.DPIEngine().AddRule(&netem.DPIResetTrafficForTLSSNI{
+			Logger: log.Log,
+			SNI:    "www.example.com",
+		})
+
+		tk := runExample(t, env)
+		expect := map[string]string{
+			"dns":      "ok",
+			"fallback": "ok",
+			"pinned":   "blocked/tls",
+			"quic":     "ok",
+			"status":   "blocked/tls",
+			"tcp":      "ok",
+			"tls":      "blocked/tls",
+			"web":      "blocked/tls",
+		}
+		if diff := cmp.Diff(expect, verdicts(tk)); diff != "" {
+			t.Fatal(diff)
+		}
+		if *tk.Components["tls"].Failure != netxlite.FailureConnectionReset {
+			t.Fatal("unexpected failure", *tk.Components["tls"].Failure)
+		}
+	})
+
+	t.Run("with a cancelled context", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+
+		env.Do(func() {
+			def := runtimex.Try1(servicedefs.Parse([]byte(newDefinition())))
+			tk := servicereachability.NewTestKeys()
+			args := &model.ExperimentArgs{
+				Callbacks:   model.NewPrinterCallbacks(log.Log),
+				Measurement: &model.Measurement{},
+				Session:     newSession(t, "[]"),
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel() // fail immediately
+			servicereachability.Measure(ctx, args, def, tk)
+			for name, cr := range tk.Components {
+				if name == "tcp" {
+					// the endpoint is an IP address, hence we fail when connecting
+					if cr.Blocking == nil || *cr.Blocking != "tcp" {
+						t.Fatal("unexpected blocking for", name)
+					}
+					continue
+				}
+				if cr.Blocking == nil || *cr.Blocking != "dns" || *cr.Failure != netxlite.FailureInterrupted {
+					t.Fatal("unexpected verdict for", name)
+				}
+			}
+		})
+	})
+}
+
+func TestTestKeysComponent(t *testing.T) {
+	tk := servicereachability.NewTestKeys()
+	cr := tk.Component("nonexistent")
+	if !cr.Blocked() || *cr.Failure != netxlite.FailureUnknown {
+		t.Fatal("expected a blocked verdict")
+	}
+}
+
+func TestNewLegacyTestKeys(t *testing.T) {
+	data, err := json.Marshal(servicereachability.NewLegacyTestKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"agent":"redirect","failed_operation":null,"failure":null}`
+	if diff := cmp.Diff(expect, string(data)); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,247 @@
+package servicereachability
+
+//
+// Test keys and per-component verdicts
+//
+
+import (
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/servicedefs"
+)
+
+// CheckResult is the result of running a [*servicedefs.Check].
+type CheckResult struct {
+	// Component is the name of the component containing the check.
+	Component string `json:"component"`
+
+	// FailedOperation is the operation that failed or nil.
+	FailedOperation *string `json:"failed_operation"`
+
+	// Failure is the failure that occurred or nil.
+	Failure *string `json:"failure"`
+
+	// Target is the domain, endpoint or URL measured by the check.
+	Target string `json:"target"`
+
+	// TransactionID is the ID of the archival data emitted by the check.
+	TransactionID int64 `json:"transaction_id"`
+
+	// Type is the check type (e.g., "tls").
+	Type string `json:"type"`
+}
+
+// These are the possible values of [ComponentResult] Status.
+const (
+	// StatusOK means that the component is reachable.
+	StatusOK = "ok"
+
+	// StatusBlocked means that the component is not reachable.
+	StatusBlocked = "blocked"
+)
+
+// BlockingMultiple is the [ComponentResult] Blocking value we use when the
+// checks of a component failed at different steps.
+const BlockingMultiple = "multiple"
+
+// ComponentResult is the verdict for a [*servicedefs.Component].
+type ComponentResult struct {
+	// Blocking is the step at which the component is blocked (one of "dns", "tcp",
+	// "tls", "quic", "http" and [BlockingMultiple]) or nil if not blocked.
+	Blocking *string `json:"blocking"`
+
+	// Failure is the failure of the first failed check or nil if not blocked.
+	Failure *string `json:"failure"`
+
+	// Status is either [StatusOK] or [StatusBlocked].
+	Status string `json:"status"`
+}
+
+// Blocked returns whether the component is blocked.
+func (cr *ComponentResult) Blocked() bool {
+	return cr.Status == StatusBlocked
+}
+
+// TestKeys contains the experiment results.
+type TestKeys struct {
+	// Checks contains the result of each check in definition order.
+	Checks []*CheckResult `json:"service_checks"`
+
+	// Components maps the name of each component to its verdict.
+	Components map[string]*ComponentResult `json:"service_components"`
+
+	// NetworkEvents contains network events.
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+
+	// Queries contains DNS queries.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// QUICHandshakes contains QUIC handshakes.
+	QUICHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"quic_handshakes"`
+
+	// Requests contains HTTP requests.
+	Requests []*model.ArchivalHTTPRequestResult `json:"requests"`
+
+	// Service is the name of the measured service.
+	Service string `json:"service"`
+
+	// ServiceVersion is the version of the service definition we used.
+	ServiceVersion int64 `json:"service_version"`
+
+	// TCPConnect contains TCP connect results.
+	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect"`
+
+	// TLSHandshakes contains TLS handshakes.
+	TLSHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
+
+	// mu provides mutual exclusion for accessing the test keys.
+	mu sync.Mutex
+}
+
+// LegacyTestKeys contains the top-level keys emitted by the facebook_messenger, signal,
+// telegram and whatsapp experiments when they were based on urlgetter. Those experiments
+// embed this struct to keep emitting such keys with the same values.
+type LegacyTestKeys struct {
+	// Agent is always "redirect".
+	Agent string `json:"agent"`
+
+	// BootstrapTime is always zero and therefore omitted.
+	BootstrapTime float64 `json:"bootstrap_time,omitempty"`
+
+	// DNSCache is always empty and therefore omitted.
+	DNSCache []string `json:"dns_cache,omitempty"`
+
+	// FailedOperation is always nil.
+	FailedOperation *string `json:"failed_operation"`
+
+	// Failure is always nil.
+	Failure *string `json:"failure"`
+
+	// SOCKSProxy is always empty and therefore omitted.
+	SOCKSProxy string `json:"socksproxy,omitempty"`
+
+	// Tunnel is always empty and therefore omitted.
+	Tunnel string `json:"tunnel,omitempty"`
+}
+
+// NewLegacyTestKeys creates a new [LegacyTestKeys].
+func NewLegacyTestKeys() LegacyTestKeys {
+	return LegacyTestKeys{
+		Agent:           "redirect",
+		BootstrapTime:   0,
+		DNSCache:        nil,
+		FailedOperation: nil,
+		Failure:         nil,
+		SOCKSProxy:      "",
+		Tunnel:          "",
+	}
+}
+
+// NewTestKeys creates new [*TestKeys].
+func NewTestKeys() *TestKeys {
+	return &TestKeys{
+		Checks:         []*CheckResult{},
+		Components:     map[string]*ComponentResult{},
+		NetworkEvents:  []*model.ArchivalNetworkEvent{},
+		Queries:        []*model.ArchivalDNSLookupResult{},
+		QUICHandshakes: []*model.ArchivalTLSOrQUICHandshakeResult{},
+		Requests:       []*model.ArchivalHTTPRequestResult{},
+		Service:        "",
+		ServiceVersion: 0,
+		TCPConnect:     []*model.ArchivalTCPConnectResult{},
+		TLSHandshakes:  []*model.ArchivalTLSOrQUICHandshakeResult{},
+		mu:             sync.Mutex{},
+	}
+}
+
+func (tk *TestKeys) appendNetworkEvents(v ...*model.ArchivalNetworkEvent) {
+	tk.mu.Lock()
+	tk.NetworkEvents = append(tk.NetworkEvents, v...)
+	tk.mu.Unlock()
+}
+
+func (tk *TestKeys) appendQueries(v ...*model.ArchivalDNSLookupResult) {
+	tk.mu.Lock()
+	tk.Queries = append(tk.Queries, v...)
+	tk.mu.Unlock()
+}
+
+func (tk *TestKeys) appendQUICHandshakes(v ...*model.ArchivalTLSOrQUICHandshakeResult) {
+	tk.mu.Lock()
+	tk.QUICHandshakes = append(tk.QUICHandshakes, v...)
+	tk.mu.Unlock()
+}
+
+func (tk *TestKeys) appendRequests(v ...*model.ArchivalHTTPRequestResult) {
+	tk.mu.Lock()
+	tk.Requests = append(tk.Requests, v...)
+	tk.mu.Unlock()
+}
+
+func (tk *TestKeys) appendTCPConnect(v ...*model.ArchivalTCPConnectResult) {
+	tk.mu.Lock()
+	tk.TCPConnect = append(tk.TCPConnect, v...)
+	tk.mu.Unlock()
+}
+
+func (tk *TestKeys) appendTLSHandshakes(v ...*model.ArchivalTLSOrQUICHandshakeResult) {
+	tk.mu.Lock()
+	tk.TLSHandshakes = append(tk.TLSHandshakes, v...)
+	tk.mu.Unlock()
+}
+
+// Component returns the verdict for the given component, which is a
+// blocked verdict with unknown failure when the component was not measured.
+func (tk *TestKeys) Component(name string) *ComponentResult {
+	if cr, found := tk.Components[name]; found {
+		return cr
+	}
+	failure := netxlite.FailureUnknown
+	return &ComponentResult{Blocking: nil, Failure: &failure, Status: StatusBlocked}
+}
+
+// blockingSteps maps failed operations to blocking steps.
+var blockingSteps = map[string]string{
+	netxlite.ResolveOperation:       "dns",
+	netxlite.ConnectOperation:       "tcp",
+	netxlite.TLSHandshakeOperation:  "tls",
+	netxlite.QUICHandshakeOperation: "quic",
+	netxlite.HTTPRoundTripOperation: "http",
+}
+
+// AnalyzeComponents computes the verdict for each component of the given
+// definition using the results of the checks, which must be in definition order.
+func AnalyzeComponents(def *servicedefs.Definition, results []*CheckResult) map[string]*ComponentResult {
+	out := map[string]*ComponentResult{}
+	for _, comp := range def.Components {
+		var failed []*CheckResult
+		for _, result := range results {
+			if result.Component == comp.Name && result.Failure != nil {
+				failed = append(failed, result)
+			}
+		}
+		blocked := len(failed) > 0
+		if comp.Success == servicedefs.SuccessAny {
+			blocked = len(failed) == len(comp.Checks)
+		}
+		if !blocked {
+			out[comp.Name] = &ComponentResult{Blocking: nil, Failure: nil, Status: StatusOK}
+			continue
+		}
+		var blocking string
+		for _, result := range failed {
+			step := *result.FailedOperation
+			if value, found := blockingSteps[step]; found {
+				step = value
+			}
+			if blocking != "" && blocking != step {
+				step = BlockingMultiple
+			}
+			blocking = step
+		}
+		out[comp.Name] = &ComponentResult{Blocking: &blocking, Failure: failed[0].Failure, Status: StatusBlocked}
+	}
+	return out
+}
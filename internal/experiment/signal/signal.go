@@ -1,82 +1,21 @@
 // Package signal contains the Signal network experiment.
 //
 // See https://github.com/ooni/spec/blob/master/nettests/ts-029-signal.md.
+//
+// We measure the endpoints listed by the "signal" service definition (see
+// the internal/servicedefs package) using the service_reachability engine.
 package signal
 
 import (
 	"context"
-	"errors"
-	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
+	"github.com/ooni/probe-cli/v3/internal/experiment/servicereachability"
 	"github.com/ooni/probe-cli/v3/internal/model"
-	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 const (
 	testName    = "signal"
-	testVersion = "0.2.5"
-
-	signalCA = `-----BEGIN CERTIFICATE-----
-MIID7zCCAtegAwIBAgIJAIm6LatK5PNiMA0GCSqGSIb3DQEBBQUAMIGNMQswCQYD
-VQQGEwJVUzETMBEGA1UECAwKQ2FsaWZvcm5pYTEWMBQGA1UEBwwNU2FuIEZyYW5j
-aXNjbzEdMBsGA1UECgwUT3BlbiBXaGlzcGVyIFN5c3RlbXMxHTAbBgNVBAsMFE9w
-ZW4gV2hpc3BlciBTeXN0ZW1zMRMwEQYDVQQDDApUZXh0U2VjdXJlMB4XDTEzMDMy
-NTIyMTgzNVoXDTIzMDMyMzIyMTgzNVowgY0xCzAJBgNVBAYTAlVTMRMwEQYDVQQI
-DApDYWxpZm9ybmlhMRYwFAYDVQQHDA1TYW4gRnJhbmNpc2NvMR0wGwYDVQQKDBRP
-cGVuIFdoaXNwZXIgU3lzdGVtczEdMBsGA1UECwwUT3BlbiBXaGlzcGVyIFN5c3Rl
-bXMxEzARBgNVBAMMClRleHRTZWN1cmUwggEiMA0GCSqGSIb3DQEBAQUAA4IBDwAw
-ggEKAoIBAQDBSWBpOCBDF0i4q2d4jAXkSXUGpbeWugVPQCjaL6qD9QDOxeW1afvf
-Po863i6Crq1KDxHpB36EwzVcjwLkFTIMeo7t9s1FQolAt3mErV2U0vie6Ves+yj6
-grSfxwIDAcdsKmI0a1SQCZlr3Q1tcHAkAKFRxYNawADyps5B+Zmqcgf653TXS5/0
-IPPQLocLn8GWLwOYNnYfBvILKDMItmZTtEbucdigxEA9mfIvvHADEbteLtVgwBm9
-R5vVvtwrD6CCxI3pgH7EH7kMP0Od93wLisvn1yhHY7FuYlrkYqdkMvWUrKoASVw4
-jb69vaeJCUdU+HCoXOSP1PQcL6WenNCHAgMBAAGjUDBOMB0GA1UdDgQWBBQBixjx
-P/s5GURuhYa+lGUypzI8kDAfBgNVHSMEGDAWgBQBixjxP/s5GURuhYa+lGUypzI8
-kDAMBgNVHRMEBTADAQH/MA0GCSqGSIb3DQEBBQUAA4IBAQB+Hr4hC56m0LvJAu1R
-K6NuPDbTMEN7/jMojFHxH4P3XPFfupjR+bkDq0pPOU6JjIxnrD1XD/EVmTTaTVY5
-iOheyv7UzJOefb2pLOc9qsuvI4fnaESh9bhzln+LXxtCrRPGhkxA1IMIo3J/s2WF
-/KVYZyciu6b4ubJ91XPAuBNZwImug7/srWvbpk0hq6A6z140WTVSKtJG7EP41kJe
-/oF4usY5J7LPkxK3LWzMJnb5EIJDmRvyH8pyRwWg6Qm6qiGFaI4nL8QU4La1x2en
-4DGXRaLMPRwjELNgQPodR38zoCMuA8gHZfZYYoZ7D7Q1wNUiVHcxuFrEeBaYJbLE
-rwLV
------END CERTIFICATE-----`
-
-	signalCANew = `
------BEGIN CERTIFICATE-----
-MIIF2zCCA8OgAwIBAgIUAMHz4g60cIDBpPr1gyZ/JDaaPpcwDQYJKoZIhvcNAQEL
-BQAwdTELMAkGA1UEBhMCVVMxEzARBgNVBAgTCkNhbGlmb3JuaWExFjAUBgNVBAcT
-DU1vdW50YWluIFZpZXcxHjAcBgNVBAoTFVNpZ25hbCBNZXNzZW5nZXIsIExMQzEZ
-MBcGA1UEAxMQU2lnbmFsIE1lc3NlbmdlcjAeFw0yMjAxMjYwMDQ1NTFaFw0zMjAx
-MjQwMDQ1NTBaMHUxCzAJBgNVBAYTAlVTMRMwEQYDVQQIEwpDYWxpZm9ybmlhMRYw
-FAYDVQQHEw1Nb3VudGFpbiBWaWV3MR4wHAYDVQQKExVTaWduYWwgTWVzc2VuZ2Vy
-LCBMTEMxGTAXBgNVBAMTEFNpZ25hbCBNZXNzZW5nZXIwggIiMA0GCSqGSIb3DQEB
-AQUAA4ICDwAwggIKAoICAQDEecifxMHHlDhxbERVdErOhGsLO08PUdNkATjZ1kT5
-1uPf5JPiRbus9F4J/GgBQ4ANSAjIDZuFY0WOvG/i0qvxthpW70ocp8IjkiWTNiA8
-1zQNQdCiWbGDU4B1sLi2o4JgJMweSkQFiyDynqWgHpw+KmvytCzRWnvrrptIfE4G
-PxNOsAtXFbVH++8JO42IaKRVlbfpe/lUHbjiYmIpQroZPGPY4Oql8KM3o39ObPnT
-o1WoM4moyOOZpU3lV1awftvWBx1sbTBL02sQWfHRxgNVF+Pj0fdDMMFdFJobArrL
-VfK2Ua+dYN4pV5XIxzVarSRW73CXqQ+2qloPW/ynpa3gRtYeGWV4jl7eD0PmeHpK
-OY78idP4H1jfAv0TAVeKpuB5ZFZ2szcySxrQa8d7FIf0kNJe9gIRjbQ+XrvnN+ZZ
-vj6d+8uBJq8LfQaFhlVfI0/aIdggScapR7w8oLpvdflUWqcTLeXVNLVrg15cEDwd
-lV8PVscT/KT0bfNzKI80qBq8LyRmauAqP0CDjayYGb2UAabnhefgmRY6aBE5mXxd
-byAEzzCS3vDxjeTD8v8nbDq+SD6lJi0i7jgwEfNDhe9XK50baK15Udc8Cr/ZlhGM
-jNmWqBd0jIpaZm1rzWA0k4VwXtDwpBXSz8oBFshiXs3FD6jHY2IhOR3ppbyd4qRU
-pwIDAQABo2MwYTAOBgNVHQ8BAf8EBAMCAQYwDwYDVR0TAQH/BAUwAwEB/zAdBgNV
-HQ4EFgQUtfNLxuXWS9DlgGuMUMNnW7yx83EwHwYDVR0jBBgwFoAUtfNLxuXWS9Dl
-gGuMUMNnW7yx83EwDQYJKoZIhvcNAQELBQADggIBABUeiryS0qjykBN75aoHO9bV
-PrrX+DSJIB9V2YzkFVyh/io65QJMG8naWVGOSpVRwUwhZVKh3JVp/miPgzTGAo7z
-hrDIoXc+ih7orAMb19qol/2Ha8OZLa75LojJNRbZoCR5C+gM8C+spMLjFf9k3JVx
-dajhtRUcR0zYhwsBS7qZ5Me0d6gRXD0ZiSbadMMxSw6KfKk3ePmPb9gX+MRTS63c
-8mLzVYB/3fe/bkpq4RUwzUHvoZf+SUD7NzSQRQQMfvAHlxk11TVNxScYPtxXDyiy
-3Cssl9gWrrWqQ/omuHipoH62J7h8KAYbr6oEIq+Czuenc3eCIBGBBfvCpuFOgckA
-XXE4MlBasEU0MO66GrTCgMt9bAmSw3TrRP12+ZUFxYNtqWluRU8JWQ4FCCPcz9pg
-MRBOgn4lTxDZG+I47OKNuSRjFEP94cdgxd3H/5BK7WHUz1tAGQ4BgepSXgmjzifF
-T5FVTDTl3ZnWUVBXiHYtbOBgLiSIkbqGMCLtrBtFIeQ7RRTb3L+IE9R0UB0cJB3A
-Xbf1lVkOcmrdu2h8A32aCwtr5S1fBF1unlG7imPmqJfpOMWa8yIF/KWVm29JAPq8
-Lrsybb0z5gg8w7ZblEuB9zOW9M3l60DXuJO6l7g+deV6P96rv2unHS8UlvWiVWDy
-9qfgAJizyy3kqM4lOwBH
------END CERTIFICATE-----`
+	testVersion = "0.3.0"
 )
 
 // Config contains the signal experiment config.
@@ -87,7 +26,8 @@ type Config struct {
 
 // TestKeys contains signal test keys.
 type TestKeys struct {
-	urlgetter.TestKeys
+	*servicereachability.TestKeys
+	servicereachability.LegacyTestKeys
 	SignalBackendStatus  string  `json:"signal_backend_status"`
 	SignalBackendFailure *string `json:"signal_backend_failure"`
 }
@@ -95,27 +35,20 @@ type TestKeys struct {
 // NewTestKeys creates new signal TestKeys.
 func NewTestKeys() *TestKeys {
 	return &TestKeys{
+		TestKeys:             servicereachability.NewTestKeys(),
+		LegacyTestKeys:       servicereachability.NewLegacyTestKeys(),
 		SignalBackendStatus:  "ok",
 		SignalBackendFailure: nil,
 	}
 }
 
-// Update updates the TestKeys using the given MultiOutput result.
-func (tk *TestKeys) Update(v urlgetter.MultiOutput) {
-	// update the easy to update entries first
-	tk.NetworkEvents = append(tk.NetworkEvents, v.TestKeys.NetworkEvents...)
-	tk.Queries = append(tk.Queries, v.TestKeys.Queries...)
-	tk.Requests = append(tk.Requests, v.TestKeys.Requests...)
-	tk.TCPConnect = append(tk.TCPConnect, v.TestKeys.TCPConnect...)
-	tk.TLSHandshakes = append(tk.TLSHandshakes, v.TestKeys.TLSHandshakes...)
-	// Ignore the result of the uptime DNS lookup
-	if v.Input.Target == "dnslookup://uptime.signal.org" {
-		return
-	}
-	if v.TestKeys.Failure != nil {
+// computeBackendStatus sets the backend fields using the verdict of the backend
+// component. We ignore the verdict of the uptime component.
+func (tk *TestKeys) computeBackendStatus() {
+	backend := tk.Component("backend")
+	if backend.Blocked() {
 		tk.SignalBackendStatus = "blocked"
-		tk.SignalBackendFailure = v.TestKeys.Failure
-		return
+		tk.SignalBackendFailure = backend.Failure
 	}
 }
 
@@ -124,9 +57,6 @@ type Measurer struct {
 	// Config contains the experiment settings. If empty we
 	// will be using default settings.
 	Config Config
-
-	// Getter is an optional getter to be used for testing.
-	Getter urlgetter.MultiGetter
 }
 
 // ExperimentName implements ExperimentMeasurer.ExperimentName
@@ -141,61 +71,20 @@ func (m Measurer) ExperimentVersion() string {
 
 // Run implements ExperimentMeasurer.Run
 func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
-	callbacks := args.Callbacks
-	measurement := args.Measurement
-	sess := args.Session
-	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
-	defer cancel()
-	urlgetter.RegisterExtensions(measurement)
-
-	certPool := netxlite.NewMozillaCertPool()
-	signalCAByteSlice := [][]byte{
-		[]byte(signalCA),
-		[]byte(signalCANew),
+	def, err := servicereachability.LoadDefinition(args.Session, testName)
+	if err != nil {
+		return err
 	}
 	if m.Config.SignalCA != "" {
-		signalCAByteSlice = [][]byte{[]byte(m.Config.SignalCA)}
-	}
-	for _, caBytes := range signalCAByteSlice {
-		if !certPool.AppendCertsFromPEM(caBytes) {
-			return errors.New("AppendCertsFromPEM failed")
+		def, err = def.WithCertificates("signal", m.Config.SignalCA)
+		if err != nil {
+			return err
 		}
 	}
-
-	// See https://github.com/ooni/probe/issues/2636 for information
-	// about which of the many available targets we should test
-	inputs := []urlgetter.MultiInput{
-		// Here we need to provide the method explicitly. See
-		// https://github.com/ooni/probe-engine/issues/827.
-		{Target: "https://cdsi.signal.org/", Config: urlgetter.Config{
-			Method:          "GET",
-			FailOnHTTPError: false,
-			CertPool:        certPool,
-		}},
-		{Target: "https://chat.signal.org/", Config: urlgetter.Config{
-			Method:          "GET",
-			FailOnHTTPError: false,
-			CertPool:        certPool,
-		}},
-		{Target: "https://sfu.voip.signal.org/", Config: urlgetter.Config{
-			Method:          "GET",
-			FailOnHTTPError: false,
-			CertPool:        certPool,
-		}},
-		{Target: "https://storage.signal.org/", Config: urlgetter.Config{
-			Method:          "GET",
-			FailOnHTTPError: false,
-			CertPool:        certPool,
-		}},
-		{Target: "dnslookup://uptime.signal.org"},
-	}
-	multi := urlgetter.Multi{Begin: time.Now(), Getter: m.Getter, Session: sess}
 	testkeys := NewTestKeys()
-	testkeys.Agent = "redirect"
-	measurement.TestKeys = testkeys
-	for entry := range multi.Collect(ctx, inputs, "signal", callbacks) {
-		testkeys.Update(entry)
-	}
+	args.Measurement.TestKeys = testkeys
+	servicereachability.Measure(ctx, args, def, testkeys.TestKeys)
+	testkeys.computeBackendStatus()
 	return nil
 }
 
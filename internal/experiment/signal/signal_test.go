@@ -2,98 +2,196 @@ package signal_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/apex/log"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/checkincache"
 	"github.com/ooni/probe-cli/v3/internal/experiment/signal"
-	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/servicedefs"
 )
 
+// signalBackendAddr is the IP address implementing the signal backend in netem-based tests.
+const signalBackendAddr = "13.248.212.111"
+
+// signalBackendDomains contains the domains of the signal backend.
+var signalBackendDomains = []string{
+	"cdsi.signal.org",
+	"chat.signal.org",
+	"sfu.voip.signal.org",
+	"storage.signal.org",
+	"uptime.signal.org",
+}
+
+// unpinnedDefinitions contains a signal definition more recent than the embedded one
+// that does not use the signal certificates, so we can use netem certificates.
+const unpinnedDefinitions = `[{
+	"name": "signal",
+	"version": 1000,
+	"components": [
+		{"name": "backend", "checks": [
+			{"type": "http", "url": "https://cdsi.signal.org/"},
+			{"type": "http", "url": "https://chat.signal.org/"},
+			{"type": "http", "url": "https://sfu.voip.signal.org/"},
+			{"type": "http", "url": "https://storage.signal.org/"}
+		]},
+		{"name": "uptime", "checks": [{"type": "dns", "domain": "uptime.signal.org"}]}
+	]
+}]`
+
+// newQAEnvironment creates a QA environment emulating the signal backend.
+func newQAEnvironment() *netemx.QAEnv {
+	env := netemx.MustNewQAEnv(
+		netemx.QAEnvOptionLogger(log.Log),
+		netemx.QAEnvOptionNetStack(
+			signalBackendAddr,
+			&netemx.HTTPSecureServerFactory{
+				Factory:          netemx.ExampleWebPageHandlerFactory(),
+				Ports:            []int{443},
+				ServerNameMain:   signalBackendDomains[0],
+				ServerNameExtras: signalBackendDomains[1:],
+			},
+		),
+	)
+	for _, domain := range signalBackendDomains {
+		env.AddRecordToAllResolvers(domain, "", signalBackendAddr)
+	}
+	return env
+}
+
+// newSession creates a session whose key-value store contains the given definitions.
+func newSession(t *testing.T, defs string) model.ExperimentSession {
+	kvStore := &kvstore.Memory{}
+	if defs != "" {
+		resp := &model.OOAPICheckInResult{
+			Conf: model.OOAPICheckInResultConfig{
+				ServiceDefinitions: []byte(defs),
+			},
+		}
+		if err := checkincache.Store(kvStore, resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &mocks.Session{
+		MockLogger:        func() model.Logger { return log.Log },
+		MockKeyValueStore: func() model.KeyValueStore { return kvStore },
+	}
+}
+
+// runMeasurement runs the signal experiment inside the given environment.
+func runMeasurement(t *testing.T, env *netemx.QAEnv, sess model.ExperimentSession) *signal.TestKeys {
+	var tk *signal.TestKeys
+	env.Do(func() {
+		measurer := signal.NewExperimentMeasurer(signal.Config{})
+		measurement := new(model.Measurement)
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(log.Log),
+			Measurement: measurement,
+			Session:     sess,
+		}
+		if err := measurer.Run(context.Background(), args); err != nil {
+			t.Fatal(err)
+		}
+		tk = measurement.TestKeys.(*signal.TestKeys)
+	})
+	return tk
+}
+
 func TestNewExperimentMeasurer(t *testing.T) {
 	measurer := signal.NewExperimentMeasurer(signal.Config{})
 	if measurer.ExperimentName() != "signal" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.2.5" {
+	if measurer.ExperimentVersion() != "0.3.0" {
 		t.Fatal("unexpected version")
 	}
 }
 
-func TestGood(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skip test in short mode")
-	}
-	t.Skip("https://github.com/ooni/probe/issues/2636")
+func TestMeasurerRun(t *testing.T) {
+	t.Run("without DPI: expect success", func(t *testing.T) {
+		env := newQAEnvironment()
+		defer env.Close()
 
-	measurer := signal.NewExperimentMeasurer(signal.Config{})
-	measurement := new(model.Measurement)
-	args := &model.ExperimentArgs{
-		Callbacks:   model.NewPrinterCallbacks(log.Log),
-		Measurement: measurement,
-		Session: &mockable.Session{
-			MockableLogger: log.Log,
-		},
-	}
-	err := measurer.Run(context.Background(), args)
-	if err != nil {
-		t.Fatal(err)
-	}
-	tk := measurement.TestKeys.(*signal.TestKeys)
-	if tk.Agent != "redirect" {
-		t.Fatal("unexpected Agent")
-	}
-	if tk.FailedOperation != nil {
-		t.Fatal("unexpected FailedOperation")
-	}
-	if tk.Failure != nil {
-		t.Fatal("unexpected Failure")
-	}
-	if len(tk.NetworkEvents) <= 0 {
-		t.Fatal("no NetworkEvents?!")
-	}
-	if len(tk.Queries) <= 0 {
-		t.Fatal("no Queries?!")
-	}
-	if len(tk.Requests) <= 0 {
-		t.Fatal("no Requests?!")
-	}
-	if len(tk.TCPConnect) <= 0 {
-		t.Fatal("no TCPConnect?!")
-	}
-	if len(tk.TLSHandshakes) <= 0 {
-		t.Fatal("no TLSHandshakes?!")
-	}
-	if tk.SignalBackendFailure != nil {
-		t.Fatal("unexpected SignalBackendFailure")
-	}
-	if tk.SignalBackendStatus != "ok" {
-		t.Fatal("unexpected SignalBackendStatus")
-	}
-}
+		tk := runMeasurement(t, env, newSession(t, unpinnedDefinitions))
+		if tk.SignalBackendFailure != nil {
+			t.Fatal("unexpected SignalBackendFailure", *tk.SignalBackendFailure)
+		}
+		if tk.SignalBackendStatus != "ok" {
+			t.Fatal("unexpected SignalBackendStatus")
+		}
+		if tk.ServiceVersion != 1000 {
+			t.Fatal("unexpected ServiceVersion")
+		}
+		if len(tk.NetworkEvents) <= 0 {
+			t.Fatal("no NetworkEvents?!")
+		}
+		if len(tk.Queries) <= 0 {
+			t.Fatal("no Queries?!")
+		}
+		if len(tk.Requests) <= 0 {
+			t.Fatal("no Requests?!")
+		}
+		if len(tk.TCPConnect) <= 0 {
+			t.Fatal("no TCPConnect?!")
+		}
+		if len(tk.TLSHandshakes) <= 0 {
+			t.Fatal("no TLSHandshakes?!")
+		}
+	})
 
-func TestUpdate(t *testing.T) {
-	tk := signal.NewTestKeys()
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{
-			Config: urlgetter.Config{Method: "GET"},
-			Target: "https://textsecure-service.whispersystems.org/",
-		},
-		TestKeys: urlgetter.TestKeys{
-			Failure: (func() *string {
-				s := netxlite.FailureEOFError
-				return &s
-			})(),
-		},
+	t.Run("with DPI resetting TLS traffic with SNI = chat.signal.org: expect SignalBackendFailure", func(t *testing.T) {
+		env := newQAEnvironment()
+		defer env.Close()
+		env.DPIEngine().AddRule(&netem.DPIResetTrafficForTLSSNI{
+			Logger: log.Log,
+			SNI:    "chat.signal.org",
+		})
+
+		tk := runMeasurement(t, env, newSession(t, unpinnedDefinitions))
+		if tk.SignalBackendFailure == nil || *tk.SignalBackendFailure != netxlite.FailureConnectionReset {
+			t.Fatal("unexpected SignalBackendFailure")
+		}
+		if tk.SignalBackendStatus != "blocked" {
+			t.Fatal("unexpected SignalBackendStatus")
+		}
+	})
+
+	t.Run("with the embedded definition: expect the signal certificates to be used", func(t *testing.T) {
+		env := newQAEnvironment()
+		defer env.Close()
+
+		tk := runMeasurement(t, env, newSession(t, ""))
+		if tk.SignalBackendFailure == nil || *tk.SignalBackendFailure != netxlite.FailureSSLUnknownAuthority {
+			t.Fatal("unexpected SignalBackendFailure")
+		}
+		if tk.SignalBackendStatus != "blocked" {
+			t.Fatal("unexpected SignalBackendStatus")
+		}
+	})
+
+	t.Run("with DNS failing for uptime.signal.org: expect success", func(t *testing.T) {
+		env := newQAEnvironment()
+		defer env.Close()
+		env.ISPResolverConfig().RemoveRecord("uptime.signal.org")
+
+		tk := runMeasurement(t, env, newSession(t, unpinnedDefinitions))
+		if !tk.Component("uptime").Blocked() {
+			t.Fatal("expected the uptime component to be blocked")
+		}
+		if tk.SignalBackendFailure != nil {
+			t.Fatal("unexpected SignalBackendFailure", *tk.SignalBackendFailure)
+		}
+		if tk.SignalBackendStatus != "ok" {
+			t.Fatal("unexpected SignalBackendStatus")
+		}
 	})
-	if tk.SignalBackendStatus != "blocked" {
-		t.Fatal("SignalBackendStatus should be blocked")
-	}
-	if *tk.SignalBackendFailure != netxlite.FailureEOFError {
-		t.Fatal("invalid SignalBackendError")
-	}
 }
 
 func TestBadSignalCA(t *testing.T) {
@@ -109,8 +207,8 @@ func TestBadSignalCA(t *testing.T) {
 		},
 	}
 	err := measurer.Run(context.Background(), args)
-	if err.Error() != "AppendCertsFromPEM failed" {
-		t.Fatal("not the error we expected")
+	if !errors.Is(err, servicedefs.ErrInvalidDefinition) {
+		t.Fatal("not the error we expected", err)
 	}
 }
 
@@ -0,0 +1,65 @@
+package signal
+
+import (
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/experiment/servicereachability"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/servicedefs"
+)
+
+// analyze fills the test keys with the results of running the checks of the embedded
+// signal definition, where the check with the given target fails with the given
+// failure, and then computes the backend status.
+func analyze(t *testing.T, target, failure string) *TestKeys {
+	def, err := servicedefs.Embedded(testName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []*servicereachability.CheckResult
+	for _, comp := range def.Components {
+		for _, check := range comp.Checks {
+			result := &servicereachability.CheckResult{
+				Component: comp.Name,
+				Target:    check.Target(),
+				Type:      check.Type,
+			}
+			if check.Target() == target {
+				operation := netxlite.HTTPRoundTripOperation
+				if check.Type == servicedefs.CheckTypeDNS {
+					operation = netxlite.ResolveOperation
+				}
+				result.FailedOperation = &operation
+				result.Failure = &failure
+			}
+			results = append(results, result)
+		}
+	}
+	tk := NewTestKeys()
+	tk.Checks = results
+	tk.Components = servicereachability.AnalyzeComponents(def, results)
+	tk.computeBackendStatus()
+	return tk
+}
+
+func TestComputeBackendStatus(t *testing.T) {
+	t.Run("when a backend check fails", func(t *testing.T) {
+		tk := analyze(t, "https://chat.signal.org/", netxlite.FailureEOFError)
+		if tk.SignalBackendStatus != "blocked" {
+			t.Fatal("SignalBackendStatus should be blocked")
+		}
+		if tk.SignalBackendFailure == nil || *tk.SignalBackendFailure != netxlite.FailureEOFError {
+			t.Fatal("invalid SignalBackendFailure")
+		}
+	})
+
+	t.Run("when only the uptime check fails", func(t *testing.T) {
+		tk := analyze(t, "uptime.signal.org", netxlite.FailureDNSNXDOMAINError)
+		if tk.SignalBackendStatus != "ok" {
+			t.Fatal("SignalBackendStatus should be ok")
+		}
+		if tk.SignalBackendFailure != nil {
+			t.Fatal("invalid SignalBackendFailure")
+		}
+	})
+}
@@ -20,7 +20,7 @@ import (
 
 const (
 	testName    = "sni_blocking"
-	testVersion = "0.3.0"
+	testVersion = "0.4.0"
 )
 
 // Config contains the experiment config.
@@ -10,8 +10,8 @@ import (
 	"net/url"
 	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/netx"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/pion/stun"
@@ -19,7 +19,7 @@ import (
 
 const (
 	testName    = "stunreachability"
-	testVersion = "0.4.0"
+	testVersion = "0.5.0"
 )
 
 // Config contains the experiment config.
@@ -30,15 +30,15 @@ type Config struct {
 
 // TestKeys contains the experiment's result.
 type TestKeys struct {
-	Endpoint      string                 `json:"endpoint"`
-	Failure       *string                `json:"failure"`
-	NetworkEvents []tracex.NetworkEvent  `json:"network_events"`
-	Queries       []tracex.DNSQueryEntry `json:"queries"`
+	Endpoint      string                           `json:"endpoint"`
+	Failure       *string                          `json:"failure"`
+	NetworkEvents []*model.ArchivalNetworkEvent    `json:"network_events"`
+	Queries       []*model.ArchivalDNSLookupResult `json:"queries"`
 }
 
 func registerExtensions(m *model.Measurement) {
-	tracex.ExtDNS.AddTo(m)
-	tracex.ExtNetevents.AddTo(m)
+	model.ArchivalExtDNS.AddTo(m)
+	model.ArchivalExtNetevents.AddTo(m)
 }
 
 // Measurer performs the measurement.
@@ -108,22 +108,15 @@ func (tk *TestKeys) run(
 	endpoint string,
 ) error {
 	tk.Endpoint = endpoint
-	saver := new(tracex.Saver)
-	begin := time.Now()
-	err := tk.do(ctx, config, netx.NewDialer(netx.Config{
-		ContextByteCounting: true,
-		Logger:              sess.Logger(),
-		ReadWriteSaver:      saver,
-		Saver:               saver,
-	}), endpoint)
-	sess.Logger().Infof("stunreachability: measuring: %s... %s", endpoint, model.ErrorToStringOrOK(err))
-	events := saver.Read()
-	tk.NetworkEvents = append(
-		tk.NetworkEvents, tracex.NewNetworkEventsList(begin, events)...,
-	)
-	tk.Queries = append(
-		tk.Queries, tracex.NewDNSQueriesList(begin, events)...,
-	)
+	logger := sess.Logger()
+	trace := measurexlite.NewTrace(0, time.Now())
+	netx := &netxlite.Netx{}
+	dialer := bytecounter.WrapWithContextAwareDialer(
+		netx.NewDialerWithResolver(logger, trace.NewStdlibResolver(logger)))
+	err := tk.do(netxlite.ContextWithTrace(ctx, trace), config, dialer, endpoint)
+	logger.Infof("stunreachability: measuring: %s... %s", endpoint, model.ErrorToStringOrOK(err))
+	tk.NetworkEvents = append(tk.NetworkEvents, trace.NetworkEvents()...)
+	tk.Queries = append(tk.Queries, trace.DNSLookupsFromRoundTrip()...)
 	return err
 }
 
@@ -1,20 +1,21 @@
 // Package telegram contains the Telegram network experiment.
 //
 // See https://github.com/ooni/spec/blob/master/nettests/ts-020-telegram.md.
+//
+// We measure the endpoints listed by the "telegram" service definition (see
+// the internal/servicedefs package) using the service_reachability engine.
 package telegram
 
 import (
 	"context"
-	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
+	"github.com/ooni/probe-cli/v3/internal/experiment/servicereachability"
 	"github.com/ooni/probe-cli/v3/internal/model"
-	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 const (
 	testName    = "telegram"
-	testVersion = "0.3.1"
+	testVersion = "0.4.0"
 )
 
 // Config contains the telegram experiment config.
@@ -22,7 +23,8 @@ type Config struct{}
 
 // TestKeys contains telegram test keys.
 type TestKeys struct {
-	urlgetter.TestKeys
+	*servicereachability.TestKeys
+	servicereachability.LegacyTestKeys
 	TelegramHTTPBlocking bool    `json:"telegram_http_blocking"`
 	TelegramTCPBlocking  bool    `json:"telegram_tcp_blocking"`
 	TelegramWebFailure   *string `json:"telegram_web_failure"`
@@ -32,37 +34,28 @@ type TestKeys struct {
 // NewTestKeys creates new telegram TestKeys.
 func NewTestKeys() *TestKeys {
 	return &TestKeys{
-		TelegramHTTPBlocking: true,
-		TelegramTCPBlocking:  true,
+		TestKeys:             servicereachability.NewTestKeys(),
+		LegacyTestKeys:       servicereachability.NewLegacyTestKeys(),
+		TelegramHTTPBlocking: false,
+		TelegramTCPBlocking:  false,
 		TelegramWebFailure:   nil,
 		TelegramWebStatus:    "ok",
 	}
 }
 
-// Update updates the TestKeys using the given MultiOutput result.
-func (tk *TestKeys) Update(v urlgetter.MultiOutput) {
-	// update the easy to update entries first
-	tk.NetworkEvents = append(tk.NetworkEvents, v.TestKeys.NetworkEvents...)
-	tk.Queries = append(tk.Queries, v.TestKeys.Queries...)
-	tk.Requests = append(tk.Requests, v.TestKeys.Requests...)
-	tk.TCPConnect = append(tk.TCPConnect, v.TestKeys.TCPConnect...)
-	tk.TLSHandshakes = append(tk.TLSHandshakes, v.TestKeys.TLSHandshakes...)
-	// then process access points
-	if v.Input.Config.Method != "GET" {
-		if v.TestKeys.Failure == nil {
-			tk.TelegramHTTPBlocking = false
-			tk.TelegramTCPBlocking = false
-			return // found successful access point connection
-		}
-		if v.TestKeys.FailedOperation == nil || *v.TestKeys.FailedOperation != netxlite.ConnectOperation {
-			tk.TelegramTCPBlocking = false
-		}
-		return
+// computeVerdicts sets the telegram fields using the verdicts of the access_points
+// and of the web components. The access points are blocked when we cannot speak HTTP
+// with any of them. When all of them fail at connect time, there is TCP blocking.
+func (tk *TestKeys) computeVerdicts() {
+	accessPoints := tk.Component("access_points")
+	if accessPoints.Blocked() {
+		tk.TelegramHTTPBlocking = true
+		tk.TelegramTCPBlocking = accessPoints.Blocking != nil && *accessPoints.Blocking == "tcp"
 	}
-	if v.TestKeys.Failure != nil {
+	web := tk.Component("web")
+	if web.Blocked() {
 		tk.TelegramWebStatus = "blocked"
-		tk.TelegramWebFailure = v.TestKeys.Failure
-		return
+		tk.TelegramWebFailure = web.Failure
 	}
 }
 
@@ -71,9 +64,6 @@ type Measurer struct {
 	// Config contains the experiment settings. If empty we
 	// will be using default settings.
 	Config Config
-
-	// Getter is an optional getter to be used for testing.
-	Getter urlgetter.MultiGetter
 }
 
 // ExperimentName implements ExperimentMeasurer.ExperimentName
@@ -86,47 +76,16 @@ func (m Measurer) ExperimentVersion() string {
 	return testVersion
 }
 
-// DatacenterIPAddrs contains the list of Telegram data centers IP addresses to measure.
-var DatacenterIPAddrs = []string{
-	"149.154.175.50",
-	"149.154.167.51",
-	"149.154.175.100",
-	"149.154.167.91",
-	"149.154.171.5",
-	"95.161.76.100",
-}
-
 // Run implements ExperimentMeasurer.Run
 func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
-	callbacks := args.Callbacks
-	measurement := args.Measurement
-	sess := args.Session
-
-	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
-	defer cancel()
-	urlgetter.RegisterExtensions(measurement)
-	inputs := []urlgetter.MultiInput{
-		// Here we need to provide the method explicitly. See
-		// https://github.com/ooni/probe-engine/issues/827.
-		{Target: "https://web.telegram.org/", Config: urlgetter.Config{
-			Method: "GET",
-		}},
+	def, err := servicereachability.LoadDefinition(args.Session, testName)
+	if err != nil {
+		return err
 	}
-
-	// We need to measure each address twice. Once using port 80 and once using port 443. In both
-	// cases, the protocol MUST be HTTP. The DCs do not support access on port 443 using TLS.
-	for _, dc := range DatacenterIPAddrs {
-		inputs = append(inputs, urlgetter.MultiInput{Target: "http://" + dc + "/", Config: urlgetter.Config{Method: "POST"}})
-		inputs = append(inputs, urlgetter.MultiInput{Target: "http://" + dc + ":443/", Config: urlgetter.Config{Method: "POST"}})
-	}
-
-	multi := urlgetter.Multi{Begin: time.Now(), Getter: m.Getter, Session: sess}
 	testkeys := NewTestKeys()
-	testkeys.Agent = "redirect"
-	measurement.TestKeys = testkeys
-	for entry := range multi.Collect(ctx, inputs, "telegram", callbacks) {
-		testkeys.Update(entry)
-	}
+	args.Measurement.TestKeys = testkeys
+	servicereachability.Measure(ctx, args, def, testkeys.TestKeys)
+	testkeys.computeVerdicts()
 	return nil
 }
 
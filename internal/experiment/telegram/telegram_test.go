@@ -10,12 +10,12 @@ import (
 	"github.com/apex/log"
 	"github.com/google/gopacket/layers"
 	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/checkincache"
 	"github.com/ooni/probe-cli/v3/internal/experiment/telegram"
-	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netemx"
-	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 func TestNewExperimentMeasurer(t *testing.T) {
@@ -23,202 +23,11 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "telegram" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.3.1" {
+	if measurer.ExperimentVersion() != "0.4.0" {
 		t.Fatal("unexpected version")
 	}
 }
 
-func TestUpdateWithNoAccessPointsBlocking(t *testing.T) {
-	tk := telegram.NewTestKeys()
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{
-			Config: urlgetter.Config{Method: "POST"},
-			Target: "http://149.154.175.50/",
-		},
-		TestKeys: urlgetter.TestKeys{
-			Failure: (func() *string {
-				s := netxlite.FailureEOFError
-				return &s
-			})(),
-		},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{
-			Config: urlgetter.Config{Method: "POST"},
-			Target: "http://149.154.175.50:443/",
-		},
-		TestKeys: urlgetter.TestKeys{
-			Failure: nil, // this should be enough to declare success
-		},
-	})
-	if tk.TelegramHTTPBlocking == true {
-		t.Fatal("there should be no TelegramHTTPBlocking")
-	}
-	if tk.TelegramTCPBlocking == true {
-		t.Fatal("there should be no TelegramTCPBlocking")
-	}
-}
-
-func TestUpdateWithNilFailedOperation(t *testing.T) {
-	tk := telegram.NewTestKeys()
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{
-			Config: urlgetter.Config{Method: "POST"},
-			Target: "http://149.154.175.50/",
-		},
-		TestKeys: urlgetter.TestKeys{
-			Failure: (func() *string {
-				s := netxlite.FailureEOFError
-				return &s
-			})(),
-		},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{
-			Config: urlgetter.Config{Method: "POST"},
-			Target: "http://149.154.175.50:443/",
-		},
-		TestKeys: urlgetter.TestKeys{
-			Failure: (func() *string {
-				s := netxlite.FailureEOFError
-				return &s
-			})(),
-		},
-	})
-	if tk.TelegramHTTPBlocking == false {
-		t.Fatal("there should be TelegramHTTPBlocking")
-	}
-	if tk.TelegramTCPBlocking == true {
-		t.Fatal("there should be no TelegramTCPBlocking")
-	}
-}
-
-func TestUpdateWithNonConnectFailedOperation(t *testing.T) {
-	tk := telegram.NewTestKeys()
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{
-			Config: urlgetter.Config{Method: "POST"},
-			Target: "http://149.154.175.50/",
-		},
-		TestKeys: urlgetter.TestKeys{
-			FailedOperation: (func() *string {
-				s := netxlite.ConnectOperation
-				return &s
-			})(),
-			Failure: (func() *string {
-				s := netxlite.FailureEOFError
-				return &s
-			})(),
-		},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{
-			Config: urlgetter.Config{Method: "POST"},
-			Target: "http://149.154.175.50:443/",
-		},
-		TestKeys: urlgetter.TestKeys{
-			FailedOperation: (func() *string {
-				s := netxlite.HTTPRoundTripOperation
-				return &s
-			})(),
-			Failure: (func() *string {
-				s := netxlite.FailureEOFError
-				return &s
-			})(),
-		},
-	})
-	if tk.TelegramHTTPBlocking == false {
-		t.Fatal("there should be TelegramHTTPBlocking")
-	}
-	if tk.TelegramTCPBlocking == true {
-		t.Fatal("there should be no TelegramTCPBlocking")
-	}
-}
-
-func TestUpdateWithAllConnectsFailed(t *testing.T) {
-	tk := telegram.NewTestKeys()
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{
-			Config: urlgetter.Config{Method: "POST"},
-			Target: "http://149.154.175.50/",
-		},
-		TestKeys: urlgetter.TestKeys{
-			FailedOperation: (func() *string {
-				s := netxlite.ConnectOperation
-				return &s
-			})(),
-			Failure: (func() *string {
-				s := netxlite.FailureEOFError
-				return &s
-			})(),
-		},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{
-			Config: urlgetter.Config{Method: "POST"},
-			Target: "http://149.154.175.50:443/",
-		},
-		TestKeys: urlgetter.TestKeys{
-			FailedOperation: (func() *string {
-				s := netxlite.ConnectOperation
-				return &s
-			})(),
-			Failure: (func() *string {
-				s := netxlite.FailureEOFError
-				return &s
-			})(),
-		},
-	})
-	if tk.TelegramHTTPBlocking == false {
-		t.Fatal("there should be TelegramHTTPBlocking")
-	}
-	if tk.TelegramTCPBlocking == false {
-		t.Fatal("there should be TelegramTCPBlocking")
-	}
-}
-
-func TestUpdateWithWebFailure(t *testing.T) {
-	failure := netxlite.FailureEOFError
-	failedOperation := netxlite.TLSHandshakeOperation
-	tk := telegram.NewTestKeys()
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{
-			Config: urlgetter.Config{Method: "GET"},
-			Target: "https://web.telegram.org/",
-		},
-		TestKeys: urlgetter.TestKeys{
-			Failure:         &failure,
-			FailedOperation: &failedOperation,
-		},
-	})
-	if tk.TelegramWebStatus != "blocked" {
-		t.Fatal("TelegramWebStatus should be blocked")
-	}
-	if *tk.TelegramWebFailure != failure {
-		t.Fatal("invalid TelegramWebFailure")
-	}
-}
-
-func TestUpdateWithAllGood(t *testing.T) {
-	tk := telegram.NewTestKeys()
-	tk.Update(urlgetter.MultiOutput{
-		Input: urlgetter.MultiInput{
-			Config: urlgetter.Config{Method: "GET"},
-			Target: "https://web.telegram.org/",
-		},
-		TestKeys: urlgetter.TestKeys{
-			HTTPResponseStatus: 200,
-			HTTPResponseBody:   "<HTML><title>Telegram Web</title></HTML>",
-		},
-	})
-	if tk.TelegramWebStatus != "ok" {
-		t.Fatal("TelegramWebStatus should be ok")
-	}
-	if tk.TelegramWebFailure != nil {
-		t.Fatal("invalid TelegramWebFailure")
-	}
-}
-
 func TestSummaryKeysWorksAsIntended(t *testing.T) {
 	failure := io.EOF.Error()
 	tests := []struct {
@@ -252,6 +61,35 @@ func TestSummaryKeysWorksAsIntended(t *testing.T) {
 	}
 }
 
+// datacenterIPAddrs contains the IP addresses of the telegram DCs in the embedded definition.
+var datacenterIPAddrs = []string{
+	"149.154.175.50",
+	"149.154.167.51",
+	"149.154.175.100",
+	"149.154.167.91",
+	"149.154.171.5",
+	"95.161.76.100",
+}
+
+// newSession creates a session whose key-value store contains the given service definitions.
+func newSession(t *testing.T, defs string) model.ExperimentSession {
+	kvStore := &kvstore.Memory{}
+	if defs != "" {
+		resp := &model.OOAPICheckInResult{
+			Conf: model.OOAPICheckInResultConfig{
+				ServiceDefinitions: []byte(defs),
+			},
+		}
+		if err := checkincache.Store(kvStore, resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &mocks.Session{
+		MockLogger:        func() model.Logger { return log.Log },
+		MockKeyValueStore: func() model.KeyValueStore { return kvStore },
+	}
+}
+
 // telegramWebAddr is the web.telegram.org IP address as of 2023-07-11
 const telegramWebAddr = "149.154.167.99"
 
@@ -311,7 +149,7 @@ func newQAEnvironment(ipaddrs ...string) *netemx.QAEnv {
 func TestMeasurerRun(t *testing.T) {
 	t.Run("without DPI: expect success", func(t *testing.T) {
 		// create a new test environment
-		env := newQAEnvironment(telegram.DatacenterIPAddrs...)
+		env := newQAEnvironment(datacenterIPAddrs...)
 		defer env.Close()
 
 		env.Do(func() {
@@ -320,7 +158,7 @@ func TestMeasurerRun(t *testing.T) {
 			args := &model.ExperimentArgs{
 				Callbacks:   model.NewPrinterCallbacks(log.Log),
 				Measurement: measurement,
-				Session:     &mocks.Session{MockLogger: func() model.Logger { return log.Log }},
+				Session:     newSession(t, ""),
 			}
 			err := measurer.Run(context.Background(), args)
 			if err != nil {
@@ -336,14 +174,8 @@ func TestMeasurerRun(t *testing.T) {
 			if tk.TelegramTCPBlocking {
 				t.Fatal("Unexpected TCP blocking")
 			}
-			if tk.Agent != "redirect" {
-				t.Fatal("unexpected Agent")
-			}
-			if tk.FailedOperation != nil {
-				t.Fatal("unexpected FailedOperation")
-			}
-			if tk.Failure != nil {
-				t.Fatal("unexpected Failure")
+			if tk.Service != "telegram" {
+				t.Fatal("unexpected Service")
 			}
 			if len(tk.NetworkEvents) <= 0 {
 				t.Fatal("no NetworkEvents?!")
@@ -368,7 +200,7 @@ func TestMeasurerRun(t *testing.T) {
 
 	t.Run("with poisoned DNS: expect TelegramWebFailure", func(t *testing.T) {
 		// create a new test environment
-		env := newQAEnvironment(telegram.DatacenterIPAddrs...)
+		env := newQAEnvironment(datacenterIPAddrs...)
 		defer env.Close()
 
 		// register bogon entries for web.telegram.org in the resolver's ISP
@@ -380,7 +212,7 @@ func TestMeasurerRun(t *testing.T) {
 			args := &model.ExperimentArgs{
 				Callbacks:   model.NewPrinterCallbacks(log.Log),
 				Measurement: measurement,
-				Session:     &mocks.Session{MockLogger: func() model.Logger { return log.Log }},
+				Session:     newSession(t, ""),
 			}
 			err := measurer.Run(context.Background(), args)
 			if err != nil {
@@ -404,22 +236,28 @@ func TestMeasurerRun(t *testing.T) {
 			t.Skip("skip test in short mode")
 		}
 
-		// overwrite global Datacenters, otherwise the test times out because there are too many endpoints
-		orig := telegram.DatacenterIPAddrs
-		telegram.DatacenterIPAddrs = []string{
-			"149.154.175.50",
-		}
-		defer func() {
-			telegram.DatacenterIPAddrs = orig
-		}()
+		// use a more recent definition with a single DC, otherwise the test
+		// times out because there are too many endpoints
+		const singleDC = `[{
+			"name": "telegram",
+			"version": 1000,
+			"components": [
+				{"name": "access_points", "success": "any", "checks": [
+					{"type": "http", "method": "POST", "url": "http://149.154.175.50/"},
+					{"type": "http", "method": "POST", "url": "http://149.154.175.50:443/"}
+				]},
+				{"name": "web", "checks": [{"type": "http", "url": "https://web.telegram.org/"}]}
+			]
+		}]`
+		dcs := []string{"149.154.175.50"}
 
 		// create a new test environment
-		env := newQAEnvironment(telegram.DatacenterIPAddrs...)
+		env := newQAEnvironment(dcs...)
 		defer env.Close()
 
 		// add DPI engine to emulate the censorship condition
 		dpi := env.DPIEngine()
-		for _, dc := range telegram.DatacenterIPAddrs {
+		for _, dc := range dcs {
 			dpi.AddRule(&netem.DPIDropTrafficForServerEndpoint{
 				Logger:          log.Log,
 				ServerIPAddress: dc,
@@ -440,7 +278,7 @@ func TestMeasurerRun(t *testing.T) {
 			args := &model.ExperimentArgs{
 				Callbacks:   model.NewPrinterCallbacks(log.Log),
 				Measurement: measurement,
-				Session:     &mocks.Session{MockLogger: func() model.Logger { return log.Log }},
+				Session:     newSession(t, singleDC),
 			}
 			err := measurer.Run(context.Background(), args)
 			if err != nil {
@@ -461,7 +299,7 @@ func TestMeasurerRun(t *testing.T) {
 
 	t.Run("with DPI that drops TLS traffic with SNI = web.telegram.org: expect TelegramWebFailure", func(t *testing.T) {
 		// create a new test environment
-		env := newQAEnvironment(telegram.DatacenterIPAddrs...)
+		env := newQAEnvironment(datacenterIPAddrs...)
 		defer env.Close()
 
 		// add DPI engine to emulate the censorship condition
@@ -477,7 +315,7 @@ func TestMeasurerRun(t *testing.T) {
 			args := &model.ExperimentArgs{
 				Callbacks:   model.NewPrinterCallbacks(log.Log),
 				Measurement: measurement,
-				Session:     &mocks.Session{MockLogger: func() model.Logger { return log.Log }},
+				Session:     newSession(t, ""),
 			}
 			err := measurer.Run(context.Background(), args)
 			if err != nil {
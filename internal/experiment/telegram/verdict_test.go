@@ -0,0 +1,203 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/experiment/servicereachability"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/servicedefs"
+)
+
+func TestComputeVerdicts(t *testing.T) {
+	blocked := func(blocking, failure string) *servicereachability.ComponentResult {
+		return &servicereachability.ComponentResult{
+			Blocking: &blocking,
+			Failure:  &failure,
+			Status:   servicereachability.StatusBlocked,
+		}
+	}
+	ok := &servicereachability.ComponentResult{Status: servicereachability.StatusOK}
+
+	type testcase struct {
+		name         string
+		components   map[string]*servicereachability.ComponentResult
+		httpBlocking bool
+		tcpBlocking  bool
+		webStatus    string
+	}
+
+	cases := []testcase{{
+		name:       "with all components ok",
+		components: map[string]*servicereachability.ComponentResult{"access_points": ok, "web": ok},
+		webStatus:  "ok",
+	}, {
+		name: "with all access points failing at connect time",
+		components: map[string]*servicereachability.ComponentResult{
+			"access_points": blocked("tcp", netxlite.FailureGenericTimeoutError),
+			"web":           ok,
+		},
+		httpBlocking: true,
+		tcpBlocking:  true,
+		webStatus:    "ok",
+	}, {
+		name: "with access points failing at different steps",
+		components: map[string]*servicereachability.ComponentResult{
+			"access_points": blocked(servicereachability.BlockingMultiple, netxlite.FailureEOFError),
+			"web":           ok,
+		},
+		httpBlocking: true,
+		webStatus:    "ok",
+	}, {
+		name: "with web failing",
+		components: map[string]*servicereachability.ComponentResult{
+			"access_points": ok,
+			"web":           blocked("tls", netxlite.FailureConnectionReset),
+		},
+		webStatus: "blocked",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tk := NewTestKeys()
+			tk.Components = tc.components
+			tk.computeVerdicts()
+			if tk.TelegramHTTPBlocking != tc.httpBlocking {
+				t.Fatal("unexpected TelegramHTTPBlocking")
+			}
+			if tk.TelegramTCPBlocking != tc.tcpBlocking {
+				t.Fatal("unexpected TelegramTCPBlocking")
+			}
+			if tk.TelegramWebStatus != tc.webStatus {
+				t.Fatal("unexpected TelegramWebStatus")
+			}
+			if (tk.TelegramWebFailure != nil) != (tc.webStatus == "blocked") {
+				t.Fatal("unexpected TelegramWebFailure")
+			}
+		})
+	}
+}
+
+// analyze fills the test keys with the results of running the checks of the embedded
+// telegram definition, where failed returns the operation that failed for the check with
+// the given component and target or the empty string, and then computes the verdicts.
+func analyze(t *testing.T, failed func(component, target string) string) *TestKeys {
+	def, err := servicedefs.Embedded(testName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failure := netxlite.FailureEOFError
+	var results []*servicereachability.CheckResult
+	for _, comp := range def.Components {
+		for _, check := range comp.Checks {
+			result := &servicereachability.CheckResult{
+				Component: comp.Name,
+				Target:    check.Target(),
+				Type:      check.Type,
+			}
+			if operation := failed(comp.Name, check.Target()); operation != "" {
+				result.FailedOperation = &operation
+				result.Failure = &failure
+			}
+			results = append(results, result)
+		}
+	}
+	tk := NewTestKeys()
+	tk.Checks = results
+	tk.Components = servicereachability.AnalyzeComponents(def, results)
+	tk.computeVerdicts()
+	return tk
+}
+
+func TestComputeVerdictsWithNoAccessPointsBlocking(t *testing.T) {
+	tk := analyze(t, func(component, target string) string {
+		if target == "http://149.154.175.50/" {
+			return netxlite.HTTPRoundTripOperation
+		}
+		return ""
+	})
+	if tk.TelegramHTTPBlocking == true {
+		t.Fatal("there should be no TelegramHTTPBlocking")
+	}
+	if tk.TelegramTCPBlocking == true {
+		t.Fatal("there should be no TelegramTCPBlocking")
+	}
+}
+
+func TestComputeVerdictsWithAllRoundTripsFailed(t *testing.T) {
+	tk := analyze(t, func(component, target string) string {
+		if component == "access_points" {
+			return netxlite.HTTPRoundTripOperation
+		}
+		return ""
+	})
+	if tk.TelegramHTTPBlocking == false {
+		t.Fatal("there should be TelegramHTTPBlocking")
+	}
+	if tk.TelegramTCPBlocking == true {
+		t.Fatal("there should be no TelegramTCPBlocking")
+	}
+}
+
+func TestComputeVerdictsWithNonConnectFailedOperation(t *testing.T) {
+	tk := analyze(t, func(component, target string) string {
+		switch {
+		case target == "http://149.154.175.50/":
+			return netxlite.ConnectOperation
+		case component == "access_points":
+			return netxlite.HTTPRoundTripOperation
+		default:
+			return ""
+		}
+	})
+	if tk.TelegramHTTPBlocking == false {
+		t.Fatal("there should be TelegramHTTPBlocking")
+	}
+	if tk.TelegramTCPBlocking == true {
+		t.Fatal("there should be no TelegramTCPBlocking")
+	}
+}
+
+func TestComputeVerdictsWithAllConnectsFailed(t *testing.T) {
+	tk := analyze(t, func(component, target string) string {
+		if component == "access_points" {
+			return netxlite.ConnectOperation
+		}
+		return ""
+	})
+	if tk.TelegramHTTPBlocking == false {
+		t.Fatal("there should be TelegramHTTPBlocking")
+	}
+	if tk.TelegramTCPBlocking == false {
+		t.Fatal("there should be TelegramTCPBlocking")
+	}
+}
+
+func TestComputeVerdictsWithWebFailure(t *testing.T) {
+	tk := analyze(t, func(component, target string) string {
+		if target == "https://web.telegram.org/" {
+			return netxlite.TLSHandshakeOperation
+		}
+		return ""
+	})
+	if tk.TelegramWebStatus != "blocked" {
+		t.Fatal("TelegramWebStatus should be blocked")
+	}
+	if tk.TelegramWebFailure == nil || *tk.TelegramWebFailure != netxlite.FailureEOFError {
+		t.Fatal("invalid TelegramWebFailure")
+	}
+}
+
+func TestComputeVerdictsWithAllGood(t *testing.T) {
+	tk := analyze(t, func(component, target string) string {
+		return ""
+	})
+	if tk.TelegramWebStatus != "ok" {
+		t.Fatal("TelegramWebStatus should be ok")
+	}
+	if tk.TelegramWebFailure != nil {
+		t.Fatal("invalid TelegramWebFailure")
+	}
+	if tk.TelegramHTTPBlocking || tk.TelegramTCPBlocking {
+		t.Fatal("there should be no blocking")
+	}
+}
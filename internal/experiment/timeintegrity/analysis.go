@@ -0,0 +1,81 @@
+package timeintegrity
+
+//
+// Analysis of the results
+//
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// These are the sources of the reference time.
+const (
+	clockSkewSourceHTTPSDate = "https_date"
+	clockSkewSourceNTP       = "ntp"
+	clockSkewSourceNTS       = "nts"
+)
+
+// analyze flags the unauthenticated servers whose time is too different from the
+// time according to the Date header as spoofed and computes the clock skew.
+func analyze(tk *TestKeys, tolerance time.Duration) {
+	var httpsOffset *float64
+	if tk.HTTPSDate != nil {
+		httpsOffset = tk.HTTPSDate.Offset
+	}
+	var ntsOffsets, ntpOffsets []float64
+	for _, srv := range tk.Servers {
+		if srv.Offset != nil && !srv.Authenticated && httpsOffset != nil &&
+			math.Abs(*srv.Offset-*httpsOffset) > tolerance.Seconds() {
+			srv.SpoofingEvidence = append(srv.SpoofingEvidence, SpoofingInconsistentWithHTTPSDate)
+		}
+		srv.Spoofed = len(srv.SpoofingEvidence) > 0
+		tk.SpoofedResponses = tk.SpoofedResponses || srv.Spoofed
+		if srv.Offset == nil || srv.Spoofed || !isSynchronized(srv) {
+			continue
+		}
+		if srv.Authenticated {
+			ntsOffsets = append(ntsOffsets, *srv.Offset)
+		} else {
+			ntpOffsets = append(ntpOffsets, *srv.Offset)
+		}
+	}
+
+	// Prefer authenticated time servers, then the Date header, which is authenticated but
+	// has a resolution of one second, then unauthenticated time servers. Note that the
+	// skew is the opposite of the offset because the offset is reference minus probe.
+	var (
+		offset float64
+		source string
+	)
+	switch {
+	case len(ntsOffsets) > 0:
+		offset, source = median(ntsOffsets), clockSkewSourceNTS
+	case httpsOffset != nil:
+		offset, source = *httpsOffset, clockSkewSourceHTTPSDate
+	case len(ntpOffsets) > 0:
+		offset, source = median(ntpOffsets), clockSkewSourceNTP
+	default:
+		return
+	}
+	skew := -offset
+	tk.ClockSkew, tk.ClockSkewSource = &skew, &source
+}
+
+// isSynchronized returns whether the server clock is synchronized.
+func isSynchronized(srv *Server) bool {
+	const leapAlarm, maxStratum = 3, 15
+	return srv.Leap != nil && *srv.Leap != leapAlarm && srv.Stratum != nil && *srv.Stratum <= maxStratum
+}
+
+// median returns the median of the given non-empty list of values.
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -0,0 +1,122 @@
+package timeintegrity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newAnalysisServer creates a synchronized [*Server] with the given offset.
+func newAnalysisServer(offset float64, authenticated bool) *Server {
+	leap, stratum := int64(0), int64(1)
+	return &Server{
+		Authenticated:    authenticated,
+		Leap:             &leap,
+		Offset:           &offset,
+		SpoofingEvidence: []string{},
+		Stratum:          &stratum,
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	type testcase struct {
+		name               string
+		httpsOffset        *float64
+		servers            []*Server
+		expectSkew         *float64
+		expectSource       *string
+		expectSpoofed      []bool
+		expectTKSpoofed    bool
+		expectServerReason [][]string
+	}
+	float := func(v float64) *float64 { return &v }
+	str := func(v string) *string { return &v }
+
+	unsynchronized := newAnalysisServer(100, true)
+	*unsynchronized.Leap = 3
+	kissOfDeath := &Server{SpoofingEvidence: []string{}}
+	originMismatch := newAnalysisServer(-3, false)
+	originMismatch.SpoofingEvidence = []string{SpoofingOriginMismatch}
+
+	cases := []testcase{{
+		name:               "without any reference time",
+		servers:            []*Server{kissOfDeath},
+		expectSpoofed:      []bool{false},
+		expectServerReason: [][]string{{}},
+	}, {
+		name:               "we prefer the median of the NTS servers",
+		httpsOffset:        float(1),
+		servers:            []*Server{newAnalysisServer(2, true), newAnalysisServer(4, true), newAnalysisServer(1.5, false)},
+		expectSkew:         float(-3),
+		expectSource:       str(clockSkewSourceNTS),
+		expectSpoofed:      []bool{false, false, false},
+		expectServerReason: [][]string{{}, {}, {}},
+	}, {
+		name:               "we use the Date header without NTS servers",
+		httpsOffset:        float(-120),
+		servers:            []*Server{unsynchronized, newAnalysisServer(-121, false)},
+		expectSkew:         float(120),
+		expectSource:       str(clockSkewSourceHTTPSDate),
+		expectSpoofed:      []bool{false, false},
+		expectServerReason: [][]string{{}, {}},
+	}, {
+		name:               "we use the NTP servers without the Date header",
+		servers:            []*Server{newAnalysisServer(1, false), newAnalysisServer(2, false), originMismatch},
+		expectSkew:         float(-1.5),
+		expectSource:       str(clockSkewSourceNTP),
+		expectSpoofed:      []bool{false, false, true},
+		expectTKSpoofed:    true,
+		expectServerReason: [][]string{{}, {}, {SpoofingOriginMismatch}},
+	}, {
+		name:            "we flag NTP servers inconsistent with the Date header",
+		httpsOffset:     float(0.5),
+		servers:         []*Server{newAnalysisServer(3600, false), newAnalysisServer(3600, true), newAnalysisServer(0.1, false)},
+		expectSkew:      float(-3600),
+		expectSource:    str(clockSkewSourceNTS),
+		expectSpoofed:   []bool{true, false, false},
+		expectTKSpoofed: true,
+		expectServerReason: [][]string{
+			{SpoofingInconsistentWithHTTPSDate}, {}, {},
+		},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tk := NewTestKeys()
+			tk.HTTPSDate = &HTTPSDate{Offset: tc.httpsOffset}
+			for _, srv := range tc.servers {
+				clone := *srv
+				clone.SpoofingEvidence = append([]string{}, srv.SpoofingEvidence...)
+				tk.Servers = append(tk.Servers, &clone)
+			}
+			analyze(tk, 5*time.Second)
+			if diff := cmp.Diff(tc.expectSkew, tk.ClockSkew); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.expectSource, tk.ClockSkewSource); diff != "" {
+				t.Fatal(diff)
+			}
+			if tk.SpoofedResponses != tc.expectTKSpoofed {
+				t.Fatal("unexpected SpoofedResponses", tk.SpoofedResponses)
+			}
+			for idx, srv := range tk.Servers {
+				if srv.Spoofed != tc.expectSpoofed[idx] {
+					t.Fatal("unexpected Spoofed for server", idx)
+				}
+				if diff := cmp.Diff(tc.expectServerReason[idx], srv.SpoofingEvidence); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+		})
+	}
+}
+
+func TestMedian(t *testing.T) {
+	if v := median([]float64{3, 1, 2}); v != 2 {
+		t.Fatal("unexpected median", v)
+	}
+	if v := median([]float64{4, 1, 3, 2}); v != 2.5 {
+		t.Fatal("unexpected median", v)
+	}
+}
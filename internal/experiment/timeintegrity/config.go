@@ -0,0 +1,141 @@
+package timeintegrity
+
+//
+// Config for the time_integrity experiment
+//
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/ntpx"
+	"github.com/ooni/probe-cli/v3/internal/probeservices"
+)
+
+// Config contains the experiment configuration.
+type Config struct {
+	// HTTPSDateURL is the URL whose Date header we use as the reference time. When
+	// empty, we use the first HTTPS probe services URL.
+	HTTPSDateURL string `ooni:"URL whose Date header we use as the reference time"`
+
+	// Servers is the space-separated list of time servers to query, where each
+	// server is either ntp://<host>[:<port>] or nts://<host>[:<port>]. The port of
+	// NTS servers is the NTS key establishment port. When empty, we query the
+	// default servers.
+	Servers string `ooni:"space-separated list of ntp:// and nts:// time servers to query"`
+
+	// Timeout is the timeout of each operation (in milliseconds).
+	Timeout int64 `ooni:"number of milliseconds after which an operation is considered timed out"`
+
+	// Tolerance is the maximum difference between the time of an unauthenticated
+	// time server and the time according to the Date header (in milliseconds).
+	Tolerance int64 `ooni:"number of milliseconds after which we consider the time of an unauthenticated server spoofed"`
+}
+
+// defaultServers contains the default time servers.
+var defaultServers = []string{
+	"nts://time.cloudflare.com",
+	"nts://nts.netnod.se",
+	"nts://ptbtime1.ptb.de",
+	"ntp://time.cloudflare.com",
+	"ntp://time.google.com",
+	"ntp://pool.ntp.org",
+}
+
+// errInvalidServer indicates that a time server is not a valid ntp:// or nts:// URL.
+var errInvalidServer = errors.New("timeintegrity: invalid time server")
+
+// server is a time server to query.
+type server struct {
+	// URL is the original server URL.
+	URL string
+
+	// host is the server host.
+	host string
+
+	// port is the server port.
+	port string
+
+	// protocol is either "ntp" or "nts".
+	protocol string
+}
+
+// address returns the server address using the given IP address.
+func (s *server) address(ipAddr string) string {
+	return net.JoinHostPort(ipAddr, s.port)
+}
+
+// parseServer parses an ntp:// or nts:// URL.
+func parseServer(value string) (*server, error) {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidServer, value)
+	}
+	var defaultPort int
+	switch parsed.Scheme {
+	case "ntp":
+		defaultPort = ntpx.Port
+	case "nts":
+		defaultPort = ntpx.NTSKEPort
+	default:
+		return nil, fmt.Errorf("%w: %s", errInvalidServer, value)
+	}
+	if parsed.Hostname() == "" || (parsed.Path != "" && parsed.Path != "/") {
+		return nil, fmt.Errorf("%w: %s", errInvalidServer, value)
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = strconv.Itoa(defaultPort)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidServer, value)
+	}
+	return &server{URL: value, host: parsed.Hostname(), port: port, protocol: parsed.Scheme}, nil
+}
+
+func (c *Config) servers() ([]*server, error) {
+	values := strings.Fields(c.Servers)
+	if len(values) <= 0 {
+		values = defaultServers
+	}
+	out := []*server{}
+	for _, value := range values {
+		srv, err := parseServer(value)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, srv)
+	}
+	return out, nil
+}
+
+func (c *Config) httpsDateURL() string {
+	if c.HTTPSDateURL != "" {
+		return c.HTTPSDateURL
+	}
+	for _, service := range probeservices.Default() {
+		if service.Type == "https" {
+			return service.Address
+		}
+	}
+	return "https://api.ooni.io"
+}
+
+func (c *Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Millisecond
+	}
+	return 10 * time.Second
+}
+
+func (c *Config) tolerance() time.Duration {
+	if c.Tolerance > 0 {
+		return time.Duration(c.Tolerance) * time.Millisecond
+	}
+	return 5 * time.Second
+}
@@ -0,0 +1,114 @@
+package timeintegrity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfigServers(t *testing.T) {
+	type testcase struct {
+		name      string
+		servers   string
+		expect    []*server
+		expectErr error
+	}
+	cases := []testcase{{
+		name:    "with NTP and NTS servers",
+		servers: "ntp://time.google.com nts://time.cloudflare.com:4461 ntp://192.0.2.1:1123/",
+		expect: []*server{{
+			URL:      "ntp://time.google.com",
+			host:     "time.google.com",
+			port:     "123",
+			protocol: "ntp",
+		}, {
+			URL:      "nts://time.cloudflare.com:4461",
+			host:     "time.cloudflare.com",
+			port:     "4461",
+			protocol: "nts",
+		}, {
+			URL:      "ntp://192.0.2.1:1123/",
+			host:     "192.0.2.1",
+			port:     "1123",
+			protocol: "ntp",
+		}},
+	}, {
+		name:      "with an unsupported scheme",
+		servers:   "ntp://time.google.com https://time.cloudflare.com",
+		expectErr: errInvalidServer,
+	}, {
+		name:      "with a missing host",
+		servers:   "ntp://:123",
+		expectErr: errInvalidServer,
+	}, {
+		name:      "with a path",
+		servers:   "nts://time.cloudflare.com/antani",
+		expectErr: errInvalidServer,
+	}, {
+		name:      "with an invalid port",
+		servers:   "ntp://time.google.com:65536",
+		expectErr: errInvalidServer,
+	}, {
+		name:      "with an invalid URL",
+		servers:   "ntp://[::1",
+		expectErr: errInvalidServer,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &Config{Servers: tc.servers}
+			servers, err := config.servers()
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if diff := cmp.Diff(tc.expect, servers, cmp.AllowUnexported(server{})); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+
+	t.Run("we use the default servers when the option is empty", func(t *testing.T) {
+		config := &Config{Servers: " "}
+		servers, err := config.servers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(servers) != len(defaultServers) {
+			t.Fatal("unexpected number of servers", len(servers))
+		}
+		for idx, srv := range servers {
+			if srv.URL != defaultServers[idx] {
+				t.Fatal("unexpected server", srv.URL)
+			}
+		}
+	})
+}
+
+func TestConfigDefaults(t *testing.T) {
+	t.Run("with the zero value", func(t *testing.T) {
+		config := &Config{}
+		if config.httpsDateURL() != "https://api.ooni.io" {
+			t.Fatal("unexpected URL", config.httpsDateURL())
+		}
+		if config.timeout() != 10*time.Second {
+			t.Fatal("unexpected timeout", config.timeout())
+		}
+		if config.tolerance() != 5*time.Second {
+			t.Fatal("unexpected tolerance", config.tolerance())
+		}
+	})
+
+	t.Run("with custom values", func(t *testing.T) {
+		config := &Config{HTTPSDateURL: "https://example.com/", Timeout: 1500, Tolerance: 2000}
+		if config.httpsDateURL() != "https://example.com/" {
+			t.Fatal("unexpected URL", config.httpsDateURL())
+		}
+		if config.timeout() != 1500*time.Millisecond {
+			t.Fatal("unexpected timeout", config.timeout())
+		}
+		if config.tolerance() != 2*time.Second {
+			t.Fatal("unexpected tolerance", config.tolerance())
+		}
+	})
+}
@@ -0,0 +1,24 @@
+// Package timeintegrity implements the time_integrity experiment.
+//
+// A wrong device clock causes TLS certificate validation failures that look like
+// censorship, and some networks block or tamper with time synchronization. This
+// experiment queries a set of time servers using NTPv4 over UDP and Network Time
+// Security (NTS), which uses a TLS-protected key establishment over TCP followed by
+// authenticated NTPv4 over UDP. For each server, we record the clock offset, the
+// round-trip delay, the stratum, and the failures, if any.
+//
+// In parallel, we fetch the Date header of the probe services using HTTPS, which
+// provides a coarse but authenticated reference time. We consider spoofed the
+// responses whose origin timestamp does not match our request, the NTS responses
+// we cannot authenticate, and the unauthenticated NTPv4 responses whose time is
+// too different from the time according to the Date header.
+//
+// Finally, we compute the clock skew using, in order of preference, authenticated
+// NTS servers, the Date header, and NTPv4 servers, and we add the clock skew to the
+// measurement annotations. We also store the clock skew in the session key-value
+// store, from which webconnectivitylte reads it to annotate its measurements, so
+// that analysts can discard certificate validity failures caused by a wrong clock.
+//
+// This experiment does not take any input. Use the Servers option to change the
+// time servers to query using ntp://<host>[:<port>] and nts://<host>[:<port>] URLs.
+package timeintegrity
@@ -0,0 +1,166 @@
+package timeintegrity
+
+//
+// Measurer
+//
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/clockskew"
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+const (
+	testName    = "time_integrity"
+	testVersion = "0.1.0"
+)
+
+// These are the annotations we add to the measurement when we know the clock skew.
+const (
+	// AnnotationClockSkew is the clock skew in seconds (see [TestKeys.ClockSkew]).
+	AnnotationClockSkew = clockskew.AnnotationClockSkew
+
+	// AnnotationClockSkewSource is the source of the reference time (see [TestKeys.ClockSkewSource]).
+	AnnotationClockSkewSource = clockskew.AnnotationClockSkewSource
+)
+
+// Measurer performs the measurement.
+type Measurer struct {
+	config Config
+}
+
+// ExperimentName implements ExperimentMeasurer.ExperimentName.
+func (m *Measurer) ExperimentName() string {
+	return testName
+}
+
+// ExperimentVersion implements ExperimentMeasurer.ExperimentVersion.
+func (m *Measurer) ExperimentVersion() string {
+	return testVersion
+}
+
+// Run implements ExperimentMeasurer.Run.
+func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	_ = args.Callbacks
+	measurement := args.Measurement
+	sess := args.Session
+	servers, err := m.config.servers()
+	if err != nil {
+		return err
+	}
+	registerExtensions(measurement)
+	tk := NewTestKeys()
+	measurement.TestKeys = tk
+	zeroTime := measurement.MeasurementStartTimeSaved
+	logger := sess.Logger()
+
+	// 1. query all the time servers and fetch the Date header in parallel
+	wg := new(sync.WaitGroup)
+	for idx, srv := range servers {
+		out := &Server{
+			Protocol:         srv.protocol,
+			Server:           srv.URL,
+			SpoofingEvidence: []string{},
+		}
+		tk.Servers = append(tk.Servers, out)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.measureServer(ctx, int64(idx+1), zeroTime, logger, srv, out)
+		}()
+	}
+	tk.HTTPSDate = &HTTPSDate{URL: m.config.httpsDateURL()}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.measureHTTPSDate(ctx, int64(len(servers)+1), zeroTime, logger, tk.HTTPSDate)
+	}()
+	wg.Wait()
+
+	// 2. analyze the results and annotate the measurement with the clock skew
+	analyze(tk, m.config.tolerance())
+	if tk.ClockSkew != nil {
+		logger.Infof("time_integrity: clock skew %.3f s (source: %s)", *tk.ClockSkew, *tk.ClockSkewSource)
+		clockskew.Annotate(measurement, *tk.ClockSkew, *tk.ClockSkewSource)
+		measurerStoreClockSkew(sess, *tk.ClockSkew, *tk.ClockSkewSource)
+	}
+	return nil // we want to submit this measurement
+}
+
+// measurerStoreClockSkew stores the clock skew into the session key-value store, so
+// that other experiments can annotate their measurements.
+func measurerStoreClockSkew(sess model.ExperimentSession, skew float64, source string) {
+	if err := clockskew.Store(sess.KeyValueStore(), skew, source); err != nil {
+		sess.Logger().Warnf("time_integrity: cannot store the clock skew: %s", err.Error())
+	}
+}
+
+// measureServer queries the given time server and saves the results into out.
+func (m *Measurer) measureServer(ctx context.Context, index int64, zeroTime time.Time,
+	logger model.Logger, srv *server, out *Server) {
+	r := &runner{
+		config: &m.config,
+		logger: logger,
+		trace:  measurexlite.NewTrace(index, zeroTime),
+	}
+	ol := logx.NewOperationLogger(logger, "time_integrity #%d %s", index, srv.URL)
+	var (
+		operation string
+		err       error
+	)
+	switch srv.protocol {
+	case "nts":
+		operation, err = r.nts(ctx, srv, out)
+	default:
+		operation, err = r.ntp(ctx, srv, out)
+	}
+	ol.Stop(err)
+	out.NetworkEvents = append(out.NetworkEvents, r.trace.NetworkEvents()...)
+	out.Queries = append(out.Queries, r.trace.DNSLookupsFromRoundTrip()...)
+	out.TCPConnect = append(out.TCPConnect, r.trace.TCPConnects()...)
+	out.TLSHandshakes = append(out.TLSHandshakes, r.trace.TLSHandshakes()...)
+	if err != nil {
+		out.FailedOperation = &operation
+		out.Failure = measurexlite.NewFailure(err)
+	}
+}
+
+// measureHTTPSDate fetches the Date header and saves the results into out.
+func (m *Measurer) measureHTTPSDate(ctx context.Context, index int64, zeroTime time.Time,
+	logger model.Logger, out *HTTPSDate) {
+	r := &runner{
+		config: &m.config,
+		logger: logger,
+		trace:  measurexlite.NewTrace(index, zeroTime),
+	}
+	ol := logx.NewOperationLogger(logger, "time_integrity #%d GET %s", index, out.URL)
+	operation, err := r.httpsDate(ctx, out.URL, out)
+	ol.Stop(err)
+	out.NetworkEvents = append(out.NetworkEvents, r.trace.NetworkEvents()...)
+	out.Queries = append(out.Queries, r.trace.DNSLookupsFromRoundTrip()...)
+	out.TCPConnect = append(out.TCPConnect, r.trace.TCPConnects()...)
+	out.TLSHandshakes = append(out.TLSHandshakes, r.trace.TLSHandshakes()...)
+	if err != nil {
+		out.FailedOperation = &operation
+		out.Failure = measurexlite.NewFailure(err)
+	}
+}
+
+// registerExtensions registers the data format extensions we use.
+func registerExtensions(m *model.Measurement) {
+	model.ArchivalExtDNS.AddTo(m)
+	model.ArchivalExtNetevents.AddTo(m)
+	model.ArchivalExtHTTP.AddTo(m)
+	model.ArchivalExtTCPConnect.AddTo(m)
+	model.ArchivalExtTLSHandshake.AddTo(m)
+}
+
+// NewExperimentMeasurer creates a new ExperimentMeasurer.
+func NewExperimentMeasurer(config Config) *Measurer {
+	return &Measurer{config: config}
+}
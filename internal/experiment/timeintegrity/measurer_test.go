@@ -0,0 +1,334 @@
+package timeintegrity
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/clockskew"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestMeasurerExperimentNameVersion(t *testing.T) {
+	measurer := NewExperimentMeasurer(Config{})
+	if measurer.ExperimentName() != "time_integrity" {
+		t.Fatal("unexpected ExperimentName")
+	}
+	if measurer.ExperimentVersion() != "0.1.0" {
+		t.Fatal("unexpected ExperimentVersion")
+	}
+}
+
+func runHelper(config Config) (*model.Measurement, error) {
+	return runHelperWithStore(config, &kvstore.Memory{})
+}
+
+func runHelperWithStore(config Config, kvStore model.KeyValueStore) (*model.Measurement, error) {
+	m := NewExperimentMeasurer(config)
+	meas := &model.Measurement{}
+	sess := &mocks.Session{
+		MockLogger: func() model.Logger {
+			return model.DiscardLogger
+		},
+		MockKeyValueStore: func() model.KeyValueStore {
+			return kvStore
+		},
+	}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+		Measurement: meas,
+		Session:     sess,
+	}
+	err := m.Run(context.Background(), args)
+	return meas, err
+}
+
+func TestMeasurer_invalid_servers(t *testing.T) {
+	meas, err := runHelper(Config{Servers: "sntp://time.google.com"})
+	if !errors.Is(err, errInvalidServer) {
+		t.Fatal("unexpected error", err)
+	}
+	if meas.TestKeys != nil {
+		t.Fatal("expected nil test keys")
+	}
+}
+
+// newTimeEnv creates a [*netemx.QAEnv] where time.cloudflare.com speaks NTS and NTPv4,
+// time.google.com speaks NTPv4, and api.ooni.io returns a Date header. The clockOffset
+// is the offset of all the servers clocks, which emulates a probe with a wrong clock,
+// while the lyingOffset is the additional offset of time.google.com.
+func newTimeEnv(clockOffset, lyingOffset time.Duration, ntpServer string) *netemx.QAEnv {
+	env := netemx.MustNewQAEnv(
+		netemx.QAEnvOptionNetStack(netemx.AddressTimeCloudflareCom, &netemx.NTPServerFactory{
+			NTPServer:        ntpServer,
+			Offset:           clockOffset,
+			ServerNameMain:   "time.cloudflare.com",
+			ServerNameExtras: []string{},
+		}),
+		netemx.QAEnvOptionNetStack(netemx.AddressTimeGoogleCom, &netemx.NTPServerFactory{
+			Offset:           clockOffset + lyingOffset,
+			ServerNameMain:   "time.google.com",
+			ServerNameExtras: []string{},
+		}),
+		netemx.QAEnvOptionNetStack(netemx.AddressApiOONIIo, &netemx.HTTPSecureServerFactory{
+			Factory: netemx.HTTPHandlerFactoryFunc(
+				func(env netemx.NetStackServerFactoryEnv, stack *netem.UNetStack) http.Handler {
+					return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Set("Date", time.Now().Add(clockOffset).UTC().Format(http.TimeFormat))
+						w.WriteHeader(http.StatusNotFound)
+					})
+				}),
+			Ports:            []int{443},
+			ServerNameMain:   "api.ooni.io",
+			ServerNameExtras: []string{},
+		}),
+	)
+	env.AddRecordToAllResolvers("time.cloudflare.com", "", netemx.AddressTimeCloudflareCom)
+	env.AddRecordToAllResolvers("time.google.com", "", netemx.AddressTimeGoogleCom)
+	env.AddRecordToAllResolvers("api.ooni.io", "", netemx.AddressApiOONIIo)
+	return env
+}
+
+func TestMeasurer_with_netem(t *testing.T) {
+	type serverExpectations struct {
+		authenticated   bool
+		failedOperation string
+		failure         string
+		kissCode        string
+		spoofed         bool
+	}
+	type testcase struct {
+		name          string
+		clockOffset   time.Duration
+		lyingOffset   time.Duration
+		ntpServer     string
+		rules         []netem.DPIRule
+		expectSkew    float64
+		expectSource  string
+		expectServers []serverExpectations
+		expectSpoofed bool
+	}
+	cases := []testcase{{
+		name:         "with a correct clock",
+		expectSkew:   0,
+		expectSource: clockSkewSourceNTS,
+		expectServers: []serverExpectations{
+			{authenticated: true},
+			{},
+		},
+	}, {
+		name:         "with a probe clock one hour behind",
+		clockOffset:  time.Hour,
+		expectSkew:   -3600,
+		expectSource: clockSkewSourceNTS,
+		expectServers: []serverExpectations{
+			{authenticated: true},
+			{},
+		},
+	}, {
+		name:         "with an NTP server lying about the time",
+		lyingOffset:  -24 * time.Hour,
+		expectSkew:   0,
+		expectSource: clockSkewSourceNTS,
+		expectServers: []serverExpectations{
+			{authenticated: true},
+			{spoofed: true},
+		},
+		expectSpoofed: true,
+	}, {
+		name:        "with NTS key establishment blocked",
+		lyingOffset: -24 * time.Hour,
+		rules: []netem.DPIRule{&netem.DPIDropTrafficForServerEndpoint{
+			Logger:          model.DiscardLogger,
+			ServerIPAddress: netemx.AddressTimeCloudflareCom,
+			ServerPort:      4460,
+			ServerProtocol:  layers.IPProtocolTCP,
+		}},
+		expectSkew:   0,
+		expectSource: clockSkewSourceHTTPSDate,
+		expectServers: []serverExpectations{
+			{failedOperation: netxlite.ConnectOperation, failure: netxlite.FailureGenericTimeoutError},
+			{spoofed: true},
+		},
+		expectSpoofed: true,
+	}, {
+		name: "with NTPv4 blocked",
+		rules: []netem.DPIRule{&netem.DPIDropTrafficForServerEndpoint{
+			Logger:          model.DiscardLogger,
+			ServerIPAddress: netemx.AddressTimeGoogleCom,
+			ServerPort:      123,
+			ServerProtocol:  layers.IPProtocolUDP,
+		}},
+		expectSkew:   0,
+		expectSource: clockSkewSourceNTS,
+		expectServers: []serverExpectations{
+			{authenticated: true},
+			{failedOperation: netxlite.ReadOperation, failure: netxlite.FailureGenericTimeoutError},
+		},
+	}, {
+		// time.google.com cannot decrypt the cookies issued by time.cloudflare.com
+		name:         "with an NTPv4 server that cannot decrypt the cookie",
+		ntpServer:    "time.google.com",
+		expectSkew:   0,
+		expectSource: clockSkewSourceHTTPSDate,
+		expectServers: []serverExpectations{
+			{failedOperation: NTPRoundTripOperation, failure: FailureNTPKissOfDeath, kissCode: "NTSN"},
+			{},
+		},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTimeEnv(tc.clockOffset, tc.lyingOffset, tc.ntpServer)
+			defer env.Close()
+			for _, rule := range tc.rules {
+				env.DPIEngine().AddRule(rule)
+			}
+
+			env.Do(func() {
+				config := Config{
+					Servers: "nts://time.cloudflare.com ntp://time.google.com",
+					Timeout: 1000,
+				}
+				kvStore := &kvstore.Memory{}
+				meas, err := runHelperWithStore(config, kvStore)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tk := meas.TestKeys.(*TestKeys)
+
+				if tk.HTTPSDate.Failure != nil || tk.HTTPSDate.Date == nil || len(tk.HTTPSDate.Requests) != 1 {
+					t.Fatal("unexpected HTTPS date result", tk.HTTPSDate.Failure)
+				}
+				if len(tk.Servers) != len(tc.expectServers) {
+					t.Fatal("unexpected number of servers", len(tk.Servers))
+				}
+				for idx, expect := range tc.expectServers {
+					srv := tk.Servers[idx]
+					if expect.failure == "" {
+						if srv.Failure != nil {
+							t.Fatal("unexpected failure", idx, *srv.Failure, *srv.FailedOperation)
+						}
+						if srv.Offset == nil || srv.RTT == nil || srv.Stratum == nil || *srv.Stratum != 1 {
+							t.Fatal("expected offset, RTT, and stratum", idx)
+						}
+					} else {
+						if srv.Failure == nil || *srv.Failure != expect.failure {
+							t.Fatal("unexpected failure", idx, srv.Failure)
+						}
+						if *srv.FailedOperation != expect.failedOperation {
+							t.Fatal("unexpected failed operation", idx, *srv.FailedOperation)
+						}
+					}
+					if srv.Authenticated != expect.authenticated {
+						t.Fatal("unexpected Authenticated", idx, srv.Authenticated)
+					}
+					if srv.Spoofed != expect.spoofed {
+						t.Fatal("unexpected Spoofed", idx, srv.SpoofingEvidence)
+					}
+					if expect.kissCode != "" && (srv.KissCode == nil || *srv.KissCode != expect.kissCode) {
+						t.Fatal("unexpected kiss code", idx, srv.KissCode)
+					}
+					if len(srv.NetworkEvents) <= 0 || len(srv.Queries) <= 0 {
+						t.Fatal("expected network events and DNS queries", idx)
+					}
+				}
+				if tk.Servers[0].NTSKE == nil || len(tk.Servers[0].TCPConnect) != 1 {
+					t.Fatal("expected the NTS key establishment results")
+				}
+				if tk.SpoofedResponses != tc.expectSpoofed {
+					t.Fatal("unexpected SpoofedResponses", tk.SpoofedResponses)
+				}
+
+				// The Date header has a resolution of one second
+				const tolerance = 1.5
+				if tk.ClockSkew == nil || math.Abs(*tk.ClockSkew-tc.expectSkew) > tolerance {
+					t.Fatal("unexpected clock skew", tk.ClockSkew)
+				}
+				if tk.ClockSkewSource == nil || *tk.ClockSkewSource != tc.expectSource {
+					t.Fatal("unexpected clock skew source", tk.ClockSkewSource)
+				}
+				skew, err := strconv.ParseFloat(meas.Annotations[AnnotationClockSkew], 64)
+				if err != nil || math.Abs(skew-tc.expectSkew) > tolerance {
+					t.Fatal("unexpected clock skew annotation", meas.Annotations)
+				}
+				if meas.Annotations[AnnotationClockSkewSource] != tc.expectSource {
+					t.Fatal("unexpected clock skew source annotation", meas.Annotations)
+				}
+				stored, err := clockskew.Get(kvStore)
+				if err != nil || stored.Skew != *tk.ClockSkew || stored.Source != tc.expectSource {
+					t.Fatal("unexpected stored clock skew", stored, err)
+				}
+			})
+		})
+	}
+
+	t.Run("without any reference time", func(t *testing.T) {
+		env := newTimeEnv(0, 0, "")
+		defer env.Close()
+		env.Do(func() {
+			config := Config{
+				HTTPSDateURL: "https://www.example.com/",
+				Servers:      "ntp://nonexistent.example.com",
+				Timeout:      1000,
+			}
+			kvStore := &kvstore.Memory{}
+			meas, err := runHelperWithStore(config, kvStore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tk := meas.TestKeys.(*TestKeys)
+			if tk.ClockSkew != nil || tk.ClockSkewSource != nil {
+				t.Fatal("expected no clock skew")
+			}
+			if *tk.Servers[0].FailedOperation != netxlite.ResolveOperation {
+				t.Fatal("unexpected failed operation", *tk.Servers[0].FailedOperation)
+			}
+			if *tk.HTTPSDate.FailedOperation != netxlite.ResolveOperation {
+				t.Fatal("unexpected failed operation", *tk.HTTPSDate.FailedOperation)
+			}
+			if len(meas.Annotations) != 0 {
+				t.Fatal("unexpected annotations", meas.Annotations)
+			}
+			if _, err := clockskew.Get(kvStore); !errors.Is(err, kvstore.ErrNoSuchKey) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	})
+}
+
+func TestMeasurerStoreClockSkew(t *testing.T) {
+	t.Run("when we cannot store the clock skew", func(t *testing.T) {
+		var warned bool
+		sess := &mocks.Session{
+			MockLogger: func() model.Logger {
+				return &mocks.Logger{
+					MockWarnf: func(format string, v ...any) {
+						warned = true
+					},
+				}
+			},
+			MockKeyValueStore: func() model.KeyValueStore {
+				return &mocks.KeyValueStore{
+					MockSet: func(key string, value []byte) error {
+						return errors.New("mocked error")
+					},
+				}
+			},
+		}
+		measurerStoreClockSkew(sess, 1, "ntp")
+		if !warned {
+			t.Fatal("expected a warning")
+		}
+	})
+}
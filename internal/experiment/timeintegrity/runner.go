@@ -0,0 +1,445 @@
+package timeintegrity
+
+//
+// Code to query the time servers and fetch the Date header
+//
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/ntpx"
+)
+
+// These are the failures specific to time synchronization, which we emit when a
+// time server or the probe services reply with something we cannot use.
+const (
+	// FailureHTTPInvalidDate indicates that the HTTP response
+	// does not contain a valid Date header.
+	FailureHTTPInvalidDate = "http_invalid_date"
+
+	// FailureNTPKissOfDeath indicates that the time server
+	// replied with a kiss-o'-death response.
+	FailureNTPKissOfDeath = "ntp_kiss_of_death"
+
+	// FailureNTSKEError indicates that the NTS key establishment
+	// server returned an error or an unusable response.
+	FailureNTSKEError = "ntske_error"
+)
+
+// These are the operations we emit in addition to the netxlite operations.
+const (
+	// NTPRoundTripOperation is the operation name for the NTP round trip.
+	NTPRoundTripOperation = "ntp_round_trip"
+
+	// NTSKEOperation is the operation name for the NTS key establishment.
+	NTSKEOperation = "ntske"
+)
+
+var (
+	errHTTPInvalidDate = &netxlite.ErrWrapper{
+		Failure:    FailureHTTPInvalidDate,
+		Operation:  netxlite.HTTPRoundTripOperation,
+		WrappedErr: errors.New("the response does not contain a valid date header"),
+	}
+
+	errNTPKissOfDeath = &netxlite.ErrWrapper{
+		Failure:    FailureNTPKissOfDeath,
+		Operation:  NTPRoundTripOperation,
+		WrappedErr: errors.New("the server replied with a kiss-o'-death response"),
+	}
+
+	errNTSKEError = &netxlite.ErrWrapper{
+		Failure:    FailureNTSKEError,
+		Operation:  NTSKEOperation,
+		WrappedErr: errors.New("the nts key establishment failed"),
+	}
+)
+
+// runner measures a single time server or the Date header.
+type runner struct {
+	config *Config
+	logger model.Logger
+	trace  *measurexlite.Trace
+}
+
+// ntsSession contains the result of the NTS key establishment.
+type ntsSession struct {
+	c2s     []byte
+	cookies [][]byte
+	s2c     []byte
+}
+
+// ntp queries an NTPv4 server. On failure, it returns the failed operation along with the error.
+func (r *runner) ntp(ctx context.Context, srv *server, out *Server) (string, error) {
+	addrs, operation, err := r.resolve(ctx, srv.host)
+	if err != nil {
+		return operation, err
+	}
+	out.Address = srv.address(addrs[0])
+	return r.query(ctx, out, nil)
+}
+
+// nts performs the NTS key establishment and then queries the NTPv4 server using
+// NTS. On failure, it returns the failed operation along with the error.
+func (r *runner) nts(ctx context.Context, srv *server, out *Server) (string, error) {
+	addrs, operation, err := r.resolve(ctx, srv.host)
+	if err != nil {
+		return operation, err
+	}
+	out.NTSKE = &NTSKE{}
+	session, operation, err := r.keyEstablishment(ctx, srv.host, srv.address(addrs[0]), out.NTSKE)
+	if err != nil {
+		return operation, err
+	}
+
+	// use the NTPv4 server negotiated by the server, if any
+	host, port := srv.host, strconv.Itoa(ntpx.Port)
+	if out.NTSKE.Server != "" {
+		host = out.NTSKE.Server
+	}
+	if out.NTSKE.Port != 0 {
+		port = strconv.FormatInt(out.NTSKE.Port, 10)
+	}
+	if host != srv.host {
+		if addrs, operation, err = r.resolve(ctx, host); err != nil {
+			return operation, err
+		}
+	}
+	out.Address = net.JoinHostPort(addrs[0], port)
+	return r.query(ctx, out, session)
+}
+
+// keyEstablishment performs the NTS key establishment with the server at the given address.
+func (r *runner) keyEstablishment(
+	ctx context.Context, serverName, address string, out *NTSKE) (*ntsSession, string, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		NextProtos: []string{ntpx.NTSKEALPN},
+		ServerName: serverName,
+	}
+	tlsConn, operation, err := r.tlsDial(ctx, address, config)
+	if err != nil {
+		return nil, operation, err
+	}
+	defer tlsConn.Close()
+	_ = tlsConn.SetDeadline(time.Now().Add(r.config.timeout()))
+
+	if _, err := tlsConn.Write(ntpx.MarshalRecords(ntpx.NewKERequest()...)); err != nil {
+		return nil, netxlite.WriteOperation, err
+	}
+	records, err := ntpx.ReadRecords(tlsConn)
+	if err != nil {
+		return nil, netxlite.ReadOperation, err
+	}
+
+	session := &ntsSession{}
+	for _, record := range records {
+		switch record.Type {
+		case ntpx.RecordNextProtocol:
+			out.NextProtocol = firstUint16(record)
+		case ntpx.RecordAEADAlgorithm:
+			out.AEADAlgorithm = firstUint16(record)
+		case ntpx.RecordError:
+			out.Error = firstUint16(record)
+		case ntpx.RecordNewCookie:
+			session.cookies = append(session.cookies, record.Body)
+		case ntpx.RecordNTPv4ServerNegotiation:
+			out.Server = string(record.Body)
+		case ntpx.RecordNTPv4PortNegotiation:
+			if port := firstUint16(record); port != nil {
+				out.Port = *port
+			}
+		case ntpx.RecordWarning:
+			// nothing
+		default:
+			if record.Critical {
+				return nil, NTSKEOperation, errNTSKEError
+			}
+		}
+	}
+	out.Cookies = int64(len(session.cookies))
+	if out.Error != nil || out.NextProtocol == nil || *out.NextProtocol != ntpx.NextProtocolNTPv4 ||
+		out.AEADAlgorithm == nil || *out.AEADAlgorithm != ntpx.AEADAESSIVCMAC256 || len(session.cookies) <= 0 {
+		return nil, NTSKEOperation, errNTSKEError
+	}
+
+	state := tlsConn.ConnectionState()
+	if session.c2s, session.s2c, err = ntpx.ExportKeys(&state); err != nil {
+		return nil, NTSKEOperation, err
+	}
+	return session, "", nil
+}
+
+// firstUint16 returns the first uint16 value contained by the record or nil.
+func firstUint16(record *ntpx.Record) *int64 {
+	values, err := record.Uint16s()
+	if err != nil || len(values) <= 0 {
+		return nil
+	}
+	value := int64(values[0])
+	return &value
+}
+
+// query sends an NTPv4 request, using NTS when session is not nil, and reads responses until
+// it receives a valid one, saving evidence of the spoofed responses it received, if any.
+func (r *runner) query(ctx context.Context, out *Server, session *ntsSession) (string, error) {
+	conn, operation, err := r.udpDial(ctx, out.Address)
+	if err != nil {
+		return operation, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(r.config.timeout()))
+
+	// create and send the request
+	request := &ntpx.Packet{
+		Version: ntpx.Version,
+		Mode:    ntpx.ModeClient,
+	}
+	var uid []byte
+	if session != nil {
+		uid = make([]byte, 32)
+		if _, err := rand.Read(uid); err != nil {
+			return NTPRoundTripOperation, err
+		}
+		request.Extensions = []ntpx.Extension{
+			{Type: ntpx.ExtensionUniqueIdentifier, Value: uid},
+			{Type: ntpx.ExtensionCookie, Value: session.cookies[0]},
+		}
+	}
+	sent := time.Now()
+	request.TransmitTime = ntpx.NewTimestamp(sent)
+	data := request.Marshal()
+	if session != nil {
+		if data, err = ntpx.SealPacket(request, session.c2s, nil); err != nil {
+			return NTPRoundTripOperation, err
+		}
+	}
+	if _, err := conn.Write(data); err != nil {
+		return netxlite.WriteOperation, err
+	}
+
+	// read responses until we receive a valid one
+	buffer := make([]byte, 1<<12)
+	for {
+		count, err := conn.Read(buffer)
+		if err != nil {
+			return netxlite.ReadOperation, err
+		}
+		received := time.Now()
+		response, evidence := verifyResponse(buffer[:count], request, uid, session)
+		if evidence != "" && !slices.Contains(out.SpoofingEvidence, evidence) {
+			out.SpoofingEvidence = append(out.SpoofingEvidence, evidence)
+		}
+		if response == nil {
+			continue
+		}
+		return r.saveResponse(response, sent, received, out)
+	}
+}
+
+// verifyResponse returns the response if it is valid or evidence of spoofing, if any.
+func verifyResponse(data []byte, request *ntpx.Packet, uid []byte, session *ntsSession) (*ntpx.Packet, string) {
+	if session == nil {
+		response, err := ntpx.ParsePacket(data)
+		if err != nil || response.Mode != ntpx.ModeServer {
+			return nil, ""
+		}
+		if response.OriginTime != request.TransmitTime {
+			return nil, SpoofingOriginMismatch
+		}
+		return response, ""
+	}
+
+	response, _, err := ntpx.OpenPacket(data, session.s2c)
+	if err != nil {
+		// the server sends an unauthenticated kiss-o'-death response containing
+		// the unique identifier when it cannot decrypt the cookie
+		unauthenticated, err := ntpx.ParsePacket(data)
+		if err != nil || unauthenticated.Mode != ntpx.ModeServer {
+			return nil, ""
+		}
+		if unauthenticated.OriginTime != request.TransmitTime {
+			return nil, SpoofingOriginMismatch
+		}
+		ext := unauthenticated.FindExtension(ntpx.ExtensionUniqueIdentifier)
+		if unauthenticated.KissCode() == "NTSN" && ext != nil && bytes.Equal(ext.Value, uid) {
+			return unauthenticated, ""
+		}
+		return nil, SpoofingNTSAuthenticationFailed
+	}
+	if ext := response.FindExtension(ntpx.ExtensionUniqueIdentifier); ext == nil || !bytes.Equal(ext.Value, uid) {
+		return nil, SpoofingUniqueIdentifierMismatch
+	}
+	if response.OriginTime != request.TransmitTime {
+		return nil, SpoofingOriginMismatch
+	}
+	return response, ""
+}
+
+// saveResponse saves the response and computes the offset and the round-trip delay.
+func (r *runner) saveResponse(response *ntpx.Packet, sent, received time.Time, out *Server) (string, error) {
+	leap, stratum := int64(response.Leap), int64(response.Stratum)
+	out.Leap, out.Stratum = &leap, &stratum
+	if code := response.KissCode(); code != "" {
+		out.KissCode = &code
+		return NTPRoundTripOperation, errNTPKissOfDeath
+	}
+	out.Authenticated = response.FindExtension(ntpx.ExtensionUniqueIdentifier) != nil
+	refID := binary.BigEndian.AppendUint32(nil, response.ReferenceID)
+	referenceID := net.IP(refID).String()
+	if response.Stratum == 1 {
+		referenceID = string(bytes.TrimRight(refID, "\x00"))
+	}
+	out.ReferenceID = &referenceID
+
+	// see https://datatracker.ietf.org/doc/html/rfc5905#section-8
+	t2, t3 := response.ReceiveTime.Time(), response.TransmitTime.Time()
+	offset := (t2.Sub(sent) + t3.Sub(received)).Seconds() / 2
+	rtt := (received.Sub(sent) - t3.Sub(t2)).Seconds()
+	out.Offset, out.RTT = &offset, &rtt
+	return "", nil
+}
+
+// httpsDate fetches the Date header. On failure, it returns the failed operation along with the error.
+func (r *runner) httpsDate(ctx context.Context, URL string, out *HTTPSDate) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", URL, nil)
+	if err != nil {
+		return netxlite.TopLevelOperation, err
+	}
+	req.Header.Set("Accept", model.HTTPHeaderAccept)
+	req.Header.Set("Accept-Language", model.HTTPHeaderAcceptLanguage)
+	req.Header.Set("User-Agent", model.HTTPHeaderUserAgent)
+	addrs, operation, err := r.resolve(ctx, req.URL.Hostname())
+	if err != nil {
+		return operation, err
+	}
+	port := req.URL.Port()
+	if port == "" {
+		port = "443"
+	}
+	address := net.JoinHostPort(addrs[0], port)
+	config := &tls.Config{
+		// We use HTTP/1.1 because a single request does not benefit from HTTP/2.
+		NextProtos: []string{"http/1.1"},
+		ServerName: req.URL.Hostname(),
+	}
+	tlsConn, operation, err := r.tlsDial(ctx, address, config)
+	if err != nil {
+		return operation, err
+	}
+	defer tlsConn.Close()
+	txp := netxlite.NewHTTPTransportWithOptions(
+		r.logger, netxlite.NewNullDialer(), netxlite.NewSingleUseTLSDialer(tlsConn))
+	defer txp.CloseIdleConnections()
+
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout())
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	const maxbody = 1 << 13
+	sent := time.Now()
+	started := r.trace.TimeSince(r.trace.ZeroTime())
+	out.NetworkEvents = append(out.NetworkEvents, measurexlite.NewArchivalNetworkEvent(
+		r.trace.Index(), started, "http_transaction_start", "tcp", address, 0, nil, started))
+	resp, err := txp.RoundTrip(req)
+	received := time.Now()
+	var body []byte
+	if err == nil {
+		defer resp.Body.Close()
+		body, err = netxlite.StreamAllContext(ctx, io.LimitReader(resp.Body, maxbody))
+	}
+	finished := r.trace.TimeSince(r.trace.ZeroTime())
+	out.NetworkEvents = append(out.NetworkEvents, measurexlite.NewArchivalNetworkEvent(
+		r.trace.Index(), finished, "http_transaction_done", "tcp", address, 0, nil, finished))
+	out.Requests = append(out.Requests, measurexlite.NewArchivalHTTPRequestResult(
+		r.trace.Index(), started, "tcp", address, "http/1.1", txp.Network(),
+		req, resp, maxbody, body, err, finished))
+	if err != nil {
+		return netxlite.HTTPRoundTripOperation, err
+	}
+
+	value := resp.Header.Get("Date")
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return netxlite.HTTPRoundTripOperation, errHTTPInvalidDate
+	}
+	out.Date = &value
+
+	// The Date header has a resolution of one second, so we assume the server generated
+	// it halfway through that second and halfway through the HTTP round trip.
+	midpoint := sent.Add(received.Sub(sent) / 2)
+	offset := date.Add(500 * time.Millisecond).Sub(midpoint).Seconds()
+	rtt := received.Sub(sent).Seconds()
+	out.Offset, out.RTT = &offset, &rtt
+	return "", nil
+}
+
+// resolve resolves the domain, which is a no-op for IP addresses.
+func (r *runner) resolve(ctx context.Context, domain string) ([]string, string, error) {
+	if net.ParseIP(domain) != nil {
+		return []string{domain}, "", nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout())
+	defer cancel()
+	reso := r.trace.NewStdlibResolver(r.logger)
+	addrs, err := reso.LookupHost(ctx, domain)
+	if err != nil {
+		return nil, netxlite.ResolveOperation, err
+	}
+	return addrs, "", nil
+}
+
+// udpDial creates a connected UDP socket.
+func (r *runner) udpDial(ctx context.Context, address string) (net.Conn, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout())
+	defer cancel()
+	dialer := r.trace.NewDialerWithoutResolver(r.logger)
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, netxlite.ConnectOperation, err
+	}
+	return conn, "", nil
+}
+
+// tcpDial establishes a TCP connection.
+func (r *runner) tcpDial(ctx context.Context, address string) (net.Conn, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout())
+	defer cancel()
+	dialer := r.trace.NewDialerWithoutResolver(r.logger)
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, netxlite.ConnectOperation, err
+	}
+	return conn, "", nil
+}
+
+// tlsDial establishes a TCP connection and performs the TLS handshake.
+func (r *runner) tlsDial(ctx context.Context, address string, config *tls.Config) (model.TLSConn, string, error) {
+	tcpConn, operation, err := r.tcpDial(ctx, address)
+	if err != nil {
+		return nil, operation, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.config.timeout())
+	defer cancel()
+	handshaker := r.trace.NewTLSHandshakerStdlib(r.logger)
+	tlsConn, err := handshaker.Handshake(ctx, tcpConn, config)
+	if err != nil {
+		tcpConn.Close()
+		return nil, netxlite.TLSHandshakeOperation, err
+	}
+	return tlsConn, "", nil
+}
@@ -0,0 +1,125 @@
+package timeintegrity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/ntpx"
+)
+
+func TestVerifyResponse(t *testing.T) {
+	session := &ntsSession{
+		c2s:     bytes.Repeat([]byte{0x01}, ntpx.SIVKeyLength),
+		cookies: [][]byte{bytes.Repeat([]byte{0x02}, 64)},
+		s2c:     bytes.Repeat([]byte{0x03}, ntpx.SIVKeyLength),
+	}
+	uid := bytes.Repeat([]byte{0x04}, 32)
+	request := &ntpx.Packet{
+		Version:      ntpx.Version,
+		Mode:         ntpx.ModeClient,
+		TransmitTime: ntpx.NewTimestamp(time.Now()),
+	}
+
+	// newResponse creates a response to the request.
+	newResponse := func(origin ntpx.Timestamp, extensions ...ntpx.Extension) *ntpx.Packet {
+		return &ntpx.Packet{
+			Version:      ntpx.Version,
+			Mode:         ntpx.ModeServer,
+			Stratum:      1,
+			OriginTime:   origin,
+			ReceiveTime:  ntpx.NewTimestamp(time.Now()),
+			TransmitTime: ntpx.NewTimestamp(time.Now()),
+			Extensions:   extensions,
+		}
+	}
+
+	// seal seals the packet using the given key.
+	seal := func(p *ntpx.Packet, key []byte) []byte {
+		data, err := ntpx.SealPacket(p, key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	uidExtension := ntpx.Extension{Type: ntpx.ExtensionUniqueIdentifier, Value: uid}
+	otherUIDExtension := ntpx.Extension{Type: ntpx.ExtensionUniqueIdentifier, Value: make([]byte, 32)}
+	nak := newResponse(request.TransmitTime, uidExtension)
+	nak.Stratum = 0
+	nak.ReferenceID = binary.BigEndian.Uint32([]byte("NTSN"))
+
+	type testcase struct {
+		name           string
+		data           []byte
+		session        *ntsSession
+		expectValid    bool
+		expectEvidence string
+	}
+	cases := []testcase{{
+		name:        "NTPv4 with a valid response",
+		data:        newResponse(request.TransmitTime).Marshal(),
+		expectValid: true,
+	}, {
+		name:           "NTPv4 with the wrong origin timestamp",
+		data:           newResponse(request.TransmitTime + 1).Marshal(),
+		expectEvidence: SpoofingOriginMismatch,
+	}, {
+		name: "NTPv4 with a truncated response",
+		data: newResponse(request.TransmitTime).Marshal()[:ntpx.HeaderLength-1],
+	}, {
+		name: "NTPv4 with a client request",
+		data: request.Marshal(),
+	}, {
+		name:        "NTS with a valid response",
+		data:        seal(newResponse(request.TransmitTime, uidExtension), session.s2c),
+		session:     session,
+		expectValid: true,
+	}, {
+		name:           "NTS with an unauthenticated response",
+		data:           newResponse(request.TransmitTime, uidExtension).Marshal(),
+		session:        session,
+		expectEvidence: SpoofingNTSAuthenticationFailed,
+	}, {
+		name:           "NTS with a response authenticated using the wrong key",
+		data:           seal(newResponse(request.TransmitTime, uidExtension), session.c2s),
+		session:        session,
+		expectEvidence: SpoofingNTSAuthenticationFailed,
+	}, {
+		name:           "NTS with an unauthenticated response with the wrong origin timestamp",
+		data:           newResponse(request.TransmitTime+1, uidExtension).Marshal(),
+		session:        session,
+		expectEvidence: SpoofingOriginMismatch,
+	}, {
+		name:           "NTS with the wrong unique identifier",
+		data:           seal(newResponse(request.TransmitTime, otherUIDExtension), session.s2c),
+		session:        session,
+		expectEvidence: SpoofingUniqueIdentifierMismatch,
+	}, {
+		name:           "NTS with an authenticated response with the wrong origin timestamp",
+		data:           seal(newResponse(request.TransmitTime+1, uidExtension), session.s2c),
+		session:        session,
+		expectEvidence: SpoofingOriginMismatch,
+	}, {
+		name:        "NTS with a NAK",
+		data:        nak.Marshal(),
+		session:     session,
+		expectValid: true,
+	}, {
+		name: "NTS with a truncated response",
+		data: newResponse(request.TransmitTime).Marshal()[:ntpx.HeaderLength-1],
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, evidence := verifyResponse(tc.data, request, uid, tc.session)
+			if (response != nil) != tc.expectValid {
+				t.Fatal("unexpected response", response)
+			}
+			if evidence != tc.expectEvidence {
+				t.Fatal("unexpected evidence", evidence)
+			}
+		})
+	}
+}
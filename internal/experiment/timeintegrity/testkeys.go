@@ -0,0 +1,170 @@
+package timeintegrity
+
+import "github.com/ooni/probe-cli/v3/internal/model"
+
+// TestKeys contains the experiment results
+type TestKeys struct {
+	// ClockSkew is the difference between the probe clock and the reference
+	// time in seconds, where a positive value means that the probe clock is
+	// ahead. It is nil when we could not obtain any reference time.
+	ClockSkew *float64 `json:"clock_skew"`
+
+	// ClockSkewSource is the source of the reference time, which is either
+	// "nts", "https_date", or "ntp", in order of preference.
+	ClockSkewSource *string `json:"clock_skew_source"`
+
+	// HTTPSDate contains the result of fetching the Date header.
+	HTTPSDate *HTTPSDate `json:"https_date"`
+
+	// Servers contains the result of querying each time server.
+	Servers []*Server `json:"servers"`
+
+	// SpoofedResponses indicates that at least one time server response was spoofed.
+	SpoofedResponses bool `json:"spoofed_responses"`
+}
+
+// NewTestKeys creates new time_integrity TestKeys
+func NewTestKeys() *TestKeys {
+	return &TestKeys{
+		Servers: []*Server{},
+	}
+}
+
+// HTTPSDate contains the result of fetching the Date header using HTTPS.
+type HTTPSDate struct {
+	// Date is the value of the Date header or nil.
+	Date *string `json:"date"`
+
+	// FailedOperation is the operation that failed or nil.
+	FailedOperation *string `json:"failed_operation"`
+
+	// Failure is the failure that occurred or nil.
+	Failure *string `json:"failure"`
+
+	// NetworkEvents contains the network events.
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+
+	// Offset is the difference between the Date header and the probe clock
+	// in seconds, computed in the same way as the NTP offset, or nil.
+	Offset *float64 `json:"offset"`
+
+	// Queries contains the DNS lookups.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// Requests contains the HTTP request.
+	Requests []*model.ArchivalHTTPRequestResult `json:"requests"`
+
+	// RTT is the round-trip time of the HTTP transaction in seconds or nil.
+	RTT *float64 `json:"rtt"`
+
+	// TCPConnect contains the TCP connect results.
+	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect"`
+
+	// TLSHandshakes contains the TLS handshake results.
+	TLSHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
+
+	// URL is the URL we fetched.
+	URL string `json:"url"`
+}
+
+// Server contains the result of querying a time server.
+type Server struct {
+	// Address is the address of the NTPv4 server.
+	Address string `json:"address"`
+
+	// Authenticated indicates that we authenticated the response using NTS.
+	Authenticated bool `json:"authenticated"`
+
+	// FailedOperation is the operation that failed or nil.
+	FailedOperation *string `json:"failed_operation"`
+
+	// Failure is the failure that occurred or nil.
+	Failure *string `json:"failure"`
+
+	// KissCode is the kiss code of a kiss-o'-death response or nil.
+	KissCode *string `json:"kiss_code"`
+
+	// Leap is the leap indicator, where 3 means that the server clock is not synchronized.
+	Leap *int64 `json:"leap"`
+
+	// NetworkEvents contains the network events.
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+
+	// NTSKE contains the result of the NTS key establishment or nil.
+	NTSKE *NTSKE `json:"ntske"`
+
+	// Offset is the difference between the server clock and the probe
+	// clock in seconds as defined by RFC 5905, or nil.
+	Offset *float64 `json:"offset"`
+
+	// Protocol is either "ntp" or "nts".
+	Protocol string `json:"protocol"`
+
+	// Queries contains the DNS lookups.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// ReferenceID is the reference ID, which is a string for stratum 1 servers
+	// and an IPv4 address or a hash for other servers, or nil.
+	ReferenceID *string `json:"reference_id"`
+
+	// RTT is the round-trip delay in seconds as defined by RFC 5905, or nil.
+	RTT *float64 `json:"rtt"`
+
+	// Server is the time server URL (e.g., nts://time.cloudflare.com).
+	Server string `json:"server"`
+
+	// Spoofed indicates that we received spoofed responses.
+	Spoofed bool `json:"spoofed"`
+
+	// SpoofingEvidence explains why we consider the responses spoofed.
+	SpoofingEvidence []string `json:"spoofing_evidence"`
+
+	// Stratum is the server stratum or nil.
+	Stratum *int64 `json:"stratum"`
+
+	// TCPConnect contains the TCP connect results.
+	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect"`
+
+	// TLSHandshakes contains the TLS handshake results.
+	TLSHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
+}
+
+// NTSKE contains the result of the NTS key establishment.
+type NTSKE struct {
+	// AEADAlgorithm is the negotiated AEAD algorithm or nil.
+	AEADAlgorithm *int64 `json:"aead_algorithm"`
+
+	// Cookies is the number of cookies we received.
+	Cookies int64 `json:"cookies"`
+
+	// Error is the error code sent by the server or nil.
+	Error *int64 `json:"error"`
+
+	// NextProtocol is the negotiated next protocol or nil.
+	NextProtocol *int64 `json:"next_protocol"`
+
+	// Port is the NTPv4 port negotiated by the server or zero.
+	Port int64 `json:"port"`
+
+	// Server is the NTPv4 server negotiated by the server or an empty string.
+	Server string `json:"server"`
+}
+
+// These are the reasons why we consider time server responses spoofed.
+const (
+	// SpoofingInconsistentWithHTTPSDate means that the unauthenticated time
+	// is too different from the time according to the Date header.
+	SpoofingInconsistentWithHTTPSDate = "inconsistent_with_https_date"
+
+	// SpoofingNTSAuthenticationFailed means that we received a response we
+	// could not authenticate using NTS.
+	SpoofingNTSAuthenticationFailed = "nts_authentication_failed"
+
+	// SpoofingOriginMismatch means that we received a response whose
+	// origin timestamp does not match the request transmit timestamp.
+	SpoofingOriginMismatch = "origin_mismatch"
+
+	// SpoofingUniqueIdentifierMismatch means that we received an authenticated
+	// response whose NTS unique identifier does not match the request.
+	SpoofingUniqueIdentifierMismatch = "unique_identifier_mismatch"
+)
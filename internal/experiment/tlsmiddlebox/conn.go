@@ -7,6 +7,7 @@ package tlsmiddlebox
 import (
 	"errors"
 	"net"
+	"strings"
 	"syscall"
 
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
@@ -14,8 +15,9 @@ import (
 
 var errInvalidConnWrapper = errors.New("invalid conn wrapper")
 
-// setConnTTL calls SetTTL to set the TTL for a dialerTTLWrapperConn
-func setConnTTL(conn net.Conn, ttl int) error {
+// SetConnTTL calls SetTTL to set the TTL for a conn created by a dialer
+// returned by [NewDialerTTLWrapper] or [NewDialerTTLWrapperWithTTL]
+func SetConnTTL(conn net.Conn, ttl int) error {
 	ttlWrapper, ok := conn.(*dialerTTLWrapperConn)
 	if !ok {
 		return errInvalidConnWrapper
@@ -43,6 +45,24 @@ type dialerTTLWrapperConn struct {
 
 var _ net.Conn = &dialerTTLWrapperConn{}
 
+// SetTTL sets the IP TTL field for the underlying TCP or UDP conn
+func (c *dialerTTLWrapperConn) SetTTL(ttl int) error {
+	sysConn, ok := c.Conn.(syscall.Conn)
+	if !ok {
+		return errInvalidConnWrapper
+	}
+	rawConn, err := sysConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return setRawConnTTL(rawConn, isIPv6Endpoint(c.Conn.RemoteAddr().String()), ttl)
+}
+
+// isIPv6Endpoint returns whether the endpoint contains a quoted IPv6 address
+func isIPv6Endpoint(endpoint string) bool {
+	return strings.Contains(endpoint, "[")
+}
+
 // Read implements net.Conn.Read
 func (c *dialerTTLWrapperConn) Read(b []byte) (int, error) {
 	count, err := c.Conn.Read(b)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -107,7 +108,7 @@ func TestSetTTL(t *testing.T) {
 			t.Fatal("expected non-nil conn")
 		}
 		// test TTL set
-		err = setConnTTL(conn, 1)
+		err = SetConnTTL(conn, 1)
 		if err != nil {
 			t.Fatal("unexpected error in setting TTL", err)
 		}
@@ -120,7 +121,7 @@ func TestSetTTL(t *testing.T) {
 		if r != 0 {
 			t.Fatal("unexpected output size", r)
 		}
-		setConnTTL(conn, 64) // reset TTL to ensure conn closes successfully
+		SetConnTTL(conn, 64) // reset TTL to ensure conn closes successfully
 		conn.Close()
 		_, err = conn.Read(buf[:])
 		if err == nil || err.Error() != netxlite.FailureConnectionAlreadyClosed {
@@ -128,9 +129,34 @@ func TestSetTTL(t *testing.T) {
 		}
 	})
 
+	t.Run("with UDP conn", func(t *testing.T) {
+		pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pconn.Close()
+		d := NewDialerTTLWrapper()
+		conn, err := d.DialContext(context.Background(), "udp", pconn.LocalAddr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if err := SetConnTTL(conn, 1); err != nil {
+			t.Fatal("unexpected error in setting TTL", err)
+		}
+	})
+
+	t.Run("with conn not supporting syscalls", func(t *testing.T) {
+		conn := &dialerTTLWrapperConn{Conn: &mocks.Conn{}}
+		err := SetConnTTL(conn, 1)
+		if !errors.Is(err, errInvalidConnWrapper) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
 	t.Run("failure case", func(t *testing.T) {
 		conn := &mocks.Conn{}
-		err := setConnTTL(conn, 1)
+		err := SetConnTTL(conn, 1)
 		if !errors.Is(err, errInvalidConnWrapper) {
 			t.Fatal("unexpected error")
 		}
@@ -7,6 +7,7 @@ package tlsmiddlebox
 import (
 	"context"
 	"net"
+	"syscall"
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -15,12 +16,26 @@ import (
 
 const timeout time.Duration = 15 * time.Second
 
+// NewDialerTTLWrapper returns a dialer whose conns allow to set the TTL using [SetConnTTL]
 func NewDialerTTLWrapper() model.Dialer {
 	return &dialerTTLWrapper{
 		Dialer: &net.Dialer{Timeout: timeout},
 	}
 }
 
+// NewDialerTTLWrapperWithTTL is like [NewDialerTTLWrapper] but sets the TTL before
+// connecting, such that also the TCP SYN segments use the given TTL value
+func NewDialerTTLWrapperWithTTL(ttl int) model.Dialer {
+	return &dialerTTLWrapper{
+		Dialer: &net.Dialer{
+			Timeout: timeout,
+			Control: func(network, address string, rawConn syscall.RawConn) error {
+				return setRawConnTTL(rawConn, isIPv6Endpoint(address), ttl)
+			},
+		},
+	}
+}
+
 // dialerTTLWrapper wraps errors and also returns a TTL wrapped conn
 type dialerTTLWrapper struct {
 	Dialer model.SimpleDialer
@@ -52,3 +52,38 @@ func TestDialerWithTTL(t *testing.T) {
 		})
 	})
 }
+
+func TestNewDialerTTLWrapperWithTTL(t *testing.T) {
+	t.Run("we can dial using the given TTL", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		d := NewDialerTTLWrapperWithTTL(64)
+		conn, err := d.DialContext(context.Background(), "tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, ok := conn.(*dialerTTLWrapperConn); !ok {
+			t.Fatal("expected a TTL wrapped conn")
+		}
+	})
+
+	t.Run("we fail with an invalid TTL", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		d := NewDialerTTLWrapperWithTTL(-17)
+		conn, err := d.DialContext(context.Background(), "tcp", listener.Addr().String())
+		if err == nil {
+			t.Fatal("expected an error here")
+		}
+		if conn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+}
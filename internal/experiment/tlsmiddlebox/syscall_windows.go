@@ -7,24 +7,13 @@ package tlsmiddlebox
 //
 
 import (
-	"net"
-	"strings"
 	"syscall"
 )
 
-// SetTTL sets the IP TTL field for the underlying net.TCPConn
-func (c *dialerTTLWrapperConn) SetTTL(ttl int) error {
-	conn := c.Conn
-	tcpConn, ok := conn.(*net.TCPConn)
-	if !ok {
-		return errInvalidConnWrapper
-	}
-	rawConn, err := tcpConn.SyscallConn()
-	if err != nil {
-		return err
-	}
+// setRawConnTTL sets the IP TTL field (or the IPv6 hop limit) of the socket
+func setRawConnTTL(rawConn syscall.RawConn, isIPv6 bool, ttl int) error {
+	var err error
 	rawErr := rawConn.Control(func(fd uintptr) {
-		isIPv6 := strings.Contains(tcpConn.RemoteAddr().String(), "[")
 		if isIPv6 {
 			err = syscall.SetsockoptInt(syscall.Handle(fd), syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
 		} else {
@@ -38,15 +27,14 @@ func (c *dialerTTLWrapperConn) SetTTL(ttl int) error {
 	return rawErr
 }
 
-// GetSoErr fetches the SO_ERROR value at look for soft ICMP errors in TCP
+// GetSoErr fetches the SO_ERROR value at look for soft ICMP errors
 func (c *dialerTTLWrapperConn) GetSoErr() (int, error) {
 	var cErrno int
-	conn := c.Conn
-	tcpConn, ok := conn.(*net.TCPConn)
+	sysConn, ok := c.Conn.(syscall.Conn)
 	if !ok {
 		return 0, errInvalidConnWrapper
 	}
-	rawConn, err := tcpConn.SyscallConn()
+	rawConn, err := sysConn.SyscallConn()
 	if err != nil {
 		return 0, errInvalidConnWrapper
 	}
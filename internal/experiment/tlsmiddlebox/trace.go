@@ -3,7 +3,7 @@ package tlsmiddlebox
 import (
 	"sync"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -35,11 +35,11 @@ func newIterationFromHandshake(ttl int, err error, soErr error, handshake *model
 		return &Iteration{
 			TTL: ttl,
 			Handshake: &model.ArchivalTLSOrQUICHandshakeResult{
-				Failure: tracex.NewFailure(err),
+				Failure: measurexlite.NewFailure(err),
 			},
 		}
 	}
-	handshake.SoError = tracex.NewFailure(soErr)
+	handshake.SoError = measurexlite.NewFailure(soErr)
 	return &Iteration{
 		TTL:       ttl,
 		Handshake: handshake,
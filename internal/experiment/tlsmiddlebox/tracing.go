@@ -83,7 +83,7 @@ func (m *Measurer) handshakeWithTTL(ctx context.Context, index int64, zeroTime t
 	}
 	defer conn.Close()
 	// 2. Set the TTL to the passed value
-	err = setConnTTL(conn, ttl)
+	err = SetConnTTL(conn, ttl)
 	if err != nil {
 		iteration := newIterationFromHandshake(ttl, err, nil, nil)
 		tr.addIterations(iteration)
@@ -99,23 +99,23 @@ func (m *Measurer) handshakeWithTTL(ctx context.Context, index int64, zeroTime t
 	}
 	_, err = thx.Handshake(ctx, conn, genTLSConfig(sni))
 	ol.Stop(err)
-	soErr := extractSoError(conn)
+	soErr := ExtractSoError(conn, netxlite.TLSHandshakeOperation)
 	// 4. reset the TTL value to ensure that conn closes successfully
 	// Note: Do not check for errors here
-	_ = setConnTTL(conn, 64)
+	_ = SetConnTTL(conn, 64)
 	iteration := newIterationFromHandshake(ttl, nil, soErr, trace.FirstTLSHandshakeOrNil())
 	tr.addIterations(iteration)
 }
 
-// extractSoError fetches the SO_ERROR value and returns a non-nil error if
-// it qualifies as a valid ICMP soft error
+// ExtractSoError fetches the SO_ERROR value and returns a non-nil error if
+// it qualifies as a valid ICMP soft error for the given operation
 // Note: The passed conn must be of type dialerTTLWrapperConn
-func extractSoError(conn net.Conn) error {
+func ExtractSoError(conn net.Conn, operation string) error {
 	soErrno, err := getSoErr(conn)
 	if err != nil || errors.Is(soErrno, syscall.Errno(0)) {
 		return nil
 	}
-	soErr := netxlite.MaybeNewErrWrapper(netxlite.ClassifyGenericError, netxlite.TLSHandshakeOperation, soErrno)
+	soErr := netxlite.MaybeNewErrWrapper(netxlite.ClassifyGenericError, operation, soErrno)
 	return soErr
 }
 
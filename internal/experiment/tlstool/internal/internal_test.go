@@ -5,18 +5,20 @@ import (
 	"testing"
 
 	"github.com/ooni/probe-cli/v3/internal/experiment/tlstool/internal"
-	"github.com/ooni/probe-cli/v3/internal/legacy/netx"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
+var netx = &netxlite.Netx{}
+
 var config = internal.DialerConfig{
-	Dialer: netx.NewDialer(netx.Config{}),
+	Dialer: netx.NewDialerWithResolver(model.DiscardLogger, netx.NewStdlibResolver(model.DiscardLogger)),
 	Delay:  10,
 	SNI:    "dns.google",
 }
 
 func dial(t *testing.T, d model.Dialer) {
-	td := netx.NewTLSDialer(netx.Config{Dialer: d})
+	td := netxlite.NewTLSDialer(d, netx.NewTLSHandshakerStdlib(model.DiscardLogger))
 	conn, err := td.DialTLSContext(context.Background(), "tcp", "dns.google:853")
 	if err != nil {
 		t.Fatal(err)
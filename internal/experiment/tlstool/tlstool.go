@@ -13,18 +13,19 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"net/http"
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/experiment/tlstool/internal"
-	"github.com/ooni/probe-cli/v3/internal/legacy/netx"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 )
 
 const (
 	testName    = "tlstool"
-	testVersion = "0.1.0"
+	testVersion = "0.2.0"
 )
 
 // Config contains the experiment configuration.
@@ -94,13 +95,13 @@ func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		// if possible and collect standard test keys.
 		err := m.run(ctx, runConfig{
 			address:   address,
-			logger:    sess.Logger(),
+			logger:    model.ValidLoggerOrDefault(sess.Logger()),
 			newDialer: meth.newDialer,
 		})
 		percent := float64(idx) / float64(len(allMethods))
 		callbacks.OnProgress(percent, fmt.Sprintf("%s: %+v", meth.name, err))
 		tk.Experiment[meth.name] = &ExperimentKeys{
-			Failure: tracex.NewFailure(err),
+			Failure: measurexlite.NewFailure(err),
 		}
 	}
 	return nil // return nil so we always submit the measurement
@@ -109,10 +110,12 @@ func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 func (m Measurer) newDialer(logger model.Logger) model.Dialer {
 	// TODO(bassosimone): this is a resolver that should hopefully work
 	// in many places. Maybe allow to configure it?
-	resolver, err := netx.NewDNSClientWithOverrides(netx.Config{Logger: logger},
-		"https://cloudflare.com/dns-query", "dns.cloudflare.com", "", "")
-	runtimex.PanicOnError(err, "cannot initialize resolver")
-	return netx.NewDialer(netx.Config{FullResolver: resolver, Logger: logger})
+	netx := &netxlite.Netx{}
+	client := &http.Client{Transport: netx.NewHTTPTransportStdlib(logger)}
+	txp := netxlite.NewDNSOverHTTPSTransportWithHostOverride(
+		client, "https://cloudflare.com/dns-query", "dns.cloudflare.com")
+	resolver := netxlite.WrapResolver(logger, netxlite.NewUnwrappedParallelResolver(txp))
+	return netx.NewDialerWithResolver(logger, resolver)
 }
 
 type runConfig struct {
@@ -127,11 +130,9 @@ func (m Measurer) run(ctx context.Context, config runConfig) error {
 		Delay:  time.Duration(m.config.Delay) * time.Millisecond,
 		SNI:    m.pattern(config.address),
 	})
-	tdialer := netx.NewTLSDialer(netx.Config{
-		Dialer:    dialer,
-		Logger:    config.logger,
-		TLSConfig: m.tlsConfig(),
-	})
+	netx := &netxlite.Netx{}
+	tdialer := netxlite.NewTLSDialerWithConfig(
+		dialer, netx.NewTLSHandshakerStdlib(config.logger), m.tlsConfig())
 	conn, err := tdialer.DialTLSContext(ctx, "tcp", config.address)
 	if err != nil {
 		return err
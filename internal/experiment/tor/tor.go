@@ -13,7 +13,6 @@ import (
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/legacy/measurex"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/logx"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
@@ -29,7 +28,7 @@ const (
 	testName = "tor"
 
 	// testVersion is the version of this experiment
-	testVersion = "0.4.0"
+	testVersion = "0.4.1"
 )
 
 // Config contains the experiment config.
@@ -62,11 +61,11 @@ type TargetResults struct {
 }
 
 func registerExtensions(m *model.Measurement) {
-	tracex.ExtHTTP.AddTo(m)
-	tracex.ExtNetevents.AddTo(m)
-	tracex.ExtDNS.AddTo(m)
-	tracex.ExtTCPConnect.AddTo(m)
-	tracex.ExtTLSHandshake.AddTo(m)
+	model.ArchivalExtHTTP.AddTo(m)
+	model.ArchivalExtNetevents.AddTo(m)
+	model.ArchivalExtDNS.AddTo(m)
+	model.ArchivalExtTCPConnect.AddTo(m)
+	model.ArchivalExtTLSHandshake.AddTo(m)
 }
 
 // fillSummary fills the Summary field used by the UI.
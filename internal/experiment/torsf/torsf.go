@@ -11,7 +11,7 @@ import (
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/bytecounter"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/ptx"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
@@ -25,7 +25,7 @@ import (
 // We may want to have a single implementation for both nettests in the future.
 
 // testVersion is the experiment version.
-const testVersion = "0.5.1"
+const testVersion = "0.5.2"
 
 // Config contains the experiment config.
 type Config struct {
@@ -240,8 +240,8 @@ func (m *Measurer) bootstrap(ctx context.Context, timeout time.Duration, sess mo
 	tk.TorVersion = debugInfo.Version
 	m.readTorLogs(sess.Logger(), tk, debugInfo.LogFilePath)
 	if err != nil {
-		// Note: tracex.NewFailure scrubs IP addresses
-		tk.Failure = tracex.NewFailure(err)
+		// Note: measurexlite.NewFailure scrubs IP addresses
+		tk.Failure = measurexlite.NewFailure(err)
 		if errors.Is(err, context.DeadlineExceeded) {
 			tk.Error = &timeoutReachedError
 		} else {
@@ -0,0 +1,311 @@
+package urlgetter
+
+//
+// Archival data format
+//
+// We produce the same archival data format that urlgetter produced when it
+// was based on the legacy tracex package (see ./testdata/golden). To this end,
+// we convert what measurexlite collects to the legacy format and we record
+// the events measurexlite does not know about: resolve and DNS round trip
+// events with their failure, DNS queries with one entry per address family,
+// UDP connect events, and the DNS-over-HTTPS HTTP transactions.
+//
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/geoipx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// archivalNetworkEvent converts a network event collected by measurexlite to
+// the legacy format, where only connect, read_from and write_to events have an
+// address and only connect events have a protocol. The failures argument maps each
+// address to the failures of the TLS handshakes with such an address, which we
+// consume to set the failure of tls_handshake_done events, because measurexlite
+// does not set it while the legacy format did.
+func archivalNetworkEvent(ev *model.ArchivalNetworkEvent, failures map[string][]*string) model.ArchivalNetworkEvent {
+	out := model.ArchivalNetworkEvent{
+		Failure:   ev.Failure,
+		NumBytes:  ev.NumBytes,
+		Operation: ev.Operation,
+		T:         ev.T,
+	}
+	switch ev.Operation {
+	case netxlite.ConnectOperation:
+		out.Address = ev.Address
+		out.Proto = ev.Proto
+	case netxlite.ReadFromOperation, netxlite.WriteToOperation:
+		out.Address = ev.Address
+	case "tls_handshake_done":
+		if entries := failures[ev.Address]; len(entries) > 0 {
+			out.Failure, failures[ev.Address] = entries[0], entries[1:]
+		}
+	}
+	return out
+}
+
+// archivalTLSHandshakeFailures returns the failures argument for archivalNetworkEvent.
+func archivalTLSHandshakeFailures(handshakes []*model.ArchivalTLSOrQUICHandshakeResult) map[string][]*string {
+	failures := make(map[string][]*string)
+	for _, entry := range handshakes {
+		failures[entry.Address] = append(failures[entry.Address], entry.Failure)
+	}
+	return failures
+}
+
+// archivalTCPConnect converts a TCP connect result collected by measurexlite to the
+// legacy format, which had neither t0, nor tags, nor the transaction ID.
+func archivalTCPConnect(ev *model.ArchivalTCPConnectResult) model.ArchivalTCPConnectResult {
+	return model.ArchivalTCPConnectResult{
+		IP:     ev.IP,
+		Port:   ev.Port,
+		Status: ev.Status,
+		T:      ev.T,
+	}
+}
+
+// archivalTLSHandshake is like archivalTCPConnect for TLS and QUIC handshakes. The
+// legacy format had no network and no peer certificates when there were none.
+func archivalTLSHandshake(ev *model.ArchivalTLSOrQUICHandshakeResult) model.ArchivalTLSOrQUICHandshakeResult {
+	out := model.ArchivalTLSOrQUICHandshakeResult{
+		Address:            ev.Address,
+		CipherSuite:        ev.CipherSuite,
+		Failure:            ev.Failure,
+		NegotiatedProtocol: ev.NegotiatedProtocol,
+		NoTLSVerify:        ev.NoTLSVerify,
+		ServerName:         ev.ServerName,
+		T:                  ev.T,
+		TLSVersion:         ev.TLSVersion,
+	}
+	if len(ev.PeerCertificates) > 0 {
+		out.PeerCertificates = ev.PeerCertificates
+	}
+	return out
+}
+
+// archivalHTTPRequest is like archivalTCPConnect for HTTP transactions.
+func archivalHTTPRequest(ev *model.ArchivalHTTPRequestResult) model.ArchivalHTTPRequestResult {
+	return model.ArchivalHTTPRequestResult{
+		Failure:  ev.Failure,
+		Request:  ev.Request,
+		Response: ev.Response,
+		T:        ev.T,
+	}
+}
+
+// archivalResolverNetwork maps the names of the system resolver to "system" because
+// we split its results into fake A and AAAA queries (see https://github.com/ooni/spec/pull/257).
+func archivalResolverNetwork(network string) string {
+	switch network {
+	case netxlite.StdlibResolverGetaddrinfo, netxlite.StdlibResolverGolangNetResolver:
+		return netxlite.StdlibResolverSystem
+	default:
+		return network
+	}
+}
+
+// NewArchivalDNSLookupResults returns the queries describing the result of calling
+// LookupHost with the given resolver, using the legacy format, where we emit an entry
+// for each address family. Because we don't know which queries the resolver actually
+// sent, we skip the families without addresses, unless the lookup failed.
+func NewArchivalDNSLookupResults(reso measurexlite.DNSNetworkAddresser, hostname string,
+	addrs []string, err error, finished time.Duration) (out []model.ArchivalDNSLookupResult) {
+	for _, qtype := range []string{"A", "AAAA"} {
+		entry := model.ArchivalDNSLookupResult{
+			Engine:          archivalResolverNetwork(reso.Network()),
+			Failure:         measurexlite.NewFailure(err),
+			Hostname:        hostname,
+			QueryType:       qtype,
+			ResolverAddress: reso.Address(),
+			T:               finished.Seconds(),
+		}
+		for _, addr := range addrs {
+			if isIPv6 := strings.Contains(addr, ":"); isIPv6 != (qtype == "AAAA") {
+				continue
+			}
+			// Web Connectivity depends on the ASN and the org name of the answers.
+			asn, org, _ := geoipx.LookupASN(addr, "")
+			answer := model.ArchivalDNSAnswer{
+				ASN:        int64(asn),
+				ASOrgName:  org,
+				AnswerType: qtype,
+			}
+			if qtype == "A" {
+				answer.IPv4 = addr
+			} else {
+				answer.IPv6 = addr
+			}
+			entry.Answers = append(entry.Answers, answer)
+		}
+		if len(entry.Answers) <= 0 && err == nil {
+			continue
+		}
+		out = append(out, entry)
+	}
+	return
+}
+
+// archiveNetworkEvent appends an event with the given operation and failure to the test keys.
+func (r *runner) archiveNetworkEvent(operation string, err error) {
+	r.archiveNetworkEventWithAddress(operation, "", "", err)
+}
+
+// archiveNetworkEventWithAddress is like archiveNetworkEvent but also sets the address and the protocol.
+func (r *runner) archiveNetworkEventWithAddress(operation, proto, address string, err error) {
+	ev := model.ArchivalNetworkEvent{
+		Address:   address,
+		Failure:   measurexlite.NewFailure(err),
+		Operation: operation,
+		Proto:     proto,
+		T:         time.Since(r.begin).Seconds(),
+	}
+	r.mu.Lock()
+	r.tk.NetworkEvents = append(r.tk.NetworkEvents, ev)
+	r.mu.Unlock()
+}
+
+// archivalResolver is a resolver recording the resolve events and the queries.
+type archivalResolver struct {
+	model.Resolver
+	r *runner
+}
+
+var _ model.Resolver = &archivalResolver{}
+
+// newArchivalResolver wraps the given resolver to record the resolve events and the queries.
+func (r *runner) newArchivalResolver(reso model.Resolver) model.Resolver {
+	return &archivalResolver{Resolver: reso, r: r}
+}
+
+// LookupHost implements model.Resolver.
+func (reso *archivalResolver) LookupHost(ctx context.Context, hostname string) ([]string, error) {
+	reso.r.archiveNetworkEvent("resolve_start", nil)
+	addrs, err := reso.Resolver.LookupHost(ctx, hostname)
+	finished := time.Since(reso.r.begin)
+	reso.r.archiveNetworkEvent("resolve_done", err)
+	queries := NewArchivalDNSLookupResults(reso.Resolver, hostname, addrs, err, finished)
+	reso.r.mu.Lock()
+	reso.r.tk.Queries = append(reso.r.tk.Queries, queries...)
+	reso.r.mu.Unlock()
+	return addrs, err
+}
+
+// Network implements model.Resolver.
+func (reso *archivalResolver) Network() string {
+	return archivalResolverNetwork(reso.Resolver.Network())
+}
+
+// archivalDNSTransport is a DNS transport recording the DNS round trip events.
+type archivalDNSTransport struct {
+	model.DNSTransport
+	r *runner
+}
+
+var _ model.DNSTransport = &archivalDNSTransport{}
+
+// newArchivalDNSTransport wraps the given DNS transport to record the DNS round trip events.
+func (r *runner) newArchivalDNSTransport(txp model.DNSTransport) model.DNSTransport {
+	return &archivalDNSTransport{DNSTransport: txp, r: r}
+}
+
+// RoundTrip implements model.DNSTransport.
+func (txp *archivalDNSTransport) RoundTrip(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+	txp.r.archiveNetworkEvent("dns_round_trip_start", nil)
+	resp, err := txp.DNSTransport.RoundTrip(ctx, query)
+	txp.r.archiveNetworkEvent("dns_round_trip_done", err)
+	return resp, err
+}
+
+// archivalUDPDialer is a dialer recording the UDP connect events, which measurexlite
+// ignores because they cannot fail in ways that are interesting for censorship.
+type archivalUDPDialer struct {
+	model.Dialer
+	r *runner
+}
+
+var _ model.Dialer = &archivalUDPDialer{}
+
+// newArchivalUDPDialer wraps the given dialer to record the UDP connect events.
+func (r *runner) newArchivalUDPDialer(dialer model.Dialer) model.Dialer {
+	return &archivalUDPDialer{Dialer: dialer, r: r}
+}
+
+// DialContext implements model.Dialer.
+func (d *archivalUDPDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.Dialer.DialContext(ctx, network, address)
+	d.r.archiveNetworkEventWithAddress(netxlite.ConnectOperation, network, address, err)
+	return conn, err
+}
+
+// archivalHTTPTransport is an HTTP transport recording the HTTP transactions. We
+// use it for DNS-over-HTTPS, whose transactions measurexlite does not record.
+type archivalHTTPTransport struct {
+	model.HTTPTransport
+	r *runner
+}
+
+var _ model.HTTPTransport = &archivalHTTPTransport{}
+
+// newArchivalHTTPTransport wraps the given HTTP transport to record the HTTP transactions.
+func (r *runner) newArchivalHTTPTransport(txp model.HTTPTransport) model.HTTPTransport {
+	return &archivalHTTPTransport{HTTPTransport: txp, r: r}
+}
+
+// RoundTrip implements model.HTTPTransport.
+func (txp *archivalHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Since(txp.r.begin)
+	txp.r.archiveNetworkEvent("http_transaction_start", nil)
+
+	// Implementation note: net/http does not set the Host header until it
+	// serializes the request, so we set it on a clone to archive it.
+	archivedReq := req.Clone(req.Context())
+	if req.Host != "" {
+		archivedReq.Header.Set("Host", req.Host)
+	} else {
+		archivedReq.Header.Set("Host", req.URL.Host)
+	}
+
+	resp, err := txp.HTTPTransport.RoundTrip(req)
+	var body []byte
+	if err == nil {
+		body, err = netxlite.ReadAllContext(req.Context(), io.LimitReader(resp.Body, httpMaxBodySnapshot))
+		if err != nil {
+			resp.Body.Close()
+			resp = nil
+		} else {
+			// allow the caller to read the whole body again
+			resp.Body = &archivalReadableAgainBody{
+				Reader: io.MultiReader(bytes.NewReader(body), resp.Body),
+				Closer: resp.Body,
+			}
+		}
+	}
+
+	finished := time.Since(txp.r.begin)
+	txp.r.archiveNetworkEvent("http_transaction_done", err)
+
+	// OONI's convention is that the last request appears first
+	ev := measurexlite.NewArchivalHTTPRequestResult(
+		0, started, "", "", "", txp.Network(), archivedReq, resp,
+		httpMaxBodySnapshot, body, err, finished)
+	txp.r.mu.Lock()
+	txp.r.tk.Requests = append([]model.ArchivalHTTPRequestResult{archivalHTTPRequest(ev)}, txp.r.tk.Requests...)
+	txp.r.mu.Unlock()
+
+	return resp, err
+}
+
+// archivalReadableAgainBody is a response body we can read again after reading its snapshot.
+type archivalReadableAgainBody struct {
+	io.Reader
+	io.Closer
+}
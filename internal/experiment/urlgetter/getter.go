@@ -6,7 +6,7 @@ import (
 	"net/url"
 	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/tunnel"
@@ -49,35 +49,20 @@ func (g Getter) Get(ctx context.Context) (TestKeys, error) {
 	if g.Begin.IsZero() {
 		g.Begin = time.Now()
 	}
-	saver := new(tracex.Saver)
-	tk, err := g.get(ctx, saver)
+	tk, err := g.get(ctx)
 	// Make sure we have an operation in cases where we fail before
 	// hitting our httptransport that does error wrapping.
 	if err != nil {
 		err = netxlite.NewTopLevelGenericErrWrapper(err)
 	}
-	tk.FailedOperation = tracex.NewFailedOperation(err)
-	tk.Failure = tracex.NewFailure(err)
-	events := saver.Read()
-	tk.Queries = append(tk.Queries, tracex.NewDNSQueriesList(g.Begin, events)...)
-	tk.NetworkEvents = append(
-		tk.NetworkEvents, tracex.NewNetworkEventsList(g.Begin, events)...,
-	)
-	tk.Requests = append(
-		tk.Requests, tracex.NewRequestList(g.Begin, events)...,
-	)
+	tk.FailedOperation = measurexlite.NewFailedOperation(err)
+	tk.Failure = measurexlite.NewFailure(err)
 	if len(tk.Requests) > 0 {
 		// OONI's convention is that the last request appears first
 		tk.HTTPResponseStatus = tk.Requests[0].Response.Code
 		tk.HTTPResponseBody = string(tk.Requests[0].Response.Body)
 		tk.HTTPResponseLocations = tk.Requests[0].Response.Locations
 	}
-	tk.TCPConnect = append(
-		tk.TCPConnect, tracex.NewTCPConnectList(g.Begin, events)...,
-	)
-	tk.TLSHandshakes = append(
-		tk.TLSHandshakes, tracex.NewTLSHandshakesList(g.Begin, events)...,
-	)
 	return tk, err
 }
 
@@ -89,7 +74,7 @@ func (g Getter) ioutilTempDir(dir, pattern string) (string, error) {
 	return ioutil.TempDir(dir, pattern)
 }
 
-func (g Getter) get(ctx context.Context, saver *tracex.Saver) (TestKeys, error) {
+func (g Getter) get(ctx context.Context) (TestKeys, error) {
 	tk := TestKeys{
 		Agent:  "redirect",
 		Tunnel: g.Config.Tunnel,
@@ -125,23 +110,12 @@ func (g Getter) get(ctx context.Context, saver *tracex.Saver) (TestKeys, error)
 		tk.SOCKSProxy = proxyURL.String()
 		defer tun.Stop()
 	}
-	// create configuration
-	configurer := Configurer{
-		Config:   g.Config,
-		Logger:   g.Session.Logger(),
-		ProxyURL: proxyURL,
-		Saver:    saver,
-	}
-	configuration, err := configurer.NewConfiguration()
+	// create the runner
+	r, err := newRunner(&tk, g.Begin, g.Config, g.Session.Logger(), proxyURL)
 	if err != nil {
 		return tk, err
 	}
-	defer configuration.CloseIdleConnections()
 	// run the measurement
-	runner := Runner{
-		Config:     g.Config,
-		HTTPConfig: configuration.HTTPConfig,
-		Target:     g.Target,
-	}
-	return tk, runner.Run(ctx)
+	err = r.Run(ctx, g.Target)
+	return tk, err
 }
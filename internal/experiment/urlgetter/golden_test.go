@@ -0,0 +1,98 @@
+package urlgetter_test
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
+	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/testingx"
+)
+
+// TestGolden runs urlgetter inside a [netemx.QAEnv] and compares the test keys with
+// the golden files inside ./testdata. Set OONI_UPDATE_GOLDEN=1 to regenerate them.
+func TestGolden(t *testing.T) {
+	type testcase struct {
+		// name is the test case name and the golden file name.
+		name string
+
+		// config is the urlgetter config.
+		config urlgetter.Config
+
+		// target is the urlgetter target.
+		target string
+
+		// configureDPI is an OPTIONAL function to configure DPI.
+		configureDPI func(dpi *netem.DPIEngine)
+	}
+
+	cases := []testcase{{
+		name:   "https_success",
+		target: "https://www.example.com/",
+	}, {
+		name:   "http_redirect",
+		target: "https://bit.ly/32447",
+	}, {
+		name:   "https_with_dns_over_udp",
+		config: urlgetter.Config{ResolverURL: "udp://8.8.8.8:53"},
+		target: "https://www.example.com/",
+	}, {
+		name:   "https_with_dns_over_https",
+		config: urlgetter.Config{ResolverURL: "https://dns.google/dns-query"},
+		target: "https://www.example.com/",
+	}, {
+		name:   "https_with_connection_reset",
+		target: "https://www.example.com/",
+		configureDPI: func(dpi *netem.DPIEngine) {
+			dpi.AddRule(&netem.DPIResetTrafficForTLSSNI{
+				Logger: log.Log,
+				SNI:    "www.example.com",
+			})
+		},
+	}, {
+		name:   "dnslookup_with_dns_over_udp",
+		config: urlgetter.Config{ResolverURL: "udp://8.8.8.8:53"},
+		target: "dnslookup://www.example.com",
+	}, {
+		name:   "dnslookup_nxdomain",
+		target: "dnslookup://www.nonexistent.example",
+	}, {
+		name:   "tlshandshake_success",
+		target: "tlshandshake://www.example.com:443",
+	}, {
+		name:   "tcpconnect_success",
+		target: "tcpconnect://www.example.com:443",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := netemx.MustNewScenario(netemx.InternetScenario)
+			defer env.Close()
+			if tc.configureDPI != nil {
+				tc.configureDPI(env.DPIEngine())
+			}
+			env.Do(func() {
+				g := urlgetter.Getter{
+					Begin:  time.Now(),
+					Config: tc.config,
+					Session: &mockable.Session{
+						MockableHTTPClient: http.DefaultClient,
+						MockableLogger:     log.Log,
+					},
+					Target: tc.target,
+				}
+				tk, _ := g.Get(context.Background())
+				path := filepath.Join("testdata", "golden", tc.name+".json")
+				if err := testingx.CompareGolden(path, tk); err != nil {
+					t.Fatal(err)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package urlgetter
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// httpMaxBodySnapshot is the maximum body snapshot size.
+const httpMaxBodySnapshot = 1 << 17
+
+// httpMaxRedirects is the maximum number of redirects we follow.
+const httpMaxRedirects = 10
+
+// errHTTPTooManyRedirects indicates we've been redirected too many times.
+var errHTTPTooManyRedirects = errors.New("stopped after 10 redirects")
+
+// newHTTPTransport creates an HTTP transport using the given resolver
+// and TLS config, which uses HTTP/3 when HTTP3Enabled is true.
+func (r *runner) newHTTPTransport(reso model.Resolver, config *tls.Config) model.HTTPTransport {
+	if r.config.HTTP3Enabled {
+		return netxlite.NewHTTP3Transport(r.logger, r.newQUICDialer(reso), config)
+	}
+	dialer := r.newDialer(reso)
+	return netxlite.NewHTTPTransport(r.logger, dialer, r.newTLSDialer(reso, config))
+}
+
+// httpGet fetches the given URL following redirects unless NoFollowRedirects is set.
+//
+// We follow redirects manually such that each HTTP transaction uses its own
+// trace. Like net/http, we switch to GET for 301, 302, and 303 unless the method
+// is HEAD, and we only keep the Host header override for same-host redirects.
+func (r *runner) httpGet(ctx context.Context, URL string) error {
+	// Implementation note: the following cookiejar accepts all cookies
+	// from all domains. As such, would not be safe for usage where cookies
+	// matter, but it's totally fine for performing measurements.
+	jar, err := cookiejar.New(nil)
+	runtimex.PanicOnError(err, "cookiejar.New failed")
+	method, host := r.config.Method, r.config.HTTPHost
+	for count := 0; ; count++ {
+		resp, err := r.httpTransaction(ctx, jar, method, URL, host)
+		if err != nil {
+			return err
+		}
+		if r.config.NoFollowRedirects || !httpIsRedirect(resp) {
+			return r.httpCheckStatusCode(resp)
+		}
+		location, err := resp.Location()
+		if errors.Is(err, http.ErrNoLocation) {
+			return r.httpCheckStatusCode(resp)
+		}
+		if err != nil {
+			return err
+		}
+		if count+1 >= httpMaxRedirects {
+			return errHTTPTooManyRedirects
+		}
+		if resp.StatusCode <= http.StatusSeeOther && method != "" &&
+			method != http.MethodGet && method != http.MethodHead {
+			method = http.MethodGet
+		}
+		if location.Host != resp.Request.URL.Host {
+			host = ""
+		}
+		URL = location.String()
+	}
+}
+
+// httpIsRedirect returns whether the response is a redirect we should follow.
+func httpIsRedirect(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case 301, 302, 303, 307, 308:
+		return true
+	default:
+		return false
+	}
+}
+
+// httpCheckStatusCode returns [ErrHTTPRequestFailed] when the status code
+// indicates failure and the FailOnHTTPError setting is true.
+func (r *runner) httpCheckStatusCode(resp *http.Response) error {
+	if resp.StatusCode >= 400 && r.config.FailOnHTTPError {
+		return ErrHTTPRequestFailed
+	}
+	return nil
+}
+
+// httpTransaction performs a single HTTP transaction using a new trace and a new
+// transport, appends the results to the test keys, and returns the response whose
+// body has already been consumed and closed.
+func (r *runner) httpTransaction(ctx context.Context,
+	jar http.CookieJar, method, URL, host string) (*http.Response, error) {
+	trace := r.newTrace()
+	ctx = netxlite.ContextWithTrace(ctx, trace)
+
+	// Implementation note: empty Method implies using the GET method
+	req, err := http.NewRequestWithContext(ctx, method, URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", model.HTTPHeaderAccept)
+	req.Header.Set("Accept-Language", model.HTTPHeaderAcceptLanguage)
+	req.Header.Set("User-Agent", MaybeUserAgent(r.config.UserAgent))
+	if host == "" {
+		host = req.URL.Host
+	}
+	// Implementation note: net/http ignores the Host header when sending the
+	// request, so we also set it explicitly such that we archive it.
+	req.Host = host
+	req.Header.Set("Host", host)
+	for _, cookie := range jar.Cookies(req.URL) {
+		req.AddCookie(cookie)
+	}
+
+	config := r.tlsConfig
+	if r.config.HTTP3Enabled {
+		config = config.Clone()
+		config.NextProtos = []string{"h3"}
+	}
+	txp := r.newHTTPTransport(r.newResolver(), config)
+	defer txp.CloseIdleConnections()
+
+	started := trace.TimeSince(trace.ZeroTime())
+	r.archiveNetworkEvent("http_transaction_start", nil)
+
+	resp, err := txp.RoundTrip(req)
+	var body []byte
+	if err == nil {
+		defer resp.Body.Close()
+		if cookies := resp.Cookies(); len(cookies) > 0 {
+			jar.SetCookies(req.URL, cookies)
+		}
+		body, err = netxlite.ReadAllContext(ctx, io.LimitReader(resp.Body, httpMaxBodySnapshot))
+		if err == nil {
+			// Implementation note: we read the whole body to know whether there
+			// was an error even though we're only saving the snapshot.
+			_, err = netxlite.CopyContext(ctx, io.Discard, resp.Body)
+		}
+	}
+
+	finished := trace.TimeSince(trace.ZeroTime())
+	r.collect(trace)
+	r.archiveNetworkEvent("http_transaction_done", err)
+
+	// OONI's convention is that the last request appears first
+	ev := measurexlite.NewArchivalHTTPRequestResult(
+		trace.Index(), started, "", "", "", txp.Network(), req, resp,
+		httpMaxBodySnapshot, body, err, finished)
+	r.mu.Lock()
+	r.tk.Requests = append([]model.ArchivalHTTPRequestResult{archivalHTTPRequest(ev)}, r.tk.Requests...)
+	r.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,178 @@
+package urlgetter
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// newDNSCache parses the DNSCache config string, which has the
+// "DOMAIN IP..." format, and returns the corresponding static DNS
+// cache or nil, if the string is empty.
+func newDNSCache(s string) (map[string][]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	entry := strings.Split(s, " ")
+	if len(entry) < 2 {
+		return nil, errors.New("invalid DNSCache string")
+	}
+	domainregex := regexp.MustCompile(`^([a-z0-9]+(-[a-z0-9]+)*\.)+[a-z]{2,}$`)
+	if !domainregex.MatchString(entry[0]) {
+		return nil, errors.New("invalid domain in DNSCache")
+	}
+	var addresses []string
+	for i := 1; i < len(entry); i++ {
+		if net.ParseIP(entry[i]) == nil {
+			return nil, errors.New("invalid IP in DNSCache")
+		}
+		addresses = append(addresses, entry[i])
+	}
+	return map[string][]string{entry[0]: addresses}, nil
+}
+
+// newResolverURL parses and validates the ResolverURL config string. The
+// "doh://google" and "doh://cloudflare" strings are shortcuts for the
+// corresponding DoH resolvers and the empty string means "system:///".
+//
+// For the "dot", "tcp", and "udp" schemes, the returned URL's Host always
+// contains a port, which defaults to 853 for DoT and to 53 otherwise.
+func newResolverURL(s string) (*url.URL, error) {
+	switch s {
+	case "doh://google":
+		s = "https://dns.google/dns-query"
+	case "doh://cloudflare":
+		s = "https://cloudflare-dns.com/dns-query"
+	case "":
+		s = "system:///"
+	}
+	URL, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	switch URL.Scheme {
+	case "system", "https":
+		return URL, nil
+	case "dot", "tcp", "udp":
+		endpoint, err := makeValidEndpoint(URL)
+		if err != nil {
+			return nil, err
+		}
+		URL.Host = endpoint
+		return URL, nil
+	default:
+		return nil, errors.New("unsupported resolver scheme")
+	}
+}
+
+// makeValidEndpoint makes a valid endpoint for DoT and Do53 given the
+// input URL representing such endpoint. Specifically, we are
+// concerned with the case where the port is missing. In such a
+// case, we ensure that we are using the default port 853 for DoT
+// and default port 53 for TCP and UDP.
+func makeValidEndpoint(URL *url.URL) (string, error) {
+	// Implementation note: when we're using a quoted IPv6
+	// address, URL.Host contains the quotes but instead the
+	// return value from URL.Hostname() does not.
+	//
+	// For example:
+	//
+	// - Host: [2620:fe::9]
+	// - Hostname(): 2620:fe::9
+	//
+	// We need to keep this in mind when trying to determine
+	// whether there is also a port or not.
+	//
+	// So the first step is to check whether URL.Host is already
+	// a whatever valid TCP/UDP endpoint and, if so, use it.
+	if _, _, err := net.SplitHostPort(URL.Host); err == nil {
+		return URL.Host, nil
+	}
+
+	// The second step is to assume that appending the default port
+	// to a host parsed by url.Parse should be giving us a valid
+	// endpoint. The possibilities in fact are:
+	//
+	// 1. domain w/o port
+	// 2. IPv4 w/o port
+	// 3. square bracket quoted IPv6 w/o port
+	// 4. other
+	//
+	// In the first three cases, appending a port leads us to a
+	// good endpoint. The fourth case does not.
+	//
+	// For this reason we check again whether we can split it using
+	// net.SplitHostPort. If we cannot, we were in case four.
+	host := URL.Host
+	if URL.Scheme == "dot" {
+		host += ":853"
+	} else {
+		host += ":53"
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return "", err
+	}
+	// Otherwise it's one of the three valid cases above.
+	return host, nil
+}
+
+// newResolver creates the resolver described by the config. We record the
+// resolve and DNS round trip events and the queries using the legacy archival
+// data format (see archival.go). All the other events are recorded by the
+// trace inside the context passed to LookupHost.
+func (r *runner) newResolver() model.Resolver {
+	var txp model.DNSTransport
+	switch r.resolverURL.Scheme {
+	case "https":
+		txp = r.newDNSOverHTTPSTransport()
+	case "udp":
+		dialer := r.newArchivalUDPDialer(r.newDialer(r.newSystemResolver()))
+		txp = netxlite.NewUnwrappedDNSOverUDPTransport(dialer, r.resolverURL.Host)
+	case "tcp":
+		dialer := r.newDialer(r.newSystemResolver())
+		txp = netxlite.NewUnwrappedDNSOverTCPTransport(dialer.DialContext, r.resolverURL.Host)
+	case "dot":
+		config := r.dnsTLSConfig.Clone()
+		config.NextProtos = []string{"dot"}
+		tlsDialer := r.newTLSDialer(r.newSystemResolver(), config)
+		txp = netxlite.NewUnwrappedDNSOverTLSTransport(tlsDialer.DialTLSContext, r.resolverURL.Host)
+	default:
+		return r.newSystemResolver()
+	}
+	// Implementation note: like the legacy implementation, we use a serial resolver
+	// such that the order of the DNS-over-HTTPS requests is deterministic.
+	reso := netxlite.WrapResolver(r.logger, netxlite.NewUnwrappedSerialResolver(r.newArchivalDNSTransport(txp)))
+	return r.newArchivalResolver(r.maybeWrapResolver(reso))
+}
+
+// newSystemResolver creates a system resolver. We also use this resolver to resolve
+// the domain name of DNS servers, so that the DNSCache setting allows us to choose
+// the IP address of the DNS server we're using.
+func (r *runner) newSystemResolver() model.Resolver {
+	// Here we make sure that we're counting bytes sent and received.
+	reso := bytecounter.WrapWithContextAwareSystemResolver(r.netx.NewStdlibResolver(r.logger))
+	return r.newArchivalResolver(r.maybeWrapResolver(reso))
+}
+
+// maybeWrapResolver wraps the resolver to honour the DNSCache and RejectDNSBogons settings.
+func (r *runner) maybeWrapResolver(reso model.Resolver) model.Resolver {
+	reso = netxlite.MaybeWrapWithStaticDNSCache(r.dnsCache, reso)
+	return netxlite.MaybeWrapWithBogonResolver(r.config.RejectDNSBogons, reso)
+}
+
+// newDNSOverHTTPSTransport creates the DNS-over-HTTPS transport honouring the
+// DNSHTTPHost, DNSTLSServerName, DNSTLSVersion and HTTP3Enabled settings.
+func (r *runner) newDNSOverHTTPSTransport() model.DNSTransport {
+	config := r.dnsTLSConfig.Clone()
+	config.NextProtos = []string{"h2", "http/1.1"}
+	txp := r.newArchivalHTTPTransport(r.newHTTPTransport(r.newSystemResolver(), config))
+	return netxlite.NewUnwrappedDNSOverHTTPSTransportWithHostOverride(
+		&http.Client{Transport: txp}, r.resolverURL.String(), r.config.DNSHTTPHost)
+}
@@ -0,0 +1,229 @@
+package urlgetter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestNewDNSCache(t *testing.T) {
+	type testcase struct {
+		input     string
+		expect    map[string][]string
+		expectErr string
+	}
+	cases := []testcase{{
+		input:  "",
+		expect: nil,
+	}, {
+		input:     "dns.google",
+		expectErr: "invalid DNSCache string",
+	}, {
+		input:     "1.1.1.1 dns.google",
+		expectErr: "invalid domain in DNSCache",
+	}, {
+		input:     "dns.google 8.8.8.8 dns.google",
+		expectErr: "invalid IP in DNSCache",
+	}, {
+		input:  "dns.google 8.8.8.8 8.8.4.4",
+		expect: map[string][]string{"dns.google": {"8.8.8.8", "8.8.4.4"}},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.input, func(t *testing.T) {
+			cache, err := newDNSCache(tc.input)
+			if tc.expectErr != "" {
+				if err == nil || err.Error() != tc.expectErr {
+					t.Fatal("not the error we expected", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expect, cache); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestNewResolverURL(t *testing.T) {
+	type testcase struct {
+		input     string
+		expect    string
+		expectErr bool
+	}
+	cases := []testcase{{
+		input:  "",
+		expect: "system:///",
+	}, {
+		input:  "doh://google",
+		expect: "https://dns.google/dns-query",
+	}, {
+		input:  "doh://cloudflare",
+		expect: "https://cloudflare-dns.com/dns-query",
+	}, {
+		input:  "https://dns.quad9.net/dns-query",
+		expect: "https://dns.quad9.net/dns-query",
+	}, {
+		input:  "udp://8.8.8.8",
+		expect: "udp://8.8.8.8:53",
+	}, {
+		input:  "tcp://8.8.8.8:5353",
+		expect: "tcp://8.8.8.8:5353",
+	}, {
+		input:  "dot://[2620:fe::9]",
+		expect: "dot://[2620:fe::9]:853",
+	}, {
+		input:     "dot://[2620:fe::9",
+		expectErr: true,
+	}, {
+		input:     "\t",
+		expectErr: true,
+	}, {
+		input:     "antani://8.8.8.8",
+		expectErr: true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.input, func(t *testing.T) {
+			URL, err := newResolverURL(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected an error here")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if URL.String() != tc.expect {
+				t.Fatal("expected", tc.expect, "got", URL.String())
+			}
+		})
+	}
+}
+
+func TestRunnerNewResolver(t *testing.T) {
+	type testcase struct {
+		resolverURL string
+		network     string
+		address     string
+	}
+	cases := []testcase{{
+		resolverURL: "",
+		network:     netxlite.StdlibResolverSystem,
+		address:     "",
+	}, {
+		resolverURL: "https://8.8.8.8/dns-query",
+		network:     "doh",
+		address:     "https://8.8.8.8/dns-query",
+	}, {
+		resolverURL: "udp://8.8.8.8",
+		network:     "udp",
+		address:     "8.8.8.8:53",
+	}, {
+		resolverURL: "tcp://8.8.8.8",
+		network:     "tcp",
+		address:     "8.8.8.8:53",
+	}, {
+		resolverURL: "dot://8.8.8.8",
+		network:     "dot",
+		address:     "8.8.8.8:853",
+	}}
+	for _, tc := range cases {
+		t.Run(tc.resolverURL, func(t *testing.T) {
+			r, _ := newTestRunner(t, Config{ResolverURL: tc.resolverURL})
+			reso := r.newResolver()
+			if reso.Network() != tc.network {
+				t.Fatal("expected", tc.network, "got", reso.Network())
+			}
+			if reso.Address() != tc.address {
+				t.Fatal("expected", tc.address, "got", reso.Address())
+			}
+		})
+	}
+
+	t.Run("we honour the DNS cache", func(t *testing.T) {
+		r, tk := newTestRunner(t, Config{
+			DNSCache:    "dns.google 8.8.8.8",
+			ResolverURL: "udp://127.0.0.1:1", // would fail if used
+		})
+		reso := r.newResolver()
+		addrs, err := reso.LookupHost(context.Background(), "dns.google")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addrs) != 1 || addrs[0] != "8.8.8.8" {
+			t.Fatal("not the addresses we expected")
+		}
+		// like the legacy implementation, we archive the cached addresses
+		if len(tk.Queries) != 1 || tk.Queries[0].QueryType != "A" || tk.Queries[0].Engine != "udp" ||
+			len(tk.Queries[0].Answers) != 1 || tk.Queries[0].Answers[0].IPv4 != "8.8.8.8" {
+			t.Fatal("not the Queries we expected")
+		}
+	})
+
+	t.Run("we use the DNS cache to resolve the DNS server domain", func(t *testing.T) {
+		// create a local DNS server and make sure we reach it using the DNS cache
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		go func() {
+			for {
+				buffer := make([]byte, 1024)
+				count, addr, err := conn.ReadFrom(buffer)
+				if err != nil {
+					return
+				}
+				query := &dns.Msg{}
+				if err := query.Unpack(buffer[:count]); err != nil {
+					continue
+				}
+				reply := &dns.Msg{}
+				reply.SetRcode(query, dns.RcodeRefused)
+				data, _ := reply.Pack()
+				conn.WriteTo(data, addr)
+			}
+		}()
+		_, port, err := net.SplitHostPort(conn.LocalAddr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, tk := newTestRunner(t, Config{
+			DNSCache:    "dns.antani.org 127.0.0.1",
+			ResolverURL: "udp://dns.antani.org:" + port,
+		})
+		if err := r.Run(context.Background(), "dnslookup://www.example.com"); err == nil {
+			t.Fatal("expected an error here")
+		}
+		var found bool
+		for _, query := range tk.Queries {
+			if query.ResolverAddress == "dns.antani.org:"+port && query.Failure != nil &&
+				*query.Failure == netxlite.FailureDNSRefusedError {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatal("we did not query the expected DNS server")
+		}
+	})
+
+	t.Run("we can reject DNS bogons", func(t *testing.T) {
+		r, _ := newTestRunner(t, Config{
+			DNSCache:        "dns.google 10.0.0.1",
+			RejectDNSBogons: true,
+		})
+		reso := r.newResolver()
+		_, err := reso.LookupHost(context.Background(), "dns.google")
+		if !errors.Is(err, netxlite.ErrDNSBogon) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+}
@@ -2,17 +2,17 @@ package urlgetter
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/http/cookiejar"
 	"net/url"
+	"sync"
+	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/netx"
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
-	"github.com/ooni/probe-cli/v3/internal/runtimex"
 )
 
 const httpRequestFailed = "http_request_failed"
@@ -24,22 +24,99 @@ var ErrHTTPRequestFailed = &netxlite.ErrWrapper{
 	WrappedErr: errors.New(httpRequestFailed),
 }
 
-// The Runner job is to run a single measurement
-type Runner struct {
-	Config     Config
-	HTTPConfig netx.Config
-	Target     string
+// runner runs a single measurement. Each network operation performed by the
+// runner uses a distinct [*measurexlite.Trace] whose results are appended
+// to the [*TestKeys] as soon as the operation is complete, using the legacy
+// archival data format (see archival.go).
+type runner struct {
+	// begin is the zero time of all the traces.
+	begin time.Time
+
+	// config contains the experiment config.
+	config Config
+
+	// dnsCache is the OPTIONAL static DNS cache.
+	dnsCache map[string][]string
+
+	// dnsTLSConfig is the TLS config for DNS-over-TLS and DNS-over-HTTPS.
+	dnsTLSConfig *tls.Config
+
+	// idx is the index of the last trace we created.
+	idx int64
+
+	// logger is the logger to use.
+	logger model.Logger
+
+	// mu protects tk, which the resolvers MAY modify concurrently.
+	mu sync.Mutex
+
+	// netx is the network we're using.
+	netx *netxlite.Netx
+
+	// proxyURL is the OPTIONAL proxy URL.
+	proxyURL *url.URL
+
+	// resolverURL is the URL of the resolver to use.
+	resolverURL *url.URL
+
+	// tk contains the test keys we're filling.
+	tk *TestKeys
+
+	// tlsConfig is the TLS config for TLS handshakes and HTTPS.
+	tlsConfig *tls.Config
+}
+
+// newRunner validates the config and creates a new [*runner] instance.
+func newRunner(tk *TestKeys, begin time.Time, config Config,
+	logger model.Logger, proxyURL *url.URL) (*runner, error) {
+	dnsCache, err := newDNSCache(config.DNSCache)
+	if err != nil {
+		return nil, err
+	}
+	resolverURL, err := newResolverURL(config.ResolverURL)
+	if err != nil {
+		return nil, err
+	}
+	dnsTLSConfig := &tls.Config{ // #nosec G402 - we need to use a large TLS versions range for measuring
+		ServerName: config.DNSTLSServerName,
+	}
+	if err := netxlite.ConfigureTLSVersion(dnsTLSConfig, config.DNSTLSVersion); err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{ // #nosec G402 - we need to use a large TLS versions range for measuring
+		NextProtos:         []string{"h2", "http/1.1"},
+		ServerName:         config.TLSServerName,
+		InsecureSkipVerify: config.NoTLSVerify,
+		RootCAs:            config.CertPool,
+	}
+	if err := netxlite.ConfigureTLSVersion(tlsConfig, config.TLSVersion); err != nil {
+		return nil, err
+	}
+	r := &runner{
+		begin:        begin,
+		config:       config,
+		dnsCache:     dnsCache,
+		dnsTLSConfig: dnsTLSConfig,
+		idx:          0,
+		logger:       model.ValidLoggerOrDefault(logger),
+		netx:         &netxlite.Netx{},
+		proxyURL:     proxyURL,
+		resolverURL:  resolverURL,
+		tk:           tk,
+		tlsConfig:    tlsConfig,
+	}
+	return r, nil
 }
 
 // Run runs a measurement and returns the measurement result
-func (r Runner) Run(ctx context.Context) error {
-	targetURL, err := url.Parse(r.Target)
+func (r *runner) Run(ctx context.Context, target string) error {
+	targetURL, err := url.Parse(target)
 	if err != nil {
 		return fmt.Errorf("urlgetter: invalid target URL: %w", err)
 	}
 	switch targetURL.Scheme {
 	case "http", "https":
-		return r.httpGet(ctx, r.Target)
+		return r.httpGet(ctx, target)
 	case "dnslookup":
 		return r.dnsLookup(ctx, targetURL.Hostname())
 	case "tlshandshake":
@@ -60,67 +137,78 @@ func MaybeUserAgent(ua string) string {
 	return ua
 }
 
-func (r Runner) httpGet(ctx context.Context, url string) error {
-	// Implementation note: empty Method implies using the GET method
-	req, err := http.NewRequest(r.Config.Method, url, nil)
-	runtimex.PanicOnError(err, "http.NewRequest failed")
-	req = req.WithContext(ctx)
-	req.Header.Set("Accept", model.HTTPHeaderAccept)
-	req.Header.Set("Accept-Language", model.HTTPHeaderAcceptLanguage)
-	req.Header.Set("User-Agent", MaybeUserAgent(r.Config.UserAgent))
-	if r.Config.HTTPHost != "" {
-		req.Host = r.Config.HTTPHost
-	}
-	// Implementation note: the following cookiejar accepts all cookies
-	// from all domains. As such, would not be safe for usage where cookies
-	// matter, but it's totally fine for performing measurements.
-	jar, err := cookiejar.New(nil)
-	runtimex.PanicOnError(err, "cookiejar.New failed")
-	httpClient := &http.Client{
-		Jar:       jar,
-		Transport: netx.NewHTTPTransport(r.HTTPConfig),
-	}
-	if r.Config.NoFollowRedirects {
-		httpClient.CheckRedirect = func(*http.Request, []*http.Request) error {
-			return http.ErrUseLastResponse
-		}
-	}
-	defer httpClient.CloseIdleConnections()
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return err
+// newTrace creates a new trace for the next network operation.
+func (r *runner) newTrace() *measurexlite.Trace {
+	r.idx++
+	return measurexlite.NewTrace(r.idx, r.begin)
+}
+
+// collect appends the results saved inside the trace to the test keys. We ignore the
+// queries saved by the trace because the resolvers record them (see archival.go).
+func (r *runner) collect(trace *measurexlite.Trace) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, entry := range trace.TCPConnects() {
+		r.tk.TCPConnect = append(r.tk.TCPConnect, archivalTCPConnect(entry))
+	}
+	handshakes := trace.TLSHandshakes()
+	for _, entry := range handshakes {
+		r.tk.TLSHandshakes = append(r.tk.TLSHandshakes, archivalTLSHandshake(entry))
 	}
-	defer resp.Body.Close()
-	if _, err = netxlite.CopyContext(ctx, io.Discard, resp.Body); err != nil {
-		return err
+	for _, entry := range trace.QUICHandshakes() {
+		r.tk.TLSHandshakes = append(r.tk.TLSHandshakes, archivalTLSHandshake(entry))
 	}
-	// Implementation note: we shall check for this error once we have read the
-	// whole body. Even though we discard the body, we want to know whether we
-	// see any error when reading the body before inspecting the HTTP status code.
-	if resp.StatusCode >= 400 && r.Config.FailOnHTTPError {
-		return ErrHTTPRequestFailed
+	failures := archivalTLSHandshakeFailures(handshakes)
+	for _, entry := range trace.NetworkEvents() {
+		r.tk.NetworkEvents = append(r.tk.NetworkEvents, archivalNetworkEvent(entry, failures))
 	}
-	return nil
 }
 
-func (r Runner) dnsLookup(ctx context.Context, hostname string) error {
-	resolver := netx.NewResolver(r.HTTPConfig)
-	_, err := resolver.LookupHost(ctx, hostname)
+// newDialer creates a new dialer using the given resolver.
+func (r *runner) newDialer(reso model.Resolver) model.Dialer {
+	dialer := r.netx.NewDialerWithResolver(r.logger, reso)
+	dialer = netxlite.MaybeWrapWithProxyDialer(dialer, r.proxyURL)
+	return bytecounter.WrapWithContextAwareDialer(dialer)
+}
+
+// newTLSDialer creates a new TLS dialer using the given resolver and TLS config.
+func (r *runner) newTLSDialer(reso model.Resolver, config *tls.Config) model.TLSDialer {
+	return netxlite.NewTLSDialerWithConfig(
+		r.newDialer(reso), r.netx.NewTLSHandshakerStdlib(r.logger), config)
+}
+
+// newQUICDialer creates a new QUIC dialer using the given resolver.
+func (r *runner) newQUICDialer(reso model.Resolver) model.QUICDialer {
+	return r.netx.NewQUICDialerWithResolver(r.netx.NewUDPListener(), r.logger, reso)
+}
+
+func (r *runner) dnsLookup(ctx context.Context, hostname string) error {
+	trace := r.newTrace()
+	defer r.collect(trace)
+	resolver := r.newResolver()
+	defer resolver.CloseIdleConnections()
+	_, err := resolver.LookupHost(netxlite.ContextWithTrace(ctx, trace), hostname)
 	return err
 }
 
-func (r Runner) tlsHandshake(ctx context.Context, address string) error {
-	tlsDialer := netx.NewTLSDialer(r.HTTPConfig)
-	conn, err := tlsDialer.DialTLSContext(ctx, "tcp", address)
+func (r *runner) tlsHandshake(ctx context.Context, address string) error {
+	trace := r.newTrace()
+	defer r.collect(trace)
+	tlsDialer := r.newTLSDialer(r.newResolver(), r.tlsConfig)
+	defer tlsDialer.CloseIdleConnections()
+	conn, err := tlsDialer.DialTLSContext(netxlite.ContextWithTrace(ctx, trace), "tcp", address)
 	if conn != nil {
 		_ = conn.Close()
 	}
 	return err
 }
 
-func (r Runner) tcpConnect(ctx context.Context, address string) error {
-	dialer := netx.NewDialer(r.HTTPConfig)
-	conn, err := dialer.DialContext(ctx, "tcp", address)
+func (r *runner) tcpConnect(ctx context.Context, address string) error {
+	trace := r.newTrace()
+	defer r.collect(trace)
+	dialer := r.newDialer(r.newResolver())
+	defer dialer.CloseIdleConnections()
+	conn, err := dialer.DialContext(netxlite.ContextWithTrace(ctx, trace), "tcp", address)
 	if conn != nil {
 		_ = conn.Close()
 	}
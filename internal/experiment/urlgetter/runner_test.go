@@ -1,4 +1,4 @@
-package urlgetter_test
+package urlgetter
 
 import (
 	"context"
@@ -8,279 +8,374 @@ import (
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
-func TestRunnerWithInvalidURLScheme(t *testing.T) {
-	r := urlgetter.Runner{Target: "antani://www.google.com"}
-	err := r.Run(context.Background())
-	if err == nil || err.Error() != "unknown targetURL scheme" {
-		t.Fatal("not the error we expected")
+// newTestRunner creates a new runner for testing or fails the test.
+func newTestRunner(t *testing.T, config Config) (*runner, *TestKeys) {
+	tk := &TestKeys{}
+	r, err := newRunner(tk, time.Now(), config, model.DiscardLogger, nil)
+	if err != nil {
+		t.Fatal(err)
 	}
+	return r, tk
 }
 
-func TestRunnerHTTPWithContextCanceled(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel()
-	r := urlgetter.Runner{Target: "https://www.google.com"}
-	err := r.Run(ctx)
-	if !errors.Is(err, context.Canceled) {
-		t.Fatal("not the error we expected")
-	}
-}
+func TestNewRunner(t *testing.T) {
+	t.Run("vanilla", func(t *testing.T) {
+		r, _ := newTestRunner(t, Config{})
+		if r.dnsCache != nil {
+			t.Fatal("not the dnsCache we expected")
+		}
+		if r.resolverURL.String() != "system:///" {
+			t.Fatal("not the resolverURL we expected")
+		}
+		if r.tlsConfig.ServerName != "" {
+			t.Fatal("not the TLS ServerName we expected")
+		}
+		if len(r.tlsConfig.NextProtos) != 2 || r.tlsConfig.NextProtos[0] != "h2" ||
+			r.tlsConfig.NextProtos[1] != "http/1.1" {
+			t.Fatal("not the TLS NextProtos we expected")
+		}
+		if r.tlsConfig.InsecureSkipVerify {
+			t.Fatal("not the TLS InsecureSkipVerify we expected")
+		}
+	})
 
-func TestRunnerDNSLookupWithContextCanceled(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel()
-	r := urlgetter.Runner{Target: "dnslookup://www.google.com"}
-	err := r.Run(ctx)
-	if err == nil || err.Error() != "interrupted" {
-		t.Fatal("not the error we expected")
-	}
-}
+	t.Run("with TLS settings", func(t *testing.T) {
+		r, _ := newTestRunner(t, Config{
+			DNSTLSServerName: "dns.google",
+			DNSTLSVersion:    "TLSv1.3",
+			NoTLSVerify:      true,
+			TLSServerName:    "www.x.org",
+			TLSVersion:       "TLSv1.2",
+		})
+		if r.tlsConfig.ServerName != "www.x.org" {
+			t.Fatal("not the TLS ServerName we expected")
+		}
+		if !r.tlsConfig.InsecureSkipVerify {
+			t.Fatal("not the TLS InsecureSkipVerify we expected")
+		}
+		if r.tlsConfig.MinVersion != 0x0303 || r.tlsConfig.MaxVersion != 0x0303 {
+			t.Fatal("not the TLS version we expected")
+		}
+		if r.dnsTLSConfig.ServerName != "dns.google" {
+			t.Fatal("not the DNS TLS ServerName we expected")
+		}
+		if r.dnsTLSConfig.MinVersion != 0x0304 || r.dnsTLSConfig.MaxVersion != 0x0304 {
+			t.Fatal("not the DNS TLS version we expected")
+		}
+	})
 
-func TestRunnerTLSHandshakeWithContextCanceled(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel()
-	r := urlgetter.Runner{Target: "tlshandshake://www.google.com:443"}
-	err := r.Run(ctx)
-	if err == nil || err.Error() != "interrupted" {
-		t.Fatal("not the error we expected")
-	}
+	t.Run("with invalid settings", func(t *testing.T) {
+		configs := []Config{
+			{DNSCache: "dns.google"},
+			{ResolverURL: "antani://8.8.8.8"},
+			{DNSTLSVersion: "TLSv1.4"},
+			{TLSVersion: "TLSv1.4"},
+		}
+		for _, config := range configs {
+			r, err := newRunner(&TestKeys{}, time.Now(), config, model.DiscardLogger, nil)
+			if err == nil || r != nil {
+				t.Fatal("expected an error here", config)
+			}
+		}
+	})
 }
 
-func TestRunnerTCPConnectWithContextCanceled(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel()
-	r := urlgetter.Runner{Target: "tcpconnect://www.google.com:443"}
-	err := r.Run(ctx)
-	if err == nil || err.Error() != "interrupted" {
-		t.Fatal("not the error we expected")
-	}
-}
+func TestRunnerRun(t *testing.T) {
+	t.Run("with invalid URL scheme", func(t *testing.T) {
+		r, _ := newTestRunner(t, Config{})
+		err := r.Run(context.Background(), "antani://www.google.com")
+		if err == nil || err.Error() != "unknown targetURL scheme" {
+			t.Fatal("not the error we expected")
+		}
+	})
 
-func TestRunnerWithInvalidURL(t *testing.T) {
-	r := urlgetter.Runner{Target: "\t"}
-	err := r.Run(context.Background())
-	if err == nil || !strings.HasSuffix(err.Error(), "invalid control character in URL") {
-		t.Fatal("not the error we expected")
-	}
-}
+	t.Run("with invalid URL", func(t *testing.T) {
+		r, _ := newTestRunner(t, Config{})
+		err := r.Run(context.Background(), "\t")
+		if err == nil || !strings.HasSuffix(err.Error(), "invalid control character in URL") {
+			t.Fatal("not the error we expected")
+		}
+	})
 
-func TestRunnerWithEmptyHostname(t *testing.T) {
-	r := urlgetter.Runner{Target: "http:///foo.txt"}
-	err := r.Run(context.Background())
-	if err == nil || !strings.HasSuffix(err.Error(), "no Host in request URL") {
-		t.Fatal("not the error we expected")
-	}
-}
+	t.Run("with empty hostname", func(t *testing.T) {
+		r, _ := newTestRunner(t, Config{})
+		err := r.Run(context.Background(), "http:///foo.txt")
+		if err == nil || !strings.HasSuffix(err.Error(), "no Host in request URL") {
+			t.Fatal("not the error we expected")
+		}
+	})
 
-func TestRunnerTLSHandshakeSuccess(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skip test in short mode")
-	}
-	r := urlgetter.Runner{Target: "tlshandshake://www.google.com:443"}
-	err := r.Run(context.Background())
-	if err != nil {
-		t.Fatal(err)
-	}
-}
+	t.Run("with canceled context", func(t *testing.T) {
+		targets := []string{
+			"dnslookup://www.google.com",
+			"tlshandshake://www.google.com:443",
+			"tcpconnect://www.google.com:443",
+		}
+		for _, target := range targets {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			r, _ := newTestRunner(t, Config{})
+			err := r.Run(ctx, target)
+			if err == nil || err.Error() != netxlite.FailureInterrupted {
+				t.Fatal("not the error we expected", target, err)
+			}
+		}
+	})
 
-func TestRunnerTCPConnectSuccess(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skip test in short mode")
-	}
-	r := urlgetter.Runner{Target: "tcpconnect://www.google.com:443"}
-	err := r.Run(context.Background())
-	if err != nil {
-		t.Fatal(err)
-	}
-}
+	t.Run("with canceled context and HTTP", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		r, tk := newTestRunner(t, Config{})
+		err := r.Run(ctx, "https://www.google.com")
+		if !errors.Is(err, context.Canceled) {
+			t.Fatal("not the error we expected")
+		}
+		if len(tk.Requests) != 1 {
+			t.Fatal("not the Requests we expected")
+		}
+		if len(tk.NetworkEvents) != 2 {
+			t.Fatal("not the NetworkEvents we expected")
+		}
+	})
 
-func TestRunnerDNSLookupSuccess(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skip test in short mode")
-	}
-	r := urlgetter.Runner{Target: "dnslookup://www.google.com"}
-	err := r.Run(context.Background())
-	if err != nil {
-		t.Fatal(err)
-	}
+	t.Run("tcpconnect and tlshandshake use the legacy archival format", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(200)
+		}))
+		defer server.Close()
+		address := strings.TrimPrefix(server.URL, "https://")
+		r, tk := newTestRunner(t, Config{NoTLSVerify: true})
+		if err := r.Run(context.Background(), "tcpconnect://"+address); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Run(context.Background(), "tlshandshake://"+address); err != nil {
+			t.Fatal(err)
+		}
+		if len(tk.TCPConnect) != 2 {
+			t.Fatal("not the TCPConnect we expected")
+		}
+		for _, entry := range tk.TCPConnect {
+			if entry.T0 != 0 || entry.TransactionID != 0 || entry.Tags != nil {
+				t.Fatal("not the TCPConnect entry we expected", entry)
+			}
+		}
+		if len(tk.TLSHandshakes) != 1 || tk.TLSHandshakes[0].Failure != nil {
+			t.Fatal("not the TLSHandshakes we expected")
+		}
+		if tk.TLSHandshakes[0].Network != "" || tk.TLSHandshakes[0].TransactionID != 0 {
+			t.Fatal("not the TLSHandshakes entry we expected")
+		}
+		for _, ev := range tk.NetworkEvents {
+			if ev.Operation == "tls_handshake_done" && ev.Address != "" {
+				t.Fatal("not the NetworkEvents we expected", ev)
+			}
+		}
+	})
 }
 
-func TestRunnerHTTPSSuccess(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skip test in short mode")
-	}
-	r := urlgetter.Runner{Target: "https://www.google.com"}
-	err := r.Run(context.Background())
-	if err != nil {
-		t.Fatal(err)
-	}
-}
+func TestRunnerHTTP(t *testing.T) {
+	t.Run("we set the Host header", func(t *testing.T) {
+		var host string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host = r.Host
+			w.WriteHeader(200)
+		}))
+		defer server.Close()
+		r, tk := newTestRunner(t, Config{HTTPHost: "x.org"})
+		if err := r.Run(context.Background(), server.URL); err != nil {
+			t.Fatal(err)
+		}
+		if host != "x.org" {
+			t.Fatal("not the host we expected")
+		}
+		if tk.Requests[0].Request.Headers["Host"] != "x.org" {
+			t.Fatal("we did not archive the Host header")
+		}
+	})
 
-func TestRunnerHTTPSetHostHeader(t *testing.T) {
-	var host string
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		host = r.Host
-		w.WriteHeader(200)
-	}))
-	defer server.Close()
-	r := urlgetter.Runner{
-		Config: urlgetter.Config{
-			HTTPHost: "x.org",
-		},
-		Target: server.URL,
-	}
-	err := r.Run(context.Background())
-	if err != nil {
-		t.Fatal(err)
-	}
-	if host != "x.org" {
-		t.Fatal("not the host we expected")
-	}
-}
+	t.Run("we don't follow redirects when asked not to", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Location", "http:///") // cause failure if we redirect
+			w.WriteHeader(302)
+		}))
+		defer server.Close()
+		r, tk := newTestRunner(t, Config{NoFollowRedirects: true})
+		if err := r.Run(context.Background(), server.URL); err != nil {
+			t.Fatal(err)
+		}
+		if len(tk.Requests) != 1 {
+			t.Fatal("not the Requests we expected")
+		}
+	})
 
-func TestRunnerHTTPNoRedirect(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Location", "http:///") // cause failure if we redirect
-		w.WriteHeader(302)
-	}))
-	defer server.Close()
-	r := urlgetter.Runner{
-		Config: urlgetter.Config{
-			NoFollowRedirects: true,
-		},
-		Target: server.URL,
-	}
-	err := r.Run(context.Background())
-	if err != nil {
-		t.Fatal(err)
-	}
-}
+	t.Run("we follow redirects", func(t *testing.T) {
+		var (
+			cookie string
+			method string
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/":
+				http.SetCookie(w, &http.Cookie{Name: "antani", Value: "mascetti"})
+				w.Header().Add("Location", "/final")
+				w.WriteHeader(302)
+			default:
+				if c, err := r.Cookie("antani"); err == nil {
+					cookie = c.Value
+				}
+				method = r.Method
+				w.Write([]byte("final"))
+			}
+		}))
+		defer server.Close()
+		r, tk := newTestRunner(t, Config{Method: "POST"})
+		if err := r.Run(context.Background(), server.URL); err != nil {
+			t.Fatal(err)
+		}
+		if cookie != "mascetti" {
+			t.Fatal("we did not send the cookie")
+		}
+		if method != "GET" {
+			t.Fatal("we did not switch to GET")
+		}
+		if len(tk.Requests) != 2 {
+			t.Fatal("not the Requests we expected")
+		}
+		// OONI's convention is that the last request appears first
+		if tk.Requests[0].Request.URL != server.URL+"/final" {
+			t.Fatal("not the Requests[0] we expected")
+		}
+		if tk.Requests[1].Request.URL != server.URL {
+			t.Fatal("not the Requests[1] we expected")
+		}
+		if string(tk.Requests[0].Response.Body) != "final" {
+			t.Fatal("not the body we expected")
+		}
+		var count int
+		for _, ev := range tk.NetworkEvents {
+			if ev.Operation == "http_transaction_start" {
+				count++
+			}
+		}
+		if count != 2 {
+			t.Fatal("not the NetworkEvents we expected")
+		}
+	})
 
-func TestRunnerHTTPWithConnectionClosedByServer(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		hijacker, ok := w.(http.Hijacker)
-		if !ok {
-			panic("hijacking not supported by this server")
-		}
-		conn, _, _ := hijacker.Hijack()
-		conn.Write([]byte("HTTP/1.1 200 Ok\r\n"))
-		conn.Write([]byte("Content-Length: 1024\r\n"))
-		conn.Write([]byte("\r\n"))
-		conn.Write([]byte("123456789"))
-		conn.Close()
-	}))
-	defer server.Close()
-	r := urlgetter.Runner{
-		Config: urlgetter.Config{
-			NoFollowRedirects: true,
-		},
-		Target: server.URL,
-	}
-	err := r.Run(context.Background())
-	if err != nil {
-		t.Fatal(err)
-	}
-}
+	t.Run("we stop after too many redirects", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Location", "/")
+			w.WriteHeader(307)
+		}))
+		defer server.Close()
+		r, tk := newTestRunner(t, Config{})
+		err := r.Run(context.Background(), server.URL)
+		if !errors.Is(err, errHTTPTooManyRedirects) {
+			t.Fatal("not the error we expected", err)
+		}
+		if len(tk.Requests) != httpMaxRedirects {
+			t.Fatal("not the Requests we expected")
+		}
+	})
 
-func TestRunnerHTTPWeHandle400Correctly(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(400)
-	}))
-	defer server.Close()
-	r := urlgetter.Runner{
-		Config: urlgetter.Config{
-			FailOnHTTPError:   true,
-			NoFollowRedirects: true,
-		},
-		Target: server.URL,
-	}
-	err := r.Run(context.Background())
-	if !errors.Is(err, urlgetter.ErrHTTPRequestFailed) {
-		t.Fatal("not the error we expected")
-	}
-}
+	t.Run("with connection closed by server", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hijacker, ok := w.(http.Hijacker)
+			if !ok {
+				panic("hijacking not supported by this server")
+			}
+			conn, _, _ := hijacker.Hijack()
+			conn.Write([]byte("HTTP/1.1 200 Ok\r\n"))
+			conn.Write([]byte("Content-Length: 1024\r\n"))
+			conn.Write([]byte("\r\n"))
+			conn.Write([]byte("123456789"))
+			conn.Close()
+		}))
+		defer server.Close()
+		r, _ := newTestRunner(t, Config{NoFollowRedirects: true})
+		if err := r.Run(context.Background(), server.URL); err != nil {
+			t.Fatal(err)
+		}
+	})
 
-func TestRunnerHTTPWithConnectionClosedByServerAnd400(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		hijacker, ok := w.(http.Hijacker)
-		if !ok {
-			panic("hijacking not supported by this server")
-		}
-		conn, _, _ := hijacker.Hijack()
-		conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n"))
-		conn.Write([]byte("Content-Length: 1024\r\n"))
-		conn.Write([]byte("\r\n"))
-		conn.Write([]byte("123456789"))
-		conn.Close()
-	}))
-	defer server.Close()
-	r := urlgetter.Runner{
-		Config: urlgetter.Config{
-			FailOnHTTPError:   true,
-			NoFollowRedirects: true,
-		},
-		Target: server.URL,
-	}
-	err := r.Run(context.Background())
-	if !errors.Is(err, urlgetter.ErrHTTPRequestFailed) {
-		t.Fatal("not the error we expected", err)
-	}
-}
+	t.Run("we handle 400 correctly", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(400)
+		}))
+		defer server.Close()
+		r, _ := newTestRunner(t, Config{FailOnHTTPError: true, NoFollowRedirects: true})
+		err := r.Run(context.Background(), server.URL)
+		if !errors.Is(err, ErrHTTPRequestFailed) {
+			t.Fatal("not the error we expected")
+		}
+	})
 
-func TestRunnerWeCanForceUserAgent(t *testing.T) {
-	expected := "antani/1.23.4-dev"
-	found := &atomic.Int64{}
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("User-Agent") == expected {
-			found.Add(1)
-		}
-		w.WriteHeader(200)
-	}))
-	defer server.Close()
-	r := urlgetter.Runner{
-		Config: urlgetter.Config{
+	t.Run("with connection closed by server and 400", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hijacker, ok := w.(http.Hijacker)
+			if !ok {
+				panic("hijacking not supported by this server")
+			}
+			conn, _, _ := hijacker.Hijack()
+			conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n"))
+			conn.Write([]byte("Content-Length: 1024\r\n"))
+			conn.Write([]byte("\r\n"))
+			conn.Write([]byte("123456789"))
+			conn.Close()
+		}))
+		defer server.Close()
+		r, _ := newTestRunner(t, Config{FailOnHTTPError: true, NoFollowRedirects: true})
+		err := r.Run(context.Background(), server.URL)
+		if !errors.Is(err, ErrHTTPRequestFailed) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("we can force the User-Agent", func(t *testing.T) {
+		expected := "antani/1.23.4-dev"
+		found := &atomic.Int64{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("User-Agent") == expected {
+				found.Add(1)
+			}
+			w.WriteHeader(200)
+		}))
+		defer server.Close()
+		r, _ := newTestRunner(t, Config{
 			FailOnHTTPError:   true,
 			NoFollowRedirects: true,
 			UserAgent:         expected,
-		},
-		Target: server.URL,
-	}
-	err := r.Run(context.Background())
-	if err != nil {
-		t.Fatal(err)
-	}
-	if found.Load() != 1 {
-		t.Fatal("we didn't override the user agent")
-	}
-}
+		})
+		if err := r.Run(context.Background(), server.URL); err != nil {
+			t.Fatal(err)
+		}
+		if found.Load() != 1 {
+			t.Fatal("we didn't override the user agent")
+		}
+	})
 
-func TestRunnerDefaultUserAgent(t *testing.T) {
-	expected := model.HTTPHeaderUserAgent
-	found := &atomic.Int64{}
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("User-Agent") == expected {
-			found.Add(1)
-		}
-		w.WriteHeader(200)
-	}))
-	defer server.Close()
-	r := urlgetter.Runner{
-		Config: urlgetter.Config{
-			FailOnHTTPError:   true,
-			NoFollowRedirects: true,
-		},
-		Target: server.URL,
-	}
-	err := r.Run(context.Background())
-	if err != nil {
-		t.Fatal(err)
-	}
-	if found.Load() != 1 {
-		t.Fatal("we didn't override the user agent")
-	}
+	t.Run("we use the default User-Agent", func(t *testing.T) {
+		expected := model.HTTPHeaderUserAgent
+		found := &atomic.Int64{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("User-Agent") == expected {
+				found.Add(1)
+			}
+			w.WriteHeader(200)
+		}))
+		defer server.Close()
+		r, _ := newTestRunner(t, Config{FailOnHTTPError: true, NoFollowRedirects: true})
+		if err := r.Run(context.Background(), server.URL); err != nil {
+			t.Fatal(err)
+		}
+		if found.Load() != 1 {
+			t.Fatal("we didn't override the user agent")
+		}
+	})
 }
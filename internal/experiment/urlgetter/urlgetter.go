@@ -2,8 +2,8 @@
 //
 // See https://github.com/ooni/spec/blob/master/nettests/ts-027-urlgetter.md.
 //
-// This package is now frozen. Please, use measurexlite for new code. New
-// network experiments should not depend on this package. Please see
+// This package is now implemented using measurexlite. New network experiments
+// should not depend on this package and should use measurexlite directly. Please see
 // https://github.com/ooni/probe-cli/blob/master/docs/design/dd-003-step-by-step.md
 // for details about this.
 package urlgetter
@@ -13,13 +13,12 @@ import (
 	"crypto/x509"
 	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
 const (
 	testName    = "urlgetter"
-	testVersion = "0.2.0"
+	testVersion = "0.2.1"
 )
 
 // Config contains the experiment's configuration.
@@ -50,18 +49,18 @@ type Config struct {
 // TestKeys contains the experiment's result.
 type TestKeys struct {
 	// The following fields are part of the typical JSON emitted by OONI.
-	Agent           string                   `json:"agent"`
-	BootstrapTime   float64                  `json:"bootstrap_time,omitempty"`
-	DNSCache        []string                 `json:"dns_cache,omitempty"`
-	FailedOperation *string                  `json:"failed_operation"`
-	Failure         *string                  `json:"failure"`
-	NetworkEvents   []tracex.NetworkEvent    `json:"network_events"`
-	Queries         []tracex.DNSQueryEntry   `json:"queries"`
-	Requests        []tracex.RequestEntry    `json:"requests"`
-	SOCKSProxy      string                   `json:"socksproxy,omitempty"`
-	TCPConnect      []tracex.TCPConnectEntry `json:"tcp_connect"`
-	TLSHandshakes   []tracex.TLSHandshake    `json:"tls_handshakes"`
-	Tunnel          string                   `json:"tunnel,omitempty"`
+	Agent           string                                   `json:"agent"`
+	BootstrapTime   float64                                  `json:"bootstrap_time,omitempty"`
+	DNSCache        []string                                 `json:"dns_cache,omitempty"`
+	FailedOperation *string                                  `json:"failed_operation"`
+	Failure         *string                                  `json:"failure"`
+	NetworkEvents   []model.ArchivalNetworkEvent             `json:"network_events"`
+	Queries         []model.ArchivalDNSLookupResult          `json:"queries"`
+	Requests        []model.ArchivalHTTPRequestResult        `json:"requests"`
+	SOCKSProxy      string                                   `json:"socksproxy,omitempty"`
+	TCPConnect      []model.ArchivalTCPConnectResult         `json:"tcp_connect"`
+	TLSHandshakes   []model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
+	Tunnel          string                                   `json:"tunnel,omitempty"`
 
 	// The following fields are not serialised but are useful to simplify
 	// analysing the measurements in telegram, whatsapp, etc.
@@ -73,12 +72,12 @@ type TestKeys struct {
 // RegisterExtensions registers the extensions used by the urlgetter
 // experiment into the provided measurement.
 func RegisterExtensions(m *model.Measurement) {
-	tracex.ExtHTTP.AddTo(m)
-	tracex.ExtDNS.AddTo(m)
-	tracex.ExtNetevents.AddTo(m)
-	tracex.ExtTCPConnect.AddTo(m)
-	tracex.ExtTLSHandshake.AddTo(m)
-	tracex.ExtTunnel.AddTo(m)
+	model.ArchivalExtHTTP.AddTo(m)
+	model.ArchivalExtDNS.AddTo(m)
+	model.ArchivalExtNetevents.AddTo(m)
+	model.ArchivalExtTCPConnect.AddTo(m)
+	model.ArchivalExtTLSHandshake.AddTo(m)
+	model.ArchivalExtTunnel.AddTo(m)
 }
 
 // Measurer performs the measurement.
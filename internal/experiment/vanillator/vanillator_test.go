@@ -24,7 +24,7 @@ func TestExperimentNameAndVersion(t *testing.T) {
 	if m.ExperimentName() != "vanilla_tor" {
 		t.Fatal("invalid experiment name")
 	}
-	if m.ExperimentVersion() != "0.3.0" {
+	if m.ExperimentVersion() != "0.3.1" {
 		t.Fatal("invalid experiment version")
 	}
 }
@@ -6,7 +6,6 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
 	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivity"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/randx"
 )
@@ -43,8 +42,8 @@ func TestHTTPBodyLengthChecks(t *testing.T) {
 		name: "response body is truncated",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						BodyIsTruncated: true,
 					},
 				}},
@@ -60,8 +59,8 @@ func TestHTTPBodyLengthChecks(t *testing.T) {
 		name: "response body length is zero",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{},
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{},
 				}},
 			},
 			ctrl: webconnectivity.ControlResponse{
@@ -75,8 +74,8 @@ func TestHTTPBodyLengthChecks(t *testing.T) {
 		name: "control length is negative",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Body: model.ArchivalScrubbedMaybeBinaryString(
 							randx.Letters(768),
 						),
@@ -94,8 +93,8 @@ func TestHTTPBodyLengthChecks(t *testing.T) {
 		name: "match with bigger control",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Body: model.ArchivalScrubbedMaybeBinaryString(
 							randx.Letters(768),
 						),
@@ -114,8 +113,8 @@ func TestHTTPBodyLengthChecks(t *testing.T) {
 		name: "match with bigger measurement",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Body: model.ArchivalScrubbedMaybeBinaryString(
 							randx.Letters(1024),
 						),
@@ -134,8 +133,8 @@ func TestHTTPBodyLengthChecks(t *testing.T) {
 		name: "not match with bigger control",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Body: model.ArchivalScrubbedMaybeBinaryString(
 							randx.Letters(8),
 						),
@@ -154,8 +153,8 @@ func TestHTTPBodyLengthChecks(t *testing.T) {
 		name: "match with bigger measurement",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Body: model.ArchivalScrubbedMaybeBinaryString(
 							randx.Letters(16),
 						),
@@ -204,15 +203,15 @@ func TestStatusCodeMatch(t *testing.T) {
 		name: "with a request but zero status codes",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{}},
+				Requests: []model.ArchivalHTTPRequestResult{{}},
 			},
 		},
 	}, {
 		name: "with equal status codes including 5xx",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 501,
 					},
 				}},
@@ -228,8 +227,8 @@ func TestStatusCodeMatch(t *testing.T) {
 		name: "with different status codes and the control being 5xx",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 407,
 					},
 				}},
@@ -245,8 +244,8 @@ func TestStatusCodeMatch(t *testing.T) {
 		name: "with different status codes and the control being not 5xx",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 407,
 					},
 				}},
@@ -262,8 +261,8 @@ func TestStatusCodeMatch(t *testing.T) {
 		name: "with only response status code and no control status code",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 200,
 					},
 				}},
@@ -273,8 +272,8 @@ func TestStatusCodeMatch(t *testing.T) {
 		name: "with response status code and -1 as control status code",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 200,
 					},
 				}},
@@ -289,8 +288,8 @@ func TestStatusCodeMatch(t *testing.T) {
 		name: "with only control status code and no response status code",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 0,
 					},
 				}},
@@ -347,7 +346,7 @@ func TestHeadersMatch(t *testing.T) {
 		name: "with request and no response status code",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{}},
+				Requests: []model.ArchivalHTTPRequestResult{{}},
 			},
 			ctrl: webconnectivity.ControlResponse{
 				HTTPRequest: webconnectivity.ControlHTTPRequestResult{
@@ -364,8 +363,8 @@ func TestHeadersMatch(t *testing.T) {
 		name: "with no control status code",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Headers: map[string]model.ArchivalScrubbedMaybeBinaryString{
 							"Date": "Mon Jul 13 21:10:08 CEST 2020",
 						},
@@ -380,8 +379,8 @@ func TestHeadersMatch(t *testing.T) {
 		name: "with negative control status code",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Headers: map[string]model.ArchivalScrubbedMaybeBinaryString{
 							"Date": "Mon Jul 13 21:10:08 CEST 2020",
 						},
@@ -400,8 +399,8 @@ func TestHeadersMatch(t *testing.T) {
 		name: "with no uncommon headers",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Headers: map[string]model.ArchivalScrubbedMaybeBinaryString{
 							"Date": "Mon Jul 13 21:10:08 CEST 2020",
 						},
@@ -423,8 +422,8 @@ func TestHeadersMatch(t *testing.T) {
 		name: "with equal uncommon headers",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Headers: map[string]model.ArchivalScrubbedMaybeBinaryString{
 							"Date":   "Mon Jul 13 21:10:08 CEST 2020",
 							"Antani": "MASCETTI",
@@ -448,8 +447,8 @@ func TestHeadersMatch(t *testing.T) {
 		name: "with different uncommon headers",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Headers: map[string]model.ArchivalScrubbedMaybeBinaryString{
 							"Date":   "Mon Jul 13 21:10:08 CEST 2020",
 							"Antani": "MASCETTI",
@@ -473,8 +472,8 @@ func TestHeadersMatch(t *testing.T) {
 		name: "with small uncommon intersection (X-Cache)",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Headers: map[string]model.ArchivalScrubbedMaybeBinaryString{
 							"Accept-Ranges":  "bytes",
 							"Age":            "404727",
@@ -520,8 +519,8 @@ func TestHeadersMatch(t *testing.T) {
 		name: "with no uncommon intersection",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Headers: map[string]model.ArchivalScrubbedMaybeBinaryString{
 							"Accept-Ranges":  "bytes",
 							"Age":            "404727",
@@ -565,8 +564,8 @@ func TestHeadersMatch(t *testing.T) {
 		name: "with exactly equal headers",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Headers: map[string]model.ArchivalScrubbedMaybeBinaryString{
 							"Accept-Ranges": "bytes",
 							"Age":           "404727",
@@ -606,8 +605,8 @@ func TestHeadersMatch(t *testing.T) {
 		name: "with equal headers except for the case",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Headers: map[string]model.ArchivalScrubbedMaybeBinaryString{
 							"accept-ranges": "bytes",
 							"AGE":           "404727",
@@ -675,7 +674,7 @@ func TestTitleMatch(t *testing.T) {
 		name: "with a request and no response",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{}},
+				Requests: []model.ArchivalHTTPRequestResult{{}},
 			},
 		},
 		wantOut: nil,
@@ -683,8 +682,8 @@ func TestTitleMatch(t *testing.T) {
 		name: "with a response with truncated body",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code:            200,
 						BodyIsTruncated: true,
 					},
@@ -696,8 +695,8 @@ func TestTitleMatch(t *testing.T) {
 		name: "with a response with good body",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 200,
 						Body: model.ArchivalScrubbedMaybeBinaryString("<HTML/>"),
 					},
@@ -709,8 +708,8 @@ func TestTitleMatch(t *testing.T) {
 		name: "with all good but no titles",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 200,
 						Body: model.ArchivalScrubbedMaybeBinaryString("<HTML/>"),
 					},
@@ -728,8 +727,8 @@ func TestTitleMatch(t *testing.T) {
 		name: "reasonably common case where it succeeds",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 200,
 						Body: model.ArchivalScrubbedMaybeBinaryString(
 							"<HTML><TITLE>La community di MSN</TITLE></HTML>"),
@@ -748,8 +747,8 @@ func TestTitleMatch(t *testing.T) {
 		name: "reasonably common case where it fails",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 200,
 						Body: model.ArchivalScrubbedMaybeBinaryString(
 							"<HTML><TITLE>La communità di MSN</TITLE></HTML>"),
@@ -768,8 +767,8 @@ func TestTitleMatch(t *testing.T) {
 		name: "when the title is too long",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 200,
 						Body: model.ArchivalScrubbedMaybeBinaryString(
 							"<HTML><TITLE>" + randx.Letters(1024) + "</TITLE></HTML>"),
@@ -788,8 +787,8 @@ func TestTitleMatch(t *testing.T) {
 		name: "reasonably common case where it succeeds with case variations",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 200,
 						Body: model.ArchivalScrubbedMaybeBinaryString(
 							"<HTML><TiTLe>La commUNity di MSN</tITLE></HTML>"),
@@ -808,8 +807,8 @@ func TestTitleMatch(t *testing.T) {
 		name: "when the control status code is negative",
 		args: args{
 			tk: urlgetter.TestKeys{
-				Requests: []tracex.RequestEntry{{
-					Response: tracex.HTTPResponse{
+				Requests: []model.ArchivalHTTPRequestResult{{
+					Response: model.ArchivalHTTPResponse{
 						Code: 200,
 						Body: model.ArchivalScrubbedMaybeBinaryString(
 							"<HTML><TiTLe>La commUNity di MSN</tITLE></HTML>"),
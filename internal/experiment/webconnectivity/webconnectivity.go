@@ -9,13 +9,13 @@ import (
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivity/internal"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
 const (
 	testName    = "web_connectivity"
-	testVersion = "0.4.3"
+	testVersion = "0.4.4"
 )
 
 // Config contains the experiment config.
@@ -34,12 +34,12 @@ type TestKeys struct {
 	// is rather obvious where they come from.
 	//
 	// See https://github.com/ooni/probe/issues/1413.
-	NetworkEvents []tracex.NetworkEvent `json:"network_events"`
-	TLSHandshakes []tracex.TLSHandshake `json:"tls_handshakes"`
+	NetworkEvents []model.ArchivalNetworkEvent             `json:"network_events"`
+	TLSHandshakes []model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
 
 	// DNS experiment
-	Queries              []tracex.DNSQueryEntry `json:"queries"`
-	DNSExperimentFailure *string                `json:"dns_experiment_failure"`
+	Queries              []model.ArchivalDNSLookupResult `json:"queries"`
+	DNSExperimentFailure *string                         `json:"dns_experiment_failure"`
 	DNSAnalysisResult
 
 	// Control experiment
@@ -48,13 +48,13 @@ type TestKeys struct {
 	Control        ControlResponse `json:"control"`
 
 	// TCP/TLS "connect" experiment
-	TCPConnect          []tracex.TCPConnectEntry `json:"tcp_connect"`
-	TCPConnectSuccesses int                      `json:"-"`
-	TCPConnectAttempts  int                      `json:"-"`
+	TCPConnect          []model.ArchivalTCPConnectResult `json:"tcp_connect"`
+	TCPConnectSuccesses int                              `json:"-"`
+	TCPConnectAttempts  int                              `json:"-"`
 
 	// HTTP experiment
-	Requests              []tracex.RequestEntry `json:"requests"`
-	HTTPExperimentFailure *string               `json:"http_experiment_failure"`
+	Requests              []model.ArchivalHTTPRequestResult `json:"requests"`
+	HTTPExperimentFailure *string                           `json:"http_experiment_failure"`
 	HTTPAnalysisResult
 
 	// Top-level analysis
@@ -172,7 +172,7 @@ func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		}
 	}
 	tk.THRuntime = time.Since(thBegin)
-	tk.ControlFailure = tracex.NewFailure(err)
+	tk.ControlFailure = measurexlite.NewFailure(err)
 	// 4. analyze DNS results
 	if tk.ControlFailure == nil {
 		tk.DNSAnalysisResult = DNSAnalysis(URL, dnsResult, tk.Control)
@@ -232,9 +232,9 @@ func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 
 // ComputeTCPBlocking will return a copy of the input TCPConnect structure
 // where we set the Blocking value depending on the control results.
-func ComputeTCPBlocking(measurement []tracex.TCPConnectEntry,
-	control map[string]ControlTCPConnectResult) (out []tracex.TCPConnectEntry) {
-	out = []tracex.TCPConnectEntry{}
+func ComputeTCPBlocking(measurement []model.ArchivalTCPConnectResult,
+	control map[string]ControlTCPConnectResult) (out []model.ArchivalTCPConnectResult) {
+	out = []model.ArchivalTCPConnectResult{}
 	for _, me := range measurement {
 		epnt := net.JoinHostPort(me.IP, strconv.Itoa(me.Port))
 		if ce, ok := control[epnt]; ok {
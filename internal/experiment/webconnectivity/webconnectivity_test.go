@@ -11,7 +11,6 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivity"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
@@ -21,7 +20,7 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "web_connectivity" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.4.3" {
+	if measurer.ExperimentVersion() != "0.4.4" {
 		t.Fatal("unexpected version")
 	}
 }
@@ -254,33 +253,33 @@ func TestComputeTCPBlocking(t *testing.T) {
 	failure := io.EOF.Error()
 	anotherFailure := "unknown_error"
 	type args struct {
-		measurement []tracex.TCPConnectEntry
+		measurement []model.ArchivalTCPConnectResult
 		control     map[string]webconnectivity.ControlTCPConnectResult
 	}
 	tests := []struct {
 		name string
 		args args
-		want []tracex.TCPConnectEntry
+		want []model.ArchivalTCPConnectResult
 	}{{
 		name: "with all empty",
 		args: args{},
-		want: []tracex.TCPConnectEntry{},
+		want: []model.ArchivalTCPConnectResult{},
 	}, {
 		name: "with control failure",
 		args: args{
-			measurement: []tracex.TCPConnectEntry{{
+			measurement: []model.ArchivalTCPConnectResult{{
 				IP:   "1.1.1.1",
 				Port: 853,
-				Status: tracex.TCPConnectStatus{
+				Status: model.ArchivalTCPConnectStatus{
 					Failure: &failure,
 					Success: false,
 				},
 			}},
 		},
-		want: []tracex.TCPConnectEntry{{
+		want: []model.ArchivalTCPConnectResult{{
 			IP:   "1.1.1.1",
 			Port: 853,
-			Status: tracex.TCPConnectStatus{
+			Status: model.ArchivalTCPConnectStatus{
 				Failure: &failure,
 				Success: false,
 			},
@@ -288,10 +287,10 @@ func TestComputeTCPBlocking(t *testing.T) {
 	}, {
 		name: "with failures on both ends",
 		args: args{
-			measurement: []tracex.TCPConnectEntry{{
+			measurement: []model.ArchivalTCPConnectResult{{
 				IP:   "1.1.1.1",
 				Port: 853,
-				Status: tracex.TCPConnectStatus{
+				Status: model.ArchivalTCPConnectStatus{
 					Failure: &failure,
 					Success: false,
 				},
@@ -303,10 +302,10 @@ func TestComputeTCPBlocking(t *testing.T) {
 				},
 			},
 		},
-		want: []tracex.TCPConnectEntry{{
+		want: []model.ArchivalTCPConnectResult{{
 			IP:   "1.1.1.1",
 			Port: 853,
-			Status: tracex.TCPConnectStatus{
+			Status: model.ArchivalTCPConnectStatus{
 				Blocked: &falseValue,
 				Failure: &failure,
 				Success: false,
@@ -315,10 +314,10 @@ func TestComputeTCPBlocking(t *testing.T) {
 	}, {
 		name: "with failure on the probe side",
 		args: args{
-			measurement: []tracex.TCPConnectEntry{{
+			measurement: []model.ArchivalTCPConnectResult{{
 				IP:   "1.1.1.1",
 				Port: 853,
-				Status: tracex.TCPConnectStatus{
+				Status: model.ArchivalTCPConnectStatus{
 					Failure: &failure,
 					Success: false,
 				},
@@ -330,10 +329,10 @@ func TestComputeTCPBlocking(t *testing.T) {
 				},
 			},
 		},
-		want: []tracex.TCPConnectEntry{{
+		want: []model.ArchivalTCPConnectResult{{
 			IP:   "1.1.1.1",
 			Port: 853,
-			Status: tracex.TCPConnectStatus{
+			Status: model.ArchivalTCPConnectStatus{
 				Blocked: &trueValue,
 				Failure: &failure,
 				Success: false,
@@ -342,10 +341,10 @@ func TestComputeTCPBlocking(t *testing.T) {
 	}, {
 		name: "with failure on the control side",
 		args: args{
-			measurement: []tracex.TCPConnectEntry{{
+			measurement: []model.ArchivalTCPConnectResult{{
 				IP:   "1.1.1.1",
 				Port: 853,
-				Status: tracex.TCPConnectStatus{
+				Status: model.ArchivalTCPConnectStatus{
 					Failure: nil,
 					Success: true,
 				},
@@ -357,10 +356,10 @@ func TestComputeTCPBlocking(t *testing.T) {
 				},
 			},
 		},
-		want: []tracex.TCPConnectEntry{{
+		want: []model.ArchivalTCPConnectResult{{
 			IP:   "1.1.1.1",
 			Port: 853,
-			Status: tracex.TCPConnectStatus{
+			Status: model.ArchivalTCPConnectStatus{
 				Blocked: &falseValue,
 				Failure: nil,
 				Success: true,
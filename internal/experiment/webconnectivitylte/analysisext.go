@@ -10,6 +10,7 @@ package webconnectivitylte
 import (
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
 	"github.com/ooni/probe-cli/v3/internal/minipipeline"
@@ -50,7 +51,8 @@ func analysisExtMain(
 	analysisExtExpectedFailures(tk, analysis, &info)
 
 	// print the content of the analysis only if there's some content to print
-	if content := info.String(); content != "" {
+	// and we are not analyzing the results of measuring a subresource host
+	if content := info.String(); content != "" && !tk.subresource {
 		fmt.Printf("\n")
 		fmt.Printf("Extended Analysis\n")
 		fmt.Printf("-----------------\n")
@@ -89,6 +91,83 @@ func analysisExtDNS(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Wr
 		tk.DNSFlags |= AnalysisDNSFlagUnexpectedAddrs
 		fmt.Fprintf(info, "- transactions with invalid IP addrs: %s\n", failures.String())
 	}
+
+	// compute the DNS consistency of each resolver we used
+	for _, entry := range minipipeline.AnalyzeDNSResolvers(analysis, tk.Queries...) {
+		tk.DNSResolvers = append(tk.DNSResolvers, &DNSResolverConsistency{
+			Engine:         entry.Engine,
+			Address:        entry.Address,
+			Consistency:    entry.Consistency,
+			TransactionIDs: entry.DNSTransactionIDs.Keys(),
+		})
+		if entry.Consistency.UnwrapOr("") == "inconsistent" {
+			fmt.Fprintf(info, "- resolver %s/%s is inconsistent: %s\n",
+				entry.Address, entry.Engine, entry.DNSTransactionIDs.String())
+		}
+	}
+
+	// match the DNS answers against the known blockpage fingerprints
+	if tk.fingerprints == nil {
+		return
+	}
+	matches := minipipeline.NewSet[int64]()
+	for _, query := range tk.Queries {
+		for _, answer := range query.Answers {
+			var ids []string
+			switch answer.AnswerType {
+			case "A":
+				ids = tk.fingerprints.MatchDNSAnswer(answer.IPv4)
+			case "AAAA":
+				ids = tk.fingerprints.MatchDNSAnswer(answer.IPv6)
+			}
+			if len(ids) > 0 {
+				analysisExtAddBlockingFingerprints(tk, ids...)
+				matches.Add(query.TransactionID)
+			}
+		}
+	}
+	if matches.Len() > 0 {
+		tk.BlockingFlags |= AnalysisBlockingFlagDNSBlocking
+		fmt.Fprintf(info, "- transactions with known blockpage IP addrs: %s\n", matches.String())
+	}
+}
+
+// analysisExtAddBlockingFingerprints adds the given fingerprint IDs to the test keys
+// unless they are already there, such that we record each ID exactly once.
+func analysisExtAddBlockingFingerprints(tk *TestKeys, ids ...string) {
+	for _, id := range ids {
+		if !slices.Contains(tk.BlockingFingerprint, id) {
+			tk.BlockingFingerprint = append(tk.BlockingFingerprint, id)
+		}
+	}
+}
+
+// analysisExtMatchHTTPFinalResponse matches the cleartext final response against the
+// known blockpage fingerprints and returns whether we found any match.
+func analysisExtMatchHTTPFinalResponse(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) bool {
+	if tk.fingerprints == nil {
+		return false
+	}
+	txID := analysis.HTTPFinalResponseSuccessTCPWithControl.UnwrapOr(
+		analysis.HTTPFinalResponseSuccessTCPWithoutControl.UnwrapOr(0))
+	if txID == 0 {
+		return false
+	}
+	for _, request := range tk.Requests {
+		if request.TransactionID != txID {
+			continue
+		}
+		ids := tk.fingerprints.MatchHTTPResponse(&request.Response)
+		if len(ids) <= 0 {
+			return false
+		}
+		analysisExtAddBlockingFingerprints(tk, ids...)
+		tk.BlockingFlags |= AnalysisBlockingFlagHTTPDiff
+		fmt.Fprintf(info, "- the final response (transaction: %d) is a known blockpage: %s\n",
+			txID, strings.Join(ids, ", "))
+		return true
+	}
+	return false
 }
 
 func analysisExtEndpointFailure(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
@@ -119,6 +198,13 @@ func analysisExtEndpointFailure(tk *TestKeys, analysis *minipipeline.WebAnalysis
 }
 
 func analysisExtHTTPFinalResponse(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
+	// case #0: HTTP final response matching a known blockpage
+	//
+	// this is blocking regardless of whether we have control information.
+	if analysisExtMatchHTTPFinalResponse(tk, analysis, info) {
+		return
+	}
+
 	// case #1: HTTP final response without control
 	//
 	// we don't know what to do in this case.
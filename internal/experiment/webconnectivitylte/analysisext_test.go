@@ -0,0 +1,80 @@
+package webconnectivitylte
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/checkincache"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+)
+
+func TestAnalysisExtAddBlockingFingerprints(t *testing.T) {
+	tk := NewTestKeys()
+	analysisExtAddBlockingFingerprints(tk, "a", "b")
+	analysisExtAddBlockingFingerprints(tk, "b", "c")
+	if diff := cmp.Diff([]string{"a", "b", "c"}, tk.BlockingFingerprint); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestMeasurerWithBlockpageFingerprints(t *testing.T) {
+	// seed the key-value store with a check-in provided database
+	// that is more recent than the embedded one
+	kvStore := &kvstore.Memory{}
+	resp := &model.OOAPICheckInResult{
+		Conf: model.OOAPICheckInResultConfig{
+			BlockpageFingerprints: []byte(`{
+				"version": 1000,
+				"fingerprints": [{
+					"id": "dns_netemx_blockpage",
+					"type": "dns",
+					"pattern": "^83\\.224\\.65\\.41$"
+				}, {
+					"id": "http_body_netemx_blockpage",
+					"type": "http_body",
+					"pattern": "This request cannot be served in your jurisdiction"
+				}]
+			}`),
+		},
+	}
+	if err := checkincache.Store(kvStore, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("with a legitimate webpage", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+
+		tk := qaRunMeasurerWithKeyValueStore(t, env, kvStore, &Config{}, "http://www.example.com/")
+		if len(tk.BlockingFingerprint) != 0 {
+			t.Fatal("unexpected fingerprints", tk.BlockingFingerprint)
+		}
+		if tk.BlockingFlags != AnalysisBlockingFlagSuccess {
+			t.Fatal("unexpected blocking flags", tk.BlockingFlags)
+		}
+	})
+
+	t.Run("with DNS spoofing redirecting to a blockpage", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+
+		env.DPIEngine().AddRule(&netem.DPISpoofDNSResponse{
+			Addresses: []string{netemx.AddressPublicBlockpage},
+			Logger:    env.Logger(),
+			Domain:    "www.example.com",
+		})
+
+		tk := qaRunMeasurerWithKeyValueStore(t, env, kvStore, &Config{}, "http://www.example.com/")
+		expect := []string{"dns_netemx_blockpage", "http_body_netemx_blockpage"}
+		if diff := cmp.Diff(expect, tk.BlockingFingerprint); diff != "" {
+			t.Fatal(diff)
+		}
+		expectFlags := int64(AnalysisBlockingFlagDNSBlocking | AnalysisBlockingFlagHTTPDiff)
+		if tk.BlockingFlags&expectFlags != expectFlags {
+			t.Fatal("unexpected blocking flags", tk.BlockingFlags)
+		}
+	})
+}
@@ -79,6 +79,14 @@ type CleartextFlow struct {
 	// Referer contains the OPTIONAL referer, used for redirects.
 	Referer string
 
+	// Resolvers contains the OPTIONAL resolvers configured by the user, which
+	// we use when following redirects. See [DNSResolvers] for more info.
+	Resolvers []*DNSResolverSpec
+
+	// Subresources is the OPTIONAL [*Subresources] to notify about the final
+	// webpage. When this field is nil, we do not measure subresources.
+	Subresources *Subresources
+
 	// UDPAddress is the OPTIONAL address of the UDP resolver to use. If this
 	// field is not set we use a default one (e.g., `8.8.8.8:53`).
 	UDPAddress string
@@ -193,8 +201,10 @@ func (t *CleartextFlow) Run(parentCtx context.Context, index int64) error {
 	// if enabled, follow possible redirects
 	t.maybeFollowRedirects(parentCtx, httpResp)
 
-	// ignore the response body
-	_ = httpRespBody
+	// if enabled, measure the subresources of the final webpage
+	if t.Subresources != nil {
+		t.Subresources.MaybeStart(parentCtx, httpResp, httpRespBody)
+	}
 
 	// completed successfully
 	ol.Stop(nil)
@@ -342,6 +352,8 @@ func (t *CleartextFlow) maybeFollowRedirects(ctx context.Context, resp *http.Res
 			ZeroTime:                t.ZeroTime,
 			WaitGroup:               t.WaitGroup,
 			Referer:                 resp.Request.URL.String(),
+			Resolvers:               t.Resolvers,
+			Subresources:            t.Subresources,
 			Session:                 nil, // no need to issue another control request
 			TestHelpers:             nil, // ditto
 			UDPAddress:              t.UDPAddress,
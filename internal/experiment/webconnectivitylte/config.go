@@ -7,4 +7,29 @@ package webconnectivitylte
 // Config contains webconnectivity experiment configuration.
 type Config struct {
 	DNSOverUDPResolver string
+
+	// DNSResolvers is a comma-separated list of resolvers to use instead of the default
+	// UDP and DNS-over-HTTPS resolvers (e.g., `udp://8.8.8.8:53,dot://1.1.1.1`). We always
+	// use the system resolver, which is the baseline of the classic analysis.
+	DNSResolvers string `ooni:"comma-separated list of resolvers (system, udp://, tcp://, dot://, https://)"`
+
+	// FetchSubresources enables measuring the hosts serving the subresources
+	// (e.g., scripts, stylesheets, images) of the final webpage.
+	FetchSubresources bool `ooni:"measure the hosts serving the subresources of the final webpage"`
+
+	// MaxSubresourceHosts is the maximum number of subresource hosts to measure.
+	MaxSubresourceHosts int64 `ooni:"maximum number of subresource hosts to measure"`
+}
+
+// dnsResolvers returns the resolvers configured by the user, if any.
+func (c *Config) dnsResolvers() ([]*DNSResolverSpec, error) {
+	return parseDNSResolvers(c.DNSResolvers)
+}
+
+// maxSubresourceHosts returns the maximum number of subresource hosts to measure.
+func (c *Config) maxSubresourceHosts() int {
+	if c.MaxSubresourceHosts > 0 {
+		return int(c.MaxSubresourceHosts)
+	}
+	return 4
 }
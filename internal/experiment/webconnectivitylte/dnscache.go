@@ -20,6 +20,12 @@ const (
 
 	// DNSAddrFlagHTTPS means we discovered this addr using the DNS-over-HTTPS resolver.
 	DNSAddrFlagHTTPS
+
+	// DNSAddrFlagTCP means we discovered this addr using a DNS-over-TCP resolver.
+	DNSAddrFlagTCP
+
+	// DNSAddrFlagTLS means we discovered this addr using a DNS-over-TLS resolver.
+	DNSAddrFlagTLS
 )
 
 // DNSCache wraps a model.Resolver to provide DNS caching.
@@ -8,6 +8,7 @@ package webconnectivitylte
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -67,6 +68,16 @@ type DNSResolvers struct {
 	// Referer contains the OPTIONAL referer, used for redirects.
 	Referer string
 
+	// Resolvers contains the OPTIONAL resolvers configured by the user. When this
+	// field is empty, we use the system resolver, the resolver at UDPAddress, and
+	// opportunistic DNS-over-HTTPS. Otherwise, we use the system resolver along
+	// with the configured resolvers and we ignore UDPAddress.
+	Resolvers []*DNSResolverSpec
+
+	// Subresources is the OPTIONAL [*Subresources] to notify about the final
+	// webpage. When this field is nil, we do not measure subresources.
+	Subresources *Subresources
+
 	// Session is the OPTIONAL session. If the session is set, we will use
 	// it to start the task that issues the control request. This request must
 	// only be sent during the first iteration. It would be pointless to
@@ -99,6 +110,10 @@ var MaybeSortAddresses = func(entries []DNSEntry) {
 
 // run performs a DNS lookup and returns the looked up addrs
 func (t *DNSResolvers) run(parentCtx context.Context) []DNSEntry {
+	if len(t.Resolvers) > 0 {
+		return t.runWithResolvers(parentCtx)
+	}
+
 	// create output channels for the lookup
 	systemOut := make(chan []string)
 	udpOut := make(chan []string)
@@ -132,27 +147,86 @@ func (t *DNSResolvers) run(parentCtx context.Context) []DNSEntry {
 
 	// merge the resolved IP addresses
 	merged := map[string]*DNSEntry{}
-	for _, addr := range systemAddrs {
-		if _, found := merged[addr]; !found {
-			merged[addr] = &DNSEntry{}
-		}
-		merged[addr].Addr = addr
-		merged[addr].Flags |= DNSAddrFlagSystemResolver
+	dnsMergeAddrs(merged, systemAddrs, DNSAddrFlagSystemResolver)
+	dnsMergeAddrs(merged, udpAddrs, DNSAddrFlagUDP)
+	dnsMergeAddrs(merged, httpsAddrs, DNSAddrFlagHTTPS)
+	return dnsEntriesFromMerged(merged)
+}
+
+// runWithResolvers is like run but uses the resolvers configured by the user.
+func (t *DNSResolvers) runWithResolvers(parentCtx context.Context) []DNSEntry {
+	// we always use the system resolver because the classic analysis depends on it
+	systemOut := make(chan []string)
+	whoamiSystemV4Out := make(chan []webconnectivityalgo.DNSWhoamiInfoEntry)
+	go t.lookupHostSystem(parentCtx, systemOut)
+	go t.whoamiSystemV4(parentCtx, whoamiSystemV4Out)
+
+	// start asynchronous lookups using the configured resolvers
+	type lookup struct {
+		flags int64
+		out   chan []string
 	}
-	for _, addr := range udpAddrs {
-		if _, found := merged[addr]; !found {
-			merged[addr] = &DNSEntry{}
+	var lookups []*lookup
+	whoamiUDPv4Out := map[string]chan []webconnectivityalgo.DNSWhoamiInfoEntry{}
+	for _, reso := range t.Resolvers {
+		out := make(chan []string)
+		switch reso.Engine {
+		case "udp":
+			lookups = append(lookups, &lookup{DNSAddrFlagUDP, out})
+			go t.lookupHostUDP(parentCtx, reso.Address, out)
+			whoamiOut := make(chan []webconnectivityalgo.DNSWhoamiInfoEntry)
+			whoamiUDPv4Out[reso.Address] = whoamiOut
+			go t.whoamiUDPv4(parentCtx, reso.Address, whoamiOut)
+		case "tcp":
+			lookups = append(lookups, &lookup{DNSAddrFlagTCP, out})
+			go t.lookupHostTCP(parentCtx, reso.Address, out)
+		case "dot":
+			lookups = append(lookups, &lookup{DNSAddrFlagTLS, out})
+			go t.lookupHostDNSOverTLS(parentCtx, reso.Address, out)
+		case "doh":
+			lookups = append(lookups, &lookup{DNSAddrFlagHTTPS, out})
+			go t.lookupHostDNSOverHTTPSWithURL(parentCtx, reso.Address, out)
+		default:
+			// nothing: we're already using the system resolver
 		}
-		merged[addr].Addr = addr
-		merged[addr].Flags |= DNSAddrFlagUDP
 	}
-	for _, addr := range httpsAddrs {
+
+	// collect resulting IP addresses (which may be nil/empty lists)
+	merged := map[string]*DNSEntry{}
+	dnsMergeAddrs(merged, <-systemOut, DNSAddrFlagSystemResolver)
+	for _, lookup := range lookups {
+		dnsMergeAddrs(merged, <-lookup.out, lookup.flags)
+	}
+
+	// collect whoami results (which also may be nil/empty)
+	whoamiSystemV4 := <-whoamiSystemV4Out
+	whoamiUDPv4 := map[string][]webconnectivityalgo.DNSWhoamiInfoEntry{}
+	for address, out := range whoamiUDPv4Out {
+		whoamiUDPv4[address] = <-out
+	}
+	t.TestKeys.WithDNSWhoami(func(di *DNSWhoamiInfo) {
+		di.SystemV4 = whoamiSystemV4
+		for address, value := range whoamiUDPv4 {
+			di.UDPv4[address] = value
+		}
+	})
+
+	return dnsEntriesFromMerged(merged)
+}
+
+// dnsMergeAddrs merges the given addrs into the given map using the given flags.
+func dnsMergeAddrs(merged map[string]*DNSEntry, addrs []string, flags int64) {
+	for _, addr := range addrs {
 		if _, found := merged[addr]; !found {
 			merged[addr] = &DNSEntry{}
 		}
 		merged[addr].Addr = addr
-		merged[addr].Flags |= DNSAddrFlagHTTPS
+		merged[addr].Flags |= flags
 	}
+}
+
+// dnsEntriesFromMerged converts merged addrs into a list of entries.
+func dnsEntriesFromMerged(merged map[string]*DNSEntry) []DNSEntry {
 	var entries []DNSEntry
 	for _, entry := range merged {
 		entries = append(entries, *entry)
@@ -287,6 +361,101 @@ func (t *DNSResolvers) waitForLateReplies(parentCtx context.Context, trace *meas
 	t.TestKeys.AppendDNSLateReplies(events...)
 }
 
+// lookupHostTCP performs a DNS lookup using a TCP resolver. This function must always
+// emit an ouput on the [out] channel to synchronize with the caller func.
+func (t *DNSResolvers) lookupHostTCP(parentCtx context.Context, tcpAddress string, out chan<- []string) {
+	// create context with attached a timeout
+	const timeout = 4 * time.Second
+	lookupCtx, lookpCancel := context.WithTimeout(parentCtx, timeout)
+	defer lookpCancel()
+
+	// create trace's index
+	index := t.IDGenerator.NewIDForDNSOverTCP()
+
+	// create trace
+	trace := measurexlite.NewTrace(index, t.ZeroTime, fmt.Sprintf("depth=%d", t.Depth))
+
+	// start the operation logger
+	ol := logx.NewOperationLogger(
+		t.Logger, "[#%d] lookup %s using %s/tcp", index, t.Domain, tcpAddress,
+	)
+
+	// runs the lookup
+	dialer := trace.NewDialerWithoutResolver(t.Logger)
+	reso := trace.NewParallelTCPResolver(t.Logger, dialer, tcpAddress)
+	addrs, err := reso.LookupHost(lookupCtx, t.Domain)
+
+	// saves the results making sure we split Do53 queries from other queries
+	do53, other := t.do53SplitQueries(trace.DNSLookupsFromRoundTrip())
+	t.TestKeys.AppendQueries(do53...)
+	t.TestKeys.WithTestKeysDo53(func(tkd *TestKeysDo53) {
+		tkd.Queries = append(tkd.Queries, other...)
+		tkd.NetworkEvents = append(tkd.NetworkEvents, trace.NetworkEvents()...)
+		tkd.TCPConnect = append(tkd.TCPConnect, trace.TCPConnects()...)
+	})
+
+	ol.Stop(err)
+	out <- addrs
+}
+
+// lookupHostDNSOverTLS performs a DNS lookup using a DoT resolver. This function must
+// always emit an ouput on the [out] channel to synchronize with the caller func.
+func (t *DNSResolvers) lookupHostDNSOverTLS(parentCtx context.Context, dotAddress string, out chan<- []string) {
+	// create context with attached a timeout
+	const timeout = 4 * time.Second
+	lookupCtx, lookpCancel := context.WithTimeout(parentCtx, timeout)
+	defer lookpCancel()
+
+	// create trace's index
+	index := t.IDGenerator.NewIDForDNSOverTLS()
+
+	// create trace
+	trace := measurexlite.NewTrace(index, t.ZeroTime, fmt.Sprintf("depth=%d", t.Depth))
+
+	// start the operation logger
+	ol := logx.NewOperationLogger(
+		t.Logger, "[#%d] lookup %s using %s/dot", index, t.Domain, dotAddress,
+	)
+
+	// runs the lookup
+	dialer := trace.NewDialerWithoutResolver(t.Logger)
+	tlsDialer := netxlite.NewTLSDialerWithConfig(
+		dialer,
+		trace.NewTLSHandshakerStdlib(t.Logger),
+		&tls.Config{NextProtos: []string{"dot"}},
+	)
+	reso := trace.NewParallelDNSOverTLSResolver(t.Logger, tlsDialer, dotAddress)
+	addrs, err := reso.LookupHost(lookupCtx, t.Domain)
+	reso.CloseIdleConnections()
+
+	// save results making sure we properly split DoT queries from other queries
+	dot, other := t.dotSplitQueries(trace.DNSLookupsFromRoundTrip())
+	t.TestKeys.AppendQueries(dot...)
+	t.TestKeys.WithTestKeysDoT(func(tkdt *TestKeysDoT) {
+		tkdt.Queries = append(tkdt.Queries, other...)
+		tkdt.NetworkEvents = append(tkdt.NetworkEvents, trace.NetworkEvents()...)
+		tkdt.TCPConnect = append(tkdt.TCPConnect, trace.TCPConnects()...)
+		tkdt.TLSHandshakes = append(tkdt.TLSHandshakes, trace.TLSHandshakes()...)
+	})
+
+	ol.Stop(err)
+	out <- addrs
+}
+
+// Divides queries generated by DoT in DoT-proper queries and other queries.
+func (t *DNSResolvers) dotSplitQueries(
+	input []*model.ArchivalDNSLookupResult) (dot, other []*model.ArchivalDNSLookupResult) {
+	for _, query := range input {
+		switch query.Engine {
+		case "dot":
+			dot = append(dot, query)
+		default:
+			other = append(other, query)
+		}
+	}
+	return
+}
+
 // Divides queries generated by Do53 in Do53-proper queries and other queries.
 func (t *DNSResolvers) do53SplitQueries(
 	input []*model.ArchivalDNSLookupResult) (do53, other []*model.ArchivalDNSLookupResult) {
@@ -320,7 +489,13 @@ func (t *DNSResolvers) lookupHostDNSOverHTTPS(parentCtx context.Context, out cha
 		out <- []string{}
 		return
 	}
+	t.lookupHostDNSOverHTTPSWithURL(parentCtx, URL, out)
+}
 
+// lookupHostDNSOverHTTPSWithURL performs a DNS lookup using the DoH resolver at the given
+// URL. This function must always emit an ouput on the [out] channel to synchronize with
+// the caller func.
+func (t *DNSResolvers) lookupHostDNSOverHTTPSWithURL(parentCtx context.Context, URL string, out chan<- []string) {
 	// create context with attached a timeout
 	const timeout = 4 * time.Second
 	lookupCtx, lookpCancel := context.WithTimeout(parentCtx, timeout)
@@ -409,6 +584,8 @@ func (t *DNSResolvers) startCleartextFlows(
 			HostHeader:              t.URL.Host,
 			PrioSelector:            ps,
 			Referer:                 t.Referer,
+			Resolvers:               t.Resolvers,
+			Subresources:            t.Subresources,
 			UDPAddress:              t.UDPAddress,
 			URLPath:                 t.URL.Path,
 			URLRawQuery:             t.URL.RawQuery,
@@ -462,6 +639,8 @@ func (t *DNSResolvers) startSecureFlows(
 			HostHeader:              t.URL.Host,
 			PrioSelector:            ps,
 			Referer:                 t.Referer,
+			Resolvers:               t.Resolvers,
+			Subresources:            t.Subresources,
 			UDPAddress:              t.UDPAddress,
 			URLPath:                 t.URL.Path,
 			URLRawQuery:             t.URL.RawQuery,
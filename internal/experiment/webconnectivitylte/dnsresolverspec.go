@@ -0,0 +1,93 @@
+package webconnectivitylte
+
+//
+// DNSResolverSpec
+//
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// DNSResolverSpec describes a resolver configured by the user.
+type DNSResolverSpec struct {
+	// Engine is the resolver engine: "system", "udp", "tcp", "dot", or "doh".
+	Engine string
+
+	// Address is the resolver address: the endpoint (e.g., `8.8.8.8:53`) for the
+	// "udp", "tcp", and "dot" engines, the URL for "doh", and empty for "system".
+	Address string
+}
+
+// errInvalidDNSResolver indicates that a resolver specification is invalid.
+var errInvalidDNSResolver = errors.New("webconnectivitylte: invalid DNS resolver")
+
+// parseDNSResolvers parses a comma-separated list of resolvers. Each entry is either
+// "system" or a URL such as `udp://8.8.8.8:53`, `tcp://8.8.8.8`, `dot://1.1.1.1`, or
+// `https://dns.google/dns-query`. When the port is missing, we use the default port
+// of the protocol. UDP, TCP, and DoT resolvers MUST use IP addresses because we dial
+// them without using any resolver. We remove duplicate entries.
+func parseDNSResolvers(spec string) ([]*DNSResolverSpec, error) {
+	var (
+		out  []*DNSResolverSpec
+		seen = make(map[DNSResolverSpec]bool)
+	)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		reso, err := parseDNSResolver(entry)
+		if err != nil {
+			return nil, err
+		}
+		if seen[*reso] {
+			continue
+		}
+		seen[*reso] = true
+		out = append(out, reso)
+	}
+	return out, nil
+}
+
+// parseDNSResolver parses a single resolver entry.
+func parseDNSResolver(entry string) (*DNSResolverSpec, error) {
+	if entry == "system" {
+		return &DNSResolverSpec{Engine: "system"}, nil
+	}
+	URL, err := url.Parse(entry)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidDNSResolver, err.Error())
+	}
+	switch URL.Scheme {
+	case "https":
+		if URL.Hostname() == "" {
+			return nil, fmt.Errorf("%w: %s: missing host", errInvalidDNSResolver, entry)
+		}
+		return &DNSResolverSpec{Engine: "doh", Address: URL.String()}, nil
+	case "udp", "tcp":
+		return parseDNSResolverEndpoint(entry, URL, URL.Scheme, "53")
+	case "dot":
+		return parseDNSResolverEndpoint(entry, URL, URL.Scheme, "853")
+	default:
+		return nil, fmt.Errorf("%w: %s: unsupported scheme", errInvalidDNSResolver, entry)
+	}
+}
+
+// parseDNSResolverEndpoint constructs a resolver spec using an IP endpoint address.
+func parseDNSResolverEndpoint(entry string, URL *url.URL, engine, defaultPort string) (*DNSResolverSpec, error) {
+	if URL.Path != "" || URL.RawQuery != "" {
+		return nil, fmt.Errorf("%w: %s: unexpected path or query", errInvalidDNSResolver, entry)
+	}
+	if net.ParseIP(URL.Hostname()) == nil {
+		return nil, fmt.Errorf("%w: %s: expected an IP address", errInvalidDNSResolver, entry)
+	}
+	port := URL.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	return &DNSResolverSpec{Engine: engine, Address: net.JoinHostPort(URL.Hostname(), port)}, nil
+}
@@ -0,0 +1,117 @@
+package webconnectivitylte
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+)
+
+func TestParseDNSResolvers(t *testing.T) {
+	type testcase struct {
+		name   string
+		spec   string
+		expect []*DNSResolverSpec
+		err    error
+	}
+
+	cases := []testcase{{
+		name:   "with empty spec",
+		spec:   "",
+		expect: nil,
+		err:    nil,
+	}, {
+		name: "with all the supported engines",
+		spec: "system, udp://8.8.8.8:53,tcp://8.8.8.8,dot://[2606:4700:4700::1111],https://dns.google/dns-query",
+		expect: []*DNSResolverSpec{
+			{Engine: "system", Address: ""},
+			{Engine: "udp", Address: "8.8.8.8:53"},
+			{Engine: "tcp", Address: "8.8.8.8:53"},
+			{Engine: "dot", Address: "[2606:4700:4700::1111]:853"},
+			{Engine: "doh", Address: "https://dns.google/dns-query"},
+		},
+		err: nil,
+	}, {
+		name: "with duplicate entries",
+		spec: "udp://1.1.1.1,udp://1.1.1.1:53,,dot://1.1.1.1:853",
+		expect: []*DNSResolverSpec{
+			{Engine: "udp", Address: "1.1.1.1:53"},
+			{Engine: "dot", Address: "1.1.1.1:853"},
+		},
+		err: nil,
+	}, {
+		name:   "with unsupported scheme",
+		spec:   "quic://1.1.1.1",
+		expect: nil,
+		err:    errInvalidDNSResolver,
+	}, {
+		name:   "with domain name instead of IP address",
+		spec:   "udp://dns.google",
+		expect: nil,
+		err:    errInvalidDNSResolver,
+	}, {
+		name:   "with path for an endpoint resolver",
+		spec:   "tcp://8.8.8.8/dns-query",
+		expect: nil,
+		err:    errInvalidDNSResolver,
+	}, {
+		name:   "with DoH URL without host",
+		spec:   "https:///dns-query",
+		expect: nil,
+		err:    errInvalidDNSResolver,
+	}, {
+		name:   "with unparseable URL",
+		spec:   "udp://[::1",
+		expect: nil,
+		err:    errInvalidDNSResolver,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseDNSResolvers(tc.spec)
+			if !errors.Is(err, tc.err) {
+				t.Fatal("expected", tc.err, "got", err)
+			}
+			if diff := cmp.Diff(tc.expect, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestMeasurerWithDNSResolvers(t *testing.T) {
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+
+	// spoof DNS-over-UDP responses such that only DoH gets the correct addresses
+	env.DPIEngine().AddRule(&netem.DPISpoofDNSResponse{
+		Addresses: []string{netemx.AddressPublicBlockpage},
+		Logger:    env.Logger(),
+		Domain:    "www.example.com",
+	})
+
+	config := &Config{
+		DNSResolvers: "udp://" + netemx.AddressDNSGoogle8888 + ",https://dns.google/dns-query",
+	}
+	tk := qaRunMeasurer(t, env, config, "http://www.example.com/")
+
+	type resolver struct {
+		Engine      string
+		Address     string
+		Consistency string
+	}
+	var got []resolver
+	for _, entry := range tk.DNSResolvers {
+		got = append(got, resolver{entry.Engine, entry.Address, entry.Consistency.UnwrapOr("")})
+	}
+	expect := []resolver{
+		{"doh", "https://dns.google/dns-query", "consistent"},
+		{"getaddrinfo", "", "inconsistent"},
+		{"udp", netemx.AddressDNSGoogle8888 + ":53", "inconsistent"},
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
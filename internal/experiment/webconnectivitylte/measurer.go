@@ -10,6 +10,8 @@ import (
 	"net/http/cookiejar"
 	"sync"
 
+	"github.com/ooni/probe-cli/v3/internal/blockpages"
+	"github.com/ooni/probe-cli/v3/internal/clockskew"
 	"github.com/ooni/probe-cli/v3/internal/inputparser"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/webconnectivityalgo"
@@ -46,7 +48,7 @@ func (m *Measurer) ExperimentName() string {
 
 // ExperimentVersion implements model.ExperimentMeasurer.
 func (m *Measurer) ExperimentVersion() string {
-	return "0.5.28"
+	return "0.5.31"
 }
 
 // Run implements model.ExperimentMeasurer.
@@ -87,6 +89,15 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	tk := NewTestKeys()
 	measurement.TestKeys = tk
 
+	// use the blockpage fingerprints database cached by the check-in API, when
+	// it is more recent than the embedded one
+	fingerprints := blockpages.Load(sess.KeyValueStore())
+	tk.fingerprints = fingerprints
+
+	// annotate with the clock skew measured by time_integrity, if any, such that
+	// analysts can discard TLS failures caused by a wrong clock
+	measurerMaybeAnnotateClockSkew(sess, measurement)
+
 	// make sure we add the ClientResolver field
 	//
 	// See https://github.com/ooni/probe/issues/2676
@@ -103,6 +114,12 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		return err
 	}
 
+	// obtain the resolvers configured by the user, if any
+	resolvers, err := m.Config.dnsResolvers()
+	if err != nil {
+		return err
+	}
+
 	// obtain the test helper's address
 	testhelpers, _ := sess.GetTestHelpersByName("web-connectivity")
 	if len(testhelpers) < 1 {
@@ -112,6 +129,23 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 
 	registerExtensions(measurement)
 
+	// possibly prepare for measuring the final webpage's subresources
+	var subresources *Subresources
+	if m.Config.FetchSubresources {
+		subresources = &Subresources{
+			DNSOverHTTPSURLProvider: m.DNSOverHTTPSURLProvider,
+			Fingerprints:            fingerprints,
+			Logger:                  sess.Logger(),
+			MaxHosts:                m.Config.maxSubresourceHosts(),
+			Resolvers:               resolvers,
+			Session:                 sess,
+			TestHelpers:             testhelpers,
+			UDPAddress:              m.Config.DNSOverUDPResolver,
+			WaitGroup:               wg,
+			ZeroTime:                measurement.MeasurementStartTimeSaved,
+		}
+	}
+
 	// start background tasks
 	resos := &DNSResolvers{
 		DNSCache:                NewDNSCache(),
@@ -127,6 +161,8 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		WaitGroup:               wg,
 		CookieJar:               jar,
 		Referer:                 "",
+		Resolvers:               resolvers,
+		Subresources:            subresources,
 		Session:                 sess,
 		TestHelpers:             testhelpers,
 		UDPAddress:              m.Config.DNSOverUDPResolver,
@@ -145,6 +181,11 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	// perform any deferred computation on the test keys
 	tk.Finalize(sess.Logger())
 
+	// analyze the subresources, if any
+	if subresources != nil {
+		tk.Subresources = subresources.Finalize(sess.Logger())
+	}
+
 	// set the test helper we used
 	// TODO(https://github.com/ooni/probe/issues/1857): record how we submitted
 	if th := tk.getTestHelper(); th != nil {
@@ -168,3 +209,13 @@ func registerExtensions(m *model.Measurement) {
 	model.ArchivalExtTLSHandshake.AddTo(m)
 	model.ArchivalExtTunnel.AddTo(m)
 }
+
+// measurerMaybeAnnotateClockSkew adds the clock skew annotations to the measurement when
+// the session key-value store contains a clock skew that is not stale.
+func measurerMaybeAnnotateClockSkew(sess model.ExperimentSession, measurement *model.Measurement) {
+	cs, err := clockskew.Get(sess.KeyValueStore())
+	if err != nil {
+		return
+	}
+	clockskew.Annotate(measurement, cs.Skew, cs.Source)
+}
@@ -0,0 +1,46 @@
+package webconnectivitylte
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/clockskew"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestMeasurerMaybeAnnotateClockSkew(t *testing.T) {
+	// newSession returns a session using the given key-value store.
+	newSession := func(kvStore model.KeyValueStore) model.ExperimentSession {
+		return &mocks.Session{
+			MockKeyValueStore: func() model.KeyValueStore {
+				return kvStore
+			},
+		}
+	}
+
+	t.Run("when there is no clock skew", func(t *testing.T) {
+		measurement := &model.Measurement{}
+		measurerMaybeAnnotateClockSkew(newSession(&kvstore.Memory{}), measurement)
+		if len(measurement.Annotations) != 0 {
+			t.Fatal("unexpected annotations", measurement.Annotations)
+		}
+	})
+
+	t.Run("when there is a clock skew", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		if err := clockskew.Store(kvStore, 86400, "nts"); err != nil {
+			t.Fatal(err)
+		}
+		measurement := &model.Measurement{}
+		measurerMaybeAnnotateClockSkew(newSession(kvStore), measurement)
+		expect := map[string]string{
+			clockskew.AnnotationClockSkew:       "86400.000",
+			clockskew.AnnotationClockSkewSource: "nts",
+		}
+		if diff := cmp.Diff(expect, measurement.Annotations); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
@@ -52,13 +52,13 @@ type prioritySelector struct {
 	// m contains a map from known addresses to their flags
 	m map[string]int64
 
-	// nhttps is the number of addrs resolved using DoH
+	// nhttps is the number of addrs resolved using DoH or DoT
 	nhttps int
 
 	// nsystem is the number of addrs resolved using the system resolver
 	nsystem int
 
-	// nudp is the nunber of addrs resolver using UDP
+	// nudp is the nunber of addrs resolver using UDP or TCP
 	nudp int
 
 	// tk contains the TestKeys.
@@ -103,10 +103,10 @@ func newPrioritySelector(
 		if (flags & DNSAddrFlagSystemResolver) != 0 {
 			ps.nsystem++
 		}
-		if (flags & DNSAddrFlagUDP) != 0 {
+		if (flags & (DNSAddrFlagUDP | DNSAddrFlagTCP)) != 0 {
 			ps.nudp++
 		}
-		if (flags & DNSAddrFlagHTTPS) != 0 {
+		if (flags & (DNSAddrFlagHTTPS | DNSAddrFlagTLS)) != 0 {
 			ps.nhttps++
 		}
 	}
@@ -232,11 +232,11 @@ func (ps *prioritySelector) isHighestPriority(r *priorityRequest) bool {
 			return true
 		}
 	} else if ps.nudp > 0 {
-		if (flags & DNSAddrFlagUDP) != 0 {
+		if (flags & (DNSAddrFlagUDP | DNSAddrFlagTCP)) != 0 {
 			return true
 		}
 	} else if ps.nhttps > 0 {
-		if (flags & DNSAddrFlagHTTPS) != 0 {
+		if (flags & (DNSAddrFlagHTTPS | DNSAddrFlagTLS)) != 0 {
 			return true
 		}
 	} else {
@@ -1,8 +1,15 @@
 package webconnectivitylte
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/webconnectivityqa"
 )
 
@@ -22,3 +29,52 @@ func TestQA(t *testing.T) {
 		})
 	}
 }
+
+// qaRunMeasurer measures the given input inside the given [*netemx.QAEnv] using
+// the given config and returns the resulting test keys.
+func qaRunMeasurer(t *testing.T, env *netemx.QAEnv, config *Config, input string) *TestKeys {
+	return qaRunMeasurerWithKeyValueStore(t, env, &kvstore.Memory{}, config, input)
+}
+
+// qaRunMeasurerWithKeyValueStore is like [qaRunMeasurer] but allows the caller
+// to provide the session's key-value store (e.g., to seed check-in data).
+func qaRunMeasurerWithKeyValueStore(
+	t *testing.T, env *netemx.QAEnv, kvStore model.KeyValueStore, config *Config, input string) *TestKeys {
+	measurement := &model.Measurement{
+		Input:                     model.MeasurementInput(input),
+		MeasurementStartTimeSaved: time.Now(),
+	}
+	env.Do(func() {
+		measurer := NewExperimentMeasurer(config)
+		httpClient := netxlite.NewHTTPClientStdlib(model.DiscardLogger)
+		sess := &mocks.Session{
+			MockGetTestHelpersByName: func(name string) ([]model.OOAPIService, bool) {
+				return []model.OOAPIService{{Address: "https://0.th.ooni.org/", Type: "https"}}, true
+			},
+			MockDefaultHTTPClient: func() model.HTTPClient {
+				return httpClient
+			},
+			MockKeyValueStore: func() model.KeyValueStore {
+				return kvStore
+			},
+			MockLogger: func() model.Logger {
+				return model.DiscardLogger
+			},
+			MockResolverIP: func() string {
+				return netemx.ISPResolverAddress
+			},
+			MockUserAgent: func() string {
+				return model.HTTPHeaderUserAgent
+			},
+		}
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+			Measurement: measurement,
+			Session:     sess,
+		}
+		if err := measurer.Run(context.Background(), args); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return measurement.TestKeys.(*TestKeys)
+}
@@ -84,6 +84,14 @@ type SecureFlow struct {
 	// Referer contains the OPTIONAL referer, used for redirects.
 	Referer string
 
+	// Resolvers contains the OPTIONAL resolvers configured by the user, which
+	// we use when following redirects. See [DNSResolvers] for more info.
+	Resolvers []*DNSResolverSpec
+
+	// Subresources is the OPTIONAL [*Subresources] to notify about the final
+	// webpage. When this field is nil, we do not measure subresources.
+	Subresources *Subresources
+
 	// SNI is the OPTIONAL SNI to use.
 	SNI string
 
@@ -229,8 +237,10 @@ func (t *SecureFlow) Run(parentCtx context.Context, index int64) error {
 	// if enabled, follow possible redirects
 	t.maybeFollowRedirects(parentCtx, httpResp)
 
-	// ignore the response body
-	_ = httpRespBody
+	// if enabled, measure the subresources of the final webpage
+	if t.Subresources != nil {
+		t.Subresources.MaybeStart(parentCtx, httpResp, httpRespBody)
+	}
 
 	// completed successfully
 	ol.Stop(nil)
@@ -401,6 +411,8 @@ func (t *SecureFlow) maybeFollowRedirects(ctx context.Context, resp *http.Respon
 			ZeroTime:                t.ZeroTime,
 			WaitGroup:               t.WaitGroup,
 			Referer:                 resp.Request.URL.String(),
+			Resolvers:               t.Resolvers,
+			Subresources:            t.Subresources,
 			Session:                 nil, // no need to issue another control request
 			TestHelpers:             nil, // ditto
 			UDPAddress:              t.UDPAddress,
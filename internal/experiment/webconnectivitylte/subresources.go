@@ -0,0 +1,325 @@
+package webconnectivitylte
+
+//
+// Subresources
+//
+// When enabled, we parse the final webpage, extract the hosts serving its
+// subresources (e.g., scripts, stylesheets, images), and measure each of
+// them using the same DNS, TCP, TLS, and HTTP flows we use for the webpage
+// itself, including a control request for each host. This allows us to
+// detect cases where the webpage loads but it is broken because critical
+// hosts (e.g., CDNs) are blocked.
+//
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/blockpages"
+	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivity"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+	"github.com/ooni/probe-cli/v3/internal/webconnectivityalgo"
+	"golang.org/x/net/html"
+)
+
+// Subresources measures the hosts serving the subresources of the final webpage.
+//
+// The zero value of this structure IS NOT valid and you MUST initialize
+// all the fields marked as MANDATORY before using this structure.
+type Subresources struct {
+	// DNSOverHTTPSURLProvider is the MANDATORY provider of DNS-over-HTTPS
+	// URLs that arranges for periodic measurements.
+	DNSOverHTTPSURLProvider *webconnectivityalgo.OpportunisticDNSOverHTTPSURLProvider
+
+	// Fingerprints is the OPTIONAL blockpage fingerprints database. When
+	// not set, we use the embedded fingerprints database.
+	Fingerprints *blockpages.Database
+
+	// Logger is the MANDATORY logger to use.
+	Logger model.Logger
+
+	// MaxHosts is the MANDATORY maximum number of hosts to measure.
+	MaxHosts int
+
+	// Resolvers contains the OPTIONAL resolvers configured by the user.
+	Resolvers []*DNSResolverSpec
+
+	// Session is the MANDATORY session to use.
+	Session model.ExperimentSession
+
+	// TestHelpers is the OPTIONAL list of test helpers. If the list is
+	// empty, we are not going to try to contact any test helper.
+	TestHelpers []model.OOAPIService
+
+	// UDPAddress is the OPTIONAL address of the UDP resolver to use.
+	UDPAddress string
+
+	// WaitGroup is the MANDATORY wait group this task belongs to.
+	WaitGroup *sync.WaitGroup
+
+	// ZeroTime is the MANDATORY zero time of the measurement.
+	ZeroTime time.Time
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// started indicates whether we already started measuring.
+	started bool
+
+	// hosts contains the hosts we are measuring.
+	hosts []*subresourcesHost
+}
+
+// subresourcesHost is a host we are measuring.
+type subresourcesHost struct {
+	// URL is the first subresource URL we found for this host.
+	URL *url.URL
+
+	// TestKeys contains the results of measuring the host.
+	TestKeys *TestKeys
+}
+
+// MaybeStart starts measuring the subresources hosts if the given response is the
+// final webpage and we did not already start. The [body] argument is the possibly
+// truncated response body. This method is safe to call from multiple goroutines.
+func (s *Subresources) MaybeStart(ctx context.Context, resp *http.Response, body []byte) {
+	if httpRedirectIsRedirect(resp) || resp.Request == nil || resp.Request.URL == nil {
+		return
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return
+	}
+
+	// make sure we only measure the subresources once
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	page := resp.Request.URL
+	URLs := subresourcesSelectURLs(page, subresourcesExtractURLs(page, body), s.MaxHosts)
+	s.Logger.Infof("subresources hosts to measure: %d", len(URLs))
+
+	for _, URL := range URLs {
+		tk := NewTestKeys()
+		tk.subresource = true
+		if s.Fingerprints != nil {
+			tk.fingerprints = s.Fingerprints
+		}
+		s.mu.Lock()
+		s.hosts = append(s.hosts, &subresourcesHost{URL: URL, TestKeys: tk})
+		s.mu.Unlock()
+		resos := &DNSResolvers{
+			DNSCache:                NewDNSCache(),
+			DNSOverHTTPSURLProvider: s.DNSOverHTTPSURLProvider,
+			Depth:                   0,
+			Domain:                  URL.Hostname(),
+			IDGenerator:             NewIDGenerator(),
+			Logger:                  s.Logger,
+			NumRedirects:            NewNumRedirects(subresourcesMaxRedirects),
+			TestKeys:                tk,
+			URL:                     URL,
+			ZeroTime:                s.ZeroTime,
+			WaitGroup:               s.WaitGroup,
+			CookieJar:               nil,
+			Referer:                 page.String(),
+			Resolvers:               s.Resolvers,
+			Session:                 s.Session,
+			TestHelpers:             s.TestHelpers,
+			UDPAddress:              s.UDPAddress,
+		}
+		resos.Start(ctx)
+	}
+}
+
+// subresourcesMaxRedirects is the maximum number of redirects we follow for a subresource.
+const subresourcesMaxRedirects = 3
+
+// Finalize analyzes the results of measuring each host and returns the
+// corresponding test keys. This method MUST be called after all the tasks
+// have completed and returns nil if we did not measure any subresource.
+func (s *Subresources) Finalize(logger model.Logger) *TestKeysSubresources {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		return nil
+	}
+	out := &TestKeysSubresources{
+		BlockedHosts:  []string{},
+		BlockingFlags: 0,
+		Hosts:         []*SubresourceHostResult{},
+	}
+	for _, host := range s.hosts {
+		host.TestKeys.Finalize(logger)
+		entry := newSubresourceHostResult(host.URL, host.TestKeys)
+		if entry.Blocking != nil && entry.Blocking != false {
+			out.BlockedHosts = append(out.BlockedHosts, entry.Host)
+		}
+		out.BlockingFlags |= entry.BlockingFlags &^ AnalysisBlockingFlagSuccess
+		out.Hosts = append(out.Hosts, entry)
+	}
+	return out
+}
+
+// TestKeysSubresources contains the subresource blocking analysis.
+type TestKeysSubresources struct {
+	// BlockedHosts contains the hosts for which we detected blocking.
+	BlockedHosts []string `json:"blocked_hosts"`
+
+	// BlockingFlags is the union of the blocking flags of all the hosts, excluding
+	// the success flag, so zero means we did not detect any blocking.
+	BlockingFlags int64 `json:"blocking_flags"`
+
+	// Hosts contains the results for each host.
+	Hosts []*SubresourceHostResult `json:"hosts"`
+}
+
+// SubresourceHostResult contains the result of measuring a subresource host.
+type SubresourceHostResult struct {
+	// Host is the host we measured.
+	Host string `json:"host"`
+
+	// URL is the subresource URL we measured.
+	URL string `json:"url"`
+
+	// Accessible has the same semantics as [TestKeys.Accessible].
+	Accessible optional.Value[bool] `json:"accessible"`
+
+	// Blocking has the same semantics as [TestKeys.Blocking].
+	Blocking any `json:"blocking"`
+
+	// BlockingFlags has the same semantics as [TestKeys.BlockingFlags].
+	BlockingFlags int64 `json:"blocking_flags"`
+
+	// Control contains the TH's response for this host.
+	Control *webconnectivity.ControlResponse `json:"control"`
+
+	// ControlFailure contains the failure of the control request.
+	ControlFailure *string `json:"control_failure"`
+
+	// DNSConsistency has the same semantics as [TestKeys.DNSConsistency].
+	DNSConsistency optional.Value[string] `json:"dns_consistency"`
+
+	// Queries contains DNS queries.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// Requests contains HTTP results.
+	Requests []*model.ArchivalHTTPRequestResult `json:"requests"`
+
+	// TCPConnect contains TCP connect results.
+	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect"`
+
+	// TLSHandshakes contains TLS handshakes results.
+	TLSHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
+}
+
+// newSubresourceHostResult creates a [*SubresourceHostResult] from finalized test keys.
+func newSubresourceHostResult(URL *url.URL, tk *TestKeys) *SubresourceHostResult {
+	return &SubresourceHostResult{
+		Host:           URL.Host,
+		URL:            URL.String(),
+		Accessible:     tk.Accessible,
+		Blocking:       tk.Blocking,
+		BlockingFlags:  tk.BlockingFlags,
+		Control:        tk.Control,
+		ControlFailure: tk.ControlFailure,
+		DNSConsistency: tk.DNSConsistency,
+		Queries:        tk.Queries,
+		Requests:       tk.Requests,
+		TCPConnect:     tk.TCPConnect,
+		TLSHandshakes:  tk.TLSHandshakes,
+	}
+}
+
+// subresourcesElements maps HTML elements to the attribute containing the subresource URL.
+var subresourcesElements = map[string]string{
+	"audio":  "src",
+	"embed":  "src",
+	"iframe": "src",
+	"img":    "src",
+	"link":   "href",
+	"script": "src",
+	"source": "src",
+	"video":  "src",
+}
+
+// subresourcesLinkRels contains the <link rel="..."> values referencing subresources.
+var subresourcesLinkRels = map[string]bool{
+	"icon":          true,
+	"modulepreload": true,
+	"preload":       true,
+	"stylesheet":    true,
+}
+
+// subresourcesExtractURLs parses the HTML body and returns the absolute
+// HTTP(S) URLs of the subresources in the order in which they appear.
+func subresourcesExtractURLs(page *url.URL, body []byte) (out []*url.URL) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return // either io.EOF or a truncated body
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if URL := subresourcesURLFromToken(page, &token); URL != nil {
+				out = append(out, URL)
+			}
+		}
+	}
+}
+
+// subresourcesURLFromToken returns the subresource URL referenced by the
+// token or nil if the token does not reference a subresource.
+func subresourcesURLFromToken(page *url.URL, token *html.Token) *url.URL {
+	attrName, found := subresourcesElements[token.Data]
+	if !found {
+		return nil
+	}
+	var value string
+	isSubresource := token.Data != "link"
+	for _, attr := range token.Attr {
+		switch {
+		case attr.Key == attrName:
+			value = strings.TrimSpace(attr.Val)
+		case attr.Key == "rel":
+			for _, rel := range strings.Fields(strings.ToLower(attr.Val)) {
+				isSubresource = isSubresource || subresourcesLinkRels[rel]
+			}
+		}
+	}
+	if !isSubresource || value == "" {
+		return nil
+	}
+	URL, err := page.Parse(value)
+	if err != nil || (URL.Scheme != "http" && URL.Scheme != "https") || URL.Hostname() == "" {
+		return nil
+	}
+	URL.Fragment = ""
+	return URL
+}
+
+// subresourcesSelectURLs returns the first URL for each host other than the page's
+// host, in the order in which hosts appear, using at most maxHosts hosts.
+func subresourcesSelectURLs(page *url.URL, URLs []*url.URL, maxHosts int) (out []*url.URL) {
+	seen := map[string]bool{page.Host: true}
+	for _, URL := range URLs {
+		if len(out) >= maxHosts {
+			break
+		}
+		if seen[URL.Host] {
+			continue
+		}
+		seen[URL.Host] = true
+		out = append(out, URL)
+	}
+	return
+}
@@ -0,0 +1,186 @@
+package webconnectivitylte
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+func TestSubresourcesExtractURLs(t *testing.T) {
+	page := runtimex.Try1(url.Parse("https://www.example.com/news/index.html"))
+
+	body := []byte(`<!DOCTYPE html>
+<html>
+<head>
+	<link rel="stylesheet" href="https://cdn.example.net/style.css">
+	<link rel="alternate" href="https://www.example.com/feed.xml">
+	<link rel="preload icon" href="//fonts.example.org/font.woff2#x">
+	<script src="/static/app.js"></script>
+	<script>console.log("inline")</script>
+</head>
+<body>
+	<img src="images/logo.png"/>
+	<img src="data:image/png;base64,AAAA">
+	<iframe src="https://player.example.tv/embed/1"></iframe>
+	<a href="https://www.example.org/">not a subresource</a>
+	<script src="  ">`)
+
+	var got []string
+	for _, URL := range subresourcesExtractURLs(page, body) {
+		got = append(got, URL.String())
+	}
+	expect := []string{
+		"https://cdn.example.net/style.css",
+		"https://fonts.example.org/font.woff2",
+		"https://www.example.com/static/app.js",
+		"https://www.example.com/news/images/logo.png",
+		"https://player.example.tv/embed/1",
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestSubresourcesSelectURLs(t *testing.T) {
+	page := runtimex.Try1(url.Parse("https://www.example.com/"))
+	var URLs []*url.URL
+	for _, s := range []string{
+		"https://www.example.com/app.js",
+		"https://cdn.example.net/a.js",
+		"https://cdn.example.net/b.js",
+		"http://cdn.example.net:8080/c.js",
+		"https://fonts.example.org/font.woff2",
+	} {
+		URLs = append(URLs, runtimex.Try1(url.Parse(s)))
+	}
+
+	t.Run("with enough hosts", func(t *testing.T) {
+		var got []string
+		for _, URL := range subresourcesSelectURLs(page, URLs, 10) {
+			got = append(got, URL.String())
+		}
+		expect := []string{
+			"https://cdn.example.net/a.js",
+			"http://cdn.example.net:8080/c.js",
+			"https://fonts.example.org/font.woff2",
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a bounded number of hosts", func(t *testing.T) {
+		got := subresourcesSelectURLs(page, URLs, 1)
+		if len(got) != 1 || got[0].String() != "https://cdn.example.net/a.js" {
+			t.Fatal("unexpected URLs", got)
+		}
+	})
+}
+
+func TestSubresourcesMaybeStart(t *testing.T) {
+	newResponse := func(status int, contentType string) *http.Response {
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {contentType}},
+			Request: &http.Request{
+				URL: runtimex.Try1(url.Parse("https://www.example.com/")),
+			},
+		}
+	}
+
+	t.Run("we ignore redirects", func(t *testing.T) {
+		s := &Subresources{Logger: model.DiscardLogger}
+		s.MaybeStart(context.Background(), newResponse(302, "text/html"), nil)
+		if s.Finalize(model.DiscardLogger) != nil {
+			t.Fatal("expected nil test keys")
+		}
+	})
+
+	t.Run("we ignore non-HTML responses", func(t *testing.T) {
+		s := &Subresources{Logger: model.DiscardLogger}
+		s.MaybeStart(context.Background(), newResponse(200, "application/json"), nil)
+		if s.Finalize(model.DiscardLogger) != nil {
+			t.Fatal("expected nil test keys")
+		}
+	})
+
+	t.Run("we handle webpages without subresources", func(t *testing.T) {
+		s := &Subresources{
+			Logger:    model.DiscardLogger,
+			MaxHosts:  4,
+			WaitGroup: &sync.WaitGroup{},
+		}
+		body := []byte(`<html><body>Hello, world!</body></html>`)
+		s.MaybeStart(context.Background(), newResponse(200, "text/html; charset=utf-8"), body)
+		s.WaitGroup.Wait()
+		tks := s.Finalize(model.DiscardLogger)
+		expect := &TestKeysSubresources{
+			BlockedHosts:  []string{},
+			BlockingFlags: 0,
+			Hosts:         []*SubresourceHostResult{},
+		}
+		if diff := cmp.Diff(expect, tks); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestMeasurerWithSubresources(t *testing.T) {
+	// serve a webpage on www.example.com that loads a script from www.example.org
+	scenario := []*netemx.ScenarioDomainAddresses{}
+	for _, sad := range netemx.InternetScenario {
+		if sad.Role == netemx.ScenarioRoleWebServer && sad.ServerNameMain == "www.example.com" {
+			copied := *sad
+			copied.WebServerFactory = netemx.HTTPHandlerFactoryFunc(
+				func(env netemx.NetStackServerFactoryEnv, stack *netem.UNetStack) http.Handler {
+					return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						switch r.URL.Path {
+						case "/app.js":
+							w.Header().Set("Content-Type", "text/javascript")
+							_, _ = w.Write([]byte(`console.log("Hello, world!")`))
+						default:
+							w.Header().Set("Content-Type", "text/html")
+							_, _ = w.Write([]byte(`<html><head><script src="https://www.example.org/app.js">` +
+								`</script></head><body>` + netemx.ExampleWebPage + `</body></html>`))
+						}
+					})
+				})
+			sad = &copied
+		}
+		scenario = append(scenario, sad)
+	}
+	env := netemx.MustNewScenario(scenario)
+	defer env.Close()
+
+	// make sure the ISP resolver cannot resolve the subresource host
+	env.ISPResolverConfig().RemoveRecord("www.example.org")
+
+	tk := qaRunMeasurer(t, env, &Config{FetchSubresources: true}, "https://www.example.com/")
+	if tk.Blocking != false {
+		t.Fatal("expected the webpage to be accessible", tk.Blocking)
+	}
+	if tk.Subresources == nil {
+		t.Fatal("expected subresources test keys")
+	}
+	if diff := cmp.Diff([]string{"www.example.org"}, tk.Subresources.BlockedHosts); diff != "" {
+		t.Fatal(diff)
+	}
+	if len(tk.Subresources.Hosts) != 1 {
+		t.Fatal("expected exactly one subresource host")
+	}
+	host := tk.Subresources.Hosts[0]
+	if host.URL != "https://www.example.org/app.js" || host.Blocking != "dns" || host.Control == nil {
+		t.Fatal("unexpected subresource host result", host.URL, host.Blocking)
+	}
+	if tk.Subresources.BlockingFlags&AnalysisBlockingFlagDNSBlocking == 0 {
+		t.Fatal("expected the DNS blocking flag")
+	}
+}
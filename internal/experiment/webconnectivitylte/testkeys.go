@@ -11,8 +11,9 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/ooni/probe-cli/v3/internal/blockpages"
 	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivity"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/optional"
 	"github.com/ooni/probe-cli/v3/internal/webconnectivityalgo"
@@ -45,6 +46,10 @@ type TestKeys struct {
 	// Do53 contains ancillary observations collected by Do53 resolvers.
 	Do53 *TestKeysDo53 `json:"x_do53"`
 
+	// DoT contains ancillary observations collected by DoT resolvers, which
+	// we only use when the user configures them.
+	DoT *TestKeysDoT `json:"x_dot,omitempty"`
+
 	// DNSDuplicateResponses contains late/duplicate responses we didn't expect to receive from
 	// a resolver (which may raise eyebrows if they're different).
 	DNSDuplicateResponses []*model.ArchivalDNSLookupResult `json:"x_dns_duplicate_responses"`
@@ -85,6 +90,10 @@ type TestKeys struct {
 	// the TH's DNS results and the probe's DNS results.
 	DNSConsistency optional.Value[string] `json:"dns_consistency"`
 
+	// DNSResolvers contains the DNS consistency of each resolver we used
+	// before following redirects, computed by the extended analysis.
+	DNSResolvers []*DNSResolverConsistency `json:"x_dns_resolvers,omitempty"`
+
 	// HTTPExperimentFailure indicates whether there was a failure in
 	// the final HTTP request that we recorded.
 	HTTPExperimentFailure optional.Value[string] `json:"http_experiment_failure"`
@@ -92,6 +101,10 @@ type TestKeys struct {
 	// BlockingFlags explains why we think that the website is blocked.
 	BlockingFlags int64 `json:"x_blocking_flags"`
 
+	// BlockingFingerprint contains the IDs of the blockpage fingerprints
+	// matching the DNS answers or the final HTTP response.
+	BlockingFingerprint []string `json:"blocking_fingerprint,omitempty"`
+
 	// NullNullFlags describes what the algorithm to avoid emitting
 	// blocking = null, accessible = null measurements did
 	NullNullFlags int64 `json:"x_null_null_flags"`
@@ -130,6 +143,13 @@ type TestKeys struct {
 	// values for this field are: nil, true, and false.
 	Accessible optional.Value[bool] `json:"accessible"`
 
+	// Subresources contains the subresource blocking analysis, which we
+	// only compute when the FetchSubresources option is enabled.
+	Subresources *TestKeysSubresources `json:"x_subresources,omitempty"`
+
+	// fingerprints is the blockpage fingerprints database.
+	fingerprints *blockpages.Database
+
 	// fundamentalFailure indicates that some fundamental error occurred
 	// in a background task. A fundamental error is something like a programmer
 	// such as a failure to parse a URL that was hardcoded in the codebase. When
@@ -140,6 +160,10 @@ type TestKeys struct {
 	// mu provides mutual exclusion for accessing the test keys.
 	mu *sync.Mutex
 
+	// subresource indicates that these test keys contain the results
+	// of measuring a subresource host rather than the input URL.
+	subresource bool
+
 	// testHelper is used to communicate the TH that worked to the main
 	// goroutine such that we can fill measurement.TestHelpers.
 	testHelper *model.OOAPIService
@@ -163,6 +187,22 @@ type DNSWhoamiInfo struct {
 	UDPv4 map[string][]webconnectivityalgo.DNSWhoamiInfoEntry `json:"udp_v4"`
 }
 
+// DNSResolverConsistency contains the DNS consistency of a resolver.
+type DNSResolverConsistency struct {
+	// Engine is the resolver engine (e.g., "udp").
+	Engine string `json:"engine"`
+
+	// Address is the resolver address (e.g., "8.8.8.8:53").
+	Address string `json:"address"`
+
+	// Consistency is "consistent", "inconsistent", or null when we
+	// cannot say anything (e.g., because the control failed).
+	Consistency optional.Value[string] `json:"consistency"`
+
+	// TransactionIDs contains the IDs of the resolver's DNS lookups.
+	TransactionIDs []int64 `json:"transaction_ids"`
+}
+
 // TestKeysDoH contains ancillary observations collected using DoH (e.g., the
 // DNS lookups, TCP connects, TLS handshakes caused by given DoH lookups).
 //
@@ -193,6 +233,26 @@ type TestKeysDo53 struct {
 
 	// Queries contains DNS queries.
 	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// TCPConnect contains the TCP connect results of DNS-over-TCP resolvers.
+	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect,omitempty"`
+}
+
+// TestKeysDoT contains ancillary observations collected using DoT.
+//
+// They are on a separate hierarchy to simplify processing.
+type TestKeysDoT struct {
+	// NetworkEvents contains network events.
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+
+	// Queries contains DNS queries.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// TCPConnect contains TCP connect results.
+	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect"`
+
+	// TLSHandshakes contains TLS handshakes results.
+	TLSHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
 }
 
 // AppendNetworkEvents appends to NetworkEvents.
@@ -256,7 +316,7 @@ func (tk *TestKeys) SetControl(v *webconnectivity.ControlResponse) {
 // SetControlFailure sets the value of controlFailure.
 func (tk *TestKeys) SetControlFailure(err error) {
 	tk.mu.Lock()
-	tk.ControlFailure = tracex.NewFailure(err)
+	tk.ControlFailure = measurexlite.NewFailure(err)
 	tk.mu.Unlock()
 }
 
@@ -283,6 +343,22 @@ func (tk *TestKeys) WithTestKeysDo53(f func(*TestKeysDo53)) {
 	tk.mu.Unlock()
 }
 
+// WithTestKeysDoT calls the given function with the mutex locked passing to
+// it as argument the pointer to the DoT field, which we create on demand.
+func (tk *TestKeys) WithTestKeysDoT(f func(*TestKeysDoT)) {
+	tk.mu.Lock()
+	if tk.DoT == nil {
+		tk.DoT = &TestKeysDoT{
+			NetworkEvents: []*model.ArchivalNetworkEvent{},
+			Queries:       []*model.ArchivalDNSLookupResult{},
+			TCPConnect:    []*model.ArchivalTCPConnectResult{},
+			TLSHandshakes: []*model.ArchivalTLSOrQUICHandshakeResult{},
+		}
+	}
+	f(tk.DoT)
+	tk.mu.Unlock()
+}
+
 // WithDNSWhoami calls the given function with the mutex locked passing to
 // it as argument the pointer to the DNSWhoami field.
 func (tk *TestKeys) WithDNSWhoami(fun func(*DNSWhoamiInfo)) {
@@ -347,8 +423,10 @@ func NewTestKeys() *TestKeys {
 		DNSFlags:              0,
 		DNSExperimentFailure:  nil,
 		DNSConsistency:        optional.None[string](),
+		DNSResolvers:          []*DNSResolverConsistency{},
 		HTTPExperimentFailure: optional.None[string](),
 		BlockingFlags:         0,
+		BlockingFingerprint:   []string{},
 		NullNullFlags:         0,
 		BodyProportion:        0,
 		BodyLengthMatch:       optional.None[bool](),
@@ -358,8 +436,10 @@ func NewTestKeys() *TestKeys {
 		Blocking:              nil,
 		Accessible:            optional.None[bool](),
 		ControlRequest:        nil,
+		fingerprints:          blockpages.Embedded(),
 		fundamentalFailure:    nil,
 		mu:                    &sync.Mutex{},
+		subresource:           false,
 		testHelper:            nil,
 	}
 }
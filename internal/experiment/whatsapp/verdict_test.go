@@ -0,0 +1,322 @@
+package whatsapp
+
+import (
+	"io"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/experiment/servicereachability"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/servicedefs"
+)
+
+func TestComputeVerdicts(t *testing.T) {
+	failure := netxlite.FailureConnectionRefused
+	blocked := &servicereachability.ComponentResult{Failure: &failure, Status: servicereachability.StatusBlocked}
+	ok := &servicereachability.ComponentResult{Status: servicereachability.StatusOK}
+
+	// check creates the result of a check for the given endpoint.
+	check := func(endpoint string, failed bool) *servicereachability.CheckResult {
+		cr := &servicereachability.CheckResult{Component: "endpoints", Target: endpoint, Type: "tcp"}
+		if failed {
+			cr.Failure = &failure
+		}
+		return cr
+	}
+
+	type testcase struct {
+		name             string
+		checks           []*servicereachability.CheckResult
+		components       map[string]*servicereachability.ComponentResult
+		endpointsBlocked []string
+		endpointsStatus  string
+		registration     string
+		web              string
+	}
+
+	cases := []testcase{{
+		name: "with mixed endpoints failures",
+		checks: []*servicereachability.CheckResult{
+			check("e7.whatsapp.net:443", true),
+			check("e7.whatsapp.net:5222", true),
+			check("e8.whatsapp.net:443", true),
+			check("e8.whatsapp.net:5222", false),
+		},
+		components:       map[string]*servicereachability.ComponentResult{"endpoints": ok, "registration": ok, "web": ok},
+		endpointsBlocked: []string{"e7.whatsapp.net"},
+		endpointsStatus:  "ok",
+		registration:     "ok",
+		web:              "ok",
+	}, {
+		name: "with all endpoints failing",
+		checks: []*servicereachability.CheckResult{
+			check("e7.whatsapp.net:443", true),
+			check("e7.whatsapp.net:5222", true),
+		},
+		components:       map[string]*servicereachability.ComponentResult{"endpoints": blocked, "registration": ok, "web": ok},
+		endpointsBlocked: []string{"e7.whatsapp.net"},
+		endpointsStatus:  "blocked",
+		registration:     "ok",
+		web:              "ok",
+	}, {
+		name:             "with only the registration server failing",
+		components:       map[string]*servicereachability.ComponentResult{"endpoints": ok, "registration": blocked, "web": ok},
+		endpointsBlocked: []string{},
+		endpointsStatus:  "ok",
+		registration:     "blocked",
+		web:              "ok",
+	}, {
+		name:             "with only web failing",
+		components:       map[string]*servicereachability.ComponentResult{"endpoints": ok, "registration": ok, "web": blocked},
+		endpointsBlocked: []string{},
+		endpointsStatus:  "ok",
+		registration:     "ok",
+		web:              "blocked",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tk := NewTestKeys()
+			tk.Checks = tc.checks
+			tk.Components = tc.components
+			tk.computeVerdicts()
+			if diff := cmp.Diff(tc.endpointsBlocked, tk.WhatsappEndpointsBlocked); diff != "" {
+				t.Fatal(diff)
+			}
+			if tk.WhatsappEndpointsStatus != tc.endpointsStatus {
+				t.Fatal("unexpected WhatsappEndpointsStatus")
+			}
+			if tk.RegistrationServerStatus != tc.registration {
+				t.Fatal("unexpected RegistrationServerStatus")
+			}
+			if (tk.RegistrationServerFailure != nil) != (tc.registration == "blocked") {
+				t.Fatal("unexpected RegistrationServerFailure")
+			}
+			if tk.WhatsappWebStatus != tc.web {
+				t.Fatal("unexpected WhatsappWebStatus")
+			}
+			if (tk.WhatsappWebFailure != nil) != (tc.web == "blocked") {
+				t.Fatal("unexpected WhatsappWebFailure")
+			}
+		})
+	}
+}
+
+// analyze fills the test keys with the results of running the checks of the embedded
+// whatsapp definition, where the checks whose target is inside failed fail with
+// the given failure, and then computes the verdicts.
+func analyze(t *testing.T, failure string, failed ...string) *TestKeys {
+	def, err := servicedefs.Embedded(testName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operation := netxlite.ConnectOperation
+	var results []*servicereachability.CheckResult
+	for _, comp := range def.Components {
+		for _, check := range comp.Checks {
+			result := &servicereachability.CheckResult{
+				Component: comp.Name,
+				Target:    check.Target(),
+				Type:      check.Type,
+			}
+			if slices.Contains(failed, check.Target()) {
+				result.FailedOperation = &operation
+				result.Failure = &failure
+			}
+			results = append(results, result)
+		}
+	}
+	tk := NewTestKeys()
+	tk.Checks = results
+	tk.Components = servicereachability.AnalyzeComponents(def, results)
+	tk.computeVerdicts()
+	return tk
+}
+
+// endpointsTargets returns the targets of all the endpoints checks.
+func endpointsTargets(t *testing.T) (out []string) {
+	def, err := servicedefs.Embedded(testName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, comp := range def.Components {
+		if comp.Name != "endpoints" {
+			continue
+		}
+		for _, check := range comp.Checks {
+			out = append(out, check.Target())
+		}
+	}
+	return
+}
+
+func TestTestKeysComputeWebStatus(t *testing.T) {
+	failure := io.EOF.Error()
+
+	t.Run("with success", func(t *testing.T) {
+		tk := analyze(t, failure)
+		if tk.WhatsappWebFailure != nil {
+			t.Fatal("invalid WhatsappWebFailure")
+		}
+		if tk.WhatsappWebStatus != "ok" {
+			t.Fatal("invalid WhatsappWebStatus")
+		}
+	})
+
+	t.Run("with HTTPS failure", func(t *testing.T) {
+		tk := analyze(t, failure, "https://web.whatsapp.com/")
+		if tk.WhatsappWebFailure == nil || *tk.WhatsappWebFailure != failure {
+			t.Fatal("invalid WhatsappWebFailure")
+		}
+		if tk.WhatsappWebStatus != "blocked" {
+			t.Fatal("invalid WhatsappWebStatus")
+		}
+	})
+}
+
+func TestTestKeysMixedEndpointsFailure(t *testing.T) {
+	failure := io.EOF.Error()
+	tk := analyze(t, failure, "e7.whatsapp.net:443")
+	if tk.RegistrationServerFailure != nil {
+		t.Fatal("invalid RegistrationServerFailure")
+	}
+	if tk.RegistrationServerStatus != "ok" {
+		t.Fatal("invalid RegistrationServerStatus")
+	}
+	if len(tk.WhatsappEndpointsBlocked) != 0 {
+		t.Fatal("invalid WhatsappEndpointsBlocked")
+	}
+	if len(tk.WhatsappEndpointsDNSInconsistent) != 0 {
+		t.Fatal("invalid WhatsappEndpointsDNSInconsistent")
+	}
+	if tk.WhatsappEndpointsStatus != "ok" {
+		t.Fatal("invalid WhatsappEndpointsStatus")
+	}
+	if tk.WhatsappWebFailure != nil {
+		t.Fatal("invalid WhatsappWebFailure")
+	}
+	if tk.WhatsappWebStatus != "ok" {
+		t.Fatal("invalid WhatsappWebStatus")
+	}
+}
+
+func TestTestKeysOnlyEndpointsFailure(t *testing.T) {
+	failure := io.EOF.Error()
+
+	t.Run("when a single endpoint fails on all ports", func(t *testing.T) {
+		tk := analyze(t, failure, "e7.whatsapp.net:443", "e7.whatsapp.net:5222")
+		if diff := cmp.Diff([]string{"e7.whatsapp.net"}, tk.WhatsappEndpointsBlocked); diff != "" {
+			t.Fatal(diff)
+		}
+		if tk.WhatsappEndpointsStatus != "ok" {
+			t.Fatal("invalid WhatsappEndpointsStatus")
+		}
+	})
+
+	t.Run("when all the endpoints fail", func(t *testing.T) {
+		tk := analyze(t, failure, endpointsTargets(t)...)
+		if tk.RegistrationServerFailure != nil {
+			t.Fatal("invalid RegistrationServerFailure")
+		}
+		if tk.RegistrationServerStatus != "ok" {
+			t.Fatal("invalid RegistrationServerStatus")
+		}
+		if len(tk.WhatsappEndpointsBlocked) != 16 {
+			t.Fatal("invalid WhatsappEndpointsBlocked")
+		}
+		if len(tk.WhatsappEndpointsDNSInconsistent) != 0 {
+			t.Fatal("invalid WhatsappEndpointsDNSInconsistent")
+		}
+		if tk.WhatsappEndpointsStatus != "blocked" {
+			t.Fatal("invalid WhatsappEndpointsStatus")
+		}
+		if tk.WhatsappWebFailure != nil {
+			t.Fatal("invalid WhatsappWebFailure")
+		}
+		if tk.WhatsappWebStatus != "ok" {
+			t.Fatal("invalid WhatsappWebStatus")
+		}
+	})
+}
+
+func TestTestKeysOnlyRegistrationServerFailure(t *testing.T) {
+	failure := io.EOF.Error()
+	tk := analyze(t, failure, "https://v.whatsapp.net/v2/register")
+	if tk.RegistrationServerFailure == nil || *tk.RegistrationServerFailure != failure {
+		t.Fatal("invalid RegistrationServerFailure")
+	}
+	if tk.RegistrationServerStatus != "blocked" {
+		t.Fatal("invalid RegistrationServerStatus")
+	}
+	if len(tk.WhatsappEndpointsBlocked) != 0 {
+		t.Fatal("invalid WhatsappEndpointsBlocked")
+	}
+	if len(tk.WhatsappEndpointsDNSInconsistent) != 0 {
+		t.Fatal("invalid WhatsappEndpointsDNSInconsistent")
+	}
+	if tk.WhatsappEndpointsStatus != "ok" {
+		t.Fatal("invalid WhatsappEndpointsStatus")
+	}
+	if tk.WhatsappWebFailure != nil {
+		t.Fatal("invalid WhatsappWebFailure")
+	}
+	if tk.WhatsappWebStatus != "ok" {
+		t.Fatal("invalid WhatsappWebStatus")
+	}
+}
+
+func TestTestKeysOnlyWebHTTPSFailure(t *testing.T) {
+	failure := io.EOF.Error()
+	tk := analyze(t, failure, "https://web.whatsapp.com/")
+	if tk.RegistrationServerFailure != nil {
+		t.Fatal("invalid RegistrationServerFailure")
+	}
+	if tk.RegistrationServerStatus != "ok" {
+		t.Fatal("invalid RegistrationServerStatus")
+	}
+	if len(tk.WhatsappEndpointsBlocked) != 0 {
+		t.Fatal("invalid WhatsappEndpointsBlocked")
+	}
+	if len(tk.WhatsappEndpointsDNSInconsistent) != 0 {
+		t.Fatal("invalid WhatsappEndpointsDNSInconsistent")
+	}
+	if tk.WhatsappEndpointsStatus != "ok" {
+		t.Fatal("invalid WhatsappEndpointsStatus")
+	}
+	if tk.WhatsappWebFailure == nil || *tk.WhatsappWebFailure != failure {
+		t.Fatal("invalid WhatsappWebFailure")
+	}
+	if tk.WhatsappWebStatus != "blocked" {
+		t.Fatal("invalid WhatsappWebStatus")
+	}
+}
+
+func TestWeConfigureWebChecksCorrectly(t *testing.T) {
+	def, err := servicedefs.Embedded(testName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, comp := range def.Components {
+		for _, check := range comp.Checks {
+			if check.Type == servicedefs.CheckTypeHTTP && (check.Method != "" || len(check.ExpectedStatusCodes) > 0) {
+				t.Fatal("unexpected HTTP check configuration", check.Target())
+			}
+			got = append(got, comp.Name+" "+check.Type+" "+check.Target())
+		}
+	}
+	expect := []string{"registration http https://v.whatsapp.net/v2/register", "web http https://web.whatsapp.com/"}
+	for _, target := range endpointsTargets(t) {
+		expect = append(expect, "endpoints tcp "+target)
+	}
+	sort.Strings(expect)
+	sort.Strings(got)
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Fatal(diff)
+	}
+	if len(got) != 34 {
+		t.Fatal("unexpected number of checks", len(got))
+	}
+}
@@ -1,118 +1,91 @@
 // Package whatsapp contains the WhatsApp network experiment.
 //
 // See https://github.com/ooni/spec/blob/master/nettests/ts-018-whatsapp.md.
+//
+// We measure the endpoints listed by the "whatsapp" service definition (see
+// the internal/servicedefs package) using the service_reachability engine.
 package whatsapp
 
 import (
 	"context"
-	"fmt"
-	"math/rand"
-	"net/url"
-	"regexp"
-	"time"
+	"net"
 
-	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
+	"github.com/ooni/probe-cli/v3/internal/experiment/servicereachability"
 	"github.com/ooni/probe-cli/v3/internal/model"
-	"github.com/ooni/probe-cli/v3/internal/runtimex"
 )
 
 const (
-	// RegistrationServiceURL is the URL used by WhatsApp registration service
-	RegistrationServiceURL = "https://v.whatsapp.net/v2/register"
-
-	// WebHTTPSURL is WhatsApp web's HTTPS URL
-	WebHTTPSURL = "https://web.whatsapp.com/"
-
 	testName    = "whatsapp"
-	testVersion = "0.11.0"
+	testVersion = "0.12.0"
 )
 
-var endpointPattern = regexp.MustCompile(`^tcpconnect://e[0-9]{1,2}\.whatsapp\.net:[0-9]{3,5}$`)
-
 // Config contains the experiment config.
 type Config struct{}
 
 // TestKeys contains the experiment results
 type TestKeys struct {
-	urlgetter.TestKeys
-	RegistrationServerFailure        *string        `json:"registration_server_failure"`
-	RegistrationServerStatus         string         `json:"registration_server_status"`
-	WhatsappEndpointsBlocked         []string       `json:"whatsapp_endpoints_blocked"`
-	WhatsappEndpointsDNSInconsistent []string       `json:"whatsapp_endpoints_dns_inconsistent"`
-	WhatsappEndpointsStatus          string         `json:"whatsapp_endpoints_status"`
-	WhatsappWebFailure               *string        `json:"whatsapp_web_failure"`
-	WhatsappWebStatus                string         `json:"whatsapp_web_status"`
-	WhatsappEndpointsCount           map[string]int `json:"-"`
-	WhatsappHTTPSFailure             *string        `json:"-"`
+	*servicereachability.TestKeys
+	servicereachability.LegacyTestKeys
+	RegistrationServerFailure        *string  `json:"registration_server_failure"`
+	RegistrationServerStatus         string   `json:"registration_server_status"`
+	WhatsappEndpointsBlocked         []string `json:"whatsapp_endpoints_blocked"`
+	WhatsappEndpointsDNSInconsistent []string `json:"whatsapp_endpoints_dns_inconsistent"`
+	WhatsappEndpointsStatus          string   `json:"whatsapp_endpoints_status"`
+	WhatsappWebFailure               *string  `json:"whatsapp_web_failure"`
+	WhatsappWebStatus                string   `json:"whatsapp_web_status"`
 }
 
 // NewTestKeys returns a new instance of the test keys.
 func NewTestKeys() *TestKeys {
-	failure := "unknown_failure"
 	return &TestKeys{
-		RegistrationServerFailure:        &failure,
-		RegistrationServerStatus:         "blocked",
+		TestKeys:                         servicereachability.NewTestKeys(),
+		LegacyTestKeys:                   servicereachability.NewLegacyTestKeys(),
+		RegistrationServerFailure:        nil,
+		RegistrationServerStatus:         "ok",
 		WhatsappEndpointsBlocked:         []string{},
 		WhatsappEndpointsDNSInconsistent: []string{},
-		WhatsappEndpointsStatus:          "blocked",
-		WhatsappWebFailure:               &failure,
-		WhatsappWebStatus:                "blocked",
-		WhatsappEndpointsCount:           make(map[string]int),
-		WhatsappHTTPSFailure:             &failure,
+		WhatsappEndpointsStatus:          "ok",
+		WhatsappWebFailure:               nil,
+		WhatsappWebStatus:                "ok",
 	}
 }
 
-// Update updates the TestKeys using the given MultiOutput result.
-func (tk *TestKeys) Update(v urlgetter.MultiOutput) {
-	// Update the easy to update entries first
-	tk.NetworkEvents = append(tk.NetworkEvents, v.TestKeys.NetworkEvents...)
-	tk.Queries = append(tk.Queries, v.TestKeys.Queries...)
-	tk.Requests = append(tk.Requests, v.TestKeys.Requests...)
-	tk.TCPConnect = append(tk.TCPConnect, v.TestKeys.TCPConnect...)
-	tk.TLSHandshakes = append(tk.TLSHandshakes, v.TestKeys.TLSHandshakes...)
-	// Set the status of WhatsApp endpoints
-	if endpointPattern.MatchString(v.Input.Target) {
-		if v.TestKeys.Failure != nil {
-			parsed, err := url.Parse(v.Input.Target)
-			runtimex.PanicOnError(err, "url.Parse should not fail here")
-			hostname := parsed.Hostname()
-			tk.WhatsappEndpointsCount[hostname]++
-			// Implementation note: here we're counting twice because we test each
-			// IP address twice: once for 443 and once for 5222. Above we use .Hostname
-			// therefore URL parsing discards the port and we only get the addr.
-			//
-			// This line of code was confusing enough to cause me to create an issue to
-			// investigate it: https://github.com/ooni/probe/issues/2383. So, it's better
-			// to document what's going on here :grimacing:.
-			if tk.WhatsappEndpointsCount[hostname] >= 2 {
-				tk.WhatsappEndpointsBlocked = append(tk.WhatsappEndpointsBlocked, hostname)
-			}
-			return
+// computeVerdicts sets the whatsapp fields using the verdicts of the endpoints,
+// registration and web components. We consider an endpoint hostname blocked when
+// all the checks using such an hostname (i.e., one per port) failed.
+func (tk *TestKeys) computeVerdicts() {
+	if endpoints := tk.Component("endpoints"); endpoints.Blocked() {
+		tk.WhatsappEndpointsStatus = "blocked"
+	}
+	var hostnames []string
+	failed := map[string]bool{}
+	for _, check := range tk.Checks {
+		if check.Component != "endpoints" {
+			continue
+		}
+		hostname, _, err := net.SplitHostPort(check.Target)
+		if err != nil {
+			continue
+		}
+		if _, found := failed[hostname]; !found {
+			hostnames = append(hostnames, hostname)
+			failed[hostname] = true
 		}
-		tk.WhatsappEndpointsStatus = "ok"
-		return
+		failed[hostname] = failed[hostname] && check.Failure != nil
 	}
-	// Set the status of the registration service
-	if v.Input.Target == RegistrationServiceURL {
-		tk.RegistrationServerFailure = v.TestKeys.Failure
-		if v.TestKeys.Failure == nil {
-			tk.RegistrationServerStatus = "ok"
+	for _, hostname := range hostnames {
+		if failed[hostname] {
+			tk.WhatsappEndpointsBlocked = append(tk.WhatsappEndpointsBlocked, hostname)
 		}
-		return
 	}
-	// Track result of accessing the web interface.
-	tk.WhatsappHTTPSFailure = v.TestKeys.Failure
-}
-
-// ComputeWebStatus sets the web status fields.
-func (tk *TestKeys) ComputeWebStatus() {
-	if tk.WhatsappHTTPSFailure == nil {
-		tk.WhatsappWebFailure = nil
-		tk.WhatsappWebStatus = "ok"
-		return
+	if registration := tk.Component("registration"); registration.Blocked() {
+		tk.RegistrationServerStatus = "blocked"
+		tk.RegistrationServerFailure = registration.Failure
+	}
+	if web := tk.Component("web"); web.Blocked() {
+		tk.WhatsappWebStatus = "blocked"
+		tk.WhatsappWebFailure = web.Failure
 	}
-	tk.WhatsappWebStatus = "blocked" // must be here because of unit tests
-	tk.WhatsappWebFailure = tk.WhatsappHTTPSFailure
 }
 
 // Measurer performs the measurement
@@ -120,9 +93,6 @@ type Measurer struct {
 	// Config contains the experiment settings. If empty we
 	// will be using default settings.
 	Config Config
-
-	// Getter is an optional getter to be used for testing.
-	Getter urlgetter.MultiGetter
 }
 
 // ExperimentName implements ExperimentMeasurer.ExperimentName
@@ -137,44 +107,14 @@ func (m Measurer) ExperimentVersion() string {
 
 // Run implements ExperimentMeasurer.Run
 func (m Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
-	callbacks := args.Callbacks
-	measurement := args.Measurement
-	sess := args.Session
-
-	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
-	defer cancel()
-	urlgetter.RegisterExtensions(measurement)
-	// generate all the inputs
-	var inputs []urlgetter.MultiInput
-	for idx := 1; idx <= 16; idx++ {
-		for _, port := range []string{"443", "5222"} {
-			inputs = append(inputs, urlgetter.MultiInput{
-				Target: fmt.Sprintf("tcpconnect://e%d.whatsapp.net:%s", idx, port),
-			})
-		}
+	def, err := servicereachability.LoadDefinition(args.Session, testName)
+	if err != nil {
+		return err
 	}
-	inputs = append(inputs, urlgetter.MultiInput{
-		Target: RegistrationServiceURL,
-	})
-	inputs = append(inputs, urlgetter.MultiInput{
-		// We consider this check successful if we can establish a TLS
-		// connection and we don't see any socket/TLS errors. Hence, we
-		// don't care about the HTTP response code.
-		Target: WebHTTPSURL,
-	})
-	rnd := rand.New(rand.NewSource(time.Now().UnixNano())) // #nosec G404 -- not really important
-	rnd.Shuffle(len(inputs), func(i, j int) {
-		inputs[i], inputs[j] = inputs[j], inputs[i]
-	})
-	// measure in parallel
-	multi := urlgetter.Multi{Begin: time.Now(), Getter: m.Getter, Session: sess}
 	testkeys := NewTestKeys()
-	testkeys.Agent = "redirect"
-	measurement.TestKeys = testkeys
-	for entry := range multi.Collect(ctx, inputs, "whatsapp", callbacks) {
-		testkeys.Update(entry)
-	}
-	testkeys.ComputeWebStatus()
+	args.Measurement.TestKeys = testkeys
+	servicereachability.Measure(ctx, args, def, testkeys.TestKeys)
+	testkeys.computeVerdicts()
 	return nil
 }
 
@@ -3,16 +3,13 @@ package whatsapp_test
 import (
 	"context"
 	"fmt"
-	"io"
 	"regexp"
-	"sync/atomic"
 	"testing"
 
 	"github.com/apex/log"
-	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/netem"
-	"github.com/ooni/probe-cli/v3/internal/experiment/urlgetter"
 	"github.com/ooni/probe-cli/v3/internal/experiment/whatsapp"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netemx"
@@ -23,11 +20,21 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "whatsapp" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.11.0" {
+	if measurer.ExperimentVersion() != "0.12.0" {
 		t.Fatal("unexpected version")
 	}
 }
 
+// newSession creates a session using the given logger and an empty key-value store, such
+// that we use the embedded whatsapp service definition.
+func newSession(logger model.Logger) model.ExperimentSession {
+	kvStore := &kvstore.Memory{}
+	return &mocks.Session{
+		MockLogger:        func() model.Logger { return logger },
+		MockKeyValueStore: func() model.KeyValueStore { return kvStore },
+	}
+}
+
 // whatsappWebAddr is the address of web.whatsapp.net and of v.whatsapp.net as of 2023-07-11
 const whatsappWebAddr = "157.240.27.54"
 
@@ -103,7 +110,7 @@ func TestMeasurerRun(t *testing.T) {
 
 		env.Do(func() {
 			measurer := whatsapp.NewExperimentMeasurer(whatsapp.Config{})
-			sess := &mocks.Session{MockLogger: func() model.Logger { return log.Log }}
+			sess := newSession(log.Log)
 			measurement := new(model.Measurement)
 			args := &model.ExperimentArgs{
 				Callbacks:   model.NewPrinterCallbacks(log.Log),
@@ -151,7 +158,7 @@ func TestMeasurerRun(t *testing.T) {
 		env.Do(func() {
 			measurer := whatsapp.NewExperimentMeasurer(whatsapp.Config{})
 			measurement := &model.Measurement{}
-			sess := &mocks.Session{MockLogger: func() model.Logger { return model.DiscardLogger }}
+			sess := newSession(model.DiscardLogger)
 			args := &model.ExperimentArgs{
 				Callbacks:   model.NewPrinterCallbacks(log.Log),
 				Measurement: measurement,
@@ -201,7 +208,7 @@ func TestMeasurerRun(t *testing.T) {
 		env.Do(func() {
 			measurer := whatsapp.NewExperimentMeasurer(whatsapp.Config{})
 			measurement := &model.Measurement{}
-			sess := &mocks.Session{MockLogger: func() model.Logger { return model.DiscardLogger }}
+			sess := newSession(model.DiscardLogger)
 			args := &model.ExperimentArgs{
 				Callbacks:   model.NewPrinterCallbacks(log.Log),
 				Measurement: measurement,
@@ -246,7 +253,7 @@ func TestFailureAllEndpoints(t *testing.T) {
 		measurer := whatsapp.NewExperimentMeasurer(whatsapp.Config{})
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel() // fail immediately
-		sess := &mocks.Session{MockLogger: func() model.Logger { return model.DiscardLogger }}
+		sess := newSession(model.DiscardLogger)
 		measurement := new(model.Measurement)
 		callbacks := model.NewPrinterCallbacks(model.DiscardLogger)
 		args := &model.ExperimentArgs{
@@ -289,271 +296,6 @@ func TestFailureAllEndpoints(t *testing.T) {
 	})
 }
 
-func TestTestKeysComputeWebStatus(t *testing.T) {
-	errorString := io.EOF.Error()
-	type fields struct {
-		TestKeys                         urlgetter.TestKeys
-		RegistrationServerFailure        *string
-		RegistrationServerStatus         string
-		WhatsappEndpointsBlocked         []string
-		WhatsappEndpointsDNSInconsistent []string
-		WhatsappEndpointsStatus          string
-		WhatsappWebStatus                string
-		WhatsappWebFailure               *string
-		WhatsappHTTPSFailure             *string
-	}
-	tests := []struct {
-		name    string
-		fields  fields
-		failure *string
-		status  string
-	}{{
-		name:    "with success",
-		failure: nil,
-		status:  "ok",
-	}, {
-		name: "with HTTPS failure",
-		fields: fields{
-			WhatsappHTTPSFailure: &errorString,
-		},
-		failure: &errorString,
-		status:  "blocked",
-	}}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tk := &whatsapp.TestKeys{
-				TestKeys:                         tt.fields.TestKeys,
-				RegistrationServerFailure:        tt.fields.RegistrationServerFailure,
-				RegistrationServerStatus:         tt.fields.RegistrationServerStatus,
-				WhatsappEndpointsBlocked:         tt.fields.WhatsappEndpointsBlocked,
-				WhatsappEndpointsDNSInconsistent: tt.fields.WhatsappEndpointsDNSInconsistent,
-				WhatsappEndpointsStatus:          tt.fields.WhatsappEndpointsStatus,
-				WhatsappWebStatus:                tt.fields.WhatsappWebStatus,
-				WhatsappWebFailure:               tt.fields.WhatsappWebFailure,
-				WhatsappHTTPSFailure:             tt.fields.WhatsappHTTPSFailure,
-			}
-			tk.ComputeWebStatus()
-			diff := cmp.Diff(tk.WhatsappWebFailure, tt.failure)
-			if diff != "" {
-				t.Fatal(diff)
-			}
-			diff = cmp.Diff(tk.WhatsappWebStatus, tt.status)
-			if diff != "" {
-				t.Fatal(diff)
-			}
-		})
-	}
-}
-
-func TestTestKeysMixedEndpointsFailure(t *testing.T) {
-	failure := io.EOF.Error()
-	tk := whatsapp.NewTestKeys()
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: "tcpconnect://e7.whatsapp.net:443"},
-		TestKeys: urlgetter.TestKeys{Failure: &failure},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: "tcpconnect://e7.whatsapp.net:5222"},
-		TestKeys: urlgetter.TestKeys{},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: whatsapp.RegistrationServiceURL},
-		TestKeys: urlgetter.TestKeys{},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: whatsapp.WebHTTPSURL},
-		TestKeys: urlgetter.TestKeys{},
-	})
-	tk.ComputeWebStatus()
-	if tk.RegistrationServerFailure != nil {
-		t.Fatal("invalid RegistrationServerFailure")
-	}
-	if tk.RegistrationServerStatus != "ok" {
-		t.Fatal("invalid RegistrationServerStatus")
-	}
-	if len(tk.WhatsappEndpointsBlocked) != 0 {
-		t.Fatal("invalid WhatsappEndpointsBlocked")
-	}
-	if len(tk.WhatsappEndpointsDNSInconsistent) != 0 {
-		t.Fatal("invalid WhatsappEndpointsDNSInconsistent")
-	}
-	if tk.WhatsappEndpointsStatus != "ok" {
-		t.Fatal("invalid WhatsappEndpointsStatus")
-	}
-	if tk.WhatsappWebFailure != nil {
-		t.Fatal("invalid WhatsappWebFailure")
-	}
-	if tk.WhatsappWebStatus != "ok" {
-		t.Fatal("invalid WhatsappWebStatus")
-	}
-}
-
-func TestTestKeysOnlyEndpointsFailure(t *testing.T) {
-	failure := io.EOF.Error()
-	tk := whatsapp.NewTestKeys()
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: "tcpconnect://e7.whatsapp.net:443"},
-		TestKeys: urlgetter.TestKeys{Failure: &failure},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: "tcpconnect://e7.whatsapp.net:5222"},
-		TestKeys: urlgetter.TestKeys{Failure: &failure},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: whatsapp.RegistrationServiceURL},
-		TestKeys: urlgetter.TestKeys{},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: whatsapp.WebHTTPSURL},
-		TestKeys: urlgetter.TestKeys{},
-	})
-	tk.ComputeWebStatus()
-	if tk.RegistrationServerFailure != nil {
-		t.Fatal("invalid RegistrationServerFailure")
-	}
-	if tk.RegistrationServerStatus != "ok" {
-		t.Fatal("invalid RegistrationServerStatus")
-	}
-	if len(tk.WhatsappEndpointsBlocked) != 1 {
-		t.Fatal("invalid WhatsappEndpointsBlocked")
-	}
-	if len(tk.WhatsappEndpointsDNSInconsistent) != 0 {
-		t.Fatal("invalid WhatsappEndpointsDNSInconsistent")
-	}
-	if tk.WhatsappEndpointsStatus != "blocked" {
-		t.Fatal("invalid WhatsappEndpointsStatus")
-	}
-	if tk.WhatsappWebFailure != nil {
-		t.Fatal("invalid WhatsappWebFailure")
-	}
-	if tk.WhatsappWebStatus != "ok" {
-		t.Fatal("invalid WhatsappWebStatus")
-	}
-}
-
-func TestTestKeysOnlyRegistrationServerFailure(t *testing.T) {
-	failure := io.EOF.Error()
-	tk := whatsapp.NewTestKeys()
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: "tcpconnect://e7.whatsapp.net:443"},
-		TestKeys: urlgetter.TestKeys{},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: whatsapp.RegistrationServiceURL},
-		TestKeys: urlgetter.TestKeys{Failure: &failure},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: whatsapp.WebHTTPSURL},
-		TestKeys: urlgetter.TestKeys{},
-	})
-	tk.ComputeWebStatus()
-	if *tk.RegistrationServerFailure != failure {
-		t.Fatal("invalid RegistrationServerFailure")
-	}
-	if tk.RegistrationServerStatus != "blocked" {
-		t.Fatal("invalid RegistrationServerStatus")
-	}
-	if len(tk.WhatsappEndpointsBlocked) != 0 {
-		t.Fatal("invalid WhatsappEndpointsBlocked")
-	}
-	if len(tk.WhatsappEndpointsDNSInconsistent) != 0 {
-		t.Fatal("invalid WhatsappEndpointsDNSInconsistent")
-	}
-	if tk.WhatsappEndpointsStatus != "ok" {
-		t.Fatal("invalid WhatsappEndpointsStatus")
-	}
-	if tk.WhatsappWebFailure != nil {
-		t.Fatal("invalid WhatsappWebFailure")
-	}
-	if tk.WhatsappWebStatus != "ok" {
-		t.Fatal("invalid WhatsappWebStatus")
-	}
-}
-
-func TestTestKeysOnlyWebHTTPSFailure(t *testing.T) {
-	failure := io.EOF.Error()
-	tk := whatsapp.NewTestKeys()
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: "tcpconnect://e7.whatsapp.net:443"},
-		TestKeys: urlgetter.TestKeys{},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: whatsapp.RegistrationServiceURL},
-		TestKeys: urlgetter.TestKeys{},
-	})
-	tk.Update(urlgetter.MultiOutput{
-		Input:    urlgetter.MultiInput{Target: whatsapp.WebHTTPSURL},
-		TestKeys: urlgetter.TestKeys{Failure: &failure},
-	})
-	tk.ComputeWebStatus()
-	if tk.RegistrationServerFailure != nil {
-		t.Fatal("invalid RegistrationServerFailure")
-	}
-	if tk.RegistrationServerStatus != "ok" {
-		t.Fatal("invalid RegistrationServerStatus")
-	}
-	if len(tk.WhatsappEndpointsBlocked) != 0 {
-		t.Fatal("invalid WhatsappEndpointsBlocked")
-	}
-	if len(tk.WhatsappEndpointsDNSInconsistent) != 0 {
-		t.Fatal("invalid WhatsappEndpointsDNSInconsistent")
-	}
-	if tk.WhatsappEndpointsStatus != "ok" {
-		t.Fatal("invalid WhatsappEndpointsStatus")
-	}
-	if *tk.WhatsappWebFailure != failure {
-		t.Fatal("invalid WhatsappWebFailure")
-	}
-	if tk.WhatsappWebStatus != "blocked" {
-		t.Fatal("invalid WhatsappWebStatus")
-	}
-}
-
-func TestWeConfigureWebChecksCorrectly(t *testing.T) {
-	called := &atomic.Int64{}
-	emptyConfig := urlgetter.Config{}
-	measurer := whatsapp.Measurer{
-		Config: whatsapp.Config{},
-		Getter: func(ctx context.Context, g urlgetter.Getter) (urlgetter.TestKeys, error) {
-			switch g.Target {
-			case whatsapp.WebHTTPSURL:
-				called.Add(1)
-				if diff := cmp.Diff(g.Config, emptyConfig); diff != "" {
-					panic(diff)
-				}
-			case whatsapp.RegistrationServiceURL:
-				called.Add(4)
-				if diff := cmp.Diff(g.Config, emptyConfig); diff != "" {
-					panic(diff)
-				}
-			default:
-				called.Add(8)
-				if diff := cmp.Diff(g.Config, emptyConfig); diff != "" {
-					panic(diff)
-				}
-			}
-			return urlgetter.DefaultMultiGetter(ctx, g)
-		},
-	}
-
-	ctx := context.Background()
-	sess := &mocks.Session{MockLogger: func() model.Logger { return model.DiscardLogger }}
-	measurement := new(model.Measurement)
-	callbacks := model.NewPrinterCallbacks(model.DiscardLogger)
-	args := &model.ExperimentArgs{
-		Callbacks:   callbacks,
-		Measurement: measurement,
-		Session:     sess,
-	}
-	if err := measurer.Run(ctx, args); err != nil {
-		t.Fatal(err)
-	}
-	const expected = 261
-	if got := called.Load(); got != expected {
-		t.Fatalf("not called the expected number of times: expected = %d, got = %d", expected, got)
-	}
-}
-
 func TestSummaryKeysWorksAsIntended(t *testing.T) {
 	tests := []struct {
 		tk                         whatsapp.TestKeys
@@ -0,0 +1,136 @@
+package wireguard
+
+//
+// WireGuard bind using measurexlite
+//
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"golang.zx2c4.com/wireguard/conn"
+)
+
+// traceBind is a [conn.Bind] sending and receiving packets using a UDP socket
+// created by a [*measurexlite.Trace], such that we collect network events.
+type traceBind struct {
+	// conn is the UDP socket or nil when the bind is closed.
+	conn model.UDPLikeConn
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// trace is the trace to use.
+	trace *measurexlite.Trace
+}
+
+// newTraceBind creates a new [*traceBind] using the given trace.
+func newTraceBind(trace *measurexlite.Trace) *traceBind {
+	return &traceBind{
+		conn:  nil,
+		mu:    sync.Mutex{},
+		trace: trace,
+	}
+}
+
+var _ conn.Bind = &traceBind{}
+
+// errBindAlreadyOpen indicates that we have already opened the bind.
+var errBindAlreadyOpen = errors.New("wireguard: bind already open")
+
+// Open implements conn.Bind.
+func (b *traceBind) Open(port uint16) ([]conn.ReceiveFunc, uint16, error) {
+	defer b.mu.Unlock()
+	b.mu.Lock()
+	if b.conn != nil {
+		return nil, 0, errBindAlreadyOpen
+	}
+	pconn, err := b.trace.NewUDPListener().Listen(&net.UDPAddr{Port: int(port)})
+	if err != nil {
+		return nil, 0, err
+	}
+	pconn = b.trace.MaybeWrapUDPLikeConn(pconn)
+	b.conn = pconn
+	var actualPort uint16
+	if addr, good := pconn.LocalAddr().(*net.UDPAddr); good {
+		actualPort = uint16(addr.Port)
+	}
+	return []conn.ReceiveFunc{b.newReceiveFunc(pconn)}, actualPort, nil
+}
+
+// newReceiveFunc returns the [conn.ReceiveFunc] reading from the given socket.
+func (b *traceBind) newReceiveFunc(pconn model.UDPLikeConn) conn.ReceiveFunc {
+	return func(packets [][]byte, sizes []int, eps []conn.Endpoint) (int, error) {
+		count, addr, err := pconn.ReadFrom(packets[0])
+		if err != nil {
+			// Note: the error wrapper wraps net.ErrClosed and WireGuard
+			// uses errors.Is to know whether the socket is closed.
+			return 0, err
+		}
+		udpAddr, good := addr.(*net.UDPAddr)
+		if !good {
+			return 0, nil // should not happen but we can safely skip the packet
+		}
+		sizes[0] = count
+		eps[0] = &conn.StdNetEndpoint{AddrPort: udpAddr.AddrPort()}
+		return 1, nil
+	}
+}
+
+// Close implements conn.Bind.
+func (b *traceBind) Close() (err error) {
+	defer b.mu.Unlock()
+	b.mu.Lock()
+	if b.conn != nil {
+		err = b.conn.Close()
+		b.conn = nil
+	}
+	return
+}
+
+// SetMark implements conn.Bind.
+func (b *traceBind) SetMark(mark uint32) error {
+	return nil
+}
+
+// errBindClosed indicates that the bind is closed.
+var errBindClosed = errors.New("wireguard: bind closed")
+
+// Send implements conn.Bind.
+func (b *traceBind) Send(bufs [][]byte, ep conn.Endpoint) error {
+	b.mu.Lock()
+	pconn := b.conn
+	b.mu.Unlock()
+	if pconn == nil {
+		return errBindClosed
+	}
+	addrport, err := netip.ParseAddrPort(ep.DstToString())
+	if err != nil {
+		return err
+	}
+	addr := net.UDPAddrFromAddrPort(addrport)
+	for _, buf := range bufs {
+		if _, err := pconn.WriteTo(buf, addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseEndpoint implements conn.Bind.
+func (b *traceBind) ParseEndpoint(s string) (conn.Endpoint, error) {
+	addrport, err := netip.ParseAddrPort(s)
+	if err != nil {
+		return nil, err
+	}
+	return &conn.StdNetEndpoint{AddrPort: addrport}, nil
+}
+
+// BatchSize implements conn.Bind.
+func (b *traceBind) BatchSize() int {
+	return 1
+}
@@ -0,0 +1,57 @@
+package wireguard
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"net/url"
+
+	"github.com/ooni/probe-cli/v3/internal/targetloading"
+)
+
+var (
+	// ErrInputRequired indicates that no richer-input target was provided.
+	ErrInputRequired = targetloading.ErrInputRequired
+
+	// ErrInvalidInput indicates that the input or the config are not valid.
+	ErrInvalidInput = targetloading.ErrInvalidInput
+
+	// ErrInvalidInputType indicates that the richer-input target has the wrong type.
+	ErrInvalidInputType = targetloading.ErrInvalidInputType
+)
+
+// parseEndpoint parses the input string and returns the endpoint to measure.
+//
+// The input URI is in the form "wireguard://1.2.3.4:51820". We require an IP
+// address because we do not want to depend on the DNS to reach the endpoint.
+func parseEndpoint(uri string) (netip.AddrPort, error) {
+	if uri == "" {
+		return netip.AddrPort{}, ErrInputRequired
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+	}
+	if parsed.Scheme != "wireguard" {
+		return netip.AddrPort{}, fmt.Errorf("%w: unknown scheme: %s", ErrInvalidInput, parsed.Scheme)
+	}
+	if parsed.Path != "" && parsed.Path != "/" {
+		return netip.AddrPort{}, fmt.Errorf("%w: unexpected path: %s", ErrInvalidInput, parsed.Path)
+	}
+	endpoint, err := netip.ParseAddrPort(parsed.Host)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("%w: expected ip:port as host: %s", ErrInvalidInput, parsed.Host)
+	}
+	return endpoint, nil
+}
+
+// parseKey parses a base64-encoded WireGuard key and returns its hex encoding, which
+// is the encoding used by the WireGuard configuration protocol.
+func parseKey(name, value string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(data) != 32 {
+		return "", fmt.Errorf("%w: %s is not a valid base64-encoded key", ErrInvalidInput, name)
+	}
+	return hex.EncodeToString(data), nil
+}
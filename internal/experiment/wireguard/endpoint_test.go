@@ -0,0 +1,100 @@
+package wireguard
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    netip.AddrPort
+		wantErr error
+	}{{
+		name:    "empty input returns error",
+		uri:     "",
+		want:    netip.AddrPort{},
+		wantErr: ErrInputRequired,
+	}, {
+		name:    "uri with illegal chars returns error",
+		uri:     "wireguard://\x7f/#",
+		want:    netip.AddrPort{},
+		wantErr: ErrInvalidInput,
+	}, {
+		name:    "invalid scheme returns error",
+		uri:     "openvpn://1.1.1.1:51820",
+		want:    netip.AddrPort{},
+		wantErr: ErrInvalidInput,
+	}, {
+		name:    "unexpected path returns error",
+		uri:     "wireguard://1.1.1.1:51820/foo",
+		want:    netip.AddrPort{},
+		wantErr: ErrInvalidInput,
+	}, {
+		name:    "domain name returns error",
+		uri:     "wireguard://vpn.example.com:51820",
+		want:    netip.AddrPort{},
+		wantErr: ErrInvalidInput,
+	}, {
+		name:    "missing port returns error",
+		uri:     "wireguard://1.1.1.1",
+		want:    netip.AddrPort{},
+		wantErr: ErrInvalidInput,
+	}, {
+		name:    "valid IPv4 endpoint",
+		uri:     "wireguard://1.1.1.1:51820",
+		want:    netip.MustParseAddrPort("1.1.1.1:51820"),
+		wantErr: nil,
+	}, {
+		name:    "valid IPv6 endpoint with trailing slash",
+		uri:     "wireguard://[2001:db8::1]:51820/",
+		want:    netip.MustParseAddrPort("[2001:db8::1]:51820"),
+		wantErr: nil,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEndpoint(tt.uri)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if got != tt.want {
+				t.Fatal("expected", tt.want, "got", got)
+			}
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	t.Run("with a valid key", func(t *testing.T) {
+		got, err := parseKey("public key", "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA=")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"
+		if got != expect {
+			t.Fatal("expected", expect, "got", got)
+		}
+	})
+
+	t.Run("with invalid base64", func(t *testing.T) {
+		got, err := parseKey("public key", "!!!")
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Fatal("unexpected error", err)
+		}
+		if got != "" {
+			t.Fatal("expected empty key")
+		}
+	})
+
+	t.Run("with the wrong length", func(t *testing.T) {
+		got, err := parseKey("public key", "AQIDBA==")
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Fatal("unexpected error", err)
+		}
+		if got != "" {
+			t.Fatal("expected empty key")
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package wireguard
+
+//
+// Fetching a URL through the tunnel
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+)
+
+// fetch fetches the given URL through the tunnel and updates the test keys.
+func (m *Measurer) fetch(ctx context.Context, logger model.Logger,
+	zeroTime time.Time, tnet *netstack.Net, URL string, tk *TestKeys) {
+	const fetchTimeout = 30 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	trace := measurexlite.NewTrace(2, zeroTime)
+	trace.Netx = &netxlite.Netx{Underlying: &tunnelNetwork{tnet}}
+
+	ol := logx.NewOperationLogger(logger, "wireguard: fetching %s through the tunnel", URL)
+	err := m.fetchWithTrace(ctx, logger, trace, URL, tk)
+	ol.Stop(err)
+
+	tk.Queries = append(tk.Queries, trace.DNSLookupsFromRoundTrip()...)
+	tk.TCPConnect = append(tk.TCPConnect, trace.TCPConnects()...)
+	tk.TLSHandshakes = append(tk.TLSHandshakes, trace.TLSHandshakes()...)
+	tk.NetworkEvents = append(tk.NetworkEvents, trace.NetworkEvents()...)
+	tk.Failure = measurexlite.NewFailure(err)
+}
+
+// fetchWithTrace resolves the URL domain, connects to the first address and
+// performs an HTTP round trip using the given trace.
+func (m *Measurer) fetchWithTrace(ctx context.Context, logger model.Logger,
+	trace *measurexlite.Trace, URL string, tk *TestKeys) error {
+	parsed, err := url.Parse(URL)
+	if err != nil {
+		return err
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[parsed.Scheme]
+	}
+
+	// resolve the domain using the DNS server configured inside the tunnel
+	addrs := []string{parsed.Hostname()}
+	if net.ParseIP(parsed.Hostname()) == nil {
+		resolver := trace.NewStdlibResolver(logger)
+		addrs, err = resolver.LookupHost(ctx, parsed.Hostname())
+		if err != nil {
+			return err
+		}
+	}
+	address := net.JoinHostPort(addrs[0], port)
+
+	// establish the connection
+	dialer := trace.NewDialerWithoutResolver(logger)
+	tcpConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer tcpConn.Close()
+
+	var (
+		alpn string
+		txp  model.HTTPTransport
+	)
+	switch parsed.Scheme {
+	case "https":
+		thx := trace.NewTLSHandshakerStdlib(logger)
+		config := &tls.Config{ // #nosec G402 - we need to use a large TLS versions range for measuring
+			NextProtos: []string{"h2", "http/1.1"},
+			RootCAs:    nil, // use the tunnel default cert pool
+			ServerName: parsed.Hostname(),
+		}
+		tlsConn, err := thx.Handshake(ctx, tcpConn, config)
+		if err != nil {
+			return err
+		}
+		defer tlsConn.Close()
+		alpn = tlsConn.ConnectionState().NegotiatedProtocol
+		txp = netxlite.NewHTTPTransportWithOptions(
+			logger, netxlite.NewNullDialer(), netxlite.NewSingleUseTLSDialer(tlsConn))
+	default:
+		txp = netxlite.NewHTTPTransportWithOptions(
+			logger, netxlite.NewSingleUseDialer(tcpConn), netxlite.NewNullTLSDialer())
+	}
+	defer txp.CloseIdleConnections()
+
+	// perform the HTTP round trip
+	req, err := http.NewRequestWithContext(ctx, "GET", URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", model.HTTPHeaderAccept)
+	req.Header.Set("Accept-Language", model.HTTPHeaderAcceptLanguage)
+	req.Header.Set("User-Agent", model.HTTPHeaderUserAgent)
+
+	const maxbody = 1 << 19
+	started := trace.TimeSince(trace.ZeroTime())
+	resp, err := txp.RoundTrip(req)
+	var body []byte
+	if err == nil {
+		defer resp.Body.Close()
+		body, err = netxlite.StreamAllContext(ctx, io.LimitReader(resp.Body, maxbody))
+	}
+	finished := trace.TimeSince(trace.ZeroTime())
+	tk.Requests = append(tk.Requests, measurexlite.NewArchivalHTTPRequestResult(
+		trace.Index(), started, "tcp", address, alpn, txp.Network(),
+		req, resp, maxbody, body, err, finished))
+	return err
+}
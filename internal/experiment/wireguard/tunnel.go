@@ -0,0 +1,69 @@
+package wireguard
+
+//
+// Using the WireGuard tunnel as the underlying network
+//
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+)
+
+// tunnelNetwork is a [model.UnderlyingNetwork] using the userspace TCP/IP
+// stack connected to the WireGuard tunnel. By setting a [*measurexlite.Trace]
+// Netx field to use this network, we can measure inside the tunnel.
+type tunnelNetwork struct {
+	tnet *netstack.Net
+}
+
+var _ model.UnderlyingNetwork = &tunnelNetwork{}
+
+// tunnelCertPool returns the cert pool to use inside the tunnel.
+var tunnelCertPool = sync.OnceValue(netxlite.NewMozillaCertPool)
+
+// DefaultCertPool implements model.UnderlyingNetwork.
+func (tn *tunnelNetwork) DefaultCertPool() *x509.CertPool {
+	return tunnelCertPool()
+}
+
+// DialTimeout implements model.UnderlyingNetwork.
+func (tn *tunnelNetwork) DialTimeout() time.Duration {
+	return 15 * time.Second
+}
+
+// DialContext implements model.UnderlyingNetwork.
+func (tn *tunnelNetwork) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return tn.tnet.DialContext(ctx, network, address)
+}
+
+// GetaddrinfoLookupANY implements model.UnderlyingNetwork.
+func (tn *tunnelNetwork) GetaddrinfoLookupANY(ctx context.Context, domain string) ([]string, string, error) {
+	addrs, err := tn.tnet.LookupContextHost(ctx, domain)
+	return addrs, "", err
+}
+
+// GetaddrinfoResolverNetwork implements model.UnderlyingNetwork.
+func (tn *tunnelNetwork) GetaddrinfoResolverNetwork() string {
+	return netxlite.StdlibResolverGolangNetResolver
+}
+
+// ListenTCP implements model.UnderlyingNetwork.
+func (tn *tunnelNetwork) ListenTCP(network string, addr *net.TCPAddr) (net.Listener, error) {
+	return tn.tnet.ListenTCP(addr)
+}
+
+// errTunnelUDPNotSupported indicates that we do not support UDP sockets inside the tunnel.
+var errTunnelUDPNotSupported = errors.New("wireguard: UDP sockets not supported inside the tunnel")
+
+// ListenUDP implements model.UnderlyingNetwork.
+func (tn *tunnelNetwork) ListenUDP(network string, addr *net.UDPAddr) (model.UDPLikeConn, error) {
+	return nil, errTunnelUDPNotSupported
+}
@@ -0,0 +1,329 @@
+// Package wireguard contains the wireguard experiment.
+//
+// This experiment performs a userspace WireGuard handshake with the configured
+// endpoint and, optionally, fetches a URL through the resulting tunnel.
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+)
+
+const (
+	testName    = "wireguard"
+	testVersion = "0.1.0"
+)
+
+// Config contains the experiment config.
+//
+// By tagging these variables with `ooni:"..."`, we allow miniooni's -O flag to find
+// them and set them. The fields whose name starts with Safe are not serialized
+// as options inside the measurement, so we use them for secrets.
+type Config struct {
+	// DNS is the IP address of the DNS server to use inside the tunnel.
+	DNS string `json:"dns,omitempty" ooni:"IP address of the DNS server to use inside the tunnel"`
+
+	// HandshakeTimeout is the handshake timeout in seconds.
+	HandshakeTimeout int64 `json:"handshake_timeout,omitempty" ooni:"handshake timeout in seconds"`
+
+	// Provider is the VPN provider.
+	Provider string `json:"provider,omitempty" ooni:"VPN provider"`
+
+	// PublicKey is the base64-encoded public key of the endpoint.
+	PublicKey string `json:"public_key,omitempty" ooni:"base64-encoded public key of the WireGuard endpoint"`
+
+	// SafeLocalAddress is our IP address inside the tunnel.
+	SafeLocalAddress string `json:"safe_local_address,omitempty" ooni:"our IP address inside the tunnel"`
+
+	// SafePresharedKey is the OPTIONAL base64-encoded preshared key.
+	SafePresharedKey string `json:"safe_preshared_key,omitempty" ooni:"base64-encoded preshared key"`
+
+	// SafePrivateKey is our base64-encoded private key.
+	SafePrivateKey string `json:"safe_private_key,omitempty" ooni:"base64-encoded private key to connect to the WireGuard endpoint"`
+
+	// URL is the OPTIONAL URL to fetch through the tunnel.
+	URL string `json:"url,omitempty" ooni:"URL to fetch through the tunnel"`
+}
+
+func (c *Config) dns() string {
+	if c.DNS != "" {
+		return c.DNS
+	}
+	return "1.1.1.1"
+}
+
+func (c *Config) handshakeTimeout() time.Duration {
+	if c.HandshakeTimeout > 0 {
+		return time.Duration(c.HandshakeTimeout) * time.Second
+	}
+	return 15 * time.Second
+}
+
+// TestKeys contains the experiment's result.
+type TestKeys struct {
+	BootstrapTime      float64                                   `json:"bootstrap_time"`
+	Failure            *string                                   `json:"failure"`
+	NetworkEvents      []*model.ArchivalNetworkEvent             `json:"network_events"`
+	Queries            []*model.ArchivalDNSLookupResult          `json:"queries"`
+	Requests           []*model.ArchivalHTTPRequestResult        `json:"requests"`
+	Success            bool                                      `json:"success"`
+	TCPConnect         []*model.ArchivalTCPConnectResult         `json:"tcp_connect"`
+	TLSHandshakes      []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
+	Tunnel             string                                    `json:"tunnel"`
+	WireGuardHandshake []*model.ArchivalWireGuardHandshakeResult `json:"wireguard_handshake"`
+}
+
+// NewTestKeys creates new wireguard TestKeys.
+func NewTestKeys() *TestKeys {
+	return &TestKeys{
+		BootstrapTime:      0,
+		Failure:            nil,
+		NetworkEvents:      []*model.ArchivalNetworkEvent{},
+		Queries:            []*model.ArchivalDNSLookupResult{},
+		Requests:           []*model.ArchivalHTTPRequestResult{},
+		Success:            false,
+		TCPConnect:         []*model.ArchivalTCPConnectResult{},
+		TLSHandshakes:      []*model.ArchivalTLSOrQUICHandshakeResult{},
+		Tunnel:             "wireguard",
+		WireGuardHandshake: []*model.ArchivalWireGuardHandshakeResult{},
+	}
+}
+
+// Measurer performs the measurement.
+type Measurer struct{}
+
+// NewExperimentMeasurer creates a new ExperimentMeasurer.
+func NewExperimentMeasurer() model.ExperimentMeasurer {
+	return &Measurer{}
+}
+
+// ExperimentName implements model.ExperimentMeasurer.ExperimentName.
+func (m *Measurer) ExperimentName() string {
+	return testName
+}
+
+// ExperimentVersion implements model.ExperimentMeasurer.ExperimentVersion.
+func (m *Measurer) ExperimentVersion() string {
+	return testVersion
+}
+
+// settings contains the validated settings for a single run.
+type settings struct {
+	dnsServer    netip.Addr
+	endpoint     netip.AddrPort
+	localAddrs   []netip.Addr
+	presharedKey string
+	privateKey   string
+	publicKey    string
+}
+
+// newSettings validates the input and the config and returns the settings to use.
+func newSettings(input string, config *Config) (*settings, error) {
+	endpoint, err := parseEndpoint(input)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := parseKey("private key", config.SafePrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := parseKey("public key", config.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	var presharedKey string
+	if config.SafePresharedKey != "" {
+		if presharedKey, err = parseKey("preshared key", config.SafePresharedKey); err != nil {
+			return nil, err
+		}
+	}
+	dnsServer, err := netip.ParseAddr(config.dns())
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid DNS server: %s", ErrInvalidInput, config.dns())
+	}
+	var localAddrs []netip.Addr
+	switch {
+	case config.SafeLocalAddress != "":
+		addr, err := netip.ParseAddr(config.SafeLocalAddress)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid local address", ErrInvalidInput)
+		}
+		localAddrs = append(localAddrs, addr)
+	case config.URL != "":
+		return nil, fmt.Errorf("%w: fetching a URL requires a local address", ErrInvalidInput)
+	}
+	s := &settings{
+		dnsServer:    dnsServer,
+		endpoint:     endpoint,
+		localAddrs:   localAddrs,
+		presharedKey: presharedKey,
+		privateKey:   privateKey,
+		publicKey:    publicKey,
+	}
+	return s, nil
+}
+
+// ipcConfig returns the configuration to pass to [*device.Device] IpcSet.
+func (s *settings) ipcConfig() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "private_key=%s\n", s.privateKey)
+	fmt.Fprintf(&sb, "public_key=%s\n", s.publicKey)
+	if s.presharedKey != "" {
+		fmt.Fprintf(&sb, "preshared_key=%s\n", s.presharedKey)
+	}
+	fmt.Fprintf(&sb, "endpoint=%s\n", s.endpoint.String())
+	fmt.Fprintf(&sb, "allowed_ip=0.0.0.0/0\n")
+	fmt.Fprintf(&sb, "allowed_ip=::/0\n")
+	// Setting a persistent keepalive causes the device to start the
+	// handshake as soon as it is up rather than on the first packet.
+	fmt.Fprintf(&sb, "persistent_keepalive_interval=25\n")
+	return sb.String()
+}
+
+// Run implements model.ExperimentMeasurer.Run.
+func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	callbacks := args.Callbacks
+	measurement := args.Measurement
+	sess := args.Session
+
+	// 1. make sure we have a valid richer input target
+	if args.Target == nil {
+		return ErrInputRequired
+	}
+	target, ok := args.Target.(*Target)
+	if !ok {
+		return ErrInvalidInputType
+	}
+	config, input := target.Config, target.URL
+
+	// 2. validate the input and the config
+	settings, err := newSettings(input, config)
+	if err != nil {
+		return err
+	}
+
+	tk := NewTestKeys()
+	measurement.TestKeys = tk
+
+	// 3. create the userspace network stack and the device
+	zeroTime := measurement.MeasurementStartTimeSaved
+	trace := measurexlite.NewTrace(1, zeroTime)
+	tundev, tnet, err := netstack.CreateNetTUN(
+		settings.localAddrs, []netip.Addr{settings.dnsServer}, device.DefaultMTU)
+	if err != nil {
+		return err
+	}
+	dev := device.NewDevice(tundev, newTraceBind(trace), newDeviceLogger(sess.Logger()))
+	defer dev.Close()
+
+	// 4. perform the handshake
+	ok = m.handshake(ctx, sess.Logger(), trace, dev, settings, config, tk)
+
+	// 5. optionally fetch the URL inside the tunnel
+	if ok && config.URL != "" {
+		m.fetch(ctx, sess.Logger(), zeroTime, tnet, config.URL, tk)
+	}
+	tk.Success = tk.Failure == nil
+
+	callbacks.OnProgress(1.0, "wireguard endpoint probed")
+
+	// Note: if here we return an error, the parent code will assume
+	// something fundamental was wrong and we don't have a measurement
+	// to submit to the OONI collector. Keep this in mind when you
+	// are writing new experiments!
+	return nil
+}
+
+// handshake configures the device, brings it up and waits for the handshake to
+// complete. It returns whether the handshake was successful.
+func (m *Measurer) handshake(ctx context.Context, logger model.Logger, trace *measurexlite.Trace,
+	dev *device.Device, settings *settings, config *Config, tk *TestKeys) bool {
+	ol := logx.NewOperationLogger(logger, "wireguard: handshake with %s", settings.endpoint.String())
+	started := trace.TimeSince(trace.ZeroTime())
+	err := dev.IpcSet(settings.ipcConfig())
+	if err == nil {
+		err = dev.Up()
+	}
+	if err == nil {
+		err = waitForHandshake(ctx, dev, config.handshakeTimeout())
+	}
+	finished := trace.TimeSince(trace.ZeroTime())
+	ol.Stop(err)
+
+	result := &model.ArchivalWireGuardHandshakeResult{
+		Endpoint:      fmt.Sprintf("wireguard://%s", settings.endpoint.String()),
+		Failure:       measurexlite.NewFailure(err),
+		HandshakeTime: 0,
+		IP:            settings.endpoint.Addr().String(),
+		Port:          int(settings.endpoint.Port()),
+		Provider:      config.Provider,
+		T0:            started.Seconds(),
+		T:             finished.Seconds(),
+		Tags:          []string{},
+		TransactionID: trace.Index(),
+		Transport:     "udp",
+	}
+	tk.WireGuardHandshake = append(tk.WireGuardHandshake, result)
+	tk.NetworkEvents = append(tk.NetworkEvents, trace.NetworkEvents()...)
+	if err != nil {
+		tk.Failure = result.Failure
+		return false
+	}
+	result.HandshakeTime = (finished - started).Seconds()
+	tk.BootstrapTime = finished.Seconds()
+	return true
+}
+
+// waitForHandshake waits for the device to complete the handshake.
+func waitForHandshake(ctx context.Context, dev *device.Device, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			state, err := dev.IpcGet()
+			if err != nil {
+				return err
+			}
+			if handshakeCompleted(state) {
+				return nil
+			}
+		}
+	}
+}
+
+// handshakeCompleted returns whether the device state returned by IpcGet
+// indicates that we have completed the handshake with the peer.
+func handshakeCompleted(state string) bool {
+	for _, line := range strings.Split(state, "\n") {
+		value, found := strings.CutPrefix(line, "last_handshake_time_sec=")
+		if found && value != "" && value != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// newDeviceLogger adapts a [model.Logger] to be a [*device.Logger].
+func newDeviceLogger(logger model.Logger) *device.Logger {
+	logf := func(format string, args ...any) {
+		logger.Debugf("wireguard: "+format, args...)
+	}
+	return &device.Logger{
+		Verbosef: logf,
+		Errorf:   logf,
+	}
+}
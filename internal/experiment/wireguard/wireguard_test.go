@@ -0,0 +1,317 @@
+package wireguard
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"golang.org/x/crypto/curve25519"
+	"golang.zx2c4.com/wireguard/conn"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+)
+
+// testKeyPair is a WireGuard key pair used for testing.
+type testKeyPair struct {
+	private []byte
+	public  []byte
+}
+
+// newTestKeyPair generates a new [*testKeyPair].
+func newTestKeyPair(t *testing.T) *testKeyPair {
+	private := make([]byte, 32)
+	if _, err := rand.Read(private); err != nil {
+		t.Fatal(err)
+	}
+	// clamp the private key as documented in RFC 7748
+	private[0] &= 248
+	private[31] = (private[31] & 127) | 64
+	public, err := curve25519.X25519(private, curve25519.Basepoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeyPair{private: private, public: public}
+}
+
+// startTestPeer starts a userspace WireGuard peer listening on the loopback interface
+// and serving HTTP at 10.0.0.1 inside the tunnel. It returns the peer UDP port.
+func startTestPeer(t *testing.T, server, client *testKeyPair) int {
+	tundev, tnet, err := netstack.CreateNetTUN(
+		[]netip.Addr{netip.MustParseAddr("10.0.0.1")}, nil, device.DefaultMTU)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := device.NewDevice(tundev, conn.NewDefaultBind(), device.NewLogger(device.LogLevelSilent, ""))
+	t.Cleanup(dev.Close)
+	config := fmt.Sprintf(
+		"private_key=%s\nlisten_port=0\npublic_key=%s\nallowed_ip=10.0.0.2/32\n",
+		hex.EncodeToString(server.private), hex.EncodeToString(client.public))
+	if err := dev.IpcSet(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Up(); err != nil {
+		t.Fatal(err)
+	}
+	state, err := dev.IpcGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var port int
+	for _, line := range strings.Split(state, "\n") {
+		if value, found := strings.CutPrefix(line, "listen_port="); found {
+			port, _ = strconv.Atoi(value)
+		}
+	}
+	if port <= 0 {
+		t.Fatal("cannot determine the peer listen port")
+	}
+
+	listener, err := tnet.ListenTCP(&net.TCPAddr{Port: 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello from the tunnel"))
+	})}
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Close() })
+	return port
+}
+
+func TestMeasurer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+
+	// setup starts a new test peer and returns the input and a valid config for
+	// connecting to it. We use a distinct peer for each subtest because the peer
+	// ignores handshake initiations from the same client arriving too quickly.
+	setup := func(t *testing.T) (string, int, *Config) {
+		server, client := newTestKeyPair(t), newTestKeyPair(t)
+		port := startTestPeer(t, server, client)
+		input := fmt.Sprintf("wireguard://127.0.0.1:%d", port)
+		config := &Config{
+			HandshakeTimeout: 5,
+			Provider:         "example",
+			PublicKey:        base64.StdEncoding.EncodeToString(server.public),
+			SafeLocalAddress: "10.0.0.2",
+			SafePrivateKey:   base64.StdEncoding.EncodeToString(client.private),
+			URL:              "http://10.0.0.1/",
+		}
+		return input, port, config
+	}
+
+	// runHelper runs the experiment with the given context, target, and config.
+	runHelper := func(ctx context.Context, target model.ExperimentTarget) (*model.Measurement, error) {
+		m := NewExperimentMeasurer()
+		if m.ExperimentName() != "wireguard" {
+			t.Fatal("invalid experiment name")
+		}
+		if m.ExperimentVersion() != "0.1.0" {
+			t.Fatal("invalid experiment version")
+		}
+		meas := &model.Measurement{MeasurementStartTimeSaved: time.Now()}
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+			Measurement: meas,
+			Session: &mocks.Session{
+				MockLogger: func() model.Logger { return model.DiscardLogger },
+			},
+			Target: target,
+		}
+		return meas, m.Run(ctx, args)
+	}
+
+	t.Run("handshake and fetch through the tunnel", func(t *testing.T) {
+		input, port, config := setup(t)
+		meas, err := runHelper(context.Background(), &Target{Config: config, URL: input})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := meas.TestKeys.(*TestKeys)
+		if tk.Failure != nil {
+			t.Fatal("unexpected failure", *tk.Failure)
+		}
+		if !tk.Success {
+			t.Fatal("expected success")
+		}
+		if tk.Tunnel != "wireguard" {
+			t.Fatal("unexpected tunnel", tk.Tunnel)
+		}
+		if len(tk.WireGuardHandshake) != 1 {
+			t.Fatal("expected a single handshake")
+		}
+		hs := tk.WireGuardHandshake[0]
+		if hs.Failure != nil || hs.HandshakeTime <= 0 || hs.Provider != "example" {
+			t.Fatal("unexpected handshake result", hs)
+		}
+		if hs.IP != "127.0.0.1" || hs.Port != port || hs.Transport != "udp" || hs.Endpoint != input {
+			t.Fatal("unexpected handshake endpoint", hs)
+		}
+		if tk.BootstrapTime <= 0 {
+			t.Fatal("expected a positive bootstrap time")
+		}
+		if len(tk.NetworkEvents) <= 0 {
+			t.Fatal("expected network events")
+		}
+		if len(tk.TCPConnect) != 1 || tk.TCPConnect[0].Status.Failure != nil {
+			t.Fatal("unexpected tcp_connect", tk.TCPConnect)
+		}
+		if len(tk.Requests) != 1 {
+			t.Fatal("expected a single request")
+		}
+		if body := string(tk.Requests[0].Response.Body); body != "hello from the tunnel" {
+			t.Fatal("unexpected body", body)
+		}
+	})
+
+	t.Run("handshake only without a URL", func(t *testing.T) {
+		input, _, config := setup(t)
+		config.URL = ""
+		config.SafeLocalAddress = ""
+		meas, err := runHelper(context.Background(), &Target{Config: config, URL: input})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := meas.TestKeys.(*TestKeys)
+		if !tk.Success || len(tk.Requests) != 0 {
+			t.Fatal("unexpected test keys", tk)
+		}
+	})
+
+	t.Run("handshake timeout with the wrong server key", func(t *testing.T) {
+		input, _, config := setup(t)
+		config.HandshakeTimeout = 1
+		config.PublicKey = base64.StdEncoding.EncodeToString(newTestKeyPair(t).public)
+		meas, err := runHelper(context.Background(), &Target{Config: config, URL: input})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := meas.TestKeys.(*TestKeys)
+		if tk.Failure == nil || *tk.Failure != netxlite.FailureGenericTimeoutError {
+			t.Fatal("unexpected failure", tk.Failure)
+		}
+		if tk.Success || tk.BootstrapTime != 0 || len(tk.Requests) != 0 {
+			t.Fatal("unexpected test keys", tk)
+		}
+		if hs := tk.WireGuardHandshake[0]; hs.HandshakeTime != 0 || hs.Failure == nil {
+			t.Fatal("unexpected handshake result", hs)
+		}
+	})
+
+	t.Run("with a canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		input, _, config := setup(t)
+		meas, err := runHelper(ctx, &Target{Config: config, URL: input})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := meas.TestKeys.(*TestKeys)
+		if tk.Failure == nil || *tk.Failure != netxlite.FailureInterrupted {
+			t.Fatal("unexpected failure", tk.Failure)
+		}
+	})
+}
+
+func TestMeasurerInvalidInput(t *testing.T) {
+	validKey := "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+
+	run := func(target model.ExperimentTarget) error {
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+			Measurement: &model.Measurement{},
+			Session: &mocks.Session{
+				MockLogger: func() model.Logger { return model.DiscardLogger },
+			},
+			Target: target,
+		}
+		return NewExperimentMeasurer().Run(context.Background(), args)
+	}
+
+	t.Run("without a target", func(t *testing.T) {
+		if err := run(nil); !errors.Is(err, ErrInputRequired) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with the wrong target type", func(t *testing.T) {
+		if err := run(&model.OOAPIURLInfo{}); !errors.Is(err, ErrInvalidInputType) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		input  string
+		config *Config
+		expect error
+	}{{
+		name:   "with empty input",
+		input:  "",
+		config: &Config{PublicKey: validKey, SafePrivateKey: validKey},
+		expect: ErrInputRequired,
+	}, {
+		name:   "with an invalid private key",
+		input:  "wireguard://1.1.1.1:51820",
+		config: &Config{PublicKey: validKey, SafePrivateKey: "xo"},
+		expect: ErrInvalidInput,
+	}, {
+		name:   "with an invalid public key",
+		input:  "wireguard://1.1.1.1:51820",
+		config: &Config{PublicKey: "", SafePrivateKey: validKey},
+		expect: ErrInvalidInput,
+	}, {
+		name:   "with an invalid preshared key",
+		input:  "wireguard://1.1.1.1:51820",
+		config: &Config{PublicKey: validKey, SafePresharedKey: "xo", SafePrivateKey: validKey},
+		expect: ErrInvalidInput,
+	}, {
+		name:   "with an invalid DNS server",
+		input:  "wireguard://1.1.1.1:51820",
+		config: &Config{DNS: "dns.google", PublicKey: validKey, SafePrivateKey: validKey},
+		expect: ErrInvalidInput,
+	}, {
+		name:   "with an invalid local address",
+		input:  "wireguard://1.1.1.1:51820",
+		config: &Config{PublicKey: validKey, SafeLocalAddress: "xo", SafePrivateKey: validKey},
+		expect: ErrInvalidInput,
+	}, {
+		name:   "with a URL but no local address",
+		input:  "wireguard://1.1.1.1:51820",
+		config: &Config{PublicKey: validKey, SafePrivateKey: validKey, URL: "https://example.com/"},
+		expect: ErrInvalidInput,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := run(&Target{Config: tt.config, URL: tt.input}); !errors.Is(err, tt.expect) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
+
+func TestHandshakeCompleted(t *testing.T) {
+	if handshakeCompleted("public_key=abcd\nlast_handshake_time_sec=0\n") {
+		t.Fatal("expected false with a zero handshake time")
+	}
+	if handshakeCompleted("public_key=abcd\n") {
+		t.Fatal("expected false without a handshake time")
+	}
+	if !handshakeCompleted("public_key=abcd\nlast_handshake_time_sec=1700000000\n") {
+		t.Fatal("expected true with a nonzero handshake time")
+	}
+}
@@ -2,6 +2,7 @@
 package hujsonx
 
 import (
+	"bytes"
 	"encoding/json"
 
 	"github.com/tailscale/hujson"
@@ -16,3 +17,15 @@ func Unmarshal(data []byte, v any) error {
 	}
 	return json.Unmarshal(data, v)
 }
+
+// UnmarshalStrict is like [Unmarshal] except that it fails when the document
+// contains fields that do not exist in v, which most likely are typos.
+func UnmarshalStrict(data []byte, v any) error {
+	data, err := hujson.Standardize(data)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
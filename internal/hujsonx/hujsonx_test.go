@@ -30,4 +30,37 @@ func TestHuJSONXWorkingAsIntended(t *testing.T) {
 			t.Fatal("unexpected error", err)
 		}
 	})
+
+	t.Run("for valid JSON with unknown fields", func(t *testing.T) {
+		input := []byte(`{"Name": "Arturo", "Agee": 45}`)
+		var v user
+		if err := Unmarshal(input, &v); err != nil {
+			t.Fatal(err)
+		}
+		err := UnmarshalStrict(input, &v)
+		expected := `json: unknown field "Agee"`
+		if err == nil || err.Error() != expected {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("for invalid input when using UnmarshalStrict", func(t *testing.T) {
+		input := []byte("{")
+		var v user
+		err := UnmarshalStrict(input, &v)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("for valid human-readable JSON when using UnmarshalStrict", func(t *testing.T) {
+		input := []byte("{\n// the name\n\"Name\": \"Arturo\",\n\"Age\": 45,\n}")
+		var v user
+		if err := UnmarshalStrict(input, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Name != "Arturo" || v.Age != 45 {
+			t.Fatal("unexpected value", v)
+		}
+	})
 }
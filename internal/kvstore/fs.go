@@ -2,6 +2,7 @@ package kvstore
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -54,3 +55,19 @@ func (kvs *FS) Get(key string) ([]byte, error) {
 func (kvs *FS) Set(key string, value []byte) error {
 	return lockedfile.Write(kvs.filename(key), bytes.NewReader(value), 0600)
 }
+
+// Update atomically replaces the value of a specific key with the value returned
+// by fn, which receives the current value or an empty value when the key does not
+// exist. We hold a file lock while calling fn, such that several processes sharing
+// the same directory cannot overwrite each other's updates.
+func (kvs *FS) Update(key string, fn func(value []byte) ([]byte, error)) error {
+	return lockedfile.Transform(kvs.filename(key), fn)
+}
+
+// Delete removes a specific key. Deleting a key that does not exist is not an error.
+func (kvs *FS) Delete(key string) error {
+	if err := os.Remove(kvs.filename(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -65,3 +65,53 @@ func TestFileSystemWithFailure(t *testing.T) {
 		t.Fatal("expected nil here")
 	}
 }
+
+func TestFileSystemDelete(t *testing.T) {
+	kvstore, err := NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kvstore.Set("antani", []byte("mascetti")); err != nil {
+		t.Fatal(err)
+	}
+	for count := 0; count < 2; count++ {
+		if err := kvstore.Delete("antani"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := kvstore.Get("antani"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestFileSystemUpdate(t *testing.T) {
+	kvstore, err := NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendValue := func(value []byte) ([]byte, error) {
+		return append(append([]byte{}, value...), 'x'), nil
+	}
+	for count := 0; count < 2; count++ {
+		if err := kvstore.Update("antani", appendValue); err != nil {
+			t.Fatal(err)
+		}
+	}
+	value, err := kvstore.Get("antani")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "xx" {
+		t.Fatal("unexpected value", string(value))
+	}
+	expect := errors.New("mocked error")
+	err = kvstore.Update("antani", func(value []byte) ([]byte, error) {
+		return nil, expect
+	})
+	if !errors.Is(err, expect) {
+		t.Fatal("not the error we expected", err)
+	}
+	if value, _ := kvstore.Get("antani"); string(value) != "xx" {
+		t.Fatal("unexpected value", string(value))
+	}
+}
@@ -45,3 +45,28 @@ func (kvs *Memory) Set(key string, value []byte) error {
 	kvs.m[key] = value
 	return nil
 }
+
+// Update atomically replaces the value of a key with the value returned by fn,
+// which receives the current value or an empty value when the key does not exist.
+func (kvs *Memory) Update(key string, fn func(value []byte) ([]byte, error)) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	value, err := fn(kvs.m[key])
+	if err != nil {
+		return err
+	}
+	if kvs.m == nil {
+		kvs.m = make(map[string][]byte)
+	}
+	kvs.m[key] = value
+	return nil
+}
+
+// Delete removes a key from the key-value store. Deleting a key
+// that does not exist is not an error.
+func (kvs *Memory) Delete(key string) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	delete(kvs.m, key)
+	return nil
+}
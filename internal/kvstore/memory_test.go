@@ -29,3 +29,45 @@ func TestExistingKey(t *testing.T) {
 		t.Fatal("not the result we expected")
 	}
 }
+
+func TestDelete(t *testing.T) {
+	kvs := &Memory{}
+	if err := kvs.Delete("nonexistent"); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvs.Set("antani", []byte("mascetti")); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvs.Delete("antani"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kvs.Get("antani"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatal("expected an error here", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	kvs := &Memory{}
+	appendValue := func(value []byte) ([]byte, error) {
+		return append(append([]byte{}, value...), 'x'), nil
+	}
+	for count := 0; count < 2; count++ {
+		if err := kvs.Update("antani", appendValue); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := errors.New("mocked error")
+	err := kvs.Update("antani", func(value []byte) ([]byte, error) {
+		return nil, expect
+	})
+	if !errors.Is(err, expect) {
+		t.Fatal("not the error we expected", err)
+	}
+	value, err := kvs.Get("antani")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "xx" {
+		t.Fatal("not the result we expected", string(value))
+	}
+}
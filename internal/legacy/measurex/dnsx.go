@@ -10,7 +10,6 @@ import (
 	"context"
 	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -43,7 +42,7 @@ func (txp *dnsxRoundTripperDB) RoundTrip(
 	response, err := txp.DNSTransport.RoundTrip(ctx, query)
 	finished := time.Since(txp.begin).Seconds()
 	txp.db.InsertIntoDNSRoundTrip(&DNSRoundTripEvent{
-		Network:  tracex.ResolverNetworkAdaptNames(txp.DNSTransport.Network()),
+		Network:  resolverNetworkAdaptNames(txp.DNSTransport.Network()),
 		Address:  txp.DNSTransport.Address(),
 		Query:    txp.maybeQueryBytes(query),
 		Started:  started,
@@ -11,7 +11,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
@@ -107,7 +106,7 @@ func (r *resolverDB) LookupHost(ctx context.Context, domain string) ([]string, e
 func (r *resolverDB) saveLookupResults(domain string, started, finished float64,
 	err error, addrs []string, qtype string) {
 	ev := &DNSLookupEvent{
-		Network:   tracex.ResolverNetworkAdaptNames(r.Resolver.Network()),
+		Network:   resolverNetworkAdaptNames(r.Resolver.Network()),
 		Address:   r.Resolver.Address(),
 		Failure:   NewFailure(err),
 		Domain:    domain,
@@ -160,7 +159,7 @@ func (r *resolverDB) LookupHTTPS(ctx context.Context, domain string) (*model.HTT
 	https, err := r.Resolver.LookupHTTPS(ctx, domain)
 	finished := time.Since(r.begin).Seconds()
 	ev := &DNSLookupEvent{
-		Network:   tracex.ResolverNetworkAdaptNames(r.Resolver.Network()),
+		Network:   resolverNetworkAdaptNames(r.Resolver.Network()),
 		Address:   r.Resolver.Address(),
 		Domain:    domain,
 		QueryType: "HTTPS",
@@ -187,3 +186,18 @@ func (r *resolverDB) computeOddityHTTPSSvc(https *model.HTTPSSvc, err error) Odd
 	addrs = append(addrs, https.IPv6...)
 	return r.computeOddityLookupHost(addrs, nil)
 }
+
+// resolverNetworkAdaptNames makes sure we map the [netxlite.StdlibResolverGolangNetResolver] and
+// [netxlite.StdlibResolverGetaddrinfo] resolver names to [netxlite.StdlibResolverSystem]. You MUST
+// call this function when your resolver splits the "stdlib" resolver results into two fake AAAA
+// and A queries rather than faking a single ANY query.
+//
+// See https://github.com/ooni/spec/pull/257 for more information.
+func resolverNetworkAdaptNames(input string) string {
+	switch input {
+	case netxlite.StdlibResolverGetaddrinfo, netxlite.StdlibResolverGolangNetResolver:
+		return netxlite.StdlibResolverSystem
+	default:
+		return input
+	}
+}
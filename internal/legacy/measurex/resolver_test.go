@@ -58,3 +58,45 @@ func TestResolverModifiesStdlibResolverName(t *testing.T) {
 	})
 
 }
+
+func TestResolverNetworkAdaptNames(t *testing.T) {
+	type args struct {
+		input string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{{
+		name: "with StdlibResolverGetaddrinfo",
+		args: args{
+			input: netxlite.StdlibResolverGetaddrinfo,
+		},
+		want: netxlite.StdlibResolverSystem,
+	}, {
+		name: "with StdlibResolverGolangNetResolver",
+		args: args{
+			input: netxlite.StdlibResolverGolangNetResolver,
+		},
+		want: netxlite.StdlibResolverSystem,
+	}, {
+		name: "with StdlibResolverSystem",
+		args: args{
+			input: netxlite.StdlibResolverSystem,
+		},
+		want: netxlite.StdlibResolverSystem,
+	}, {
+		name: "with any other name",
+		args: args{
+			input: "doh",
+		},
+		want: "doh",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolverNetworkAdaptNames(tt.args.input); got != tt.want {
+				t.Errorf("resolverNetworkAdaptNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -103,11 +103,29 @@ func (tx *Trace) NewParallelUDPResolver(logger model.DebugLogger, dialer model.D
 	return tx.wrapResolver(tx.Netx.NewParallelUDPResolver(logger, dialer, address))
 }
 
+// NewParallelTCPResolver returns a trace-ware parallel TCP resolver
+func (tx *Trace) NewParallelTCPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
+	return tx.wrapResolver(tx.Netx.NewParallelTCPResolver(logger, dialer, address))
+}
+
+// NewParallelDNSOverTLSResolver returns a trace-aware parallel DoT resolver
+func (tx *Trace) NewParallelDNSOverTLSResolver(
+	logger model.DebugLogger, tlsDialer model.TLSDialer, address string) model.Resolver {
+	return tx.wrapResolver(tx.Netx.NewParallelDNSOverTLSResolver(logger, tlsDialer, address))
+}
+
 // NewParallelDNSOverHTTPSResolver returns a trace-aware parallel DoH resolver
 func (tx *Trace) NewParallelDNSOverHTTPSResolver(logger model.DebugLogger, URL string) model.Resolver {
 	return tx.wrapResolver(tx.Netx.NewParallelDNSOverHTTPSResolver(logger, URL))
 }
 
+// NewParallelResolver returns a trace-aware parallel resolver using the given
+// DNS transport, which allows callers to fully customize the transport (e.g., to
+// use DNS-over-HTTPS with a Host header override and a custom HTTP client).
+func (tx *Trace) NewParallelResolver(logger model.DebugLogger, txp model.DNSTransport) model.Resolver {
+	return tx.wrapResolver(netxlite.WrapResolver(logger, netxlite.NewUnwrappedParallelResolver(txp)))
+}
+
 // OnDNSRoundTripForLookupHost implements model.Trace.OnDNSRoundTripForLookupHost
 func (tx *Trace) OnDNSRoundTripForLookupHost(started time.Time, reso model.Resolver, query model.DNSQuery,
 	response model.DNSResponse, addrs []string, err error, finished time.Time) {
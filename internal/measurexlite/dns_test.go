@@ -311,6 +311,24 @@ func TestNewWrappedResolvers(t *testing.T) {
 		}
 	})
 
+	t.Run("NewParallelResolver works as intended", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		txp := netxlite.NewDNSOverHTTPSTransportWithHostOverride(
+			&mocks.HTTPClient{}, "https://8.8.8.8/dns-query", "dns.google")
+		resolver := trace.NewParallelResolver(model.DiscardLogger, txp)
+		resolvert := resolver.(*resolverTrace)
+		if resolvert.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if resolver.Network() != "doh" {
+			t.Fatal("unexpected resolver network")
+		}
+		if resolver.Address() != "https://8.8.8.8/dns-query" {
+			t.Fatal("unexpected resolver address")
+		}
+	})
+
 	t.Run("NewParallelUDPResolver works as intended", func(t *testing.T) {
 		zeroTime := time.Now()
 		trace := NewTrace(0, zeroTime)
@@ -325,6 +343,35 @@ func TestNewWrappedResolvers(t *testing.T) {
 		}
 	})
 
+	t.Run("NewParallelTCPResolver works as intended", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		dialer := netxlite.NewDialerWithStdlibResolver(model.DiscardLogger)
+		resolver := trace.NewParallelTCPResolver(model.DiscardLogger, dialer, "1.1.1.1:53")
+		resolvert := resolver.(*resolverTrace)
+		if resolvert.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if resolver.Network() != "tcp" {
+			t.Fatal("unexpected resolver network")
+		}
+	})
+
+	t.Run("NewParallelDNSOverTLSResolver works as intended", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		dialer := netxlite.NewDialerWithStdlibResolver(model.DiscardLogger)
+		tlsDialer := netxlite.NewTLSDialer(dialer, trace.NewTLSHandshakerStdlib(model.DiscardLogger))
+		resolver := trace.NewParallelDNSOverTLSResolver(model.DiscardLogger, tlsDialer, "1.1.1.1:853")
+		resolvert := resolver.(*resolverTrace)
+		if resolvert.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if resolver.Network() != "dot" {
+			t.Fatal("unexpected resolver network")
+		}
+	})
+
 	t.Run("NewStdlibResolver works as intended", func(t *testing.T) {
 		zeroTime := time.Now()
 		trace := NewTrace(0, zeroTime)
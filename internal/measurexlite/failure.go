@@ -39,3 +39,21 @@ func NewFailure(err error) *string {
 	}
 	return &s
 }
+
+// NewFailedOperation creates an OONI failed operation string from an error. If
+// the error is nil, we return nil. If the error is an ErrWrapper with a non-empty
+// Operation, we return such an operation. Otherwise, we return the string
+// representing an unknown operation (i.e., [netxlite.UnknownOperation]).
+func NewFailedOperation(err error) *string {
+	if err == nil {
+		return nil
+	}
+	var (
+		errWrapper *netxlite.ErrWrapper
+		s          = netxlite.UnknownOperation
+	)
+	if errors.As(err, &errWrapper) && errWrapper.Operation != "" {
+		s = errWrapper.Operation
+	}
+	return &s
+}
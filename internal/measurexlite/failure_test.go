@@ -80,3 +80,35 @@ func TestNewFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestNewFailedOperation(t *testing.T) {
+	t.Run("when error is nil", func(t *testing.T) {
+		if got := NewFailedOperation(nil); got != nil {
+			t.Fatal("expected nil, got", *got)
+		}
+	})
+
+	t.Run("when error is wrapped and has an operation", func(t *testing.T) {
+		got := NewFailedOperation(&netxlite.ErrWrapper{
+			Failure:   netxlite.FailureConnectionRefused,
+			Operation: netxlite.ConnectOperation,
+		})
+		if got == nil || *got != netxlite.ConnectOperation {
+			t.Fatal("unexpected result", got)
+		}
+	})
+
+	t.Run("when error is wrapped and has no operation", func(t *testing.T) {
+		got := NewFailedOperation(&netxlite.ErrWrapper{Failure: netxlite.FailureEOFError})
+		if got == nil || *got != netxlite.UnknownOperation {
+			t.Fatal("unexpected result", got)
+		}
+	})
+
+	t.Run("when error is not wrapped", func(t *testing.T) {
+		got := NewFailedOperation(io.EOF)
+		if got == nil || *got != netxlite.UnknownOperation {
+			t.Fatal("unexpected result", got)
+		}
+	})
+}
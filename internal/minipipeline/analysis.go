@@ -392,11 +392,11 @@ func (wa *WebAnalysis) dnsComputeFailureMetrics(c *WebObservationsContainer) {
 		}
 		already.Add(obs.DNSTransactionID.Unwrap())
 
-		// Implementation note: a DoH failure is not information about the URL we're
-		// measuring but about the DoH service being blocked.
+		// Implementation note: a DoH (or DoT) failure is not information about the URL
+		// we're measuring but about the DoH (or DoT) service being blocked.
 		//
 		// See https://github.com/ooni/probe/issues/2274
-		if utilsDNSEngineIsDNSOverHTTPS(obs) {
+		if utilsDNSEngineIsEncrypted(obs) {
 			continue
 		}
 
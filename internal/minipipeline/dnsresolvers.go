@@ -0,0 +1,76 @@
+package minipipeline
+
+import (
+	"sort"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+)
+
+// WebDNSResolverAnalysis contains the DNS consistency analysis of a resolver.
+type WebDNSResolverAnalysis struct {
+	// Engine is the resolver engine (e.g., "udp").
+	Engine string
+
+	// Address is the resolver address (e.g., "8.8.8.8:53").
+	Address string
+
+	// DNSTransactionIDs contains the IDs of the resolver's DNS transactions.
+	DNSTransactionIDs Set[int64]
+
+	// Consistency is "consistent" when the resolver's lookups are consistent with the
+	// control, "inconsistent" when at least one lookup is not consistent with the control,
+	// and optional.None when we cannot say anything (e.g., without control data).
+	Consistency optional.Value[string]
+}
+
+// AnalyzeDNSResolvers groups the DNS lookups performed before following redirects by
+// resolver and uses the given [*WebAnalysis] to compute the DNS consistency of each
+// resolver. This allows analyzing measurements using an arbitrary set of resolvers. The
+// returned list is sorted by engine and then by address.
+func AnalyzeDNSResolvers(
+	analysis *WebAnalysis, lookups ...*model.ArchivalDNSLookupResult) (out []*WebDNSResolverAnalysis) {
+	resolvers := map[[2]string]*WebDNSResolverAnalysis{}
+	for _, lookup := range lookups {
+		// lookups once we started following redirects do not have control information
+		if depth := utilsExtractTagDepth(lookup.Tags); depth.IsNone() || depth.Unwrap() != 0 {
+			continue
+		}
+
+		// create or fetch the resolver entry
+		key := [2]string{lookup.Engine, lookup.ResolverAddress}
+		entry, found := resolvers[key]
+		if !found {
+			entry = &WebDNSResolverAnalysis{
+				Engine:      lookup.Engine,
+				Address:     lookup.ResolverAddress,
+				Consistency: optional.None[string](),
+			}
+			resolvers[key] = entry
+			out = append(out, entry)
+		}
+		entry.DNSTransactionIDs.Add(lookup.TransactionID)
+
+		// determine whether this lookup is consistent with the control
+		txid := lookup.TransactionID
+		switch {
+		case analysis.DNSLookupSuccessWithInvalidAddresses.Contains(txid),
+			analysis.DNSLookupUnexpectedFailure.Contains(txid):
+			entry.Consistency = optional.Some("inconsistent")
+
+		case analysis.DNSLookupSuccessWithValidAddress.Contains(txid),
+			analysis.DNSLookupExpectedFailure.Contains(txid):
+			if entry.Consistency.IsNone() {
+				entry.Consistency = optional.Some("consistent")
+			}
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Engine != out[j].Engine {
+			return out[i].Engine < out[j].Engine
+		}
+		return out[i].Address < out[j].Address
+	})
+	return
+}
@@ -0,0 +1,52 @@
+package minipipeline
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestAnalyzeDNSResolvers(t *testing.T) {
+	analysis := &WebAnalysis{}
+	analysis.DNSLookupSuccessWithValidAddress.Add(1, 2)
+	analysis.DNSLookupSuccessWithInvalidAddresses.Add(3)
+	analysis.DNSLookupExpectedFailure.Add(5)
+
+	newLookup := func(txid int64, engine, address, depth string) *model.ArchivalDNSLookupResult {
+		return &model.ArchivalDNSLookupResult{
+			Engine:          engine,
+			ResolverAddress: address,
+			Tags:            []string{"depth=" + depth},
+			TransactionID:   txid,
+		}
+	}
+	lookups := []*model.ArchivalDNSLookupResult{
+		newLookup(1, "udp", "8.8.8.8:53", "0"),
+		newLookup(2, "getaddrinfo", "", "0"),
+		newLookup(3, "udp", "8.8.8.8:53", "0"),
+		newLookup(4, "dot", "1.1.1.1:853", "0"),
+		newLookup(5, "doh", "https://dns.google/dns-query", "0"),
+		newLookup(6, "tcp", "1.1.1.1:53", "1"),
+	}
+
+	type entry struct {
+		Engine      string
+		Address     string
+		TxIDs       []int64
+		Consistency string
+	}
+	var got []entry
+	for _, reso := range AnalyzeDNSResolvers(analysis, lookups...) {
+		got = append(got, entry{reso.Engine, reso.Address, reso.DNSTransactionIDs.Keys(), reso.Consistency.UnwrapOr("")})
+	}
+	expect := []entry{
+		{"doh", "https://dns.google/dns-query", []int64{5}, "consistent"},
+		{"dot", "1.1.1.1:853", []int64{4}, ""},
+		{"getaddrinfo", "", []int64{2}, "consistent"},
+		{"udp", "8.8.8.8:53", []int64{1, 3}, "inconsistent"},
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -106,8 +106,13 @@ func utilsDNSLookupFailureIsDNSNoAnswerForAAAA(obs *WebObservation) bool {
 		obs.DNSLookupFailure.UnwrapOr("") == netxlite.FailureDNSNoAnswer
 }
 
-func utilsDNSEngineIsDNSOverHTTPS(obs *WebObservation) bool {
-	return obs.DNSEngine.UnwrapOr("") == "doh"
+func utilsDNSEngineIsEncrypted(obs *WebObservation) bool {
+	switch obs.DNSEngine.UnwrapOr("") {
+	case "doh", "dot":
+		return true
+	default:
+		return false
+	}
 }
 
 // utilsTCPConnectFailureSeemsMisconfiguredIPv6 returns whether IPv6 seems to be
@@ -13,6 +13,10 @@ type MeasuringNetwork struct {
 
 	MockNewParallelUDPResolver func(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver
 
+	MockNewParallelTCPResolver func(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver
+
+	MockNewParallelDNSOverTLSResolver func(logger model.DebugLogger, tlsDialer model.TLSDialer, address string) model.Resolver
+
 	MockNewQUICDialerWithoutResolver func(listener model.UDPListener, logger model.DebugLogger, w ...model.QUICDialerWrapper) model.QUICDialer
 
 	MockNewStdlibResolver func(logger model.DebugLogger) model.Resolver
@@ -41,6 +45,16 @@ func (mn *MeasuringNetwork) NewParallelUDPResolver(logger model.DebugLogger, dia
 	return mn.MockNewParallelUDPResolver(logger, dialer, address)
 }
 
+// NewParallelTCPResolver implements model.MeasuringNetwork.
+func (mn *MeasuringNetwork) NewParallelTCPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
+	return mn.MockNewParallelTCPResolver(logger, dialer, address)
+}
+
+// NewParallelDNSOverTLSResolver implements model.MeasuringNetwork.
+func (mn *MeasuringNetwork) NewParallelDNSOverTLSResolver(logger model.DebugLogger, tlsDialer model.TLSDialer, address string) model.Resolver {
+	return mn.MockNewParallelDNSOverTLSResolver(logger, tlsDialer, address)
+}
+
 // NewQUICDialerWithoutResolver implements model.MeasuringNetwork.
 func (mn *MeasuringNetwork) NewQUICDialerWithoutResolver(listener model.UDPListener, logger model.DebugLogger, w ...model.QUICDialerWrapper) model.QUICDialer {
 	return mn.MockNewQUICDialerWithoutResolver(listener, logger, w...)
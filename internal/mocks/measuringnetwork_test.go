@@ -47,6 +47,32 @@ func TestMeasuringN(t *testing.T) {
 		}
 	})
 
+	t.Run("MockNewParallelTCPResolver", func(t *testing.T) {
+		expected := &Resolver{}
+		mn := &MeasuringNetwork{
+			MockNewParallelTCPResolver: func(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
+				return expected
+			},
+		}
+		got := mn.NewParallelTCPResolver(nil, nil, "")
+		if expected != got {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("MockNewParallelDNSOverTLSResolver", func(t *testing.T) {
+		expected := &Resolver{}
+		mn := &MeasuringNetwork{
+			MockNewParallelDNSOverTLSResolver: func(logger model.DebugLogger, tlsDialer model.TLSDialer, address string) model.Resolver {
+				return expected
+			},
+		}
+		got := mn.NewParallelDNSOverTLSResolver(nil, nil, "")
+		if expected != got {
+			t.Fatal("unexpected result")
+		}
+	})
+
 	t.Run("MockNewQUICDialerWithoutResolver", func(t *testing.T) {
 		expected := &QUICDialer{}
 		mn := &MeasuringNetwork{
@@ -422,3 +422,22 @@ type ArchivalOpenVPNOptions struct {
 	Cipher      string `json:"cipher,omitempty"`
 	Compression string `json:"compression,omitempty"`
 }
+
+//
+// WireGuard
+//
+
+// ArchivalWireGuardHandshakeResult contains the result of a WireGuard handshake.
+type ArchivalWireGuardHandshakeResult struct {
+	Endpoint      string   `json:"endpoint"`
+	Failure       *string  `json:"failure"`
+	HandshakeTime float64  `json:"handshake_time,omitempty"`
+	IP            string   `json:"ip"`
+	Port          int      `json:"port"`
+	Provider      string   `json:"provider"`
+	T0            float64  `json:"t0"`
+	T             float64  `json:"t"`
+	Tags          []string `json:"tags"`
+	TransactionID int64    `json:"transaction_id,omitempty"`
+	Transport     string   `json:"transport"`
+}
@@ -32,12 +32,18 @@ type ExperimentSession interface {
 	// FetchTorTargets returns the targets for the Tor experiment or an error.
 	FetchTorTargets(ctx context.Context, cc string) (map[string]OOAPITorTarget, error)
 
+	// KeyValueStore returns the session's key-value store.
+	KeyValueStore() KeyValueStore
+
 	// Logger returns the logger used by the session.
 	Logger() Logger
 
 	// ProbeCC returns the country code.
 	ProbeCC() string
 
+	// ProbeIP returns the probe IP address.
+	ProbeIP() string
+
 	// ResolverIP returns the resolver's IP.
 	ResolverIP() string
 
@@ -289,6 +295,65 @@ type ExperimentTargetLoaderConfig struct {
 	// per line. We will fail if any file is unreadable
 	// as well as if any file is empty.
 	SourceFiles []string
+
+	// TargetFilter contains OPTIONAL rules to filter and sample
+	// the loaded targets (see [ExperimentTargetFilter]).
+	TargetFilter *ExperimentTargetFilter
+
+	// TestLists contains OPTIONAL test lists to read targets from,
+	// using either the citizenlab/test-lists CSV format or a JSON
+	// array of [OOAPIURLInfo]. Unlike SourceFiles, test lists carry
+	// the category code and the country code of each target.
+	TestLists []string
+}
+
+// These are the sampling strategies an [ExperimentTargetFilter] supports.
+const (
+	// TargetSampleRandom selects SampleSize targets at random.
+	TargetSampleRandom = "random"
+
+	// TargetSampleStratified selects SampleSize targets at random
+	// taking the same number of targets from each category.
+	TargetSampleStratified = "stratified"
+)
+
+// ExperimentTargetFilter filters and samples the targets loaded by an
+// [ExperimentTargetLoader]. The zero value keeps all the targets.
+type ExperimentTargetFilter struct {
+	// CategoryCodes OPTIONALLY contains the category codes of the
+	// targets to keep. When empty, we keep all the categories.
+	CategoryCodes []string `json:"category_codes,omitempty"`
+
+	// ExcludeCategoryCodes OPTIONALLY contains the category codes
+	// of the targets to discard.
+	ExcludeCategoryCodes []string `json:"exclude_category_codes,omitempty"`
+
+	// ExcludePatterns OPTIONALLY contains regular expressions such that
+	// we discard the targets whose input matches any of them.
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"`
+
+	// IncludePatterns OPTIONALLY contains regular expressions such that
+	// we only keep the targets whose input matches any of them.
+	IncludePatterns []string `json:"include_patterns,omitempty"`
+
+	// SampleSize is the OPTIONAL maximum number of targets to keep after
+	// filtering. When zero or negative, we keep all the targets.
+	SampleSize int `json:"sample_size,omitempty"`
+
+	// SampleStrategy is the OPTIONAL sampling strategy, which is either
+	// [TargetSampleRandom] or [TargetSampleStratified]. When empty, we
+	// use [TargetSampleRandom].
+	SampleStrategy string `json:"sample_strategy,omitempty"`
+
+	// Seed is the OPTIONAL seed for sampling. When zero, we use the current
+	// time, such that each run samples different targets.
+	Seed int64 `json:"seed,omitempty"`
+}
+
+// Empty returns whether the filter keeps all the targets.
+func (f *ExperimentTargetFilter) Empty() bool {
+	return len(f.CategoryCodes) <= 0 && len(f.ExcludeCategoryCodes) <= 0 &&
+		len(f.ExcludePatterns) <= 0 && len(f.IncludePatterns) <= 0 && f.SampleSize <= 0
 }
 
 // ExperimentTargetLoaderSession is the session according to [ExperimentTargetLoader].
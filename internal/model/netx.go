@@ -211,6 +211,18 @@ type MeasuringNetwork interface {
 	// The address argument is the UDP endpoint address (e.g., 1.1.1.1:53, [::1]:53).
 	NewParallelUDPResolver(logger DebugLogger, dialer Dialer, address string) Resolver
 
+	// NewParallelTCPResolver creates a new Resolver using DNS-over-TCP
+	// that performs parallel A/AAAA lookups during LookupHost.
+	//
+	// The address argument is the TCP endpoint address (e.g., 1.1.1.1:53, [::1]:53).
+	NewParallelTCPResolver(logger DebugLogger, dialer Dialer, address string) Resolver
+
+	// NewParallelDNSOverTLSResolver creates a new Resolver using DNS-over-TLS
+	// that performs parallel A/AAAA lookups during LookupHost.
+	//
+	// The address argument is the TCP endpoint address (e.g., 1.1.1.1:853, [::1]:853).
+	NewParallelDNSOverTLSResolver(logger DebugLogger, tlsDialer TLSDialer, address string) Resolver
+
 	// NewQUICDialerWithoutResolver creates a [QUICDialer] with error wrapping and without an attached
 	// resolver, meaning that you MUST pass UDP endpoint addresses to this dialer.
 	//
@@ -6,7 +6,10 @@ package model
 // See https://api.ooni.io/apidocs/.
 //
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // OOAPICheckInConfigWebConnectivity is the WebConnectivity
 // portion of OOAPICheckInConfig.
@@ -85,9 +88,17 @@ type OOAPICheckInResult struct {
 
 // OOAPICheckInResultConfig contains configuration.
 type OOAPICheckInResultConfig struct {
+	// BlockpageFingerprints OPTIONALLY contains a versioned database of
+	// blockpage fingerprints (see the internal/blockpages package).
+	BlockpageFingerprints json.RawMessage `json:"blockpage_fingerprints,omitempty"`
+
 	// Features contains feature flags.
 	Features map[string]bool `json:"features"`
 
+	// ServiceDefinitions OPTIONALLY contains a list of versioned service
+	// definitions (see the internal/servicedefs package).
+	ServiceDefinitions json.RawMessage `json:"service_definitions,omitempty"`
+
 	// TestHelpers contains test-helpers information.
 	TestHelpers map[string][]OOAPIService `json:"test_helpers"`
 }
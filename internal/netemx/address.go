@@ -89,3 +89,23 @@ const AddressOpenDNS220 = "208.67.220.220"
 
 // AddressNextDNSIo is a dns.nextdns.io address.
 const AddressNextDNSIo = "38.175.119.129"
+
+// AddressMLabSpeedTest is the address of the M-Lab-compatible speed test server
+// that we export as mlab-speedtest.org and we use for running ndt7 and DASH.
+const AddressMLabSpeedTest = "4.71.254.147"
+
+// AddressMailExampleCom is the address of the mail server that we export as
+// mail.example.com and we use for measuring SMTP, IMAP, and POP3.
+const AddressMailExampleCom = "93.184.216.40"
+
+// AddressTorBridge is the address of the host emulating Tor bridges that we export
+// as front.example.com, broker.example.com, meek.example.com, and webtunnel.example.com.
+const AddressTorBridge = "192.95.36.142"
+
+// AddressTimeCloudflareCom is the address of the time server that we export
+// as time.cloudflare.com and we use for measuring NTP and NTS.
+const AddressTimeCloudflareCom = "162.159.200.1"
+
+// AddressTimeGoogleCom is the address of the time server that we export
+// as time.google.com and we use for measuring NTP.
+const AddressTimeGoogleCom = "216.239.35.0"
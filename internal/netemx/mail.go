@@ -0,0 +1,266 @@
+package netemx
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// MailServerFactory is a [NetStackServerFactory] for a mail server speaking SMTP
+// on ports 25, 465 and 587, IMAP on ports 143 and 993, and POP3 on ports 110 and
+// 995. The server supports STARTTLS on ports 25, 587, 143, and 110 and uses
+// implicit TLS on ports 465, 993, and 995.
+//
+// The zero value is invalid; please, fill all the MANDATORY fields.
+type MailServerFactory struct {
+	// MaskSTARTTLS OPTIONALLY emulates a middlebox stripping STARTTLS. For SMTP, we
+	// replace the STARTTLS capability with XXXXXXXA, as some firewalls do, while for
+	// IMAP and POP3 we do not advertise the capability. In all cases, we refuse
+	// STARTTLS commands as if the middlebox were rewriting them.
+	MaskSTARTTLS bool
+
+	// ServerNameMain is the MANDATORY server name to use as common name for X.509 certs.
+	ServerNameMain string
+
+	// ServerNameExtras contains OPTIONAL extra names to also configure into the cert.
+	ServerNameExtras []string
+}
+
+var _ NetStackServerFactory = &MailServerFactory{}
+
+// MustNewServer implements NetStackServerFactory.
+func (f *MailServerFactory) MustNewServer(env NetStackServerFactoryEnv, stack *netem.UNetStack) NetStackServer {
+	return &mailServer{
+		closers:   []io.Closer{},
+		factory:   f,
+		logger:    env.Logger(),
+		mu:        sync.Mutex{},
+		tlsConfig: stack.MustNewServerTLSConfig(f.ServerNameMain, f.ServerNameExtras...),
+		unet:      stack,
+	}
+}
+
+// mailProtocol describes the protocol spoken on a given port.
+type mailProtocol struct {
+	name        string
+	implicitTLS bool
+}
+
+// mailPorts maps each port to the protocol we speak on it.
+var mailPorts = map[uint16]mailProtocol{
+	25:  {"smtp", false},
+	110: {"pop3", false},
+	143: {"imap", false},
+	465: {"smtp", true},
+	587: {"smtp", false},
+	993: {"imap", true},
+	995: {"pop3", true},
+}
+
+type mailServer struct {
+	closers   []io.Closer
+	factory   *MailServerFactory
+	logger    model.Logger
+	mu        sync.Mutex
+	tlsConfig *tls.Config
+	unet      *netem.UNetStack
+}
+
+// Close implements NetStackServer.
+func (srv *mailServer) Close() error {
+	// "this method MUST be CONCURRENCY SAFE"
+	defer srv.mu.Unlock()
+	srv.mu.Lock()
+
+	// make sure we close all the child listeners
+	for _, closer := range srv.closers {
+		_ = closer.Close()
+	}
+
+	// "this method MUST be IDEMPOTENT"
+	srv.closers = []io.Closer{}
+
+	return nil
+}
+
+// MustStart implements NetStackServer.
+func (srv *mailServer) MustStart() {
+	// "this method MUST be CONCURRENCY SAFE"
+	defer srv.mu.Unlock()
+	srv.mu.Lock()
+
+	// for each port of interest - note that here we panic liberally because we are
+	// allowed to do so by the [NetStackServer] documentation.
+	for port, protocol := range mailPorts {
+		// create the endpoint address
+		ipAddr := net.ParseIP(srv.unet.IPAddress())
+		runtimex.Assert(ipAddr != nil, "invalid IP address")
+		epnt := &net.TCPAddr{IP: ipAddr, Port: int(port)}
+
+		// attempt to listen
+		listener := runtimex.Try1(srv.unet.ListenTCP("tcp", epnt))
+
+		// spawn goroutine for accepting
+		go srv.acceptLoop(listener, protocol)
+
+		// track this listener as something to close later
+		srv.closers = append(srv.closers, listener)
+	}
+}
+
+func (srv *mailServer) acceptLoop(listener net.Listener, protocol mailProtocol) {
+	// Implementation note: because this function is only used for writing QA tests, it is
+	// fine that we are using runtimex.Try1 and ignoring any panic.
+	defer runtimex.CatchLogAndIgnorePanic(srv.logger, "mailServer.acceptLoop")
+	for {
+		conn := runtimex.Try1(listener.Accept())
+		go srv.serve(conn, protocol)
+	}
+}
+
+// mailSession is a session with a mail client.
+type mailSession struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	tls    bool
+}
+
+// upgrade performs the TLS handshake and replaces the underlying conn.
+func (s *mailSession) upgrade(config *tls.Config) {
+	tlsConn := tls.Server(s.conn, config)
+	runtimex.Try0(tlsConn.Handshake())
+	s.conn, s.reader, s.tls = tlsConn, bufio.NewReader(tlsConn), true
+}
+
+// readLine reads the next line sent by the client.
+func (s *mailSession) readLine() string {
+	line := runtimex.Try1(s.reader.ReadString('\n'))
+	return strings.TrimRight(line, "\r\n")
+}
+
+// writeLines sends the given lines to the client.
+func (s *mailSession) writeLines(lines ...string) {
+	for _, line := range lines {
+		_ = runtimex.Try1(fmt.Fprintf(s.conn, "%s\r\n", line))
+	}
+}
+
+func (srv *mailServer) serve(conn net.Conn, protocol mailProtocol) {
+	// Implementation note: because this function is only used for writing QA tests, it is
+	// fine that we are using runtimex.Try1 and ignoring any panic.
+	defer runtimex.CatchLogAndIgnorePanic(srv.logger, "mailServer.serve")
+
+	// make sure we close the conn
+	defer conn.Close()
+
+	// make sure we do not keep idle clients around forever
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	session := &mailSession{conn: conn, reader: bufio.NewReader(conn)}
+	if protocol.implicitTLS {
+		session.upgrade(srv.tlsConfig)
+	}
+	switch protocol.name {
+	case "smtp":
+		srv.serveSMTP(session)
+	case "imap":
+		srv.serveIMAP(session)
+	case "pop3":
+		srv.servePOP3(session)
+	}
+}
+
+// canStartTLS returns whether the client can use STARTTLS in the session.
+func (srv *mailServer) canStartTLS(session *mailSession) bool {
+	return !session.tls && !srv.factory.MaskSTARTTLS
+}
+
+func (srv *mailServer) serveSMTP(session *mailSession) {
+	session.writeLines(fmt.Sprintf("220 %s ESMTP netemx", srv.factory.ServerNameMain))
+	for {
+		line := session.readLine()
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case command == "EHLO":
+			starttls := "250-STARTTLS"
+			switch {
+			case session.tls:
+				starttls = "250-AUTH PLAIN"
+			case srv.factory.MaskSTARTTLS:
+				starttls = "250-XXXXXXXA"
+			}
+			session.writeLines(fmt.Sprintf("250-%s", srv.factory.ServerNameMain),
+				"250-PIPELINING", starttls, "250 8BITMIME")
+		case command == "STARTTLS" && srv.canStartTLS(session):
+			session.writeLines("220 2.0.0 Ready to start TLS")
+			session.upgrade(srv.tlsConfig)
+		case command == "QUIT":
+			session.writeLines("221 2.0.0 Bye")
+			return
+		default:
+			session.writeLines("502 5.5.2 Error: command not recognized")
+		}
+	}
+}
+
+func (srv *mailServer) serveIMAP(session *mailSession) {
+	session.writeLines("* OK netemx IMAP4rev1 ready")
+	for {
+		line := session.readLine()
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			session.writeLines("* BAD missing tag or command")
+			continue
+		}
+		tag, command := fields[0], strings.ToUpper(fields[1])
+		switch {
+		case command == "CAPABILITY":
+			capabilities := "* CAPABILITY IMAP4rev1 LOGINDISABLED"
+			if srv.canStartTLS(session) {
+				capabilities += " STARTTLS"
+			}
+			session.writeLines(capabilities, fmt.Sprintf("%s OK CAPABILITY completed", tag))
+		case command == "STARTTLS" && srv.canStartTLS(session):
+			session.writeLines(fmt.Sprintf("%s OK Begin TLS negotiation now", tag))
+			session.upgrade(srv.tlsConfig)
+		case command == "LOGOUT":
+			session.writeLines("* BYE netemx IMAP4rev1 logging out", fmt.Sprintf("%s OK LOGOUT completed", tag))
+			return
+		default:
+			session.writeLines(fmt.Sprintf("%s BAD unknown command", tag))
+		}
+	}
+}
+
+func (srv *mailServer) servePOP3(session *mailSession) {
+	session.writeLines("+OK netemx POP3 ready")
+	for {
+		line := session.readLine()
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case command == "CAPA":
+			lines := []string{"+OK Capability list follows", "USER"}
+			if srv.canStartTLS(session) {
+				lines = append(lines, "STLS")
+			}
+			session.writeLines(append(lines, ".")...)
+		case command == "STLS" && srv.canStartTLS(session):
+			session.writeLines("+OK Begin TLS negotiation")
+			session.upgrade(srv.tlsConfig)
+		case command == "QUIT":
+			session.writeLines("+OK Bye")
+			return
+		default:
+			session.writeLines("-ERR unknown command")
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package netemx
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// mailTestClient is a minimal line-oriented client for testing [MailServerFactory].
+type mailTestClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	t      *testing.T
+}
+
+func newMailTestClient(t *testing.T, port int, implicitTLS bool) *mailTestClient {
+	netx := &netxlite.Netx{}
+	dialer := netx.NewDialerWithoutResolver(log.Log)
+	endpoint := net.JoinHostPort(AddressMailExampleCom, fmt.Sprintf("%d", port))
+	conn, err := dialer.DialContext(context.Background(), "tcp", endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &mailTestClient{conn: conn, reader: bufio.NewReader(conn), t: t}
+	if implicitTLS {
+		client.upgrade()
+	}
+	return client
+}
+
+func (c *mailTestClient) upgrade() {
+	netx := &netxlite.Netx{}
+	handshaker := netx.NewTLSHandshakerStdlib(log.Log)
+	tlsConn, err := handshaker.Handshake(context.Background(), c.conn, &tls.Config{ServerName: "mail.example.com"})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.conn, c.reader = tlsConn, bufio.NewReader(tlsConn)
+}
+
+func (c *mailTestClient) readLine() string {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+// exchange sends the given command and reads lines until stop returns true.
+func (c *mailTestClient) exchange(command string, stop func(line string) bool) (lines []string) {
+	if _, err := fmt.Fprintf(c.conn, "%s\r\n", command); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		line := c.readLine()
+		lines = append(lines, line)
+		if stop(line) {
+			return
+		}
+	}
+}
+
+func smtpLastLine(line string) bool {
+	return len(line) < 4 || line[3] != '-'
+}
+
+func TestMailServer(t *testing.T) {
+	t.Run("SMTP with STARTTLS", func(t *testing.T) {
+		env := MustNewScenario(InternetScenario)
+		defer env.Close()
+
+		env.Do(func() {
+			client := newMailTestClient(t, 587, false)
+			defer client.conn.Close()
+			if banner := client.readLine(); !strings.HasPrefix(banner, "220 ") {
+				t.Fatal("unexpected banner", banner)
+			}
+			lines := client.exchange("EHLO localhost", smtpLastLine)
+			if !strings.Contains(strings.Join(lines, "\n"), "250-STARTTLS") {
+				t.Fatal("expected STARTTLS capability", lines)
+			}
+			if lines := client.exchange("STARTTLS", smtpLastLine); !strings.HasPrefix(lines[0], "220 ") {
+				t.Fatal("unexpected STARTTLS response", lines)
+			}
+			client.upgrade()
+			lines = client.exchange("EHLO localhost", smtpLastLine)
+			if strings.Contains(strings.Join(lines, "\n"), "STARTTLS") {
+				t.Fatal("did not expect STARTTLS capability after upgrade", lines)
+			}
+			if lines := client.exchange("QUIT", smtpLastLine); !strings.HasPrefix(lines[0], "221 ") {
+				t.Fatal("unexpected QUIT response", lines)
+			}
+		})
+	})
+
+	t.Run("IMAP with implicit TLS", func(t *testing.T) {
+		env := MustNewScenario(InternetScenario)
+		defer env.Close()
+
+		env.Do(func() {
+			client := newMailTestClient(t, 993, true)
+			defer client.conn.Close()
+			if banner := client.readLine(); !strings.HasPrefix(banner, "* OK") {
+				t.Fatal("unexpected banner", banner)
+			}
+			lines := client.exchange("a001 CAPABILITY", func(line string) bool {
+				return strings.HasPrefix(line, "a001 ")
+			})
+			if len(lines) != 2 || strings.Contains(lines[0], "STARTTLS") || lines[1] != "a001 OK CAPABILITY completed" {
+				t.Fatal("unexpected CAPABILITY response", lines)
+			}
+		})
+	})
+
+	t.Run("POP3 with STLS", func(t *testing.T) {
+		env := MustNewScenario(InternetScenario)
+		defer env.Close()
+
+		env.Do(func() {
+			client := newMailTestClient(t, 110, false)
+			defer client.conn.Close()
+			if banner := client.readLine(); !strings.HasPrefix(banner, "+OK") {
+				t.Fatal("unexpected banner", banner)
+			}
+			lines := client.exchange("CAPA", func(line string) bool {
+				return line == "."
+			})
+			if !strings.Contains(strings.Join(lines, "\n"), "STLS") {
+				t.Fatal("expected STLS capability", lines)
+			}
+			if lines := client.exchange("STLS", smtpLastLine); !strings.HasPrefix(lines[0], "+OK") {
+				t.Fatal("unexpected STLS response", lines)
+			}
+			client.upgrade()
+		})
+	})
+
+	t.Run("with MaskSTARTTLS", func(t *testing.T) {
+		env := MustNewQAEnv(QAEnvOptionNetStack(AddressMailExampleCom, &MailServerFactory{
+			MaskSTARTTLS:   true,
+			ServerNameMain: "mail.example.com",
+		}))
+		defer env.Close()
+
+		env.Do(func() {
+			client := newMailTestClient(t, 25, false)
+			defer client.conn.Close()
+			_ = client.readLine()
+			lines := client.exchange("EHLO localhost", smtpLastLine)
+			if !strings.Contains(strings.Join(lines, "\n"), "250-XXXXXXXA") {
+				t.Fatal("expected masked STARTTLS capability", lines)
+			}
+			if lines := client.exchange("STARTTLS", smtpLastLine); !strings.HasPrefix(lines[0], "502 ") {
+				t.Fatal("unexpected STARTTLS response", lines)
+			}
+		})
+	})
+}
@@ -0,0 +1,286 @@
+package netemx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// MLabHandlerFactory returns an [HTTPHandlerFactory] for constructing an [MLabHandler].
+func MLabHandlerFactory() HTTPHandlerFactory {
+	return HTTPHandlerFactoryFunc(func(env NetStackServerFactoryEnv, stack *netem.UNetStack) http.Handler {
+		return &MLabHandler{}
+	})
+}
+
+// MLabHandler is an [http.Handler] implementing a minimal M-Lab-compatible speed
+// test server that allows us to run ndt7 and DASH inside a [*QAEnv].
+//
+// We currently implement the following API endpoints:
+//
+//	/ndt/v7/download
+//		Upgrades to WebSocket and sends binary messages to the client along with
+//		periodic server-side measurements as text messages.
+//
+//	/ndt/v7/upload
+//		Upgrades to WebSocket and reads binary messages from the client while
+//		sending periodic server-side measurements as text messages.
+//
+//	/negotiate/dash
+//		Authorizes the client to run a DASH test.
+//
+//	/dash/download/<size>
+//		Returns a DASH segment containing <size> bytes.
+//
+//	/collect/dash
+//		Receives the client results and returns the (empty) server results.
+//
+// Any other request URL causes a 404 response.
+//
+// The zero value is ready to use.
+type MLabHandler struct {
+	// MaxRuntime is the OPTIONAL maximum runtime of ndt7 subtests. If this field
+	// is zero, we use a default of ten seconds, like M-Lab servers.
+	MaxRuntime time.Duration
+}
+
+var _ http.Handler = &MLabHandler{}
+
+const (
+	// mlabNDT7Subprotocol is the ndt7 WebSocket subprotocol.
+	mlabNDT7Subprotocol = "net.measurementlab.ndt.v7"
+
+	// mlabNDT7MessageSize is the size of the messages we send during the download.
+	mlabNDT7MessageSize = 1 << 13
+
+	// mlabNDT7MeasureInterval is the interval between server-side measurements.
+	mlabNDT7MeasureInterval = 250 * time.Millisecond
+
+	// mlabDASHMaxSegmentSize is the maximum size of a DASH segment.
+	mlabDASHMaxSegmentSize = 1 << 24
+
+	// mlabDASHDownloadPath is the URL path prefix for downloading DASH segments.
+	mlabDASHDownloadPath = "/dash/download/"
+
+	// mlabDASHAuthorization is the token with which we authorize all clients.
+	mlabDASHAuthorization = "deadbeefdeadbeefdeadbeefdeadbeef"
+)
+
+// ServeHTTP implements [http.Handler].
+func (h *MLabHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/ndt/v7/download" && r.Method == http.MethodGet:
+		h.ndt7(w, r, h.ndt7Download)
+
+	case r.URL.Path == "/ndt/v7/upload" && r.Method == http.MethodGet:
+		h.ndt7(w, r, h.ndt7Upload)
+
+	case r.URL.Path == "/negotiate/dash" && r.Method == http.MethodPost:
+		h.dashNegotiate(w, r)
+
+	case strings.HasPrefix(r.URL.Path, mlabDASHDownloadPath) && r.Method == http.MethodGet:
+		h.dashDownload(w, r)
+
+	case r.URL.Path == "/collect/dash" && r.Method == http.MethodPost:
+		h.dashCollect(w, r)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (h *MLabHandler) maxRuntime() time.Duration {
+	if h.MaxRuntime > 0 {
+		return h.MaxRuntime
+	}
+	return 10 * time.Second
+}
+
+// ndt7 upgrades the connection to WebSocket and invokes the given subtest.
+func (h *MLabHandler) ndt7(w http.ResponseWriter, r *http.Request,
+	subtest func(conn *websocket.Conn, deadline time.Time)) {
+	if r.Header.Get("Sec-WebSocket-Protocol") != mlabNDT7Subprotocol {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1 << 17,
+		WriteBufferSize: 1 << 17,
+	}
+	headers := http.Header{}
+	headers.Add("Sec-WebSocket-Protocol", mlabNDT7Subprotocol)
+	conn, err := upgrader.Upgrade(w, r, headers)
+	if err != nil {
+		return // the upgrader has already written a response
+	}
+	defer conn.Close()
+	// Implementation note: the subtests stop at the deadline and we use a slightly
+	// longer I/O deadline to avoid breaking the connection before the closing handshake.
+	deadline := time.Now().Add(h.maxRuntime())
+	_ = conn.SetReadDeadline(deadline)
+	_ = conn.SetWriteDeadline(deadline.Add(time.Second))
+	subtest(conn, deadline)
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+}
+
+// mlabNDT7Measurement is the subset of the ndt7 measurement message that we send.
+type mlabNDT7Measurement struct {
+	AppInfo struct {
+		ElapsedTime int64
+		NumBytes    int64
+	}
+	ConnectionInfo struct {
+		Client string
+		Server string
+	}
+	Origin  string
+	Test    string
+	TCPInfo struct {
+		BytesAcked    int64
+		BytesReceived int64
+		BytesSent     int64
+		ElapsedTime   int64
+	}
+}
+
+// newNDT7Measurement creates a new server-side measurement message.
+func (h *MLabHandler) newNDT7Measurement(conn *websocket.Conn, test string,
+	elapsed time.Duration, sent int64, received int64) *websocket.PreparedMessage {
+	m := &mlabNDT7Measurement{}
+	m.AppInfo.ElapsedTime = int64(elapsed / time.Microsecond)
+	m.AppInfo.NumBytes = sent + received
+	m.ConnectionInfo.Client = conn.RemoteAddr().String()
+	m.ConnectionInfo.Server = conn.LocalAddr().String()
+	m.Origin = "server"
+	m.Test = test
+	m.TCPInfo.BytesAcked = sent
+	m.TCPInfo.BytesReceived = received
+	m.TCPInfo.BytesSent = sent
+	m.TCPInfo.ElapsedTime = m.AppInfo.ElapsedTime
+	data := runtimex.Try1(json.Marshal(m))
+	return runtimex.Try1(websocket.NewPreparedMessage(websocket.TextMessage, data))
+}
+
+// ndt7Download sends data to the client until the deadline expires or the client goes away.
+func (h *MLabHandler) ndt7Download(conn *websocket.Conn, deadline time.Time) {
+	message := runtimex.Try1(websocket.NewPreparedMessage(websocket.BinaryMessage, make([]byte, mlabNDT7MessageSize)))
+	go h.ndt7DiscardIncomingMessages(conn)
+	ticker := time.NewTicker(mlabNDT7MeasureInterval)
+	defer ticker.Stop()
+	var total int64
+	start := time.Now()
+	for time.Now().Before(deadline) {
+		if err := conn.WritePreparedMessage(message); err != nil {
+			return
+		}
+		total += mlabNDT7MessageSize
+		select {
+		case <-ticker.C:
+			measurement := h.newNDT7Measurement(conn, "download", time.Since(start), total, 0)
+			if err := conn.WritePreparedMessage(measurement); err != nil {
+				return
+			}
+		default:
+			// nothing
+		}
+	}
+}
+
+// ndt7DiscardIncomingMessages reads and discards the messages sent by the client.
+func (h *MLabHandler) ndt7DiscardIncomingMessages(conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+// ndt7Upload reads data from the client until the deadline expires or the client goes away.
+func (h *MLabHandler) ndt7Upload(conn *websocket.Conn, deadline time.Time) {
+	conn.SetReadLimit(1 << 24)
+	ticker := time.NewTicker(mlabNDT7MeasureInterval)
+	defer ticker.Stop()
+	var total int64
+	start := time.Now()
+	for time.Now().Before(deadline) {
+		_, reader, err := conn.NextReader()
+		if err != nil {
+			return
+		}
+		count, err := io.Copy(io.Discard, reader)
+		if err != nil {
+			return
+		}
+		total += count
+		select {
+		case <-ticker.C:
+			measurement := h.newNDT7Measurement(conn, "upload", time.Since(start), 0, total)
+			if err := conn.WritePreparedMessage(measurement); err != nil {
+				return
+			}
+		default:
+			// nothing
+		}
+	}
+}
+
+// dashNegotiate authorizes the client to run a DASH test.
+func (h *MLabHandler) dashNegotiate(w http.ResponseWriter, r *http.Request) {
+	address, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	resp := map[string]any{
+		"authorization": mlabDASHAuthorization,
+		"queue_pos":     0,
+		"real_address":  address,
+		"unchoked":      1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(runtimex.Try1(json.Marshal(resp)))
+}
+
+// dashDownload sends a DASH segment to an authorized client.
+func (h *MLabHandler) dashDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != mlabDASHAuthorization {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	size, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, mlabDASHDownloadPath))
+	if err != nil || size < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if size > mlabDASHMaxSegmentSize {
+		size = mlabDASHMaxSegmentSize
+	}
+	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", size))
+	_, _ = w.Write(make([]byte, size))
+}
+
+// dashCollect receives the client results and returns the server results.
+func (h *MLabHandler) dashCollect(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != mlabDASHAuthorization {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var results []map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&results); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte("[]"))
+}
@@ -0,0 +1,168 @@
+package netemx
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestMLabHandler(t *testing.T) {
+	srv := httptest.NewServer(&MLabHandler{MaxRuntime: 500 * time.Millisecond})
+	defer srv.Close()
+	wsURL := strings.Replace(srv.URL, "http://", "ws://", 1)
+
+	dialNDT7 := func(t *testing.T, path string) *websocket.Conn {
+		headers := http.Header{}
+		headers.Add("Sec-WebSocket-Protocol", mlabNDT7Subprotocol)
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+path, headers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+
+	t.Run("/ndt/v7/download", func(t *testing.T) {
+		conn := dialNDT7(t, "/ndt/v7/download")
+		defer conn.Close()
+		var binary, text int
+		for {
+			kind, _, err := conn.ReadMessage()
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch kind {
+			case websocket.BinaryMessage:
+				binary++
+			case websocket.TextMessage:
+				text++
+			}
+		}
+		if binary <= 0 || text <= 0 {
+			t.Fatal("expected binary and text messages", binary, text)
+		}
+	})
+
+	t.Run("/ndt/v7/upload", func(t *testing.T) {
+		conn := dialNDT7(t, "/ndt/v7/upload")
+		defer conn.Close()
+		texts := make(chan int)
+		go func() {
+			var count int
+			for {
+				kind, _, err := conn.ReadMessage()
+				if err != nil {
+					texts <- count
+					return
+				}
+				if kind == websocket.TextMessage {
+					count++
+				}
+			}
+		}()
+		message := make([]byte, 1<<10)
+		for conn.WriteMessage(websocket.BinaryMessage, message) == nil {
+			// continue until the server closes the connection
+		}
+		if count := <-texts; count <= 0 {
+			t.Fatal("expected server measurements")
+		}
+	})
+
+	t.Run("ndt7 without the subprotocol", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/ndt/v7/download")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+	})
+
+	t.Run("DASH", func(t *testing.T) {
+		resp, err := http.Post(srv.URL+"/negotiate/dash", "application/json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+
+		expect := map[string]int{
+			"/dash/download/1024":     http.StatusOK,
+			"/dash/download/antani":   http.StatusBadRequest,
+			"/dash/download/-1":       http.StatusBadRequest,
+			"/dash/download/33554432": http.StatusOK,
+		}
+		for path, status := range expect {
+			req, _ := http.NewRequest("GET", srv.URL+path, nil)
+			req.Header.Set("Authorization", mlabDASHAuthorization)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != status {
+				t.Fatal(path, "unexpected status code", resp.StatusCode)
+			}
+			if path == "/dash/download/33554432" && len(data) != mlabDASHMaxSegmentSize {
+				t.Fatal("the segment size should have been capped", len(data))
+			}
+		}
+
+		req, _ := http.NewRequest("POST", srv.URL+"/collect/dash", bytes.NewReader([]byte("[{}]")))
+		req.Header.Set("Authorization", mlabDASHAuthorization)
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(data) != "[]" {
+			t.Fatal("unexpected collect response", resp.StatusCode, string(data))
+		}
+	})
+
+	t.Run("DASH without authorization", func(t *testing.T) {
+		for _, path := range []string{"/dash/download/1024", "/collect/dash"} {
+			method := "GET"
+			if path == "/collect/dash" {
+				method = "POST"
+			}
+			req, _ := http.NewRequest(method, srv.URL+path, http.NoBody)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Fatal(path, "unexpected status code", resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("unknown path", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/antani")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package netemx
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/ntpx"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// NTPServerFactory is a [NetStackServerFactory] for a stratum 1 time server speaking
+// NTPv4 on UDP port 123 and NTS key establishment on TCP port 4460. The NTPv4 server
+// authenticates the requests and responses using NTS when the client includes the
+// cookies obtained through the NTS key establishment.
+//
+// The zero value is invalid; please, fill all the MANDATORY fields.
+type NTPServerFactory struct {
+	// NTPServer is the OPTIONAL NTPv4 server to advertise during the NTS key
+	// establishment. When empty, the client uses the same server.
+	NTPServer string
+
+	// Offset is the OPTIONAL offset between the server clock and the real time,
+	// which allows to emulate a server that is lying about the time.
+	Offset time.Duration
+
+	// ServerNameMain is the MANDATORY server name to use as common name for X.509 certs.
+	ServerNameMain string
+
+	// ServerNameExtras contains OPTIONAL extra names to also configure into the cert.
+	ServerNameExtras []string
+}
+
+var _ NetStackServerFactory = &NTPServerFactory{}
+
+// MustNewServer implements NetStackServerFactory.
+func (f *NTPServerFactory) MustNewServer(env NetStackServerFactoryEnv, stack *netem.UNetStack) NetStackServer {
+	tlsConfig := stack.MustNewServerTLSConfig(f.ServerNameMain, f.ServerNameExtras...)
+	tlsConfig.MinVersion = tls.VersionTLS13
+	tlsConfig.NextProtos = []string{ntpx.NTSKEALPN}
+	srv := &ntpServer{
+		closers:   []io.Closer{},
+		cookieKey: make([]byte, ntpx.SIVKeyLength),
+		factory:   f,
+		logger:    env.Logger(),
+		mu:        sync.Mutex{},
+		unet:      stack,
+	}
+	_ = runtimex.Try1(rand.Read(srv.cookieKey))
+	srv.ke = &tcpServer{
+		closers: []io.Closer{},
+		handle: func(conn net.Conn) {
+			srv.serveKE(tls.Server(conn, tlsConfig))
+		},
+		logger: env.Logger(),
+		mu:     sync.Mutex{},
+		ports:  []uint16{ntpx.NTSKEPort},
+		unet:   stack,
+	}
+	return srv
+}
+
+// ntpServerCookies is the number of cookies we send to clients.
+const ntpServerCookies = 8
+
+type ntpServer struct {
+	closers   []io.Closer
+	cookieKey []byte
+	factory   *NTPServerFactory
+	ke        *tcpServer
+	logger    model.Logger
+	mu        sync.Mutex
+	unet      *netem.UNetStack
+}
+
+// Close implements NetStackServer.
+func (srv *ntpServer) Close() error {
+	// "this method MUST be CONCURRENCY SAFE"
+	defer srv.mu.Unlock()
+	srv.mu.Lock()
+
+	// make sure we close the key establishment server and the NTP socket
+	_ = srv.ke.Close()
+	for _, closer := range srv.closers {
+		_ = closer.Close()
+	}
+
+	// "this method MUST be IDEMPOTENT"
+	srv.closers = []io.Closer{}
+
+	return nil
+}
+
+// MustStart implements NetStackServer.
+func (srv *ntpServer) MustStart() {
+	// "this method MUST be CONCURRENCY SAFE"
+	defer srv.mu.Unlock()
+	srv.mu.Lock()
+
+	// start the key establishment server
+	srv.ke.MustStart()
+
+	// create the endpoint address
+	ipAddr := net.ParseIP(srv.unet.IPAddress())
+	runtimex.Assert(ipAddr != nil, "invalid IP address")
+	epnt := &net.UDPAddr{IP: ipAddr, Port: ntpx.Port}
+
+	// attempt to listen
+	pconn := runtimex.Try1(srv.unet.ListenUDP("udp", epnt))
+
+	// spawn goroutine for serving
+	go srv.serveNTP(pconn)
+
+	// track this socket as something to close later
+	srv.closers = append(srv.closers, pconn)
+}
+
+// now returns the current time according to the server clock.
+func (srv *ntpServer) now() time.Time {
+	return time.Now().Add(srv.factory.Offset)
+}
+
+// serveKE serves an NTS key establishment request.
+func (srv *ntpServer) serveKE(conn *tls.Conn) {
+	// Implementation note: because this function is only used for writing QA tests, it is
+	// fine that we are using runtimex.Try1 and ignoring any panic.
+	defer runtimex.CatchLogAndIgnorePanic(srv.logger, "ntpServer.serveKE")
+	defer conn.Close()
+
+	runtimex.Try0(conn.Handshake())
+	records := runtimex.Try1(ntpx.ReadRecords(conn))
+
+	// make sure the client supports NTPv4 and AEAD_AES_SIV_CMAC_256
+	var protocols, algorithms []uint16
+	for _, record := range records {
+		switch record.Type {
+		case ntpx.RecordNextProtocol:
+			protocols = runtimex.Try1(record.Uint16s())
+		case ntpx.RecordAEADAlgorithm:
+			algorithms = runtimex.Try1(record.Uint16s())
+		}
+	}
+	if !slices.Contains(protocols, ntpx.NextProtocolNTPv4) ||
+		!slices.Contains(algorithms, ntpx.AEADAESSIVCMAC256) {
+		const badRequest = 1
+		_ = runtimex.Try1(conn.Write(ntpx.MarshalRecords(
+			ntpx.NewUint16Record(true, ntpx.RecordError, badRequest),
+			&ntpx.Record{Critical: true, Type: ntpx.RecordEndOfMessage},
+		)))
+		return
+	}
+
+	// send the response including fresh cookies
+	state := conn.ConnectionState()
+	c2s, s2c := runtimex.Try2(ntpx.ExportKeys(&state))
+	response := []*ntpx.Record{
+		ntpx.NewUint16Record(true, ntpx.RecordNextProtocol, ntpx.NextProtocolNTPv4),
+		ntpx.NewUint16Record(false, ntpx.RecordAEADAlgorithm, ntpx.AEADAESSIVCMAC256),
+	}
+	for idx := 0; idx < ntpServerCookies; idx++ {
+		response = append(response, &ntpx.Record{Type: ntpx.RecordNewCookie, Body: srv.newCookie(c2s, s2c)})
+	}
+	if srv.factory.NTPServer != "" {
+		response = append(response, &ntpx.Record{
+			Type: ntpx.RecordNTPv4ServerNegotiation,
+			Body: []byte(srv.factory.NTPServer),
+		})
+	}
+	response = append(response, &ntpx.Record{Critical: true, Type: ntpx.RecordEndOfMessage})
+	_ = runtimex.Try1(conn.Write(ntpx.MarshalRecords(response...)))
+}
+
+// newCookie creates a cookie containing the given keys encrypted with the server key.
+func (srv *ntpServer) newCookie(c2s, s2c []byte) []byte {
+	nonce := make([]byte, 16)
+	_ = runtimex.Try1(rand.Read(nonce))
+	ciphertext := runtimex.Try1(ntpx.SIVSeal(srv.cookieKey, nonce, append(append([]byte{}, c2s...), s2c...), nil))
+	return append(nonce, ciphertext...)
+}
+
+// openCookie returns the keys contained by a cookie created using newCookie.
+func (srv *ntpServer) openCookie(cookie []byte) (c2s, s2c []byte, err error) {
+	if len(cookie) < 16 {
+		return nil, nil, ntpx.ErrAuthenticationFailed
+	}
+	keys, err := ntpx.SIVOpen(srv.cookieKey, cookie[:16], cookie[16:], nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keys[:ntpx.SIVKeyLength], keys[ntpx.SIVKeyLength:], nil
+}
+
+// serveNTP serves NTPv4 requests.
+func (srv *ntpServer) serveNTP(pconn netem.UDPLikeConn) {
+	// Implementation note: because this function is only used for writing QA tests, it is
+	// fine that we are using runtimex.Try1 and ignoring any panic.
+	defer runtimex.CatchLogAndIgnorePanic(srv.logger, "ntpServer.serveNTP")
+	buffer := make([]byte, 1<<12)
+	for {
+		count, addr := runtimex.Try2(pconn.ReadFrom(buffer))
+		if response := srv.respond(buffer[:count]); response != nil {
+			_, _ = pconn.WriteTo(response, addr)
+		}
+	}
+}
+
+// respond returns the response to the given request or nil.
+func (srv *ntpServer) respond(request []byte) []byte {
+	received := srv.now()
+	req, err := ntpx.ParsePacket(request)
+	if err != nil || req.Mode != ntpx.ModeClient {
+		return nil
+	}
+	resp := &ntpx.Packet{
+		Version:        req.Version,
+		Mode:           ntpx.ModeServer,
+		Stratum:        1,
+		Poll:           req.Poll,
+		Precision:      -20,
+		RootDelay:      0,
+		RootDispersion: time.Millisecond,
+		ReferenceID:    binary.BigEndian.Uint32([]byte("GPS\x00")),
+		ReferenceTime:  ntpx.NewTimestamp(received.Truncate(time.Minute)),
+		OriginTime:     req.TransmitTime,
+		ReceiveTime:    ntpx.NewTimestamp(received),
+	}
+
+	// handle plain NTPv4 requests
+	if req.FindExtension(ntpx.ExtensionAuthenticator) == nil {
+		resp.TransmitTime = ntpx.NewTimestamp(srv.now())
+		return resp.Marshal()
+	}
+
+	// handle NTS requests, where we reply with a NTS NAK when we cannot use the cookie
+	uid, cookie := req.FindExtension(ntpx.ExtensionUniqueIdentifier), req.FindExtension(ntpx.ExtensionCookie)
+	if uid == nil || cookie == nil {
+		return nil
+	}
+	resp.Extensions = []ntpx.Extension{*uid}
+	c2s, s2c, err := srv.openCookie(cookie.Value)
+	if err != nil {
+		resp.Stratum = 0
+		resp.ReferenceID = binary.BigEndian.Uint32([]byte("NTSN"))
+		resp.TransmitTime = ntpx.NewTimestamp(srv.now())
+		return resp.Marshal()
+	}
+	if _, _, err := ntpx.OpenPacket(request, c2s); err != nil {
+		return nil
+	}
+	resp.TransmitTime = ntpx.NewTimestamp(srv.now())
+	encrypted := []ntpx.Extension{{Type: ntpx.ExtensionCookie, Value: srv.newCookie(c2s, s2c)}}
+	return runtimex.Try1(ntpx.SealPacket(resp, s2c, encrypted))
+}
@@ -0,0 +1,158 @@
+package netemx
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/ntpx"
+)
+
+func TestNTPServer(t *testing.T) {
+	const offset = time.Hour
+	env := MustNewQAEnv(QAEnvOptionNetStack(AddressTimeCloudflareCom, &NTPServerFactory{
+		Offset:           offset,
+		ServerNameMain:   "time.cloudflare.com",
+		ServerNameExtras: []string{},
+	}))
+	defer env.Close()
+
+	env.Do(func() {
+		netx := &netxlite.Netx{}
+
+		// roundTrip sends the request and returns the response.
+		roundTrip := func(t *testing.T, request []byte) []byte {
+			dialer := netx.NewDialerWithoutResolver(log.Log)
+			conn, err := dialer.DialContext(context.Background(), "udp", net.JoinHostPort(AddressTimeCloudflareCom, "123"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if _, err := conn.Write(request); err != nil {
+				t.Fatal(err)
+			}
+			_ = conn.SetDeadline(time.Now().Add(time.Second))
+			buffer := make([]byte, 1<<12)
+			count, err := conn.Read(buffer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return buffer[:count]
+		}
+
+		// checkTime ensures that the response reflects the server clock offset.
+		checkTime := func(t *testing.T, request, response *ntpx.Packet) {
+			if response.Mode != ntpx.ModeServer || response.Stratum != 1 {
+				t.Fatal("unexpected mode or stratum", response.Mode, response.Stratum)
+			}
+			if response.OriginTime != request.TransmitTime {
+				t.Fatal("unexpected origin time")
+			}
+			if diff := time.Until(response.TransmitTime.Time()) - offset; diff.Abs() > time.Second {
+				t.Fatal("unexpected server time", response.TransmitTime.Time())
+			}
+		}
+
+		t.Run("NTPv4", func(t *testing.T) {
+			request := &ntpx.Packet{
+				Version:      ntpx.Version,
+				Mode:         ntpx.ModeClient,
+				TransmitTime: ntpx.NewTimestamp(time.Now()),
+			}
+			response, err := ntpx.ParsePacket(roundTrip(t, request.Marshal()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTime(t, request, response)
+		})
+
+		t.Run("NTS", func(t *testing.T) {
+			// perform the key establishment
+			dialer := netx.NewDialerWithoutResolver(log.Log)
+			conn, err := dialer.DialContext(context.Background(), "tcp", net.JoinHostPort(AddressTimeCloudflareCom, "4460"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			handshaker := netx.NewTLSHandshakerStdlib(log.Log)
+			tlsConn, err := handshaker.Handshake(context.Background(), conn, &tls.Config{
+				NextProtos: []string{ntpx.NTSKEALPN},
+				ServerName: "time.cloudflare.com",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tlsConn.Close()
+			if _, err := tlsConn.Write(ntpx.MarshalRecords(ntpx.NewKERequest()...)); err != nil {
+				t.Fatal(err)
+			}
+			records, err := ntpx.ReadRecords(tlsConn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cookies [][]byte
+			for _, record := range records {
+				if record.Type == ntpx.RecordNewCookie {
+					cookies = append(cookies, record.Body)
+				}
+			}
+			if len(cookies) != ntpServerCookies {
+				t.Fatal("unexpected number of cookies", len(cookies))
+			}
+			state := tlsConn.ConnectionState()
+			c2s, s2c, err := ntpx.ExportKeys(&state)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// newRequest creates an NTS request using the given cookie
+			newRequest := func(cookie []byte) (*ntpx.Packet, []byte) {
+				request := &ntpx.Packet{
+					Version:      ntpx.Version,
+					Mode:         ntpx.ModeClient,
+					TransmitTime: ntpx.NewTimestamp(time.Now()),
+					Extensions: []ntpx.Extension{
+						{Type: ntpx.ExtensionUniqueIdentifier, Value: bytes.Repeat([]byte{0x11}, 32)},
+						{Type: ntpx.ExtensionCookie, Value: cookie},
+					},
+				}
+				data, err := ntpx.SealPacket(request, c2s, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return request, data
+			}
+
+			t.Run("with a valid cookie", func(t *testing.T) {
+				request, data := newRequest(cookies[0])
+				response, encrypted, err := ntpx.OpenPacket(roundTrip(t, data), s2c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkTime(t, request, response)
+				uid := response.FindExtension(ntpx.ExtensionUniqueIdentifier)
+				if uid == nil || !bytes.Equal(uid.Value, request.Extensions[0].Value) {
+					t.Fatal("unexpected unique identifier")
+				}
+				if len(encrypted) != 1 || encrypted[0].Type != ntpx.ExtensionCookie {
+					t.Fatal("expected a fresh cookie")
+				}
+			})
+
+			t.Run("with an invalid cookie", func(t *testing.T) {
+				_, data := newRequest(bytes.Repeat([]byte{0x22}, 96))
+				response, err := ntpx.ParsePacket(roundTrip(t, data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if code := response.KissCode(); code != "NTSN" {
+					t.Fatal("unexpected kiss code", code)
+				}
+			})
+		})
+	})
+}
@@ -37,6 +37,18 @@ const (
 	// ScenarioRoleBadSSL means that the host hosts services to
 	// measure against common TLS issues.
 	ScenarioRoleBadSSL
+
+	// ScenarioRoleMailServer means that the host is a mail server speaking
+	// SMTP, IMAP, and POP3 (see [MailServerFactory]).
+	ScenarioRoleMailServer
+
+	// ScenarioRoleTorBridge means that the host emulates Tor bridges using
+	// obfs4, meek, webtunnel, and SSH, as well as the snowflake broker.
+	ScenarioRoleTorBridge
+
+	// ScenarioRoleTimeServer means that the host is a time server speaking
+	// NTPv4 and NTS key establishment (see [NTPServerFactory]).
+	ScenarioRoleTimeServer
 )
 
 // ScenarioDomainAddresses describes a domain and address used in a scenario.
@@ -259,6 +271,60 @@ var InternetScenario = []*ScenarioDomainAddresses{{
 	ServerNameMain:   "httpbin.com",
 	ServerNameExtras: []string{},
 	WebServerFactory: HTTPBinHandlerFactory(),
+}, {
+	Addresses: []string{
+		AddressMLabSpeedTest,
+	},
+	Domains: []string{
+		"mlab-speedtest.org",
+	},
+	Role:             ScenarioRoleWebServer,
+	ServerNameMain:   "mlab-speedtest.org",
+	ServerNameExtras: []string{},
+	WebServerFactory: MLabHandlerFactory(),
+}, {
+	Addresses: []string{
+		AddressMailExampleCom,
+	},
+	Domains: []string{
+		"mail.example.com",
+	},
+	Role:             ScenarioRoleMailServer,
+	ServerNameMain:   "mail.example.com",
+	ServerNameExtras: []string{},
+}, {
+	Addresses: []string{
+		AddressTorBridge,
+	},
+	Domains: []string{
+		"front.example.com",
+		"broker.example.com",
+		"meek.example.com",
+		"webtunnel.example.com",
+	},
+	Role:             ScenarioRoleTorBridge,
+	ServerNameMain:   "front.example.com",
+	ServerNameExtras: []string{"broker.example.com", "meek.example.com", "webtunnel.example.com"},
+}, {
+	Addresses: []string{
+		AddressTimeCloudflareCom,
+	},
+	Domains: []string{
+		"time.cloudflare.com",
+	},
+	Role:             ScenarioRoleTimeServer,
+	ServerNameMain:   "time.cloudflare.com",
+	ServerNameExtras: []string{},
+}, {
+	Addresses: []string{
+		AddressTimeGoogleCom,
+	},
+	Domains: []string{
+		"time.google.com",
+	},
+	Role:             ScenarioRoleTimeServer,
+	ServerNameMain:   "time.google.com",
+	ServerNameExtras: []string{},
 }, {
 	Domains: []string{"dns.nextdns.io"},
 	Addresses: []string{
@@ -390,6 +456,36 @@ func MustNewScenario(config []*ScenarioDomainAddresses) *QAEnv {
 			for _, addr := range sad.Addresses {
 				opts = append(opts, qaEnvOptionNetStack(addr, &BadSSLServerFactory{}))
 			}
+
+		case ScenarioRoleMailServer:
+			for _, addr := range sad.Addresses {
+				opts = append(opts, QAEnvOptionNetStack(addr, &MailServerFactory{
+					ServerNameMain:   sad.ServerNameMain,
+					ServerNameExtras: sad.ServerNameExtras,
+				}))
+			}
+
+		case ScenarioRoleTorBridge:
+			for _, addr := range sad.Addresses {
+				opts = append(opts, QAEnvOptionNetStack(addr,
+					&HTTPSecureServerFactory{
+						Factory:          TorBridgeHandlerFactory(),
+						Ports:            []int{443},
+						ServerNameMain:   sad.ServerNameMain,
+						ServerNameExtras: sad.ServerNameExtras,
+					},
+					&OBFS4ServerFactory{Ports: []uint16{9443}},
+					&SSHServerFactory{Banner: "SSH-2.0-OpenSSH_9.6", Ports: []uint16{22}},
+				))
+			}
+
+		case ScenarioRoleTimeServer:
+			for _, addr := range sad.Addresses {
+				opts = append(opts, QAEnvOptionNetStack(addr, &NTPServerFactory{
+					ServerNameMain:   sad.ServerNameMain,
+					ServerNameExtras: sad.ServerNameExtras,
+				}))
+			}
 		}
 	}
 
@@ -0,0 +1,251 @@
+package netemx
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"gitlab.com/yawning/obfs4.git/transports/base"
+	"gitlab.com/yawning/obfs4.git/transports/obfs4"
+	pt "gitlab.torproject.org/tpo/anti-censorship/pluggable-transports/goptlib"
+)
+
+// TorBridgeHandlerFactory returns an [HTTPHandlerFactory] for constructing a [TorBridgeHandler].
+func TorBridgeHandlerFactory() HTTPHandlerFactory {
+	return HTTPHandlerFactoryFunc(func(env NetStackServerFactoryEnv, stack *netem.UNetStack) http.Handler {
+		return &TorBridgeHandler{}
+	})
+}
+
+// TorBridgeHandler is an [http.Handler] emulating the HTTP-based pluggable transports
+// servers, which allows us to measure their reachability inside a [*QAEnv].
+//
+// We currently implement the following API endpoints:
+//
+//	/
+//		Returns the same body returned by meek servers.
+//
+//	/robots.txt
+//		Returns the same body returned by the snowflake broker.
+//
+//	/webtunnel
+//		Upgrades to WebSocket, like a webtunnel server does, and closes the connection.
+//
+// Any other request URL causes a 404 response. Note that this handler does not care
+// about the Host header, therefore it also emulates domain fronting.
+//
+// The zero value is ready to use.
+type TorBridgeHandler struct{}
+
+var _ http.Handler = &TorBridgeHandler{}
+
+const (
+	// TorBridgeMeekBody is the body returned by meek servers.
+	TorBridgeMeekBody = "I’m just a happy little web server.\n"
+
+	// TorBridgeWebTunnelPath is the path where we accept webtunnel connections.
+	TorBridgeWebTunnelPath = "/webtunnel"
+)
+
+// ServeHTTP implements http.Handler.
+func (h *TorBridgeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(TorBridgeMeekBody))
+
+	case r.URL.Path == "/robots.txt" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("User-agent: *\nDisallow: /\n"))
+
+	case r.URL.Path == TorBridgeWebTunnelPath && r.Method == http.MethodGet:
+		upgrader := &websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return // the upgrader has already written the response
+		}
+		_ = conn.Close()
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// These are the fixed obfs4 server keys used by [OBFS4ServerFactory], which
+// allow us to know the bridge cert in advance.
+const (
+	obfs4ServerNodeID     = "74fad13168806246602538555b5521a0383a1875"
+	obfs4ServerPrivateKey = "a0d5c5c7e6f1ad40c4d0a1c5b1a8f8e3d9c0b7a6f5e4d3c2b1a09f8e7d6c5b44"
+	obfs4ServerDrbgSeed   = "c0ffeec0ffeec0ffeec0ffeec0ffeec0ffeec0ffeec0ffee"
+)
+
+// OBFS4ServerFactory is a [NetStackServerFactory] for an obfs4 server
+// that echoes back what it receives after the obfs4 handshake.
+//
+// The zero value is invalid; please, fill all the MANDATORY fields.
+type OBFS4ServerFactory struct {
+	// Ports is the MANDATORY list of ports where to listen.
+	Ports []uint16
+}
+
+var _ NetStackServerFactory = &OBFS4ServerFactory{}
+
+// MustNewServer implements NetStackServerFactory.
+func (f *OBFS4ServerFactory) MustNewServer(env NetStackServerFactoryEnv, stack *netem.UNetStack) NetStackServer {
+	stateDir := runtimex.Try1(os.MkdirTemp("", "netemx-obfs4"))
+	factory := mustNewOBFS4ServerFactory(stateDir)
+	return &tcpServer{
+		closers: []io.Closer{closerFunc(func() error {
+			return os.RemoveAll(stateDir)
+		})},
+		handle: func(conn net.Conn) {
+			conn = runtimex.Try1(factory.WrapConn(conn))
+			_, _ = io.Copy(conn, conn)
+		},
+		logger: env.Logger(),
+		mu:     sync.Mutex{},
+		ports:  f.Ports,
+		unet:   stack,
+	}
+}
+
+// mustNewOBFS4ServerFactory creates the obfs4 server factory using the fixed keys.
+func mustNewOBFS4ServerFactory(stateDir string) base.ServerFactory {
+	args := &pt.Args{
+		"drbg-seed":   []string{obfs4ServerDrbgSeed},
+		"iat-mode":    []string{"0"},
+		"node-id":     []string{obfs4ServerNodeID},
+		"private-key": []string{obfs4ServerPrivateKey},
+	}
+	return runtimex.Try1((&obfs4.Transport{}).ServerFactory(stateDir, args))
+}
+
+// MustOBFS4ServerCert returns the cert that clients must use to connect to
+// the servers constructed by [OBFS4ServerFactory].
+func MustOBFS4ServerCert() string {
+	stateDir := runtimex.Try1(os.MkdirTemp("", "netemx-obfs4"))
+	defer os.RemoveAll(stateDir)
+	cert, found := mustNewOBFS4ServerFactory(stateDir).Args().Get("cert")
+	runtimex.Assert(found, "obfs4 server factory without cert")
+	return cert
+}
+
+// SSHServerFactory is a [NetStackServerFactory] for a server that sends the
+// given SSH banner to clients and then waits for them to close the connection.
+//
+// The zero value is invalid; please, fill all the MANDATORY fields.
+type SSHServerFactory struct {
+	// Banner is the MANDATORY banner to send (e.g., "SSH-2.0-OpenSSH_9.6").
+	Banner string
+
+	// Ports is the MANDATORY list of ports where to listen.
+	Ports []uint16
+}
+
+var _ NetStackServerFactory = &SSHServerFactory{}
+
+// MustNewServer implements NetStackServerFactory.
+func (f *SSHServerFactory) MustNewServer(env NetStackServerFactoryEnv, stack *netem.UNetStack) NetStackServer {
+	return &tcpServer{
+		closers: []io.Closer{},
+		handle: func(conn net.Conn) {
+			_ = runtimex.Try1(conn.Write([]byte(f.Banner + "\r\n")))
+			_, _ = io.Copy(io.Discard, conn)
+		},
+		logger: env.Logger(),
+		mu:     sync.Mutex{},
+		ports:  f.Ports,
+		unet:   stack,
+	}
+}
+
+// closerFunc allows to use a function as an [io.Closer].
+type closerFunc func() error
+
+// Close implements io.Closer.
+func (fx closerFunc) Close() error {
+	return fx()
+}
+
+// tcpServer is a [NetStackServer] handling TCP connections using a function.
+type tcpServer struct {
+	closers []io.Closer
+	handle  func(conn net.Conn)
+	logger  model.Logger
+	mu      sync.Mutex
+	ports   []uint16
+	unet    *netem.UNetStack
+}
+
+// Close implements NetStackServer.
+func (srv *tcpServer) Close() error {
+	// "this method MUST be CONCURRENCY SAFE"
+	defer srv.mu.Unlock()
+	srv.mu.Lock()
+
+	// make sure we close all the child listeners
+	for _, closer := range srv.closers {
+		_ = closer.Close()
+	}
+
+	// "this method MUST be IDEMPOTENT"
+	srv.closers = []io.Closer{}
+
+	return nil
+}
+
+// MustStart implements NetStackServer.
+func (srv *tcpServer) MustStart() {
+	// "this method MUST be CONCURRENCY SAFE"
+	defer srv.mu.Unlock()
+	srv.mu.Lock()
+
+	// for each port of interest - note that here we panic liberally because we are
+	// allowed to do so by the [NetStackServer] documentation.
+	for _, port := range srv.ports {
+		// create the endpoint address
+		ipAddr := net.ParseIP(srv.unet.IPAddress())
+		runtimex.Assert(ipAddr != nil, "invalid IP address")
+		epnt := &net.TCPAddr{IP: ipAddr, Port: int(port)}
+
+		// attempt to listen
+		listener := runtimex.Try1(srv.unet.ListenTCP("tcp", epnt))
+
+		// spawn goroutine for accepting
+		go srv.acceptLoop(listener)
+
+		// track this listener as something to close later
+		srv.closers = append(srv.closers, listener)
+	}
+}
+
+func (srv *tcpServer) acceptLoop(listener net.Listener) {
+	// Implementation note: because this function is only used for writing QA tests, it is
+	// fine that we are using runtimex.Try1 and ignoring any panic.
+	defer runtimex.CatchLogAndIgnorePanic(srv.logger, "tcpServer.acceptLoop")
+	for {
+		conn := runtimex.Try1(listener.Accept())
+		go srv.serve(conn)
+	}
+}
+
+func (srv *tcpServer) serve(conn net.Conn) {
+	// Implementation note: because this function is only used for writing QA tests, it is
+	// fine that we are using runtimex.Try1 and ignoring any panic.
+	defer runtimex.CatchLogAndIgnorePanic(srv.logger, "tcpServer.serve")
+
+	// make sure we close the conn
+	defer conn.Close()
+
+	// make sure we do not keep idle clients around forever
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	srv.handle(conn)
+}
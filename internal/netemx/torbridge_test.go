@@ -0,0 +1,149 @@
+package netemx
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/gorilla/websocket"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/ptx"
+)
+
+func TestTorBridgeHandler(t *testing.T) {
+	srv := httptest.NewServer(&TorBridgeHandler{})
+	defer srv.Close()
+
+	get := func(t *testing.T, path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("/", func(t *testing.T) {
+		status, body := get(t, "/")
+		if status != 200 || body != TorBridgeMeekBody {
+			t.Fatal("unexpected response", status, body)
+		}
+	})
+
+	t.Run("/robots.txt", func(t *testing.T) {
+		status, body := get(t, "/robots.txt")
+		if status != 200 || !strings.HasPrefix(body, "User-agent: *") {
+			t.Fatal("unexpected response", status, body)
+		}
+	})
+
+	t.Run("/webtunnel", func(t *testing.T) {
+		wsURL := strings.Replace(srv.URL, "http://", "ws://", 1) + TorBridgeWebTunnelPath
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+	})
+
+	t.Run("/webtunnel without upgrading", func(t *testing.T) {
+		status, _ := get(t, TorBridgeWebTunnelPath)
+		if status != http.StatusBadRequest {
+			t.Fatal("unexpected status code", status)
+		}
+	})
+
+	t.Run("any other path", func(t *testing.T) {
+		status, _ := get(t, "/antani")
+		if status != http.StatusNotFound {
+			t.Fatal("unexpected status code", status)
+		}
+	})
+}
+
+func TestTorBridgeServers(t *testing.T) {
+	env := MustNewScenario(InternetScenario)
+	defer env.Close()
+
+	env.Do(func() {
+		netx := &netxlite.Netx{}
+
+		t.Run("obfs4", func(t *testing.T) {
+			dialer := &ptx.OBFS4Dialer{
+				Address:          net.JoinHostPort(AddressTorBridge, "9443"),
+				Cert:             MustOBFS4ServerCert(),
+				DataDir:          t.TempDir(),
+				Fingerprint:      strings.ToUpper(obfs4ServerNodeID),
+				IATMode:          "0",
+				UnderlyingDialer: netx.NewDialerWithoutResolver(log.Log),
+			}
+			conn, err := dialer.DialContext(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if _, err := conn.Write([]byte("antani")); err != nil {
+				t.Fatal(err)
+			}
+			buffer := make([]byte, 6)
+			if _, err := io.ReadFull(conn, buffer); err != nil {
+				t.Fatal(err)
+			}
+			if string(buffer) != "antani" {
+				t.Fatal("unexpected echo", string(buffer))
+			}
+		})
+
+		t.Run("obfs4 with invalid cert", func(t *testing.T) {
+			dialer := &ptx.OBFS4Dialer{
+				Address:          net.JoinHostPort(AddressTorBridge, "9443"),
+				Cert:             ptx.DefaultTestingOBFS4Bridge().Cert,
+				DataDir:          t.TempDir(),
+				Fingerprint:      strings.ToUpper(obfs4ServerNodeID),
+				IATMode:          "0",
+				UnderlyingDialer: netx.NewDialerWithoutResolver(log.Log),
+			}
+			// the server does not reply to invalid handshakes, so we need a timeout
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			conn, err := dialer.DialContext(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatal("unexpected error", err)
+			}
+			if conn != nil {
+				t.Fatal("expected nil conn")
+			}
+		})
+
+		t.Run("ssh", func(t *testing.T) {
+			dialer := netx.NewDialerWithoutResolver(log.Log)
+			conn, err := dialer.DialContext(context.Background(), "tcp", net.JoinHostPort(AddressTorBridge, "22"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			banner, err := bufio.NewReader(conn).ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if banner != "SSH-2.0-OpenSSH_9.6\r\n" {
+				t.Fatal("unexpected banner", banner)
+			}
+		})
+	})
+}
@@ -67,6 +67,13 @@ func NewUnwrappedDNSOverHTTPSTransportWithHostOverride(
 	}
 }
 
+// NewDNSOverHTTPSTransportWithHostOverride is like NewUnwrappedDNSOverHTTPSTransportWithHostOverride
+// but returns an already wrapped DNSTransport.
+func NewDNSOverHTTPSTransportWithHostOverride(
+	client model.HTTPClient, URL, hostOverride string) model.DNSTransport {
+	return wrapDNSTransport(NewUnwrappedDNSOverHTTPSTransportWithHostOverride(client, URL, hostOverride))
+}
+
 // RoundTrip sends a query and receives a reply.
 func (t *DNSOverHTTPSTransport) RoundTrip(
 	ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
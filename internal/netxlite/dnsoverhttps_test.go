@@ -47,6 +47,23 @@ func TestNewDNSOverHTTPSTransportWithHTTPTransport(t *testing.T) {
 	}
 }
 
+func TestNewDNSOverHTTPSTransportWithHostOverride(t *testing.T) {
+	const URL = "https://8.8.8.8/dns-query"
+	clnt := &mocks.HTTPClient{}
+	txp := NewDNSOverHTTPSTransportWithHostOverride(clnt, URL, "dns.google")
+	ew := txp.(*dnsTransportErrWrapper)
+	https := ew.DNSTransport.(*DNSOverHTTPSTransport)
+	if https.Client != clnt {
+		t.Fatal("invalid client")
+	}
+	if https.URL != URL {
+		t.Fatal("invalid URL")
+	}
+	if https.HostOverride != "dns.google" {
+		t.Fatal("invalid host override")
+	}
+}
+
 func TestDNSOverHTTPSTransport(t *testing.T) {
 	t.Run("RoundTrip", func(t *testing.T) {
 		t.Run("query serialization failure", func(t *testing.T) {
@@ -1,7 +1,7 @@
 package netxlite
 
 //
-// QUIRKy Legacy HTTP code and behavior assumed by ./legacy/netx 😅
+// QUIRKy Legacy HTTP code and behavior originally assumed by ./legacy/netx 😅
 //
 // Ideally, we should not modify this code or apply minimal and obvious changes.
 //
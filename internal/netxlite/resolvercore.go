@@ -77,6 +77,21 @@ func (netx *Netx) NewParallelUDPResolver(logger model.DebugLogger, dialer model.
 	))
 }
 
+// NewParallelTCPResolver implements [model.MeasuringNetwork].
+func (netx *Netx) NewParallelTCPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
+	return WrapResolver(logger, NewUnwrappedParallelResolver(
+		wrapDNSTransport(NewUnwrappedDNSOverTCPTransport(dialer.DialContext, address)),
+	))
+}
+
+// NewParallelDNSOverTLSResolver implements [model.MeasuringNetwork].
+func (netx *Netx) NewParallelDNSOverTLSResolver(
+	logger model.DebugLogger, tlsDialer model.TLSDialer, address string) model.Resolver {
+	return WrapResolver(logger, NewUnwrappedParallelResolver(
+		wrapDNSTransport(NewUnwrappedDNSOverTLSTransport(tlsDialer.DialTLSContext, address)),
+	))
+}
+
 // WrapResolver creates a new resolver that wraps an
 // existing resolver to add these properties:
 //
@@ -74,6 +74,51 @@ func TestNewParallelUDPResolver(t *testing.T) {
 	}
 }
 
+func TestNewParallelTCPResolver(t *testing.T) {
+	netx := &Netx{}
+	d := netx.NewDialerWithoutResolver(log.Log)
+	resolver := netx.NewParallelTCPResolver(log.Log, d, "1.1.1.1:53")
+	idnaReso := resolver.(*resolverIDNA)
+	logger := idnaReso.Resolver.(*resolverLogger)
+	if logger.Logger != log.Log {
+		t.Fatal("invalid logger")
+	}
+	shortCircuit := logger.Resolver.(*ResolverShortCircuitIPAddr)
+	errWrapper := shortCircuit.Resolver.(*resolverErrWrapper)
+	para := errWrapper.Resolver.(*ParallelResolver)
+	txp := para.Transport().(*dnsTransportErrWrapper)
+	dnsTxp := txp.DNSTransport.(*DNSOverTCPTransport)
+	if dnsTxp.Address() != "1.1.1.1:53" {
+		t.Fatal("invalid address")
+	}
+	if dnsTxp.Network() != "tcp" {
+		t.Fatal("invalid network")
+	}
+}
+
+func TestNewParallelDNSOverTLSResolver(t *testing.T) {
+	netx := &Netx{}
+	d := netx.NewDialerWithoutResolver(log.Log)
+	td := NewTLSDialer(d, netx.NewTLSHandshakerStdlib(log.Log))
+	resolver := netx.NewParallelDNSOverTLSResolver(log.Log, td, "1.1.1.1:853")
+	idnaReso := resolver.(*resolverIDNA)
+	logger := idnaReso.Resolver.(*resolverLogger)
+	if logger.Logger != log.Log {
+		t.Fatal("invalid logger")
+	}
+	shortCircuit := logger.Resolver.(*ResolverShortCircuitIPAddr)
+	errWrapper := shortCircuit.Resolver.(*resolverErrWrapper)
+	para := errWrapper.Resolver.(*ParallelResolver)
+	txp := para.Transport().(*dnsTransportErrWrapper)
+	dnsTxp := txp.DNSTransport.(*DNSOverTCPTransport)
+	if dnsTxp.Address() != "1.1.1.1:853" {
+		t.Fatal("invalid address")
+	}
+	if dnsTxp.Network() != "dot" {
+		t.Fatal("invalid network")
+	}
+}
+
 func TestNewParallelDNSOverHTTPSResolver(t *testing.T) {
 	netx := &Netx{}
 	resolver := netx.NewParallelDNSOverHTTPSResolver(log.Log, "https://1.1.1.1/dns-query")
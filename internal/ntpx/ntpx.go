@@ -0,0 +1,226 @@
+// Package ntpx contains code to serialize and parse NTPv4 packets (RFC 5905),
+// including extension fields (RFC 7822), and Network Time Security (RFC 8915).
+package ntpx
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// These are the NTP modes we use.
+const (
+	// ModeClient is the mode of client requests.
+	ModeClient = 3
+
+	// ModeServer is the mode of server responses.
+	ModeServer = 4
+)
+
+// Version is the NTP version we use.
+const Version = 4
+
+// Port is the NTP port.
+const Port = 123
+
+// HeaderLength is the length of the NTP header.
+const HeaderLength = 48
+
+// minExtensionLength is the minimum length of an extension field (RFC 7822).
+const minExtensionLength = 16
+
+// ErrInvalidPacket indicates that we cannot parse an NTP packet.
+var ErrInvalidPacket = errors.New("ntpx: invalid packet")
+
+// Timestamp is an NTP timestamp in the 64-bit format, where the first 32
+// bits are the seconds since 1900 and the other bits are the fraction.
+type Timestamp uint64
+
+// ntpEpochOffset is the number of seconds between 1900 and 1970.
+const ntpEpochOffset = 2208988800
+
+// NewTimestamp converts a [time.Time] to a [Timestamp]. The zero [time.Time]
+// is converted to the zero [Timestamp], which means "unknown" in NTP.
+func NewTimestamp(t time.Time) Timestamp {
+	if t.IsZero() {
+		return 0
+	}
+	nanos := t.UnixNano()
+	seconds := uint64(nanos/1e9 + ntpEpochOffset)
+	fraction := (uint64(nanos%1e9) << 32) / 1e9
+	return Timestamp(seconds<<32 | fraction)
+}
+
+// Time converts a [Timestamp] to a [time.Time]. We assume timestamps whose
+// seconds have the most significant bit cleared belong to the era starting
+// in 2036, which allows us to represent times between 1968 and 2104.
+func (ts Timestamp) Time() time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	seconds := int64(ts >> 32)
+	if seconds&0x80000000 == 0 {
+		seconds += 1 << 32
+	}
+	nanos := (int64(ts&0xffffffff)*1e9 + 1<<31) >> 32
+	return time.Unix(seconds-ntpEpochOffset, nanos)
+}
+
+// Extension is an NTP extension field.
+type Extension struct {
+	// Type is the field type.
+	Type uint16
+
+	// Value is the field value. When serializing, we pad the value as
+	// needed. When parsing, the value includes the padding.
+	Value []byte
+}
+
+// Packet is an NTP packet.
+type Packet struct {
+	// Leap is the leap indicator.
+	Leap uint8
+
+	// Version is the NTP version.
+	Version uint8
+
+	// Mode is the association mode.
+	Mode uint8
+
+	// Stratum is the stratum, where zero means that
+	// the packet is a kiss-o'-death packet.
+	Stratum uint8
+
+	// Poll is the poll exponent.
+	Poll int8
+
+	// Precision is the precision exponent.
+	Precision int8
+
+	// RootDelay is the total round-trip delay to the reference clock.
+	RootDelay time.Duration
+
+	// RootDispersion is the total dispersion to the reference clock.
+	RootDispersion time.Duration
+
+	// ReferenceID identifies the reference clock or contains the kiss code.
+	ReferenceID uint32
+
+	// ReferenceTime is the time when the clock was last set.
+	ReferenceTime Timestamp
+
+	// OriginTime is the client transmit time echoed by the server.
+	OriginTime Timestamp
+
+	// ReceiveTime is the time when the server received the request.
+	ReceiveTime Timestamp
+
+	// TransmitTime is the time when the packet was sent.
+	TransmitTime Timestamp
+
+	// Extensions contains the extension fields.
+	Extensions []Extension
+}
+
+// KissCode returns the kiss code of a kiss-o'-death packet or an empty string.
+func (p *Packet) KissCode() string {
+	if p.Stratum != 0 {
+		return ""
+	}
+	return string(binary.BigEndian.AppendUint32(nil, p.ReferenceID))
+}
+
+// FindExtension returns the first extension field with the given type or nil.
+func (p *Packet) FindExtension(fieldType uint16) *Extension {
+	for idx := range p.Extensions {
+		if p.Extensions[idx].Type == fieldType {
+			return &p.Extensions[idx]
+		}
+	}
+	return nil
+}
+
+// Marshal serializes the packet.
+func (p *Packet) Marshal() []byte {
+	out := make([]byte, 0, HeaderLength)
+	out = append(out, p.Leap<<6|(p.Version&0x07)<<3|p.Mode&0x07, p.Stratum, byte(p.Poll), byte(p.Precision))
+	out = binary.BigEndian.AppendUint32(out, durationToShort(p.RootDelay))
+	out = binary.BigEndian.AppendUint32(out, durationToShort(p.RootDispersion))
+	out = binary.BigEndian.AppendUint32(out, p.ReferenceID)
+	out = binary.BigEndian.AppendUint64(out, uint64(p.ReferenceTime))
+	out = binary.BigEndian.AppendUint64(out, uint64(p.OriginTime))
+	out = binary.BigEndian.AppendUint64(out, uint64(p.ReceiveTime))
+	out = binary.BigEndian.AppendUint64(out, uint64(p.TransmitTime))
+	return appendExtensions(out, p.Extensions)
+}
+
+// appendExtensions appends the serialized extension fields to out.
+func appendExtensions(out []byte, extensions []Extension) []byte {
+	for _, ext := range extensions {
+		length := max(4+len(ext.Value)+padding(len(ext.Value)), minExtensionLength)
+		out = binary.BigEndian.AppendUint16(out, ext.Type)
+		out = binary.BigEndian.AppendUint16(out, uint16(length))
+		out = append(out, ext.Value...)
+		out = append(out, make([]byte, length-4-len(ext.Value))...)
+	}
+	return out
+}
+
+// ParsePacket parses an NTP packet. We stop parsing extension fields when the
+// remaining data does not look like an extension field, which happens, e.g.,
+// when the packet contains a legacy message authentication code.
+func ParsePacket(data []byte) (*Packet, error) {
+	p, _, err := parsePacket(data)
+	return p, err
+}
+
+// parsePacket is like ParsePacket but also returns the offset of each extension field.
+func parsePacket(data []byte) (*Packet, []int, error) {
+	if len(data) < HeaderLength {
+		return nil, nil, ErrInvalidPacket
+	}
+	p := &Packet{
+		Leap:           data[0] >> 6,
+		Version:        (data[0] >> 3) & 0x07,
+		Mode:           data[0] & 0x07,
+		Stratum:        data[1],
+		Poll:           int8(data[2]),
+		Precision:      int8(data[3]),
+		RootDelay:      shortToDuration(binary.BigEndian.Uint32(data[4:8])),
+		RootDispersion: shortToDuration(binary.BigEndian.Uint32(data[8:12])),
+		ReferenceID:    binary.BigEndian.Uint32(data[12:16]),
+		ReferenceTime:  Timestamp(binary.BigEndian.Uint64(data[16:24])),
+		OriginTime:     Timestamp(binary.BigEndian.Uint64(data[24:32])),
+		ReceiveTime:    Timestamp(binary.BigEndian.Uint64(data[32:40])),
+		TransmitTime:   Timestamp(binary.BigEndian.Uint64(data[40:48])),
+	}
+	offsets := []int{}
+	for offset := HeaderLength; len(data)-offset >= minExtensionLength; {
+		length := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		if length < minExtensionLength || length%4 != 0 || length > len(data)-offset {
+			break
+		}
+		p.Extensions = append(p.Extensions, Extension{
+			Type:  binary.BigEndian.Uint16(data[offset : offset+2]),
+			Value: data[offset+4 : offset+length],
+		})
+		offsets = append(offsets, offset)
+		offset += length
+	}
+	return p, offsets, nil
+}
+
+// padding returns the number of bytes required to pad length to a multiple of four.
+func padding(length int) int {
+	return (4 - length%4) % 4
+}
+
+// shortToDuration converts the NTP 32-bit short format to a [time.Duration].
+func shortToDuration(value uint32) time.Duration {
+	return time.Duration((int64(value)*int64(time.Second) + 1<<15) >> 16)
+}
+
+// durationToShort converts a [time.Duration] to the NTP 32-bit short format.
+func durationToShort(value time.Duration) uint32 {
+	return uint32((int64(value) << 16) / int64(time.Second))
+}
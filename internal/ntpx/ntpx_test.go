@@ -0,0 +1,145 @@
+package ntpx
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTimestamp(t *testing.T) {
+	t.Run("the zero time maps to the zero timestamp", func(t *testing.T) {
+		if ts := NewTimestamp(time.Time{}); ts != 0 {
+			t.Fatal("expected zero timestamp, got", ts)
+		}
+		if !Timestamp(0).Time().IsZero() {
+			t.Fatal("expected zero time")
+		}
+	})
+
+	t.Run("we correctly convert the Unix epoch", func(t *testing.T) {
+		ts := NewTimestamp(time.Unix(0, 0))
+		if ts != Timestamp(ntpEpochOffset<<32) {
+			t.Fatalf("unexpected timestamp %x", uint64(ts))
+		}
+	})
+
+	t.Run("we correctly handle the second NTP era", func(t *testing.T) {
+		// 2036-02-07T06:28:16Z is where the first era ends
+		when := time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC)
+		ts := NewTimestamp(when)
+		if ts>>63 != 0 {
+			t.Fatal("expected the most significant bit to be cleared")
+		}
+		if got := ts.Time(); !got.Equal(when) {
+			t.Fatal("unexpected time", got)
+		}
+	})
+
+	t.Run("the roundtrip has sub-microsecond precision", func(t *testing.T) {
+		when := time.Date(2026, 10, 19, 12, 34, 56, 123456789, time.UTC)
+		got := NewTimestamp(when).Time()
+		if diff := got.Sub(when); diff < -time.Nanosecond || diff > time.Nanosecond {
+			t.Fatal("unexpected difference", diff)
+		}
+	})
+}
+
+func TestPacket(t *testing.T) {
+	t.Run("we can roundtrip a packet with extensions", func(t *testing.T) {
+		expect := &Packet{
+			Leap:           0,
+			Version:        Version,
+			Mode:           ModeServer,
+			Stratum:        2,
+			Poll:           6,
+			Precision:      -20,
+			RootDelay:      500 * time.Millisecond,
+			RootDispersion: 250 * time.Millisecond,
+			ReferenceID:    0xc0000201,
+			ReferenceTime:  NewTimestamp(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)),
+			OriginTime:     NewTimestamp(time.Date(2026, 10, 19, 12, 0, 1, 0, time.UTC)),
+			ReceiveTime:    NewTimestamp(time.Date(2026, 10, 19, 12, 0, 2, 0, time.UTC)),
+			TransmitTime:   NewTimestamp(time.Date(2026, 10, 19, 12, 0, 3, 0, time.UTC)),
+			Extensions: []Extension{{
+				Type:  ExtensionUniqueIdentifier,
+				Value: make([]byte, 32),
+			}, {
+				Type:  ExtensionCookie,
+				Value: []byte("0123456789abcdef0123"),
+			}},
+		}
+		data := expect.Marshal()
+		if len(data) != HeaderLength+36+24 {
+			t.Fatal("unexpected length", len(data))
+		}
+		got, err := ParsePacket(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we pad short extension fields", func(t *testing.T) {
+		p := &Packet{Extensions: []Extension{{Type: 1, Value: []byte("abc")}}}
+		got, err := ParsePacket(p.Marshal())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []Extension{{Type: 1, Value: append([]byte("abc"), make([]byte, 9)...)}}
+		if diff := cmp.Diff(expect, got.Extensions); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we stop parsing at a legacy MAC", func(t *testing.T) {
+		p := &Packet{Version: Version, Mode: ModeServer, Stratum: 1}
+		data := append(p.Marshal(), make([]byte, 20)...)
+		got, err := ParsePacket(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Extensions) != 0 {
+			t.Fatal("expected no extensions")
+		}
+	})
+
+	t.Run("we reject too short packets", func(t *testing.T) {
+		got, err := ParsePacket(make([]byte, HeaderLength-1))
+		if !errors.Is(err, ErrInvalidPacket) {
+			t.Fatal("unexpected error", err)
+		}
+		if got != nil {
+			t.Fatal("expected nil packet")
+		}
+	})
+}
+
+func TestPacketKissCode(t *testing.T) {
+	t.Run("for a kiss-o'-death packet", func(t *testing.T) {
+		p := &Packet{Stratum: 0, ReferenceID: 0x52415445}
+		if code := p.KissCode(); code != "RATE" {
+			t.Fatal("unexpected kiss code", code)
+		}
+	})
+
+	t.Run("for a regular packet", func(t *testing.T) {
+		p := &Packet{Stratum: 1, ReferenceID: 0x47505300}
+		if code := p.KissCode(); code != "" {
+			t.Fatal("unexpected kiss code", code)
+		}
+	})
+}
+
+func TestPacketFindExtension(t *testing.T) {
+	p := &Packet{Extensions: []Extension{{Type: 1}, {Type: 2, Value: []byte("x")}, {Type: 2}}}
+	if ext := p.FindExtension(2); ext == nil || string(ext.Value) != "x" {
+		t.Fatal("unexpected extension", ext)
+	}
+	if ext := p.FindExtension(3); ext != nil {
+		t.Fatal("expected nil extension")
+	}
+}
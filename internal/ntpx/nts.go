@@ -0,0 +1,221 @@
+package ntpx
+
+//
+// Network Time Security (RFC 8915)
+//
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// NTSKEPort is the NTS key establishment port.
+const NTSKEPort = 4460
+
+// NTSKEALPN is the ALPN of the NTS key establishment protocol.
+const NTSKEALPN = "ntske/1"
+
+// NextProtocolNTPv4 is the NTS next protocol ID for NTPv4.
+const NextProtocolNTPv4 = 0
+
+// AEADAESSIVCMAC256 is the AEAD algorithm ID for AEAD_AES_SIV_CMAC_256.
+const AEADAESSIVCMAC256 = 15
+
+// These are the NTS key establishment record types.
+const (
+	RecordEndOfMessage           = 0
+	RecordNextProtocol           = 1
+	RecordError                  = 2
+	RecordWarning                = 3
+	RecordAEADAlgorithm          = 4
+	RecordNewCookie              = 5
+	RecordNTPv4ServerNegotiation = 6
+	RecordNTPv4PortNegotiation   = 7
+)
+
+// These are the NTS extension field types.
+const (
+	ExtensionUniqueIdentifier  = 0x0104
+	ExtensionCookie            = 0x0204
+	ExtensionCookiePlaceholder = 0x0304
+	ExtensionAuthenticator     = 0x0404
+)
+
+// maxRecords is the maximum number of records we read.
+const maxRecords = 64
+
+// nonceLength is the length of the nonces we generate.
+const nonceLength = 16
+
+var (
+	// ErrInvalidRecord indicates that we cannot parse an NTS key establishment record.
+	ErrInvalidRecord = errors.New("ntpx: invalid record")
+
+	// ErrTooManyRecords indicates that the peer sent too many records.
+	ErrTooManyRecords = errors.New("ntpx: too many records")
+
+	// ErrMissingAuthenticator indicates that an NTP packet does not contain
+	// the NTS authenticator and encrypted extension fields extension field.
+	ErrMissingAuthenticator = errors.New("ntpx: missing authenticator")
+)
+
+// Record is an NTS key establishment record.
+type Record struct {
+	// Critical indicates that the peer must understand the record.
+	Critical bool
+
+	// Type is the record type.
+	Type uint16
+
+	// Body is the record body.
+	Body []byte
+}
+
+// NewUint16Record creates a [*Record] containing a list of uint16 values.
+func NewUint16Record(critical bool, recordType uint16, values ...uint16) *Record {
+	body := []byte{}
+	for _, value := range values {
+		body = binary.BigEndian.AppendUint16(body, value)
+	}
+	return &Record{Critical: critical, Type: recordType, Body: body}
+}
+
+// Uint16s returns the record body as a list of uint16 values.
+func (r *Record) Uint16s() ([]uint16, error) {
+	if len(r.Body)%2 != 0 {
+		return nil, ErrInvalidRecord
+	}
+	values := []uint16{}
+	for idx := 0; idx < len(r.Body); idx += 2 {
+		values = append(values, binary.BigEndian.Uint16(r.Body[idx:idx+2]))
+	}
+	return values, nil
+}
+
+// MarshalRecords serializes the given records.
+func MarshalRecords(records ...*Record) []byte {
+	out := []byte{}
+	for _, record := range records {
+		recordType := record.Type & 0x7fff
+		if record.Critical {
+			recordType |= 0x8000
+		}
+		out = binary.BigEndian.AppendUint16(out, recordType)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(record.Body)))
+		out = append(out, record.Body...)
+	}
+	return out
+}
+
+// ReadRecords reads records until the end of message record, which
+// is not included in the returned list of records.
+func ReadRecords(r io.Reader) ([]*Record, error) {
+	records := []*Record{}
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		record := &Record{
+			Critical: header[0]&0x80 != 0,
+			Type:     binary.BigEndian.Uint16(header[:2]) & 0x7fff,
+			Body:     make([]byte, binary.BigEndian.Uint16(header[2:])),
+		}
+		if _, err := io.ReadFull(r, record.Body); err != nil {
+			return nil, err
+		}
+		if record.Type == RecordEndOfMessage {
+			return records, nil
+		}
+		if len(records) >= maxRecords {
+			return nil, ErrTooManyRecords
+		}
+		records = append(records, record)
+	}
+}
+
+// NewKERequest returns the records of a key establishment request
+// for NTPv4 using the AEAD_AES_SIV_CMAC_256 algorithm.
+func NewKERequest() []*Record {
+	return []*Record{
+		NewUint16Record(true, RecordNextProtocol, NextProtocolNTPv4),
+		NewUint16Record(false, RecordAEADAlgorithm, AEADAESSIVCMAC256),
+		{Critical: true, Type: RecordEndOfMessage},
+	}
+}
+
+// ExportKeys exports the client-to-server and the server-to-client keys for
+// NTPv4 and AEAD_AES_SIV_CMAC_256 from the given TLS connection state.
+func ExportKeys(state *tls.ConnectionState) (c2s, s2c []byte, err error) {
+	const label = "EXPORTER-network-time-security"
+	context := binary.BigEndian.AppendUint16(nil, NextProtocolNTPv4)
+	context = binary.BigEndian.AppendUint16(context, AEADAESSIVCMAC256)
+	if c2s, err = state.ExportKeyingMaterial(label, append(context, 0x00), SIVKeyLength); err != nil {
+		return nil, nil, err
+	}
+	if s2c, err = state.ExportKeyingMaterial(label, append(context, 0x01), SIVKeyLength); err != nil {
+		return nil, nil, err
+	}
+	return c2s, s2c, nil
+}
+
+// SealPacket serializes the packet and appends the NTS authenticator and encrypted
+// extension fields extension field, which authenticates the serialized packet and
+// contains the given encrypted extension fields.
+func SealPacket(p *Packet, key []byte, encrypted []Extension) ([]byte, error) {
+	data := p.Marshal()
+	nonce := make([]byte, nonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ciphertext, err := SIVSeal(key, nonce, appendExtensions(nil, encrypted), data)
+	if err != nil {
+		return nil, err
+	}
+	value := binary.BigEndian.AppendUint16(nil, uint16(len(nonce)))
+	value = binary.BigEndian.AppendUint16(value, uint16(len(ciphertext)))
+	value = append(value, nonce...)
+	value = append(value, make([]byte, padding(len(nonce)))...)
+	value = append(value, ciphertext...)
+	return appendExtensions(data, []Extension{{Type: ExtensionAuthenticator, Value: value}}), nil
+}
+
+// OpenPacket parses a packet sealed with [SealPacket] and returns the packet, which
+// only contains the extension fields preceding the authenticator, and the decrypted
+// extension fields. We ignore any extension field following the authenticator.
+func OpenPacket(data []byte, key []byte) (*Packet, []Extension, error) {
+	p, offsets, err := parsePacket(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	for idx, ext := range p.Extensions {
+		if ext.Type != ExtensionAuthenticator {
+			continue
+		}
+		if len(ext.Value) < 4 {
+			return nil, nil, ErrInvalidPacket
+		}
+		nonceLen := int(binary.BigEndian.Uint16(ext.Value[0:2]))
+		ciphertextLen := int(binary.BigEndian.Uint16(ext.Value[2:4]))
+		nonceEnd := 4 + nonceLen
+		ciphertextStart := nonceEnd + padding(nonceLen)
+		if ciphertextStart+ciphertextLen > len(ext.Value) {
+			return nil, nil, ErrInvalidPacket
+		}
+		plaintext, err := SIVOpen(key, ext.Value[4:nonceEnd],
+			ext.Value[ciphertextStart:ciphertextStart+ciphertextLen], data[:offsets[idx]])
+		if err != nil {
+			return nil, nil, err
+		}
+		inner, _, err := parsePacket(append(make([]byte, HeaderLength), plaintext...))
+		if err != nil {
+			return nil, nil, err
+		}
+		p.Extensions = p.Extensions[:idx]
+		return p, inner.Extensions, nil
+	}
+	return nil, nil, ErrMissingAuthenticator
+}
@@ -0,0 +1,201 @@
+package ntpx
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecords(t *testing.T) {
+	t.Run("we can roundtrip a key establishment request", func(t *testing.T) {
+		data := MarshalRecords(NewKERequest()...)
+		expect := []byte{
+			0x80, 0x01, 0x00, 0x02, 0x00, 0x00, // next protocol
+			0x00, 0x04, 0x00, 0x02, 0x00, 0x0f, // AEAD algorithm
+			0x80, 0x00, 0x00, 0x00, // end of message
+		}
+		if diff := cmp.Diff(expect, data); diff != "" {
+			t.Fatal(diff)
+		}
+		records, err := ReadRecords(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(NewKERequest()[:2], records); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we handle truncated records", func(t *testing.T) {
+		data := MarshalRecords(NewKERequest()...)
+		for _, length := range []int{0, 2, 5, 14} {
+			records, err := ReadRecords(bytes.NewReader(data[:length]))
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatal("unexpected error", err)
+			}
+			if records != nil {
+				t.Fatal("expected nil records")
+			}
+		}
+	})
+
+	t.Run("we limit the number of records", func(t *testing.T) {
+		records := []*Record{}
+		for idx := 0; idx <= maxRecords; idx++ {
+			records = append(records, &Record{Type: RecordNewCookie, Body: []byte("cookie")})
+		}
+		data := MarshalRecords(records...)
+		got, err := ReadRecords(bytes.NewReader(data))
+		if !errors.Is(err, ErrTooManyRecords) {
+			t.Fatal("unexpected error", err)
+		}
+		if got != nil {
+			t.Fatal("expected nil records")
+		}
+	})
+}
+
+func TestRecordUint16s(t *testing.T) {
+	t.Run("with a valid body", func(t *testing.T) {
+		values, err := NewUint16Record(false, RecordAEADAlgorithm, 15, 30).Uint16s()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]uint16{15, 30}, values); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with an odd body length", func(t *testing.T) {
+		values, err := (&Record{Body: []byte{0x00}}).Uint16s()
+		if !errors.Is(err, ErrInvalidRecord) {
+			t.Fatal("unexpected error", err)
+		}
+		if values != nil {
+			t.Fatal("expected nil values")
+		}
+	})
+}
+
+func TestExportKeys(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	config := srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	config.MinVersion = tls.VersionTLS13
+	conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	state := conn.ConnectionState()
+
+	c2s, s2c, err := ExportKeys(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c2s) != SIVKeyLength || len(s2c) != SIVKeyLength || bytes.Equal(c2s, s2c) {
+		t.Fatal("unexpected keys")
+	}
+	expect, err := state.ExportKeyingMaterial(
+		"EXPORTER-network-time-security", []byte{0x00, 0x00, 0x00, 0x0f, 0x01}, SIVKeyLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expect, s2c) {
+		t.Fatal("unexpected server-to-client key")
+	}
+}
+
+func TestSealOpenPacket(t *testing.T) {
+	key := bytes.Repeat([]byte{0x11}, SIVKeyLength)
+	request := &Packet{
+		Version: Version,
+		Mode:    ModeClient,
+		Extensions: []Extension{{
+			Type:  ExtensionUniqueIdentifier,
+			Value: bytes.Repeat([]byte{0x22}, 32),
+		}, {
+			Type:  ExtensionCookie,
+			Value: bytes.Repeat([]byte{0x33}, 64),
+		}},
+	}
+	encrypted := []Extension{{Type: ExtensionCookie, Value: bytes.Repeat([]byte{0x44}, 64)}}
+	data, err := SealPacket(request, key, encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("we can open a sealed packet", func(t *testing.T) {
+		p, inner, err := OpenPacket(data, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(request, p); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff(encrypted, inner); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we can open a sealed packet without encrypted extensions", func(t *testing.T) {
+		data, err := SealPacket(request, key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, inner, err := OpenPacket(data, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inner) != 0 {
+			t.Fatal("expected no encrypted extensions")
+		}
+	})
+
+	t.Run("we detect a tampered header", func(t *testing.T) {
+		tampered := append([]byte{}, data...)
+		tampered[1] = 1
+		p, inner, err := OpenPacket(tampered, key)
+		if !errors.Is(err, ErrAuthenticationFailed) {
+			t.Fatal("unexpected error", err)
+		}
+		if p != nil || inner != nil {
+			t.Fatal("expected nil results")
+		}
+	})
+
+	t.Run("we detect the wrong key", func(t *testing.T) {
+		_, _, err := OpenPacket(data, bytes.Repeat([]byte{0x55}, SIVKeyLength))
+		if !errors.Is(err, ErrAuthenticationFailed) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we detect a missing authenticator", func(t *testing.T) {
+		_, _, err := OpenPacket(request.Marshal(), key)
+		if !errors.Is(err, ErrMissingAuthenticator) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we detect an invalid authenticator", func(t *testing.T) {
+		p := &Packet{Extensions: []Extension{{Type: ExtensionAuthenticator, Value: []byte{0x00, 0x10, 0x00, 0x10}}}}
+		_, _, err := OpenPacket(p.Marshal(), key)
+		if !errors.Is(err, ErrInvalidPacket) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we detect a too short packet", func(t *testing.T) {
+		_, _, err := OpenPacket(data[:HeaderLength-1], key)
+		if !errors.Is(err, ErrInvalidPacket) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
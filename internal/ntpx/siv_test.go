@@ -0,0 +1,177 @@
+package ntpx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// mustDecodeHex decodes hex data ignoring spaces.
+func mustDecodeHex(s string) []byte {
+	data, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func TestSIV(t *testing.T) {
+	// testcase is a test case implemented by this function.
+	type testcase struct {
+		// name is the test case name
+		name string
+
+		// key is the key
+		key []byte
+
+		// components contains the associated data and the nonce
+		components [][]byte
+
+		// plaintext is the plaintext
+		plaintext []byte
+
+		// expect is the expected output
+		expect []byte
+	}
+
+	// See https://datatracker.ietf.org/doc/html/rfc5297#appendix-A
+	cases := []testcase{{
+		name: "RFC 5297 A.1 deterministic authenticated encryption",
+		key:  mustDecodeHex("fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff"),
+		components: [][]byte{
+			mustDecodeHex("10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627"),
+		},
+		plaintext: mustDecodeHex("11223344 55667788 99aabbcc ddee"),
+		expect:    mustDecodeHex("85632d07 c6e8f37f 950acd32 0a2ecc93 40c02b96 90c4dc04 daef7f6a fe5c"),
+	}, {
+		name: "RFC 5297 A.2 nonce-based authenticated encryption",
+		key:  mustDecodeHex("7f7e7d7c 7b7a7978 77767574 73727170 40414243 44454647 48494a4b 4c4d4e4f"),
+		components: [][]byte{
+			mustDecodeHex("00112233 44556677 8899aabb ccddeeff deaddada deaddada ffeeddcc bbaa9988 77665544 33221100"),
+			mustDecodeHex("10203040 50607080 90a0"),
+			mustDecodeHex("09f91102 9d74e35b d84156c5 635688c0"),
+		},
+		plaintext: mustDecodeHex("74686973 20697320 736f6d65 20706c61 696e7465 78742074 6f20656e 63727970 74207573 696e6720 5349562d 414553"),
+		expect:    mustDecodeHex("7bdb6e3b 432667eb 06f4d14b ff2fbd0f cb900f2f ddbe4043 26601965 c889bf17 dba77ceb 094fa663 b7a3f748 ba8af829 ea64ad54 4a272e9c 485b62a3 fd5c0d"),
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := sivEncrypt(tc.key, tc.plaintext, tc.components...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(output, tc.expect) {
+				t.Fatalf("expected %x, got %x", tc.expect, output)
+			}
+			plaintext, err := sivDecrypt(tc.key, output, tc.components...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(plaintext, tc.plaintext) {
+				t.Fatalf("expected %x, got %x", tc.plaintext, plaintext)
+			}
+		})
+	}
+}
+
+func TestSIVSealOpen(t *testing.T) {
+	key := bytes.Repeat([]byte{0x11}, SIVKeyLength)
+	nonce := bytes.Repeat([]byte{0x22}, 16)
+	ad := []byte("associated data")
+	plaintext := []byte("the quick brown fox jumps over the lazy dog")
+
+	ciphertext, err := SIVSeal(key, nonce, plaintext, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("we can open what we sealed", func(t *testing.T) {
+		output, err := SIVOpen(key, nonce, ciphertext, ad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(output, plaintext) {
+			t.Fatal("unexpected plaintext", string(output))
+		}
+	})
+
+	t.Run("we can seal an empty plaintext", func(t *testing.T) {
+		ciphertext, err := SIVSeal(key, nonce, nil, ad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output, err := SIVOpen(key, nonce, ciphertext, ad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(output) != 0 {
+			t.Fatal("expected empty plaintext")
+		}
+	})
+
+	t.Run("we detect tampering", func(t *testing.T) {
+		type testcase struct {
+			name       string
+			key        []byte
+			nonce      []byte
+			ciphertext []byte
+			ad         []byte
+		}
+		tampered := append([]byte{}, ciphertext...)
+		tampered[len(tampered)-1] ^= 0x01
+		cases := []testcase{{
+			name:       "with a different key",
+			key:        bytes.Repeat([]byte{0x33}, SIVKeyLength),
+			nonce:      nonce,
+			ciphertext: ciphertext,
+			ad:         ad,
+		}, {
+			name:       "with a different nonce",
+			key:        key,
+			nonce:      bytes.Repeat([]byte{0x33}, 16),
+			ciphertext: ciphertext,
+			ad:         ad,
+		}, {
+			name:       "with tampered ciphertext",
+			key:        key,
+			nonce:      nonce,
+			ciphertext: tampered,
+			ad:         ad,
+		}, {
+			name:       "with different associated data",
+			key:        key,
+			nonce:      nonce,
+			ciphertext: ciphertext,
+			ad:         []byte("other data"),
+		}, {
+			name:       "with truncated ciphertext",
+			key:        key,
+			nonce:      nonce,
+			ciphertext: ciphertext[:8],
+			ad:         ad,
+		}}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				output, err := SIVOpen(tc.key, tc.nonce, tc.ciphertext, tc.ad)
+				if !errors.Is(err, ErrAuthenticationFailed) {
+					t.Fatal("unexpected error", err)
+				}
+				if output != nil {
+					t.Fatal("expected nil output")
+				}
+			})
+		}
+	})
+
+	t.Run("we reject keys with invalid length", func(t *testing.T) {
+		if _, err := SIVSeal(key[:16], nonce, plaintext, ad); !errors.Is(err, errInvalidKeyLength) {
+			t.Fatal("unexpected error", err)
+		}
+		if _, err := SIVOpen(key[:16], nonce, ciphertext, ad); !errors.Is(err, errInvalidKeyLength) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
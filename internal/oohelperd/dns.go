@@ -9,14 +9,14 @@ import (
 	"sync"
 	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 // newfailure is a convenience shortcut to save typing.
-var newfailure = tracex.NewFailure
+var newfailure = measurexlite.NewFailure
 
 // ctrlDNSResult is the result returned by the [dnsDo] function and
 // included into the response sent to the client.
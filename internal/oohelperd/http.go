@@ -13,7 +13,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/logx"
 	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -239,7 +238,7 @@ func parseAltSvc(resp *http.Response) string {
 // See https://github.com/ooni/backend/blob/6ec4fda5b18/oonib/testhelpers/http_helpers.py#L361
 func httpMapFailure(err error) *string {
 	failure := newfailure(err)
-	failedOperation := tracex.NewFailedOperation(err)
+	failedOperation := measurexlite.NewFailedOperation(err)
 	switch failure {
 	case nil:
 		return nil
@@ -0,0 +1,152 @@
+package oonirun
+
+//
+// Checkpointing runs to resume them
+//
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// RunCheckpoint is the state of a run with several inputs (e.g., Web Connectivity)
+// that we store to resume the run after an interruption.
+type RunCheckpoint struct {
+	// ID identifies the run configuration, such that we do not resume a run using
+	// a checkpoint created by a different configuration (see [NewRunCheckpointID]).
+	ID string `json:"id"`
+
+	// NextIndex is the index of the first target we have not measured yet.
+	NextIndex int `json:"next_index"`
+
+	// ReportID is the OPTIONAL report ID of the measurements we have already
+	// measured, which we reuse on resume such that the whole run belongs to the
+	// same report rather than to a new report for each interruption.
+	ReportID string `json:"report_id,omitempty"`
+
+	// ResultID is the OPTIONAL ooniprobe database result ID.
+	ResultID int64 `json:"result_id,omitempty"`
+
+	// Targets contains the targets we loaded when we started the run.
+	Targets []*model.OOAPIURLInfo `json:"targets"`
+
+	// TestName is the name of the experiment.
+	TestName string `json:"test_name"`
+
+	// UpdatedAt is the last time we updated the checkpoint.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewRunCheckpointID returns the ID of a run given the values describing its
+// configuration (e.g., the experiment name, the options, and the inputs).
+func NewRunCheckpointID(values ...any) string {
+	data, err := json.Marshal(values)
+	runtimex.PanicOnError(err, "json.Marshal unexpectedly failed")
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
+
+// NewRunCheckpoint creates a new [*RunCheckpoint] for the given targets. This
+// function returns false when we cannot checkpoint the run, because some targets
+// are not [*model.OOAPIURLInfo], which is what Web Connectivity uses.
+func NewRunCheckpoint(ID, testName string, targets []model.ExperimentTarget) (*RunCheckpoint, bool) {
+	cp := &RunCheckpoint{
+		ID:        ID,
+		NextIndex: 0,
+		ReportID:  "",
+		ResultID:  0,
+		Targets:   []*model.OOAPIURLInfo{},
+		TestName:  testName,
+		UpdatedAt: time.Now(),
+	}
+	for _, target := range targets {
+		entry, ok := target.(*model.OOAPIURLInfo)
+		if !ok {
+			return nil, false
+		}
+		cp.Targets = append(cp.Targets, entry)
+	}
+	return cp, true
+}
+
+// AllTargets returns all the targets of the run.
+func (cp *RunCheckpoint) AllTargets() (out []model.ExperimentTarget) {
+	for _, target := range cp.Targets {
+		out = append(out, target)
+	}
+	return
+}
+
+// Done returns whether we measured all the targets.
+func (cp *RunCheckpoint) Done() bool {
+	return cp.NextIndex >= len(cp.Targets)
+}
+
+// RunCheckpointKey returns the key where we store the checkpoint of the given run.
+func RunCheckpointKey(name string) string {
+	return "runcheckpoint." + name + ".state"
+}
+
+// ErrNoRunCheckpoint indicates that there is no checkpoint to resume from.
+var ErrNoRunCheckpoint = errors.New("oonirun: no run checkpoint")
+
+// RunCheckpointStore stores a [*RunCheckpoint] into a key-value store.
+//
+// The zero value of this structure IS NOT valid and you MUST initialize
+// all the fields marked as MANDATORY before using this structure.
+type RunCheckpointStore struct {
+	// KVStore is the MANDATORY key-value store.
+	KVStore model.KeyValueStore
+
+	// Key is the MANDATORY key (see [RunCheckpointKey]).
+	Key string
+}
+
+// Load loads the checkpoint. When there is no checkpoint or the checkpoint
+// is unusable, this function returns an error wrapping [ErrNoRunCheckpoint].
+func (s *RunCheckpointStore) Load() (*RunCheckpoint, error) {
+	data, err := s.KVStore.Get(s.Key)
+	if err != nil || len(data) <= 0 {
+		return nil, ErrNoRunCheckpoint
+	}
+	var cp RunCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, ErrNoRunCheckpoint
+	}
+	if cp.NextIndex < 0 || cp.NextIndex > len(cp.Targets) {
+		return nil, ErrNoRunCheckpoint
+	}
+	return &cp, nil
+}
+
+// LoadForID is like Load but only returns checkpoints with the given ID
+// that have not measured all the targets yet.
+func (s *RunCheckpointStore) LoadForID(ID string) (*RunCheckpoint, error) {
+	cp, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	if cp.ID != ID || cp.Done() {
+		return nil, ErrNoRunCheckpoint
+	}
+	return cp, nil
+}
+
+// Save saves the checkpoint.
+func (s *RunCheckpointStore) Save(cp *RunCheckpoint) error {
+	cp.UpdatedAt = time.Now()
+	data, err := json.Marshal(cp)
+	runtimex.PanicOnError(err, "json.Marshal unexpectedly failed")
+	return s.KVStore.Set(s.Key, data)
+}
+
+// Clear removes the checkpoint.
+func (s *RunCheckpointStore) Clear() error {
+	return s.KVStore.Set(s.Key, nil)
+}
@@ -0,0 +1,108 @@
+package oonirun
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestNewRunCheckpoint(t *testing.T) {
+	t.Run("with URL targets", func(t *testing.T) {
+		targets := []model.ExperimentTarget{
+			model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("https://a.com/"),
+			model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("https://b.com/"),
+		}
+		cp, good := NewRunCheckpoint("id", "web_connectivity", targets)
+		if !good {
+			t.Fatal("expected to be able to checkpoint")
+		}
+		if len(cp.AllTargets()) != 2 || cp.NextIndex != 0 || cp.Done() {
+			t.Fatal("unexpected checkpoint", cp)
+		}
+	})
+
+	t.Run("with other targets", func(t *testing.T) {
+		type otherTarget struct {
+			model.ExperimentTarget
+		}
+		targets := []model.ExperimentTarget{&otherTarget{}}
+		if _, good := NewRunCheckpoint("id", "web_connectivity", targets); good {
+			t.Fatal("expected to be unable to checkpoint")
+		}
+	})
+}
+
+func TestNewRunCheckpointID(t *testing.T) {
+	if NewRunCheckpointID("a", []string{"b"}) != NewRunCheckpointID("a", []string{"b"}) {
+		t.Fatal("expected the same ID for the same values")
+	}
+	if NewRunCheckpointID("a", []string{"b"}) == NewRunCheckpointID("a", []string{"c"}) {
+		t.Fatal("expected different IDs for different values")
+	}
+}
+
+func TestRunCheckpointStore(t *testing.T) {
+	newCheckpoint := func() *RunCheckpoint {
+		targets := []model.ExperimentTarget{
+			model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("https://a.com/"),
+			model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("https://b.com/"),
+		}
+		cp, _ := NewRunCheckpoint("id", "web_connectivity", targets)
+		return cp
+	}
+
+	t.Run("Save, LoadForID, and Clear", func(t *testing.T) {
+		store := &RunCheckpointStore{KVStore: &kvstore.Memory{}, Key: RunCheckpointKey("x")}
+		if _, err := store.LoadForID("id"); !errors.Is(err, ErrNoRunCheckpoint) {
+			t.Fatal("unexpected error", err)
+		}
+		cp := newCheckpoint()
+		cp.NextIndex = 1
+		if err := store.Save(cp); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := store.LoadForID("id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded.NextIndex != 1 || len(loaded.Targets) != 2 || loaded.Targets[1].URL != "https://b.com/" {
+			t.Fatal("unexpected checkpoint", loaded)
+		}
+		if _, err := store.LoadForID("other"); !errors.Is(err, ErrNoRunCheckpoint) {
+			t.Fatal("unexpected error", err)
+		}
+		if err := store.Clear(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Load(); !errors.Is(err, ErrNoRunCheckpoint) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("LoadForID ignores completed runs", func(t *testing.T) {
+		store := &RunCheckpointStore{KVStore: &kvstore.Memory{}, Key: RunCheckpointKey("x")}
+		cp := newCheckpoint()
+		cp.NextIndex = 2
+		if err := store.Save(cp); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.LoadForID("id"); !errors.Is(err, ErrNoRunCheckpoint) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("Load ignores invalid checkpoints", func(t *testing.T) {
+		for _, data := range []string{"{", `{"next_index":3,"targets":[]}`, `{"next_index":-1}`} {
+			kvs := &kvstore.Memory{}
+			if err := kvs.Set(RunCheckpointKey("x"), []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+			store := &RunCheckpointStore{KVStore: kvs, Key: RunCheckpointKey("x")}
+			if _, err := store.Load(); !errors.Is(err, ErrNoRunCheckpoint) {
+				t.Fatal("unexpected error", data, err)
+			}
+		}
+	})
+}
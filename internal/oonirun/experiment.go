@@ -8,11 +8,13 @@ import (
 	"context"
 	"encoding/json"
 	"math/rand"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/humanize"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 )
 
 // experimentShuffledInputs counts how many times we shuffled inputs
@@ -24,12 +26,21 @@ type Experiment struct {
 	// Annotations contains OPTIONAL Annotations for the experiment.
 	Annotations map[string]string
 
+	// Checkpoints is the OPTIONAL key-value store where we checkpoint runs with
+	// several inputs (e.g., Web Connectivity runs), such that we can resume them.
+	Checkpoints model.KeyValueStore
+
 	// ExtraOptions contains OPTIONAL extra options that modify the
 	// default experiment-specific configuration. We apply
 	// the changes described by this field after using the InitialOptions
 	// field to initialize the experiment-specific configuration.
 	ExtraOptions map[string]any
 
+	// FollowUps OPTIONALLY indicates we should automatically run targeted
+	// follow-up experiments on inputs for which Web Connectivity detects
+	// "dns" or "http-failure" blocking (see [FollowUpRunner]).
+	FollowUps bool
+
 	// InitialOptions contains an OPTIONAL [json.RawMessage] object
 	// used to initialize the default experiment-specific
 	// configuration. After we have initialized the configuration
@@ -62,6 +73,10 @@ type Experiment struct {
 	// NoJSON OPTIONALLY indicates we don't want to save measurements to a JSON file.
 	NoJSON bool
 
+	// Parallelism is the OPTIONAL maximum number of inputs to measure
+	// concurrently. Zero or one means we measure inputs sequentially.
+	Parallelism int64
+
 	// Random OPTIONALLY indicates we should randomize inputs.
 	Random bool
 
@@ -69,9 +84,26 @@ type Experiment struct {
 	// used when noJSON is set to false.
 	ReportFile string
 
+	// Resume OPTIONALLY indicates we should resume the latest interrupted run of
+	// this experiment with the same configuration by skipping the targets we have
+	// already measured. This setting has no effect unless Checkpoints is set.
+	Resume bool
+
 	// Session is the MANDATORY session.
 	Session Session
 
+	// TargetFilter contains OPTIONAL rules to filter and sample the targets.
+	TargetFilter *model.ExperimentTargetFilter
+
+	// TestLists contains OPTIONAL test lists to read targets from, which,
+	// unlike InputFilePaths, carry category and country codes.
+	TestLists []string
+
+	// SubmitQueue is the OPTIONAL queue where we store the measurements we could
+	// not submit. When set, we also retry submitting the queued measurements in
+	// the background while running the experiment.
+	SubmitQueue *submitqueue.Queue
+
 	// newExperimentBuilderFn is OPTIONAL and used for testing.
 	newExperimentBuilderFn func(experimentName string) (model.ExperimentBuilder, error)
 
@@ -106,15 +138,24 @@ func (ed *Experiment) Run(ctx context.Context) error {
 		return err
 	}
 
-	// 3. create target loader and load targets for this experiment
-	targetLoader := ed.newTargetLoader(builder)
-	targetList, err := targetLoader.Load(ctx)
-	if err != nil {
-		return err
+	// 3. create target loader and load targets for this experiment, unless
+	// we are resuming a run, in which case we reuse the run's targets
+	checkpoints, checkpoint := ed.loadCheckpoint()
+	var targetList []model.ExperimentTarget
+	if checkpoint != nil {
+		targetList = checkpoint.AllTargets()
+		ed.Session.Logger().Infof("oonirun: resuming %s from input %d/%d", ed.Name,
+			checkpoint.NextIndex+1, len(targetList))
+	} else {
+		targetLoader := ed.newTargetLoader(builder)
+		targetList, err = targetLoader.Load(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
 	// 4. randomize input, if needed
-	if ed.Random {
+	if ed.Random && checkpoint == nil {
 		// Note: since go1.20 the default random generator is randomly seeded
 		//
 		// See https://tip.golang.org/doc/go1.20
@@ -146,11 +187,96 @@ func (ed *Experiment) Run(ctx context.Context) error {
 		return err
 	}
 
+	// 7.1. while running, retry submitting previously queued measurements
+	if uploader := newSubmitQueueUploader(submitter, logger); uploader != nil {
+		if depth := uploader.Queue.Len(); depth > 0 {
+			logger.Infof("submitqueue: %d measurements queued for submission", depth)
+		}
+		if dropped := uploader.Queue.Dropped(); dropped > 0 {
+			logger.Warnf("submitqueue: %d measurements dropped because the queue was full", dropped)
+		}
+		uploaderCtx, uploaderCancel := context.WithCancel(ctx)
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uploader.Run(uploaderCtx)
+		}()
+		defer wg.Wait()
+		defer uploaderCancel()
+	}
+
+	// 7.2. checkpoint the run, if possible, skipping the targets we already measured
+	var checkpointSaver *experimentCheckpointSaver
+	if checkpoints != nil {
+		if checkpoint == nil {
+			checkpoint, _ = NewRunCheckpoint(ed.checkpointID(), ed.Name, targetList)
+		}
+		if checkpoint != nil {
+			targetList = targetList[checkpoint.NextIndex:]
+			checkpointSaver = &experimentCheckpointSaver{
+				checkpoint: checkpoint,
+				child:      nil, // set by newInputProcessor
+				logger:     logger,
+				offset:     checkpoint.NextIndex,
+				store:      checkpoints,
+			}
+		}
+	}
+
+	// 7.3. run follow-ups in the background, if needed, sharing the saver
+	var followUps InputProcessorFollowUpScheduler
+	if ed.FollowUps {
+		saver = &followUpLockedSaver{saver: saver}
+		runner := NewFollowUpRunner(ctx, ed, saver)
+		defer runner.Close()
+		followUps = runner
+	}
+
 	// 8. create an input processor
-	inputProcessor := ed.newInputProcessor(experiment, targetList, saver, submitter)
+	inputProcessor := ed.newInputProcessor(experiment, targetList, saver, submitter, checkpointSaver, followUps)
 
 	// 9. process input and generate measurements
-	return inputProcessor.Run(ctx)
+	if err := inputProcessor.Run(ctx); err != nil {
+		return err // keep the checkpoint, if any, such that we can resume
+	}
+
+	// 10. when the run is complete, there is nothing to resume (note that the
+	// run is not complete when we stopped early because of MaxRuntime)
+	if checkpoints != nil && checkpoint != nil && checkpoint.Done() {
+		if err := checkpoints.Clear(); err != nil {
+			logger.Warnf("oonirun: cannot clear checkpoint: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// checkpointID returns the ID identifying this experiment's configuration.
+func (ed *Experiment) checkpointID() string {
+	return NewRunCheckpointID(ed.Name, string(ed.InitialOptions), ed.ExtraOptions,
+		ed.Inputs, ed.InputFilePaths, ed.TestLists, ed.TargetFilter)
+}
+
+// loadCheckpoint returns the store where to checkpoint the run, which is nil when we
+// cannot checkpoint, and the checkpoint to resume from, which is nil when we're not
+// resuming or there is no suitable checkpoint for this experiment's configuration.
+func (ed *Experiment) loadCheckpoint() (*RunCheckpointStore, *RunCheckpoint) {
+	if ed.Checkpoints == nil {
+		return nil, nil
+	}
+	store := &RunCheckpointStore{
+		KVStore: ed.Checkpoints,
+		Key:     RunCheckpointKey(ed.Name),
+	}
+	if !ed.Resume {
+		return store, nil
+	}
+	checkpoint, err := store.LoadForID(ed.checkpointID())
+	if err != nil {
+		ed.Session.Logger().Infof("oonirun: no interrupted %s run to resume", ed.Name)
+		return store, nil
+	}
+	return store, checkpoint
 }
 
 func (ed *Experiment) setOptions(builder model.ExperimentBuilder) error {
@@ -167,21 +293,36 @@ func (ed *Experiment) setOptions(builder model.ExperimentBuilder) error {
 type inputProcessor = model.ExperimentInputProcessor
 
 // newInputProcessor creates a new inputProcessor instance.
+//
+// When checkpointSaver is not nil, we use it to checkpoint the run after saving
+// each measurement and we reuse the report ID of the run we're resuming, if any.
+// When followUps is not nil, we use it to schedule follow-up measurements.
 func (ed *Experiment) newInputProcessor(experiment model.Experiment,
-	inputList []model.ExperimentTarget, saver model.Saver, submitter model.Submitter) inputProcessor {
+	inputList []model.ExperimentTarget, saver model.Saver, submitter model.Submitter,
+	checkpointSaver *experimentCheckpointSaver, followUps InputProcessorFollowUpScheduler) inputProcessor {
 	if ed.newInputProcessorFn != nil {
 		return ed.newInputProcessorFn(experiment, inputList, saver, submitter)
 	}
+	saverWrapper := NewInputProcessorSaverWrapper(saver)
+	var reportID string
+	if checkpointSaver != nil {
+		checkpointSaver.child = saverWrapper
+		saverWrapper = checkpointSaver
+		reportID = checkpointSaver.checkpoint.ReportID
+	}
 	return &InputProcessor{
 		Annotations: ed.Annotations,
 		Experiment: &experimentWrapper{
-			child:  NewInputProcessorExperimentWrapper(experiment),
-			logger: ed.Session.Logger(),
-			total:  len(inputList),
+			child:    NewInputProcessorExperimentWrapper(experiment),
+			logger:   ed.Session.Logger(),
+			reportID: reportID,
+			total:    len(inputList),
 		},
-		Inputs:     inputList,
-		MaxRuntime: time.Duration(ed.MaxRuntime) * time.Second,
-		Saver:      NewInputProcessorSaverWrapper(saver),
+		FollowUps:   followUps,
+		Inputs:      inputList,
+		MaxRuntime:  time.Duration(ed.MaxRuntime) * time.Second,
+		Parallelism: int(ed.Parallelism),
+		Saver:       saverWrapper,
 		Submitter: &experimentSubmitterWrapper{
 			child:  NewInputProcessorSubmitterWrapper(submitter),
 			logger: ed.Session.Logger(),
@@ -210,6 +351,7 @@ func (ed *Experiment) newSubmitter(ctx context.Context) (model.Submitter, error)
 		Enabled: !ed.NoCollector,
 		Session: ed.Session,
 		Logger:  ed.Session.Logger(),
+		Queue:   ed.SubmitQueue,
 		// oonirun is only used by the miniooni CLI, which submits with a
 		// credential by default; oonimkall does not go through oonirun.
 		UseAuth: !ed.NoCredentials,
@@ -242,6 +384,8 @@ func (ed *Experiment) newTargetLoader(builder model.ExperimentBuilder) targetLoa
 		StaticInputsConfig: ed.InputsExtra,
 		SourceFiles:        ed.InputFilePaths,
 		Session:            ed.Session,
+		TargetFilter:       ed.TargetFilter,
+		TestLists:          ed.TestLists,
 	})
 }
 
@@ -253,6 +397,9 @@ type experimentWrapper struct {
 	// logger is the logger to use
 	logger model.Logger
 
+	// reportID is the OPTIONAL report ID of the run we're resuming
+	reportID string
+
 	// total is the total number of inputs
 	total int
 }
@@ -262,7 +409,51 @@ func (ew *experimentWrapper) MeasureWithContext(
 	if target.Input() != "" {
 		ew.logger.Infof("[%d/%d] running with input: %s", idx+1, ew.total, target)
 	}
-	return ew.child.MeasureWithContext(ctx, target, idx)
+	meas, err := ew.child.MeasureWithContext(ctx, target, idx)
+	if err == nil && meas.ReportID == "" {
+		meas.ReportID = ew.reportID
+	}
+	return meas, err
+}
+
+// experimentCheckpointSaver is an [InputProcessorSaverWrapper] that updates the
+// checkpoint after saving each measurement, which works because the [*InputProcessor]
+// saves the measurements in input order and stops when saving fails.
+type experimentCheckpointSaver struct {
+	// checkpoint is the checkpoint to update.
+	checkpoint *RunCheckpoint
+
+	// child is the underlying saver.
+	child InputProcessorSaverWrapper
+
+	// logger is the logger to use.
+	logger model.Logger
+
+	// offset is the index within the checkpoint's targets of the first target we measure.
+	offset int
+
+	// store is where we store the checkpoint.
+	store *RunCheckpointStore
+}
+
+var _ InputProcessorSaverWrapper = &experimentCheckpointSaver{}
+
+// SaveMeasurement implements InputProcessorSaverWrapper.
+func (cs *experimentCheckpointSaver) SaveMeasurement(idx int, m *model.Measurement) error {
+	if err := cs.child.SaveMeasurement(idx, m); err != nil {
+		return err
+	}
+	// Note: we use the index of the target we processed rather than counting the
+	// measurements we saved, which would not account for skipped targets
+	cs.checkpoint.NextIndex = cs.offset + idx + 1
+	if m.ReportID != "" {
+		cs.checkpoint.ReportID = m.ReportID
+	}
+	if err := cs.store.Save(cs.checkpoint); err != nil {
+		// policy: failing to checkpoint does not stop the run
+		cs.logger.Warnf("oonirun: cannot save checkpoint: %s", err.Error())
+	}
+	return nil
 }
 
 // experimentSubmitterWrapper implements a submission policy where we don't
@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/testingx"
@@ -486,3 +487,185 @@ func TestExperimentRun(t *testing.T) {
 		})
 	}
 }
+
+func TestExperimentRunResume(t *testing.T) {
+	var (
+		delay     time.Duration
+		failInput string
+		loaded    int
+		reportID  string
+		saved     []string
+		savedIDs  []string
+	)
+	kvStore := &kvstore.Memory{}
+	newExperiment := func(resume bool) *Experiment {
+		return &Experiment{
+			Checkpoints: kvStore,
+			Name:        "example",
+			NoCollector: true,
+			NoJSON:      true,
+			Resume:      resume,
+			Session: &mocks.Session{
+				MockNewExperimentBuilder: func(name string) (model.ExperimentBuilder, error) {
+					eb := &mocks.ExperimentBuilder{
+						MockInputPolicy: func() model.InputPolicy {
+							return model.InputOptional
+						},
+						MockSetOptionsJSON: func(value json.RawMessage) error {
+							return nil
+						},
+						MockSetOptionsAny: func(options map[string]any) error {
+							return nil
+						},
+						MockNewExperiment: func() model.Experiment {
+							return &mocks.Experiment{
+								MockMeasureWithContext: func(
+									ctx context.Context, target model.ExperimentTarget) (*model.Measurement, error) {
+									if target.Input() == failInput {
+										return nil, errors.New("mocked error")
+									}
+									time.Sleep(delay)
+									return &model.Measurement{
+										Input:    model.MeasurementInput(target.Input()),
+										ReportID: reportID,
+									}, nil
+								},
+								MockKibiBytesReceived: func() float64 {
+									return 0
+								},
+								MockKibiBytesSent: func() float64 {
+									return 0
+								},
+							}
+						},
+						MockNewTargetLoader: func(config *model.ExperimentTargetLoaderConfig) model.ExperimentTargetLoader {
+							return &mocks.ExperimentTargetLoader{
+								MockLoad: func(ctx context.Context) ([]model.ExperimentTarget, error) {
+									loaded++
+									return []model.ExperimentTarget{
+										model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("a"),
+										model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("b"),
+										model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("c"),
+									}, nil
+								},
+							}
+						},
+					}
+					return eb, nil
+				},
+				MockLogger: func() model.Logger {
+					return model.DiscardLogger
+				},
+			},
+			newSaverFn: func() (model.Saver, error) {
+				return &mocks.Saver{
+					MockSaveMeasurement: func(m *model.Measurement) error {
+						saved = append(saved, string(m.Input))
+						savedIDs = append(savedIDs, m.ReportID)
+						return nil
+					},
+				}, nil
+			},
+		}
+	}
+	store := &RunCheckpointStore{KVStore: kvStore, Key: RunCheckpointKey("example")}
+
+	// the first run is interrupted while measuring "b"
+	failInput = "b"
+	reportID = "20261019T000000Z_example_IT_30722_n1_abc"
+	if err := newExperiment(false).Run(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	cp, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.NextIndex != 1 || len(cp.Targets) != 3 || cp.ReportID != reportID {
+		t.Fatal("unexpected checkpoint", cp)
+	}
+
+	// resuming skips "a" without loading the targets again and reuses the report ID
+	failInput = ""
+	reportID = ""
+	saved, savedIDs = nil, nil
+	if err := newExperiment(true).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"b", "c"}, saved); diff != "" {
+		t.Fatal(diff)
+	}
+	expectIDs := []string{cp.ReportID, cp.ReportID}
+	if diff := cmp.Diff(expectIDs, savedIDs); diff != "" {
+		t.Fatal(diff)
+	}
+	if loaded != 1 {
+		t.Fatal("unexpected number of loads", loaded)
+	}
+
+	// once the run is complete, there is nothing left to resume
+	if _, err := store.Load(); !errors.Is(err, ErrNoRunCheckpoint) {
+		t.Fatal("unexpected error", err)
+	}
+	saved = nil
+	if err := newExperiment(true).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a", "b", "c"}, saved); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// stopping early because of MaxRuntime keeps the checkpoint
+	saved = nil
+	delay = 1100 * time.Millisecond
+	exp := newExperiment(false)
+	exp.MaxRuntime = 1
+	if err := exp.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a"}, saved); diff != "" {
+		t.Fatal(diff)
+	}
+	cp, err = store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.NextIndex != 1 {
+		t.Fatal("unexpected checkpoint", cp)
+	}
+}
+
+func TestExperimentCheckpointSaver(t *testing.T) {
+	kvStore := &kvstore.Memory{}
+	store := &RunCheckpointStore{KVStore: kvStore, Key: RunCheckpointKey("example")}
+	checkpoint, _ := NewRunCheckpoint("xx", "example", []model.ExperimentTarget{
+		model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("a"),
+		model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("b"),
+		model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("c"),
+		model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("d"),
+	})
+	checkpoint.NextIndex = 1
+	cs := &experimentCheckpointSaver{
+		checkpoint: checkpoint,
+		child: NewInputProcessorSaverWrapper(&mocks.Saver{
+			MockSaveMeasurement: func(m *model.Measurement) error {
+				return nil
+			},
+		}),
+		logger: model.DiscardLogger,
+		offset: 1,
+		store:  store,
+	}
+
+	// the checkpoint records the index of the processed target, so saving the
+	// measurement of "d" after skipping "c" means we have nothing left to measure
+	if err := cs.SaveMeasurement(2, &model.Measurement{ReportID: "report"}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.NextIndex != 4 || !loaded.Done() || loaded.ReportID != "report" {
+		t.Fatal("unexpected checkpoint", loaded)
+	}
+}
@@ -0,0 +1,388 @@
+package oonirun
+
+//
+// Automatic follow-up measurements.
+//
+// When Web Connectivity detects an anomaly, we can immediately run targeted
+// experiments on the same input, which helps to characterize the blocking
+// without requiring a human to notice and manually run more experiments.
+//
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// These are the annotations we add to follow-up measurements to link
+// them to the parent measurement that triggered them.
+const (
+	// FollowUpAnnotationParentMeasurementUID contains the measurement UID
+	// assigned by the OONI backend to the parent measurement. We only set
+	// this annotation when we successfully submitted the parent.
+	FollowUpAnnotationParentMeasurementUID = "followup_parent_measurement_uid"
+
+	// FollowUpAnnotationParentReportID contains the parent's report ID.
+	FollowUpAnnotationParentReportID = "followup_parent_report_id"
+
+	// FollowUpAnnotationParentInput contains the parent's input.
+	FollowUpAnnotationParentInput = "followup_parent_input"
+
+	// FollowUpAnnotationReason contains the anomaly that triggered
+	// the follow-up (e.g., "dns", "http-failure").
+	FollowUpAnnotationReason = "followup_reason"
+)
+
+// followUpDNSResolvers contains the resolvers we use with dnscheck when
+// following up on a DNS anomaly. We use cleartext as well as encrypted
+// resolvers to understand whether the blocking is specific to Do53.
+var followUpDNSResolvers = []string{
+	"udp://8.8.8.8:53",
+	"udp://1.1.1.1:53",
+	"dot://8.8.8.8:853",
+	"https://dns.google/dns-query",
+	"https://cloudflare-dns.com/dns-query",
+}
+
+// followUpSpec describes a follow-up experiment.
+type followUpSpec struct {
+	// ExtraOptions contains the experiment options.
+	ExtraOptions map[string]any
+
+	// Inputs contains the inputs to measure.
+	Inputs []string
+
+	// Name is the experiment name.
+	Name string
+}
+
+// followUpTestKeys contains the Web Connectivity test keys we use to
+// decide which follow-ups to run.
+type followUpTestKeys struct {
+	// Blocking is the blocking reason or false.
+	Blocking any `json:"blocking"`
+
+	// TLSHandshakes contains the TLS handshakes results.
+	TLSHandshakes []struct {
+		Failure *string `json:"failure"`
+	} `json:"tls_handshakes"`
+}
+
+// followUpParseTestKeys returns the Web Connectivity test keys or nil if the
+// measurement is not a Web Connectivity measurement or we cannot parse them.
+func followUpParseTestKeys(meas *model.Measurement) *followUpTestKeys {
+	if meas.TestName != "web_connectivity" || meas.TestKeys == nil {
+		return nil
+	}
+	// Note: we serialize the test keys to avoid depending on the concrete
+	// test keys type, which differs between Web Connectivity versions.
+	data, err := json.Marshal(meas.TestKeys)
+	if err != nil {
+		return nil
+	}
+	var tk followUpTestKeys
+	if err := json.Unmarshal(data, &tk); err != nil {
+		return nil
+	}
+	return &tk
+}
+
+// blockingReason returns the blocking reason or an empty string if
+// the test keys do not contain a string blocking reason.
+func (tk *followUpTestKeys) blockingReason() string {
+	reason, _ := tk.Blocking.(string)
+	return reason
+}
+
+// tlsHandshakeFailed returns whether any TLS handshake failed, which happens, e.g.,
+// when a middlebox resets the connection after seeing the SNI. Note that Web
+// Connectivity reports these failures as "http-failure" blocking.
+func (tk *followUpTestKeys) tlsHandshakeFailed() bool {
+	for _, entry := range tk.TLSHandshakes {
+		if entry.Failure != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// followUpPlan returns the follow-up experiments to run for the given measurement
+// along with the blocking reason. The returned list is empty when there is no
+// anomaly we know how to follow up on.
+func followUpPlan(meas *model.Measurement) (string, []*followUpSpec) {
+	tk := followUpParseTestKeys(meas)
+	if tk == nil {
+		return "", nil
+	}
+	reason := tk.blockingReason()
+	URL, err := url.Parse(string(meas.Input))
+	if err != nil || URL.Hostname() == "" {
+		return reason, nil
+	}
+	domain := URL.Hostname()
+	switch {
+	case reason == "dns":
+		return reason, []*followUpSpec{{
+			ExtraOptions: map[string]any{"Domain": domain},
+			Inputs:       followUpDNSResolvers,
+			Name:         "dnscheck",
+		}}
+	case reason == "http-failure" && tk.tlsHandshakeFailed():
+		return reason, []*followUpSpec{{
+			ExtraOptions: map[string]any{},
+			Inputs:       []string{(&url.URL{Scheme: "tlstrace", Host: domain}).String()},
+			Name:         "tlsmiddlebox",
+		}}
+	case reason == "http-failure":
+		return reason, []*followUpSpec{{
+			ExtraOptions: map[string]any{},
+			Inputs:       []string{domain},
+			Name:         "sniblocking",
+		}, {
+			ExtraOptions: map[string]any{},
+			Inputs:       []string{domain},
+			Name:         "httphostheader",
+		}}
+	default:
+		return reason, nil
+	}
+}
+
+// InputProcessorFollowUpScheduler is InputProcessor's hook for scheduling follow-up
+// measurements after a measurement has been submitted and saved.
+type InputProcessorFollowUpScheduler interface {
+	// Schedule schedules the follow-ups for the given measurement, whose measurement
+	// UID is empty when we could not submit it. This method MUST NOT block.
+	Schedule(meas *model.Measurement, measurementUID string)
+}
+
+// followUpTask is a follow-up experiment scheduled by a [*FollowUpRunner].
+type followUpTask struct {
+	annotations map[string]string
+	reason      string
+	spec        *followUpSpec
+	parentInput string
+}
+
+// FollowUpRunner runs follow-up experiments in a background goroutine, such that
+// we do not block measuring, submitting, and saving the parent measurements.
+//
+// All the follow-ups share the same submitter and saver and we stop starting
+// follow-ups when the template experiment's MaxRuntime expires.
+//
+// You MUST construct using [NewFollowUpRunner].
+type FollowUpRunner struct {
+	// cond allows the background goroutine to wait for tasks.
+	cond *sync.Cond
+
+	// closed indicates that we're not going to schedule more tasks.
+	closed bool
+
+	// deadline is the OPTIONAL deadline after which we stop running follow-ups.
+	deadline time.Time
+
+	// done is closed when the background goroutine terminates.
+	done chan struct{}
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// saver is the saver used by all the follow-ups.
+	saver model.Saver
+
+	// submitter is the submitter used by all the follow-ups or nil
+	// if we have not created it yet.
+	submitter model.Submitter
+
+	// tasks contains the follow-ups we should run.
+	tasks []*followUpTask
+
+	// template is the experiment whose settings we use for the follow-ups.
+	template *Experiment
+}
+
+var _ InputProcessorFollowUpScheduler = &FollowUpRunner{}
+
+// NewFollowUpRunner creates a new [*FollowUpRunner] running the follow-ups using the
+// annotations, the session, and the submission and saving settings of the given
+// template experiment, and the given saver, which MUST be safe to use concurrently
+// with the caller. When the template's MaxRuntime is positive, we do not start
+// follow-ups after MaxRuntime seconds have passed since calling this function.
+//
+// You MUST call Close when done to wait for the background goroutine.
+func NewFollowUpRunner(ctx context.Context, template *Experiment, saver model.Saver) *FollowUpRunner {
+	fr := &FollowUpRunner{
+		cond:      nil, // set below
+		closed:    false,
+		deadline:  time.Time{},
+		done:      make(chan struct{}),
+		mu:        sync.Mutex{},
+		saver:     saver,
+		submitter: nil, // created lazily
+		tasks:     nil,
+		template:  template,
+	}
+	fr.cond = sync.NewCond(&fr.mu)
+	if template.MaxRuntime > 0 {
+		fr.deadline = time.Now().Add(time.Duration(template.MaxRuntime) * time.Second)
+	}
+	go fr.loop(ctx)
+	return fr
+}
+
+// Schedule schedules running the follow-ups for the given measurement, whose
+// measurement UID is empty when we could not submit it. This method does not
+// block and does nothing when the measurement does not need follow-ups.
+func (fr *FollowUpRunner) Schedule(meas *model.Measurement, measurementUID string) {
+	reason, plan := followUpPlan(meas)
+	if len(plan) <= 0 {
+		return
+	}
+	annotations := map[string]string{}
+	for key, value := range fr.template.Annotations {
+		annotations[key] = value
+	}
+	if measurementUID != "" {
+		annotations[FollowUpAnnotationParentMeasurementUID] = measurementUID
+	}
+	annotations[FollowUpAnnotationParentReportID] = meas.ReportID
+	annotations[FollowUpAnnotationParentInput] = string(meas.Input)
+	annotations[FollowUpAnnotationReason] = reason
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	for _, spec := range plan {
+		fr.tasks = append(fr.tasks, &followUpTask{
+			annotations: annotations,
+			reason:      reason,
+			spec:        spec,
+			parentInput: string(meas.Input),
+		})
+	}
+	fr.cond.Signal()
+}
+
+// Close waits for the scheduled follow-ups to complete. We skip the follow-ups
+// we did not start yet when the context is done or MaxRuntime has expired.
+func (fr *FollowUpRunner) Close() {
+	fr.mu.Lock()
+	fr.closed = true
+	fr.cond.Signal()
+	fr.mu.Unlock()
+	<-fr.done
+}
+
+// next blocks until there is a task to run and returns false when
+// we have been closed and there are no more tasks to run.
+func (fr *FollowUpRunner) next() (*followUpTask, bool) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	for len(fr.tasks) <= 0 && !fr.closed {
+		fr.cond.Wait()
+	}
+	if len(fr.tasks) <= 0 {
+		return nil, false
+	}
+	task := fr.tasks[0]
+	fr.tasks = fr.tasks[1:]
+	return task, true
+}
+
+// loop runs the follow-ups in the background.
+func (fr *FollowUpRunner) loop(ctx context.Context) {
+	defer close(fr.done)
+	logger := fr.template.Session.Logger()
+	for {
+		task, good := fr.next()
+		if !good {
+			return
+		}
+		maxRuntime := fr.maxRuntime()
+		if ctx.Err() != nil || maxRuntime < 0 {
+			logger.Infof("follow-up: skipping %s for %s because we're out of time", task.spec.Name, task.parentInput)
+			continue
+		}
+		logger.Infof("follow-up: running %s because of %s anomaly for %s",
+			task.spec.Name, task.reason, task.parentInput)
+		// policy: a failing follow-up does not stop the parent experiment
+		if err := fr.run(ctx, task, maxRuntime); err != nil {
+			logger.Warnf("follow-up: %s failed: %s", task.spec.Name, err.Error())
+		}
+	}
+}
+
+// maxRuntime returns the MaxRuntime in seconds we should use for the next follow-up,
+// which is zero when there is no deadline and negative when the deadline expired.
+func (fr *FollowUpRunner) maxRuntime() int64 {
+	if fr.deadline.IsZero() {
+		return 0
+	}
+	remaining := time.Until(fr.deadline)
+	if remaining <= 0 {
+		return -1
+	}
+	return int64(math.Ceil(remaining.Seconds()))
+}
+
+// run runs the given follow-up with the given MaxRuntime.
+func (fr *FollowUpRunner) run(ctx context.Context, task *followUpTask, maxRuntime int64) error {
+	child := &Experiment{
+		Annotations:            task.annotations,
+		ExtraOptions:           task.spec.ExtraOptions,
+		Inputs:                 task.spec.Inputs,
+		MaxRuntime:             maxRuntime,
+		Name:                   task.spec.Name,
+		NoCollector:            fr.template.NoCollector,
+		NoCredentials:          fr.template.NoCredentials,
+		Session:                fr.template.Session,
+		SubmitQueue:            fr.template.SubmitQueue,
+		newExperimentBuilderFn: fr.template.newExperimentBuilderFn,
+		newTargetLoaderFn:      fr.template.newTargetLoaderFn,
+		newSubmitterFn:         fr.template.newSubmitterFn,
+		newSaverFn:             nil, // we use the saver passed to the constructor
+		newInputProcessorFn:    fr.template.newInputProcessorFn,
+	}
+
+	// Note: we create the submitter once to avoid creating a new
+	// submitter (and possibly a new credential) for each follow-up
+	if fr.submitter == nil {
+		submitter, err := child.newSubmitter(ctx)
+		if err != nil {
+			return err
+		}
+		fr.submitter = submitter
+	}
+
+	builder, err := child.newExperimentBuilder(child.Name)
+	if err != nil {
+		return err
+	}
+	if err := child.setOptions(builder); err != nil {
+		return err
+	}
+	targets, err := child.newTargetLoader(builder).Load(ctx)
+	if err != nil {
+		return err
+	}
+	experiment := builder.NewExperiment()
+	return child.newInputProcessor(experiment, targets, fr.saver, fr.submitter, nil, nil).Run(ctx)
+}
+
+// followUpLockedSaver is a [model.Saver] we share with a [*FollowUpRunner].
+type followUpLockedSaver struct {
+	mu    sync.Mutex
+	saver model.Saver
+}
+
+var _ model.Saver = &followUpLockedSaver{}
+
+// SaveMeasurement implements model.Saver.
+func (s *followUpLockedSaver) SaveMeasurement(m *model.Measurement) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saver.SaveMeasurement(m)
+}
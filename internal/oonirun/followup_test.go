@@ -0,0 +1,318 @@
+package oonirun
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestFollowUpPlan(t *testing.T) {
+	type testcase struct {
+		name         string
+		testName     string
+		input        string
+		testKeys     any
+		expectReason string
+		expectNames  []string
+		expectInputs [][]string
+	}
+
+	cases := []testcase{{
+		name:         "for a measurement that is not web_connectivity",
+		testName:     "dnscheck",
+		input:        "https://www.example.com/",
+		testKeys:     map[string]any{"blocking": "dns"},
+		expectReason: "",
+	}, {
+		name:         "for an accessible website",
+		testName:     "web_connectivity",
+		input:        "https://www.example.com/",
+		testKeys:     map[string]any{"blocking": false},
+		expectReason: "",
+	}, {
+		name:         "for test keys that cannot be serialized",
+		testName:     "web_connectivity",
+		input:        "https://www.example.com/",
+		testKeys:     make(chan int),
+		expectReason: "",
+	}, {
+		name:         "for DNS blocking",
+		testName:     "web_connectivity",
+		input:        "https://www.example.com/",
+		testKeys:     map[string]any{"blocking": "dns"},
+		expectReason: "dns",
+		expectNames:  []string{"dnscheck"},
+		expectInputs: [][]string{followUpDNSResolvers},
+	}, {
+		name:         "for TCP/IP blocking",
+		testName:     "web_connectivity",
+		input:        "https://www.example.com/",
+		testKeys:     map[string]any{"blocking": "tcp_ip"},
+		expectReason: "tcp_ip",
+	}, {
+		name:     "for TLS handshake failures",
+		testName: "web_connectivity",
+		input:    "https://www.example.com/",
+		testKeys: map[string]any{
+			"blocking": "http-failure",
+			"tls_handshakes": []any{
+				map[string]any{"failure": nil},
+				map[string]any{"failure": "connection_reset"},
+			},
+		},
+		expectReason: "http-failure",
+		expectNames:  []string{"tlsmiddlebox"},
+		expectInputs: [][]string{{"tlstrace://www.example.com"}},
+	}, {
+		name:     "for HTTP failures",
+		testName: "web_connectivity",
+		input:    "http://www.example.com/",
+		testKeys: map[string]any{
+			"blocking":       "http-failure",
+			"tls_handshakes": []any{map[string]any{"failure": nil}},
+		},
+		expectReason: "http-failure",
+		expectNames:  []string{"sniblocking", "httphostheader"},
+		expectInputs: [][]string{{"www.example.com"}, {"www.example.com"}},
+	}, {
+		name:         "for HTTP diff",
+		testName:     "web_connectivity",
+		input:        "http://www.example.com/",
+		testKeys:     map[string]any{"blocking": "http-diff"},
+		expectReason: "http-diff",
+	}, {
+		name:         "for an input without a host",
+		testName:     "web_connectivity",
+		input:        "\t",
+		testKeys:     map[string]any{"blocking": "dns"},
+		expectReason: "dns",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meas := &model.Measurement{
+				Input:    model.MeasurementInput(tc.input),
+				TestKeys: tc.testKeys,
+				TestName: tc.testName,
+			}
+			reason, plan := followUpPlan(meas)
+			if reason != tc.expectReason {
+				t.Fatal("unexpected reason", reason)
+			}
+			var (
+				names  []string
+				inputs [][]string
+			)
+			for _, spec := range plan {
+				names = append(names, spec.Name)
+				inputs = append(inputs, spec.Inputs)
+			}
+			if diff := cmp.Diff(tc.expectNames, names); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.expectInputs, inputs); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestExperimentRunWithFollowUps(t *testing.T) {
+	// keep track of the experiments we run and of what we submit
+	var (
+		mu         sync.Mutex
+		names      []string
+		options    []map[string]any
+		submitted  []*model.Measurement
+		submitters int
+	)
+
+	newExperimentBuilder := func(name string) (model.ExperimentBuilder, error) {
+		mu.Lock()
+		names = append(names, name)
+		mu.Unlock()
+		eb := &mocks.ExperimentBuilder{
+			MockSetOptionsJSON: func(value json.RawMessage) error {
+				return nil
+			},
+			MockSetOptionsAny: func(value map[string]any) error {
+				mu.Lock()
+				options = append(options, value)
+				mu.Unlock()
+				return nil
+			},
+			MockNewExperiment: func() model.Experiment {
+				return &mocks.Experiment{
+					MockMeasureWithContext: func(
+						ctx context.Context, target model.ExperimentTarget) (*model.Measurement, error) {
+						meas := &model.Measurement{
+							Input:    model.MeasurementInput(target.Input()),
+							ReportID: "20261018T000000Z_" + name,
+							TestName: name,
+							TestKeys: map[string]any{},
+						}
+						if name == "web_connectivity" && target.Input() != "https://www.example.com/" {
+							meas.TestKeys = map[string]any{"blocking": "dns"}
+						}
+						return meas, nil
+					},
+					MockKibiBytesReceived: func() float64 {
+						return 0
+					},
+					MockKibiBytesSent: func() float64 {
+						return 0
+					},
+				}
+			},
+			MockNewTargetLoader: func(config *model.ExperimentTargetLoaderConfig) model.ExperimentTargetLoader {
+				return &mocks.ExperimentTargetLoader{
+					MockLoad: func(ctx context.Context) (out []model.ExperimentTarget, err error) {
+						for _, input := range config.StaticInputs {
+							out = append(out, model.NewOOAPIURLInfoWithDefaultCategoryAndCountry(input))
+						}
+						return
+					},
+				}
+			},
+		}
+		return eb, nil
+	}
+
+	desc := &Experiment{
+		Annotations: map[string]string{"platform": "linux"},
+		FollowUps:   true,
+		Inputs: []string{
+			"https://www.example.com/",
+			"https://blocked.example.com/",
+			"https://blocked.example.org/",
+		},
+		Name:   "web_connectivity",
+		NoJSON: true,
+		Session: &mocks.Session{
+			MockLogger: func() model.Logger {
+				return model.DiscardLogger
+			},
+		},
+		newExperimentBuilderFn: newExperimentBuilder,
+		newSubmitterFn: func(ctx context.Context) (model.Submitter, error) {
+			mu.Lock()
+			submitters++
+			mu.Unlock()
+			subm := &mocks.Submitter{
+				MockSubmit: func(ctx context.Context, m *model.Measurement) (string, error) {
+					mu.Lock()
+					submitted = append(submitted, m)
+					mu.Unlock()
+					return "uid_" + m.TestName, nil
+				},
+			}
+			return subm, nil
+		},
+	}
+	if err := desc.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// we expect to run dnscheck once for each blocked input
+	if diff := cmp.Diff([]string{"web_connectivity", "dnscheck", "dnscheck"}, names); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff(map[string]any{"Domain": "blocked.example.com"}, options[1]); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff(map[string]any{"Domain": "blocked.example.org"}, options[2]); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// we expect all the follow-ups to share the same submitter
+	if submitters != 2 {
+		t.Fatal("unexpected number of submitters", submitters)
+	}
+
+	// make sure the follow-ups are linked to the parent measurement
+	var followUps []*model.Measurement
+	for _, meas := range submitted {
+		if meas.TestName == "dnscheck" {
+			followUps = append(followUps, meas)
+		}
+	}
+	if len(submitted) != 3+len(followUps) || len(followUps) != 2*len(followUpDNSResolvers) {
+		t.Fatal("unexpected number of submitted measurements", len(submitted))
+	}
+	for idx, meas := range followUps {
+		parent := "https://blocked.example.com/"
+		if idx >= len(followUpDNSResolvers) {
+			parent = "https://blocked.example.org/"
+		}
+		expectAnnotations := map[string]string{
+			"platform":                             "linux",
+			FollowUpAnnotationParentMeasurementUID: "uid_web_connectivity",
+			FollowUpAnnotationParentReportID:       "20261018T000000Z_web_connectivity",
+			FollowUpAnnotationParentInput:          parent,
+			FollowUpAnnotationReason:               "dns",
+		}
+		if string(meas.Input) != followUpDNSResolvers[idx%len(followUpDNSResolvers)] {
+			t.Fatal("unexpected follow-up measurement", meas.Input)
+		}
+		if diff := cmp.Diff(expectAnnotations, meas.Annotations); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+}
+
+func TestFollowUpRunner(t *testing.T) {
+	// newTemplate returns a template experiment that fails if we try to run any follow-up.
+	newTemplate := func(maxRuntime int64) *Experiment {
+		return &Experiment{
+			MaxRuntime: maxRuntime,
+			Session: &mocks.Session{
+				MockLogger: func() model.Logger {
+					return model.DiscardLogger
+				},
+			},
+			newExperimentBuilderFn: func(experimentName string) (model.ExperimentBuilder, error) {
+				t.Fatal("should not be called")
+				return nil, nil
+			},
+		}
+	}
+
+	blocked := &model.Measurement{
+		Input:    "https://blocked.example.com/",
+		TestKeys: map[string]any{"blocking": "dns"},
+		TestName: "web_connectivity",
+	}
+
+	t.Run("we do not run follow-ups after MaxRuntime", func(t *testing.T) {
+		fr := NewFollowUpRunner(context.Background(), newTemplate(10), &mocks.Saver{})
+		fr.deadline = time.Now().Add(-time.Second) // pretend we're out of time
+		fr.Schedule(blocked, "")
+		fr.Close()
+	})
+
+	t.Run("we do not run follow-ups when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel() // immediately cancel
+		fr := NewFollowUpRunner(ctx, newTemplate(0), &mocks.Saver{})
+		fr.Schedule(blocked, "")
+		fr.Close()
+	})
+
+	t.Run("the MaxRuntime of follow-ups is the remaining runtime", func(t *testing.T) {
+		fr := NewFollowUpRunner(context.Background(), newTemplate(10), &mocks.Saver{})
+		defer fr.Close()
+		if value := fr.maxRuntime(); value <= 0 || value > 10 {
+			t.Fatal("unexpected MaxRuntime", value)
+		}
+		fr.deadline = time.Time{}
+		if value := fr.maxRuntime(); value != 0 {
+			t.Fatal("unexpected MaxRuntime", value)
+		}
+	})
+}
@@ -46,6 +46,10 @@ type InputProcessor struct {
 	// Experiment is the code that will run the experiment.
 	Experiment InputProcessorExperimentWrapper
 
+	// FollowUps is the OPTIONAL code that schedules follow-up measurements
+	// after each measurement has been submitted and saved.
+	FollowUps InputProcessorFollowUpScheduler
+
 	// Inputs is the list of inputs to measure.
 	Inputs []model.ExperimentTarget
 
@@ -55,6 +59,15 @@ type InputProcessor struct {
 	// there will be no MaxRuntime limit.
 	MaxRuntime time.Duration
 
+	// Parallelism is the OPTIONAL maximum number of inputs to measure
+	// concurrently. Zero or one means we measure inputs sequentially. Regardless
+	// of this setting, we save and submit measurements in input order.
+	Parallelism int
+
+	// PerHostParallelism is the OPTIONAL maximum number of inputs with the
+	// same host to measure concurrently (see [InputScheduler]).
+	PerHostParallelism int
+
 	// Saver is the code that will save measurement results
 	// on persistent storage (e.g. the file system).
 	Saver InputProcessorSaverWrapper
@@ -131,31 +144,37 @@ const (
 // run is like Run but, in addition to returning an error, it
 // also returns the reason why we stopped.
 func (ip *InputProcessor) run(ctx context.Context) (int, error) {
-	start := time.Now()
-	for idx, target := range ip.Inputs {
-		if ip.MaxRuntime > 0 && time.Since(start) > ip.MaxRuntime {
-			return stopMaxRuntime, nil
-		}
-		meas, err := ip.Experiment.MeasureWithContext(ctx, target, idx)
+	scheduler := &InputScheduler{
+		Inputs:             ip.Inputs,
+		MaxRuntime:         ip.MaxRuntime,
+		Measure:            ip.Experiment.MeasureWithContext,
+		Parallelism:        ip.Parallelism,
+		PerHostParallelism: ip.PerHostParallelism,
+		ShouldStop:         nil,
+	}
+	return scheduler.Run(ctx, func(idx int, meas *model.Measurement, err error) error {
 		if err != nil {
-			return 0, err
+			return err
 		}
 		meas.AddAnnotations(ip.Annotations)
-		_, err = ip.Submitter.Submit(ctx, idx, meas)
+		mstUID, err := ip.Submitter.Submit(ctx, idx, meas)
 		if err != nil {
 			// TODO(bassosimone): when re-reading this code, I find it confusing that
 			// we return on error because I am always like "wait, this is not the right
 			// thing to do here". Then, I remember that the experimentSubmitterWrapper{}
 			// ignores this error and so it's like it does not exist. Maybe we should
 			// rewrite the code to do the right thing here 😬😬😬.
-			return 0, err
+			return err
 		}
 		// Note: must be after submission because submission modifies
 		// the measurement to include the report ID.
 		err = ip.Saver.SaveMeasurement(idx, meas)
 		if err != nil {
-			return 0, err
+			return err
 		}
-	}
-	return stopNormal, nil
+		if ip.FollowUps != nil {
+			ip.FollowUps.Schedule(meas, mstUID)
+		}
+		return nil
+	})
 }
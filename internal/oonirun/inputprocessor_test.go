@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -192,3 +193,45 @@ func TestInputProcessorMaxRuntime(t *testing.T) {
 		t.Fatal("not terminated by max runtime")
 	}
 }
+
+func TestInputProcessorParallelism(t *testing.T) {
+	inputs := []string{
+		"https://www.kernel.org/",
+		"https://www.slashdot.org/",
+		"https://www.example.com/",
+		"https://www.example.org/",
+	}
+	exp := &mocks.Experiment{
+		MockMeasureWithContext: func(ctx context.Context, target model.ExperimentTarget) (*model.Measurement, error) {
+			// make sure the first inputs are the slowest ones
+			if target.Input() == inputs[0] || target.Input() == inputs[1] {
+				time.Sleep(50 * time.Millisecond)
+			}
+			return &model.Measurement{Input: model.MeasurementInput(target.Input())}, nil
+		},
+	}
+	saver := &FakeInputProcessorSaver{Err: nil}
+	submitter := &FakeInputProcessorSubmitter{Err: nil}
+	ip := &InputProcessor{
+		Experiment:  NewInputProcessorExperimentWrapper(exp),
+		Inputs:      newInputSchedulerTargets(inputs...),
+		Parallelism: 4,
+		Saver:       NewInputProcessorSaverWrapper(saver),
+		Submitter:   NewInputProcessorSubmitterWrapper(submitter),
+	}
+	reason, err := ip.run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason != stopNormal {
+		t.Fatal("terminated by max runtime!?")
+	}
+	if len(saver.M) != len(inputs) || len(submitter.M) != len(inputs) {
+		t.Fatal("not all measurements saved")
+	}
+	for idx, input := range inputs {
+		if string(submitter.M[idx].Input) != input || string(saver.M[idx].Input) != input {
+			t.Fatal("measurements not saved or submitted in order", idx)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package oonirun
+
+//
+// Bounded parallel measurement of inputs.
+//
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// InputSchedulerDefaultPerHostParallelism is the default maximum number of
+// inputs sharing the same host that we measure concurrently.
+const InputSchedulerDefaultPerHostParallelism = 1
+
+// InputSchedulerStopMaxRuntime is the reason returned by [*InputScheduler.Run]
+// when we stop starting new measurements because of MaxRuntime.
+const InputSchedulerStopMaxRuntime = stopMaxRuntime
+
+// InputScheduler measures inputs using bounded parallelism, limits the number
+// of concurrent measurements of the same host, and delivers the results in the
+// same order of the inputs, such that saving and submitting measurements
+// happens as if we had measured the inputs one after the other.
+//
+// The zero value of this structure IS NOT valid and you MUST initialize
+// all the fields marked as MANDATORY before using this structure.
+type InputScheduler struct {
+	// Inputs contains the MANDATORY inputs to measure.
+	Inputs []model.ExperimentTarget
+
+	// MaxRuntime is the OPTIONAL maximum runtime after which we stop
+	// starting new measurements. Zero means there is no limit.
+	MaxRuntime time.Duration
+
+	// Measure is the MANDATORY function measuring the idx-th target. We call this
+	// function from several goroutines when Parallelism is greater than one.
+	Measure func(ctx context.Context, target model.ExperimentTarget, idx int) (*model.Measurement, error)
+
+	// Parallelism is the OPTIONAL maximum number of inputs to measure
+	// concurrently. Values lower than two mean no parallelism.
+	Parallelism int
+
+	// PerHostParallelism is the OPTIONAL maximum number of inputs with the same
+	// host to measure concurrently. When zero or negative, we use the
+	// [InputSchedulerDefaultPerHostParallelism] default.
+	PerHostParallelism int
+
+	// ShouldStop is the OPTIONAL function telling us to stop starting new
+	// measurements before measuring the idx-th target (e.g., because the user
+	// pressed ^C). Like Measure, it may be called from a background goroutine.
+	ShouldStop func(idx int) bool
+}
+
+// Run measures all the inputs and calls consume for each input in order from the
+// calling goroutine, passing it either the measurement or the error that occurred.
+// When consume returns an error, we stop and return such an error. The first
+// return value explains why we stopped (e.g., whether it was MaxRuntime).
+func (s *InputScheduler) Run(ctx context.Context,
+	consume func(idx int, meas *model.Measurement, err error) error) (int, error) {
+	if s.Parallelism <= 1 {
+		return s.runSequential(ctx, consume)
+	}
+	return s.runParallel(ctx, consume)
+}
+
+// shouldStopBefore returns the reason to stop before measuring the idx-th input or zero.
+func (s *InputScheduler) shouldStopBefore(start time.Time, idx int) int {
+	if s.MaxRuntime > 0 && time.Since(start) > s.MaxRuntime {
+		return stopMaxRuntime
+	}
+	if s.ShouldStop != nil && s.ShouldStop(idx) {
+		return stopNormal
+	}
+	return 0
+}
+
+// runSequential measures each input one after the other.
+func (s *InputScheduler) runSequential(ctx context.Context,
+	consume func(idx int, meas *model.Measurement, err error) error) (int, error) {
+	start := time.Now()
+	for idx, target := range s.Inputs {
+		if reason := s.shouldStopBefore(start, idx); reason != 0 {
+			return reason, nil
+		}
+		meas, err := s.Measure(ctx, target, idx)
+		if err := consume(idx, meas, err); err != nil {
+			return 0, err
+		}
+	}
+	return stopNormal, nil
+}
+
+// inputSchedulerResult is the result of measuring an input.
+type inputSchedulerResult struct {
+	err  error
+	idx  int
+	meas *model.Measurement
+}
+
+// runParallel measures inputs in parallel.
+func (s *InputScheduler) runParallel(ctx context.Context,
+	consume func(idx int, meas *model.Measurement, err error) error) (int, error) {
+	// make sure we can interrupt the background goroutines
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		// stop is closed when consume fails to stop dispatching inputs
+		stop = make(chan struct{})
+
+		// hosts limits the per-host parallelism
+		hosts = newInputSchedulerHosts(s.PerHostParallelism)
+
+		// indexes contains the index of the inputs to measure
+		indexes = make(chan int)
+
+		// reason is the reason why the dispatcher stopped
+		reason = &atomic.Int64{}
+
+		// results contains the measurement results
+		results = make(chan *inputSchedulerResult)
+
+		// window bounds the number of results we buffer while waiting for
+		// an earlier input to complete, to keep memory usage bounded
+		window = make(chan struct{}, 2*s.Parallelism)
+
+		// wg allows us to know when all the workers are done
+		wg = &sync.WaitGroup{}
+	)
+
+	// dispatch the inputs to measure in order
+	//
+	// Note: we only stop dispatching when consume fails and otherwise keep going even
+	// if the parent context is done, such that consume sees an error for each input
+	// we did not measure, like it happens when we measure sequentially
+	reason.Store(stopNormal)
+	go func() {
+		defer close(indexes)
+		start := time.Now()
+		for idx := range s.Inputs {
+			select {
+			case window <- struct{}{}:
+			case <-stop:
+				return
+			}
+			// Note: we check whether to stop after acquiring the window such that
+			// MaxRuntime accounts for the time we've been waiting
+			if value := s.shouldStopBefore(start, idx); value != 0 {
+				reason.Store(int64(value))
+				return
+			}
+			select {
+			case indexes <- idx:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	// measure the inputs using a bounded number of workers
+	//
+	// Note: each worker emits a result for each input it receives, including the
+	// inputs it could not measure because the context is done, such that there are
+	// no gaps in the results and we consume all the inputs we have dispatched
+	for count := 0; count < s.Parallelism; count++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				results <- s.measure(ctx, hosts, idx)
+			}
+		}()
+	}
+
+	// close the results channel when all workers are done
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// consume the results in order
+	var (
+		next    int
+		pending = make(map[int]*inputSchedulerResult)
+		failure error
+	)
+	for result := range results {
+		if failure != nil {
+			continue // drain until all the workers are done
+		}
+		pending[result.idx] = result
+		for {
+			entry, found := pending[next]
+			if !found {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-window
+			if err := consume(entry.idx, entry.meas, entry.err); err != nil {
+				failure = err
+				close(stop)
+				cancel()
+				break
+			}
+		}
+	}
+	if failure != nil {
+		return 0, failure
+	}
+	return int(reason.Load()), nil
+}
+
+// measure measures the idx-th input while holding its host, or returns the context
+// error when the context is done before we could start measuring.
+func (s *InputScheduler) measure(ctx context.Context, hosts *inputSchedulerHosts, idx int) *inputSchedulerResult {
+	target := s.Inputs[idx]
+	host := inputSchedulerHostKey(target)
+	if !hosts.acquire(ctx, host) {
+		return &inputSchedulerResult{err: ctx.Err(), idx: idx, meas: nil}
+	}
+	defer hosts.release(host)
+	meas, err := s.Measure(ctx, target, idx)
+	return &inputSchedulerResult{err: err, idx: idx, meas: meas}
+}
+
+// inputSchedulerHostKey returns the host key for the given target, which is the
+// URL host when the input is a URL with a host, and the empty string otherwise,
+// meaning that the per-host limit does not apply to the target.
+func inputSchedulerHostKey(target model.ExperimentTarget) string {
+	URL, err := url.Parse(target.Input())
+	if err != nil {
+		return ""
+	}
+	return URL.Hostname()
+}
+
+// inputSchedulerHosts limits the number of concurrent measurements per host.
+type inputSchedulerHosts struct {
+	// limit is the maximum number of concurrent measurements per host.
+	limit int
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// sema maps a host to its semaphore.
+	sema map[string]chan struct{}
+}
+
+// newInputSchedulerHosts creates a new [*inputSchedulerHosts].
+func newInputSchedulerHosts(limit int) *inputSchedulerHosts {
+	if limit <= 0 {
+		limit = InputSchedulerDefaultPerHostParallelism
+	}
+	return &inputSchedulerHosts{
+		limit: limit,
+		mu:    sync.Mutex{},
+		sema:  make(map[string]chan struct{}),
+	}
+}
+
+// semaphore returns the semaphore for the given host.
+func (h *inputSchedulerHosts) semaphore(host string) chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sema, found := h.sema[host]
+	if !found {
+		sema = make(chan struct{}, h.limit)
+		h.sema[host] = sema
+	}
+	return sema
+}
+
+// acquire blocks until we can measure the given host and returns false
+// if the context has been canceled while we were waiting.
+func (h *inputSchedulerHosts) acquire(ctx context.Context, host string) bool {
+	if host == "" {
+		return true
+	}
+	select {
+	case h.semaphore(host) <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release releases a host previously acquired using acquire.
+func (h *inputSchedulerHosts) release(host string) {
+	if host == "" {
+		return
+	}
+	<-h.semaphore(host)
+}
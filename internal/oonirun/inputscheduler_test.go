@@ -0,0 +1,325 @@
+package oonirun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// newInputSchedulerTargets returns targets for the given inputs.
+func newInputSchedulerTargets(inputs ...string) (out []model.ExperimentTarget) {
+	for _, input := range inputs {
+		out = append(out, model.NewOOAPIURLInfoWithDefaultCategoryAndCountry(input))
+	}
+	return
+}
+
+// inputSchedulerConcurrency keeps track of the maximum concurrency.
+type inputSchedulerConcurrency struct {
+	mu      sync.Mutex
+	current map[string]int
+	max     map[string]int
+}
+
+func newInputSchedulerConcurrency() *inputSchedulerConcurrency {
+	return &inputSchedulerConcurrency{
+		current: map[string]int{},
+		max:     map[string]int{},
+	}
+}
+
+// enter records that we're entering the given host (and the "" host, which
+// we use to keep track of the overall concurrency).
+func (c *inputSchedulerConcurrency) enter(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range inputSchedulerConcurrencyKeys(host) {
+		c.current[key]++
+		c.max[key] = max(c.max[key], c.current[key])
+	}
+}
+
+// inputSchedulerConcurrencyKeys returns the keys to update for the given host.
+func inputSchedulerConcurrencyKeys(host string) []string {
+	if host == "" {
+		return []string{""}
+	}
+	return []string{"", host}
+}
+
+// leave records that we're leaving the given host.
+func (c *inputSchedulerConcurrency) leave(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range inputSchedulerConcurrencyKeys(host) {
+		c.current[key]--
+	}
+}
+
+func TestInputScheduler(t *testing.T) {
+	t.Run("we consume results in order when measuring in parallel", func(t *testing.T) {
+		var inputs []string
+		for idx := 0; idx < 16; idx++ {
+			inputs = append(inputs, fmt.Sprintf("https://%d.example.com/", idx))
+		}
+		concurrency := newInputSchedulerConcurrency()
+		scheduler := &InputScheduler{
+			Inputs: newInputSchedulerTargets(inputs...),
+			Measure: func(ctx context.Context, target model.ExperimentTarget, idx int) (*model.Measurement, error) {
+				concurrency.enter("")
+				defer concurrency.leave("")
+				// make sure later inputs complete before earlier ones
+				time.Sleep(time.Duration(16-idx) * time.Millisecond)
+				return &model.Measurement{Input: model.MeasurementInput(target.Input())}, nil
+			},
+			Parallelism: 4,
+		}
+		var got []string
+		reason, err := scheduler.Run(context.Background(), func(idx int, meas *model.Measurement, err error) error {
+			if err != nil {
+				return err
+			}
+			got = append(got, string(meas.Input))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reason != stopNormal {
+			t.Fatal("unexpected reason", reason)
+		}
+		if diff := cmp.Diff(inputs, got); diff != "" {
+			t.Fatal(diff)
+		}
+		if value := concurrency.max[""]; value < 2 || value > 4 {
+			t.Fatal("unexpected maximum concurrency", value)
+		}
+	})
+
+	t.Run("we deliver measurement errors to the consumer", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		scheduler := &InputScheduler{
+			Inputs: newInputSchedulerTargets("a", "b", "c"),
+			Measure: func(ctx context.Context, target model.ExperimentTarget, idx int) (*model.Measurement, error) {
+				if idx == 1 {
+					return nil, expected
+				}
+				return &model.Measurement{}, nil
+			},
+			Parallelism: 2,
+		}
+		var failures []error
+		_, err := scheduler.Run(context.Background(), func(idx int, meas *model.Measurement, err error) error {
+			failures = append(failures, err)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(failures) != 3 || failures[0] != nil || !errors.Is(failures[1], expected) || failures[2] != nil {
+			t.Fatal("unexpected failures", failures)
+		}
+	})
+
+	t.Run("we stop when the consumer fails", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		scheduler := &InputScheduler{
+			Inputs: newInputSchedulerTargets("a", "b", "c", "d", "e", "f", "g", "h"),
+			Measure: func(ctx context.Context, target model.ExperimentTarget, idx int) (*model.Measurement, error) {
+				return &model.Measurement{}, nil
+			},
+			Parallelism: 3,
+		}
+		var count int
+		_, err := scheduler.Run(context.Background(), func(idx int, meas *model.Measurement, err error) error {
+			count++
+			if idx == 2 {
+				return expected
+			}
+			return nil
+		})
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if count != 3 {
+			t.Fatal("unexpected number of consumed results", count)
+		}
+	})
+
+	t.Run("we honour MaxRuntime", func(t *testing.T) {
+		scheduler := &InputScheduler{
+			Inputs:     newInputSchedulerTargets("a", "b", "c", "d", "e", "f", "g", "h"),
+			MaxRuntime: 10 * time.Millisecond,
+			Measure: func(ctx context.Context, target model.ExperimentTarget, idx int) (*model.Measurement, error) {
+				time.Sleep(20 * time.Millisecond)
+				return &model.Measurement{}, nil
+			},
+			Parallelism: 2,
+		}
+		var count int
+		reason, err := scheduler.Run(context.Background(), func(idx int, meas *model.Measurement, err error) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reason != InputSchedulerStopMaxRuntime {
+			t.Fatal("unexpected reason", reason)
+		}
+		if count <= 0 || count >= 8 {
+			t.Fatal("unexpected number of consumed results", count)
+		}
+	})
+
+	t.Run("we honour ShouldStop", func(t *testing.T) {
+		for _, parallelism := range []int{1, 4} {
+			scheduler := &InputScheduler{
+				Inputs: newInputSchedulerTargets("a", "b", "c", "d"),
+				Measure: func(ctx context.Context, target model.ExperimentTarget, idx int) (*model.Measurement, error) {
+					return &model.Measurement{}, nil
+				},
+				Parallelism: parallelism,
+				ShouldStop: func(idx int) bool {
+					return idx >= 2
+				},
+			}
+			var count int
+			reason, err := scheduler.Run(context.Background(), func(idx int, meas *model.Measurement, err error) error {
+				count++
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reason != stopNormal || count != 2 {
+				t.Fatal("unexpected result", parallelism, reason, count)
+			}
+		}
+	})
+
+	t.Run("we deliver an error for each input when the context is done", func(t *testing.T) {
+		for _, parallelism := range []int{1, 3} {
+			ctx, cancel := context.WithCancel(context.Background())
+			scheduler := &InputScheduler{
+				Inputs: newInputSchedulerTargets(
+					"https://www.example.com/a", "https://www.example.com/b",
+					"https://www.example.com/c", "https://www.example.com/d",
+				),
+				Measure: func(ctx context.Context, target model.ExperimentTarget, idx int) (*model.Measurement, error) {
+					if idx == 0 {
+						// give the other workers time to wait for the same host
+						time.Sleep(10 * time.Millisecond)
+						cancel()
+					}
+					return nil, ctx.Err()
+				},
+				Parallelism: parallelism,
+			}
+			var failures []error
+			reason, err := scheduler.Run(ctx, func(idx int, meas *model.Measurement, err error) error {
+				if idx != len(failures) {
+					t.Fatal("unexpected index", parallelism, idx)
+				}
+				failures = append(failures, err)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reason != stopNormal || len(failures) != 4 {
+				t.Fatal("unexpected result", parallelism, reason, len(failures))
+			}
+			for _, failure := range failures {
+				if !errors.Is(failure, context.Canceled) {
+					t.Fatal("unexpected failure", parallelism, failure)
+				}
+			}
+		}
+	})
+}
+
+func TestInputSchedulerWithNetem(t *testing.T) {
+	// replace www.example.com's handler with one tracking the concurrency per host
+	concurrency := newInputSchedulerConcurrency()
+	scenario := []*netemx.ScenarioDomainAddresses{}
+	for _, sad := range netemx.InternetScenario {
+		if sad.Role == netemx.ScenarioRoleWebServer && sad.ServerNameMain == "www.example.com" {
+			copied := *sad
+			copied.WebServerFactory = netemx.HTTPHandlerFactoryFunc(
+				func(env netemx.NetStackServerFactoryEnv, stack *netem.UNetStack) http.Handler {
+					return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						concurrency.enter(r.Host)
+						defer concurrency.leave(r.Host)
+						time.Sleep(50 * time.Millisecond)
+						_, _ = w.Write([]byte(netemx.ExampleWebPage))
+					})
+				})
+			sad = &copied
+		}
+		scenario = append(scenario, sad)
+	}
+	env := netemx.MustNewScenario(scenario)
+	defer env.Close()
+
+	var inputs []string
+	for idx := 0; idx < 4; idx++ {
+		for _, domain := range []string{"www.example.com", "example.com", "www.example.org"} {
+			inputs = append(inputs, fmt.Sprintf("http://%s/%d", domain, idx))
+		}
+	}
+
+	var got []string
+	env.Do(func() {
+		client := netxlite.NewHTTPClientStdlib(model.DiscardLogger)
+		defer client.CloseIdleConnections()
+		scheduler := &InputScheduler{
+			Inputs: newInputSchedulerTargets(inputs...),
+			Measure: func(ctx context.Context, target model.ExperimentTarget, idx int) (*model.Measurement, error) {
+				req, err := http.NewRequestWithContext(ctx, "GET", target.Input(), nil)
+				if err != nil {
+					return nil, err
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					return nil, err
+				}
+				resp.Body.Close()
+				return &model.Measurement{Input: model.MeasurementInput(target.Input())}, nil
+			},
+			Parallelism: 6,
+		}
+		_, err := scheduler.Run(context.Background(), func(idx int, meas *model.Measurement, err error) error {
+			if err != nil {
+				return err
+			}
+			got = append(got, string(meas.Input))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if diff := cmp.Diff(inputs, got); diff != "" {
+		t.Fatal(diff)
+	}
+	for _, host := range []string{"www.example.com", "example.com", "www.example.org"} {
+		if value := concurrency.max[host]; value != 1 {
+			t.Fatal("unexpected per-host concurrency", host, value)
+		}
+	}
+	if value := concurrency.max[""]; value < 2 {
+		t.Fatal("expected to measure distinct hosts in parallel", value)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 )
 
 // LinkConfig contains config for an OONI Run link. You MUST fill all the fields that
@@ -26,6 +27,10 @@ type LinkConfig struct {
 	// Annotations contains OPTIONAL Annotations for the experiment.
 	Annotations map[string]string
 
+	// FollowUps OPTIONALLY indicates we should automatically run targeted
+	// follow-up experiments when Web Connectivity detects an anomaly.
+	FollowUps bool
+
 	// KVStore is the MANDATORY key-value store to use to keep track of
 	// OONI Run links and know when they are new or modified.
 	KVStore model.KeyValueStore
@@ -40,6 +45,10 @@ type LinkConfig struct {
 	// credential.
 	NoCredentials bool
 
+	// Parallelism is the OPTIONAL maximum number of inputs to measure
+	// concurrently for each experiment.
+	Parallelism int64
+
 	// ProbeCC is the OPTIONAL probe country code.
 	ProbeCC string
 
@@ -56,8 +65,21 @@ type LinkConfig struct {
 	// used when noJSON is set to false.
 	ReportFile string
 
+	// Resume OPTIONALLY indicates we should resume the interrupted runs of the
+	// link's experiments, which we checkpoint using the KVStore.
+	Resume bool
+
+	// RunType OPTIONALLY indicates whether this is a manual or a background
+	// (i.e., timed) run. We only run the nettests that the link enables for the
+	// given run type. When empty, we assume [model.RunTypeManual].
+	RunType model.RunType
+
 	// Session is the MANDATORY Session to use.
 	Session Session
+
+	// SubmitQueue is the OPTIONAL queue where we store the
+	// measurements we could not submit to submit them later.
+	SubmitQueue *submitqueue.Queue
 }
 
 // LinkRunner knows how to run an OONI Run v1 or v2 link.
@@ -4,11 +4,9 @@ import (
 	"context"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 )
 
-// TODO(bassosimone): maybe keep track of which measurements
-// could not be submitted by a specific submitter?
-
 // Submitter is an alias for model.Submitter
 type Submitter = model.Submitter
 
@@ -29,6 +27,10 @@ type SubmitterConfig struct {
 	// Logger is the logger to be used.
 	Logger model.Logger
 
+	// Queue is the OPTIONAL queue where we store the measurements
+	// we could not submit, to submit them later.
+	Queue *submitqueue.Queue
+
 	UseAuth bool
 }
 
@@ -43,7 +45,7 @@ func NewSubmitter(ctx context.Context, config SubmitterConfig) (Submitter, error
 	if err != nil {
 		return nil, err
 	}
-	return realSubmitter{subm: subm, logger: config.Logger}, nil
+	return realSubmitter{subm: subm, logger: config.Logger, queue: config.Queue}, nil
 }
 
 type stubSubmitter struct{}
@@ -57,9 +59,32 @@ var _ Submitter = stubSubmitter{}
 type realSubmitter struct {
 	subm   Submitter
 	logger model.Logger
+	queue  *submitqueue.Queue
 }
 
 func (rs realSubmitter) Submit(ctx context.Context, m *model.Measurement) (string, error) {
 	rs.logger.Info("submitting measurement to OONI collector; please be patient...")
-	return rs.subm.Submit(ctx, m)
+	mstUID, err := rs.subm.Submit(ctx, m)
+	if err != nil && rs.queue != nil {
+		if err := rs.queue.Enqueue(m, ""); err != nil {
+			rs.logger.Warnf("cannot queue measurement for later submission: %s", err.Error())
+		} else {
+			rs.logger.Infof("queued measurement for later submission (%d queued)", rs.queue.Len())
+		}
+	}
+	return mstUID, err
+}
+
+// newSubmitQueueUploader returns the uploader for the measurements queued by
+// the given submitter or nil when the submitter does not queue measurements.
+func newSubmitQueueUploader(submitter Submitter, logger model.Logger) *submitqueue.Uploader {
+	rs, ok := submitter.(realSubmitter)
+	if !ok || rs.queue == nil {
+		return nil
+	}
+	return &submitqueue.Uploader{
+		Logger:    logger,
+		Queue:     rs.queue,
+		Submitter: rs.subm, // not rs, otherwise we would queue failed entries again
+	}
 }
@@ -7,7 +7,9 @@ import (
 	"testing"
 
 	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 )
 
 func TestSubmitterNotEnabled(t *testing.T) {
@@ -92,3 +94,48 @@ func TestNewSubmitterWithFailedSubmission(t *testing.T) {
 		t.Fatal("unexpected number of calls")
 	}
 }
+
+func TestNewSubmitterWithQueue(t *testing.T) {
+	expected := errors.New("mocked error")
+	ctx := context.Background()
+	fakeSubmitter := &FakeSubmitter{
+		Calls: &atomic.Int64{},
+		Error: expected,
+	}
+	queue := submitqueue.New(&kvstore.Memory{})
+	submitter, err := NewSubmitter(ctx, SubmitterConfig{
+		Enabled: true,
+		Logger:  log.Log,
+		Queue:   queue,
+		Session: FakeSubmitterSession{Submitter: fakeSubmitter},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed submission should add the measurement to the queue
+	if _, err := submitter.Submit(context.Background(), new(model.Measurement)); !errors.Is(err, expected) {
+		t.Fatalf("not the error we expected: %+v", err)
+	}
+	if queue.Len() != 1 {
+		t.Fatal("expected the measurement to be queued")
+	}
+
+	// the uploader should use the underlying submitter, so that failing
+	// again does not add the same measurement to the queue twice
+	uploader := newSubmitQueueUploader(submitter, log.Log)
+	if uploader == nil || uploader.Queue != queue || uploader.Submitter != fakeSubmitter {
+		t.Fatal("unexpected uploader", uploader)
+	}
+	if _, err := queue.Flush(ctx, uploader.Submitter, nil); err != nil {
+		t.Fatal(err)
+	}
+	if queue.Len() != 1 || fakeSubmitter.Calls.Load() != 2 {
+		t.Fatal("unexpected state", queue.Len(), fakeSubmitter.Calls.Load())
+	}
+
+	// there's no uploader when we're not queueing
+	if newSubmitQueueUploader(stubSubmitter{}, log.Log) != nil {
+		t.Fatal("expected no uploader")
+	}
+}
@@ -73,7 +73,9 @@ func v1Measure(ctx context.Context, config *LinkConfig, URL string) error {
 	}
 	exp := &Experiment{
 		Annotations:            config.Annotations,
+		Checkpoints:            config.KVStore,
 		ExtraOptions:           nil, // no way to specify with v1 URLs
+		FollowUps:              config.FollowUps,
 		Inputs:                 inputs,
 		InputFilePaths:         nil,
 		MaxRuntime:             config.MaxRuntime,
@@ -81,9 +83,12 @@ func v1Measure(ctx context.Context, config *LinkConfig, URL string) error {
 		NoCollector:            config.NoCollector,
 		NoCredentials:          config.NoCredentials,
 		NoJSON:                 config.NoJSON,
+		Parallelism:            config.Parallelism,
 		Random:                 config.Random,
 		ReportFile:             config.ReportFile,
+		Resume:                 config.Resume,
 		Session:                config.Session,
+		SubmitQueue:            config.SubmitQueue,
 		newExperimentBuilderFn: nil,
 		newTargetLoaderFn:      nil,
 		newSubmitterFn:         nil,
@@ -96,6 +96,24 @@ type V2Nettest struct {
 	TestName string `json:"test_name"`
 }
 
+// EnabledForRunType returns whether we should run this nettest during a
+// run of the given type, where an empty run type means a manual run.
+func (n *V2Nettest) EnabledForRunType(runType model.RunType) bool {
+	enabled := n.IsManualRunEnabled
+	if runType == model.RunTypeTimed {
+		enabled = n.IsBackgroundRunEnabled
+	}
+	return enabled == nil || *enabled
+}
+
+// v2RunType returns the run type of the given config.
+func v2RunType(config *LinkConfig) model.RunType {
+	if config.RunType == "" {
+		return model.RunTypeManual
+	}
+	return config.RunType
+}
+
 // v2EngineDescriptorRequest is the request body for the OONI Run v2
 // engine-descriptor endpoint.
 type v2EngineDescriptorRequest struct {
@@ -131,7 +149,7 @@ func getV2EngineDescriptor(ctx context.Context, config *LinkConfig,
 	client model.HTTPClient, logger model.Logger, URL string) (*V2Descriptor, error) {
 	request := &v2EngineDescriptorRequest{
 		IsCharging:           true,
-		RunType:              string(model.RunTypeManual),
+		RunType:              string(v2RunType(config)),
 		ProbeCC:              config.ProbeCC,
 		ProbeASN:             config.ProbeASN,
 		NetworkType:          "wifi",
@@ -289,10 +307,19 @@ func V2MeasureDescriptor(ctx context.Context, config *LinkConfig, desc *V2Descri
 			continue
 		}
 
+		// skip the nettests that the link disables for this run type
+		if runType := v2RunType(config); !nettest.EnabledForRunType(runType) {
+			logger.Infof("oonirun: skipping %s, which is disabled for %s runs", nettest.TestName, runType)
+			v2CountSkippedNettests.Add(1)
+			continue
+		}
+
 		// construct an experiment from the current nettest
 		exp := &Experiment{
 			Annotations:            config.Annotations,
+			Checkpoints:            config.KVStore,
 			ExtraOptions:           make(map[string]any),
+			FollowUps:              config.FollowUps,
 			InitialOptions:         nettest.Options,
 			Inputs:                 nettest.Inputs,
 			InputsExtra:            nettest.InputsExtra,
@@ -302,9 +329,12 @@ func V2MeasureDescriptor(ctx context.Context, config *LinkConfig, desc *V2Descri
 			NoCollector:            config.NoCollector,
 			NoCredentials:          config.NoCredentials,
 			NoJSON:                 config.NoJSON,
+			Parallelism:            config.Parallelism,
 			Random:                 config.Random,
 			ReportFile:             config.ReportFile,
+			Resume:                 config.Resume,
 			Session:                config.Session,
+			SubmitQueue:            config.SubmitQueue,
 			newExperimentBuilderFn: nil,
 			newTargetLoaderFn:      nil,
 			newSubmitterFn:         nil,
@@ -330,6 +360,13 @@ var ErrNeedToAcceptChanges = errors.New("oonirun: need to accept changes")
 
 // v2DescriptorDiff shows what changed between the old and the new descriptors.
 func v2DescriptorDiff(oldValue, newValue *V2Descriptor, URL string) string {
+	return V2DescriptorDiff("OLD "+URL, oldValue, "NEW "+URL, newValue)
+}
+
+// V2DescriptorDiff returns a unified diff showing what changed between the old
+// and the new descriptors, which we name using the given file names. The diff is
+// empty when the descriptors are equal.
+func V2DescriptorDiff(oldFile string, oldValue *V2Descriptor, newFile string, newValue *V2Descriptor) string {
 	// JSON serialize old descriptor
 	oldData, err := json.MarshalIndent(oldValue, "", "  ")
 	runtimex.PanicOnError(err, "json.MarshalIndent failed unexpectedly")
@@ -341,10 +378,6 @@ func v2DescriptorDiff(oldValue, newValue *V2Descriptor, URL string) string {
 	// make sure the serializations are newline-terminated
 	oldString, newString := string(oldData)+"\n", string(newData)+"\n"
 
-	// generate names for the final diff
-	oldFile := "OLD " + URL
-	newFile := "NEW " + URL
-
 	// compute the edits to update from the old to the new descriptor
 	edits := myers.ComputeEdits(span.URIFromPath(oldFile), oldString, newString)
 
@@ -366,10 +399,42 @@ func v2MeasureHTTPS(ctx context.Context, config *LinkConfig, URL string) error {
 	logger := config.Session.Logger()
 	logger.Infof("oonirun/v2: running %s", URL)
 
+	// fetch the possibly-new descriptor and update the cache
+	newValue, _, err := V2RefreshDescriptor(ctx, config, URL)
+	if err != nil {
+		return err
+	}
+
+	// measure using the possibly-new descriptor
+	//
+	// note: this function gracefully handles nil values
+	return V2MeasureDescriptor(ctx, config, newValue)
+}
+
+// V2CachedDescriptor returns the descriptor of the given URL that we cached
+// when we last fetched it, or nil if we have not fetched it yet.
+func V2CachedDescriptor(kvStore model.KeyValueStore, URL string) (*V2Descriptor, error) {
+	cache, err := v2DescriptorCacheLoad(kvStore)
+	if err != nil {
+		return nil, err
+	}
+	return cache.Entries[URL], nil
+}
+
+// V2RefreshDescriptor fetches the descriptor of the given HTTPS v2 OONI Run URL
+// and returns it along with a diff showing what changed since the last time we
+// fetched it, which is empty when nothing changed.
+//
+// If there are any changes and config.AcceptChanges is false, this function logs
+// what has changed and returns an ErrNeedToAcceptChanges error. Otherwise, it
+// updates the on-disk cache of OONI Run v2 links with the new descriptor.
+func V2RefreshDescriptor(ctx context.Context, config *LinkConfig, URL string) (*V2Descriptor, string, error) {
+	logger := config.Session.Logger()
+
 	// load the descriptor from the cache
 	cache, err := v2DescriptorCacheLoad(config.KVStore)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
 	// pull a possibly new descriptor without updating the old descriptor
@@ -380,7 +445,7 @@ func v2MeasureHTTPS(ctx context.Context, config *LinkConfig, URL string) error {
 	}
 	oldValue, newValue, err := cache.PullChangesWithoutSideEffects(ctx, config, clnt, logger, URL, auth)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
 	// compare the new descriptor to the old descriptor
@@ -390,20 +455,16 @@ func v2MeasureHTTPS(ctx context.Context, config *LinkConfig, URL string) error {
 	if !config.AcceptChanges && diff != "" {
 		logger.Warnf("oonirun: %s changed as follows:\n\n%s", URL, diff)
 		logger.Warnf("oonirun: we are not going to run this link until you accept changes")
-		return ErrNeedToAcceptChanges
+		return nil, "", ErrNeedToAcceptChanges
 	}
 
 	// in case there are changes, update the descriptor
 	if diff != "" {
 		if err := cache.Update(config.KVStore, URL, newValue); err != nil {
-			return err
+			return nil, "", err
 		}
 	}
-
-	// measure using the possibly-new descriptor
-	//
-	// note: this function gracefully handles nil values
-	return V2MeasureDescriptor(ctx, config, newValue)
+	return newValue, diff, nil
 }
 
 func v2MaybeGetAuthenticationTokenFromFile(path string) (string, error) {
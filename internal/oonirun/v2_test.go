@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ooni/probe-cli/v3/internal/httpclientx"
@@ -264,6 +265,93 @@ func TestOONIRunV2LinkEmptyTestName(t *testing.T) {
 	}
 }
 
+func TestV2NettestEnabledForRunType(t *testing.T) {
+	enabled, disabled := true, false
+	nettest := &V2Nettest{}
+	if !nettest.EnabledForRunType(model.RunTypeManual) || !nettest.EnabledForRunType(model.RunTypeTimed) {
+		t.Fatal("nettests should be enabled by default")
+	}
+
+	nettest = &V2Nettest{IsBackgroundRunEnabled: &disabled, IsManualRunEnabled: &enabled}
+	if !nettest.EnabledForRunType(model.RunTypeManual) || !nettest.EnabledForRunType("") {
+		t.Fatal("expected the nettest to be enabled for manual runs")
+	}
+	if nettest.EnabledForRunType(model.RunTypeTimed) {
+		t.Fatal("expected the nettest to be disabled for timed runs")
+	}
+
+	nettest = &V2Nettest{IsBackgroundRunEnabled: &enabled, IsManualRunEnabled: &disabled}
+	if nettest.EnabledForRunType(model.RunTypeManual) || !nettest.EnabledForRunType(model.RunTypeTimed) {
+		t.Fatal("expected the nettest to only be enabled for timed runs")
+	}
+}
+
+func TestOONIRunV2LinkSkipsDisabledNettests(t *testing.T) {
+	// load the count of the skipped nettests so we can later on check
+	// whether this count has increased due to running this test
+	skippedPrev := v2CountSkippedNettests.Load()
+
+	disabled := false
+	descriptor := &V2Descriptor{
+		Name:   "integration-test",
+		Author: "integration-test",
+		Nettests: []V2Nettest{{
+			IsBackgroundRunEnabled: &disabled,
+			Options: json.RawMessage(`{
+				"SleepTime": 10000000
+			}`),
+			TestName: "example",
+		}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.Marshal(descriptor)
+		runtimex.PanicOnError(err, "json.Marshal failed")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	config := &LinkConfig{
+		AcceptChanges: true,
+		KVStore:       &kvstore.Memory{},
+		NoCollector:   true,
+		NoJSON:        true,
+		RunType:       model.RunTypeTimed,
+		Session:       newMinimalFakeSession(),
+	}
+	if err := NewLinkRunner(config, server.URL).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if v2CountSkippedNettests.Load() != skippedPrev+1 {
+		t.Fatal("expected to see 1 more skipped nettest")
+	}
+
+	// make sure we cached the descriptor and that refreshing it again
+	// returns the same descriptor without any diff
+	cached, err := V2CachedDescriptor(config.KVStore, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached == nil || cached.Name != descriptor.Name {
+		t.Fatal("unexpected cached descriptor", cached)
+	}
+	desc, diff, err := V2RefreshDescriptor(context.Background(), config, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc == nil || desc.Name != descriptor.Name || diff != "" {
+		t.Fatal("unexpected refresh result", desc, diff)
+	}
+
+	// make sure that refreshing returns the diff when the descriptor changes
+	descriptor.Name = "integration-test-v2"
+	if _, diff, err = V2RefreshDescriptor(context.Background(), config, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "integration-test-v2") {
+		t.Fatal("unexpected diff", diff)
+	}
+}
+
 func TestOONIRunV2LinkWithAuthentication(t *testing.T) {
 
 	t.Run("authentication raises error if no token is passed", func(t *testing.T) {
@@ -0,0 +1,171 @@
+package oonirun
+
+//
+// OONI Run v2 tooling for local descriptors
+//
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/experimentname"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/registry"
+)
+
+// ErrInvalidDescriptor indicates that a v2 descriptor is not valid.
+var ErrInvalidDescriptor = errors.New("oonirun: invalid descriptor")
+
+// V2ReadDescriptorFile reads a [*V2Descriptor] from the given local file.
+func V2ReadDescriptorFile(filename string) (*V2Descriptor, error) {
+	data, err := os.ReadFile(filename) // #nosec G304 - this is working as intended
+	if err != nil {
+		return nil, err
+	}
+	var desc V2Descriptor
+	if err := json.Unmarshal(data, &desc); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDescriptor, err.Error())
+	}
+	return &desc, nil
+}
+
+// V2IsDescriptorURL returns whether the given descriptor source is an HTTPS URL
+// rather than a local file (see [V2LoadDescriptor]).
+func V2IsDescriptorURL(source string) bool {
+	return strings.HasPrefix(source, "https://")
+}
+
+// V2LoadDescriptor loads a [*V2Descriptor] from the given source, which is either an
+// HTTPS URL, which we fetch like we do when running links, or a local file. When the
+// source is a local file, we do not use the config, which MAY be empty.
+func V2LoadDescriptor(ctx context.Context, config *LinkConfig, source string) (*V2Descriptor, error) {
+	if !V2IsDescriptorURL(source) {
+		return V2ReadDescriptorFile(source)
+	}
+	logger := config.Session.Logger()
+	auth, err := v2MaybeGetAuthenticationTokenFromFile(config.AuthFile)
+	if err != nil {
+		logger.Warnf("oonirun: failed to retrieve auth token: %v", err)
+	}
+	return v2FetchDescriptor(ctx, config, config.Session.DefaultHTTPClient(), logger, source, auth)
+}
+
+// V2ValidateDescriptor checks whether the given descriptor is valid using the
+// experiments registry: each nettest must exist, its options and inputs_extra must
+// be valid options of the experiment, and its inputs must match the experiment's
+// input policy. The returned error joins all the problems we found, each of
+// which wraps [ErrInvalidDescriptor], or is nil when the descriptor is valid.
+func V2ValidateDescriptor(desc *V2Descriptor) error {
+	if desc == nil {
+		return ErrNilDescriptor
+	}
+	var problems []error
+	if desc.Name == "" {
+		problems = append(problems, fmt.Errorf("%w: name is empty", ErrInvalidDescriptor))
+	}
+	if len(desc.Nettests) <= 0 {
+		problems = append(problems, fmt.Errorf("%w: nettests is empty", ErrInvalidDescriptor))
+	}
+	for idx, nettest := range desc.Nettests {
+		for _, problem := range v2ValidateNettest(&nettest) {
+			problems = append(problems, fmt.Errorf("%w: nettests[%d] (%s): %s",
+				ErrInvalidDescriptor, idx, nettest.TestName, problem))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// v2ValidateNettest returns the problems of the given nettest.
+func v2ValidateNettest(nettest *V2Nettest) (problems []string) {
+	if nettest.TestName == "" {
+		return []string{"test_name is empty"}
+	}
+	ff := registry.AllExperiments[experimentname.Canonicalize(nettest.TestName)]
+	if ff == nil {
+		return []string{"no such experiment"}
+	}
+	factory := ff()
+
+	if err := factory.ValidateOptionsJSON(nettest.Options); err != nil {
+		problems = append(problems, fmt.Sprintf("options: %s", err.Error()))
+	}
+
+	switch policy := factory.InputPolicy(); {
+	case policy == model.InputNone && len(nettest.Inputs) > 0:
+		problems = append(problems, "inputs: this experiment does not take inputs")
+	case policy == model.InputStrictlyRequired && len(nettest.Inputs) <= 0 && nettest.TargetsName == "":
+		problems = append(problems, "inputs: this experiment requires inputs or targets_name")
+	}
+	for idx, input := range nettest.Inputs {
+		if input == "" {
+			problems = append(problems, fmt.Sprintf("inputs[%d]: input is empty", idx))
+		}
+	}
+
+	if len(nettest.InputsExtra) > 0 && len(nettest.InputsExtra) != len(nettest.Inputs) {
+		problems = append(problems, fmt.Sprintf("inputs_extra: expected %d entries, found %d",
+			len(nettest.Inputs), len(nettest.InputsExtra)))
+	}
+	for idx, extra := range nettest.InputsExtra {
+		if err := factory.ValidateOptionsJSON(extra); err != nil {
+			problems = append(problems, fmt.Sprintf("inputs_extra[%d]: %s", idx, err.Error()))
+		}
+	}
+	return
+}
+
+// V2ValidateDescriptorFiles reads the descriptors inside the given local files, validates
+// them using [V2ValidateDescriptor], logs whether each of them is valid, and returns an
+// error when we cannot read or parse any of them or any of them is not valid.
+func V2ValidateDescriptorFiles(logger model.Logger, filenames []string) error {
+	var failed int
+	for _, filename := range filenames {
+		desc, err := V2ReadDescriptorFile(filename)
+		if err == nil {
+			err = V2ValidateDescriptor(desc)
+		}
+		if err != nil {
+			logger.Warnf("oonirun: %s is not valid:\n%s", filename, err.Error())
+			failed++
+			continue
+		}
+		logger.Infof("oonirun: %s is valid", filename)
+	}
+	if failed > 0 {
+		return fmt.Errorf("oonirun: %d of %d descriptors are not valid", failed, len(filenames))
+	}
+	return nil
+}
+
+// V2DiffDescriptorSources loads the old and the new descriptors using [V2LoadDescriptor]
+// and returns their differences using [V2DescriptorDiff], which are empty when the
+// two descriptors are equal.
+func V2DiffDescriptorSources(ctx context.Context, config *LinkConfig, oldSource, newSource string) (string, error) {
+	oldValue, err := V2LoadDescriptor(ctx, config, oldSource)
+	if err != nil {
+		return "", fmt.Errorf("oonirun: cannot load %s: %w", oldSource, err)
+	}
+	newValue, err := V2LoadDescriptor(ctx, config, newSource)
+	if err != nil {
+		return "", fmt.Errorf("oonirun: cannot load %s: %w", newSource, err)
+	}
+	return V2DescriptorDiff(oldSource, oldValue, newSource, newValue), nil
+}
+
+// V2MeasureDescriptorFile reads the descriptor inside the given local file and performs
+// the measurements it describes. Like when running links, we do not validate the
+// descriptor using [V2ValidateDescriptor], which is stricter than running it.
+func V2MeasureDescriptorFile(ctx context.Context, config *LinkConfig, filename string) error {
+	desc, err := V2ReadDescriptorFile(filename)
+	if err != nil {
+		return err
+	}
+	logger := config.Session.Logger()
+	logger.Infof("oonirun: running '%s'", desc.Name)
+	logger.Infof("oonirun: link authored by '%s'", desc.Author)
+	return V2MeasureDescriptor(ctx, config, desc)
+}
@@ -0,0 +1,260 @@
+package oonirun
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestV2ValidateDescriptor(t *testing.T) {
+	type testcase struct {
+		// name is the name of the test case
+		name string
+
+		// desc is the descriptor to validate
+		desc *V2Descriptor
+
+		// expectProblems contains the problems we expect
+		expectProblems []string
+	}
+
+	cases := []testcase{{
+		name:           "with a nil descriptor",
+		desc:           nil,
+		expectProblems: []string{ErrNilDescriptor.Error()},
+	}, {
+		name: "with a valid descriptor",
+		desc: &V2Descriptor{
+			Name: "test",
+			Nettests: []V2Nettest{{
+				Inputs:   []string{"https://www.example.com/"},
+				TestName: "web_connectivity",
+			}, {
+				Inputs:      []string{"https://dns.google/dns-query"},
+				InputsExtra: []json.RawMessage{[]byte(`{"domain":"example.org"}`)},
+				TestName:    "dnscheck",
+			}, {
+				Inputs:   []string{"8.8.8.8:443"},
+				Options:  []byte(`{"Repetitions":3}`),
+				TestName: "tcpping",
+			}, {
+				TestName: "WebConnectivity", // historical name
+			}},
+		},
+		expectProblems: nil,
+	}, {
+		name:           "with an empty descriptor",
+		desc:           &V2Descriptor{},
+		expectProblems: []string{"name is empty", "nettests is empty"},
+	}, {
+		name: "with invalid nettests",
+		desc: &V2Descriptor{
+			Name: "test",
+			Nettests: []V2Nettest{{
+				TestName: "",
+			}, {
+				TestName: "web_conectivity",
+			}, {
+				Inputs:   []string{"8.8.8.8:443"},
+				Options:  []byte(`{"Repetitions":"ten"}`),
+				TestName: "tcpping",
+			}, {
+				TestName: "tcpping",
+			}, {
+				Inputs:   []string{"x"},
+				TestName: "signal",
+			}, {
+				Inputs:      []string{"https://dns.google/dns-query", ""},
+				InputsExtra: []json.RawMessage{[]byte(`{"domian":"example.org"}`)},
+				TestName:    "dnscheck",
+			}},
+		},
+		expectProblems: []string{
+			"nettests[0] (): test_name is empty",
+			"nettests[1] (web_conectivity): no such experiment",
+			"nettests[2] (tcpping): options: json: cannot unmarshal string",
+			"nettests[3] (tcpping): inputs: this experiment requires inputs or targets_name",
+			"nettests[4] (signal): inputs: this experiment does not take inputs",
+			"nettests[5] (dnscheck): inputs[1]: input is empty",
+			"nettests[5] (dnscheck): inputs_extra: expected 2 entries, found 1",
+			`nettests[5] (dnscheck): inputs_extra[0]: json: unknown field "domian"`,
+		},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := V2ValidateDescriptor(tc.desc)
+			if len(tc.expectProblems) <= 0 {
+				if err != nil {
+					t.Fatal("unexpected error", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tc.desc != nil && !errors.Is(err, ErrInvalidDescriptor) {
+				t.Fatal("unexpected error", err)
+			}
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != len(tc.expectProblems) {
+				t.Fatal("unexpected problems", lines)
+			}
+			for idx, problem := range tc.expectProblems {
+				if !strings.Contains(lines[idx], problem) {
+					t.Fatal("expected", problem, "got", lines[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestV2ReadDescriptorFile(t *testing.T) {
+	t.Run("with a valid file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "descriptor.json")
+		if err := os.WriteFile(filename, []byte(`{"name":"test","nettests":[{"test_name":"dnscheck"}]}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		desc, err := V2LoadDescriptor(context.Background(), &LinkConfig{}, filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if desc.Name != "test" || len(desc.Nettests) != 1 || desc.Nettests[0].TestName != "dnscheck" {
+			t.Fatal("unexpected descriptor", desc)
+		}
+	})
+
+	t.Run("with a nonexistent file", func(t *testing.T) {
+		_, err := V2ReadDescriptorFile(filepath.Join(t.TempDir(), "nonexistent.json"))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with invalid JSON", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "descriptor.json")
+		if err := os.WriteFile(filename, []byte(`{`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := V2ReadDescriptorFile(filename); !errors.Is(err, ErrInvalidDescriptor) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestV2MeasureDescriptorFile(t *testing.T) {
+	t.Run("we run descriptors that would not pass validation", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "descriptor.json")
+		data := []byte(`{"name":"test","nettests":[{"test_name":"example","options":{"Antani":true}}]}`)
+		if err := os.WriteFile(filename, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		sess := newMinimalFakeSession()
+		sess.MockNewExperimentBuilder = func(name string) (model.ExperimentBuilder, error) {
+			names = append(names, name)
+			return nil, errors.New("mocked error")
+		}
+		if err := V2MeasureDescriptorFile(context.Background(), &LinkConfig{Session: sess}, filename); err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 1 || names[0] != "example" {
+			t.Fatal("unexpected experiments", names)
+		}
+	})
+
+	t.Run("with a file we cannot parse", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "descriptor.json")
+		if err := os.WriteFile(filename, []byte(`{`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		// Note: using a nil session would cause a panic if we tried to run the descriptor
+		err := V2MeasureDescriptorFile(context.Background(), &LinkConfig{}, filename)
+		if !errors.Is(err, ErrInvalidDescriptor) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestV2ValidateDescriptorFiles(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	if err := os.WriteFile(valid, []byte(`{"name":"test","nettests":[{"test_name":"example"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"name":"test","nettests":[{"test_name":"antani"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	nonexistent := filepath.Join(dir, "nonexistent.json")
+
+	if err := V2ValidateDescriptorFiles(model.DiscardLogger, []string{valid}); err != nil {
+		t.Fatal(err)
+	}
+	err := V2ValidateDescriptorFiles(model.DiscardLogger, []string{valid, invalid, nonexistent})
+	if err == nil || err.Error() != "oonirun: 2 of 3 descriptors are not valid" {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestV2DiffDescriptorSources(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old.json")
+	if err := os.WriteFile(oldFile, []byte(`{"name":"test"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	newFile := filepath.Join(dir, "new.json")
+	if err := os.WriteFile(newFile, []byte(`{"name":"other"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	nonexistent := filepath.Join(dir, "nonexistent.json")
+
+	t.Run("with equal descriptors", func(t *testing.T) {
+		diff, err := V2DiffDescriptorSources(context.Background(), &LinkConfig{}, oldFile, oldFile)
+		if err != nil || diff != "" {
+			t.Fatal("unexpected result", diff, err)
+		}
+	})
+
+	t.Run("with different descriptors", func(t *testing.T) {
+		diff, err := V2DiffDescriptorSources(context.Background(), &LinkConfig{}, oldFile, newFile)
+		if err != nil || !strings.Contains(diff, `+  "name": "other",`) {
+			t.Fatal("unexpected result", diff, err)
+		}
+	})
+
+	t.Run("when we cannot load a descriptor", func(t *testing.T) {
+		for _, sources := range [][2]string{{nonexistent, newFile}, {oldFile, nonexistent}} {
+			_, err := V2DiffDescriptorSources(context.Background(), &LinkConfig{}, sources[0], sources[1])
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Fatal("unexpected error", err)
+			}
+		}
+	})
+}
+
+func TestV2IsDescriptorURL(t *testing.T) {
+	if !V2IsDescriptorURL("https://run.ooni.org/v2/123") {
+		t.Fatal("expected true")
+	}
+	if V2IsDescriptorURL("descriptor.json") || V2IsDescriptorURL("http://example.com/") {
+		t.Fatal("expected false")
+	}
+}
+
+func TestV2DescriptorDiff(t *testing.T) {
+	desc := &V2Descriptor{Name: "test"}
+	if diff := V2DescriptorDiff("a.json", desc, "b.json", desc); diff != "" {
+		t.Fatal("expected no diff", diff)
+	}
+	diff := V2DescriptorDiff("a.json", desc, "b.json", &V2Descriptor{Name: "other"})
+	if !strings.Contains(diff, "--- a.json") || !strings.Contains(diff, `+  "name": "other",`) {
+		t.Fatal("unexpected diff", diff)
+	}
+}
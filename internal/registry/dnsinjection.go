@@ -0,0 +1,27 @@
+package registry
+
+//
+// Registers the `dnsinjection' experiment.
+//
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/experiment/dnsinjection"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	const canonicalName = "dnsinjection"
+	AllExperiments[canonicalName] = func() *Factory {
+		return &Factory{
+			build: func(config interface{}) model.ExperimentMeasurer {
+				return dnsinjection.NewExperimentMeasurer(
+					*config.(*dnsinjection.Config),
+				)
+			},
+			canonicalName:    canonicalName,
+			config:           &dnsinjection.Config{},
+			enabledByDefault: true,
+			inputPolicy:      model.InputNone,
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package registry
+
+//
+// Registers the `email_reachability' experiment.
+//
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/experiment/emailreachability"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	const canonicalName = "email_reachability"
+	AllExperiments[canonicalName] = func() *Factory {
+		return &Factory{
+			build: func(config interface{}) model.ExperimentMeasurer {
+				return emailreachability.NewExperimentMeasurer()
+			},
+			canonicalName:    canonicalName,
+			config:           &emailreachability.Config{},
+			enabledByDefault: true,
+			inputPolicy:      model.InputStrictlyRequired,
+			newLoader:        emailreachability.NewLoader,
+		}
+	}
+}
@@ -5,6 +5,7 @@ package registry
 //
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -58,6 +59,8 @@ func (b *Factory) NewTargetLoader(config *model.ExperimentTargetLoaderConfig) mo
 		StaticInputs:       config.StaticInputs,
 		StaticInputsConfig: config.StaticInputsConfig,
 		SourceFiles:        config.SourceFiles,
+		TargetFilter:       config.TargetFilter,
+		TestLists:          config.TestLists,
 	}
 
 	// If an experiment implements richer input, it will use its custom loader
@@ -268,6 +271,28 @@ func (b *Factory) SetOptionsJSON(value json.RawMessage) error {
 	return json.Unmarshal(value, b.config)
 }
 
+// ValidateOptionsJSON checks whether we could set the options contained in the given
+// [json.RawMessage] using SetOptionsJSON. Unlike SetOptionsJSON, this method fails
+// when the options contain unknown fields and does not modify the configuration.
+func (b *Factory) ValidateOptionsJSON(value json.RawMessage) error {
+	// handle the case where the options are empty
+	if len(value) <= 0 {
+		return nil
+	}
+
+	// make sure we're dealing with a pointer to a struct
+	ptrinfo := reflect.ValueOf(b.config)
+	if ptrinfo.Kind() != reflect.Ptr || ptrinfo.Elem().Kind() != reflect.Struct {
+		return ErrConfigIsNotAStructPointer
+	}
+
+	// unmarshal into a scratch copy of the configuration
+	scratch := reflect.New(ptrinfo.Elem().Type())
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(scratch.Interface())
+}
+
 // fieldbyname return v's field whose name is equal to the given key.
 func (b *Factory) fieldbyname(v interface{}, key string) (reflect.Value, error) {
 	// See https://stackoverflow.com/a/6396678/4354461
@@ -564,6 +564,84 @@ func TestFactorySetOptionsJSON(t *testing.T) {
 	}
 }
 
+func TestFactoryValidateOptionsJSON(t *testing.T) {
+	// PersonRecord is a fake experiment configuration.
+	type PersonRecord struct {
+		Name    string
+		Age     int64
+		Friends []string
+	}
+
+	// testcase is a test case for this function.
+	type testcase struct {
+		// name is the name of the test case
+		name string
+
+		// config is the factory's config
+		config any
+
+		// rawJSON contains the raw JSON to validate
+		rawJSON json.RawMessage
+
+		// expectErr is the error we expect
+		expectErr error
+	}
+
+	cases := []testcase{{
+		name:      "we correctly accept zero-length options",
+		config:    &PersonRecord{},
+		rawJSON:   []byte{},
+		expectErr: nil,
+	}, {
+		name:      "we accept known fields",
+		config:    &PersonRecord{},
+		rawJSON:   []byte(`{"Name":"foo","Age":55}`),
+		expectErr: nil,
+	}, {
+		name:      "we reject unknown fields",
+		config:    &PersonRecord{},
+		rawJSON:   []byte(`{"Nmae":"foo"}`),
+		expectErr: errors.New(`json: unknown field "Nmae"`),
+	}, {
+		name:      "we reject fields with the wrong type",
+		config:    &PersonRecord{},
+		rawJSON:   []byte(`{"Age":"foo"}`),
+		expectErr: errors.New("json: cannot unmarshal string into Go struct field PersonRecord.Age of type int64"),
+	}, {
+		name:      "we reject a config that is not a pointer to struct",
+		config:    PersonRecord{},
+		rawJSON:   []byte(`{}`),
+		expectErr: ErrConfigIsNotAStructPointer,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			factory := &Factory{config: tc.config}
+			err := factory.ValidateOptionsJSON(tc.rawJSON)
+			switch {
+			case err == nil && tc.expectErr == nil:
+			case err != nil && tc.expectErr != nil:
+				if err.Error() != tc.expectErr.Error() {
+					t.Fatal("expected", tc.expectErr, "got", err)
+				}
+			default:
+				t.Fatal("expected", tc.expectErr, "got", err)
+			}
+		})
+	}
+
+	t.Run("we do not modify the config", func(t *testing.T) {
+		config := &PersonRecord{Name: "bar"}
+		factory := &Factory{config: config}
+		if err := factory.ValidateOptionsJSON([]byte(`{"Name":"foo"}`)); err != nil {
+			t.Fatal(err)
+		}
+		if config.Name != "bar" {
+			t.Fatal("unexpected name", config.Name)
+		}
+	})
+}
+
 func TestNewFactory(t *testing.T) {
 	// experimentSpecificExpectations contains expectations for an experiment
 	type experimentSpecificExpectations struct {
@@ -588,6 +666,10 @@ func TestNewFactory(t *testing.T) {
 			enabledByDefault: true,
 			inputPolicy:      model.InputOrStaticDefault,
 		},
+		"dnsinjection": {
+			enabledByDefault: true,
+			inputPolicy:      model.InputNone,
+		},
 		"dnsping": {
 			enabledByDefault: true,
 			inputPolicy:      model.InputOrStaticDefault,
@@ -596,6 +678,10 @@ func TestNewFactory(t *testing.T) {
 			enabledByDefault: true,
 			inputPolicy:      model.InputOptional,
 		},
+		"email_reachability": {
+			enabledByDefault: true,
+			inputPolicy:      model.InputStrictlyRequired,
+		},
 		"example": {
 			enabledByDefault: true,
 			inputPolicy:      model.InputNone,
@@ -627,6 +713,10 @@ func TestNewFactory(t *testing.T) {
 			inputPolicy:      model.InputOrQueryBackend,
 			interruptible:    true,
 		},
+		"pathtrace": {
+			enabledByDefault: true,
+			inputPolicy:      model.InputStrictlyRequired,
+		},
 		"portfiltering": {
 			enabledByDefault: true,
 			inputPolicy:      model.InputNone,
@@ -635,10 +725,18 @@ func TestNewFactory(t *testing.T) {
 			enabledByDefault: true,
 			inputPolicy:      model.InputOptional,
 		},
+		"ptreachability": {
+			enabledByDefault: true,
+			inputPolicy:      model.InputStrictlyRequired,
+		},
 		"quicping": {
 			enabledByDefault: true,
 			inputPolicy:      model.InputStrictlyRequired,
 		},
+		"quicvariations": {
+			enabledByDefault: true,
+			inputPolicy:      model.InputStrictlyRequired,
+		},
 		"riseupvpn": {
 			// Note: riseupvpn is not enabled by default because it has been flaky
 			// in the past and we want to be defensive here.
@@ -649,6 +747,10 @@ func TestNewFactory(t *testing.T) {
 			enabledByDefault: true,
 			inputPolicy:      model.InputStrictlyRequired,
 		},
+		"service_reachability": {
+			enabledByDefault: true,
+			inputPolicy:      model.InputOrStaticDefault,
+		},
 		"signal": {
 			enabledByDefault: true,
 			inputPolicy:      model.InputNone,
@@ -683,6 +785,10 @@ func TestNewFactory(t *testing.T) {
 			enabledByDefault: true,
 			inputPolicy:      model.InputNone,
 		},
+		"time_integrity": {
+			enabledByDefault: true,
+			inputPolicy:      model.InputNone,
+		},
 		"tlsping": {
 			enabledByDefault: true,
 			inputPolicy:      model.InputStrictlyRequired,
@@ -725,6 +831,11 @@ func TestNewFactory(t *testing.T) {
 			enabledByDefault: true,
 			inputPolicy:      model.InputNone,
 		},
+		"wireguard": {
+			enabledByDefault: true,
+			inputPolicy:      model.InputStrictlyRequired,
+			interruptible:    true,
+		},
 	}
 
 	// testCase is a test case checked by this func
@@ -0,0 +1,27 @@
+package registry
+
+//
+// Registers the `pathtrace' experiment.
+//
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/experiment/pathtrace"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	const canonicalName = "pathtrace"
+	AllExperiments[canonicalName] = func() *Factory {
+		return &Factory{
+			build: func(config interface{}) model.ExperimentMeasurer {
+				return pathtrace.NewExperimentMeasurer(
+					*config.(*pathtrace.Config),
+				)
+			},
+			canonicalName:    canonicalName,
+			config:           &pathtrace.Config{},
+			enabledByDefault: true,
+			inputPolicy:      model.InputStrictlyRequired,
+		}
+	}
+}
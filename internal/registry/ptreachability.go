@@ -0,0 +1,26 @@
+package registry
+
+//
+// Registers the `ptreachability' experiment.
+//
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/experiment/ptreachability"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	const canonicalName = "ptreachability"
+	AllExperiments[canonicalName] = func() *Factory {
+		return &Factory{
+			build: func(config interface{}) model.ExperimentMeasurer {
+				return ptreachability.NewExperimentMeasurer()
+			},
+			canonicalName:    canonicalName,
+			config:           &ptreachability.Config{},
+			enabledByDefault: true,
+			inputPolicy:      model.InputStrictlyRequired,
+			newLoader:        ptreachability.NewLoader,
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package registry
+
+//
+// Registers the `quicvariations' experiment.
+//
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/experiment/quicvariations"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	const canonicalName = "quicvariations"
+	AllExperiments[canonicalName] = func() *Factory {
+		return &Factory{
+			build: func(config interface{}) model.ExperimentMeasurer {
+				return quicvariations.NewExperimentMeasurer(
+					*config.(*quicvariations.Config),
+				)
+			},
+			canonicalName:    canonicalName,
+			config:           &quicvariations.Config{},
+			enabledByDefault: true,
+			inputPolicy:      model.InputStrictlyRequired,
+		}
+	}
+}
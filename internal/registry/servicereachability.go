@@ -0,0 +1,27 @@
+package registry
+
+//
+// Registers the `service_reachability' experiment.
+//
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/experiment/servicereachability"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	const canonicalName = "service_reachability"
+	AllExperiments[canonicalName] = func() *Factory {
+		return &Factory{
+			build: func(config interface{}) model.ExperimentMeasurer {
+				return servicereachability.NewExperimentMeasurer(
+					*config.(*servicereachability.Config),
+				)
+			},
+			canonicalName:    canonicalName,
+			config:           &servicereachability.Config{},
+			enabledByDefault: true,
+			inputPolicy:      model.InputOrStaticDefault,
+		}
+	}
+}
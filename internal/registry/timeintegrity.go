@@ -0,0 +1,27 @@
+package registry
+
+//
+// Registers the `time_integrity' experiment.
+//
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/experiment/timeintegrity"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	const canonicalName = "time_integrity"
+	AllExperiments[canonicalName] = func() *Factory {
+		return &Factory{
+			build: func(config any) model.ExperimentMeasurer {
+				return timeintegrity.NewExperimentMeasurer(
+					*config.(*timeintegrity.Config),
+				)
+			},
+			canonicalName:    canonicalName,
+			config:           &timeintegrity.Config{},
+			enabledByDefault: true,
+			inputPolicy:      model.InputNone,
+		}
+	}
+}
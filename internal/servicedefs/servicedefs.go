@@ -0,0 +1,334 @@
+// Package servicedefs contains versioned definitions of the services whose
+// reachability we measure using the service_reachability experiment.
+//
+// A definition lists the DNS names, TCP, TLS and QUIC endpoints, and HTTP URLs
+// used by a service, grouped into components. Each component has a success
+// criterion telling whether all or any of its checks must succeed for the
+// component to be reachable. We embed a copy of the definitions and we use
+// more recent copies when the check-in API provides them.
+package servicedefs
+
+import (
+	"crypto/x509"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/checkincache"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// These are the supported check types.
+const (
+	// CheckTypeDNS resolves a domain name.
+	CheckTypeDNS = "dns"
+
+	// CheckTypeTCP connects to a TCP endpoint.
+	CheckTypeTCP = "tcp"
+
+	// CheckTypeTLS connects to a TCP endpoint and performs a TLS handshake.
+	CheckTypeTLS = "tls"
+
+	// CheckTypeQUIC performs a QUIC handshake with a UDP endpoint.
+	CheckTypeQUIC = "quic"
+
+	// CheckTypeHTTP performs an HTTP or HTTPS round trip.
+	CheckTypeHTTP = "http"
+)
+
+// These are the supported component success criteria.
+const (
+	// SuccessAll means that all the checks must succeed.
+	SuccessAll = "all"
+
+	// SuccessAny means that at least one check must succeed.
+	SuccessAny = "any"
+)
+
+// Check is a single check belonging to a [*Component].
+type Check struct {
+	// Type is the MANDATORY check type (e.g., [CheckTypeTLS]).
+	Type string `json:"type"`
+
+	// ALPN is the OPTIONAL ALPN for TLS and QUIC checks.
+	ALPN []string `json:"alpn,omitempty"`
+
+	// Domain is the domain to resolve, which is MANDATORY
+	// when using the [CheckTypeDNS] type.
+	Domain string `json:"domain,omitempty"`
+
+	// Endpoint is the host:port endpoint, which is MANDATORY when using the
+	// [CheckTypeTCP], [CheckTypeTLS] and [CheckTypeQUIC] types. The host
+	// may either be a domain name or an IP address.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// ExpectedASNs OPTIONALLY contains the ASNs to which the resolved IP
+	// addresses must belong for us to consider the DNS lookup consistent.
+	ExpectedASNs []int64 `json:"expected_asns,omitempty"`
+
+	// ExpectedStatusCodes OPTIONALLY contains the HTTP status codes we
+	// expect. When empty, we accept any status code.
+	ExpectedStatusCodes []int64 `json:"expected_status_codes,omitempty"`
+
+	// Method is the OPTIONAL HTTP method. When empty, we use GET.
+	Method string `json:"method,omitempty"`
+
+	// RootCAs is the OPTIONAL name of the [Definition] certificates
+	// bundle to trust in addition to the Mozilla bundle.
+	RootCAs string `json:"root_cas,omitempty"`
+
+	// SNI is the OPTIONAL SNI for TLS and QUIC checks. When empty, we
+	// use the endpoint host, unless it is an IP address.
+	SNI string `json:"sni,omitempty"`
+
+	// URL is the http or https URL, which is MANDATORY when
+	// using the [CheckTypeHTTP] type.
+	URL string `json:"url,omitempty"`
+}
+
+// Target returns the domain, endpoint or URL measured by the check.
+func (c *Check) Target() string {
+	switch c.Type {
+	case CheckTypeDNS:
+		return c.Domain
+	case CheckTypeHTTP:
+		return c.URL
+	default:
+		return c.Endpoint
+	}
+}
+
+// Component is a part of a service (e.g., its web interface).
+type Component struct {
+	// Name is the MANDATORY component name, unique within a [Definition].
+	Name string `json:"name"`
+
+	// Checks contains the MANDATORY checks.
+	Checks []*Check `json:"checks"`
+
+	// Success is the OPTIONAL success criterion (e.g., [SuccessAny]). When
+	// empty, we use [SuccessAll].
+	Success string `json:"success,omitempty"`
+}
+
+// Definition is a versioned service definition.
+type Definition struct {
+	// Name is the MANDATORY service name (e.g., "signal").
+	Name string `json:"name"`
+
+	// Certificates OPTIONALLY maps the name of a certificates bundle to
+	// the PEM-encoded certificates it contains.
+	Certificates map[string][]string `json:"certificates,omitempty"`
+
+	// Components contains the MANDATORY service components.
+	Components []*Component `json:"components"`
+
+	// Version is the MANDATORY definition version. We only replace the embedded
+	// definition with definitions having a greater version number.
+	Version int64 `json:"version"`
+}
+
+// ErrInvalidDefinition indicates that a service definition is invalid.
+var ErrInvalidDefinition = errors.New("servicedefs: invalid service definition")
+
+// ErrNoSuchService indicates that there is no definition for a service.
+var ErrNoSuchService = errors.New("servicedefs: no such service")
+
+// Parse parses and validates a JSON-serialized service definition.
+func Parse(data []byte) (*Definition, error) {
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDefinition, err.Error())
+	}
+	if err := def.validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// ParseList parses and validates a JSON-serialized list of service definitions,
+// which is the format used by the embedded definitions and by the check-in API.
+func ParseList(data []byte) ([]*Definition, error) {
+	var defs []*Definition
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDefinition, err.Error())
+	}
+	names := map[string]bool{}
+	for _, def := range defs {
+		if def == nil {
+			return nil, fmt.Errorf("%w: null definition", ErrInvalidDefinition)
+		}
+		if err := def.validate(); err != nil {
+			return nil, err
+		}
+		if names[def.Name] {
+			return nil, fmt.Errorf("%w: %s: duplicate definition", ErrInvalidDefinition, def.Name)
+		}
+		names[def.Name] = true
+	}
+	return defs, nil
+}
+
+func (d *Definition) validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidDefinition)
+	}
+	if d.Version <= 0 {
+		return fmt.Errorf("%w: %s: invalid version %d", ErrInvalidDefinition, d.Name, d.Version)
+	}
+	for bundle, certs := range d.Certificates {
+		for _, cert := range certs {
+			if !x509.NewCertPool().AppendCertsFromPEM([]byte(cert)) {
+				return fmt.Errorf("%w: %s: certificates: %s: invalid PEM", ErrInvalidDefinition, d.Name, bundle)
+			}
+		}
+	}
+	if len(d.Components) <= 0 {
+		return fmt.Errorf("%w: %s: no components", ErrInvalidDefinition, d.Name)
+	}
+	names := map[string]bool{}
+	for _, comp := range d.Components {
+		if comp == nil || comp.Name == "" || names[comp.Name] {
+			return fmt.Errorf("%w: %s: missing or duplicate component name", ErrInvalidDefinition, d.Name)
+		}
+		names[comp.Name] = true
+		switch comp.Success {
+		case "", SuccessAll, SuccessAny:
+		default:
+			return fmt.Errorf("%w: %s: %s: unknown success criterion %q",
+				ErrInvalidDefinition, d.Name, comp.Name, comp.Success)
+		}
+		if len(comp.Checks) <= 0 {
+			return fmt.Errorf("%w: %s: %s: no checks", ErrInvalidDefinition, d.Name, comp.Name)
+		}
+		for idx, check := range comp.Checks {
+			if err := d.validateCheck(check); err != nil {
+				return fmt.Errorf("%w: %s: %s: checks[%d]: %s",
+					ErrInvalidDefinition, d.Name, comp.Name, idx, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Definition) validateCheck(check *Check) error {
+	if check == nil {
+		return errors.New("null check")
+	}
+	switch check.Type {
+	case CheckTypeDNS:
+		if check.Domain == "" || net.ParseIP(check.Domain) != nil {
+			return errors.New("missing or invalid domain")
+		}
+	case CheckTypeTCP, CheckTypeTLS, CheckTypeQUIC:
+		host, port, err := net.SplitHostPort(check.Endpoint)
+		if err != nil || host == "" || port == "" {
+			return fmt.Errorf("invalid endpoint %q", check.Endpoint)
+		}
+	case CheckTypeHTTP:
+		URL, err := url.Parse(check.URL)
+		if err != nil || (URL.Scheme != "http" && URL.Scheme != "https") || URL.Host == "" {
+			return fmt.Errorf("invalid URL %q", check.URL)
+		}
+	default:
+		return fmt.Errorf("unknown type %q", check.Type)
+	}
+	if _, found := d.Certificates[check.RootCAs]; check.RootCAs != "" && !found {
+		return fmt.Errorf("unknown certificates bundle %q", check.RootCAs)
+	}
+	return nil
+}
+
+// WithCertificates returns a copy of the definition where the given certificates
+// bundle contains the given PEM-encoded certificates.
+func (d *Definition) WithCertificates(bundle string, certs ...string) (*Definition, error) {
+	out := *d
+	out.Certificates = map[string][]string{}
+	for key, value := range d.Certificates {
+		out.Certificates[key] = value
+	}
+	out.Certificates[bundle] = certs
+	if err := out.validate(); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CertPool returns a new Mozilla certificate pool extended with the certificates in the
+// given bundle, or nil, meaning the default pool, when the bundle name is empty.
+func (d *Definition) CertPool(bundle string) *x509.CertPool {
+	if bundle == "" {
+		return nil
+	}
+	pool := netxlite.NewMozillaCertPool()
+	for _, cert := range d.Certificates[bundle] {
+		// Assumption: AppendCertsFromPEM cannot fail because we validated the definition
+		ok := pool.AppendCertsFromPEM([]byte(cert))
+		runtimex.Assert(ok, "pool.AppendCertsFromPEM failed")
+	}
+	return pool
+}
+
+//go:embed definitions.json
+var embeddedDefinitions []byte
+
+var (
+	embeddedOnce   sync.Once
+	embeddedParsed map[string]*Definition
+)
+
+// embedded returns the embedded definitions indexed by name.
+func embedded() map[string]*Definition {
+	embeddedOnce.Do(func() {
+		embeddedParsed = map[string]*Definition{}
+		for _, def := range runtimex.Try1(ParseList(embeddedDefinitions)) {
+			embeddedParsed[def.Name] = def
+		}
+	})
+	return embeddedParsed
+}
+
+// Names returns the sorted names of the embedded definitions.
+func Names() (out []string) {
+	for name := range embedded() {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return
+}
+
+// Embedded returns the embedded definition of the given service.
+func Embedded(name string) (*Definition, error) {
+	def, found := embedded()[name]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchService, name)
+	}
+	return def, nil
+}
+
+// Load returns the definition of the given service cached by the most recent check-in,
+// if it is valid and more recent than the embedded one, and otherwise the embedded one.
+func Load(kvStore model.KeyValueStore, name string) (*Definition, error) {
+	def, err := Embedded(name)
+	data, cacheErr := checkincache.GetServiceDefinitions(kvStore)
+	if cacheErr != nil {
+		return def, err
+	}
+	cached, cacheErr := ParseList(data)
+	if cacheErr != nil {
+		return def, err
+	}
+	for _, entry := range cached {
+		if entry.Name == name && (def == nil || entry.Version > def.Version) {
+			return entry, nil
+		}
+	}
+	return def, err
+}
@@ -0,0 +1,224 @@
+package servicedefs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/checkincache"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestParse(t *testing.T) {
+	type testcase struct {
+		name  string
+		input string
+	}
+
+	cases := []testcase{{
+		name:  "with invalid JSON",
+		input: `{`,
+	}, {
+		name:  "with missing name",
+		input: `{"version":1,"components":[{"name":"a","checks":[{"type":"dns","domain":"x.org"}]}]}`,
+	}, {
+		name:  "with invalid version",
+		input: `{"name":"x","version":0,"components":[{"name":"a","checks":[{"type":"dns","domain":"x.org"}]}]}`,
+	}, {
+		name:  "with invalid certificates",
+		input: `{"name":"x","version":1,"certificates":{"ca":["INVALIDCA"]},"components":[]}`,
+	}, {
+		name:  "without components",
+		input: `{"name":"x","version":1,"components":[]}`,
+	}, {
+		name: "with duplicate component name",
+		input: `{"name":"x","version":1,"components":[{"name":"a","checks":[{"type":"dns","domain":"x.org"}]},` +
+			`{"name":"a","checks":[{"type":"dns","domain":"y.org"}]}]}`,
+	}, {
+		name:  "with unknown success criterion",
+		input: `{"name":"x","version":1,"components":[{"name":"a","success":"most","checks":[{"type":"dns","domain":"x.org"}]}]}`,
+	}, {
+		name:  "without checks",
+		input: `{"name":"x","version":1,"components":[{"name":"a","checks":[]}]}`,
+	}, {
+		name:  "with unknown check type",
+		input: `{"name":"x","version":1,"components":[{"name":"a","checks":[{"type":"ping","domain":"x.org"}]}]}`,
+	}, {
+		name:  "with a DNS check for an IP address",
+		input: `{"name":"x","version":1,"components":[{"name":"a","checks":[{"type":"dns","domain":"8.8.8.8"}]}]}`,
+	}, {
+		name:  "with a TLS check without port",
+		input: `{"name":"x","version":1,"components":[{"name":"a","checks":[{"type":"tls","endpoint":"x.org"}]}]}`,
+	}, {
+		name:  "with an HTTP check using an unsupported scheme",
+		input: `{"name":"x","version":1,"components":[{"name":"a","checks":[{"type":"http","url":"ftp://x.org/"}]}]}`,
+	}, {
+		name: "with an unknown certificates bundle",
+		input: `{"name":"x","version":1,"components":[{"name":"a","checks":[` +
+			`{"type":"http","url":"https://x.org/","root_cas":"ca"}]}]}`,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			def, err := Parse([]byte(tc.input))
+			if !errors.Is(err, ErrInvalidDefinition) {
+				t.Fatal("unexpected error", err)
+			}
+			if def != nil {
+				t.Fatal("expected nil definition")
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	t.Run("with duplicate definitions", func(t *testing.T) {
+		input := `[{"name":"x","version":1,"components":[{"name":"a","checks":[{"type":"dns","domain":"x.org"}]}]},` +
+			`{"name":"x","version":2,"components":[{"name":"a","checks":[{"type":"dns","domain":"x.org"}]}]}]`
+		defs, err := ParseList([]byte(input))
+		if !errors.Is(err, ErrInvalidDefinition) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(defs) != 0 {
+			t.Fatal("expected no definitions")
+		}
+	})
+
+	t.Run("with a null definition", func(t *testing.T) {
+		defs, err := ParseList([]byte(`[null]`))
+		if !errors.Is(err, ErrInvalidDefinition) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(defs) != 0 {
+			t.Fatal("expected no definitions")
+		}
+	})
+}
+
+func TestEmbedded(t *testing.T) {
+	expectNames := []string{"facebook_messenger", "signal", "telegram", "whatsapp"}
+	if diff := cmp.Diff(expectNames, Names()); diff != "" {
+		t.Fatal(diff)
+	}
+	for _, name := range Names() {
+		def, err := Embedded(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if def.Name != name || def.Version <= 0 {
+			t.Fatal("unexpected embedded definition")
+		}
+	}
+	if _, err := Embedded("nonexistent"); !errors.Is(err, ErrNoSuchService) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestDefinitionWithCertificates(t *testing.T) {
+	orig, err := Embedded("signal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("with invalid certificates", func(t *testing.T) {
+		def, err := orig.WithCertificates("signal", "INVALIDCA")
+		if !errors.Is(err, ErrInvalidDefinition) {
+			t.Fatal("unexpected error", err)
+		}
+		if def != nil {
+			t.Fatal("expected nil definition")
+		}
+	})
+
+	t.Run("with valid certificates", func(t *testing.T) {
+		certs := orig.Certificates["signal"][:1]
+		def, err := orig.WithCertificates("signal", certs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(def.Certificates["signal"]) != 1 || len(orig.Certificates["signal"]) != 2 {
+			t.Fatal("expected to modify only the copy")
+		}
+		if def.CertPool("signal") == nil {
+			t.Fatal("expected a cert pool")
+		}
+		if def.CertPool("") != nil {
+			t.Fatal("expected the default cert pool")
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	store := func(data string) model.KeyValueStore {
+		kvStore := &kvstore.Memory{}
+		resp := &model.OOAPICheckInResult{
+			Conf: model.OOAPICheckInResultConfig{
+				ServiceDefinitions: []byte(data),
+			},
+		}
+		if err := checkincache.Store(kvStore, resp); err != nil {
+			t.Fatal(err)
+		}
+		return kvStore
+	}
+
+	embedded, err := Embedded("telegram")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("without cached definitions", func(t *testing.T) {
+		def, err := Load(&kvstore.Memory{}, "telegram")
+		if err != nil || def != embedded {
+			t.Fatal("expected the embedded definition")
+		}
+	})
+
+	t.Run("with invalid cached definitions", func(t *testing.T) {
+		def, err := Load(store(`[{"name":"telegram","version":1000}]`), "telegram")
+		if err != nil || def != embedded {
+			t.Fatal("expected the embedded definition")
+		}
+	})
+
+	t.Run("with an older cached definition", func(t *testing.T) {
+		def, err := Load(store(`[{"name":"telegram","version":1,"components":[`+
+			`{"name":"web","checks":[{"type":"dns","domain":"web.telegram.org"}]}]}]`), "telegram")
+		if err != nil || def != embedded {
+			t.Fatal("expected the embedded definition")
+		}
+	})
+
+	t.Run("with a newer cached definition", func(t *testing.T) {
+		def, err := Load(store(`[{"name":"telegram","version":1000,"components":[`+
+			`{"name":"web","checks":[{"type":"dns","domain":"web.telegram.org"}]}]}]`), "telegram")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if def.Version != 1000 {
+			t.Fatal("expected the cached definition")
+		}
+	})
+
+	t.Run("with a cached definition for a new service", func(t *testing.T) {
+		def, err := Load(store(`[{"name":"example","version":1,"components":[`+
+			`{"name":"web","checks":[{"type":"dns","domain":"www.example.com"}]}]}]`), "example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if def.Name != "example" {
+			t.Fatal("expected the cached definition")
+		}
+	})
+
+	t.Run("with an unknown service", func(t *testing.T) {
+		def, err := Load(&kvstore.Memory{}, "example")
+		if !errors.Is(err, ErrNoSuchService) {
+			t.Fatal("unexpected error", err)
+		}
+		if def != nil {
+			t.Fatal("expected nil definition")
+		}
+	})
+}
@@ -0,0 +1,377 @@
+// Package submitqueue implements a durable queue of measurements that we
+// could not submit, along with a background uploader retrying to submit them
+// using exponential backoff, which survives process restarts because the queue
+// is stored inside a [model.KeyValueStore].
+package submitqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// State is the key containing the queue index.
+const State = "submitqueue.state"
+
+// MaxEntries is the maximum number of entries in the queue. When we exceed
+// this number of entries, we drop the oldest entries (see [*Queue.Dropped]).
+const MaxEntries = 1024
+
+// BackoffBase is the delay before retrying after the first failure.
+const BackoffBase = 30 * time.Second
+
+// BackoffMax is the maximum delay between two attempts.
+const BackoffMax = 6 * time.Hour
+
+// Entry is an entry in the queue.
+type Entry struct {
+	// Attempts is the number of failed submission attempts.
+	Attempts int64 `json:"attempts"`
+
+	// ID uniquely identifies the entry.
+	ID string `json:"id"`
+
+	// LastError is the error that occurred during the last attempt.
+	LastError string `json:"last_error,omitempty"`
+
+	// NextAttempt is the time after which we should retry.
+	NextAttempt time.Time `json:"next_attempt"`
+
+	// Tag is an opaque string that the caller associates with the entry
+	// (e.g., the measurement ID inside the ooniprobe database).
+	Tag string `json:"tag,omitempty"`
+
+	// TestName is the name of the measurement's experiment.
+	TestName string `json:"test_name"`
+}
+
+// queueState is the queue state we store in the key-value store.
+type queueState struct {
+	// Dropped counts the entries we dropped because the queue was full.
+	Dropped int64 `json:"dropped,omitempty"`
+
+	// Entries contains the entries in the queue.
+	Entries []*Entry `json:"entries"`
+}
+
+// Queue is a durable submission queue. The zero value is invalid; use [New].
+//
+// It is safe to call Flush concurrently because we keep track of the entries
+// we are submitting and do not attempt to submit them twice.
+//
+// When the key-value store supports atomic updates (e.g., [*kvstore.FS], which
+// uses a file lock), it is also safe for several processes to share the queue.
+type Queue struct {
+	// inflight contains the IDs of the entries we're submitting.
+	inflight map[string]bool
+
+	// kvStore is the underlying key-value store.
+	kvStore model.KeyValueStore
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// timeNow is the function returning the current time.
+	timeNow func() time.Time
+}
+
+// New creates a new [*Queue] using the given key-value store.
+func New(kvStore model.KeyValueStore) *Queue {
+	return &Queue{
+		inflight: map[string]bool{},
+		kvStore:  kvStore,
+		mu:       sync.Mutex{},
+		timeNow:  time.Now,
+	}
+}
+
+// bodyKey returns the key containing the measurement of the entry with the given ID.
+func bodyKey(ID string) string {
+	return "submitqueue." + ID + ".json"
+}
+
+// Backoff returns the delay to wait after the given number of failed attempts.
+func Backoff(attempts int64) time.Duration {
+	delay := BackoffBase
+	for idx := int64(1); idx < attempts && delay < BackoffMax; idx++ {
+		delay *= 2
+	}
+	return min(delay, BackoffMax)
+}
+
+// decodeState decodes the queue state. We treat a missing or corrupt state as
+// an empty queue because there is nothing else we could do with it.
+func decodeState(data []byte) *queueState {
+	state := &queueState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return &queueState{}
+	}
+	return state
+}
+
+// load loads the queue state. This function MUST be called while holding the mutex.
+func (q *Queue) load() *queueState {
+	data, err := q.kvStore.Get(State)
+	if err != nil {
+		return &queueState{}
+	}
+	return decodeState(data)
+}
+
+// updater is the optional interface of key-value stores that support atomically
+// updating a key, which allows several processes to share the queue state.
+type updater interface {
+	Update(key string, fn func(value []byte) ([]byte, error)) error
+}
+
+// modify loads the queue state, calls fn to modify it, and stores it. When the key-value
+// store supports atomic updates, we perform all these steps atomically, otherwise we can
+// only guarantee atomicity within this process. This function MUST be called while
+// holding the mutex. When fn returns an error, we do not store the state. Because fn
+// runs while the key-value store is possibly locked, it MUST NOT use the store.
+func (q *Queue) modify(fn func(state *queueState) error) error {
+	update := func(data []byte) ([]byte, error) {
+		state := decodeState(data)
+		if err := fn(state); err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(state)
+		runtimex.PanicOnError(err, "json.Marshal unexpectedly failed")
+		return data, nil
+	}
+	if kvs, ok := q.kvStore.(updater); ok {
+		return kvs.Update(State, update)
+	}
+	data, _ := q.kvStore.Get(State) // a missing state is an empty queue
+	data, err := update(data)
+	if err != nil {
+		return err
+	}
+	return q.kvStore.Set(State, data)
+}
+
+// deleter is the optional interface of key-value stores that support deleting keys.
+type deleter interface {
+	Delete(key string) error
+}
+
+// removeBody removes the measurement of the entry with the given ID.
+func (q *Queue) removeBody(ID string) {
+	if kvs, ok := q.kvStore.(deleter); ok {
+		_ = kvs.Delete(bodyKey(ID))
+		return
+	}
+	_ = q.kvStore.Set(bodyKey(ID), nil)
+}
+
+// Enqueue adds a measurement to the queue, associating it with the given opaque tag.
+func (q *Queue) Enqueue(m *model.Measurement, tag string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	entry := &Entry{
+		Attempts:    0,
+		ID:          uuid.New().String(),
+		LastError:   "",
+		NextAttempt: q.timeNow(),
+		Tag:         tag,
+		TestName:    m.TestName,
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.kvStore.Set(bodyKey(entry.ID), data); err != nil {
+		return err
+	}
+	var dropped []string
+	err = q.modify(func(state *queueState) error {
+		state.Entries = append(state.Entries, entry)
+		for len(state.Entries) > MaxEntries {
+			dropped = append(dropped, state.Entries[0].ID)
+			state.Entries = state.Entries[1:]
+			state.Dropped++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, ID := range dropped {
+		q.removeBody(ID)
+	}
+	return nil
+}
+
+// Len returns the number of measurements in the queue.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.load().Entries)
+}
+
+// Dropped returns the number of measurements we dropped because the queue was full.
+func (q *Queue) Dropped() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.load().Dropped
+}
+
+// Entries returns a copy of the entries in the queue.
+func (q *Queue) Entries() (out []Entry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, entry := range q.load().Entries {
+		out = append(out, *entry)
+	}
+	return
+}
+
+// NextAttempt returns the time of the next attempt, and false when the queue is empty.
+func (q *Queue) NextAttempt() (time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var (
+		found bool
+		next  time.Time
+	)
+	for _, entry := range q.load().Entries {
+		if !found || entry.NextAttempt.Before(next) {
+			found, next = true, entry.NextAttempt
+		}
+	}
+	return next, found
+}
+
+// ErrCorruptEntry indicates that an entry's measurement is missing or we cannot decode it.
+var ErrCorruptEntry = errors.New("submitqueue: corrupt entry")
+
+// readEntry reads the measurement of the given entry. The error wraps [ErrCorruptEntry]
+// when we will never be able to read the measurement and is otherwise the error
+// returned by the key-value store, which may be temporary.
+func (q *Queue) readEntry(ID string) (*model.Measurement, error) {
+	data, err := q.kvStore.Get(bodyKey(ID))
+	if errors.Is(err, kvstore.ErrNoSuchKey) {
+		return nil, ErrCorruptEntry
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m model.Measurement
+	if err := json.Unmarshal(data, &m); err != nil || len(data) <= 0 {
+		return nil, ErrCorruptEntry
+	}
+	return &m, nil
+}
+
+// acquireDueEntries returns the entries that we should attempt to submit now
+// and marks them as inflight. The caller MUST call releaseEntry for each of them.
+func (q *Queue) acquireDueEntries() (out []Entry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.timeNow()
+	for _, entry := range q.load().Entries {
+		if !entry.NextAttempt.After(now) && !q.inflight[entry.ID] {
+			q.inflight[entry.ID] = true
+			out = append(out, *entry)
+		}
+	}
+	return
+}
+
+// releaseEntry marks the entry with the given ID as not inflight.
+func (q *Queue) releaseEntry(ID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inflight, ID)
+}
+
+// update updates the entry with the given ID after an attempt that failed with
+// the given error. When the error is nil, we remove the entry from the queue.
+func (q *Queue) update(ID string, err error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var removed bool
+	failure := q.modify(func(state *queueState) error {
+		for idx, entry := range state.Entries {
+			if entry.ID != ID {
+				continue
+			}
+			if err == nil {
+				removed = true
+				state.Entries = append(state.Entries[:idx], state.Entries[idx+1:]...)
+				return nil
+			}
+			entry.Attempts++
+			entry.LastError = err.Error()
+			entry.NextAttempt = q.timeNow().Add(Backoff(entry.Attempts))
+			return nil
+		}
+		return nil // the entry is gone (e.g., it was dropped because the queue was full)
+	})
+	if failure != nil {
+		return failure
+	}
+	if removed {
+		q.removeBody(ID)
+	}
+	return nil
+}
+
+// Flush attempts to submit the measurements whose next attempt is due using the
+// given submitter. For each successful submission, we call the OPTIONAL onSubmitted
+// callback with the entry's tag and the measurement UID. We reschedule failed
+// submissions using exponential backoff. We stop early when the context is done.
+// The return value is the number of measurements we successfully submitted.
+func (q *Queue) Flush(ctx context.Context, submitter model.Submitter,
+	onSubmitted func(tag string, measurementUID string)) (int, error) {
+	entries := q.acquireDueEntries()
+	defer func() {
+		for _, entry := range entries {
+			q.releaseEntry(entry.ID)
+		}
+	}()
+	var count int
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		m, err := q.readEntry(entry.ID)
+		if errors.Is(err, ErrCorruptEntry) {
+			// Note: we cannot do anything with an entry we cannot decode
+			if err := q.update(entry.ID, nil); err != nil {
+				return count, err
+			}
+			continue
+		}
+		if err != nil {
+			// Note: we keep the entry because the key-value store may work next time
+			if err := q.update(entry.ID, err); err != nil {
+				return count, err
+			}
+			continue
+		}
+		measurementUID, err := submitter.Submit(ctx, m)
+		if err != nil && ctx.Err() != nil {
+			// Note: a failure caused by interrupting us does not count as an attempt
+			return count, ctx.Err()
+		}
+		if err := q.update(entry.ID, err); err != nil {
+			return count, err
+		}
+		if err != nil {
+			continue
+		}
+		count++
+		if onSubmitted != nil {
+			onSubmitted(entry.Tag, measurementUID)
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,375 @@
+package submitqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// newSubmitter returns a submitter that fails when failure is not nil and
+// otherwise records the input of the submitted measurements.
+func newSubmitter(failure error, submitted *[]string) model.Submitter {
+	return &mocks.Submitter{
+		MockSubmit: func(ctx context.Context, m *model.Measurement) (string, error) {
+			if failure != nil {
+				return "", failure
+			}
+			*submitted = append(*submitted, string(m.Input))
+			return "uid_" + string(m.Input), nil
+		},
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	expect := map[int64]time.Duration{
+		0:  BackoffBase,
+		1:  BackoffBase,
+		2:  2 * BackoffBase,
+		3:  4 * BackoffBase,
+		12: BackoffMax,
+		99: BackoffMax,
+	}
+	for attempts, delay := range expect {
+		if got := Backoff(attempts); got != delay {
+			t.Fatal("unexpected delay", attempts, got)
+		}
+	}
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("Flush submits and removes the queued measurements", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		queue := New(kvStore)
+		for _, input := range []string{"a", "b"} {
+			if err := queue.Enqueue(&model.Measurement{Input: model.MeasurementInput(input)}, "tag_"+input); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if queue.Len() != 2 {
+			t.Fatal("unexpected queue length", queue.Len())
+		}
+		entries := queue.Entries()
+
+		var (
+			submitted []string
+			tags      []string
+		)
+		count, err := queue.Flush(context.Background(), newSubmitter(nil, &submitted),
+			func(tag, measurementUID string) {
+				tags = append(tags, tag+"/"+measurementUID)
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 || queue.Len() != 0 {
+			t.Fatal("unexpected result", count, queue.Len())
+		}
+		if diff := cmp.Diff([]string{"a", "b"}, submitted); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff([]string{"tag_a/uid_a", "tag_b/uid_b"}, tags); diff != "" {
+			t.Fatal(diff)
+		}
+
+		// make sure we removed the measurements from the key-value store
+		for _, entry := range entries {
+			if _, err := kvStore.Get(bodyKey(entry.ID)); !errors.Is(err, kvstore.ErrNoSuchKey) {
+				t.Fatal("expected the measurement to be gone", err)
+			}
+		}
+	})
+
+	t.Run("Flush reschedules failed submissions using backoff", func(t *testing.T) {
+		now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+		queue := New(&kvstore.Memory{})
+		queue.timeNow = func() time.Time {
+			return now
+		}
+		if err := queue.Enqueue(&model.Measurement{Input: "a"}, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		// the first attempt fails and we should wait for BackoffBase
+		expected := errors.New("mocked error")
+		count, err := queue.Flush(context.Background(), newSubmitter(expected, nil), nil)
+		if err != nil || count != 0 {
+			t.Fatal("unexpected result", count, err)
+		}
+		entries := queue.Entries()
+		if len(entries) != 1 || entries[0].Attempts != 1 || entries[0].LastError != "mocked error" {
+			t.Fatal("unexpected entries", entries)
+		}
+		if next, found := queue.NextAttempt(); !found || !next.Equal(now.Add(BackoffBase)) {
+			t.Fatal("unexpected next attempt", next, found)
+		}
+
+		// the entry is not due yet, so we should not attempt to submit it
+		var submitted []string
+		if count, _ := queue.Flush(context.Background(), newSubmitter(nil, &submitted), nil); count != 0 {
+			t.Fatal("unexpected count", count)
+		}
+
+		// when the entry is due, we submit it
+		now = now.Add(BackoffBase)
+		if count, _ := queue.Flush(context.Background(), newSubmitter(nil, &submitted), nil); count != 1 {
+			t.Fatal("unexpected count", count)
+		}
+		if queue.Len() != 0 {
+			t.Fatal("expected an empty queue")
+		}
+	})
+
+	t.Run("the queue survives recreating it", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		if err := New(kvStore).Enqueue(&model.Measurement{Input: "a"}, ""); err != nil {
+			t.Fatal(err)
+		}
+		var submitted []string
+		count, err := New(kvStore).Flush(context.Background(), newSubmitter(nil, &submitted), nil)
+		if err != nil || count != 1 {
+			t.Fatal("unexpected result", count, err)
+		}
+	})
+
+	t.Run("Flush drops entries whose measurement is corrupt", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		queue := New(kvStore)
+		if err := queue.Enqueue(&model.Measurement{Input: "a"}, ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := kvStore.Set(bodyKey(queue.Entries()[0].ID), []byte("{")); err != nil {
+			t.Fatal(err)
+		}
+		var submitted []string
+		count, err := queue.Flush(context.Background(), newSubmitter(nil, &submitted), nil)
+		if err != nil || count != 0 || queue.Len() != 0 {
+			t.Fatal("unexpected result", count, err, queue.Len())
+		}
+	})
+
+	t.Run("Flush drops entries whose measurement is missing", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		queue := New(kvStore)
+		if err := queue.Enqueue(&model.Measurement{Input: "a"}, ""); err != nil {
+			t.Fatal(err)
+		}
+		queue.removeBody(queue.Entries()[0].ID)
+		var submitted []string
+		count, err := queue.Flush(context.Background(), newSubmitter(nil, &submitted), nil)
+		if err != nil || count != 0 || queue.Len() != 0 {
+			t.Fatal("unexpected result", count, err, queue.Len())
+		}
+	})
+
+	t.Run("Flush keeps entries when it cannot read the measurement", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		child := &kvstore.Memory{}
+		var broken bool
+		kvStore := &mocks.KeyValueStore{
+			MockGet: func(key string) ([]byte, error) {
+				if broken && key != State {
+					return nil, expected
+				}
+				return child.Get(key)
+			},
+			MockSet: child.Set,
+		}
+		queue := New(kvStore)
+		if err := queue.Enqueue(&model.Measurement{Input: "a"}, ""); err != nil {
+			t.Fatal(err)
+		}
+		broken = true
+		var submitted []string
+		count, err := queue.Flush(context.Background(), newSubmitter(nil, &submitted), nil)
+		if err != nil || count != 0 {
+			t.Fatal("unexpected result", count, err)
+		}
+		entries := queue.Entries()
+		if len(entries) != 1 || entries[0].Attempts != 1 || entries[0].LastError != expected.Error() {
+			t.Fatal("unexpected entries", entries)
+		}
+		broken = false
+		queue.timeNow = func() time.Time {
+			return time.Now().Add(BackoffBase)
+		}
+		count, err = queue.Flush(context.Background(), newSubmitter(nil, &submitted), nil)
+		if err != nil || count != 1 || queue.Len() != 0 {
+			t.Fatal("unexpected result", count, err, queue.Len())
+		}
+	})
+
+	t.Run("Flush does not count interrupted attempts", func(t *testing.T) {
+		queue := New(&kvstore.Memory{})
+		if err := queue.Enqueue(&model.Measurement{Input: "a"}, ""); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		submitter := &mocks.Submitter{
+			MockSubmit: func(ctx context.Context, m *model.Measurement) (string, error) {
+				cancel()
+				return "", ctx.Err()
+			},
+		}
+		if _, err := queue.Flush(ctx, submitter, nil); !errors.Is(err, context.Canceled) {
+			t.Fatal("unexpected error", err)
+		}
+		if entries := queue.Entries(); len(entries) != 1 || entries[0].Attempts != 0 {
+			t.Fatal("unexpected entries", entries)
+		}
+	})
+
+	t.Run("concurrent calls to Flush do not submit the same entry twice", func(t *testing.T) {
+		queue := New(&kvstore.Memory{})
+		if err := queue.Enqueue(&model.Measurement{Input: "a"}, ""); err != nil {
+			t.Fatal(err)
+		}
+		var nested int
+		submitter := &mocks.Submitter{}
+		submitter.MockSubmit = func(ctx context.Context, m *model.Measurement) (string, error) {
+			// simulate another uploader running while we're submitting
+			count, err := queue.Flush(ctx, submitter, nil)
+			if err != nil {
+				return "", err
+			}
+			nested += count
+			return "uid", nil
+		}
+		count, err := queue.Flush(context.Background(), submitter, nil)
+		if err != nil || count != 1 || nested != 0 {
+			t.Fatal("unexpected result", count, nested, err)
+		}
+	})
+
+	t.Run("Enqueue drops the oldest entries when the queue is full", func(t *testing.T) {
+		queue := New(&kvstore.Memory{})
+		for idx := 0; idx < MaxEntries+1; idx++ {
+			tag := "first"
+			if idx > 0 {
+				tag = "other"
+			}
+			if err := queue.Enqueue(&model.Measurement{}, tag); err != nil {
+				t.Fatal(err)
+			}
+		}
+		entries := queue.Entries()
+		if len(entries) != MaxEntries || entries[0].Tag != "other" {
+			t.Fatal("unexpected entries", len(entries))
+		}
+		if dropped := queue.Dropped(); dropped != 1 {
+			t.Fatal("unexpected number of dropped entries", dropped)
+		}
+	})
+
+	t.Run("queues sharing a file system store do not lose entries", func(t *testing.T) {
+		// simulate several processes each with its own queue and key-value store
+		basedir := t.TempDir()
+		const queues, count = 8, 32
+		wg := &sync.WaitGroup{}
+		for idx := 0; idx < queues; idx++ {
+			kvStore, err := kvstore.NewFS(basedir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			queue := New(kvStore)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for idx := 0; idx < count; idx++ {
+					if err := queue.Enqueue(&model.Measurement{}, ""); err != nil {
+						t.Error(err)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		kvStore, err := kvstore.NewFS(basedir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if length := New(kvStore).Len(); length != queues*count {
+			t.Fatal("unexpected queue length", length)
+		}
+	})
+
+	t.Run("a corrupt state is an empty queue", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		if err := kvStore.Set(State, []byte("{")); err != nil {
+			t.Fatal(err)
+		}
+		queue := New(kvStore)
+		if queue.Len() != 0 {
+			t.Fatal("expected an empty queue")
+		}
+		if _, found := queue.NextAttempt(); found {
+			t.Fatal("expected no next attempt")
+		}
+	})
+
+	t.Run("Enqueue fails when we cannot write the measurement", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		kvStore := &mocks.KeyValueStore{
+			MockGet: func(key string) ([]byte, error) {
+				return nil, kvstore.ErrNoSuchKey
+			},
+			MockSet: func(key string, value []byte) error {
+				return expected
+			},
+		}
+		if err := New(kvStore).Enqueue(&model.Measurement{}, ""); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestUploader(t *testing.T) {
+	queue := New(&kvstore.Memory{})
+	if err := queue.Enqueue(&model.Measurement{Input: "a"}, "tag_a"); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu   sync.Mutex
+		tags []string
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	uploader := &Uploader{
+		Logger: model.DiscardLogger,
+		OnSubmitted: func(tag, measurementUID string) {
+			mu.Lock()
+			tags = append(tags, tag)
+			mu.Unlock()
+			cancel() // we're done
+		},
+		Queue: queue,
+		Submitter: &mocks.Submitter{
+			MockSubmit: func(ctx context.Context, m *model.Measurement) (string, error) {
+				return "uid", nil
+			},
+		},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		uploader.Run(ctx)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the uploader did not stop")
+	}
+
+	if diff := cmp.Diff([]string{"tag_a"}, tags); diff != "" {
+		t.Fatal(diff)
+	}
+	if queue.Len() != 0 {
+		t.Fatal("expected an empty queue")
+	}
+}
@@ -0,0 +1,81 @@
+package submitqueue
+
+//
+// Background uploader
+//
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// UploaderDefaultInterval is the default interval after which the
+// [*Uploader] checks again whether there are entries to submit.
+const UploaderDefaultInterval = time.Minute
+
+// uploaderMinDelay is the minimum delay between two flushes, which
+// prevents busy looping when we cannot update the queue.
+const uploaderMinDelay = time.Second
+
+// Uploader submits the measurements in a [*Queue] in the background.
+//
+// The zero value of this structure IS NOT valid and you MUST initialize
+// all the fields marked as MANDATORY before using this structure.
+type Uploader struct {
+	// Interval is the OPTIONAL maximum interval between two checks of the
+	// queue. When zero or negative, we use [UploaderDefaultInterval].
+	Interval time.Duration
+
+	// Logger is the MANDATORY logger to use.
+	Logger model.Logger
+
+	// OnSubmitted is the OPTIONAL callback called after each successful
+	// submission with the entry's tag and the measurement UID.
+	OnSubmitted func(tag string, measurementUID string)
+
+	// Queue is the MANDATORY queue.
+	Queue *Queue
+
+	// Submitter is the MANDATORY submitter to use.
+	Submitter model.Submitter
+}
+
+// Run submits the queued measurements until the context is done, waiting for
+// each entry's next attempt time, which uses exponential backoff. This function
+// returns when the context is done. You typically run it in a goroutine.
+func (u *Uploader) Run(ctx context.Context) {
+	interval := u.Interval
+	if interval <= 0 {
+		interval = UploaderDefaultInterval
+	}
+	for {
+		if count, err := u.Queue.Flush(ctx, u.Submitter, u.OnSubmitted); count > 0 || err != nil {
+			u.logResult(count, err)
+		}
+		delay := interval
+		if next, found := u.Queue.NextAttempt(); found {
+			delay = min(max(time.Until(next), uploaderMinDelay), interval)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// logResult logs the result of flushing the queue.
+func (u *Uploader) logResult(count int, err error) {
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		u.Logger.Warnf("submitqueue: cannot update the queue: %s", err.Error())
+	}
+	if count > 0 {
+		u.Logger.Infof("submitqueue: submitted %d queued measurements; %d still queued",
+			count, u.Queue.Len())
+	}
+}
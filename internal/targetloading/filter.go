@@ -0,0 +1,139 @@
+package targetloading
+
+//
+// Filtering and sampling targets
+//
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// ErrInvalidTargetFilter indicates that a [*model.ExperimentTargetFilter] is not valid.
+var ErrInvalidTargetFilter = errors.New("invalid target filter")
+
+// FilterTargets returns the targets matching the given filter, sampled according
+// to the filter's sampling strategy. Sampling preserves the relative order of the
+// selected targets. A nil filter returns the targets unchanged.
+func FilterTargets(
+	targets []model.ExperimentTarget, filter *model.ExperimentTargetFilter) ([]model.ExperimentTarget, error) {
+	if filter == nil {
+		return targets, nil
+	}
+	include, err := compilePatterns(filter.IncludePatterns)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compilePatterns(filter.ExcludePatterns)
+	if err != nil {
+		return nil, err
+	}
+	var output []model.ExperimentTarget
+	for _, target := range targets {
+		switch {
+		case len(filter.CategoryCodes) > 0 && !slices.Contains(filter.CategoryCodes, target.Category()):
+		case slices.Contains(filter.ExcludeCategoryCodes, target.Category()):
+		case len(include) > 0 && !matchesAny(include, target.Input()):
+		case matchesAny(exclude, target.Input()):
+		default:
+			output = append(output, target)
+		}
+	}
+	return sampleTargets(output, filter)
+}
+
+// compilePatterns compiles the given regular expressions.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var output []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTargetFilter, err.Error())
+		}
+		output = append(output, re)
+	}
+	return output, nil
+}
+
+// matchesAny returns whether the input matches any of the given regular expressions.
+func matchesAny(patterns []*regexp.Regexp, input string) bool {
+	for _, re := range patterns {
+		if re.MatchString(input) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleTargets samples the targets according to the filter.
+func sampleTargets(
+	targets []model.ExperimentTarget, filter *model.ExperimentTargetFilter) ([]model.ExperimentTarget, error) {
+	if filter.SampleSize <= 0 || filter.SampleSize >= len(targets) {
+		return targets, nil
+	}
+	seed := filter.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rnd := rand.New(rand.NewSource(seed)) // #nosec G404 -- used for sampling not for security
+
+	var indexes []int
+	switch filter.SampleStrategy {
+	case "", model.TargetSampleRandom:
+		indexes = rnd.Perm(len(targets))[:filter.SampleSize]
+	case model.TargetSampleStratified:
+		indexes = sampleStratified(rnd, targets, filter.SampleSize)
+	default:
+		return nil, fmt.Errorf("%w: unknown sample strategy: %s", ErrInvalidTargetFilter, filter.SampleStrategy)
+	}
+
+	sort.Ints(indexes)
+	var output []model.ExperimentTarget
+	for _, idx := range indexes {
+		output = append(output, targets[idx])
+	}
+	return output, nil
+}
+
+// sampleStratified returns the indexes of size targets taking, in turn, a
+// random target of each category, so that each category is equally represented
+// unless it does not contain enough targets.
+func sampleStratified(rnd *rand.Rand, targets []model.ExperimentTarget, size int) []int {
+	byCategory := map[string][]int{}
+	for idx, target := range targets {
+		byCategory[target.Category()] = append(byCategory[target.Category()], idx)
+	}
+	var categories []string
+	for category := range byCategory {
+		categories = append(categories, category)
+	}
+	// Sort the categories before shuffling such that, given the same seed, we make
+	// the same choices. Shuffling the categories avoids favouring the categories sorting
+	// first when the sample size is not a multiple of the number of categories.
+	sort.Strings(categories)
+	rnd.Shuffle(len(categories), func(i, j int) {
+		categories[i], categories[j] = categories[j], categories[i]
+	})
+	for _, category := range categories {
+		indexes := byCategory[category]
+		rnd.Shuffle(len(indexes), func(i, j int) {
+			indexes[i], indexes[j] = indexes[j], indexes[i]
+		})
+	}
+	var output []int
+	for round := 0; len(output) < size; round++ {
+		for _, category := range categories {
+			if indexes := byCategory[category]; round < len(indexes) && len(output) < size {
+				output = append(output, indexes[round])
+			}
+		}
+	}
+	return output
+}
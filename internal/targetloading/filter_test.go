@@ -0,0 +1,172 @@
+package targetloading
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// newFilterTestTargets returns the targets we use for testing the filters.
+func newFilterTestTargets() []model.ExperimentTarget {
+	var targets []model.ExperimentTarget
+	for _, entry := range []struct{ category, URL string }{
+		{"NEWS", "https://news1.example.com/"},
+		{"NEWS", "https://news2.example.com/"},
+		{"NEWS", "https://news3.example.com/"},
+		{"NEWS", "https://news4.example.com/"},
+		{"HUMR", "https://humr1.example.org/"},
+		{"HUMR", "https://humr2.example.org/"},
+		{"GAME", "https://game1.example.net/"},
+	} {
+		targets = append(targets, &model.OOAPIURLInfo{
+			CategoryCode: entry.category,
+			CountryCode:  "IT",
+			URL:          entry.URL,
+		})
+	}
+	return targets
+}
+
+// targetInputs returns the inputs of the given targets.
+func targetInputs(targets []model.ExperimentTarget) (out []string) {
+	for _, target := range targets {
+		out = append(out, target.Input())
+	}
+	return
+}
+
+func TestFilterTargets(t *testing.T) {
+	type testcase struct {
+		name   string
+		filter *model.ExperimentTargetFilter
+		expect []string
+	}
+
+	cases := []testcase{{
+		name:   "with a nil filter",
+		filter: nil,
+		expect: targetInputs(newFilterTestTargets()),
+	}, {
+		name: "with category codes",
+		filter: &model.ExperimentTargetFilter{
+			CategoryCodes: []string{"HUMR", "GAME"},
+		},
+		expect: []string{
+			"https://humr1.example.org/",
+			"https://humr2.example.org/",
+			"https://game1.example.net/",
+		},
+	}, {
+		name: "with excluded category codes",
+		filter: &model.ExperimentTargetFilter{
+			ExcludeCategoryCodes: []string{"NEWS"},
+		},
+		expect: []string{
+			"https://humr1.example.org/",
+			"https://humr2.example.org/",
+			"https://game1.example.net/",
+		},
+	}, {
+		name: "with include and exclude patterns",
+		filter: &model.ExperimentTargetFilter{
+			ExcludePatterns: []string{`news[12]\.`},
+			IncludePatterns: []string{`\.com/$`, `^https://game`},
+		},
+		expect: []string{
+			"https://news3.example.com/",
+			"https://news4.example.com/",
+			"https://game1.example.net/",
+		},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			targets, err := FilterTargets(newFilterTestTargets(), tc.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expect, targetInputs(targets)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+
+	t.Run("with an invalid pattern", func(t *testing.T) {
+		filter := &model.ExperimentTargetFilter{IncludePatterns: []string{"("}}
+		if _, err := FilterTargets(newFilterTestTargets(), filter); !errors.Is(err, ErrInvalidTargetFilter) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with an invalid sample strategy", func(t *testing.T) {
+		filter := &model.ExperimentTargetFilter{SampleSize: 1, SampleStrategy: "antani"}
+		if _, err := FilterTargets(newFilterTestTargets(), filter); !errors.Is(err, ErrInvalidTargetFilter) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestFilterTargetsSampling(t *testing.T) {
+	t.Run("random sampling is reproducible and preserves the order", func(t *testing.T) {
+		filter := &model.ExperimentTargetFilter{SampleSize: 4, Seed: 4}
+		first, err := FilterTargets(newFilterTestTargets(), filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := FilterTargets(newFilterTestTargets(), filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(first) != 4 {
+			t.Fatal("unexpected number of targets", len(first))
+		}
+		if diff := cmp.Diff(targetInputs(first), targetInputs(second)); diff != "" {
+			t.Fatal(diff)
+		}
+		all, position := targetInputs(newFilterTestTargets()), -1
+		for _, input := range targetInputs(first) {
+			idx := slices.Index(all, input)
+			if idx <= position {
+				t.Fatal("sampling did not preserve the order", targetInputs(first))
+			}
+			position = idx
+		}
+	})
+
+	t.Run("stratified sampling takes the same number of targets per category", func(t *testing.T) {
+		for seed := int64(1); seed <= 16; seed++ {
+			filter := &model.ExperimentTargetFilter{
+				SampleSize:     6,
+				SampleStrategy: model.TargetSampleStratified,
+				Seed:           seed,
+			}
+			targets, err := FilterTargets(newFilterTestTargets(), filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			count := map[string]int{}
+			for _, target := range targets {
+				count[target.Category()]++
+			}
+			// the first round takes a target per category, the second round
+			// exhausts GAME, and the third round exhausts HUMR
+			if diff := cmp.Diff(map[string]int{"GAME": 1, "HUMR": 2, "NEWS": 3}, count); diff != "" {
+				t.Fatal(seed, diff)
+			}
+		}
+	})
+
+	t.Run("we keep all the targets when the sample size is large enough", func(t *testing.T) {
+		filter := &model.ExperimentTargetFilter{SampleSize: 100}
+		targets, err := FilterTargets(newFilterTestTargets(), filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(targets) != len(newFilterTestTargets()) {
+			t.Fatal("unexpected number of targets", len(targets))
+		}
+	})
+}
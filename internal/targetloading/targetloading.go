@@ -14,18 +14,20 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/experimentname"
 	"github.com/ooni/probe-cli/v3/internal/fsx"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/servicedefs"
 	"github.com/ooni/probe-cli/v3/internal/stuninput"
 )
 
 // These errors are returned by the [*Loader] or the experiment execution.
 var (
-	ErrNoURLsReturned    = errors.New("no URLs returned")
-	ErrDetectedEmptyFile = errors.New("file did not contain any input")
-	ErrInputRequired     = errors.New("no input provided")
-	ErrNoInputExpected   = errors.New("we did not expect any input")
-	ErrNoStaticInput     = errors.New("no static input for this experiment")
-	ErrInvalidInputType  = errors.New("invalid richer input type")
-	ErrInvalidInput      = errors.New("input does not conform to spec")
+	ErrNoURLsReturned     = errors.New("no URLs returned")
+	ErrDetectedEmptyFile  = errors.New("file did not contain any input")
+	ErrInputRequired      = errors.New("no input provided")
+	ErrNoInputExpected    = errors.New("we did not expect any input")
+	ErrNoStaticInput      = errors.New("no static input for this experiment")
+	ErrInvalidInputType   = errors.New("invalid richer input type")
+	ErrInvalidInput       = errors.New("input does not conform to spec")
+	ErrAllTargetsFiltered = errors.New("the target filter discarded all targets")
 )
 
 // Session is the session according to a [*Loader] instance.
@@ -46,32 +48,39 @@ type Logger interface {
 //
 // # InputNone
 //
-// We fail if there is any StaticInput or any SourceFiles. If
+// We fail if there is any StaticInput, SourceFiles, or TestLists. If
 // there's no input, we return a single, empty entry that causes
 // experiments that don't require input to run once.
 //
 // # InputOptional
 //
-// We gather input from StaticInput and SourceFiles. If there is
+// We gather input from StaticInput, SourceFiles, and TestLists. If there is
 // input, we return it. Otherwise we return a single, empty entry
 // that causes experiments that don't require input to run once.
 //
 // # InputOrQueryBackend
 //
-// We gather input from StaticInput and SourceFiles. If there is
+// We gather input from StaticInput, SourceFiles, and TestLists. If there is
 // input, we return it. Otherwise, we use OONI's probe services
 // to gather input using the best API for the task.
 //
 // # InputOrStaticDefault
 //
-// We gather input from StaticInput and SourceFiles. If there is
+// We gather input from StaticInput, SourceFiles, and TestLists. If there is
 // input, we return it. Otherwise, we return an internal static
 // list of inputs to be used with this experiment.
 //
 // # InputStrictlyRequired
 //
-// We gather input from StaticInput and SourceFiles. If there is
+// We gather input from StaticInput, SourceFiles, and TestLists. If there is
 // input, we return it. Otherwise, we return an error.
+//
+// # Filtering
+//
+// When TargetFilter is set, we filter and sample the input we gather
+// locally as well as the input returned by OONI's probe services. If the
+// filter discards all the input we gathered locally, we fail rather than
+// falling back to OONI's probe services or to the static input.
 type Loader struct {
 	// CheckInConfig contains options for the CheckIn API. If
 	// not set, then we'll create a default config. If set but
@@ -112,6 +121,15 @@ type Loader struct {
 	// per line. We will fail if any file is unreadable
 	// as well as if any file is empty.
 	SourceFiles []string
+
+	// TargetFilter contains optional rules to filter and
+	// sample the loaded targets.
+	TargetFilter *model.ExperimentTargetFilter
+
+	// TestLists contains optional test lists to read targets
+	// from along with their category and country codes (see
+	// [ReadTestList] for more information about the format).
+	TestLists []string
 }
 
 // Load attempts to load input using the specified input loader. We will
@@ -133,7 +151,7 @@ func (il *Loader) Load(ctx context.Context) ([]model.ExperimentTarget, error) {
 
 // loadNone implements the InputNone policy.
 func (il *Loader) loadNone() ([]model.ExperimentTarget, error) {
-	if len(il.StaticInputs) > 0 || len(il.SourceFiles) > 0 {
+	if len(il.StaticInputs) > 0 || len(il.SourceFiles) > 0 || len(il.TestLists) > 0 {
 		return nil, ErrNoInputExpected
 	}
 	// Implementation note: the convention for input-less experiments is that
@@ -237,6 +255,9 @@ func staticBareInputForExperiment(name string) ([]string, error) {
 		// TODO(https://github.com/ooni/probe/issues/2557): server STUNReachability
 		// inputs using richer input (aka check-in v2).
 		return stunReachabilityDefaultInput, nil
+	case "service_reachability":
+		// By default, we measure all the services with an embedded definition.
+		return servicedefs.Names(), nil
 	default:
 		return nil, ErrNoStaticInput
 	}
@@ -257,7 +278,8 @@ func (il *Loader) loadOrStaticDefault(_ context.Context) ([]model.ExperimentTarg
 	return staticInputForExperiment(il.ExperimentName)
 }
 
-// loadLocal loads inputs from the [*Loader] StaticInputs and SourceFiles.
+// loadLocal loads inputs from the [*Loader] StaticInputs, SourceFiles, and TestLists
+// and filters them using the [*Loader] TargetFilter.
 func (il *Loader) loadLocal() ([]model.ExperimentTarget, error) {
 	inputs, err := LoadStatic(il)
 	if err != nil {
@@ -267,7 +289,31 @@ func (il *Loader) loadLocal() ([]model.ExperimentTarget, error) {
 	for _, input := range inputs {
 		targets = append(targets, model.NewOOAPIURLInfoWithDefaultCategoryAndCountry(input))
 	}
-	return targets, nil
+	for _, filepath := range il.TestLists {
+		entries, err := ReadTestList(filepath)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			targets = append(targets, entry)
+		}
+	}
+	if len(targets) <= 0 {
+		return targets, nil
+	}
+	return il.filter(targets)
+}
+
+// filter filters the given targets using the [*Loader] TargetFilter.
+func (il *Loader) filter(targets []model.ExperimentTarget) ([]model.ExperimentTarget, error) {
+	output, err := FilterTargets(targets, il.TargetFilter)
+	if err != nil {
+		return nil, err
+	}
+	if len(output) <= 0 {
+		return nil, ErrAllTargetsFiltered
+	}
+	return output, nil
 }
 
 // PerInputConfig returns a copy of base with the per-input richer-input config
@@ -347,7 +393,7 @@ func (il *Loader) loadRemoteWebConnectivity(ctx context.Context) ([]model.Experi
 		return nil, ErrNoURLsReturned
 	}
 	output := modelOOAPIURLInfoToModelExperimentTarget(reply.WebConnectivity.URLs)
-	return output, nil
+	return il.filter(output)
 }
 
 func modelOOAPIURLInfoToModelExperimentTarget(
@@ -16,6 +16,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/servicedefs"
 )
 
 func TestTargetLoaderInputNoneWithStaticInputs(t *testing.T) {
@@ -403,8 +404,29 @@ func TestTargetLoaderInputOrStaticDefaultWithoutInputStunReachability(t *testing
 	}
 }
 
+func TestTargetLoaderInputOrStaticDefaultWithoutInputServiceReachability(t *testing.T) {
+	il := &Loader{
+		ExperimentName: "service_reachability",
+		InputPolicy:    model.InputOrStaticDefault,
+	}
+	ctx := context.Background()
+	out, err := il.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := servicedefs.Names()
+	if len(out) != len(expect) {
+		t.Fatal("invalid output length")
+	}
+	for idx := 0; idx < len(expect); idx++ {
+		if out[idx].Input() != expect[idx] {
+			t.Fatal("invalid service name")
+		}
+	}
+}
+
 func TestStaticBareInputForExperimentWorksWithNonCanonicalNames(t *testing.T) {
-	names := []string{"DNSCheck", "STUNReachability"}
+	names := []string{"DNSCheck", "STUNReachability", "ServiceReachability"}
 	for _, name := range names {
 		if _, err := staticInputForExperiment(name); err != nil {
 			t.Fatal("failure for", name, ":", err)
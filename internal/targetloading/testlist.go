@@ -0,0 +1,141 @@
+package targetloading
+
+//
+// Reading test lists with category and country metadata
+//
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/fsx"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// ErrInvalidTestList indicates that a test list is not valid.
+var ErrInvalidTestList = errors.New("invalid test list")
+
+// globalTestListCountryCode is the country code of global URLs.
+const globalTestListCountryCode = "ZZ"
+
+// ReadTestList reads the targets inside the given test list file. A file
+// ending in ".json" MUST contain a JSON array of [model.OOAPIURLInfo]. Any
+// other file MUST use the citizenlab/test-lists CSV format, i.e., a CSV file
+// with a header line containing at least the url and category_code columns.
+//
+// The citizenlab/test-lists CSV format does not contain the country code, which
+// is implied by the file name (e.g., "it.csv" contains Italian URLs and "global.csv"
+// contains global URLs). Therefore, we use the file name to infer the country code
+// unless the CSV file has a country_code column.
+func ReadTestList(filename string) ([]*model.OOAPIURLInfo, error) {
+	return readTestList(filename, fsx.OpenFile)
+}
+
+// readTestList is like ReadTestList but allows to mock opening the file.
+func readTestList(filename string, open openFunc) ([]*model.OOAPIURLInfo, error) {
+	filep, err := open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer filep.Close()
+	var entries []*model.OOAPIURLInfo
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		entries, err = readTestListJSON(filep)
+	} else {
+		entries, err = readTestListCSV(filep, testListCountryCode(filename))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidTestList, filename, err.Error())
+	}
+	if len(entries) <= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDetectedEmptyFile, filename)
+	}
+	return entries, nil
+}
+
+// testListCountryCode infers the country code from the test list file name.
+func testListCountryCode(filename string) string {
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	switch {
+	case strings.EqualFold(name, "global"):
+		return globalTestListCountryCode
+	case len(name) == 2:
+		return strings.ToUpper(name)
+	default:
+		return model.DefaultCountryCode
+	}
+}
+
+// readTestListJSON reads a JSON test list.
+func readTestListJSON(reader io.Reader) ([]*model.OOAPIURLInfo, error) {
+	var entries []*model.OOAPIURLInfo
+	if err := json.NewDecoder(reader).Decode(&entries); err != nil {
+		return nil, err
+	}
+	for idx, entry := range entries {
+		if entry == nil || entry.URL == "" {
+			return nil, fmt.Errorf("entry %d: missing url", idx)
+		}
+		if entry.CategoryCode == "" {
+			entry.CategoryCode = model.DefaultCategoryCode
+		}
+		if entry.CountryCode == "" {
+			entry.CountryCode = model.DefaultCountryCode
+		}
+	}
+	return entries, nil
+}
+
+// readTestListCSV reads a CSV test list using the given default country code.
+func readTestListCSV(reader io.Reader, countryCode string) ([]*model.OOAPIURLInfo, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1 // some test lists have trailing empty notes
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for idx, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = idx
+	}
+	urlColumn, found := columns["url"]
+	if !found {
+		return nil, errors.New("missing url column")
+	}
+	field := func(record []string, name string) string {
+		if idx, found := columns[name]; found && idx < len(record) {
+			return strings.TrimSpace(record[idx])
+		}
+		return ""
+	}
+	var entries []*model.OOAPIURLInfo
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if urlColumn >= len(record) || strings.TrimSpace(record[urlColumn]) == "" {
+			continue // be lenient with empty lines
+		}
+		entry := &model.OOAPIURLInfo{
+			CategoryCode: field(record, "category_code"),
+			CountryCode:  field(record, "country_code"),
+			URL:          strings.TrimSpace(record[urlColumn]),
+		}
+		if entry.CategoryCode == "" {
+			entry.CategoryCode = model.DefaultCategoryCode
+		}
+		if entry.CountryCode == "" {
+			entry.CountryCode = countryCode
+		}
+		entries = append(entries, entry)
+	}
+}
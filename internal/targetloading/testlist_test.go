@@ -0,0 +1,189 @@
+package targetloading
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestReadTestList(t *testing.T) {
+	t.Run("with a citizenlab CSV file", func(t *testing.T) {
+		entries, err := ReadTestList("testdata/it.csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []*model.OOAPIURLInfo{{
+			CategoryCode: "NEWS",
+			CountryCode:  "IT",
+			URL:          "https://www.example.it/",
+		}, {
+			CategoryCode: "HUMR",
+			CountryCode:  "IT",
+			URL:          "https://www.example.org/",
+		}, {
+			CategoryCode: "NEWS",
+			CountryCode:  "IT",
+			URL:          "https://www.example.com/",
+		}}
+		if diff := cmp.Diff(expect, entries); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with the global citizenlab CSV file", func(t *testing.T) {
+		entries, err := ReadTestList("testdata/global.csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].CountryCode != "ZZ" || entries[0].CategoryCode != "ANON" {
+			t.Fatal("unexpected entries", entries)
+		}
+	})
+
+	t.Run("with a JSON file", func(t *testing.T) {
+		entries, err := ReadTestList("testdata/testlist.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []*model.OOAPIURLInfo{{
+			CategoryCode: "POLR",
+			CountryCode:  "DE",
+			URL:          "https://www.example.net/",
+		}, {
+			CategoryCode: model.DefaultCategoryCode,
+			CountryCode:  model.DefaultCountryCode,
+			URL:          "https://www.example.edu/",
+		}}
+		if diff := cmp.Diff(expect, entries); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a CSV file without the url column", func(t *testing.T) {
+		entries, err := ReadTestList("testdata/nourl.csv")
+		if !errors.Is(err, ErrInvalidTestList) {
+			t.Fatal("unexpected error", err)
+		}
+		if entries != nil {
+			t.Fatal("expected nil entries")
+		}
+	})
+
+	t.Run("with an empty file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "empty.json")
+		if err := os.WriteFile(filename, []byte("[]"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadTestList(filename); !errors.Is(err, ErrDetectedEmptyFile) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when we cannot read the file", func(t *testing.T) {
+		_, err := readTestList("it.csv", TargetLoaderBrokenFS{}.Open)
+		if !errors.Is(err, ErrInvalidTestList) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with a nonexistent file", func(t *testing.T) {
+		if _, err := ReadTestList("testdata/nonexistent.csv"); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestTargetLoaderWithTestLists(t *testing.T) {
+	t.Run("we load and filter the test lists", func(t *testing.T) {
+		il := &Loader{
+			InputPolicy:  model.InputOrQueryBackend,
+			StaticInputs: []string{"https://www.example.co.uk/"},
+			TargetFilter: &model.ExperimentTargetFilter{
+				ExcludeCategoryCodes: []string{"HUMR"},
+				ExcludePatterns:      []string{`\.com/$`},
+			},
+			TestLists: []string{"testdata/it.csv", "testdata/global.csv"},
+		}
+		targets, err := il.Load(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, target := range targets {
+			got = append(got, target.Category()+" "+target.Country()+" "+target.Input())
+		}
+		expect := []string{
+			"MISC XX https://www.example.co.uk/",
+			"NEWS IT https://www.example.it/",
+			"ANON ZZ https://www.torproject.org/",
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we fail when the filter discards all the targets", func(t *testing.T) {
+		il := &Loader{
+			InputPolicy: model.InputOrQueryBackend,
+			TargetFilter: &model.ExperimentTargetFilter{
+				CategoryCodes: []string{"GAME"},
+			},
+			TestLists: []string{"testdata/it.csv"},
+		}
+		targets, err := il.Load(context.Background())
+		if !errors.Is(err, ErrAllTargetsFiltered) {
+			t.Fatal("unexpected error", err)
+		}
+		if targets != nil {
+			t.Fatal("expected nil targets")
+		}
+	})
+
+	t.Run("we fail with InputNone", func(t *testing.T) {
+		il := &Loader{
+			InputPolicy: model.InputNone,
+			TestLists:   []string{"testdata/it.csv"},
+		}
+		if _, err := il.Load(context.Background()); !errors.Is(err, ErrNoInputExpected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestTargetLoaderFiltersRemoteTargets(t *testing.T) {
+	il := &Loader{
+		Session: &TargetLoaderMockableSession{
+			Output: &model.OOAPICheckInResult{
+				Tests: model.OOAPICheckInResultNettests{
+					WebConnectivity: &model.OOAPICheckInInfoWebConnectivity{
+						URLs: []model.OOAPIURLInfo{{
+							CategoryCode: "NEWS",
+							CountryCode:  "IT",
+							URL:          "https://repubblica.it",
+						}, {
+							CategoryCode: "NEWS",
+							CountryCode:  "IT",
+							URL:          "https://corriere.it",
+						}},
+					},
+				},
+			},
+		},
+		TargetFilter: &model.ExperimentTargetFilter{
+			IncludePatterns: []string{"corriere"},
+		},
+	}
+	out, err := il.loadRemoteWebConnectivity(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"https://corriere.it"}, targetInputs(out)); diff != "" {
+		t.Fatal(diff)
+	}
+}
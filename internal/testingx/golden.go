@@ -0,0 +1,255 @@
+package testingx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// GoldenUpdateEnv is the environment variable that, when set to "1", causes
+// [CompareGolden] to (re)generate golden files instead of comparing them.
+const GoldenUpdateEnv = "OONI_UPDATE_GOLDEN"
+
+// ErrGoldenMismatch indicates that a value differs from its golden file.
+var ErrGoldenMismatch = errors.New("testingx: value differs from golden file")
+
+// CompareGolden serializes value to JSON, normalizes it using [NormalizeGolden], and
+// compares the result with the content of the golden file at the given path.
+//
+// When the [GoldenUpdateEnv] environment variable is set to "1", this function
+// writes the normalized value to the golden file rather than comparing.
+func CompareGolden(path string, value any) error {
+	got, err := NormalizeGolden(value)
+	if err != nil {
+		return err
+	}
+	if os.Getenv(GoldenUpdateEnv) == "1" {
+		data, err := json.MarshalIndent(got, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		return os.WriteFile(path, append(data, '\n'), 0600)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var expect any
+	if err := json.Unmarshal(data, &expect); err != nil {
+		return err
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		return fmt.Errorf("%w: %s (set %s=1 to regenerate)\n%s", ErrGoldenMismatch, path, GoldenUpdateEnv, diff)
+	}
+	return nil
+}
+
+// NormalizeGolden serializes value to JSON and returns the corresponding
+// generic JSON value after removing the fields that change across runs:
+//
+// 1. timing fields (e.g., "t", "t0") become zero;
+//
+// 2. X.509 certificates, raw DNS messages (including the bodies of
+// DNS-over-HTTPS messages), and the Date HTTP header are replaced with
+// fixed placeholders;
+//
+// 3. ASN information, which depends on the GeoIP database, is zeroed;
+//
+// 4. network events lose their timing and byte counts and are sorted
+// and deduplicated, since their order and how reads and writes are split
+// depend on scheduling;
+//
+// 5. DNS queries are sorted, since we issue A and AAAA queries in parallel.
+func NormalizeGolden(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return goldenNormalizeValue(generic), nil
+}
+
+// goldenTimeKeys contains the keys of timing fields.
+var goldenTimeKeys = map[string]bool{
+	"bootstrap_time":         true,
+	"measurement_start_time": true,
+	"started":                true,
+	"t":                      true,
+	"t0":                     true,
+	"test_runtime":           true,
+	"test_start_time":        true,
+}
+
+func goldenNormalizeValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return goldenNormalizeObject(v)
+	case []any:
+		for idx, entry := range v {
+			v[idx] = goldenNormalizeValue(entry)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func goldenNormalizeObject(obj map[string]any) map[string]any {
+	goldenMaybeReplaceDNSMessageBody(obj)
+	for key, value := range obj {
+		switch {
+		case value == nil:
+			// nothing to normalize
+		case goldenTimeKeys[key]:
+			obj[key] = goldenZeroOf(value)
+		case key == "asn":
+			obj[key] = float64(0)
+		case key == "as_org_name":
+			obj[key] = ""
+		case key == "peer_certificates":
+			obj[key] = goldenReplaceEntries(value, "<certificate>")
+		case key == "raw_response":
+			obj[key] = "<raw_dns_message>"
+		case key == "headers":
+			obj[key] = goldenNormalizeHeaders(value)
+		case key == "headers_list":
+			obj[key] = goldenNormalizeHeadersList(value)
+		case key == "network_events":
+			obj[key] = goldenNormalizeNetworkEvents(value)
+		case key == "queries":
+			obj[key] = goldenSortEntries(value)
+		default:
+			obj[key] = goldenNormalizeValue(value)
+		}
+	}
+	return obj
+}
+
+// goldenMaybeReplaceDNSMessageBody replaces the body of HTTP requests and
+// responses containing DNS messages, which include a random query ID.
+func goldenMaybeReplaceDNSMessageBody(obj map[string]any) {
+	headers, ok := obj["headers"].(map[string]any)
+	if !ok || headers["Content-Type"] != "application/dns-message" {
+		return
+	}
+	if body, found := obj["body"]; found && body != nil && body != "" {
+		obj["body"] = "<raw_dns_message>"
+	}
+}
+
+func goldenZeroOf(value any) any {
+	switch value.(type) {
+	case float64:
+		return float64(0)
+	case string:
+		return ""
+	default:
+		return value
+	}
+}
+
+func goldenReplaceEntries(value any, placeholder string) any {
+	list, ok := value.([]any)
+	if !ok {
+		return placeholder
+	}
+	for idx := range list {
+		list[idx] = placeholder
+	}
+	return list
+}
+
+func goldenNormalizeHeaders(value any) any {
+	headers, ok := value.(map[string]any)
+	if !ok {
+		return goldenNormalizeValue(value)
+	}
+	if _, found := headers["Date"]; found {
+		headers["Date"] = "<date>"
+	}
+	return headers
+}
+
+func goldenNormalizeHeadersList(value any) any {
+	list, ok := value.([]any)
+	if !ok {
+		return goldenNormalizeValue(value)
+	}
+	for _, entry := range list {
+		pair, ok := entry.([]any)
+		if ok && len(pair) == 2 && pair[0] == "Date" {
+			pair[1] = "<date>"
+		}
+	}
+	return list
+}
+
+func goldenSortEntries(value any) any {
+	list, ok := goldenNormalizeValue(value).([]any)
+	if !ok {
+		return value
+	}
+	keys := make([]string, len(list))
+	for idx, entry := range list {
+		data, _ := json.Marshal(entry) // cannot fail: it was JSON to begin with
+		keys[idx] = string(data)
+	}
+	sort.Sort(goldenByKey{keys: keys, values: list})
+	return list
+}
+
+// goldenByKey sorts values according to the corresponding keys.
+type goldenByKey struct {
+	keys   []string
+	values []any
+}
+
+func (s goldenByKey) Len() int { return len(s.keys) }
+
+func (s goldenByKey) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+
+func (s goldenByKey) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
+
+func goldenNormalizeNetworkEvents(value any) any {
+	list, ok := value.([]any)
+	if !ok {
+		return goldenNormalizeValue(value)
+	}
+	var (
+		keys   []string
+		unique = make(map[string]any)
+	)
+	for _, entry := range list {
+		if ev, ok := entry.(map[string]any); ok {
+			delete(ev, "num_bytes")
+		}
+		entry = goldenNormalizeValue(entry)
+		data, _ := json.Marshal(entry) // cannot fail: it was JSON to begin with
+		key := string(data)
+		if _, found := unique[key]; found {
+			continue
+		}
+		unique[key] = entry
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := []any{}
+	for _, key := range keys {
+		out = append(out, unique[key])
+	}
+	return out
+}
@@ -0,0 +1,199 @@
+package testingx
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalizeGolden(t *testing.T) {
+	t.Run("we normalize the fields that change across runs", func(t *testing.T) {
+		input := map[string]any{
+			"queries": []any{map[string]any{
+				"answers": []any{map[string]any{
+					"asn":         15169,
+					"as_org_name": "Google LLC",
+					"ipv4":        "8.8.8.8",
+				}},
+				"raw_response": "AAAA",
+				"t0":           0.1,
+				"t":            0.2,
+			}},
+			"requests": []any{map[string]any{
+				"response": map[string]any{
+					"headers":      map[string]any{"Date": "Mon, 19 Oct 2026 02:00:00 GMT", "Server": "nginx"},
+					"headers_list": []any{[]any{"Date", "Mon, 19 Oct 2026 02:00:00 GMT"}, []any{"Server", "nginx"}},
+				},
+				"t": 0.5,
+			}},
+			"tls_handshakes": []any{map[string]any{
+				"peer_certificates": []any{map[string]any{"data": "AAAA", "format": "base64"}},
+				"t":                 0.3,
+			}},
+			"network_events": []any{
+				map[string]any{"operation": "write", "num_bytes": 10, "t": 0.4},
+				map[string]any{"operation": "read", "num_bytes": 20, "t": 0.5},
+				map[string]any{"operation": "read", "num_bytes": 30, "t": 0.6},
+			},
+			"failure": nil,
+		}
+		expect := map[string]any{
+			"queries": []any{map[string]any{
+				"answers": []any{map[string]any{
+					"asn":         float64(0),
+					"as_org_name": "",
+					"ipv4":        "8.8.8.8",
+				}},
+				"raw_response": "<raw_dns_message>",
+				"t0":           float64(0),
+				"t":            float64(0),
+			}},
+			"requests": []any{map[string]any{
+				"response": map[string]any{
+					"headers":      map[string]any{"Date": "<date>", "Server": "nginx"},
+					"headers_list": []any{[]any{"Date", "<date>"}, []any{"Server", "nginx"}},
+				},
+				"t": float64(0),
+			}},
+			"tls_handshakes": []any{map[string]any{
+				"peer_certificates": []any{"<certificate>"},
+				"t":                 float64(0),
+			}},
+			"network_events": []any{
+				map[string]any{"operation": "read", "t": float64(0)},
+				map[string]any{"operation": "write", "t": float64(0)},
+			},
+			"failure": nil,
+		}
+		got, err := NormalizeGolden(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we sort the DNS queries", func(t *testing.T) {
+		input := map[string]any{
+			"queries": []any{
+				map[string]any{"query_type": "AAAA", "failure": nil},
+				map[string]any{"query_type": "A", "failure": nil},
+			},
+		}
+		expect := map[string]any{
+			"queries": []any{
+				map[string]any{"query_type": "A", "failure": nil},
+				map[string]any{"query_type": "AAAA", "failure": nil},
+			},
+		}
+		got, err := NormalizeGolden(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we replace the body of DNS-over-HTTPS messages", func(t *testing.T) {
+		input := map[string]any{
+			"request": map[string]any{
+				"body":    "",
+				"headers": map[string]any{"Content-Type": "application/dns-message"},
+			},
+			"response": map[string]any{
+				"body":    map[string]any{"data": "qNiBAAAB", "format": "base64"},
+				"headers": map[string]any{"Content-Type": "application/dns-message"},
+			},
+		}
+		expect := map[string]any{
+			"request": map[string]any{
+				"body":    "",
+				"headers": map[string]any{"Content-Type": "application/dns-message"},
+			},
+			"response": map[string]any{
+				"body":    "<raw_dns_message>",
+				"headers": map[string]any{"Content-Type": "application/dns-message"},
+			},
+		}
+		got, err := NormalizeGolden(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we handle values that cannot be serialized", func(t *testing.T) {
+		got, err := NormalizeGolden(make(chan int))
+		if err == nil {
+			t.Fatal("expected an error here")
+		}
+		if got != nil {
+			t.Fatal("expected nil value")
+		}
+	})
+}
+
+func TestCompareGolden(t *testing.T) {
+	value := map[string]any{"t": 1.5, "failure": "generic_timeout_error"}
+
+	t.Run("we write and then compare the golden file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "testdata", "golden.json")
+		t.Setenv(GoldenUpdateEnv, "1")
+		if err := CompareGolden(path, value); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var written map[string]any
+		if err := json.Unmarshal(data, &written); err != nil {
+			t.Fatal(err)
+		}
+		if written["t"] != float64(0) {
+			t.Fatal("expected normalized golden file")
+		}
+		t.Setenv(GoldenUpdateEnv, "")
+		if err := CompareGolden(path, map[string]any{"t": 17.0, "failure": "generic_timeout_error"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("we detect mismatches", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "golden.json")
+		if err := os.WriteFile(path, []byte(`{"t":0,"failure":null}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv(GoldenUpdateEnv, "")
+		if err := CompareGolden(path, value); !errors.Is(err, ErrGoldenMismatch) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we fail if the golden file does not exist", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nonexistent.json")
+		t.Setenv(GoldenUpdateEnv, "")
+		if err := CompareGolden(path, value); !errors.Is(err, os.ErrNotExist) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we fail if the golden file is not JSON", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "golden.json")
+		if err := os.WriteFile(path, []byte(`{`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv(GoldenUpdateEnv, "")
+		if err := CompareGolden(path, value); err == nil {
+			t.Fatal("expected an error here")
+		}
+	})
+}
@@ -37,11 +37,11 @@ import (
 
 	// ```
 	//
-	// The `tracex` package contains code used to format internal
+	// The `measurexlite` package contains code used to format internal
 	// measurements representations to the OONI data format.
 	//
 	// ```Go
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 
 	// ```
 	//
@@ -176,7 +176,7 @@ func (m *Measurer) run(ctx context.Context,
 	//
 	// ```Go
 	if err := ptl.Start(); err != nil {
-		testkeys.Failure = tracex.NewFailure(err)
+		testkeys.Failure = measurexlite.NewFailure(err)
 		errch <- err
 		return
 	}
@@ -202,7 +202,7 @@ func (m *Measurer) run(ctx context.Context,
 	// ```
 	//
 	// In case of error, we convert `err` to a OONI failure using
-	// the `NewFailure` function of `tracex`. This function reduces
+	// the `NewFailure` function of `measurexlite`. This function reduces
 	// Go error strings to the error strings used by OONI. You can
 	// read the [errors spec](https://github.com/ooni/spec/blob/master/data-formats/df-007-errors.md)
 	// at the [github.com/ooni/spec repo](https://github.com/ooni/spec).
@@ -213,7 +213,7 @@ func (m *Measurer) run(ctx context.Context,
 	//
 	// ```Go
 	if err != nil {
-		testkeys.Failure = tracex.NewFailure(err)
+		testkeys.Failure = measurexlite.NewFailure(err)
 		errch <- nil
 		return
 	}
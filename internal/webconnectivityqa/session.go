@@ -1,6 +1,7 @@
 package webconnectivityqa
 
 import (
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netemx"
@@ -38,7 +39,9 @@ func newSession(client model.HTTPClient, logger model.Logger) model.ExperimentSe
 
 		MockFetchTorTargets: nil,
 
-		MockKeyValueStore: nil,
+		MockKeyValueStore: func() model.KeyValueStore {
+			return &kvstore.Memory{}
+		},
 
 		MockLogger: func() model.Logger {
 			return logger
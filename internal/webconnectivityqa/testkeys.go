@@ -74,11 +74,11 @@ func compareTestKeys(expected, got *TestKeys, ignoreFields ...string) error {
 	}
 
 	switch got.XExperimentVersion {
-	case "0.4.3":
+	case "0.4.4":
 		// ignore the fields that are specific to LTE
 		options = append(options, cmpopts.IgnoreFields(TestKeys{}, "XDNSFlags", "XBlockingFlags", "XNullNullFlags"))
 
-	case "0.5.28":
+	case "0.5.31":
 		// ignore the fields that are specific to v0.4
 		options = append(options, cmpopts.IgnoreFields(TestKeys{}, "XStatus"))
 
@@ -74,6 +74,7 @@ const (
 	eventTypeStatusReportCreate           = "status.report_create"
 	eventTypeStatusResolverLookup         = "status.resolver_lookup"
 	eventTypeStatusStarted                = "status.started"
+	eventTypeStatusSubmissionQueue        = "status.submission_queue"
 )
 
 // taskEmitter is anything that allows us to
@@ -140,6 +141,12 @@ type eventStatusReportGeneric struct {
 	ReportID string `json:"report_id"`
 }
 
+// eventStatusSubmissionQueue reports the number of measurements
+// waiting to be submitted in the durable submission queue.
+type eventStatusSubmissionQueue struct {
+	Depth int64 `json:"depth"`
+}
+
 type eventStatusResolverLookup struct {
 	GeoipDB             string `json:"geoip_db"`
 	ResolverASN         string `json:"resolver_asn"`
@@ -181,6 +188,13 @@ type taskSession interface {
 	// a new experiment given the experiment's name.
 	NewExperimentBuilder(name string) (model.ExperimentBuilder, error)
 
+	// KeyValueStore returns the session's key-value store.
+	KeyValueStore() model.KeyValueStore
+
+	// NewSubmitter creates a submitter that is not bound to any
+	// specific experiment, which we use to submit queued measurements.
+	NewSubmitter(ctx context.Context, useAuth bool) (model.Submitter, error)
+
 	// MaybeLookupBackendsContext lookups the OONI backend unless
 	// this operation has already been performed.
 	MaybeLookupBackendsContext(ctx context.Context) error
@@ -332,6 +346,13 @@ type settingsOptions struct {
 	// present, then the library startup will fail.
 	SoftwareVersion string `json:"software_version,omitempty"`
 
+	// SubmitQueue indicates whether to durably queue the measurements we could
+	// not submit and to retry submitting them in the background while running
+	// tasks. When enabled, we emit status.submission_queue events. Apps that
+	// resubmit failed measurements on their own should not enable this
+	// option, otherwise we may end up submitting a measurement twice.
+	SubmitQueue bool `json:"submit_queue,omitempty"`
+
 	// TODO(https://github.com/ooni/probe/issues/2767): to support OONI Run v2 descriptors with
 	// richer input from mobile, here we also need a string-serialization
 	// of the descriptor options to load.
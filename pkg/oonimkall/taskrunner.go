@@ -11,6 +11,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 )
 
 // runnerForTask runs a specific task
@@ -235,6 +236,15 @@ func (r *runnerForTask) Run(rootCtx context.Context) {
 		})
 	}
 
+	// when requested, queue the measurements we cannot submit and retry
+	// submitting previously queued measurements in the background
+	var submitQueue *submitqueue.Queue
+	if !r.settings.Options.NoCollector && r.settings.Options.SubmitQueue {
+		submitQueue = submitqueue.New(sess.KeyValueStore())
+		stopUploader := r.startSubmitQueueUploader(rootCtx, sess, submitQueue, logger)
+		defer stopUploader()
+	}
+
 	// create the default context for measuring
 	measCtx, measCancel := context.WithCancel(rootCtx)
 	defer measCancel()
@@ -365,6 +375,9 @@ func (r *runnerForTask) Run(rootCtx context.Context) {
 				Failure:        measurementSubmissionFailure(err),
 				MeasurementUID: muid,
 			})
+			if err != nil && submitQueue != nil {
+				r.enqueueMeasurement(submitQueue, m, logger)
+			}
 		}
 
 		// let the app know that we're done measuring this entry
@@ -375,6 +388,50 @@ func (r *runnerForTask) Run(rootCtx context.Context) {
 	}
 }
 
+// startSubmitQueueUploader starts submitting the queued measurements in the background
+// and returns the function to stop the uploader, which emits the final queue depth.
+func (r *runnerForTask) startSubmitQueueUploader(ctx context.Context,
+	sess taskSession, queue *submitqueue.Queue, logger model.Logger) func() {
+	r.emitSubmissionQueueDepth(queue)
+	submitter, err := sess.NewSubmitter(ctx, false)
+	if err != nil {
+		warnOnFailure(logger, "cannot create submitter for queued measurements", err)
+		return func() {}
+	}
+	uploader := &submitqueue.Uploader{
+		Logger:    logger,
+		Queue:     queue,
+		Submitter: submitter,
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		uploader.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+		r.emitSubmissionQueueDepth(queue)
+	}
+}
+
+// enqueueMeasurement adds a measurement we could not submit to the queue.
+func (r *runnerForTask) enqueueMeasurement(queue *submitqueue.Queue, m *model.Measurement, logger model.Logger) {
+	if err := queue.Enqueue(m, ""); err != nil {
+		warnOnFailure(logger, "cannot queue measurement for later submission", err)
+		return
+	}
+	r.emitSubmissionQueueDepth(queue)
+}
+
+// emitSubmissionQueueDepth emits the number of measurements in the queue.
+func (r *runnerForTask) emitSubmissionQueueDepth(queue *submitqueue.Queue) {
+	r.emitter.Emit(eventTypeStatusSubmissionQueue, eventStatusSubmissionQueue{
+		Depth: int64(queue.Len()),
+	})
+}
+
 func warnOnFailure(logger model.Logger, message string, err error) {
 	if err != nil {
 		logger.Warnf("%s: %s (%+v)", message, err.Error(), err)
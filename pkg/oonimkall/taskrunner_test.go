@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
@@ -731,6 +732,60 @@ func TestTaskRunnerRun(t *testing.T) {
 		assertReducedEventsLike(t, expect, reduced)
 	})
 
+	t.Run("with measurement submission failure and the submission queue", func(t *testing.T) {
+		runner, emitter := newRunnerForTesting()
+		runner.settings.Options.SubmitQueue = true
+		fake := fakeSuccessfulDeps()
+		fake.Experiment.MockSubmitAndUpdateMeasurementContext = func(ctx context.Context, measurement *model.Measurement) (string, error) {
+			return "", errors.New("cannot submit")
+		}
+		kvStore := &kvstore.Memory{}
+		fake.Session.MockKeyValueStore = func() model.KeyValueStore {
+			return kvStore
+		}
+		fake.Session.MockNewSubmitter = func(ctx context.Context, useAuth bool) (model.Submitter, error) {
+			return &mocks.Submitter{
+				MockSubmit: func(ctx context.Context, m *model.Measurement) (string, error) {
+					return "", errors.New("cannot submit")
+				},
+			}, nil
+		}
+		runner.newSession = fake.NewSession
+		events := runAndCollect(runner, emitter)
+		reduced := reduceEventsKeysIgnoreLog(t, events)
+		expect := []eventKeyCount{
+			{Key: eventTypeStatusQueued, Count: 1},
+			{Key: eventTypeStatusStarted, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 3},
+			{Key: eventTypeStatusGeoIPLookup, Count: 1},
+			{Key: eventTypeStatusResolverLookup, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 1},
+			{Key: eventTypeStatusReportCreate, Count: 1},
+			{Key: eventTypeStatusSubmissionQueue, Count: 1},
+			//
+			{Key: eventTypeStatusMeasurementStart, Count: 1},
+			{Key: eventTypeMeasurement, Count: 1},
+			{Key: eventTypeFailureMeasurementSubmission, Count: 1},
+			{Key: eventTypeStatusSubmissionQueue, Count: 1},
+			{Key: eventTypeStatusMeasurementDone, Count: 1},
+			//
+			{Key: eventTypeStatusSubmissionQueue, Count: 1},
+			{Key: eventTypeStatusEnd, Count: 1},
+		}
+		assertReducedEventsLike(t, expect, reduced)
+
+		// the queue depth should reflect the failed submission
+		var depths []int64
+		for _, ev := range events {
+			if ev.Key == eventTypeStatusSubmissionQueue {
+				depths = append(depths, ev.Value.(eventStatusSubmissionQueue).Depth)
+			}
+		}
+		if diff := cmp.Diff([]int64{0, 1, 1}, depths); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
 	t.Run("with success and progress", func(t *testing.T) {
 		runner, emitter := newRunnerForTesting()
 		fake := fakeSuccessfulDeps()